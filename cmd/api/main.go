@@ -6,34 +6,64 @@ import (
 	"os"
 	"path/filepath"
 
+	"tts/internal/cachecmd"
+	"tts/internal/clipboard"
+	"tts/internal/config"
+	"tts/internal/doctor"
 	"tts/internal/http/server"
+	"tts/internal/service"
+	"tts/internal/setup"
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "init":
+			if err := setup.Run(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+				log.Fatalf("生成配置失败: %v", err)
+			}
+			return
+		case "doctor":
+			if err := doctor.Run(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("自检未通过: %v", err)
+			}
+			return
+		case "service":
+			if err := service.Run(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("服务管理失败: %v", err)
+			}
+			return
+		case "cache":
+			if err := cachecmd.Run(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("缓存导出导入失败: %v", err)
+			}
+			return
+		case "clipboard":
+			if err := clipboard.Run(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("剪贴板朗读失败: %v", err)
+			}
+			return
+		}
+	}
+
 	// 解析命令行参数
 	configPath := flag.String("config", "", "配置文件路径")
+	env := flag.String("env", "", "配置环境名称，合并同目录下的 config.{env}.yaml，默认使用环境变量 TTS_ENV")
+	printConfig := flag.Bool("print-config", false, "列出合并后生效的配置项及其来源后退出")
+	workDir := flag.String("workdir", "", "启动前切换到的工作目录，配置中的相对路径按该目录解析")
 	flag.Parse()
+	config.SetEnvironment(*env)
 
-	// 如果没有指定配置文件，尝试默认位置
-	if *configPath == "" {
-		// 尝试多个位置查找配置文件
-		possiblePaths := []string{
-			"./configs/config.yaml",
-			"../configs/config.yaml",
-			"/etc/tts/config.yaml",
-		}
-
-		for _, path := range possiblePaths {
-			if _, err := os.Stat(path); err == nil {
-				*configPath = path
-				break
-			}
+	if *workDir != "" {
+		if err := os.Chdir(*workDir); err != nil {
+			log.Fatalf("切换工作目录失败: %v", err)
 		}
+	}
 
-		// 如果还是没找到，使用默认位置
-		if *configPath == "" {
-			*configPath = "./configs/config.yaml"
-		}
+	// 如果没有指定配置文件，尝试默认位置
+	if *configPath == "" {
+		*configPath = config.FindFile()
 	}
 
 	// 确保配置文件路径是绝对路径
@@ -42,6 +72,13 @@ func main() {
 		log.Fatalf("无法获取配置文件的绝对路径: %v", err)
 	}
 
+	if *printConfig {
+		if err := config.WriteEffective(os.Stdout, absConfigPath); err != nil {
+			log.Fatalf("读取配置失败: %v", err)
+		}
+		return
+	}
+
 	// 打印使用的配置文件路径
 	log.Printf("使用配置文件: %s", absConfigPath)
 
@@ -52,7 +89,7 @@ func main() {
 	}
 
 	// 启动应用并处理错误
-	if err := app.Start(); err != nil {
+	if err := service.RunApp(app.Start, app.Stop); err != nil {
 		log.Fatalf("应用运行出错: %v", err)
 	}
 }
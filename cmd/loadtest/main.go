@@ -0,0 +1,238 @@
+// loadtest 将录制的请求回放到运行中的服务，并报告延迟分位数与吞吐量。
+//
+// 请求文件为 JSON Lines 格式，每行一个请求：
+//
+//	{"method":"POST","path":"/v1/audio/speech","headers":{"Content-Type":"application/json"},"body":{"input":"你好","voice":"alloy"}}
+//	{"method":"GET","path":"/tts?t=你好&v=zh-CN-XiaoxiaoNeural"}
+//
+// 用法：
+//
+//	go run ./cmd/loadtest -target http://localhost:8080 -file requests.jsonl -c 8 -n 200
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recordedRequest 是一条录制的请求
+type recordedRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// result 记录单次请求的结果
+type result struct {
+	latency time.Duration
+	status  int
+	bytes   int64
+	err     error
+}
+
+// headerFlags 支持多次指定 -H
+type headerFlags []string
+
+func (h *headerFlags) String() string     { return strings.Join(*h, ", ") }
+func (h *headerFlags) Set(v string) error { *h = append(*h, v); return nil }
+
+func main() {
+	target := flag.String("target", "http://localhost:8080", "服务地址")
+	file := flag.String("file", "", "录制的请求文件 (JSON Lines)")
+	concurrency := flag.Int("c", 4, "并发数")
+	total := flag.Int("n", 100, "请求总数，循环使用录制的请求")
+	duration := flag.Duration("d", 0, "持续时间，设置后忽略 -n")
+	timeout := flag.Duration("timeout", 60*time.Second, "单个请求超时")
+	var headers headerFlags
+	flag.Var(&headers, "H", "附加请求头，如 \"Authorization: Bearer xxx\"，可重复")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("必须通过 -file 指定请求文件")
+	}
+	requests, err := loadRequests(*file)
+	if err != nil {
+		log.Fatalf("读取请求文件失败: %v", err)
+	}
+	if len(requests) == 0 {
+		log.Fatal("请求文件为空")
+	}
+
+	extraHeaders := map[string]string{}
+	for _, h := range headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			log.Fatalf("无效的请求头: %s", h)
+		}
+		extraHeaders[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	var counter int64
+	var deadline time.Time
+	if *duration > 0 {
+		deadline = time.Now().Add(*duration)
+	}
+
+	// next 返回下一条要发送的请求，没有更多请求时返回 false
+	next := func() (recordedRequest, bool) {
+		i := atomic.AddInt64(&counter, 1) - 1
+		if deadline.IsZero() {
+			if i >= int64(*total) {
+				return recordedRequest{}, false
+			}
+		} else if time.Now().After(deadline) {
+			return recordedRequest{}, false
+		}
+		return requests[i%int64(len(requests))], true
+	}
+
+	results := make(chan result, *concurrency*2)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < *concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				req, ok := next()
+				if !ok {
+					return
+				}
+				results <- send(client, *target, req, extraHeaders)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var all []result
+	for r := range results {
+		all = append(all, r)
+	}
+	report(all, time.Since(start))
+}
+
+// loadRequests 读取 JSON Lines 格式的请求文件
+func loadRequests(path string) ([]recordedRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var requests []recordedRequest
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var req recordedRequest
+		if err := json.Unmarshal([]byte(text), &req); err != nil {
+			return nil, fmt.Errorf("第 %d 行解析失败: %w", line, err)
+		}
+		if req.Method == "" {
+			req.Method = http.MethodGet
+		}
+		requests = append(requests, req)
+	}
+	return requests, scanner.Err()
+}
+
+// send 发送一条请求并读取完整响应
+func send(client *http.Client, target string, rec recordedRequest, extraHeaders map[string]string) result {
+	var body io.Reader
+	if len(rec.Body) > 0 {
+		body = bytes.NewReader(rec.Body)
+	}
+	req, err := http.NewRequest(rec.Method, strings.TrimRight(target, "/")+rec.Path, body)
+	if err != nil {
+		return result{err: err}
+	}
+	if len(rec.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range rec.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range extraHeaders {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{latency: time.Since(start), err: err}
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(io.Discard, resp.Body)
+	return result{latency: time.Since(start), status: resp.StatusCode, bytes: n, err: err}
+}
+
+// percentile 返回已排序延迟中的分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+// report 打印压测报告
+func report(results []result, elapsed time.Duration) {
+	var latencies []time.Duration
+	statuses := map[int]int{}
+	errors := 0
+	var totalBytes int64
+	for _, r := range results {
+		if r.err != nil {
+			errors++
+			continue
+		}
+		statuses[r.status]++
+		totalBytes += r.bytes
+		latencies = append(latencies, r.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Printf("请求总数:   %d\n", len(results))
+	fmt.Printf("总耗时:     %v\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("吞吐量:     %.2f 请求/秒\n", float64(len(results))/elapsed.Seconds())
+	fmt.Printf("传输数据:   %.2f MB\n", float64(totalBytes)/(1024*1024))
+	fmt.Printf("网络错误:   %d\n", errors)
+
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Printf("状态码 %d: %d\n", code, statuses[code])
+	}
+
+	if len(latencies) > 0 {
+		fmt.Printf("延迟 p50:   %v\n", percentile(latencies, 0.50).Round(time.Millisecond))
+		fmt.Printf("延迟 p95:   %v\n", percentile(latencies, 0.95).Round(time.Millisecond))
+		fmt.Printf("延迟 p99:   %v\n", percentile(latencies, 0.99).Round(time.Millisecond))
+		fmt.Printf("延迟 max:   %v\n", latencies[len(latencies)-1].Round(time.Millisecond))
+	}
+}
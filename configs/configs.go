@@ -0,0 +1,9 @@
+// Package configs 提供随程序发布的示例配置，tts init 以其为模板生成配置文件
+package configs
+
+import _ "embed"
+
+// Default 是示例配置文件 config.yaml 的内容
+//
+//go:embed config.yaml
+var Default []byte
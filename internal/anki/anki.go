@@ -0,0 +1,196 @@
+// Package anki 解析闪卡的 CSV/TSV 列表，并生成可以直接放入 Anki 媒体文件夹的音频文件名与导入文件
+package anki
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"tts/internal/utils"
+)
+
+// maxNameRunes 是由文本生成的文件名最多保留的字符数
+const maxNameRunes = 60
+
+// Card 是一张卡片，FrontText、BackText 为去掉 HTML 与声音标记后用于朗读的文本
+type Card struct {
+	Line      int
+	Front     string
+	Back      string
+	FrontText string
+	BackText  string
+	Name      string // 不带扩展名的文件名，未指定时为空
+}
+
+var (
+	// soundTags 匹配字段中已有的 Anki 声音标记
+	soundTags = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	// unsafeNameChars 匹配 Anki 与常见文件系统不允许出现在文件名中的字符
+	unsafeNameChars = regexp.MustCompile(`[\\/:*?"<>|\[\]\x00-\x1f]+`)
+	// spaces 匹配连续的空白
+	spaces = regexp.MustCompile(`\s+`)
+)
+
+// columns 是可以出现在表头中的列名
+var columns = map[string]bool{"front": true, "back": true, "filename": true}
+
+// Parse 解析卡片列表。每行依次为 front、back、filename，后两列可以省略；
+// 第一行的各列都是这些列名时作为表头，按表头确定列的顺序。
+// delimiter 为 0 时根据第一行是否含有制表符判断是 TSV 还是 CSV，以 # 开头的行（如 Anki 导出的 #separator:tab）被忽略
+func Parse(data []byte, delimiter rune) ([]Card, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("文件不是有效的 UTF-8 编码")
+	}
+	if delimiter == 0 {
+		delimiter = sniff(data)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	index := map[string]int{"front": 0, "back": 1, "filename": 2}
+	var cards []Card
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if first && isHeader(record) {
+			index = map[string]int{"front": -1, "back": -1, "filename": -1}
+			for i, field := range record {
+				index[strings.ToLower(strings.TrimSpace(field))] = i
+			}
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i := index[name]; i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		card := Card{Line: line, Front: field("front"), Back: field("back")}
+		card.FrontText = speakable(card.Front)
+		card.BackText = speakable(card.Back)
+		card.Name = strings.TrimSuffix(sanitizeName(field("filename")), ".mp3")
+		if card.Front == "" && card.Back == "" {
+			continue
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// sniff 根据第一个非注释行判断分隔符
+func sniff(data []byte) rune {
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, "\t") {
+			return '\t'
+		}
+		break
+	}
+	return ','
+}
+
+func isHeader(record []string) bool {
+	for _, field := range record {
+		if !columns[strings.ToLower(strings.TrimSpace(field))] {
+			return false
+		}
+	}
+	return true
+}
+
+// speakable 把字段转换为朗读用的纯文本：Anki 的字段是 HTML，并且可能已经带有声音标记
+func speakable(field string) string {
+	text := utils.HTMLToText(soundTags.ReplaceAllString(field, ""))
+	return strings.TrimSpace(spaces.ReplaceAllString(text, " "))
+}
+
+// sanitizeName 移除文件名中不允许的字符，空白替换为下划线
+func sanitizeName(name string) string {
+	name = unsafeNameChars.ReplaceAllString(name, "")
+	name = spaces.ReplaceAllString(strings.TrimSpace(name), "_")
+	return strings.Trim(name, ".")
+}
+
+// Names 为各卡片的每一面分配不重复的文件名。没有指定文件名的卡片使用 prefix 加上正面文本，
+// 正面与背面都朗读时背面的文件名加上 _back；重名时依次加上 -2、-3
+type Names struct {
+	prefix string
+	used   map[string]bool
+}
+
+// NewNames 创建文件名分配器，prefix 用于避免与媒体文件夹中其他卡组的文件重名
+func NewNames(prefix string) *Names {
+	return &Names{prefix: sanitizeName(prefix), used: make(map[string]bool)}
+}
+
+// Assign 返回卡片一面的文件名（带 .mp3 扩展名），suffix 为空或 "_back"
+func (n *Names) Assign(card Card, index int, suffix string) string {
+	base := card.Name
+	if base == "" {
+		base = sanitizeName(card.FrontText)
+		if runes := []rune(base); len(runes) > maxNameRunes {
+			base = string(runes[:maxNameRunes])
+		}
+		if base == "" {
+			base = fmt.Sprintf("card%03d", index+1)
+		}
+		base = n.prefix + base
+	}
+	base += suffix
+
+	name := base + ".mp3"
+	// Anki 在不区分大小写的文件系统上也要能区分文件
+	for i := 2; n.used[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d.mp3", base, i)
+	}
+	n.used[strings.ToLower(name)] = true
+	return name
+}
+
+// SoundTag 返回引用音频文件的 Anki 声音标记
+func SoundTag(name string) string {
+	return "[sound:" + name + "]"
+}
+
+// WriteNotes 写出可以用 Anki 的“导入文件”功能导入的 TSV：front 与 back 两列，
+// 音频的声音标记附加在对应字段的末尾。frontSounds、backSounds 中为空的卡片不附加声音标记
+func WriteNotes(w io.Writer, cards []Card, frontSounds, backSounds []string) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = '\t'
+	if _, err := io.WriteString(w, "#separator:tab\n#html:true\n"); err != nil {
+		return err
+	}
+	for i, card := range cards {
+		front, back := card.Front, card.Back
+		if frontSounds[i] != "" {
+			front += SoundTag(frontSounds[i])
+		}
+		if backSounds[i] != "" {
+			back += SoundTag(backSounds[i])
+		}
+		if err := writer.Write([]string{front, back}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
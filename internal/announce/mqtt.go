@@ -0,0 +1,217 @@
+// Package announce 订阅 MQTT 主题，将收到的消息合成为语音，
+// 保存到指定目录或通过响应主题发布，适用于智能家居的语音播报。
+package announce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/utils"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
+)
+
+// AudioPath 是通过 HTTP 提供已保存播报音频的路径前缀
+const AudioPath = "/mqtt/audio"
+
+// unsafeNameChars 匹配文件名中不安全的字符
+var unsafeNameChars = regexp.MustCompile(`[^\w.-]+`)
+
+// message 是主题上收到的 JSON 消息，也可以直接发送纯文本
+type message struct {
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+	Voice string `json:"voice"`
+	Rate  string `json:"rate"`
+	Pitch string `json:"pitch"`
+	Style string `json:"style"`
+	File  string `json:"file"` // 保存的文件名，默认使用 ID
+}
+
+// result 是在响应主题上发布的 JSON 结果
+type result struct {
+	ID    string `json:"id"`
+	File  string `json:"file,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Size  int    `json:"size,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Announcer 订阅 MQTT 主题并合成语音
+type Announcer struct {
+	synthesizer *ttspkg.Synthesizer
+	config      *config.Config
+	client      mqtt.Client
+}
+
+// New 创建播报服务
+func New(synthesizer *ttspkg.Synthesizer, cfg *config.Config) *Announcer {
+	return &Announcer{synthesizer: synthesizer, config: cfg}
+}
+
+// Start 连接 MQTT 服务器并订阅主题，ctx 结束时断开连接
+func (a *Announcer) Start(ctx context.Context) error {
+	cfg := a.config.MQTT
+	if cfg.OutputDir != "" {
+		if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+			return fmt.Errorf("创建播报音频目录失败: %w", err)
+		}
+	}
+
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "tts-" + uuid.New().String()[:8]
+	}
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(clientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(client mqtt.Client) {
+			// 重连后需要重新订阅
+			token := client.Subscribe(cfg.Topic, byte(cfg.QoS), func(_ mqtt.Client, msg mqtt.Message) {
+				go a.handle(ctx, msg.Payload())
+			})
+			if token.Wait() && token.Error() != nil {
+				log.Printf("订阅 MQTT 主题 %s 失败: %v", cfg.Topic, token.Error())
+				return
+			}
+			log.Printf("已订阅 MQTT 主题 %s", cfg.Topic)
+		})
+
+	a.client = mqtt.NewClient(opts)
+	token := a.client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		// 开启了连接重试，服务器恢复后会自动连接并订阅
+		log.Printf("MQTT 服务器 %s 暂时无法连接，将在后台重试", cfg.Broker)
+	} else if token.Error() != nil {
+		return fmt.Errorf("连接 MQTT 服务器失败: %w", token.Error())
+	}
+
+	go func() {
+		<-ctx.Done()
+		a.client.Disconnect(250)
+	}()
+	return nil
+}
+
+// handle 处理一条消息
+func (a *Announcer) handle(ctx context.Context, payload []byte) {
+	var msg message
+	if err := json.Unmarshal(payload, &msg); err != nil || msg.Text == "" {
+		// 不是 JSON 时整条消息作为文本
+		msg = message{Text: string(payload)}
+	}
+	msg.Text = strings.TrimSpace(msg.Text)
+	if msg.Text == "" {
+		return
+	}
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+
+	req := models.TTSRequest{
+		Text:  msg.Text,
+		Voice: msg.Voice,
+		Rate:  msg.Rate,
+		Pitch: msg.Pitch,
+		Style: msg.Style,
+	}
+	if req.Voice == "" {
+		req.Voice = a.config.TTS.DefaultVoice
+	}
+	if req.Rate == "" {
+		req.Rate = a.config.TTS.DefaultRate
+	}
+	if req.Pitch == "" {
+		req.Pitch = a.config.TTS.DefaultPitch
+	}
+
+	timeout := time.Duration(a.config.TTS.RequestTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	synthCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res := result{ID: msg.ID}
+	resp, err := a.synthesizer.Synthesize(synthCtx, req)
+	if err != nil {
+		log.Printf("MQTT 播报合成失败: %v", err)
+		res.Error = utils.SanitizeMessage(err.Error(), a.config.TTS.ApiKey, a.config.OpenAI.ApiKey)
+		a.publishResult(res)
+		return
+	}
+	if resp.AudioContent, err = watermark.Stamp(a.config, resp.AudioContent, "mqtt"); err != nil {
+		log.Printf("MQTT 播报%v", err)
+		res.Error = "添加水印失败"
+		a.publishResult(res)
+		return
+	}
+	res.Size = len(resp.AudioContent)
+
+	if a.config.MQTT.OutputDir != "" {
+		name := msg.File
+		if name == "" {
+			name = msg.ID
+		}
+		// 只保留文件名，防止写到输出目录之外
+		name = unsafeNameChars.ReplaceAllString(filepath.Base(name), "_")
+		if !strings.HasSuffix(name, ".mp3") {
+			name += ".mp3"
+		}
+		if err := os.WriteFile(filepath.Join(a.config.MQTT.OutputDir, name), resp.AudioContent, 0644); err != nil {
+			log.Printf("保存播报音频失败: %v", err)
+			res.Error = "保存音频失败"
+		} else {
+			res.File = name
+			if a.config.MQTT.BaseURL != "" {
+				res.URL = strings.TrimRight(a.config.MQTT.BaseURL, "/") + AudioPath + "/" + url.PathEscape(name)
+			}
+		}
+	}
+
+	log.Printf("MQTT 播报完成: %s, 文本长度: %d, 音频大小: %s", msg.ID, utils.GraphemeCount(msg.Text), utils.FormatFileSize(res.Size))
+
+	if a.config.MQTT.ResponseMode == "audio" && res.Error == "" {
+		a.publish(resp.AudioContent)
+		return
+	}
+	a.publishResult(res)
+}
+
+// publishResult 在响应主题上发布 JSON 结果
+func (a *Announcer) publishResult(res result) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	a.publish(data)
+}
+
+// publish 在响应主题上发布消息，未配置响应主题时不发布
+func (a *Announcer) publish(payload []byte) {
+	topic := a.config.MQTT.ResponseTopic
+	if topic == "" {
+		return
+	}
+	token := a.client.Publish(topic, byte(a.config.MQTT.QoS), false, payload)
+	if token.WaitTimeout(10*time.Second) && token.Error() != nil {
+		log.Printf("发布 MQTT 消息失败: %v", token.Error())
+	}
+}
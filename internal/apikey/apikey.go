@@ -0,0 +1,73 @@
+// Package apikey 识别请求使用的 API 密钥，并提供 keys 配置中为该密钥单独设置的选项，
+// 例如是否添加水印。
+package apikey
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/config"
+)
+
+// contextKey 是保存当前请求密钥配置的上下文键
+const contextKey = "api_key_profile"
+
+// Presented 返回请求携带的密钥，依次检查 Bearer 令牌、X-Api-Key 请求头与 api_key、key 查询参数
+func Presented(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key := c.GetHeader("X-Api-Key"); key != "" {
+		return key
+	}
+	if key := c.Query("api_key"); key != "" {
+		return key
+	}
+	return c.Query("key")
+}
+
+// Lookup 在 keys 配置中查找密钥
+func Lookup(cfg *config.Config, key string) *config.APIKey {
+	if key == "" {
+		return nil
+	}
+	for i := range cfg.Keys {
+		if cfg.Keys[i].Key == key {
+			return &cfg.Keys[i]
+		}
+	}
+	return nil
+}
+
+// ByName 按名称查找密钥配置，用于后台任务沿用提交方密钥的设置
+func ByName(cfg *config.Config, name string) *config.APIKey {
+	if name == "" {
+		return nil
+	}
+	for i := range cfg.Keys {
+		if cfg.Keys[i].Name == name {
+			return &cfg.Keys[i]
+		}
+	}
+	return nil
+}
+
+// Identify 返回识别请求密钥的中间件，密钥在 keys 中配置时保存到上下文，并把密钥名称作为租户写入请求上下文
+func Identify(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if profile := Lookup(cfg, Presented(c)); profile != nil {
+			c.Set(contextKey, profile)
+			// 合成时按密钥名称选择单独设置的 SSML 处理器
+			c.Request = c.Request.WithContext(config.WithTenant(c.Request.Context(), profile.Name))
+		}
+		c.Next()
+	}
+}
+
+// FromContext 返回当前请求的密钥配置，未使用 keys 中的密钥时返回 nil
+func FromContext(c *gin.Context) *config.APIKey {
+	if v, ok := c.Get(contextKey); ok {
+		return v.(*config.APIKey)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package apperr 定义带机器可读错误码的统一错误类型，
+// 并负责将其转换为 HTTP 状态码与 OpenAI 风格的错误响应体。
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/config"
+	"tts/internal/utils"
+)
+
+// Code 是机器可读的错误码
+type Code string
+
+const (
+	CodeInvalidRequest     Code = "invalid_request"      // 请求参数无效
+	CodeUnauthorized       Code = "unauthorized"         // 未授权访问
+	CodeNotFound           Code = "not_found"            // 资源不存在
+	CodeMethodNotAllowed   Code = "method_not_allowed"   // 请求方法不支持
+	CodeNotSupported       Code = "not_supported"        // 当前服务不支持该功能
+	CodeConflict           Code = "conflict"             // 与资源当前状态冲突
+	CodeTermsNotAccepted   Code = "terms_not_accepted"   // 尚未确认使用条款
+	CodePayloadTooLarge    Code = "payload_too_large"    // 请求体超过大小上限
+	CodeRateLimited        Code = "rate_limited"         // 客户端请求过于频繁
+	CodeInvalidVoice       Code = "invalid_voice"        // 语音不存在或不可用
+	CodeTextTooLong        Code = "text_too_long"        // 文本超过长度限制
+	CodeSSMLInvalid        Code = "ssml_invalid"         // SSML 文档无效
+	CodeProviderThrottled  Code = "provider_throttled"   // 上游服务限流
+	CodeProviderAuthFailed Code = "provider_auth_failed" // 上游服务认证失败
+	CodeProviderError      Code = "provider_error"       // 上游服务其他错误
+	CodeInternal           Code = "internal_error"       // 服务器内部错误
+)
+
+// codeInfo 描述错误码对应的 HTTP 状态与 OpenAI 错误类型
+type codeInfo struct {
+	status  int
+	errType string
+}
+
+var codeInfos = map[Code]codeInfo{
+	CodeInvalidRequest:     {http.StatusBadRequest, "invalid_request_error"},
+	CodeUnauthorized:       {http.StatusUnauthorized, "authentication_error"},
+	CodeNotFound:           {http.StatusNotFound, "invalid_request_error"},
+	CodeMethodNotAllowed:   {http.StatusMethodNotAllowed, "invalid_request_error"},
+	CodeNotSupported:       {http.StatusNotImplemented, "invalid_request_error"},
+	CodeConflict:           {http.StatusConflict, "invalid_request_error"},
+	CodeTermsNotAccepted:   {http.StatusForbidden, "permission_error"},
+	CodePayloadTooLarge:    {http.StatusRequestEntityTooLarge, "invalid_request_error"},
+	CodeRateLimited:        {http.StatusTooManyRequests, "rate_limit_error"},
+	CodeInvalidVoice:       {http.StatusBadRequest, "invalid_request_error"},
+	CodeTextTooLong:        {http.StatusBadRequest, "invalid_request_error"},
+	CodeSSMLInvalid:        {http.StatusBadRequest, "invalid_request_error"},
+	CodeProviderThrottled:  {http.StatusServiceUnavailable, "rate_limit_error"},
+	CodeProviderAuthFailed: {http.StatusBadGateway, "server_error"},
+	CodeProviderError:      {http.StatusBadGateway, "server_error"},
+	CodeInternal:           {http.StatusInternalServerError, "server_error"},
+}
+
+// Status 返回错误码对应的 HTTP 状态码
+func (c Code) Status() int {
+	if info, ok := codeInfos[c]; ok {
+		return info.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Type 返回错误码对应的 OpenAI 错误类型
+func (c Code) Type() string {
+	if info, ok := codeInfos[c]; ok {
+		return info.errType
+	}
+	return "server_error"
+}
+
+// Error 是带错误码的应用错误
+type Error struct {
+	Code    Code   // 机器可读的错误码
+	Message string // 面向客户端的错误描述
+	Err     error  // 原始错误，仅用于日志
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap 返回原始错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New 创建一个应用错误
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf 使用格式化字符串创建一个应用错误
+func Newf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap 使用错误码包装一个原始错误
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// From 从错误链中提取应用错误，无法识别的错误视为内部错误。
+// 读取请求体超过大小上限时，无论处理器如何包装，都返回 payload_too_large
+func From(err error) *Error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return Newf(CodePayloadTooLarge, "请求体超过 %d 字节的上限", tooLarge.Limit)
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Wrap(CodeInternal, "服务器内部错误", err)
+}
+
+// CodeOf 返回错误链中的错误码
+func CodeOf(err error) Code {
+	return From(err).Code
+}
+
+// Body 是 OpenAI 风格的错误响应体
+type Body struct {
+	Error BodyError `json:"error"`
+}
+
+// BodyError 是错误响应体中的错误详情
+type BodyError struct {
+	Message   string `json:"message"`
+	Type      string `json:"type"`
+	Code      Code   `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Abort 以统一格式写入错误响应并中止请求
+func Abort(c *gin.Context, err error) {
+	appErr := From(err)
+
+	// 原始错误可能包含上游响应、内部路径或密钥，只写入服务端日志，客户端只看到 Message
+	if appErr.Err != nil {
+		cfg := config.Get()
+		log.Printf("请求出错 [%s] request_id=%s: %s", appErr.Code, c.GetString("request_id"),
+			utils.SanitizeMessage(appErr.Error(), cfg.TTS.ApiKey, cfg.OpenAI.ApiKey))
+	}
+
+	c.AbortWithStatusJSON(appErr.Code.Status(), Body{Error: BodyError{
+		Message:   appErr.Message,
+		Type:      appErr.Code.Type(),
+		Code:      appErr.Code,
+		RequestID: c.GetString("request_id"),
+	}})
+}
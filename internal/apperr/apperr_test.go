@@ -0,0 +1,46 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAbortHidesWrappedError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	upstream := errors.New(`上游返回 401: {"token":"eyJhbGciOiJIUzI1NiJ9.e30.sig"} /etc/tts/config.yaml`)
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"invalid request", Wrap(CodeInvalidRequest, "无效的请求参数", upstream), "无效的请求参数"},
+		{"upstream", Wrap(CodeProviderError, "语音合成服务出错", upstream), "语音合成服务出错"},
+		{"internal", Wrap(CodeInternal, "服务器内部错误", upstream), "服务器内部错误"},
+		{"plain error", upstream, "服务器内部错误"},
+		{"no wrapped error", New(CodeNotFound, "语音不存在"), "语音不存在"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			Abort(c, tt.err)
+
+			var body Body
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("响应体不是 JSON: %v", err)
+			}
+			if body.Error.Message != tt.want {
+				t.Errorf("Message = %q, want %q", body.Error.Message, tt.want)
+			}
+			if w.Code != From(tt.err).Code.Status() {
+				t.Errorf("状态码 = %d", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"tts/internal/utils"
+)
+
+// RunFFmpeg 运行 ffmpeg 命令并返回其标准输出，输出先写入缓冲池中的缓冲区，结束后复制一份，避免随输出反复扩容
+func RunFFmpeg(cmd *exec.Cmd, action string) ([]byte, error) {
+	stdout, stderr := utils.GetBuffer(), utils.GetBuffer()
+	defer utils.PutBuffer(stdout)
+	defer utils.PutBuffer(stderr)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", action, err, stderr.String())
+	}
+	return bytes.Clone(stdout.Bytes()), nil
+}
+
+// Stretch 使用 ffmpeg 的 atempo 滤镜把 MP3 的播放速度调整为 factor 倍，音调不变，输出保持原音频的编码参数。
+// 单个 atempo 只接受 0.5～2.0 倍，超出时串联多个
+func Stretch(data []byte, factor float64) ([]byte, error) {
+	format, ok := ProbeMP3(data)
+	if !ok {
+		return nil, fmt.Errorf("无法识别的 MP3 音频")
+	}
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-f", "mp3", "-i", "pipe:0",
+		"-filter:a", tempoFilters(factor),
+		"-ac", strconv.Itoa(format.Channels), "-ar", strconv.Itoa(format.SampleRate),
+		"-b:a", fmt.Sprintf("%dk", format.Bitrate),
+		"-f", "mp3", "pipe:1")
+	cmd.Stdin = bytes.NewReader(data)
+	return RunFFmpeg(cmd, "音频变速失败")
+}
+
+// tempoFilters 把倍数拆成若干个 0.5～2.0 之间的 atempo 滤镜
+func tempoFilters(factor float64) string {
+	var filters []string
+	for factor > 2 {
+		filters = append(filters, "atempo=2")
+		factor /= 2
+	}
+	for factor < 0.5 {
+		filters = append(filters, "atempo=0.5")
+		factor /= 0.5
+	}
+	filters = append(filters, "atempo="+strconv.FormatFloat(factor, 'f', 4, 64))
+	return strings.Join(filters, ",")
+}
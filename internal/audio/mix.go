@@ -0,0 +1,80 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Track 是按顺序拼接的一段音频
+type Track struct {
+	Data []byte
+	// Pan 是立体声中的声像位置，-1 为最左，0 为居中，1 为最右；单声道输出时忽略
+	Pan float64
+}
+
+// Concat 使用 ffmpeg 按顺序拼接多段 MP3，每段之间插入 gap 的静音。
+// stereo 为 true 时输出立体声，各段按 Pan 放置到左右声道（等功率声像），否则输出单声道。
+// 输出采样率与第一段相同，每个声道的比特率与第一段相同
+func Concat(tracks []Track, gap time.Duration, stereo bool) ([]byte, error) {
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("没有音频片段可拼接")
+	}
+	format, ok := ProbeMP3(tracks[0].Data)
+	if !ok {
+		return nil, fmt.Errorf("无法识别的 MP3 音频")
+	}
+
+	tempDir, err := os.MkdirTemp("", "audio_concat_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	args := []string{"-hide_banner", "-loglevel", "error"}
+	var filters, labels []string
+	for i, track := range tracks {
+		path := filepath.Join(tempDir, fmt.Sprintf("track_%d.mp3", i))
+		if err := os.WriteFile(path, track.Data, 0644); err != nil {
+			return nil, err
+		}
+		args = append(args, "-f", "mp3", "-i", path)
+
+		// 先统一为单声道与相同的采样率，再按声像分配到左右声道
+		chain := fmt.Sprintf("[%d:a]aresample=%d,aformat=channel_layouts=mono", i, format.SampleRate)
+		if stereo {
+			left, right := panGains(track.Pan)
+			chain += fmt.Sprintf(",pan=stereo|c0=%.4f*c0|c1=%.4f*c0", left, right)
+		}
+		if gap > 0 && i < len(tracks)-1 {
+			chain += fmt.Sprintf(",apad=pad_dur=%.3f", gap.Seconds())
+		}
+		label := fmt.Sprintf("[a%d]", i)
+		filters = append(filters, chain+label)
+		labels = append(labels, label)
+	}
+	filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=0:a=1[out]", strings.Join(labels, ""), len(tracks)))
+
+	// 按每个声道的比特率计算，立体声输出时加倍
+	channels, bitrate := 1, format.Bitrate/format.Channels
+	if stereo {
+		channels, bitrate = 2, bitrate*2
+	}
+	args = append(args,
+		"-filter_complex", strings.Join(filters, ";"), "-map", "[out]",
+		"-ac", strconv.Itoa(channels), "-ar", strconv.Itoa(format.SampleRate),
+		"-b:a", fmt.Sprintf("%dk", bitrate),
+		"-f", "mp3", "pipe:1")
+	return RunFFmpeg(exec.Command("ffmpeg", args...), "拼接音频失败")
+}
+
+// panGains 返回声像位置对应的左右声道增益，两者的平方和为 1，声音在各个位置响度一致
+func panGains(pan float64) (left, right float64) {
+	angle := (math.Max(-1, math.Min(1, pan)) + 1) * math.Pi / 4
+	return math.Cos(angle), math.Sin(angle)
+}
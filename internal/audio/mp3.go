@@ -0,0 +1,60 @@
+// Package audio 提供对合成音频的后期处理：识别 MP3 编码参数，以及通过 ffmpeg 进行的变速等处理。
+package audio
+
+// mp3Bitrates 是 Layer III 的比特率表 (kbps)，分别对应 MPEG-1 与 MPEG-2/2.5
+var mp3Bitrates = [2][15]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// mp3SampleRates 是采样率表，按版本位 (0: MPEG-2.5, 2: MPEG-2, 3: MPEG-1) 索引
+var mp3SampleRates = map[byte][3]int{
+	0: {11025, 12000, 8000},
+	2: {22050, 24000, 16000},
+	3: {44100, 48000, 32000},
+}
+
+// Format 是 MP3 流的编码参数
+type Format struct {
+	SampleRate int
+	Bitrate    int // kbps
+	Channels   int
+}
+
+// ProbeMP3 从第一个 Layer III 帧头读取编码参数，跳过开头的 ID3v2 标签
+func ProbeMP3(data []byte) (Format, bool) {
+	if len(data) >= 10 && string(data[:3]) == "ID3" {
+		size := int(data[6])<<21 | int(data[7])<<14 | int(data[8])<<7 | int(data[9])
+		if 10+size > len(data) {
+			return Format{}, false
+		}
+		data = data[10+size:]
+	}
+	for i := 0; i+4 <= len(data); i++ {
+		if data[i] != 0xFF || data[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		version := (data[i+1] >> 3) & 0x03
+		layer := (data[i+1] >> 1) & 0x03
+		bitrateIndex := data[i+2] >> 4
+		rateIndex := (data[i+2] >> 2) & 0x03
+		rates, ok := mp3SampleRates[version]
+		if !ok || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+			continue
+		}
+		table := 1
+		if version == 3 {
+			table = 0
+		}
+		channels := 2
+		if data[i+3]>>6 == 3 {
+			channels = 1
+		}
+		return Format{
+			SampleRate: rates[rateIndex],
+			Bitrate:    mp3Bitrates[table][bitrateIndex],
+			Channels:   channels,
+		}, true
+	}
+	return Format{}, false
+}
@@ -0,0 +1,47 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Pad 在 MP3 的开头与末尾补充静音，align 大于 0 时继续在末尾补充静音，使总时长为 align 的整数倍。
+// 解码为 PCM 后按采样数补齐再重新编码，输出保持原音频的编码参数
+func Pad(data []byte, start, end, align time.Duration) ([]byte, error) {
+	format, ok := ProbeMP3(data)
+	if !ok {
+		return nil, fmt.Errorf("无法识别的 MP3 音频")
+	}
+	channels, rate := strconv.Itoa(format.Channels), strconv.Itoa(format.SampleRate)
+	decode := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-f", "mp3", "-i", "pipe:0",
+		"-f", "s16le", "-acodec", "pcm_s16le", "-ac", channels, "-ar", rate,
+		"pipe:1")
+	decode.Stdin = bytes.NewReader(data)
+	pcm, err := RunFFmpeg(decode, "解码音频失败")
+	if err != nil {
+		return nil, err
+	}
+
+	frameSize := 2 * format.Channels
+	frames := func(d time.Duration) int {
+		return int(d.Seconds() * float64(format.SampleRate))
+	}
+	lead, trail := frames(start), frames(end)
+	if step := frames(align); step > 0 {
+		total := lead + len(pcm)/frameSize + trail
+		trail += (step - total%step) % step
+	}
+
+	padded := make([]byte, (lead+trail)*frameSize+len(pcm))
+	copy(padded[lead*frameSize:], pcm)
+	encode := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-f", "s16le", "-ac", channels, "-ar", rate, "-i", "pipe:0",
+		"-b:a", fmt.Sprintf("%dk", format.Bitrate),
+		"-f", "mp3", "pipe:1")
+	encode.Stdin = bytes.NewReader(padded)
+	return RunFFmpeg(encode, "编码音频失败")
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// archiveVersion 是导出文件的格式版本
+const archiveVersion = 1
+
+// manifestName 是导出文件中列出缓存键的文件，位于所有音频之前，导入时可以边读边写入
+const manifestName = "manifest.json"
+
+var (
+	// validKey 匹配 Key 生成的缓存键，导入时拒绝其他键，避免写到缓存目录之外
+	validKey = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	// validHash 匹配音频内容的哈希
+	validHash = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// manifest 是导出文件的清单
+type manifest struct {
+	Version int             `json:"version"`
+	Created time.Time       `json:"created"`
+	Entries []manifestEntry `json:"entries"`
+}
+
+type manifestEntry struct {
+	Key  string `json:"key"`
+	Hash string `json:"hash"`
+}
+
+// Entry 是一个缓存条目
+type Entry struct {
+	Key  string
+	Data []byte
+}
+
+// WriteArchive 把条目写为 tar.gz：manifest.json 列出各缓存键引用的音频哈希，
+// 音频按内容保存在 blobs/ 下，相同的音频只保存一份
+func WriteArchive(w io.Writer, entries []Entry) error {
+	blobs := make(map[string][]byte)
+	refs := make([]manifestEntry, 0, len(entries))
+	for _, e := range entries {
+		hash := contentHash(e.Data)
+		blobs[hash] = e.Data
+		refs = append(refs, manifestEntry{Key: e.Key, Hash: hash})
+	}
+	return writeArchive(w, refs, func(hash string) ([]byte, error) { return blobs[hash], nil })
+}
+
+// Export 把缓存中的全部条目（内存与磁盘）写为 tar.gz，返回条目数。音频逐个从磁盘读取，
+// 不会一次加载到内存
+func (c *Cache) Export(w io.Writer) (int, error) {
+	refs := make(map[string]string)
+	c.diskMu.Lock()
+	for key, hash := range c.diskRefs {
+		refs[key] = hash
+	}
+	c.diskMu.Unlock()
+
+	memory := make(map[string][]byte)
+	c.mu.Lock()
+	for key, el := range c.items {
+		hash := el.Value.(*entry).hash
+		if _, ok := refs[key]; !ok {
+			refs[key] = hash
+			memory[hash] = c.blobs[hash].data
+		}
+	}
+	c.mu.Unlock()
+
+	entries := make([]manifestEntry, 0, len(refs))
+	for key, hash := range refs {
+		entries = append(entries, manifestEntry{Key: key, Hash: hash})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	err := writeArchive(w, entries, func(hash string) ([]byte, error) {
+		if data, ok := memory[hash]; ok {
+			return data, nil
+		}
+		return os.ReadFile(c.blobPath(hash))
+	})
+	return len(entries), err
+}
+
+// writeArchive 写出清单与清单引用的音频，load 按哈希读取音频
+func writeArchive(w io.Writer, entries []manifestEntry, load func(hash string) ([]byte, error)) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now().UTC()
+
+	data, err := json.MarshalIndent(manifest{Version: archiveVersion, Created: now, Entries: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, manifestName, data, now); err != nil {
+		return err
+	}
+
+	written := make(map[string]bool)
+	for _, e := range entries {
+		if written[e.Hash] {
+			continue
+		}
+		written[e.Hash] = true
+		audio, err := load(e.Hash)
+		if err != nil {
+			return fmt.Errorf("读取缓存音频 %s 失败: %w", e.Hash, err)
+		}
+		if err := writeTarFile(tw, path.Join(blobDir, e.Hash+legacyExt), audio, now); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modified time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modified}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Import 读取 WriteArchive 或 Export 写出的 tar.gz，把其中的条目写入缓存，返回导入的条目数。
+// 每个音频都校验哈希，与清单不符的文件使整个导入失败（已经写入的条目保留）
+func (c *Cache) Import(r io.Reader) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("不是有效的 gzip 文件: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestName {
+		return 0, errors.New("导出文件缺少 " + manifestName)
+	}
+	var m manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return 0, fmt.Errorf("解析 %s 失败: %w", manifestName, err)
+	}
+	if m.Version != archiveVersion {
+		return 0, fmt.Errorf("不支持的导出文件版本: %d", m.Version)
+	}
+	keys := make(map[string][]string)
+	for _, e := range m.Entries {
+		if !validKey.MatchString(e.Key) || !validHash.MatchString(e.Hash) {
+			return 0, fmt.Errorf("无效的缓存条目: %s", e.Key)
+		}
+		keys[e.Hash] = append(keys[e.Hash], e.Key)
+	}
+
+	imported := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, err
+		}
+		dir, name := path.Split(header.Name)
+		hash := strings.TrimSuffix(name, legacyExt)
+		if dir != blobDir+"/" || len(keys[hash]) == 0 {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return imported, err
+		}
+		if contentHash(data) != hash {
+			return imported, fmt.Errorf("音频 %s 的内容与哈希不符", hash)
+		}
+		for _, key := range keys[hash] {
+			c.Set(key, data)
+			imported++
+		}
+		delete(keys, hash)
+	}
+	if len(keys) > 0 {
+		return imported, fmt.Errorf("导出文件缺少 %d 个音频", len(keys))
+	}
+	return imported, nil
+}
+
+// recorderKey 是 Recorder 在 context 中的键
+type recorderKey struct{}
+
+// Recorder 记录一次操作读写的缓存条目，用于导出指定内容的缓存
+type Recorder struct {
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+// WithRecorder 返回带有 Recorder 的 context，之后经过合成结果缓存的条目都记录在其中
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	r := &Recorder{entries: make(map[string][]byte)}
+	return context.WithValue(ctx, recorderKey{}, r), r
+}
+
+// Record 把缓存条目记录到 ctx 中的 Recorder，没有 Recorder 时什么也不做
+func Record(ctx context.Context, key string, data []byte) {
+	if r, ok := ctx.Value(recorderKey{}).(*Recorder); ok {
+		r.mu.Lock()
+		r.entries[key] = data
+		r.mu.Unlock()
+	}
+}
+
+// Entries 返回记录的条目，按缓存键排序
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := make([]Entry, 0, len(r.entries))
+	for key, data := range r.entries {
+		entries = append(entries, Entry{Key: key, Data: data})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
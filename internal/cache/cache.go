@@ -0,0 +1,291 @@
+// Package cache 提供合成音频的缓存：内存中按 LRU 淘汰，可选持久化到磁盘。
+// 音频按内容寻址保存：不同缓存键得到字节相同的音频时（如规范化后文本相同），内存与磁盘中只保存一份，
+// 按引用计数在不再被任何键引用时删除。
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"tts/internal/metrics"
+	"tts/internal/privacy"
+)
+
+// defaultMaxEntries 未配置时内存中最多缓存的条目数
+const defaultMaxEntries = 256
+
+const (
+	// blobDir 是磁盘上按内容寻址保存音频的子目录
+	blobDir = "blobs"
+	// refExt 是缓存键引用文件的扩展名，文件内容为音频的哈希
+	refExt = ".ref"
+	// legacyExt 是按缓存键直接保存音频的旧格式，读取时迁移到新格式
+	legacyExt = ".mp3"
+)
+
+var dedupTotal = metrics.NewCounter("tts_cache_dedup_total",
+	"写入缓存时音频与已有条目字节相同、只增加引用的次数", "tier")
+
+// Cache 是线程安全的音频缓存
+type Cache struct {
+	mu         sync.Mutex
+	dir        string
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	blobs      map[string]*blob // 内存中的音频，按哈希共享
+
+	// 磁盘上各缓存键引用的音频哈希与各音频的引用数
+	diskMu   sync.Mutex
+	diskRefs map[string]string
+	diskUses map[string]int
+}
+
+// entry 是 LRU 链表中的一个条目
+type entry struct {
+	key  string
+	hash string
+}
+
+// blob 是内存中被一个或多个条目引用的音频
+type blob struct {
+	data []byte
+	refs int
+}
+
+// New 创建缓存，dir 为空时只使用内存
+func New(dir string, maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	c := &Cache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		blobs:      make(map[string]*blob),
+		diskRefs:   make(map[string]string),
+		diskUses:   make(map[string]int),
+	}
+	if dir != "" {
+		if err := os.MkdirAll(filepath.Join(dir, blobDir), 0755); err != nil {
+			log.Printf("创建缓存目录失败，仅使用内存缓存: %v", err)
+			return c
+		}
+		c.dir = dir
+		c.loadRefs()
+	}
+	return c
+}
+
+var (
+	openMu sync.Mutex
+	opened = map[string]*Cache{}
+)
+
+// Open 返回目录 dir 对应的缓存，同一目录只创建一个实例，保证磁盘上的引用计数一致；
+// dir 为空时每次返回新的内存缓存
+func Open(dir string, maxEntries int) *Cache {
+	if dir == "" {
+		return New(dir, maxEntries)
+	}
+	openMu.Lock()
+	defer openMu.Unlock()
+	if c, ok := opened[dir]; ok {
+		return c
+	}
+	c := New(dir, maxEntries)
+	opened[dir] = c
+	return c
+}
+
+// Key 根据若干组成部分生成缓存键，隐私模式下使用加盐哈希，无法由文本反推
+func Key(parts ...string) string {
+	data := []byte(strings.Join(parts, "\x00"))
+	if privacy.Salt() != nil {
+		return hex.EncodeToString(privacy.Hash(data)[:16])
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// Get 读取缓存，内存未命中时尝试从磁盘加载
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		data := c.blobs[el.Value.(*entry).hash].data
+		c.mu.Unlock()
+		return data, true
+	}
+	c.mu.Unlock()
+
+	if c.dir == "" {
+		return nil, false
+	}
+	c.diskMu.Lock()
+	hash, ok := c.diskRefs[key]
+	c.diskMu.Unlock()
+	if ok {
+		data, err := os.ReadFile(c.blobPath(hash))
+		if err != nil {
+			return nil, false
+		}
+		c.add(key, hash, data)
+		return data, true
+	}
+
+	// 旧格式的缓存文件，读取后迁移为引用与按内容寻址的音频
+	legacy := filepath.Join(c.dir, key+legacyExt)
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return nil, false
+	}
+	c.Set(key, data)
+	os.Remove(legacy)
+	return data, true
+}
+
+// Set 写入缓存，配置了磁盘目录时同时持久化；与已有条目字节相同的音频只保存一份
+func (c *Cache) Set(key string, data []byte) {
+	hash := contentHash(data)
+	c.add(key, hash, data)
+	if c.dir == "" {
+		return
+	}
+
+	c.diskMu.Lock()
+	defer c.diskMu.Unlock()
+	old, existed := c.diskRefs[key]
+	if existed && old == hash {
+		return
+	}
+	if c.diskUses[hash] > 0 {
+		dedupTotal.Inc("disk")
+	} else if err := writeFile(c.blobPath(hash), data); err != nil {
+		log.Printf("写入缓存文件失败: %v", err)
+		return
+	}
+	if err := writeFile(c.refPath(key), []byte(hash)); err != nil {
+		log.Printf("写入缓存文件失败: %v", err)
+		if c.diskUses[hash] == 0 {
+			os.Remove(c.blobPath(hash))
+		}
+		return
+	}
+	c.diskRefs[key] = hash
+	c.diskUses[hash]++
+	if existed {
+		c.releaseDiskLocked(old)
+	}
+}
+
+// Len 返回内存中的条目数
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// add 将条目加入内存并按 LRU 淘汰，相同内容的条目共享同一份音频
+func (c *Cache) add(key, hash string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		c.ll.MoveToFront(el)
+		if e.hash == hash {
+			return
+		}
+		c.releaseLocked(e.hash)
+		e.hash = hash
+	} else {
+		c.items[key] = c.ll.PushFront(&entry{key: key, hash: hash})
+	}
+	if b, ok := c.blobs[hash]; ok {
+		b.refs++
+		dedupTotal.Inc("memory")
+	} else {
+		c.blobs[hash] = &blob{data: data, refs: 1}
+	}
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		e := oldest.Value.(*entry)
+		delete(c.items, e.key)
+		c.releaseLocked(e.hash)
+	}
+}
+
+// releaseLocked 减少内存中音频的引用，不再被引用时释放
+func (c *Cache) releaseLocked(hash string) {
+	b := c.blobs[hash]
+	if b.refs--; b.refs <= 0 {
+		delete(c.blobs, hash)
+	}
+}
+
+// releaseDiskLocked 减少磁盘上音频的引用，不再被引用时删除文件
+func (c *Cache) releaseDiskLocked(hash string) {
+	if c.diskUses[hash]--; c.diskUses[hash] > 0 {
+		return
+	}
+	delete(c.diskUses, hash)
+	if err := os.Remove(c.blobPath(hash)); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除缓存文件失败: %v", err)
+	}
+}
+
+// loadRefs 读取磁盘上的引用文件统计各音频的引用数，并删除没有被引用的音频（写入中途退出时遗留）
+func (c *Cache) loadRefs() {
+	refs, _ := filepath.Glob(filepath.Join(c.dir, "*"+refExt))
+	for _, path := range refs {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		hash := strings.TrimSpace(string(content))
+		if _, err := os.Stat(c.blobPath(hash)); err != nil {
+			os.Remove(path)
+			continue
+		}
+		c.diskRefs[strings.TrimSuffix(filepath.Base(path), refExt)] = hash
+		c.diskUses[hash]++
+	}
+	blobs, _ := filepath.Glob(filepath.Join(c.dir, blobDir, "*"+legacyExt))
+	for _, path := range blobs {
+		if c.diskUses[strings.TrimSuffix(filepath.Base(path), legacyExt)] == 0 {
+			os.Remove(path)
+		}
+	}
+}
+
+// refPath 返回缓存键的引用文件路径
+func (c *Cache) refPath(key string) string {
+	return filepath.Join(c.dir, key+refExt)
+}
+
+// blobPath 返回音频的磁盘文件路径
+func (c *Cache) blobPath(hash string) string {
+	return filepath.Join(c.dir, blobDir, hash+legacyExt)
+}
+
+// contentHash 返回音频内容的哈希
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFile 先写临时文件再重命名，避免并发读取到不完整的文件
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,82 @@
+// Package cachecmd 实现 tts cache export|import：在不启动服务的情况下导出或导入 cache.dir 中的音频缓存，
+// 用于给离线设备预先装入常用的播报内容
+package cachecmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"tts/internal/cache"
+	"tts/internal/config"
+)
+
+// Run 执行缓存的导出或导入，args 为 cache 之后的命令行参数
+func Run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: tts cache export [-config path] [-o file] | tts cache import [-config path] [file]")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("cache "+action, flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", "", "配置文件路径，默认按服务启动时的顺序查找")
+	env := fs.String("env", "", "配置环境名称，默认使用环境变量 TTS_ENV")
+	output := fs.String("o", "tts-cache.tar.gz", "导出文件路径，为 - 时写到标准输出（只用于 export）；import 从参数指定的文件或标准输入读取")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	config.SetEnvironment(*env)
+	if *configPath == "" {
+		*configPath = config.FindFile()
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if cfg.Cache.Dir == "" {
+		return fmt.Errorf("未配置 cache.dir")
+	}
+	c := cache.Open(cfg.Cache.Dir, cfg.Cache.MaxEntries)
+
+	switch action {
+	case "export":
+		if *output == "-" {
+			_, err := c.Export(os.Stdout)
+			return err
+		}
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		count, err := c.Export(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(*output)
+			return err
+		}
+		fmt.Fprintf(out, "已导出 %d 个缓存条目到 %s\n", count, *output)
+	case "import":
+		r := io.Reader(os.Stdin)
+		if fs.NArg() > 0 && fs.Arg(0) != "-" {
+			f, err := os.Open(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		count, err := c.Import(r)
+		if err != nil {
+			return fmt.Errorf("已导入 %d 个缓存条目后失败: %w", count, err)
+		}
+		fmt.Fprintf(out, "已导入 %d 个缓存条目到 %s\n", count, cfg.Cache.Dir)
+	default:
+		return fmt.Errorf("未知的操作: %s，应为 export 或 import", action)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+// Package clipboard 实现 tts clipboard：监视系统剪贴板，复制新的文本后经本地合成流程朗读，
+// 用本机的播放器播放或保存为文件，作为个人的朗读工具使用
+package clipboard
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"tts/internal/config"
+	"tts/internal/feature"
+	"tts/internal/http/routes"
+	"tts/internal/privacy"
+	ttspkg "tts/pkg/tts"
+)
+
+// players 是未指定 -player 时按顺序查找的播放器，均从标准输入读取音频、边接收边播放
+var players = [][]string{
+	{"ffplay", "-nodisp", "-autoexit", "-loglevel", "error", "-i", "-"},
+	{"mpv", "--no-video", "--really-quiet", "-"},
+}
+
+// watcher 监视剪贴板并朗读新的文本
+type watcher struct {
+	synthesizer *ttspkg.Synthesizer
+	reader      []string // 读取剪贴板的命令
+	player      []string // 播放命令，为空时不播放
+	outDir      string   // 保存音频的目录，为空时不保存
+	interval    time.Duration
+	maxLength   int // 超过该字符数的文本不朗读，<= 0 时不限制
+	voice       string
+	rate        string
+	out         io.Writer
+}
+
+// Run 运行剪贴板朗读，args 为 clipboard 之后的命令行参数，收到中断信号时退出
+func Run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("clipboard", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", "", "配置文件路径，默认按服务启动时的顺序查找")
+	env := fs.String("env", "", "配置环境名称，默认使用环境变量 TTS_ENV")
+	voice := fs.String("voice", "", "朗读使用的语音，默认使用 tts.default_voice")
+	rate := fs.String("rate", "", "语速，默认使用 tts.default_rate")
+	interval := fs.Duration("interval", 500*time.Millisecond, "检查剪贴板的间隔")
+	player := fs.String("player", "", "播放命令，从标准输入读取音频，默认查找 ffplay 或 mpv；为 none 时不播放")
+	outDir := fs.String("out", "", "同时把每次朗读的音频保存到该目录")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval 应大于 0")
+	}
+
+	config.SetEnvironment(*env)
+	if *configPath == "" {
+		*configPath = config.FindFile()
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	privacy.Configure(cfg)
+	feature.Configure(cfg)
+	service, err := routes.InitializeServices(cfg)
+	if err != nil {
+		return fmt.Errorf("初始化服务失败: %w", err)
+	}
+
+	w := &watcher{
+		synthesizer: ttspkg.NewSynthesizer(service, ttspkg.NewSegmenter(&cfg.TTS), cfg.TTS.MaxConcurrent),
+		outDir:      *outDir,
+		interval:    *interval,
+		maxLength:   cfg.TTS.MaxTextLength,
+		voice:       *voice,
+		rate:        *rate,
+		out:         out,
+	}
+	if w.voice == "" {
+		w.voice = cfg.TTS.DefaultVoice
+	}
+	if w.rate == "" {
+		w.rate = cfg.TTS.DefaultRate
+	}
+	if w.reader, err = findReader(); err != nil {
+		return err
+	}
+	if w.player, err = findPlayer(*player); err != nil {
+		return err
+	}
+	if w.player == nil && w.outDir == "" {
+		return fmt.Errorf("-player 为 none 时需要用 -out 指定保存音频的目录")
+	}
+	if w.outDir != "" {
+		if err := os.MkdirAll(w.outDir, 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(out, "正在监视剪贴板（%s），复制文本即可朗读，按 Ctrl+C 退出\n", w.reader[0])
+	w.run(ctx)
+	return nil
+}
+
+// findPlayer 返回播放命令：name 为 none 时不播放，为空时查找已安装的播放器，否则按空格拆分为命令与参数
+func findPlayer(name string) ([]string, error) {
+	switch name {
+	case "none":
+		return nil, nil
+	case "":
+		for _, command := range players {
+			if _, err := exec.LookPath(command[0]); err == nil {
+				return command, nil
+			}
+		}
+		return nil, fmt.Errorf("未找到播放器，请安装 ffplay（ffmpeg）或 mpv，或用 -player 指定播放命令")
+	}
+	command := strings.Fields(name)
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return nil, fmt.Errorf("播放器不可用: %w", err)
+	}
+	return command, nil
+}
+
+// run 定时读取剪贴板，内容变化时停止正在进行的朗读并朗读新的文本；启动时剪贴板中已有的内容不朗读
+func (w *watcher) run(ctx context.Context) {
+	last := read(w.reader)
+	var current *speaking
+	defer func() { current.stop() }()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		text := read(w.reader)
+		if text == "" || text == last {
+			continue
+		}
+		last = text
+		if n := utf8.RuneCountInString(text); w.maxLength > 0 && n > w.maxLength {
+			fmt.Fprintf(w.out, "文本共 %d 字，超过 tts.max_text_length（%d），不朗读\n", n, w.maxLength)
+			continue
+		}
+		current.stop()
+		current = w.start(ctx, text)
+	}
+}
+
+// speaking 是正在进行的一次朗读
+type speaking struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// start 在后台朗读 text
+func (w *watcher) start(ctx context.Context, text string) *speaking {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &speaking{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		if err := w.speak(ctx, text); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(w.out, "朗读失败: %v\n", err)
+		}
+	}()
+	return s
+}
+
+// stop 停止朗读并等待播放器退出
+func (s *speaking) stop() {
+	if s == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// speak 流式合成 text，音频写入播放器的标准输入，指定了 -out 时同时写入文件；ctx 取消时停止合成与播放
+func (w *watcher) speak(ctx context.Context, text string) error {
+	var sinks []io.Writer
+	var player *exec.Cmd
+	var stdin io.WriteCloser
+	if w.player != nil {
+		player = exec.CommandContext(ctx, w.player[0], w.player[1:]...)
+		var err error
+		if stdin, err = player.StdinPipe(); err != nil {
+			return err
+		}
+		if err := player.Start(); err != nil {
+			return fmt.Errorf("启动播放器失败: %w", err)
+		}
+		sinks = append(sinks, stdin)
+	}
+	var file *os.File
+	if w.outDir != "" {
+		path := filepath.Join(w.outDir, "clip-"+time.Now().Format("20060102-150405.000")+".mp3")
+		var err error
+		if file, err = os.Create(path); err != nil {
+			if player != nil {
+				stdin.Close()
+				player.Wait()
+			}
+			return fmt.Errorf("创建文件失败: %w", err)
+		}
+		defer file.Close()
+		sinks = append(sinks, file)
+	}
+
+	// 日志中只记录字数，不记录复制的内容
+	log.Printf("朗读剪贴板文本: %d 字", utf8.RuneCountInString(text))
+	req := ttspkg.Request{Text: text, Voice: w.voice, Rate: w.rate}
+	err := w.synthesizer.Stream(ctx, req, io.MultiWriter(sinks...), 0)
+	if player != nil {
+		// 关闭标准输入后播放器播放完已接收的音频再退出
+		stdin.Close()
+		if waitErr := player.Wait(); err == nil && waitErr != nil && ctx.Err() == nil {
+			err = fmt.Errorf("播放器异常退出: %w", waitErr)
+		}
+	}
+	if file != nil {
+		if err != nil {
+			// 被打断或失败时不保留不完整的文件
+			file.Close()
+			os.Remove(file.Name())
+		} else {
+			fmt.Fprintf(w.out, "已保存 %s\n", file.Name())
+		}
+	}
+	return err
+}
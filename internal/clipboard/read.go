@@ -0,0 +1,49 @@
+package clipboard
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// readers 是各系统读取剪贴板文本的命令，按顺序使用第一个已安装的命令
+var readers = map[string][][]string{
+	"linux": {
+		{"wl-paste", "--no-newline", "--type", "text"}, // Wayland
+		{"xclip", "-selection", "clipboard", "-out"},
+		{"xsel", "--clipboard", "--output"},
+	},
+	"darwin":  {{"pbpaste"}},
+	"windows": {{"powershell", "-NoProfile", "-NonInteractive", "-Command", "Get-Clipboard -Raw"}},
+}
+
+// findReader 返回当前系统可用的读取剪贴板命令
+func findReader() ([]string, error) {
+	candidates := readers[runtime.GOOS]
+	if runtime.GOOS == "linux" && os.Getenv("WAYLAND_DISPLAY") == "" {
+		// X11 会话中 wl-paste 无法读取剪贴板
+		candidates = candidates[1:]
+	}
+	var names []string
+	for _, command := range candidates {
+		if _, err := exec.LookPath(command[0]); err == nil {
+			return command, nil
+		}
+		names = append(names, command[0])
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("不支持读取 %s 的剪贴板", runtime.GOOS)
+	}
+	return nil, fmt.Errorf("未找到读取剪贴板的命令，请安装 %s 之一", strings.Join(names, "、"))
+}
+
+// read 运行命令读取剪贴板中的文本；剪贴板为空或不是文本时部分命令会失败，按空文本处理
+func read(command []string) string {
+	output, err := exec.Command(command[0], command[1:]...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(output), "\r\n", "\n"))
+}
@@ -1,21 +1,483 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"html"
+	"log"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+
+	"tts/internal/metrics"
+	"tts/internal/utils"
 )
 
 // Config 包含应用程序的所有配置
 type Config struct {
-	Server ServerConfig `mapstructure:"server"`
-	TTS    TTSConfig    `mapstructure:"tts"`
-	OpenAI OpenAIConfig `mapstructure:"openai"`
-	SSML   SSMLConfig   `mapstructure:"ssml"`
+	// Version 是配置文件的格式版本，旧版本的配置在加载时自动迁移，未设置时按版本 1 处理
+	Version    int                     `mapstructure:"version"`
+	Server     ServerConfig            `mapstructure:"server"`
+	TTS        TTSConfig               `mapstructure:"tts"`
+	OpenAI     OpenAIConfig            `mapstructure:"openai"`
+	SSML       SSMLConfig              `mapstructure:"ssml"`
+	Middleware MiddlewareConfig        `mapstructure:"middleware"`
+	Shadow     ShadowConfig            `mapstructure:"shadow"`
+	Cache      CacheConfig             `mapstructure:"cache"`
+	Polly      PollyConfig             `mapstructure:"polly"`
+	Google     GoogleConfig            `mapstructure:"google"`
+	Wyoming    WyomingConfig           `mapstructure:"wyoming"`
+	MQTT       MQTTConfig              `mapstructure:"mqtt"`
+	Telegram   TelegramConfig          `mapstructure:"telegram"`
+	Discord    DiscordConfig           `mapstructure:"discord"`
+	Slack      SlackConfig             `mapstructure:"slack"`
+	Matrix     MatrixConfig            `mapstructure:"matrix"`
+	Store      StoreConfig             `mapstructure:"store"`
+	Storage    StorageConfig           `mapstructure:"storage"`
+	Podcast    PodcastConfig           `mapstructure:"podcast"`
+	Schedule   ScheduleConfig          `mapstructure:"schedule"`
+	Admin      AdminConfig             `mapstructure:"admin"`
+	Metrics    MetricsConfig           `mapstructure:"metrics"`
+	Templates  map[string]TextTemplate `mapstructure:"templates"`
+	Keys       []APIKey                `mapstructure:"keys"`
+	Watermark  WatermarkConfig         `mapstructure:"watermark"`
+	Policy     PolicyConfig            `mapstructure:"policy"`
+	Privacy    PrivacyConfig           `mapstructure:"privacy"`
+	Redact     RedactConfig            `mapstructure:"redact"`
+	Verbalize  VerbalizeConfig         `mapstructure:"verbalize"`
+	Sessions   SessionsConfig          `mapstructure:"sessions"`
+	ReadAloud  ReadAloudConfig         `mapstructure:"read_aloud"`
+	Overlay    OverlayConfig           `mapstructure:"overlay"`
+	Telephony  TelephonyConfig         `mapstructure:"telephony"`
+	Cloning    CloningConfig           `mapstructure:"cloning"`
+	Eval       EvalConfig              `mapstructure:"eval"`
+	Notify     NotifyConfig            `mapstructure:"notify"`
+	Jobs       JobsConfig              `mapstructure:"jobs"`
+	Logging    LoggingConfig           `mapstructure:"logging"`
+	Sentry     SentryConfig            `mapstructure:"sentry"`
+	Features   map[string]bool         `mapstructure:"features"` // 功能开关，未设置的开关使用默认状态
+	Remote     RemoteConfig            `mapstructure:"remote"`
+}
+
+// RemoteConfig 包含远程配置来源的配置，只在本地配置文件或环境变量中生效。
+// 远程配置为 YAML 文档，合并在本地配置文件之后、环境变量之前
+type RemoteConfig struct {
+	// URL 为 https://host/config.yaml、consul://host:8500/{key} 或 etcd://host:2379/{key}，为空时不使用远程配置
+	URL      string `mapstructure:"url"`
+	Token    string `mapstructure:"token"`    // HTTP 为 Bearer 令牌，Consul 为 ACL 令牌
+	Interval int    `mapstructure:"interval"` // 轮询间隔（秒），内容变化时按 SIGHUP 的方式重新加载，0 表示不轮询
+	Timeout  int    `mapstructure:"timeout"`  // 读取超时（秒），默认 10
+	Cache    string `mapstructure:"cache"`    // 保存最近一次读取结果的文件，启动时远程来源不可用则使用该文件
+}
+
+// SentryConfig 包含错误上报的配置，panic 与上游服务错误上报到 Sentry（或兼容的 GlitchTip 等服务）
+type SentryConfig struct {
+	DSN         string  `mapstructure:"dsn"`         // 项目的 DSN，为空时不上报
+	Environment string  `mapstructure:"environment"` // 环境名称，如 production
+	Release     string  `mapstructure:"release"`     // 版本号
+	SampleRate  float64 `mapstructure:"sample_rate"` // 上游服务错误的上报比例 (0, 1]，默认全部上报；panic 始终上报
+}
+
+// LoggingConfig 包含请求日志的配置。级别依次为 off（不记录）、error（只记录 4xx 与 5xx 响应）、
+// info（每个请求一行，默认）与 debug（另外记录密钥名称、查询参数与请求体）
+type LoggingConfig struct {
+	Level        string            `mapstructure:"level"`          // 默认级别
+	Body         bool              `mapstructure:"body"`           // debug 级别是否记录查询参数与请求体，密钥可以通过 log_body 单独设置
+	MaxBodyBytes int               `mapstructure:"max_body_bytes"` // 请求体最多记录的字节数
+	Endpoints    []EndpointLogging `mapstructure:"endpoints"`      // 按接口设置级别，按顺序使用第一条匹配的规则
+	Access       AccessLogConfig   `mapstructure:"access"`
+}
+
+// AccessLogConfig 包含独立访问日志的配置，格式与 Apache/Nginx 相同，可直接交给 GoAccess、AWStats 等工具分析
+type AccessLogConfig struct {
+	Path     string `mapstructure:"path"`     // 日志文件路径，stdout 表示标准输出，为空时不记录
+	Format   string `mapstructure:"format"`   // common 或 combined（默认），combined 另外记录 Referer 与 User-Agent
+	Duration bool   `mapstructure:"duration"` // 在行末追加请求耗时（秒，毫秒精度），与 Nginx 的 $request_time 相同
+}
+
+// EndpointLogging 为一个接口设置日志级别
+type EndpointLogging struct {
+	Path  string `mapstructure:"path"`  // 路由路径（不含 server.base_path），如 /metrics；以 * 结尾时按前缀匹配，如 /admin/*
+	Level string `mapstructure:"level"` // 日志级别
+}
+
+// JobsConfig 包含异步合成任务（如有声书等长文本）的配置
+type JobsConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	Dir           string `mapstructure:"dir"`             // 保存任务记录、文本与已合成片段（检查点）的目录，音频保存到文件存储的 jobs/ 下
+	Workers       int    `mapstructure:"workers"`         // 同时执行的任务数
+	MaxQueued     int    `mapstructure:"max_queued"`      // 最多排队的任务数，超过时拒绝提交
+	MaxTextLength int    `mapstructure:"max_text_length"` // 单个任务的最大文本长度（字符）
+	KeepHours     int    `mapstructure:"keep_hours"`      // 已结束的任务记录与音频保留的小时数
+	// Shared 为 true 时多个实例共享 dir（如网络文件系统上的同一目录），通过租约保证每个任务只由一个实例执行
+	Shared       bool `mapstructure:"shared"`
+	LeaseSeconds int  `mapstructure:"lease_seconds"` // 租约有效期（秒），执行中的实例超过该时长未续约时任务由其他实例回收
+	PollSeconds  int  `mapstructure:"poll_seconds"`  // 扫描其他实例提交的任务与过期租约的间隔（秒）
+}
+
+// NotifyConfig 包含任务完成与失败通知的配置
+type NotifyConfig struct {
+	Sinks   []NotifySink `mapstructure:"sinks"`
+	Default []string     `mapstructure:"default"` // 任务与密钥没有单独设置 notify 时使用的通知渠道
+}
+
+// NotifySink 是一个通知渠道
+type NotifySink struct {
+	Name string `mapstructure:"name"`
+	Type string `mapstructure:"type"` // slack, email, ntfy 或 webhook
+	// URL 对 slack 为 Incoming Webhook 地址，对 ntfy 为主题地址（如 https://ntfy.sh/my-topic），对 webhook 为接收 JSON 的地址
+	URL   string     `mapstructure:"url"`
+	Token string     `mapstructure:"token"` // ntfy 的访问令牌
+	On    string     `mapstructure:"on"`    // all: 完成与失败都通知（默认）, failure: 只通知失败
+	SMTP  SMTPConfig `mapstructure:"smtp"`  // type 为 email 时使用
+}
+
+// SMTPConfig 是发送邮件通知的 SMTP 服务器配置
+type SMTPConfig struct {
+	Host     string   `mapstructure:"host"`
+	Port     int      `mapstructure:"port"` // 默认 587，服务器支持时使用 STARTTLS
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// SessionsConfig 包含按会话只合成新增文本的配置
+type SessionsConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	TTL         int  `mapstructure:"ttl"`          // 会话空闲多久后过期（分钟）
+	MaxSessions int  `mapstructure:"max_sessions"` // 最多同时保存的会话数，超过时淘汰最久未使用的会话
+	// LiveMaxBuffer 是实时会话中未成句的文本的字符数上限，超过后不等句末直接合成，默认 200
+	LiveMaxBuffer int `mapstructure:"live_max_buffer"`
+}
+
+// ReadAloudConfig 包含浏览器扩展朗读会话的配置
+type ReadAloudConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	TTL         int  `mapstructure:"ttl"`          // 会话空闲多久后过期（分钟）
+	MaxSessions int  `mapstructure:"max_sessions"` // 最多同时保存的会话数，超过时淘汰最久未使用的会话
+	MaxChunks   int  `mapstructure:"max_chunks"`   // 每个会话最多保存的分段数
+}
+
+// TelephonyConfig 包含电话系统语音提示接口的配置
+type TelephonyConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Voice   string `mapstructure:"voice"`   // 默认语音，为空时使用 tts.default_voice
+	MaxAge  int    `mapstructure:"max_age"` // 响应的缓存时间（秒），0 表示每次重新验证
+}
+
+// CloningConfig 包含语音克隆的配置：上传参考音频在支持的服务中创建自定义语音，按密钥保存
+type CloningConfig struct {
+	Enabled     bool `mapstructure:"enabled"`
+	MaxVoices   int  `mapstructure:"max_voices"`    // 每个密钥最多创建的自定义语音数，默认 10
+	MaxSamples  int  `mapstructure:"max_samples"`   // 每次创建最多上传的参考音频数，默认 10
+	MaxSampleMB int  `mapstructure:"max_sample_mb"` // 每段参考音频的大小上限（MB），默认 10
+}
+
+// EvalConfig 包含语音质量评测的配置：用各候选语音合成固定的提示句，由评测人盲听两两比较并打分，
+// 汇总为报告供挑选语音参考
+type EvalConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Token 是评测页面获取比较组合、音频与提交评分使用的令牌，通过 token 查询参数传递；为空时不认证
+	Token      string          `mapstructure:"token"`
+	Prompts    []string        `mapstructure:"prompts"`    // 提示句
+	Candidates []EvalCandidate `mapstructure:"candidates"` // 参与比较的候选语音，至少两个
+}
+
+// EvalCandidate 是一个参与评测的候选语音
+type EvalCandidate struct {
+	Name     string `mapstructure:"name"`     // 报告中的名称，为空时由服务与语音生成
+	Provider string `mapstructure:"provider"` // 为空时使用 tts.provider
+	Voice    string `mapstructure:"voice"`
+	Rate     string `mapstructure:"rate"`
+	Pitch    string `mapstructure:"pitch"`
+	Style    string `mapstructure:"style"`
+}
+
+// OverlayConfig 包含直播播报的配置：观众的聊天消息与打赏经播报队列合成，由 OBS 浏览器源页面依次播放
+type OverlayConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Token 是浏览器源页面连接 WebSocket 与获取音频使用的令牌，OBS 浏览器源无法设置请求头，通过 token 查询参数传递；为空时不认证
+	Token          string            `mapstructure:"token"`
+	Voice          string            `mapstructure:"voice"`           // 默认语音，为空时使用 tts.default_voice
+	Voices         map[string]string `mapstructure:"voices"`          // 按用户名（不区分大小写）指定语音，支持 voice_mapping 中的别名
+	Format         string            `mapstructure:"format"`          // 聊天消息朗读的文本，{user}、{text} 替换为用户名与消息
+	DonationFormat string            `mapstructure:"donation_format"` // 打赏朗读的文本，另外可以使用 {amount}
+	MaxQueue       int               `mapstructure:"max_queue"`       // 最多排队的消息数
+	MaxLength      int               `mapstructure:"max_length"`      // 每条消息最多朗读的字符数，超过时截断
+	UserInterval   int               `mapstructure:"user_interval"`   // 同一用户两条聊天消息的最小间隔（秒），打赏不受限制
+	BlockedWords   []string          `mapstructure:"blocked_words"`   // 屏蔽词，不区分大小写
+	Filter         string            `mapstructure:"filter"`          // 含屏蔽词的消息：mask 把屏蔽词替换为 replacement（默认），drop 丢弃整条消息
+	Replacement    string            `mapstructure:"replacement"`
+}
+
+// VerbalizeConfig 包含按语言包展开数字、日期、单位与缩写的配置
+type VerbalizeConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Dir     string `mapstructure:"dir"` // 额外的语言包目录（YAML 或 JSON），相同区域会覆盖内置语言包
+}
+
+// RedactConfig 包含合成前替换个人信息的配置
+type RedactConfig struct {
+	Enabled      bool              `mapstructure:"enabled"`
+	Types        []string          `mapstructure:"types"`        // 启用的内置类型：phone、email、id_card、bank_card，为空时全部启用
+	Replacements map[string]string `mapstructure:"replacements"` // 按类型替换默认的替换文本
+	Patterns     []RedactPattern   `mapstructure:"patterns"`     // 自定义识别规则
+}
+
+// RedactPattern 是一条自定义的个人信息识别规则
+type RedactPattern struct {
+	Name        string `mapstructure:"name"`
+	Pattern     string `mapstructure:"pattern"`     // 正则表达式
+	Replacement string `mapstructure:"replacement"` // 替换文本，为空时直接删除
+}
+
+// PolicyConfig 包含合成语音使用政策的配置
+type PolicyConfig struct {
+	Headers           map[string]string `mapstructure:"headers"`            // 添加到所有响应的响应头
+	RequireAcceptance bool              `mapstructure:"require_acceptance"` // 是否要求先确认使用条款
+	TermsVersion      string            `mapstructure:"terms_version"`      // 条款版本，更新后需要重新确认
+	TermsURL          string            `mapstructure:"terms_url"`          // 条款全文地址
+}
+
+// APIKey 是一个带有独立设置的 API 密钥，可用于所有使用 api_key 或 Bearer 令牌认证的接口
+type APIKey struct {
+	Name      string `mapstructure:"name"` // 用于日志与水印的名称，不会泄露密钥本身
+	Key       string `mapstructure:"key"`
+	Watermark *bool  `mapstructure:"watermark"` // 是否添加水印，未设置时使用 watermark.enabled
+	Privacy   *bool  `mapstructure:"privacy"`   // 是否使用隐私模式，未设置时使用 privacy.enabled
+	// SSML 为该密钥单独设置允许的标签与网址朗读方式，未设置的项使用全局的 ssml 配置
+	SSML *KeySSMLConfig `mapstructure:"ssml"`
+	// Notify 是该密钥提交的后台任务（缓存预热与异步合成）完成时使用的通知渠道名称，为空时使用 notify.default
+	Notify []string `mapstructure:"notify"`
+	// LogLevel 为该密钥的请求设置日志级别，优先于 logging.endpoints，便于单独排查一个调用方
+	LogLevel string `mapstructure:"log_level"`
+	// LogBody 设置 debug 级别是否记录该密钥请求的查询参数与请求体，未设置时使用 logging.body
+	LogBody *bool `mapstructure:"log_body"`
+	// Features 为该密钥单独设置功能开关，优先于全局的 features，用于向部分调用方逐步开放实验性功能
+	Features map[string]bool `mapstructure:"features"`
+}
+
+// KeySSMLConfig 是为单个密钥覆盖的 SSML 设置
+type KeySSMLConfig struct {
+	// PreserveTags 设置后替换全局的 preserve_tags，例如只允许不受信任的调用方使用 <break>
+	PreserveTags []TagPattern `mapstructure:"preserve_tags"`
+	// URLMode 覆盖 ssml.urls.mode
+	URLMode string `mapstructure:"url_mode"`
+}
+
+// PrivacyConfig 包含隐私模式的配置
+type PrivacyConfig struct {
+	Enabled bool   `mapstructure:"enabled"` // 全局开启：输入文本不写入日志与录制夹具
+	Salt    string `mapstructure:"salt"`    // 缓存键使用的盐，为空时每次启动随机生成
+}
+
+// WatermarkConfig 包含生成音频水印的配置
+type WatermarkConfig struct {
+	Enabled       bool    `mapstructure:"enabled"`
+	Mode          string  `mapstructure:"mode"`           // id3: 写入 ID3 标签, tone: 在末尾追加提示音, both: 两者都使用
+	ToneFrequency int     `mapstructure:"tone_frequency"` // 提示音频率 (Hz)
+	ToneDuration  float64 `mapstructure:"tone_duration"`  // 提示音时长（秒）
+}
+
+// TextTemplate 是一个命名文本模板，合成前用请求提供的变量渲染
+type TextTemplate struct {
+	Text   string `mapstructure:"text"`   // Go text/template 模板，如 "{{.number}} 号顾客请取餐"
+	Voice  string `mapstructure:"voice"`  // 默认语音
+	Locale string `mapstructure:"locale"` // 未配置语音时从该区域的语音中选择
+	Rate   string `mapstructure:"rate"`
+	Pitch  string `mapstructure:"pitch"`
+	Style  string `mapstructure:"style"`
+}
+
+// AdminConfig 包含管理接口的配置
+type AdminConfig struct {
+	Token string `mapstructure:"token"` // 管理接口的 Bearer 令牌，为空时不开放管理接口
+}
+
+// MetricsConfig 包含 /metrics 指标导出的配置
+type MetricsConfig struct {
+	Public bool `mapstructure:"public"` // 不需要认证即可访问 /metrics，默认需要 admin.token
+}
+
+// ScheduleConfig 包含定时任务的配置
+type ScheduleConfig struct {
+	Timezone    string         `mapstructure:"timezone"`     // cron 表达式使用的时区，为空时使用本地时区
+	HistorySize int            `mapstructure:"history_size"` // 保留的执行记录数
+	Jobs        []ScheduledJob `mapstructure:"jobs"`
+}
+
+// ScheduledJob 是一个定时合成任务
+type ScheduledJob struct {
+	Name  string `mapstructure:"name"`
+	Cron  string `mapstructure:"cron"` // 五段式 cron 表达式，如 "0 7 * * *"
+	URL   string `mapstructure:"url"`  // 执行时抓取的地址，内容作为模板中的 .Data
+	Text  string `mapstructure:"text"` // Go text/template 文本模板，为空时直接朗读 URL 内容
+	Voice string `mapstructure:"voice"`
+	Rate  string `mapstructure:"rate"`
+	// Notify 是执行完成或失败时使用的通知渠道名称，为空时使用 notify.default
+	Notify []string `mapstructure:"notify"`
+}
+
+// StorageConfig 包含生成音频文件存储的配置
+type StorageConfig struct {
+	Dir     string `mapstructure:"dir"`      // 保存音频文件的目录
+	BaseURL string `mapstructure:"base_url"` // 生成下载地址使用的服务地址，为空时使用当前请求的地址
+	ChunkMB int    `mapstructure:"chunk_mb"` // 超过该大小（MB）的文件分块保存并生成清单，0 表示不分块
+	// ContentURLs 为 true 时开放 /cdn/{sha256} 按内容哈希访问已保存的文件，不需要认证，响应可以永久缓存
+	ContentURLs bool `mapstructure:"content_urls"`
+}
+
+// PodcastConfig 包含 RSS 转播客的配置
+type PodcastConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Title        string        `mapstructure:"title"`
+	Description  string        `mapstructure:"description"`
+	PollInterval int           `mapstructure:"poll_interval"` // 检查订阅源的间隔（分钟）
+	MaxEpisodes  int           `mapstructure:"max_episodes"`  // 每个订阅源保留的节目数
+	Feeds        []PodcastFeed `mapstructure:"feeds"`
+}
+
+// PodcastFeed 是一个要朗读的 RSS/Atom 订阅源
+type PodcastFeed struct {
+	Name     string `mapstructure:"name"`
+	URL      string `mapstructure:"url"`
+	Voice    string `mapstructure:"voice"`
+	Rate     string `mapstructure:"rate"`
+	MaxItems int    `mapstructure:"max_items"` // 每次最多转换的新文章数
+}
+
+// StoreConfig 包含持久化存储的配置
+type StoreConfig struct {
+	Path string `mapstructure:"path"` // 存储文件路径，为空时只保存在内存中
+}
+
+// TelegramConfig 包含 Telegram 机器人的配置
+type TelegramConfig struct {
+	Enabled      bool    `mapstructure:"enabled"`
+	Token        string  `mapstructure:"token"`         // BotFather 提供的机器人令牌
+	APIURL       string  `mapstructure:"api_url"`       // Bot API 地址，默认 https://api.telegram.org
+	AllowedChats []int64 `mapstructure:"allowed_chats"` // 允许使用的会话ID，为空时不限制
+}
+
+// DiscordConfig 包含 Discord 机器人的配置
+type DiscordConfig struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	Token         string   `mapstructure:"token"`          // Developer Portal 中机器人的令牌
+	Prefix        string   `mapstructure:"prefix"`         // 命令前缀，默认 !tts
+	AllowedGuilds []string `mapstructure:"allowed_guilds"` // 允许使用的服务器ID，为空时不限制
+	ReadNames     bool     `mapstructure:"read_names"`     // 朗读消息前先读出发送者的名字
+	APIURL        string   `mapstructure:"api_url"`        // REST API 地址，默认 https://discord.com/api/v10
+	GatewayURL    string   `mapstructure:"gateway_url"`    // 网关地址，默认 wss://gateway.discord.gg
+}
+
+// ChatVoice 是聊天工具中一个工作区或房间使用的语音
+type ChatVoice struct {
+	Voice    string `mapstructure:"voice"`    // 语音，可以使用 voice_mapping 中的别名
+	Language string `mapstructure:"language"` // 未设置语音时使用该语言（如 en-US）的第一个语音
+}
+
+// SlackConfig 包含 Slack 斜杠命令的配置
+type SlackConfig struct {
+	Enabled       bool                 `mapstructure:"enabled"`
+	SigningSecret string               `mapstructure:"signing_secret"` // 应用的 Signing Secret，用于验证请求来自 Slack
+	BotToken      string               `mapstructure:"bot_token"`      // 配置后把音频上传到频道，否则回复音频链接
+	APIURL        string               `mapstructure:"api_url"`        // Web API 地址，默认 https://slack.com/api
+	Voice         string               `mapstructure:"voice"`          // 未在 workspaces 中配置的工作区使用的语音
+	Language      string               `mapstructure:"language"`
+	Workspaces    map[string]ChatVoice `mapstructure:"workspaces"` // 按工作区ID（team_id）设置语音
+}
+
+// MatrixConfig 包含 Matrix 机器人的配置
+type MatrixConfig struct {
+	Enabled     bool         `mapstructure:"enabled"`
+	Homeserver  string       `mapstructure:"homeserver"`   // 如 https://matrix.example.org
+	AccessToken string       `mapstructure:"access_token"` // 机器人账号的访问令牌
+	Prefix      string       `mapstructure:"prefix"`       // 命令前缀，默认 !tts
+	AutoJoin    bool         `mapstructure:"auto_join"`    // 自动接受房间邀请，配置了 rooms 时只接受其中的房间
+	Voice       string       `mapstructure:"voice"`        // 未在 rooms 中配置的房间使用的语音
+	Language    string       `mapstructure:"language"`
+	Rooms       []MatrixRoom `mapstructure:"rooms"` // 按房间设置语音，为空时不限制房间
+}
+
+// MatrixRoom 是一个房间的语音设置。房间ID中含有 "."，不能作为配置中的键，因此使用列表
+type MatrixRoom struct {
+	ID        string `mapstructure:"id"` // 如 !abc:example.org
+	ChatVoice `mapstructure:",squash"`
+}
+
+// MQTTConfig 包含 MQTT 语音播报的配置
+type MQTTConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	Broker        string `mapstructure:"broker"` // 如 tcp://localhost:1883
+	ClientID      string `mapstructure:"client_id"`
+	Username      string `mapstructure:"username"`
+	Password      string `mapstructure:"password"`
+	QoS           int    `mapstructure:"qos"`
+	Topic         string `mapstructure:"topic"`          // 订阅的主题，消息为纯文本或 JSON
+	ResponseTopic string `mapstructure:"response_topic"` // 发布结果的主题，为空时不发布
+	ResponseMode  string `mapstructure:"response_mode"`  // json: 发布结果与URL, audio: 直接发布音频
+	OutputDir     string `mapstructure:"output_dir"`     // 保存音频的目录，为空时不保存
+	BaseURL       string `mapstructure:"base_url"`       // 生成音频URL使用的服务地址
+}
+
+// WyomingConfig 包含 Wyoming 协议服务（Home Assistant 语音后端）的配置
+type WyomingConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Address    string `mapstructure:"address"`     // 监听地址，如 ":10200"
+	SampleRate int    `mapstructure:"sample_rate"` // 输出 PCM 的采样率
+}
+
+// GoogleConfig 包含 Google Cloud TTS 兼容接口的配置
+type GoogleConfig struct {
+	APIKey       string            `mapstructure:"api_key"`       // API 密钥，为空时不验证
+	VoiceMapping map[string]string `mapstructure:"voice_mapping"` // Google 语音名称 → 实际语音
+}
+
+// PollyConfig 包含 Amazon Polly 兼容接口的配置
+type PollyConfig struct {
+	AccessKeyID     string            `mapstructure:"access_key_id"`     // SigV4 访问密钥ID，为空时不验证签名
+	SecretAccessKey string            `mapstructure:"secret_access_key"` // SigV4 私有访问密钥
+	VoiceMapping    map[string]string `mapstructure:"voice_mapping"`     // Polly VoiceId → 实际语音，未配置的按 tts.voice_mapping 解析
+	Engines         map[string]string `mapstructure:"engines"`           // Polly Engine → TTS 服务名称，未配置的使用 tts.provider
+}
+
+// CacheConfig 包含合成音频缓存的配置
+type CacheConfig struct {
+	MaxEntries int    `mapstructure:"max_entries"` // 内存中最多缓存的音频数
+	Dir        string `mapstructure:"dir"`         // 磁盘缓存目录，为空时只使用内存
+	Synthesis  bool   `mapstructure:"synthesis"`   // 同时缓存合成结果（按片段），预热接口需要启用
+	// NegativeTTL 是 SSML 无效、语音不存在等确定性失败被记住的秒数，期间相同请求直接返回同一错误，0 表示不记录
+	NegativeTTL int `mapstructure:"negative_ttl"`
+}
+
+// ShadowConfig 包含双服务对比（影子请求）的调试配置
+type ShadowConfig struct {
+	Enabled      bool              `mapstructure:"enabled"`
+	Provider     string            `mapstructure:"provider"`      // 影子服务名称
+	SampleRate   float64           `mapstructure:"sample_rate"`   // 触发影子请求的比例 (0-1)
+	OutputDir    string            `mapstructure:"output_dir"`    // 对比结果保存目录
+	Timeout      int               `mapstructure:"timeout"`       // 影子请求超时（秒）
+	MaxInFlight  int               `mapstructure:"max_in_flight"` // 同时进行的影子请求上限，已满时丢弃新的影子请求
+	VoiceMapping map[string]string `mapstructure:"voice_mapping"` // 主服务语音到影子服务语音的映射
+}
+
+// MiddlewareConfig 控制中间件链中各中间件的启用状态
+type MiddlewareConfig struct {
+	// Enabled 按名称启用或禁用中间件，未配置的中间件使用其默认状态
+	Enabled   map[string]bool `mapstructure:"enabled"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+}
+
+// RateLimitConfig 包含按客户端限流的配置
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"` // 每秒补充的令牌数
+	Burst             int     `mapstructure:"burst"`               // 令牌桶容量
 }
 
 // OpenAIConfig 包含OpenAI API配置
@@ -29,23 +491,146 @@ type ServerConfig struct {
 	ReadTimeout  int    `mapstructure:"read_timeout"`
 	WriteTimeout int    `mapstructure:"write_timeout"`
 	BasePath     string `mapstructure:"base_path"`
+	// Listen 为空时监听 TCP 端口 port；unix:/run/tts/tts.sock 监听 unix 域套接字；systemd 使用套接字激活传入的套接字
+	Listen string `mapstructure:"listen"`
+	// SocketMode 监听 unix 域套接字时设置的文件权限（八进制），如 "0660"，为空时由 umask 决定
+	SocketMode string `mapstructure:"socket_mode"`
+	// MaxBodyMB 请求体（解压后）的大小上限（MB），超过时返回 413，默认 64
+	MaxBodyMB int `mapstructure:"max_body_mb"`
 }
 
 // TTSConfig 包含Microsoft TTS API配置
 type TTSConfig struct {
-	ApiKey            string            `mapstructure:"api_key"`
-	Region            string            `mapstructure:"region"`
-	DefaultVoice      string            `mapstructure:"default_voice"`
-	DefaultRate       string            `mapstructure:"default_rate"`
-	DefaultPitch      string            `mapstructure:"default_pitch"`
-	DefaultFormat     string            `mapstructure:"default_format"`
-	MaxTextLength     int               `mapstructure:"max_text_length"`
-	RequestTimeout    int               `mapstructure:"request_timeout"`
-	MaxConcurrent     int               `mapstructure:"max_concurrent"`
-	SegmentThreshold  int               `mapstructure:"segment_threshold"`
-	MinSentenceLength int               `mapstructure:"min_sentence_length"`
-	MaxSentenceLength int               `mapstructure:"max_sentence_length"`
-	VoiceMapping      map[string]string `mapstructure:"voice_mapping"`
+	Provider          string `mapstructure:"provider"`
+	ApiKey            string `mapstructure:"api_key"`
+	Region            string `mapstructure:"region"`
+	DefaultVoice      string `mapstructure:"default_voice"`
+	DefaultRate       string `mapstructure:"default_rate"`
+	DefaultPitch      string `mapstructure:"default_pitch"`
+	DefaultFormat     string `mapstructure:"default_format"`
+	MaxTextLength     int    `mapstructure:"max_text_length"`
+	RequestTimeout    int    `mapstructure:"request_timeout"`
+	MaxConcurrent     int    `mapstructure:"max_concurrent"`
+	SegmentThreshold  int    `mapstructure:"segment_threshold"`
+	MinSentenceLength int    `mapstructure:"min_sentence_length"`
+	MaxSentenceLength int    `mapstructure:"max_sentence_length"`
+	// MaxAudioSeconds 单次请求预计音频时长上限（秒），超过时自动继续分段，Azure 限制为 10 分钟
+	MaxAudioSeconds int `mapstructure:"max_audio_seconds"`
+	// EstimatedCharsPerSecond 估算音频时长时使用的默认语速（字/秒）
+	EstimatedCharsPerSecond float64 `mapstructure:"estimated_chars_per_second"`
+	// StreamBuffer 流式返回时每个连接最多缓冲的片段数，客户端读取较慢时暂停合成后续片段，默认 4
+	StreamBuffer int `mapstructure:"stream_buffer"`
+	// VoiceMapping 将请求中的语音名称（如 OpenAI 的 alloy）映射到实际语音
+	VoiceMapping map[string]VoiceAlias `mapstructure:"voice_mapping"`
+	KeepAlive    KeepAliveConfig       `mapstructure:"keep_alive"`
+	Outbound     OutboundConfig        `mapstructure:"outbound"`
+	Mock         MockConfig            `mapstructure:"mock"`
+	VCR          VCRConfig             `mapstructure:"vcr"`
+	// VoiceRollout 按比例将部分流量切换到新语音，键为请求中的语音名称
+	VoiceRollout map[string]VoiceRollout `mapstructure:"voice_rollout"`
+	// Voices 为语音附加本地信息，键为语音简称 (zh-CN-XiaoxiaoNeural)，合并到语音列表中
+	Voices map[string]VoiceEntry `mapstructure:"voices"`
+	// PreviewTexts 语音试听使用的示例句子，键为语言 (zh)、区域 (zh-cn) 或 default
+	PreviewTexts map[string]string `mapstructure:"preview_texts"`
+	// SegmentRules 按语言配置分句规则，键为语言 (zh)、区域 (zh-cn) 或 default
+	SegmentRules map[string]SegmentRule `mapstructure:"segment_rules"`
+	// Pools 按服务名称 (microsoft、mock) 限制所有请求合计同时发往上游的请求数，0 表示不限制但仍可通过管理接口调整；
+	// 未配置的服务不经过并发池，只受每个请求的 max_concurrent 限制
+	Pools map[string]int `mapstructure:"pools"`
+	// Adaptive 根据上游限流响应自动调整并发池的生效上限
+	Adaptive AdaptiveConfig `mapstructure:"adaptive"`
+	// TimeStretch 对超出上游语速范围的请求进行变速处理（需要 ffmpeg）
+	TimeStretch TimeStretchConfig `mapstructure:"time_stretch"`
+	// FirstChunk 是流式合成的低延迟模式
+	FirstChunk FirstChunkConfig `mapstructure:"first_chunk"`
+}
+
+// FirstChunkConfig 是流式合成的低延迟模式：第一段只取开头的一句或分句，尽快返回第一段音频，之后的片段按正常大小合成。
+// 第一段的长度按目标延迟与最近观测到的合成速度自动调整，请求可以通过 latency 参数覆盖 Enabled
+type FirstChunkConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TargetMs 是第一段的目标合成延迟（毫秒），默认 800
+	TargetMs int `mapstructure:"target_ms"`
+	// MinLength 与 MaxLength 限制第一段的长度（字），默认 8 与 60
+	MinLength int `mapstructure:"min_length"`
+	MaxLength int `mapstructure:"max_length"`
+}
+
+// TimeStretchConfig 是变速处理的配置：语速超出 [min_rate, max_rate] 时按范围上下限合成，再变速补足剩余的倍数
+type TimeStretchConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	MaxRate int  `mapstructure:"max_rate"` // 直接由上游合成的最大语速（百分比），默认 100，即 2 倍速
+	MinRate int  `mapstructure:"min_rate"` // 直接由上游合成的最小语速（百分比），默认 -50，即 0.5 倍速
+}
+
+// AdaptiveConfig 是自适应并发的配置：上游返回 429 时生效上限减半，之后逐步恢复到配置的上限
+type AdaptiveConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Min     int  `mapstructure:"min"` // 生效上限的最小值，默认 1
+}
+
+// KeepAliveConfig 包含上游连接预热与保活配置
+type KeepAliveConfig struct {
+	Enabled  bool `mapstructure:"enabled"`
+	Interval int  `mapstructure:"interval"` // 保活间隔（秒），默认 60
+}
+
+// IntervalDuration 返回保活间隔，未配置或不大于 0 时为 60 秒
+func (k KeepAliveConfig) IntervalDuration() time.Duration {
+	if k.Interval <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(k.Interval) * time.Second
+}
+
+// OutboundConfig 包含发往 Azure 的请求使用的代理与请求头，用于只能通过代理访问外网的环境
+type OutboundConfig struct {
+	// Proxy 出站代理地址，如 http://proxy.corp:3128；为空时使用 HTTPS_PROXY 等环境变量
+	Proxy         string `mapstructure:"proxy"`
+	ProxyUsername string `mapstructure:"proxy_username"`
+	ProxyPassword string `mapstructure:"proxy_password"`
+	// UserAgent 替换默认的 User-Agent
+	UserAgent string `mapstructure:"user_agent"`
+	// Headers 附加到每个请求的请求头，与默认请求头同名时覆盖
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// MockConfig 包含模拟服务 (provider: mock) 的配置
+type MockConfig struct {
+	CharsPerSecond float64 `mapstructure:"chars_per_second"` // 模拟语速，决定静音时长
+	LatencyMs      int     `mapstructure:"latency_ms"`       // 模拟上游延迟（毫秒）
+}
+
+// VCRConfig 包含 Azure 交互录制/回放的配置
+type VCRConfig struct {
+	Mode string `mapstructure:"mode"` // 空: 关闭, record: 录制, replay: 只回放, auto: 有录制则回放否则录制
+	Dir  string `mapstructure:"dir"`  // 夹具文件目录
+}
+
+// VoiceAlias 是 voice_mapping 中的一项
+type VoiceAlias struct {
+	Voice       string `mapstructure:"voice"`       // 实际使用的语音
+	Description string `mapstructure:"description"` // 说明，只用于标注
+}
+
+// VoiceEntry 是一个语音的本地信息
+type VoiceEntry struct {
+	DisplayName string   `mapstructure:"display_name"` // 替换上游的显示名称
+	Tags        []string `mapstructure:"tags"`         // 标签，语音列表可按 ?tag= 筛选
+	SampleText  string   `mapstructure:"sample_text"`  // 试听使用的示例句子，优先于 preview_texts
+	Enabled     *bool    `mapstructure:"enabled"`      // 设为 false 时不在语音列表中展示，默认 true
+}
+
+// SegmentRule 描述一种语言的分句规则
+type SegmentRule struct {
+	Delimiters []string `mapstructure:"delimiters"` // 额外的分句符号，符号保留在前一句末尾
+	Protected  []string `mapstructure:"protected"`  // 词或缩写后紧跟分句符号时不切分，如 "Mr."、"世纪"
+}
+
+// VoiceRollout 描述一条语音灰度规则
+type VoiceRollout struct {
+	Voice   string  `mapstructure:"voice"`   // 灰度使用的新语音
+	Percent float64 `mapstructure:"percent"` // 分流比例 (0-100)，按API密钥固定分组
 }
 
 var (
@@ -57,28 +642,7 @@ var (
 func Load(configPath string) (*Config, error) {
 	var err error
 	once.Do(func() {
-		v := viper.New()
-
-		// 配置 Viper
-		v.SetConfigName("config")
-		v.SetConfigType("yaml")
-		v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-		v.AutomaticEnv() // 自动绑定环境变量
-
-		// 从配置文件加载
-		if configPath != "" {
-			v.SetConfigFile(configPath)
-			if err = v.ReadInConfig(); err != nil {
-				err = fmt.Errorf("加载配置文件失败: %w", err)
-				return
-			}
-		}
-
-		// 将配置绑定到结构体
-		if err = v.Unmarshal(&config); err != nil {
-			err = fmt.Errorf("解析配置失败: %w", err)
-			return
-		}
+		err = read(configPath, &config)
 	})
 
 	if err != nil {
@@ -88,6 +652,59 @@ func Load(configPath string) (*Config, error) {
 	return &config, nil
 }
 
+// Reread 重新读取配置文件并返回新的配置，不影响 Get 返回的已加载配置，用于热重载
+func Reread(configPath string) (*Config, error) {
+	cfg := &Config{}
+	if err := read(configPath, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// read 按优先级合并配置文件与环境变量并解析到 cfg
+func read(configPath string, cfg *Config) error {
+	v, layers, err := load(configPath)
+	if err != nil {
+		return err
+	}
+	if len(layers) > 1 {
+		names := make([]string, len(layers))
+		for i, l := range layers {
+			names[i] = l.name
+		}
+		log.Printf("合并配置: %s", strings.Join(names, ", "))
+	}
+	return decode(v, cfg)
+}
+
+// Parse 解析 YAML 格式的配置文档，不读取配置文件与远程配置，用于检查生成的配置
+func Parse(data []byte) (*Config, error) {
+	v := newViper()
+	if err := v.MergeConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+	cfg := &Config{}
+	if err := decode(v, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// decode 将合并后的配置迁移到当前版本并解析到 cfg
+func decode(v *viper.Viper, cfg *Config) error {
+	if err := migrate(v); err != nil {
+		return err
+	}
+
+	// 将配置绑定到结构体，无法识别的配置项记录警告，避免拼写错误或已删除的配置被悄悄忽略
+	var md mapstructure.Metadata
+	if err := v.Unmarshal(cfg, func(dc *mapstructure.DecoderConfig) { dc.Metadata = &md }); err != nil {
+		return fmt.Errorf("解析配置失败: %w", err)
+	}
+	warnUnused(md.Unused)
+	return nil
+}
+
 // Get 返回已加载的配置
 func Get() *Config {
 	return &config
@@ -103,112 +720,286 @@ type TagPattern struct {
 type SSMLConfig struct {
 	// PreserveTags 包含所有需要保留的标签的正则表达式模式
 	PreserveTags []TagPattern `mapstructure:"preserve_tags"`
+	// Validate 为 true 时，发送前按 Azure 支持的元素与属性校验完整SSML文档
+	Validate bool `mapstructure:"validate"`
+	// InlineHints 为 true 时，把 {重庆|chóng qìng}、{AI|人工智能} 形式的读音提示转换为 <phoneme>、<sub> 标签
+	InlineHints bool `mapstructure:"inline_hints"`
+	// Ruby 是 <ruby> 注音的朗读方式：base 只读正文，reading 只读注音，both 两者都读，为空时不处理
+	Ruby string `mapstructure:"ruby"`
+	// Bidi 处理夹杂在其他文字中的阿拉伯文、希伯来文
+	Bidi BidiConfig `mapstructure:"bidi"`
+	// SayAs 按实体类型自动添加 <say-as> 标签
+	SayAs SayAsConfig `mapstructure:"say_as"`
+	// Acronyms 是全大写缩写词的读法
+	Acronyms AcronymConfig `mapstructure:"acronyms"`
+	// URLs 是文本中网址、域名与邮箱的朗读方式
+	URLs URLConfig `mapstructure:"urls"`
+	// Pacing 按句子的难度调整语速
+	Pacing PacingConfig `mapstructure:"pacing"`
+	// Markdown 控制加粗、引用与标题的朗读方式
+	Markdown MarkdownConfig `mapstructure:"markdown"`
+	// Quotes 控制引号中对话的朗读方式
+	Quotes QuotesConfig `mapstructure:"quotes"`
+}
+
+// QuotesConfig 控制引号中对话的朗读方式：识别 他说：“……”、"……," she said 等对话，
+// 对话换用第二个语音或调整语调，叙述仍使用请求的语音。请求可以通过 quotes 参数覆盖 Mode
+type QuotesConfig struct {
+	// Mode 可选 off（默认，不处理）、voice（对话使用 Voices 中的语音）、prosody（同一语音调整语速与音调）
+	Mode string `mapstructure:"mode"`
+	// Voices 按区域（如 zh-CN）或语言（如 zh）指定朗读对话的语音；没有对应的语音或与请求的语音相同时按 prosody 处理
+	Voices map[string]string `mapstructure:"voices"`
+	// Rate 与 Pitch 是 prosody 方式下对话相对于请求的语速与音调，默认 0% 与 +10%
+	Rate  string `mapstructure:"rate"`
+	Pitch string `mapstructure:"pitch"`
+	// Words 是追加的引述词，默认已包含“说”“问”“回答”、said、asked 等
+	Words []string `mapstructure:"words"`
+	// RequireAttribution 为 true 时只有前后有引述词的引号才是对话；
+	// 默认内容以句末标点结尾的引号（“你好！”）也是对话
+	RequireAttribution bool `mapstructure:"require_attribution"`
 }
 
+// MarkdownConfig 控制 Markdown 结构的朗读方式。启用后清理 Markdown 时不再只删除标记：
+// 加粗转换为 <emphasis>，引用放慢并降低音调，一二级标题前后停顿并加强调。
+// 生成的标签需要在 preserve_tags 中保留，否则不生成
+type MarkdownConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Bold 是加粗文字的强调级别：strong、moderate（默认）、reduced，none 表示不强调
+	Bold string `mapstructure:"bold"`
+	// QuoteRate 与 QuotePitch 是引用的语速与音调，默认 -10% 与 -5%，"0%" 表示不调整
+	QuoteRate  string `mapstructure:"quote_rate"`
+	QuotePitch string `mapstructure:"quote_pitch"`
+	// HeadingLevel 是加停顿与强调的最低一级标题，默认 2（# 与 ##）
+	HeadingLevel int `mapstructure:"heading_level"`
+	// HeadingEmphasis 是标题的强调级别，默认 strong，none 表示只停顿
+	HeadingEmphasis string `mapstructure:"heading_emphasis"`
+	// HeadingBreakMs 是标题前后的停顿（毫秒），默认 300
+	HeadingBreakMs int `mapstructure:"heading_break_ms"`
+}
+
+// PacingConfig 包含按句子难度调整语速的配置：数字、长单词与代码标识符密集的句子放慢，普通叙述句稍微加快。
+// 难度为这些词在句子中的占比（代码标识符计两次，中文每两个汉字计为一个词），在 0 与 1 之间
+type PacingConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	SlowRate string `mapstructure:"slow_rate"` // 难句的语速，相对于请求的语速，默认 -15%
+	FastRate string `mapstructure:"fast_rate"` // 简单句的语速，默认 +8%，"0%" 表示不加快
+	// DenseThreshold 与 SimpleThreshold 是难句与简单句的难度阈值，默认 0.25 与 0.05
+	DenseThreshold  float64 `mapstructure:"dense_threshold"`
+	SimpleThreshold float64 `mapstructure:"simple_threshold"`
+	LongWord        int     `mapstructure:"long_word"` // 达到该长度的英文单词算作长单词，默认 12
+	MinWords        int     `mapstructure:"min_words"` // 少于该词数的短句不加快，默认 6
+}
+
+// URLConfig 是文本中网址、域名与邮箱的朗读方式，请求可以通过 url_mode 覆盖
+type URLConfig struct {
+	// Mode 可选 remove（删除，默认）、domain（只读域名）、text（只读链接文字）、keep（保持原样）
+	Mode string `mapstructure:"mode"`
+	// Dot 是 domain 模式下域名中 "." 的读法，默认 dot，中文语音可以配置为 "点"
+	Dot string `mapstructure:"dot"`
+}
+
+// AcronymConfig 包含全大写缩写词（如 NASA、SQL）的读法配置
+type AcronymConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Unknown 是不在词表中的缩写词的处理方式：spell 逐个字母朗读，keep 保持原样交给语音判断
+	Unknown string `mapstructure:"unknown"`
+	// MaxLength 是逐个字母朗读的最大长度，更长的全大写词（如强调用的 WARNING）保持原样，默认 5
+	MaxLength int `mapstructure:"max_length"`
+	// Words 是缩写词的读法，键不区分大小写，如 SQL: "sequel"、JSON: "jason"
+	Words map[string]string `mapstructure:"words"`
+}
+
+// SayAsConfig 控制自动添加 <say-as> 的实体类型，字段与 ssml.SayAsRules 一一对应
+type SayAsConfig struct {
+	Ordinal   bool `mapstructure:"ordinal"`   // 英文序数词，如 1st、22nd
+	Fraction  bool `mapstructure:"fraction"`  // 分数，如 3/4
+	Telephone bool `mapstructure:"telephone"` // 电话号码
+	Address   bool `mapstructure:"address"`   // 门牌地址
+	Date      bool `mapstructure:"date"`      // ISO 日期，如 2024-05-01
+	Currency  bool `mapstructure:"currency"`  // 金额，如 $9.99、¥100
+}
+
+// BidiConfig 包含双向文本（从右向左与从左向右混排）的处理配置
+type BidiConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Voices 按文字 (ar、he) 指定朗读该文字片段的语音，如 ar: "ar-SA-HamedNeural"。
+	// 请求的语音是多语言语音时改为用 <lang> 标注该语音所属的语言
+	Voices map[string]string `mapstructure:"voices"`
+}
+
+// preprocessBuckets 是文本预处理耗时直方图的桶（秒），预处理通常在微秒到毫秒之间
+var preprocessBuckets = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1}
+
+var (
+	preprocessDuration = metrics.NewHistogram("tts_preprocess_duration_seconds",
+		"文本预处理各阶段的耗时（秒）", preprocessBuckets, "stage")
+	markdownRemovedBytes = metrics.NewCounter("tts_markdown_removed_bytes_total",
+		"StripMarkdown 清理掉的字节数")
+)
+
 // SSMLProcessor 处理SSML内容
 type SSMLProcessor struct {
-	config       *SSMLConfig
-	patternCache map[string]*regexp.Regexp
+	config   *SSMLConfig
+	patterns []compiledTagPattern
+	markdown *markdownProsody // 未启用 ssml.markdown 时为 nil
+}
+
+// compiledTagPattern 是编译后的保留标签模式，正则已锚定为匹配完整标签
+type compiledTagPattern struct {
+	name  string
+	regex *regexp.Regexp
 }
 
 // NewSSMLProcessor 从配置对象创建SSMLProcessor
 func NewSSMLProcessor(config *SSMLConfig) (*SSMLProcessor, error) {
-	processor := &SSMLProcessor{
-		config:       config,
-		patternCache: make(map[string]*regexp.Regexp),
+	processor := &SSMLProcessor{config: config}
+
+	if !utils.ValidRubyMode(config.Ruby) {
+		return nil, fmt.Errorf("未知的 ssml.ruby: %s", config.Ruby)
+	}
+	if !ValidURLMode(config.URLs.Mode) {
+		return nil, fmt.Errorf("未知的 ssml.urls.mode: %s，可选 remove、domain、text、keep", config.URLs.Mode)
+	}
+	switch config.Acronyms.Unknown {
+	case "", "spell", "keep":
+	default:
+		return nil, fmt.Errorf("未知的 ssml.acronyms.unknown: %s，可选 spell、keep", config.Acronyms.Unknown)
+	}
+	for _, rate := range []string{config.Pacing.SlowRate, config.Pacing.FastRate} {
+		if rate != "" && !validPercent(rate) {
+			return nil, fmt.Errorf("ssml.pacing 的语速应为百分比，如 -15%%: %s", rate)
+		}
+	}
+	for _, value := range []string{config.Markdown.QuoteRate, config.Markdown.QuotePitch} {
+		if value != "" && !validPercent(value) {
+			return nil, fmt.Errorf("ssml.markdown 引用的语速与音调应为百分比，如 -10%%: %s", value)
+		}
+	}
+	if !ValidQuoteMode(config.Quotes.Mode) {
+		return nil, fmt.Errorf("未知的 ssml.quotes.mode: %s，可选 off、voice、prosody", config.Quotes.Mode)
+	}
+	for _, value := range []string{config.Quotes.Rate, config.Quotes.Pitch} {
+		if value != "" && !validPercent(value) {
+			return nil, fmt.Errorf("ssml.quotes 的语速与音调应为百分比，如 +10%%: %s", value)
+		}
+	}
+	for _, level := range []string{config.Markdown.Bold, config.Markdown.HeadingEmphasis} {
+		if !validEmphasis(level) {
+			return nil, fmt.Errorf("未知的 ssml.markdown 强调级别: %s，可选 strong、moderate、reduced、none", level)
+		}
+	}
+	for script := range config.Bidi.Voices {
+		if script != utils.ScriptArabic && script != utils.ScriptHebrew {
+			return nil, fmt.Errorf("ssml.bidi.voices 中未知的文字: %s，可选 ar、he", script)
+		}
 	}
 
-	// 预编译正则表达式
-	for _, tagPattern := range config.PreserveTags {
-		regex, err := regexp.Compile(tagPattern.Pattern)
+	// 预编译正则表达式，并校验名称唯一、模式不能匹配空串
+	seen := make(map[string]bool, len(config.PreserveTags))
+	for i, tagPattern := range config.PreserveTags {
+		if tagPattern.Name == "" {
+			return nil, fmt.Errorf("preserve_tags 第 %d 项缺少名称", i+1)
+		}
+		if seen[tagPattern.Name] {
+			return nil, fmt.Errorf("preserve_tags 名称'%s'重复", tagPattern.Name)
+		}
+		seen[tagPattern.Name] = true
+		if tagPattern.Pattern == "" {
+			return nil, fmt.Errorf("preserve_tags '%s' 缺少模式", tagPattern.Name)
+		}
+		regex, err := regexp.Compile(`^(?:` + tagPattern.Pattern + `)$`)
 		if err != nil {
 			return nil, fmt.Errorf("编译正则表达式'%s'失败: %w", tagPattern.Name, err)
 		}
-		processor.patternCache[tagPattern.Name] = regex
+		if regex.MatchString("") {
+			return nil, fmt.Errorf("preserve_tags '%s' 的模式不能匹配空字符串", tagPattern.Name)
+		}
+		processor.patterns = append(processor.patterns, compiledTagPattern{name: tagPattern.Name, regex: regex})
+	}
+	if config.Markdown.Enabled {
+		processor.markdown = newMarkdownProsody(config.Markdown, processor)
 	}
 
 	return processor, nil
 }
 
-// EscapeSSML 转义SSML内容，但保留配置的标签
+// validPercent 判断是否为 -15%、+8% 形式的百分比
+func validPercent(value string) bool {
+	number, ok := strings.CutSuffix(value, "%")
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseFloat(number, 64)
+	return err == nil
+}
+
+// allowed 判断一个完整的标签是否匹配任一保留模式
+func (p *SSMLProcessor) allowed(tag string) bool {
+	for _, pattern := range p.patterns {
+		if pattern.regex.MatchString(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// EscapeSSML 转义SSML内容，但保留配置的标签。
+// 输入按顺序扫描一次：词法上完整且匹配保留模式的标签原样输出，其余内容都作为文本转义。
+// 未匹配开始标签的结束标签按文本处理，末尾未闭合的标签会自动补齐，保证输出结构完整。
 func (p *SSMLProcessor) EscapeSSML(ssml string) string {
-	// 使用占位符替换标签
-	placeholders := make(map[string]string)
-	processedSSML := ssml
-
-	counter := 0
-
-	// 处理所有配置的标签
-	for name, pattern := range p.patternCache {
-		processedSSML = pattern.ReplaceAllStringFunc(processedSSML, func(match string) string {
-			placeholder := fmt.Sprintf("__SSML_PLACEHOLDER_%s_%d__", name, counter)
-			placeholders[placeholder] = match
-			counter++
-			return placeholder
-		})
-	}
-
-	// 对处理后的文本进行HTML转义
-	escapedContent := html.EscapeString(processedSSML)
-
-	// 恢复所有标签占位符
-	for placeholder, tag := range placeholders {
-		escapedContent = strings.Replace(escapedContent, placeholder, tag, 1)
-	}
-
-	return escapedContent
-}
-
-// StripMarkdown 清理 Markdown 标记，避免在语音中被朗读
-func (p *SSMLProcessor) StripMarkdown(input string) string {
-    if input == "" {
-        return ""
-    }
-
-    text := input
-
-    // 1) 代码块 ``` ```
-    text = regexp.MustCompile("(?s)```[\\s\\S]*?```").ReplaceAllString(text, "")
-    // 2) 行内代码 `code`
-    text = regexp.MustCompile("`[^`]*`").ReplaceAllString(text, "")
-    // 3) 标题 #, ##, ### 前缀
-    text = regexp.MustCompile("(?m)^\\s{0,3}#{1,6}\\s+").ReplaceAllString(text, "")
-    // 4) 列表标记 -, *, + 开头
-    text = regexp.MustCompile("(?m)^\\s*[-*+]\\s+").ReplaceAllString(text, "")
-    // 6) 加粗/斜体 **text** *text* __text__ _text_
-    text = regexp.MustCompile("\\*\\*([^*]+)\\*\\*").ReplaceAllString(text, "$1")
-    text = regexp.MustCompile("\\*([^*]+)\\*").ReplaceAllString(text, "$1")
-    text = regexp.MustCompile("__([^_]+)__").ReplaceAllString(text, "$1")
-    text = regexp.MustCompile("_([^_]+)_").ReplaceAllString(text, "$1")
-    // 7) 链接与图片 [text](url) ![alt](url)
-    text = regexp.MustCompile("!\\[[^\\]]*\\]\\([^\\)]*\\)").ReplaceAllString(text, "")
-    text = regexp.MustCompile("\\[([^\\]]+)\\]\\(([^\\)]+)\\)").ReplaceAllString(text, "$1")
-    // 7.1) HTML 链接 <a href="...">text</a> 保留可读文本，去掉标签与URL
-    text = regexp.MustCompile(`(?is)<a\s+[^>]*href=("|')[^"']+("|')[^>]*>(.*?)</a>`).ReplaceAllString(text, "$3")
-    // 7.2) HTML 图片直接移除
-    text = regexp.MustCompile(`(?is)<img\s+[^>]*>`).ReplaceAllString(text, "")
-    // 7.3) 自动链接 <https://...>
-    text = regexp.MustCompile(`(?i)<https?://[^>\s]+>`).ReplaceAllString(text, "")
-    text = regexp.MustCompile(`(?i)<www\.[^>\s]+>`).ReplaceAllString(text, "")
-    // 7.4) 纯 URL（http/https/ftp 或 www 开头）
-    text = regexp.MustCompile(`(?i)\b(?:https?://|ftp://|www\.)[^\s<)]+`).ReplaceAllString(text, "")
-    // 7.5) 域名路径（example.com/.. 等常见顶级域名）
-    text = regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)+(?:com|org|net|edu|gov|io|ai|cn|xyz|top|info|me|site|club|dev|app|tech|tv|gg|so|uk|jp|de|fr|au|ca|us|hk|sg)(?:/[\S]*)?`).ReplaceAllString(text, "")
-    // 7.6) 邮箱
-    text = regexp.MustCompile(`(?i)\b[\w.+-]+@[\w-]+(?:\.[\w-]+)+\b`).ReplaceAllString(text, "")
-    // 8) 引用行 >
-    text = regexp.MustCompile(`(?m)^\s*>+\s?`).ReplaceAllString(text, "")
-    // 9) 水平线 --- *** ___
-    text = regexp.MustCompile(`(?m)^\s*(?:-{3,}|\*{3,}|_{3,})\s*$`).ReplaceAllString(text, "")
-    // 10) 转义反斜杠 \\*
-    text = regexp.MustCompile("\\\\([*_`\\\\\\[\\\\\\]()>#+\\-])").ReplaceAllString(text, "$1")
-    // 11) 剩余孤立 Markdown 符号清理（避免误删 HTML/比较符号，不处理 '>'）
-    text = regexp.MustCompile("[#*_`]+").ReplaceAllString(text, "")
-    // 12) 多空白合并
-    text = regexp.MustCompile(`[\t\f\v]+`).ReplaceAllString(text, " ")
-    text = regexp.MustCompile(`\s{2,}`).ReplaceAllString(text, " ")
-    // 13) 多个空行压缩
-    text = regexp.MustCompile(`\n{3,}`).ReplaceAllString(text, "\n\n")
-
-    return strings.TrimSpace(text)
-}
\ No newline at end of file
+	defer observePreprocess("escape", time.Now())
+
+	// 快速路径：标签都以 '<' 开头，不含 '<' 的文本无需扫描
+	if !strings.ContainsRune(ssml, '<') {
+		return html.EscapeString(ssml)
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(ssml) + len(ssml)/8)
+	var open []string
+	textStart := 0
+	flush := func(end int) {
+		if end > textStart {
+			sb.WriteString(html.EscapeString(ssml[textStart:end]))
+		}
+	}
+
+	for i := 0; i < len(ssml); {
+		next := strings.IndexByte(ssml[i:], '<')
+		if next < 0 {
+			break
+		}
+		i += next
+		tag, ok := scanTag(ssml, i)
+		if !ok || !p.allowed(ssml[i:tag.end]) {
+			i++
+			continue
+		}
+		switch tag.kind {
+		case tagOpen:
+			open = append(open, tag.name)
+		case tagClose:
+			if len(open) == 0 || open[len(open)-1] != tag.name {
+				// 不成对的结束标签按普通文本转义
+				i = tag.end
+				continue
+			}
+			open = open[:len(open)-1]
+		}
+		flush(i)
+		sb.WriteString(ssml[i:tag.end])
+		i = tag.end
+		textStart = i
+	}
+	flush(len(ssml))
+
+	for j := len(open) - 1; j >= 0; j-- {
+		sb.WriteString("</" + open[j] + ">")
+	}
+	return sb.String()
+}
+
+// observePreprocess 记录预处理阶段 stage 自 start 起的耗时
+func observePreprocess(stage string, start time.Time) {
+	preprocessDuration.Observe(time.Since(start).Seconds(), stage)
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// testPreserveTags 与 configs/config.yaml 中默认保留的标签一致
+var testPreserveTags = []TagPattern{
+	{Name: "break", Pattern: `<break\s+[^>]*/>`},
+	{Name: "speak", Pattern: `<speak>|</speak>`},
+	{Name: "prosody", Pattern: `<prosody\s+[^>]*>|</prosody>`},
+	{Name: "emphasis", Pattern: `<emphasis\s+[^>]*>|</emphasis>`},
+	{Name: "voice", Pattern: `<voice\s+[^>]*>|</voice>`},
+	{Name: "say-as", Pattern: `<say-as\s+[^>]*>|</say-as>`},
+	{Name: "phoneme", Pattern: `<phoneme\s+[^>]*>|</phoneme>`},
+	{Name: "p", Pattern: `<p>|</p>`},
+	{Name: "s", Pattern: `<s>|</s>`},
+	{Name: "sub", Pattern: `<sub\s+[^>]*>|</sub>`},
+	{Name: "mstts", Pattern: `<mstts:[^>]*>|</mstts:[^>]*>`},
+}
+
+// 基准测试使用的文本
+const (
+	benchShortText    = "你好，欢迎使用语音合成服务。今天天气不错，适合出门散步。"
+	benchMarkdownText = "# 标题\n\n这是**加粗**和*斜体*文本，参见 [链接](https://example.com)。\n\n- 列表项一\n- 列表项二\n\n```go\nfmt.Println(1)\n```\n"
+	benchTaggedText   = `开始<break time="500ms"/>中间<prosody rate="+10%">加速</prosody>结束 & 完成`
+)
+
+var benchLongText = strings.Repeat(benchShortText+"\n", 200)
+
+func newTestProcessor(tb testing.TB) *SSMLProcessor {
+	tb.Helper()
+	p, err := NewSSMLProcessor(&SSMLConfig{PreserveTags: testPreserveTags})
+	if err != nil {
+		tb.Fatalf("NewSSMLProcessor: %v", err)
+	}
+	return p
+}
+
+// FuzzEscapeSSML 检查转义结果总是结构完整的 XML，且保留标签之外的文本原样保留：
+// 把输出中的文本解码、标签原样拼回后，应得到输入本身加上末尾自动补齐的结束标签
+func FuzzEscapeSSML(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"普通文本",
+		"a < b && c > d",
+		"Tom & Jerry's \"show\"",
+		"&amp; &lt; &#39; &unknown;",
+		"__SSML_PLACEHOLDER_0__",
+		"前缀__SSML_PLACEHOLDER_12__<break time=\"500ms\"/>__SSML_PLACEHOLDER_1__",
+		"<break time=\"500ms\"/>",
+		"<break time='1s' />停顿",
+		"<prosody rate=\"+10%\">快</prosody>",
+		"<prosody rate=\"+10%\">未闭合",
+		"<emphasis level=\"strong\"><prosody pitch=\"high\">嵌套未闭合",
+		"</prosody>多余的结束标签",
+		"<emphasis level=\"strong\">交叉</prosody></emphasis>",
+		"<prosody rate=\"+10%\"",
+		"<prosody rate=\"a>b\">引号中的 &gt;</prosody>",
+		"<prosody rate=\"<\">",
+		"<script>alert(1)</script>",
+		"<p><s>第一句</s><s>第二句</s></p>",
+		"<mstts:express-as style=\"cheerful\">开心</mstts:express-as>",
+		"<say-as interpret-as=\"date\">2024-03-05</say-as>",
+		"<<break time=\"1s\"/>>",
+		"<",
+		"</",
+		"<>",
+		"<!-- 注释 -->",
+		"<![CDATA[x]]>",
+	} {
+		f.Add(seed)
+	}
+
+	p := newTestProcessor(f)
+	f.Fuzz(func(t *testing.T, input string) {
+		// XML 不允许控制字符与无效的 UTF-8，解析时还会把 \r 规范化为 \n，这类输入不检查
+		if !validXMLText(input) || strings.ContainsRune(input, '\r') {
+			t.Skip()
+		}
+		output := p.EscapeSSML(input)
+
+		rebuilt, closers, err := reassemble(output)
+		if err != nil {
+			t.Fatalf("输出不是结构完整的 XML: %v\n输入: %q\n输出: %q", err, input, output)
+		}
+		// 输入可能以结束标签结尾，这些标签也在 closers 中，去掉之后剩下的应全部是自动补齐的结束标签
+		if !strings.HasPrefix(input, rebuilt) || !strings.HasPrefix(rebuilt+closers, input) {
+			t.Fatalf("保留标签之外的文本被改变\n输入: %q\n还原: %q\n输出: %q", input, rebuilt+closers, output)
+		}
+	})
+}
+
+// reassemble 逐个解析 output 中的 XML 记号：文本按解码后的内容、标签按原文拼接。
+// 末尾连续的结束标签（包括自动补齐的部分）单独返回
+func reassemble(output string) (string, string, error) {
+	dec := xml.NewDecoder(strings.NewReader(output))
+	dec.Strict = true
+	var sb strings.Builder
+	var trailing strings.Builder
+	prev := int64(0)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", "", err
+		}
+		raw := output[prev:dec.InputOffset()]
+		prev = dec.InputOffset()
+		switch tok := tok.(type) {
+		case xml.CharData:
+			sb.WriteString(trailing.String())
+			trailing.Reset()
+			sb.Write(tok)
+		case xml.EndElement:
+			trailing.WriteString(raw)
+		default:
+			sb.WriteString(trailing.String())
+			trailing.Reset()
+			sb.WriteString(raw)
+		}
+	}
+	return sb.String(), trailing.String(), nil
+}
+
+// validXMLText 判断文本是否只包含 XML 允许的字符
+func validXMLText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r == utf8.RuneError || (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || (r >= 0xFFFE && r <= 0xFFFF) || (r >= 0xD800 && r <= 0xDFFF) {
+			return false
+		}
+	}
+	return true
+}
+
+func BenchmarkEscapeSSML(b *testing.B) {
+	p := newTestProcessor(b)
+	for _, bm := range []struct{ name, text string }{
+		{"plain", benchShortText},
+		{"tagged", benchTaggedText},
+		{"long", benchLongText},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p.EscapeSSML(bm.text)
+			}
+		})
+	}
+}
+
+func BenchmarkStripMarkdown(b *testing.B) {
+	p := newTestProcessor(b)
+	for _, bm := range []struct{ name, text string }{
+		{"short", benchShortText},
+		{"markdown", benchMarkdownText},
+		{"long", benchLongText},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				p.StripMarkdown(bm.text)
+			}
+		})
+	}
+}
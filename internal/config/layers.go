@@ -0,0 +1,247 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/viper"
+)
+
+// EnvironmentVariable 是指定配置环境名称的环境变量
+const EnvironmentVariable = "TTS_ENV"
+
+var (
+	envMu       sync.RWMutex
+	environment string
+)
+
+// SetEnvironment 设置配置环境名称（如 prod），优先于环境变量 TTS_ENV
+func SetEnvironment(name string) {
+	envMu.Lock()
+	defer envMu.Unlock()
+	environment = name
+}
+
+// Environment 返回配置环境名称，未设置时为空
+func Environment() string {
+	envMu.RLock()
+	defer envMu.RUnlock()
+	if environment != "" {
+		return environment
+	}
+	return os.Getenv(EnvironmentVariable)
+}
+
+// searchPaths 是未指定配置文件时依次查找的位置
+var searchPaths = []string{
+	"./configs/config.yaml",
+	"../configs/config.yaml",
+	"/etc/tts/config.yaml",
+}
+
+// FindFile 返回默认位置中第一个存在的配置文件，都不存在时返回 ./configs/config.yaml
+func FindFile() string {
+	for _, path := range searchPaths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return searchPaths[0]
+}
+
+// Files 返回按优先级从低到高合并的配置文件：configPath、同目录下的 {name}.{env}{ext} 与 {name}.local{ext}，
+// 只包含存在的文件，configPath 总是在第一个
+func Files(configPath string) []string {
+	if configPath == "" {
+		return nil
+	}
+	files := []string{configPath}
+	ext := filepath.Ext(configPath)
+	base := strings.TrimSuffix(configPath, ext)
+	var overlays []string
+	if env := Environment(); env != "" {
+		overlays = append(overlays, base+"."+env+ext)
+	}
+	overlays = append(overlays, base+".local"+ext)
+	for _, path := range overlays {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// newViper 创建读取 YAML 并绑定环境变量的 Viper
+func newViper() *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv() // 自动绑定环境变量
+	return v
+}
+
+// layer 是合并的一个配置来源
+type layer struct {
+	name  string // 配置文件路径，远程配置为 remote
+	data  []byte
+	local bool
+}
+
+// label 返回列出配置项来源时使用的名称
+func (l layer) label() string {
+	if l.local {
+		return filepath.Base(l.name)
+	}
+	return l.name
+}
+
+// load 依次合并配置文件与远程配置，返回合并后的 Viper 与各配置来源。
+// 后合并的配置项覆盖之前的，映射按键合并，列表整体替换；环境变量优先于所有来源
+func load(configPath string) (*viper.Viper, []layer, error) {
+	v := newViper()
+	var layers []layer
+	for _, path := range Files(configPath) {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = v.MergeConfig(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("加载配置文件 %s 失败: %w", path, err)
+		}
+		layers = append(layers, layer{name: path, data: data, local: true})
+	}
+
+	// 逐项读取，以便 remote 配置同样可以由环境变量（如 REMOTE_URL）设置
+	remote := map[string]any{}
+	for _, name := range []string{"url", "token", "interval", "timeout", "cache"} {
+		remote[name] = v.Get("remote." + name)
+	}
+	rc := RemoteConfig{
+		URL:     v.GetString("remote.url"),
+		Token:   v.GetString("remote.token"),
+		Timeout: v.GetInt("remote.timeout"),
+		Cache:   v.GetString("remote.cache"),
+	}
+	if rc.URL != "" {
+		data, err := loadRemote(rc)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := v.MergeConfig(bytes.NewReader(data)); err != nil {
+			return nil, nil, fmt.Errorf("解析远程配置失败: %w", err)
+		}
+		layers = append(layers, layer{name: "remote", data: data})
+		// 远程配置不能修改 remote 本身
+		for name, value := range remote {
+			if value != nil {
+				v.Set("remote."+name, value)
+			}
+		}
+	}
+	return v, layers, nil
+}
+
+// WriteEffective 列出合并后（并迁移到当前版本）的每个配置项、生效的值与来源，密钥、密码等敏感值被隐藏
+func WriteEffective(w io.Writer, configPath string) error {
+	v, layers, err := load(configPath)
+	if err != nil {
+		return err
+	}
+	if err := migrate(v); err != nil {
+		return err
+	}
+
+	defined := make([]map[string]bool, len(layers))
+	names := make([]string, len(layers))
+	for i, l := range layers {
+		lv := viper.New()
+		lv.SetConfigType("yaml")
+		if err := lv.ReadConfig(bytes.NewReader(l.data)); err != nil {
+			return fmt.Errorf("加载配置 %s 失败: %w", l.name, err)
+		}
+		defined[i] = map[string]bool{}
+		for _, key := range lv.AllKeys() {
+			defined[i][key] = true
+		}
+		names[i] = l.name
+	}
+
+	fmt.Fprintf(w, "# 配置来源（优先级从低到高）: %s\n", strings.Join(names, ", "))
+	if env := Environment(); env != "" {
+		fmt.Fprintf(w, "# 配置环境: %s\n", env)
+	}
+	fmt.Fprintln(w, "# 配置项 来源 值")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	keys := v.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		var value strings.Builder
+		enc := json.NewEncoder(&value)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(maskValue(key, v.Get(key))); err != nil {
+			value.Reset()
+			fmt.Fprint(&value, v.Get(key))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key, source(key, layers, defined), strings.TrimSpace(value.String()))
+	}
+	return tw.Flush()
+}
+
+// source 返回配置项的来源：环境变量或定义了该配置项（或其上级）的最后一个来源
+func source(key string, layers []layer, defined []map[string]bool) string {
+	env := strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if _, ok := os.LookupEnv(env); ok {
+		return "env " + env
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		for k := key; k != ""; {
+			if defined[i][k] {
+				return layers[i].label()
+			}
+			dot := strings.LastIndex(k, ".")
+			if dot < 0 {
+				break
+			}
+			k = k[:dot]
+		}
+	}
+	return "迁移"
+}
+
+// sensitive 返回名为 name 的配置项是否为密钥、密码等敏感值
+func sensitive(name string) bool {
+	return name == "key" || name == "dsn" || strings.HasSuffix(name, "_key") ||
+		strings.Contains(name, "password") || strings.Contains(name, "token") || strings.Contains(name, "secret")
+}
+
+// maskValue 隐藏敏感配置项的值，列表中的映射按字段名隐藏（如 keys 中各密钥的 key）
+func maskValue(key string, value any) any {
+	name := key[strings.LastIndex(key, ".")+1:]
+	switch val := value.(type) {
+	case string:
+		if val != "" && sensitive(name) {
+			return "******"
+		}
+	case []any:
+		masked := make([]any, len(val))
+		for i, item := range val {
+			masked[i] = maskValue(name, item)
+		}
+		return masked
+	case map[string]any:
+		masked := make(map[string]any, len(val))
+		for k, item := range val {
+			masked[k] = maskValue(k, item)
+		}
+		return masked
+	}
+	return value
+}
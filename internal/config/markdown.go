@@ -0,0 +1,672 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// markdownTLDs 是识别裸域名（example.com/path）时使用的常见顶级域名
+var markdownTLDs = map[string]bool{
+	"com": true, "org": true, "net": true, "edu": true, "gov": true, "io": true, "ai": true, "cn": true,
+	"xyz": true, "top": true, "info": true, "me": true, "site": true, "club": true, "dev": true, "app": true,
+	"tech": true, "tv": true, "gg": true, "so": true, "uk": true, "jp": true, "de": true, "fr": true,
+	"au": true, "ca": true, "us": true, "hk": true, "sg": true,
+}
+
+// 文本中网址、域名与邮箱的朗读方式
+const (
+	URLRemove = "remove" // 删除网址、裸域名与邮箱，Markdown 与 HTML 链接只读文字
+	URLDomain = "domain" // 网址与裸域名只读域名，如 example dot com
+	URLText   = "text"   // 只读链接文字，带协议或 www 的网址删除，裸域名（如 example.com）与邮箱当作普通单词保留
+	URLKeep   = "keep"   // 网址、域名与邮箱保持原样，Markdown 与 HTML 链接仍只读文字
+)
+
+// defaultURLDot 是未配置时 domain 模式下域名中 "." 的读法
+const defaultURLDot = "dot"
+
+// ValidURLMode 判断网址朗读方式是否有效，空字符串表示使用配置的默认值
+func ValidURLMode(mode string) bool {
+	switch mode {
+	case "", URLRemove, URLDomain, URLText, URLKeep:
+		return true
+	}
+	return false
+}
+
+// 引号的朗读方式
+const (
+	QuotesOff     = "off"     // 不处理，与叙述使用同一语音与语调
+	QuotesVoice   = "voice"   // 引号中的对话使用第二个语音
+	QuotesProsody = "prosody" // 引号中的对话使用同一语音，调整语速与音调
+)
+
+// ValidQuoteMode 判断引号朗读方式是否有效，空字符串表示使用配置的默认值
+func ValidQuoteMode(mode string) bool {
+	switch mode {
+	case "", QuotesOff, QuotesVoice, QuotesProsody:
+		return true
+	}
+	return false
+}
+
+// 未配置时 Markdown 结构的朗读参数
+const (
+	defaultMarkdownBold            = "moderate"
+	defaultMarkdownQuoteRate       = "-10%"
+	defaultMarkdownQuotePitch      = "-5%"
+	defaultMarkdownHeadingLevel    = 2
+	defaultMarkdownHeadingEmphasis = "strong"
+	defaultMarkdownHeadingBreakMs  = 300
+)
+
+// validEmphasis 判断 <emphasis> 的级别是否有效，空字符串表示使用默认值，none 表示不强调
+func validEmphasis(level string) bool {
+	switch level {
+	case "", "strong", "moderate", "reduced", "none":
+		return true
+	}
+	return false
+}
+
+// markdownProsody 是 Markdown 结构对应的 SSML 标签，创建处理器时按 ssml.markdown 生成。
+// 标签不在 preserve_tags 中时为空字符串，对应的结构仍只删除标记，避免标签被转义后读出
+type markdownProsody struct {
+	boldOpen, boldClose       string
+	quoteOpen, quoteClose     string
+	headingLevel              int
+	headingOpen, headingClose string
+}
+
+// newMarkdownProsody 按配置生成各结构的标签
+func newMarkdownProsody(cfg MarkdownConfig, p *SSMLProcessor) *markdownProsody {
+	m := &markdownProsody{headingLevel: cfg.HeadingLevel}
+	if m.headingLevel <= 0 {
+		m.headingLevel = defaultMarkdownHeadingLevel
+	}
+	// pair 在开始与结束标签都被保留时返回它们
+	pair := func(open, close string) (string, string) {
+		if p.allowed(open) && p.allowed(close) {
+			return open, close
+		}
+		return "", ""
+	}
+	emphasis := func(level, fallback string) (string, string) {
+		if level == "" {
+			level = fallback
+		}
+		if level == "none" {
+			return "", ""
+		}
+		return pair(`<emphasis level="`+level+`">`, "</emphasis>")
+	}
+
+	m.boldOpen, m.boldClose = emphasis(cfg.Bold, defaultMarkdownBold)
+
+	var attrs string
+	for _, a := range [][3]string{{"rate", cfg.QuoteRate, defaultMarkdownQuoteRate}, {"pitch", cfg.QuotePitch, defaultMarkdownQuotePitch}} {
+		value := a[1]
+		if value == "" {
+			value = a[2]
+		}
+		if value != "0%" {
+			attrs += fmt.Sprintf(` %s="%s"`, a[0], value)
+		}
+	}
+	if attrs != "" {
+		m.quoteOpen, m.quoteClose = pair("<prosody"+attrs+">", "</prosody>")
+	}
+
+	var pause string
+	if ms := cfg.HeadingBreakMs; ms >= 0 {
+		if ms == 0 {
+			ms = defaultMarkdownHeadingBreakMs
+		}
+		if tag := fmt.Sprintf(`<break time="%dms"/>`, ms); p.allowed(tag) {
+			pause = tag
+		}
+	}
+	open, close := emphasis(cfg.HeadingEmphasis, defaultMarkdownHeadingEmphasis)
+	if pause != "" || open != "" {
+		m.headingOpen, m.headingClose = pause+open, close+pause
+	}
+	return m
+}
+
+// markdownEscapable 是可以用反斜杠转义的 Markdown 字符
+const markdownEscapable = "*_`\\[]()>#+-"
+
+// StripMarkdown 清理 Markdown 标记，避免在语音中被朗读，网址按 ssml.urls.mode 处理
+func (p *SSMLProcessor) StripMarkdown(input string) string {
+	return p.StripMarkdownMode(input, "")
+}
+
+// StripMarkdownMode 清理 Markdown 标记，网址、邮箱与裸域名按 urlMode 处理，为空时使用 ssml.urls.mode。
+// 输入按顺序扫描一次，每个位置依次识别代码块与行内代码（整体删除）、行首的标题/列表/引用标记与水平线、
+// 图片（删除）、链接（保留文字）、HTML 链接与图片、URL、邮箱与常见顶级域名的裸域名、反斜杠转义，
+// 剩余的 # * _ ` 符号删除；输出时连续空白合并为一个空格，单个换行保留。
+// 启用 ssml.markdown 时加粗、引用与标题不只删除标记，而是转换为对应的 SSML 标签。
+func (p *SSMLProcessor) StripMarkdownMode(input, urlMode string) string {
+	if input == "" {
+		return ""
+	}
+	defer observePreprocess("markdown", time.Now())
+
+	s := markdownScanner{input: input, urlMode: urlMode, dot: p.config.URLs.Dot, prosody: p.markdown}
+	if s.urlMode == "" {
+		s.urlMode = p.config.URLs.Mode
+	}
+	if s.urlMode == "" {
+		s.urlMode = URLRemove
+	}
+	if s.dot == "" {
+		s.dot = defaultURLDot
+	}
+	s.out.Grow(len(input))
+	s.scan()
+	text := strings.TrimSpace(s.out.String())
+
+	// 生成的 SSML 标签不计入输出，指标只反映删除的 Markdown 标记
+	markdownRemovedBytes.Add(float64(len(input) - len(text) + s.tagBytes))
+	return text
+}
+
+// markdownScanner 是 StripMarkdown 的单遍扫描器
+type markdownScanner struct {
+	input   string
+	urlMode string
+	dot     string
+	out     strings.Builder
+	// skips 是扫描到对应位置时需要跳过的区间：链接保留文字，但要跳过文字之后的 ](url) 或 </a>
+	skips []markdownSkip
+	// space 是尚未输出的连续空白数，lastSpace 是其中最后一个空白字符
+	space     int
+	lastSpace byte
+	// emailFailed、domainFailed 是上次匹配邮箱、裸域名失败时扫描到的位置，之前的单词开头不必再尝试
+	emailFailed  int
+	domainFailed int
+	// prosody 是 Markdown 结构对应的标签，未启用 ssml.markdown 时为 nil；
+	// lineClose 是行尾需要写出的结束标签（引用与标题），heading 表示当前行是转换了的标题
+	prosody   *markdownProsody
+	lineClose string
+	heading   bool
+	tagBytes  int // 已写出的 SSML 标签的字节数
+}
+
+// markdownSkip 表示扫描到 at 时跳到 to，并写出 emit（加粗的结束标签）
+type markdownSkip struct {
+	at, to int
+	emit   string
+}
+
+// scan 扫描整个输入并写出清理后的文本
+func (s *markdownScanner) scan() {
+	in := s.input
+	for i := 0; i < len(in); {
+		if next, ok := s.skip(i); ok {
+			i = next
+			continue
+		}
+		if i == 0 || in[i-1] == '\n' {
+			if next := s.lineStart(i); next != i {
+				i = next
+				continue
+			}
+		}
+
+		c := in[i]
+		switch {
+		case c == '\n' && s.lineClose != "":
+			s.writeTag(s.lineClose)
+			s.lineClose, s.heading = "", false
+		case (c == '*' || c == '_') && s.prosody != nil && s.prosody.boldOpen != "" && !s.heading:
+			if end, ok := matchBold(in, i); ok {
+				s.flushSpace()
+				s.writeTag(s.prosody.boldOpen)
+				s.skips = append(s.skips, markdownSkip{at: end, to: end + 2, emit: s.prosody.boldClose})
+				i += 2
+				continue
+			}
+		case c == '`':
+			i = s.code(i)
+			continue
+		case c == '\\' && i+1 < len(in) && strings.IndexByte(markdownEscapable, in[i+1]) >= 0:
+			s.emit(in[i+1])
+			i += 2
+			continue
+		case c == '!' && i+1 < len(in) && in[i+1] == '[':
+			if end, ok := matchLink(in, i+1, true); ok {
+				i = end
+				continue
+			}
+		case c == '[':
+			if textEnd, end, ok := matchLinkText(in, i); ok {
+				s.skips = append(s.skips, markdownSkip{at: textEnd, to: end})
+				i++
+				continue
+			}
+		case c == '<':
+			if next, ok := s.html(i); ok {
+				i = next
+				continue
+			}
+		case isWordChar(c) && (i == 0 || !isWordChar(in[i-1])):
+			if end, ok := s.address(i); ok {
+				i = end
+				continue
+			}
+		}
+		s.emit(c)
+		i++
+	}
+	s.writeTag(s.lineClose)
+}
+
+// skip 在 i 处有待跳过的区间时返回跳过之后的位置
+func (s *markdownScanner) skip(i int) (int, bool) {
+	for j, sk := range s.skips {
+		if sk.at == i {
+			s.skips = append(s.skips[:j], s.skips[j+1:]...)
+			s.writeTag(sk.emit)
+			return sk.to, true
+		}
+	}
+	return i, false
+}
+
+// emit 写出一个字节：Markdown 符号直接丢弃，空白先计数，遇到下一个非空白字符时再合并写出
+func (s *markdownScanner) emit(c byte) {
+	switch c {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		s.space++
+		s.lastSpace = c
+		return
+	case '#', '*', '_', '`':
+		return
+	}
+	s.flushSpace()
+	s.out.WriteByte(c)
+}
+
+// emitRaw 原样写出保留的网址、域名等，其中的 _ # 等符号不删除
+func (s *markdownScanner) emitRaw(text string) {
+	s.flushSpace()
+	s.out.WriteString(text)
+}
+
+// writeTag 写出 Markdown 结构对应的 SSML 标签，结束标签紧跟在文字之后，之前的空白留到下一个字符时写出
+func (s *markdownScanner) writeTag(tag string) {
+	s.out.WriteString(tag)
+	s.tagBytes += len(tag)
+}
+
+// flushSpace 写出合并后的空白：开头的空白由调用方去掉；单个换行保留，其余空白（包括多个换行）合并为一个空格
+func (s *markdownScanner) flushSpace() {
+	if s.space == 0 {
+		return
+	}
+	if s.out.Len() > 0 {
+		if s.space == 1 && (s.lastSpace == '\n' || s.lastSpace == '\r') {
+			s.out.WriteByte(s.lastSpace)
+		} else {
+			s.out.WriteByte(' ')
+		}
+	}
+	s.space = 0
+}
+
+// url 按网址朗读方式写出 [start, end) 处带协议或 www 的网址
+func (s *markdownScanner) url(start, end int) {
+	switch s.urlMode {
+	case URLDomain:
+		s.speakDomain(s.input[start:end])
+	case URLKeep:
+		s.emitRaw(s.input[start:end])
+	}
+}
+
+// speakDomain 写出网址的域名部分，去掉协议、www.、端口与路径，"." 按配置读出，如 example dot com
+func (s *markdownScanner) speakDomain(url string) {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+	}
+	if i := strings.IndexAny(url, "/?#:"); i >= 0 {
+		url = url[:i]
+	}
+	if hasPrefixFold(url, "www.") {
+		url = url[4:]
+	}
+	url = strings.Trim(url, ".")
+	if url == "" {
+		return
+	}
+	s.emitRaw(strings.ReplaceAll(url, ".", " "+s.dot+" "))
+}
+
+// lineStart 处理行首的水平线、标题、列表与引用标记，返回标记之后的位置，没有标记时返回 i
+func (s *markdownScanner) lineStart(i int) int {
+	in := s.input
+	lineEnd := strings.IndexByte(in[i:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(in)
+	} else {
+		lineEnd += i
+	}
+	if isRule(in[i:lineEnd]) {
+		return lineEnd
+	}
+
+	// 与标记之间的空行一并删除，段落之间只保留一个换行
+	j := skipSpaces(in, i)
+	switch {
+	case j < len(in) && in[j] == '#':
+		k := j
+		for k < len(in) && in[k] == '#' && k-j < 6 {
+			k++
+		}
+		if j-i <= 3 && k < len(in) && isBlank(in[k]) {
+			next := skipBlank(in, k)
+			if p := s.prosody; p != nil && p.headingOpen != "" && k-j <= p.headingLevel && hasText(in, next) {
+				s.flushSpace()
+				s.writeTag(p.headingOpen)
+				s.lineClose = p.headingClose + s.lineClose
+				s.heading = true
+			}
+			return next
+		}
+	case j+1 < len(in) && strings.IndexByte("-*+", in[j]) >= 0 && isBlank(in[j+1]):
+		return skipBlank(in, j+1)
+	case j < len(in) && in[j] == '>':
+		for j < len(in) && in[j] == '>' {
+			j++
+		}
+		if j < len(in) && in[j] == ' ' {
+			j++
+		}
+		if p := s.prosody; p != nil && p.quoteOpen != "" && hasText(in, j) {
+			s.flushSpace()
+			s.writeTag(p.quoteOpen)
+			s.lineClose = p.quoteClose
+		}
+		// 引用中可以嵌套标题与列表
+		if next := s.lineStart(j); next != j {
+			return next
+		}
+		return j
+	}
+	return i
+}
+
+// code 删除从 i 开始的代码块或行内代码，没有闭合的反引号只删除它本身，返回之后的位置
+func (s *markdownScanner) code(i int) int {
+	in := s.input
+	if strings.HasPrefix(in[i:], "```") {
+		if end := strings.Index(in[i+3:], "```"); end >= 0 {
+			return i + 3 + end + 3
+		}
+	}
+	if end := strings.IndexByte(in[i+1:], '`'); end >= 0 {
+		return i + 1 + end + 1
+	}
+	return i + 1
+}
+
+// html 处理从 i 开始的 HTML 链接（保留文字）、图片与自动链接 <https://...>（删除），返回之后的位置
+func (s *markdownScanner) html(i int) (int, bool) {
+	in := s.input
+	rest := in[i+1:]
+	switch {
+	case hasPrefixFold(rest, "http://"), hasPrefixFold(rest, "https://"), hasPrefixFold(rest, "www."):
+		j := i + 1
+		for j < len(in) && in[j] != '>' && !isSpace(in[j]) {
+			j++
+		}
+		if j < len(in) && in[j] == '>' {
+			s.url(i+1, j)
+			return j + 1, true
+		}
+	case hasPrefixFold(rest, "img") && len(rest) > 3 && isSpace(rest[3]):
+		if end := strings.IndexByte(rest, '>'); end >= 0 {
+			return i + 1 + end + 1, true
+		}
+	case hasPrefixFold(rest, "a") && len(rest) > 1 && isSpace(rest[1]):
+		end := strings.IndexByte(rest, '>')
+		if end < 0 || !hasQuotedHref(rest[:end]) {
+			return i, false
+		}
+		open := i + 1 + end + 1
+		closing := indexFold(in[open:], "</a>")
+		if closing < 0 {
+			return i, false
+		}
+		s.skips = append(s.skips, markdownSkip{at: open + closing, to: open + closing + len("</a>")})
+		return open, true
+	}
+	return i, false
+}
+
+// matchBold 匹配从 i 开始、在同一行内闭合的 **text** 或 __text__，返回结束标记的位置。
+// 标记内侧不能是空白，避免把 2 ** 10 这样的表达式当作加粗
+func matchBold(in string, i int) (int, bool) {
+	if i+2 >= len(in) || in[i+1] != in[i] || isSpace(in[i+2]) {
+		return 0, false
+	}
+	marker := in[i : i+2]
+	lineEnd := strings.IndexByte(in[i:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(in)
+	} else {
+		lineEnd += i
+	}
+	end := strings.Index(in[i+3:lineEnd], marker)
+	if end < 0 {
+		return 0, false
+	}
+	end += i + 3
+	if isSpace(in[end-1]) {
+		return 0, false
+	}
+	return end, true
+}
+
+// hasText 判断从 i 到行尾是否有非空白字符
+func hasText(in string, i int) bool {
+	for ; i < len(in) && in[i] != '\n'; i++ {
+		if !isSpace(in[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLink 匹配从 start（'['）开始的 [text](url)，返回结束位置；allowEmpty 为 true 时文字与 URL 可以为空（用于图片）
+func matchLink(in string, start int, allowEmpty bool) (int, bool) {
+	textEnd := strings.IndexByte(in[start+1:], ']')
+	if textEnd < 0 || (textEnd == 0 && !allowEmpty) {
+		return 0, false
+	}
+	textEnd += start + 1
+	if textEnd+1 >= len(in) || in[textEnd+1] != '(' {
+		return 0, false
+	}
+	urlEnd := strings.IndexByte(in[textEnd+2:], ')')
+	if urlEnd < 0 || (urlEnd == 0 && !allowEmpty) {
+		return 0, false
+	}
+	return textEnd + 2 + urlEnd + 1, true
+}
+
+// matchLinkText 匹配从 start 开始的 [text](url)，返回文字结束的位置（']'）与整个链接结束的位置
+func matchLinkText(in string, start int) (int, int, bool) {
+	end, ok := matchLink(in, start, false)
+	if !ok {
+		return 0, 0, false
+	}
+	return start + 1 + strings.IndexByte(in[start+1:], ']'), end, true
+}
+
+// address 匹配从单词开头 start 开始的 URL、邮箱或裸域名，返回结束位置
+func (s *markdownScanner) address(start int) (int, bool) {
+	in := s.input
+	rest := in[start:]
+	if hasPrefixFold(rest, "http://") || hasPrefixFold(rest, "https://") ||
+		hasPrefixFold(rest, "ftp://") || hasPrefixFold(rest, "www.") {
+		i := start
+		for i < len(in) && !isSpace(in[i]) && in[i] != '<' && in[i] != ')' {
+			i++
+		}
+		s.url(start, i)
+		return i, true
+	}
+	// 从同一串字符中更靠后的单词开头匹配一定也会失败，记录失败时扫描到的位置，避免长串 a.b.c... 被反复扫描
+	if start >= s.emailFailed {
+		end, ok := matchEmail(in, start)
+		if ok {
+			s.bare(in[start:end], true)
+			return end, true
+		}
+		s.emailFailed = end
+	}
+	if start >= s.domainFailed {
+		end, ok := matchDomain(in, start)
+		if ok {
+			s.bare(in[start:end], false)
+			return end, true
+		}
+		s.domainFailed = end
+	}
+	return start, false
+}
+
+// bare 按网址朗读方式写出邮箱或裸域名：domain 模式只读域名，text、keep 模式当作普通单词原样保留
+func (s *markdownScanner) bare(text string, email bool) {
+	switch s.urlMode {
+	case URLDomain:
+		if email {
+			text = text[strings.IndexByte(text, '@')+1:]
+		}
+		s.speakDomain(text)
+	case URLText, URLKeep:
+		s.emitRaw(text)
+	}
+}
+
+// matchEmail 匹配 name@example.com 形式的邮箱，失败时返回扫描停止的位置
+func matchEmail(in string, start int) (int, bool) {
+	i := start
+	for i < len(in) && (isWordChar(in[i]) || in[i] == '.' || in[i] == '+' || in[i] == '-') {
+		i++
+	}
+	if i >= len(in) || in[i] != '@' {
+		return i, false
+	}
+	labels := 0
+	for j := i + 1; ; j++ {
+		labelStart := j
+		for j < len(in) && (isWordChar(in[j]) || in[j] == '-') {
+			j++
+		}
+		if j == labelStart {
+			return i, false
+		}
+		labels++
+		i = j
+		if j+1 >= len(in) || in[j] != '.' || !(isWordChar(in[j+1]) || in[j+1] == '-') {
+			break
+		}
+	}
+	return i, labels >= 2
+}
+
+// matchDomain 匹配以常见顶级域名结尾的裸域名及其后的路径，如 example.com/docs，失败时返回扫描停止的位置
+func matchDomain(in string, start int) (int, bool) {
+	end := 0
+	i := start
+	for labels := 0; ; labels++ {
+		labelStart := i
+		for i < len(in) && (isAlnum(in[i]) || in[i] == '-') {
+			i++
+		}
+		if i == labelStart {
+			break
+		}
+		if labels > 0 && markdownTLDs[strings.ToLower(in[labelStart:i])] {
+			end = i
+		}
+		if i+1 >= len(in) || in[i] != '.' {
+			break
+		}
+		i++
+	}
+	if end == 0 {
+		return i, false
+	}
+	if end < len(in) && in[end] == '/' {
+		for end < len(in) && !isSpace(in[end]) {
+			end++
+		}
+	}
+	return end, true
+}
+
+// isRule 判断一行是否是水平线 --- *** ___
+func isRule(line string) bool {
+	line = strings.TrimSpace(line)
+	if len(line) < 3 {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line) && strings.IndexByte("-*_", line[0]) >= 0
+}
+
+// hasQuotedHref 判断 HTML 标签中是否有加引号的 href 属性
+func hasQuotedHref(tag string) bool {
+	i := indexFold(tag, "href=")
+	return i >= 0 && i+5 < len(tag) && (tag[i+5] == '"' || tag[i+5] == '\'')
+}
+
+// hasPrefixFold 不区分 ASCII 大小写判断前缀
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// indexFold 不区分 ASCII 大小写查找子串
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// skipSpaces 跳过包括换行在内的空白
+func skipSpaces(in string, i int) int {
+	for i < len(in) && isSpace(in[i]) {
+		i++
+	}
+	return i
+}
+
+// skipBlank 跳过空格与制表符
+func skipBlank(in string, i int) int {
+	for i < len(in) && isBlank(in[i]) {
+		i++
+	}
+	return i
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isWordChar(c byte) bool {
+	return isAlnum(c) || c == '_'
+}
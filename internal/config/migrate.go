@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// CurrentVersion 是当前的配置文件格式版本
+const CurrentVersion = 2
+
+// migration 将 from 版本的配置升级到下一版本，返回需要提示用户的旧配置项
+type migration struct {
+	from  int
+	apply func(v *viper.Viper) []string
+}
+
+// migrations 按版本排列，加载旧版本的配置时依次执行
+var migrations = []migration{
+	{from: 1, apply: structuredVoiceMapping},
+}
+
+// migrate 将旧版本的配置迁移到当前版本，只修改内存中的配置，不改写配置文件。
+// 版本高于 CurrentVersion 时返回错误，避免旧程序忽略新版本配置中的设置
+func migrate(v *viper.Viper) error {
+	version := v.GetInt("version")
+	if version == 0 {
+		if len(v.AllKeys()) == 0 {
+			return nil
+		}
+		version = 1
+	}
+	if version > CurrentVersion {
+		return fmt.Errorf("配置文件版本 %d 高于程序支持的版本 %d，请升级程序", version, CurrentVersion)
+	}
+	if version == CurrentVersion {
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.from < version {
+			continue
+		}
+		for _, deprecated := range m.apply(v) {
+			log.Printf("配置已过时: %s", deprecated)
+		}
+	}
+	log.Printf("配置文件为版本 %d，已按版本 %d 加载，请按提示修改后设置 version: %d", version, CurrentVersion, CurrentVersion)
+	v.Set("version", CurrentVersion)
+	return nil
+}
+
+// structuredVoiceMapping 将版本 1 中 tts.voice_mapping 的 名称: 语音 改为 名称: {voice: 语音}
+func structuredVoiceMapping(v *viper.Viper) []string {
+	mapping, ok := v.Get("tts.voice_mapping").(map[string]any)
+	if !ok {
+		return nil
+	}
+	var deprecated []string
+	converted := make(map[string]any, len(mapping))
+	for name, value := range mapping {
+		if voice, ok := value.(string); ok {
+			value = map[string]any{"voice": voice}
+			deprecated = append(deprecated, fmt.Sprintf("tts.voice_mapping.%s 应改为 {voice: %q}", name, voice))
+		}
+		converted[name] = value
+	}
+	if len(deprecated) > 0 {
+		v.Set("tts.voice_mapping", converted)
+	}
+	sort.Strings(deprecated)
+	return deprecated
+}
+
+// warnUnused 记录配置文件中无法识别的配置项
+func warnUnused(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+	log.Printf("配置文件中的以下配置项无法识别，已忽略: %s", strings.Join(keys, ", "))
+}
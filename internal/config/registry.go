@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// tenantKey 是上下文中保存租户（API 密钥名称）的键
+type tenantKey struct{}
+
+// WithTenant 返回携带租户名称的上下文，合成时按租户选择 SSML 处理器
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext 返回上下文中的租户名称，没有时返回空字符串
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// ProcessorRegistry 按租户提供 SSML 处理器，重新加载时整体原子替换，读取不加锁
+type ProcessorRegistry struct {
+	current atomic.Pointer[processorSet]
+}
+
+// processorSet 是某个配置版本编译出的全部处理器，创建后不再修改
+type processorSet struct {
+	version uint64
+	base    *SSMLProcessor
+	tenants map[string]*SSMLProcessor // 在 keys 中单独设置了 ssml 的密钥，按名称索引
+}
+
+var (
+	// processorVersion 是最近一次编译的配置版本
+	processorVersion atomic.Uint64
+
+	registriesMu sync.Mutex
+	registries   []*ProcessorRegistry
+)
+
+// NewProcessorRegistry 按配置创建处理器注册表，之后由 ReloadProcessors 统一重新加载
+func NewProcessorRegistry(cfg *Config) (*ProcessorRegistry, error) {
+	set, err := buildProcessors(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &ProcessorRegistry{}
+	r.current.Store(set)
+
+	registriesMu.Lock()
+	registries = append(registries, r)
+	registriesMu.Unlock()
+	return r, nil
+}
+
+// ReloadProcessors 按新配置重新编译处理器并替换所有注册表中的处理器，返回新的版本号。
+// 编译失败时保留原有的处理器；进行中的请求继续使用替换前取得的处理器。
+func ReloadProcessors(cfg *Config) (uint64, error) {
+	set, err := buildProcessors(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+	for _, r := range registries {
+		r.current.Store(set)
+	}
+	return set.version, nil
+}
+
+// Get 返回租户的处理器，租户没有单独设置 ssml 时返回全局处理器
+func (r *ProcessorRegistry) Get(tenant string) *SSMLProcessor {
+	set := r.current.Load()
+	if p, ok := set.tenants[tenant]; ok {
+		return p
+	}
+	return set.base
+}
+
+// Version 返回当前处理器对应的配置版本
+func (r *ProcessorRegistry) Version() uint64 {
+	return r.current.Load().version
+}
+
+// buildProcessors 编译全局处理器与各密钥的处理器
+func buildProcessors(cfg *Config) (*processorSet, error) {
+	base, err := NewSSMLProcessor(&cfg.SSML)
+	if err != nil {
+		return nil, err
+	}
+	set := &processorSet{base: base, tenants: make(map[string]*SSMLProcessor)}
+	for i, key := range cfg.Keys {
+		if key.SSML == nil {
+			continue
+		}
+		if key.Name == "" {
+			return nil, fmt.Errorf("keys 第 %d 项设置了 ssml，需要同时设置 name", i+1)
+		}
+		tenantCfg := cfg.SSML
+		if len(key.SSML.PreserveTags) > 0 {
+			tenantCfg.PreserveTags = key.SSML.PreserveTags
+		}
+		if key.SSML.URLMode != "" {
+			tenantCfg.URLs.Mode = key.SSML.URLMode
+		}
+		processor, err := NewSSMLProcessor(&tenantCfg)
+		if err != nil {
+			return nil, fmt.Errorf("密钥 %s 的 ssml 配置无效: %w", key.Name, err)
+		}
+		set.tenants[key.Name] = processor
+	}
+	set.version = processorVersion.Add(1)
+	return set, nil
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tts/internal/metrics"
+)
+
+const (
+	defaultRemoteTimeout = 10 * time.Second
+	// maxRemoteBytes 是远程配置文档的大小上限
+	maxRemoteBytes = 4 << 20
+)
+
+var remoteFetchTotal = metrics.NewCounter("tts_remote_config_fetch_total",
+	"读取远程配置的次数，result 为 ok、failed 或 cached（远程来源不可用时使用了本地缓存）", "result")
+
+// remoteClient 是读取远程配置使用的客户端，超时由每次请求的 context 控制
+var remoteClient = &http.Client{}
+
+// fetchRemote 从 rc.URL 读取远程配置文档。
+// http/https 直接 GET；consul 读取 KV 中键的原始值；etcd 通过 v3 的 JSON 网关读取键的值。
+// consul+https、etcd+https 使用 HTTPS 访问
+func fetchRemote(ctx context.Context, rc RemoteConfig) ([]byte, error) {
+	u, err := url.Parse(rc.URL)
+	if err != nil {
+		return nil, fmt.Errorf("remote.url 无效: %w", err)
+	}
+	timeout := defaultRemoteTimeout
+	if rc.Timeout > 0 {
+		timeout = time.Duration(rc.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	kind, scheme, _ := strings.Cut(u.Scheme, "+")
+	if scheme == "" {
+		scheme = "http"
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+
+	var req *http.Request
+	switch kind {
+	case "http", "https":
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, rc.URL, nil); err != nil {
+			return nil, err
+		}
+		if rc.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+rc.Token)
+		}
+	case "consul":
+		endpoint := fmt.Sprintf("%s://%s/v1/kv/%s?raw", scheme, u.Host, key)
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil); err != nil {
+			return nil, err
+		}
+		if rc.Token != "" {
+			req.Header.Set("X-Consul-Token", rc.Token)
+		}
+	case "etcd":
+		body, _ := json.Marshal(map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(key))})
+		endpoint := fmt.Sprintf("%s://%s/v3/kv/range", scheme, u.Host)
+		if req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body)); err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+	default:
+		return nil, fmt.Errorf("remote.url 不支持的协议: %s", u.Scheme)
+	}
+
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("读取远程配置失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取远程配置失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("读取远程配置失败: HTTP %d", resp.StatusCode)
+	}
+	if len(data) > maxRemoteBytes {
+		return nil, fmt.Errorf("远程配置超过 %d 字节", maxRemoteBytes)
+	}
+	if kind != "etcd" {
+		return data, nil
+	}
+
+	var result struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析 etcd 响应失败: %w", err)
+	}
+	if len(result.Kvs) == 0 {
+		return nil, fmt.Errorf("etcd 中不存在键 %s", key)
+	}
+	return base64.StdEncoding.DecodeString(result.Kvs[0].Value)
+}
+
+// loadRemote 读取远程配置并更新本地缓存，远程来源不可用时使用 rc.Cache 中最近一次读取的结果
+func loadRemote(rc RemoteConfig) ([]byte, error) {
+	data, err := fetchRemote(context.Background(), rc)
+	if err == nil {
+		remoteFetchTotal.Inc("ok")
+		if rc.Cache != "" {
+			writeRemoteCache(rc.Cache, data)
+		}
+		return data, nil
+	}
+	if rc.Cache == "" {
+		remoteFetchTotal.Inc("failed")
+		return nil, err
+	}
+	cached, cacheErr := os.ReadFile(rc.Cache)
+	if cacheErr != nil {
+		remoteFetchTotal.Inc("failed")
+		return nil, err
+	}
+	remoteFetchTotal.Inc("cached")
+	log.Printf("%v，使用缓存的远程配置 %s", err, rc.Cache)
+	return cached, nil
+}
+
+// writeRemoteCache 内容变化时写入缓存文件，先写入临时文件再改名，避免留下不完整的缓存
+func writeRemoteCache(path string, data []byte) {
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, data) {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".remote-*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), path)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		log.Printf("保存远程配置缓存失败: %v", err)
+	}
+}
+
+// WatchRemote 按 remote.interval 轮询远程配置，内容变化时调用 changed，直到 ctx 结束。
+// 未配置远程来源或轮询间隔时立即返回
+func WatchRemote(ctx context.Context, rc RemoteConfig, changed func()) {
+	if rc.URL == "" || rc.Interval <= 0 {
+		return
+	}
+	var last [sha256.Size]byte
+	if data, err := fetchRemote(ctx, rc); err == nil {
+		last = sha256.Sum256(data)
+	}
+	ticker := time.NewTicker(time.Duration(rc.Interval) * time.Second)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		data, err := fetchRemote(ctx, rc)
+		if err != nil {
+			remoteFetchTotal.Inc("failed")
+			if !failing {
+				log.Printf("%v，保留当前配置", err)
+				failing = true
+			}
+			continue
+		}
+		remoteFetchTotal.Inc("ok")
+		if failing {
+			log.Printf("远程配置已恢复访问")
+			failing = false
+		}
+		if sum := sha256.Sum256(data); sum != last {
+			last = sum
+			log.Printf("远程配置已变化，重新加载")
+			changed()
+		}
+	}
+}
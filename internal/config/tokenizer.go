@@ -0,0 +1,106 @@
+package config
+
+// tagKind 表示标签的类型
+type tagKind int
+
+const (
+	tagOpen        tagKind = iota // <name ...>
+	tagClose                      // </name>
+	tagSelfClosing                // <name .../>
+)
+
+// scannedTag 是词法扫描得到的一个标签
+type scannedTag struct {
+	name string
+	kind tagKind
+	end  int // 标签结束后的位置
+}
+
+// scanTag 从 input[start]（必须为 '<'）开始按 XML 词法读取一个标签。
+// 属性值必须加引号且不能包含 '<'；引号内的 '>' 不会提前结束标签。
+// 无法构成完整标签时返回 false，调用方应把 '<' 当作普通文本。
+func scanTag(input string, start int) (scannedTag, bool) {
+	i := start + 1
+	kind := tagOpen
+	if i < len(input) && input[i] == '/' {
+		kind = tagClose
+		i++
+	}
+
+	nameStart := i
+	if i >= len(input) || !isNameStart(input[i]) {
+		return scannedTag{}, false
+	}
+	for i < len(input) && isNameChar(input[i]) {
+		i++
+	}
+	name := input[nameStart:i]
+
+	if kind == tagClose {
+		i = skipSpace(input, i)
+		if i < len(input) && input[i] == '>' {
+			return scannedTag{name: name, kind: tagClose, end: i + 1}, true
+		}
+		return scannedTag{}, false
+	}
+
+	for {
+		afterName := i
+		i = skipSpace(input, i)
+		if i >= len(input) {
+			return scannedTag{}, false
+		}
+		switch {
+		case input[i] == '>':
+			return scannedTag{name: name, kind: tagOpen, end: i + 1}, true
+		case input[i] == '/' && i+1 < len(input) && input[i+1] == '>':
+			return scannedTag{name: name, kind: tagSelfClosing, end: i + 2}, true
+		}
+
+		// 属性之间必须以空白分隔
+		if i == afterName || !isNameStart(input[i]) {
+			return scannedTag{}, false
+		}
+		for i < len(input) && isNameChar(input[i]) {
+			i++
+		}
+		i = skipSpace(input, i)
+		if i >= len(input) || input[i] != '=' {
+			return scannedTag{}, false
+		}
+		i = skipSpace(input, i+1)
+		if i >= len(input) || (input[i] != '"' && input[i] != '\'') {
+			return scannedTag{}, false
+		}
+		quote := input[i]
+		i++
+		for i < len(input) && input[i] != quote {
+			if input[i] == '<' {
+				return scannedTag{}, false
+			}
+			i++
+		}
+		if i >= len(input) {
+			return scannedTag{}, false
+		}
+		i++
+	}
+}
+
+// skipSpace 跳过空白字符
+func skipSpace(input string, i int) int {
+	for i < len(input) && (input[i] == ' ' || input[i] == '\t' || input[i] == '\n' || input[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// isNameStart 判断字符能否作为标签或属性名的开头
+func isNameStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// isNameChar 判断字符能否出现在标签或属性名中
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9' || c == '-' || c == '.' || c == ':'
+}
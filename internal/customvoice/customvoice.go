@@ -0,0 +1,89 @@
+// Package customvoice 按租户（API 密钥名称）保存用参考音频创建的自定义语音，保存在持久化存储中。
+// 自定义语音的名称只在所属租户内有效，合成时替换为上游服务中的语音 ID。
+package customvoice
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tts/internal/store"
+)
+
+// bucket 是保存自定义语音的存储桶，键为租户名称
+const bucket = "custom_voices"
+
+// Voice 是一个自定义语音
+type Voice struct {
+	Name        string    `json:"name"`
+	Provider    string    `json:"provider"` // 创建语音的服务，语音只能用于该服务
+	VoiceID     string    `json:"voice_id"` // 上游服务中的语音 ID
+	Language    string    `json:"language,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// Registry 读写各租户的自定义语音
+type Registry struct {
+	mu    sync.Mutex // 保护读取后写回的更新
+	store *store.Store
+}
+
+// New 创建自定义语音记录
+func New(st *store.Store) *Registry {
+	return &Registry{store: st}
+}
+
+// List 返回租户的自定义语音，按名称排序
+func (r *Registry) List(tenant string) []Voice {
+	var voices []Voice
+	if found, err := r.store.Get(bucket, tenant, &voices); err != nil || !found {
+		return []Voice{}
+	}
+	sort.Slice(voices, func(i, j int) bool { return voices[i].Name < voices[j].Name })
+	return voices
+}
+
+// Lookup 按名称（不区分大小写）查找租户的自定义语音
+func (r *Registry) Lookup(tenant, name string) (Voice, bool) {
+	if name == "" {
+		return Voice{}, false
+	}
+	for _, voice := range r.List(tenant) {
+		if strings.EqualFold(voice.Name, name) {
+			return voice, true
+		}
+	}
+	return Voice{}, false
+}
+
+// Add 保存自定义语音，同名的语音已存在时返回 false
+func (r *Registry) Add(tenant string, voice Voice) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	voices := r.List(tenant)
+	for _, existing := range voices {
+		if strings.EqualFold(existing.Name, voice.Name) {
+			return false, nil
+		}
+	}
+	return true, r.store.Put(bucket, tenant, append(voices, voice))
+}
+
+// Remove 删除租户的自定义语音，返回被删除的语音，不存在时返回 false
+func (r *Registry) Remove(tenant, name string) (Voice, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	voices := r.List(tenant)
+	for i, voice := range voices {
+		if strings.EqualFold(voice.Name, name) {
+			voices = append(voices[:i], voices[i+1:]...)
+			if len(voices) == 0 {
+				return voice, true, r.store.Delete(bucket, tenant)
+			}
+			return voice, true, r.store.Put(bucket, tenant, voices)
+		}
+	}
+	return Voice{}, false, nil
+}
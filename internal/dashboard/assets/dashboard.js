@@ -0,0 +1,204 @@
+// 管理面板：用浏览器中保存的管理令牌定期读取管理接口，页面与接口使用相对路径，兼容 server.base_path
+(function () {
+    const TOKEN_KEY = 'tts-admin-token';
+    const REFRESH_MS = 5000;
+
+    let timer = null;
+    let previous = null; // 上一次读取的累计请求数与时间，用于计算请求速率
+
+    const $ = (id) => document.getElementById(id);
+
+    // api 以管理令牌请求 /admin 下的接口，路径相对于当前页面 /admin/ui/
+    async function api(path) {
+        const resp = await fetch('../' + path, {
+            headers: {'Authorization': 'Bearer ' + sessionStorage.getItem(TOKEN_KEY)},
+        });
+        if (resp.status === 401) {
+            throw new Error('令牌无效');
+        }
+        if (!resp.ok) {
+            throw new Error(path + ' 返回 ' + resp.status);
+        }
+        return resp.json();
+    }
+
+    // fill 用 rows 替换表格内容，单元格为文本或 {text, cls}，不解析 HTML
+    function fill(id, rows, columns) {
+        const body = $(id);
+        body.replaceChildren();
+        if (rows.length === 0) {
+            const tr = body.insertRow();
+            const td = tr.insertCell();
+            td.colSpan = columns;
+            td.className = 'muted';
+            td.textContent = '暂无数据';
+            return;
+        }
+        for (const cells of rows) {
+            const tr = body.insertRow();
+            for (const cell of cells) {
+                const td = tr.insertCell();
+                if (cell !== null && typeof cell === 'object') {
+                    td.textContent = cell.text;
+                    td.className = cell.cls || '';
+                } else {
+                    td.textContent = cell === undefined || cell === null ? '' : String(cell);
+                }
+                td.title = td.textContent;
+            }
+        }
+    }
+
+    function duration(seconds) {
+        const d = Math.floor(seconds / 86400);
+        const h = Math.floor(seconds % 86400 / 3600);
+        const m = Math.floor(seconds % 3600 / 60);
+        return (d ? d + '天 ' : '') + (d || h ? h + '小时 ' : '') + m + '分';
+    }
+
+    function time(value) {
+        return value ? new Date(value).toLocaleString() : '';
+    }
+
+    function number(value) {
+        return Math.round(value).toLocaleString();
+    }
+
+    function stateClass(state) {
+        return {healthy: 'ok', succeeded: 'ok', success: 'ok', running: 'ok', degraded: 'warn', queued: 'warn', unhealthy: 'bad', failed: 'bad'}[state] || '';
+    }
+
+    function renderStats(stats) {
+        $('uptime').textContent = duration(stats.uptime_seconds);
+        $('requests').textContent = number(stats.requests.total);
+        $('errors').textContent = number(stats.requests.server_errors);
+        const now = Date.now();
+        if (previous && now > previous.at) {
+            const rate = (stats.requests.total - previous.total) * 1000 / (now - previous.at);
+            $('rps').textContent = rate.toFixed(2) + '/s';
+        }
+        previous = {total: stats.requests.total, at: now};
+
+        const lookups = stats.cache.hits + stats.cache.misses;
+        $('hit-rate').textContent = lookups ? (stats.cache.hits * 100 / lookups).toFixed(1) + '%' : '-';
+        $('cache-entries').textContent = number(stats.cache.entries);
+
+        fill('providers', stats.providers.map((p) => [
+            p.name,
+            {text: p.state, cls: stateClass(p.state)},
+            p.region,
+            p.requests,
+            (p.error_rate * 100).toFixed(1) + '%',
+            p.throttled,
+            p.p50_latency_ms + 'ms',
+            p.p95_latency_ms + 'ms',
+            p.last_error ? time(p.last_error_at) + ' ' + p.last_error : '',
+        ]), 9);
+
+        const usage = stats.usage.slice().sort((a, b) => b.characters - a.characters);
+        fill('usage', usage.map((u) => [u.key, number(u.characters)]), 2);
+
+        fill('pools', stats.pools.map((p) => [
+            p.name,
+            p.limit === p.effective ? (p.limit || '不限') : p.effective + ' / ' + p.limit,
+            p.active,
+            p.waiting,
+        ]), 4);
+
+        if (stats.jobs) {
+            $('job-counts').textContent = Object.entries(stats.jobs).map(([k, v]) => k + ' ' + v).join(' · ');
+        } else {
+            $('job-counts').textContent = '未启用';
+        }
+    }
+
+    function renderJobs(data) {
+        fill('jobs', data.jobs.map((j) => [
+            j.id,
+            j.tenant || 'anonymous',
+            {text: j.status, cls: stateClass(j.status)},
+            j.priority,
+            j.voice,
+            j.segments ? (j.completed_segments || 0) + ' / ' + j.segments : '',
+            time(j.created),
+            j.error,
+        ]), 8);
+    }
+
+    function renderSchedules(data) {
+        fill('schedules', data.jobs.map((j) => [
+            j.name,
+            j.cron,
+            j.running ? '执行中' : time(j.next_run),
+            j.last_run ? {text: time(j.last_run.finished) + ' ' + j.last_run.status, cls: stateClass(j.last_run.status)} : '',
+        ]), 4);
+    }
+
+    function renderRequests(data) {
+        fill('recent', data.requests.map((r) => [
+            time(r.time),
+            r.method,
+            r.path,
+            {text: r.status, cls: r.status >= 500 ? 'bad' : r.status >= 400 ? 'warn' : ''},
+            r.duration_ms + 'ms',
+            r.key,
+            r.client_ip,
+        ]), 7);
+    }
+
+    async function refresh() {
+        try {
+            const [stats, jobs, schedules, requests] = await Promise.all([
+                api('stats'), api('async-jobs?limit=20'), api('jobs'), api('requests?limit=50'),
+            ]);
+            renderStats(stats);
+            renderJobs(jobs);
+            renderSchedules(schedules);
+            renderRequests(requests);
+            $('status').textContent = '更新于 ' + new Date().toLocaleTimeString();
+        } catch (err) {
+            $('status').textContent = err.message;
+            if (err.message === '令牌无效') {
+                disconnect();
+            }
+        }
+    }
+
+    function connect() {
+        $('panels').hidden = false;
+        $('logout').hidden = false;
+        $('token').hidden = true;
+        $('connect').hidden = true;
+        previous = null;
+        refresh();
+        timer = setInterval(refresh, REFRESH_MS);
+    }
+
+    function disconnect() {
+        clearInterval(timer);
+        sessionStorage.removeItem(TOKEN_KEY);
+        $('panels').hidden = true;
+        $('logout').hidden = true;
+        $('token').hidden = false;
+        $('connect').hidden = false;
+    }
+
+    $('login').addEventListener('submit', (event) => {
+        event.preventDefault();
+        const token = $('token').value.trim();
+        if (!token) {
+            return;
+        }
+        sessionStorage.setItem(TOKEN_KEY, token);
+        $('token').value = '';
+        connect();
+    });
+    $('logout').addEventListener('click', () => {
+        disconnect();
+        $('status').textContent = '';
+    });
+
+    if (sessionStorage.getItem(TOKEN_KEY)) {
+        connect();
+    }
+})();
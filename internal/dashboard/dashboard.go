@@ -0,0 +1,27 @@
+// Package dashboard 提供编译进二进制的管理页面，页面通过管理接口读取运行状态，不需要额外部署静态文件
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// Handler 返回管理页面的静态文件，路由参数 filepath 为文件路径，"/" 对应 index.html。
+// 页面本身不包含任何数据，由浏览器中输入的管理令牌调用管理接口获取，因此页面不需要认证
+func Handler() gin.HandlerFunc {
+	files, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	root := http.FS(files)
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+		c.FileFromFS(c.Param("filepath"), root)
+	}
+}
@@ -0,0 +1,588 @@
+// Package discord 提供内置的 Discord 机器人：加入语音频道，把绑定的文字频道中的消息合成后在语音频道中朗读，
+// 每个服务器的语音设置保存在持久化存储中。
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/store"
+	"tts/internal/utils"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// defaultAPIURL 与 defaultGatewayURL 是 Discord 的默认地址
+	defaultAPIURL     = "https://discord.com/api/v10"
+	defaultGatewayURL = "wss://gateway.discord.gg"
+	// defaultPrefix 是默认的命令前缀
+	defaultPrefix = "!tts"
+	// settingsBucket 是保存服务器设置的存储桶
+	settingsBucket = "discord_guilds"
+	// queueSize 是每个服务器最多排队朗读的消息数
+	queueSize = 20
+	// reconnectDelay 是网关断开后重新连接的等待时间
+	reconnectDelay = 5 * time.Second
+)
+
+// helpText 是 help 命令的回复，%s 为命令前缀
+const helpText = "先加入一个语音频道，然后在文字频道中发送 `%[1]s join`，之后这个频道中的消息会在语音频道中朗读。\n\n" +
+	"`%[1]s join` - 加入你所在的语音频道并朗读当前文字频道\n" +
+	"`%[1]s leave` - 离开语音频道\n" +
+	"`%[1]s skip` - 跳过正在朗读的消息\n" +
+	"`%[1]s voice [名称]` - 查看或设置语音，如 `%[1]s voice zh-CN-YunxiNeural`\n" +
+	"`%[1]s rate [-100..100]` - 查看或设置语速"
+
+var (
+	// customEmoji 匹配自定义表情 <:name:id>，朗读时只读名称
+	customEmoji = regexp.MustCompile(`<a?:(\w+):\d+>`)
+	// mention 匹配用户、角色与频道的提及，朗读时忽略
+	mention = regexp.MustCompile(`<(?:@[!&]?|#)\d+>`)
+)
+
+// GuildSettings 是单个服务器的设置，VoiceChannel 非空时机器人重启后会重新加入
+type GuildSettings struct {
+	Voice        string `json:"voice"`
+	Rate         string `json:"rate"`
+	TextChannel  string `json:"text_channel"`
+	VoiceChannel string `json:"voice_channel"`
+}
+
+// Bot 是 Discord 机器人
+type Bot struct {
+	synthesizer *ttspkg.Synthesizer
+	store       *store.Store
+	config      *config.Config
+	client      *http.Client
+	apiURL      string
+	gatewayURL  string
+	prefix      string
+	allowed     map[string]bool
+
+	mu      sync.Mutex
+	gateway *gatewayConn
+	userID  string
+	guilds  map[string]*guild
+}
+
+// guild 是单个服务器的运行状态
+type guild struct {
+	id string
+
+	// 以下字段由 Bot.mu 保护
+	voiceStates map[string]string // 用户ID -> 所在语音频道ID
+	sessionID   string
+	token       string
+	endpoint    string
+	conn        *voiceConn
+	skip        context.CancelFunc
+
+	queue chan speech
+}
+
+// speech 是一条排队朗读的消息
+type speech struct {
+	text    string
+	channel string
+}
+
+// message 是 MESSAGE_CREATE 事件中用到的字段
+type message struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	Content   string `json:"content"`
+	Author    struct {
+		ID         string `json:"id"`
+		Username   string `json:"username"`
+		GlobalName string `json:"global_name"`
+		Bot        bool   `json:"bot"`
+	} `json:"author"`
+	Member *struct {
+		Nick string `json:"nick"`
+	} `json:"member"`
+}
+
+// voiceState 是 VOICE_STATE_UPDATE 事件与 GUILD_CREATE 中的语音状态
+type voiceState struct {
+	GuildID   string  `json:"guild_id"`
+	ChannelID *string `json:"channel_id"`
+	UserID    string  `json:"user_id"`
+	SessionID string  `json:"session_id"`
+}
+
+// New 创建 Discord 机器人
+func New(synthesizer *ttspkg.Synthesizer, st *store.Store, cfg *config.Config) *Bot {
+	apiURL := strings.TrimRight(cfg.Discord.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	gatewayURL := strings.TrimRight(cfg.Discord.GatewayURL, "/")
+	if gatewayURL == "" {
+		gatewayURL = defaultGatewayURL
+	}
+	prefix := strings.TrimSpace(cfg.Discord.Prefix)
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	allowed := make(map[string]bool, len(cfg.Discord.AllowedGuilds))
+	for _, id := range cfg.Discord.AllowedGuilds {
+		allowed[id] = true
+	}
+	return &Bot{
+		synthesizer: synthesizer,
+		store:       st,
+		config:      cfg,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		apiURL:      apiURL,
+		gatewayURL:  gatewayURL,
+		prefix:      prefix,
+		allowed:     allowed,
+		guilds:      make(map[string]*guild),
+	}
+}
+
+// Run 连接网关并处理事件，断开后自动重连，直到 ctx 结束
+func (b *Bot) Run(ctx context.Context) {
+	log.Printf("Discord 机器人已启动")
+	for ctx.Err() == nil {
+		if err := b.connect(ctx); err != nil {
+			b.logGateway(err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(reconnectDelay):
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, g := range b.guilds {
+		if g.conn != nil {
+			g.conn.close()
+		}
+	}
+}
+
+// dispatch 处理网关事件
+func (b *Bot) dispatch(ctx context.Context, event string, data json.RawMessage) {
+	switch event {
+	case "READY":
+		var ready struct {
+			User struct {
+				ID       string `json:"id"`
+				Username string `json:"username"`
+			} `json:"user"`
+		}
+		if json.Unmarshal(data, &ready) == nil {
+			b.mu.Lock()
+			b.userID = ready.User.ID
+			b.mu.Unlock()
+			log.Printf("Discord 机器人已登录: %s", ready.User.Username)
+		}
+	case "GUILD_CREATE":
+		var created struct {
+			ID          string       `json:"id"`
+			Unavailable bool         `json:"unavailable"`
+			VoiceStates []voiceState `json:"voice_states"`
+		}
+		if json.Unmarshal(data, &created) != nil || created.Unavailable || !b.allowedGuild(created.ID) {
+			return
+		}
+		g := b.guild(ctx, created.ID)
+		b.mu.Lock()
+		g.voiceStates = make(map[string]string, len(created.VoiceStates))
+		for _, state := range created.VoiceStates {
+			if state.ChannelID != nil {
+				g.voiceStates[state.UserID] = *state.ChannelID
+			}
+		}
+		connected := g.conn != nil
+		b.mu.Unlock()
+		// 重启或重连后回到之前所在的语音频道
+		if settings := b.settings(created.ID); settings.VoiceChannel != "" && !connected {
+			if err := b.updateVoiceState(created.ID, settings.VoiceChannel); err != nil {
+				log.Printf("Discord 重新加入语音频道失败: %v", err)
+			}
+		}
+	case "VOICE_STATE_UPDATE":
+		var state voiceState
+		if json.Unmarshal(data, &state) != nil || !b.allowedGuild(state.GuildID) {
+			return
+		}
+		g := b.guild(ctx, state.GuildID)
+		b.mu.Lock()
+		if state.ChannelID != nil {
+			g.voiceStates[state.UserID] = *state.ChannelID
+		} else {
+			delete(g.voiceStates, state.UserID)
+		}
+		self := state.UserID == b.userID
+		if self {
+			g.sessionID = state.SessionID
+		}
+		b.mu.Unlock()
+		if self && state.ChannelID == nil {
+			// 被移出语音频道
+			b.disconnect(g)
+		}
+	case "VOICE_SERVER_UPDATE":
+		var server struct {
+			GuildID  string  `json:"guild_id"`
+			Token    string  `json:"token"`
+			Endpoint *string `json:"endpoint"`
+		}
+		if json.Unmarshal(data, &server) != nil || !b.allowedGuild(server.GuildID) {
+			return
+		}
+		g := b.guild(ctx, server.GuildID)
+		endpoint := ""
+		if server.Endpoint != nil {
+			endpoint = *server.Endpoint
+		}
+		b.mu.Lock()
+		g.token = server.Token
+		g.endpoint = endpoint
+		b.mu.Unlock()
+		// 语音服务器变化时需要重新连接，endpoint 为空表示服务器暂不可用，稍后会再次收到
+		if endpoint != "" {
+			go b.connectVoice(ctx, g)
+		}
+	case "MESSAGE_CREATE":
+		var msg message
+		if json.Unmarshal(data, &msg) != nil || msg.GuildID == "" || msg.Author.Bot {
+			return
+		}
+		if !b.allowedGuild(msg.GuildID) {
+			return
+		}
+		b.handle(ctx, b.guild(ctx, msg.GuildID), &msg)
+	}
+}
+
+// allowedGuild 判断服务器是否允许使用机器人
+func (b *Bot) allowedGuild(id string) bool {
+	return len(b.allowed) == 0 || b.allowed[id]
+}
+
+// guild 返回服务器的运行状态，首次使用时创建并启动朗读队列
+func (b *Bot) guild(ctx context.Context, id string) *guild {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g, ok := b.guilds[id]
+	if !ok {
+		g = &guild{id: id, voiceStates: make(map[string]string), queue: make(chan speech, queueSize)}
+		b.guilds[id] = g
+		go b.speakLoop(ctx, g)
+	}
+	return g
+}
+
+// connectVoice 使用最新的会话与语音服务器信息建立语音连接，替换已有的连接
+func (b *Bot) connectVoice(ctx context.Context, g *guild) {
+	b.mu.Lock()
+	userID, sessionID, token, endpoint := b.userID, g.sessionID, g.token, g.endpoint
+	old := g.conn
+	g.conn = nil
+	b.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	if sessionID == "" || token == "" {
+		return
+	}
+
+	conn, err := dialVoice(ctx, endpoint, g.id, userID, sessionID, token)
+	if err != nil {
+		log.Printf("Discord 连接语音服务器失败: %v", err)
+		return
+	}
+	b.mu.Lock()
+	// 连接期间收到了新的语音服务器信息，由新的 connectVoice 负责连接
+	if g.token != token || g.endpoint != endpoint || g.conn != nil {
+		b.mu.Unlock()
+		conn.close()
+		return
+	}
+	g.conn = conn
+	b.mu.Unlock()
+	log.Printf("Discord 已加入语音频道: 服务器 %s", g.id)
+}
+
+// disconnect 关闭服务器的语音连接
+func (b *Bot) disconnect(g *guild) {
+	b.mu.Lock()
+	conn := g.conn
+	g.conn = nil
+	g.token = ""
+	if g.skip != nil {
+		g.skip()
+	}
+	b.mu.Unlock()
+	if conn != nil {
+		conn.close()
+		log.Printf("Discord 已离开语音频道: 服务器 %s", g.id)
+	}
+}
+
+// handle 处理一条文字消息：命令或需要朗读的消息。
+// 在网关的读取循环中调用以保持消息的顺序，命令与回复在单独的协程中执行
+func (b *Bot) handle(ctx context.Context, g *guild, msg *message) {
+	text := strings.TrimSpace(msg.Content)
+	if text == b.prefix || strings.HasPrefix(text, b.prefix+" ") {
+		go b.command(ctx, g, msg, strings.Fields(text)[1:])
+		return
+	}
+
+	settings := b.settings(g.id)
+	if settings.TextChannel != msg.ChannelID {
+		return
+	}
+	b.mu.Lock()
+	connected := g.conn != nil
+	b.mu.Unlock()
+	if !connected {
+		return
+	}
+
+	text = cleanContent(text)
+	if text == "" {
+		return
+	}
+	if b.config.Discord.ReadNames {
+		text = displayName(msg) + "说: " + text
+	}
+	if length := utils.GraphemeCount(text); length > b.config.TTS.MaxTextLength {
+		go b.reply(ctx, msg, fmt.Sprintf("文本长度超过限制 (%d > %d)", length, b.config.TTS.MaxTextLength))
+		return
+	}
+	select {
+	case g.queue <- speech{text: text, channel: msg.ChannelID}:
+	default:
+		go b.reply(ctx, msg, "朗读队列已满，请稍后再试")
+	}
+}
+
+// command 处理机器人命令
+func (b *Bot) command(ctx context.Context, g *guild, msg *message, args []string) {
+	name := "help"
+	if len(args) > 0 {
+		name = strings.ToLower(args[0])
+		args = args[1:]
+	}
+	settings := b.settings(g.id)
+
+	switch name {
+	case "join":
+		b.mu.Lock()
+		channel := g.voiceStates[msg.Author.ID]
+		b.mu.Unlock()
+		if channel == "" {
+			b.reply(ctx, msg, "请先加入一个语音频道")
+			return
+		}
+		if err := b.updateVoiceState(g.id, channel); err != nil {
+			log.Printf("Discord 加入语音频道失败: %v", err)
+			b.reply(ctx, msg, "加入语音频道失败")
+			return
+		}
+		settings.TextChannel = msg.ChannelID
+		settings.VoiceChannel = channel
+		b.saveSettings(ctx, g.id, msg, settings, "已加入语音频道，将朗读这个频道中的消息")
+	case "leave":
+		if err := b.updateVoiceState(g.id, ""); err != nil {
+			log.Printf("Discord 离开语音频道失败: %v", err)
+		}
+		b.disconnect(g)
+		settings.TextChannel = ""
+		settings.VoiceChannel = ""
+		b.saveSettings(ctx, g.id, msg, settings, "已离开语音频道")
+	case "skip":
+		b.mu.Lock()
+		if g.skip != nil {
+			g.skip()
+		}
+		b.mu.Unlock()
+	case "voice":
+		if len(args) == 0 {
+			b.reply(ctx, msg, "当前语音: "+settings.Voice)
+			return
+		}
+		voice, err := b.findVoice(ctx, args[0])
+		if err != nil {
+			b.reply(ctx, msg, apperr.From(err).Message)
+			return
+		}
+		settings.Voice = voice
+		b.saveSettings(ctx, g.id, msg, settings, "语音已设置为 "+voice)
+	case "rate":
+		if len(args) == 0 {
+			b.reply(ctx, msg, "当前语速: "+settings.Rate)
+			return
+		}
+		rate, err := strconv.Atoi(strings.TrimSuffix(args[0], "%"))
+		if err != nil || rate < -100 || rate > 100 {
+			b.reply(ctx, msg, "语速必须是 -100 到 100 之间的整数")
+			return
+		}
+		settings.Rate = strconv.Itoa(rate)
+		b.saveSettings(ctx, g.id, msg, settings, "语速已设置为 "+settings.Rate)
+	default:
+		b.reply(ctx, msg, fmt.Sprintf(helpText, b.prefix))
+	}
+}
+
+// speakLoop 按顺序合成并播放服务器的排队消息，直到 ctx 结束
+func (b *Bot) speakLoop(ctx context.Context, g *guild) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-g.queue:
+			b.speak(ctx, g, s)
+		}
+	}
+}
+
+// speak 合成一条消息并在语音频道中播放，播放期间可以被 skip 命令或离开语音频道打断
+func (b *Bot) speak(ctx context.Context, g *guild, s speech) {
+	settings := b.settings(g.id)
+	// 朗读前频道绑定已变化的消息不再朗读
+	if settings.TextChannel != s.channel {
+		return
+	}
+	resp, err := b.synthesizer.Synthesize(ctx, models.TTSRequest{
+		Text:  s.text,
+		Voice: settings.Voice,
+		Rate:  settings.Rate,
+		Pitch: b.config.TTS.DefaultPitch,
+	})
+	if err != nil {
+		log.Printf("Discord 合成失败: %v", err)
+		return
+	}
+	audio, err := watermark.Stamp(b.config, resp.AudioContent, "discord")
+	if err != nil {
+		log.Printf("Discord %v", err)
+		return
+	}
+
+	playCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	b.mu.Lock()
+	conn := g.conn
+	g.skip = cancel
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		g.skip = nil
+		b.mu.Unlock()
+	}()
+	if conn == nil {
+		return
+	}
+	if err := conn.play(playCtx, audio); err != nil && playCtx.Err() == nil {
+		log.Printf("Discord 播放失败: %v", err)
+	}
+}
+
+// cleanContent 把消息内容转换为适合朗读的文本：自定义表情只读名称，忽略提及
+func cleanContent(text string) string {
+	text = customEmoji.ReplaceAllString(text, "$1")
+	text = mention.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// displayName 返回发送者在服务器中显示的名字
+func displayName(msg *message) string {
+	if msg.Member != nil && msg.Member.Nick != "" {
+		return msg.Member.Nick
+	}
+	if msg.Author.GlobalName != "" {
+		return msg.Author.GlobalName
+	}
+	return msg.Author.Username
+}
+
+// findVoice 按名称查找语音，忽略大小写
+func (b *Bot) findVoice(ctx context.Context, name string) (string, error) {
+	voices, err := b.synthesizer.ListVoices(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	for _, voice := range voices {
+		if strings.EqualFold(voice.ShortName, name) {
+			return voice.ShortName, nil
+		}
+	}
+	return "", apperr.Newf(apperr.CodeInvalidVoice, "语音不存在: %s", name)
+}
+
+// settings 读取服务器设置，未设置的语音参数使用默认值
+func (b *Bot) settings(guildID string) GuildSettings {
+	var settings GuildSettings
+	if _, err := b.store.Get(settingsBucket, guildID, &settings); err != nil {
+		log.Printf("读取 Discord 服务器设置失败: %v", err)
+	}
+	if settings.Voice == "" {
+		settings.Voice = b.config.TTS.DefaultVoice
+	}
+	if settings.Rate == "" {
+		settings.Rate = b.config.TTS.DefaultRate
+	}
+	return settings
+}
+
+// saveSettings 保存服务器设置并回复结果
+func (b *Bot) saveSettings(ctx context.Context, guildID string, msg *message, settings GuildSettings, reply string) {
+	if err := b.store.Put(settingsBucket, guildID, settings); err != nil {
+		log.Printf("保存 Discord 服务器设置失败: %v", err)
+		b.reply(ctx, msg, "保存设置失败")
+		return
+	}
+	b.reply(ctx, msg, reply)
+}
+
+// reply 在消息所在的频道中回复
+func (b *Bot) reply(ctx context.Context, msg *message, text string) {
+	body, _ := json.Marshal(map[string]any{
+		"content":           text,
+		"message_reference": map[string]any{"message_id": msg.ID, "fail_if_not_exists": false},
+		"allowed_mentions":  map[string]any{"parse": []string{}},
+	})
+	url := fmt.Sprintf("%s/channels/%s/messages", b.apiURL, msg.ChannelID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bot "+b.config.Discord.Token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		log.Printf("Discord 发送消息失败: %s", b.sanitize(err))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		log.Printf("Discord 发送消息失败: HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+}
+
+// sanitize 从错误信息中移除机器人令牌
+func (b *Bot) sanitize(err error) string {
+	return utils.SanitizeMessage(err.Error(), b.config.Discord.Token)
+}
@@ -0,0 +1,184 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// intents 是订阅的网关事件：服务器、语音状态、服务器消息与消息内容
+	intents = 1 | 1<<7 | 1<<9 | 1<<15
+	// maxGatewayMessage 是网关单条消息的大小上限，GUILD_CREATE 在大服务器中可能较大
+	maxGatewayMessage = 8 << 20
+	gatewayTimeout    = 30 * time.Second
+)
+
+// gatewayPayload 是网关的消息
+type gatewayPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+	S  *int64          `json:"s"`
+	T  string          `json:"t"`
+}
+
+// gatewayConn 是与网关的一次连接，写操作通过互斥锁串行执行
+type gatewayConn struct {
+	ws *websocket.Conn
+	mu sync.Mutex
+}
+
+// send 向网关发送消息
+func (g *gatewayConn) send(op int, d any) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.ws.SetWriteDeadline(time.Now().Add(gatewayTimeout))
+	return g.ws.WriteJSON(map[string]any{"op": op, "d": d})
+}
+
+// connect 连接网关并认证，然后处理事件直到连接断开或 ctx 结束。
+// 断开后重新认证而不是恢复会话，期间错过的消息不会朗读
+func (b *Bot) connect(ctx context.Context) error {
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, b.gatewayURL+"/?v=10&encoding=json", nil)
+	if err != nil {
+		return fmt.Errorf("连接网关失败: %w", err)
+	}
+	defer ws.Close()
+	ws.SetReadLimit(maxGatewayMessage)
+	conn := &gatewayConn{ws: ws}
+
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	ws.SetReadDeadline(time.Now().Add(gatewayTimeout))
+	var p gatewayPayload
+	if err := ws.ReadJSON(&p); err != nil {
+		return fmt.Errorf("等待网关 Hello 失败: %w", err)
+	}
+	if p.Op != 10 {
+		return fmt.Errorf("网关首条消息不是 Hello: op %d", p.Op)
+	}
+	var hello struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	if err := json.Unmarshal(p.D, &hello); err != nil || hello.HeartbeatInterval <= 0 {
+		return fmt.Errorf("网关 Hello 无效")
+	}
+	interval := time.Duration(hello.HeartbeatInterval) * time.Millisecond
+
+	if err := conn.send(2, map[string]any{
+		"token":   b.config.Discord.Token,
+		"intents": intents,
+		"properties": map[string]string{
+			"os":      "linux",
+			"browser": "tts",
+			"device":  "tts",
+		},
+	}); err != nil {
+		return fmt.Errorf("发送认证失败: %w", err)
+	}
+
+	var (
+		seqMu sync.Mutex
+		seq   *int64
+		acked = true
+	)
+	heartbeat := func() error {
+		seqMu.Lock()
+		defer seqMu.Unlock()
+		acked = false
+		return conn.send(1, seq)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				seqMu.Lock()
+				zombie := !acked
+				seqMu.Unlock()
+				// 上次心跳没有收到确认，连接可能已失效
+				if zombie || heartbeat() != nil {
+					ws.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	b.setGateway(conn)
+	defer b.setGateway(nil)
+	for {
+		ws.SetReadDeadline(time.Now().Add(interval + gatewayTimeout))
+		var p gatewayPayload
+		if err := ws.ReadJSON(&p); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("读取网关消息失败: %w", err)
+		}
+		if p.S != nil {
+			seqMu.Lock()
+			seq = p.S
+			seqMu.Unlock()
+		}
+		switch p.Op {
+		case 0:
+			b.dispatch(ctx, p.T, p.D)
+		case 1:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		case 7:
+			return fmt.Errorf("网关要求重新连接")
+		case 9:
+			return fmt.Errorf("网关会话无效")
+		case 11:
+			seqMu.Lock()
+			acked = true
+			seqMu.Unlock()
+		}
+	}
+}
+
+// setGateway 设置当前的网关连接，断开时为 nil
+func (b *Bot) setGateway(conn *gatewayConn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.gateway = conn
+}
+
+// updateVoiceState 请求加入语音频道，channelID 为空时离开语音频道
+func (b *Bot) updateVoiceState(guildID, channelID string) error {
+	b.mu.Lock()
+	conn := b.gateway
+	b.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("网关未连接")
+	}
+	var channel any
+	if channelID != "" {
+		channel = channelID
+	}
+	return conn.send(4, map[string]any{
+		"guild_id":   guildID,
+		"channel_id": channel,
+		"self_mute":  false,
+		"self_deaf":  true,
+	})
+}
+
+// logGateway 记录网关错误，错误信息中的令牌被移除
+func (b *Bot) logGateway(err error) {
+	log.Printf("Discord 网关连接断开: %s", b.sanitize(err))
+}
@@ -0,0 +1,73 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// oggReader 从 Ogg 流中逐个读取 Opus 数据包，跨页的数据包会被拼接
+type oggReader struct {
+	r       io.Reader
+	lacing  []byte // 当前页尚未读取的分段长度表
+	body    []byte // 当前页尚未读取的数据
+	partial []byte // 跨页数据包已读取的部分
+}
+
+func newOggReader(r io.Reader) *oggReader {
+	return &oggReader{r: r}
+}
+
+// next 返回下一个数据包，流结束时返回 io.EOF
+func (o *oggReader) next() ([]byte, error) {
+	for {
+		for len(o.lacing) > 0 {
+			n := int(o.lacing[0])
+			o.lacing = o.lacing[1:]
+			o.partial = append(o.partial, o.body[:n]...)
+			o.body = o.body[n:]
+			// 长度为 255 的分段表示数据包在下一个分段中继续
+			if n < 255 {
+				packet := o.partial
+				o.partial = nil
+				return packet, nil
+			}
+		}
+		if err := o.readPage(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readPage 读取下一页的分段表与数据
+func (o *oggReader) readPage() error {
+	var header [27]byte
+	if _, err := io.ReadFull(o.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("Ogg 页不完整")
+		}
+		return err
+	}
+	if !bytes.Equal(header[:4], []byte("OggS")) {
+		return fmt.Errorf("不是 Ogg 流")
+	}
+	lacing := make([]byte, header[26])
+	if _, err := io.ReadFull(o.r, lacing); err != nil {
+		return fmt.Errorf("读取 Ogg 分段表失败: %w", err)
+	}
+	size := 0
+	for _, n := range lacing {
+		size += int(n)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(o.r, body); err != nil {
+		return fmt.Errorf("读取 Ogg 页失败: %w", err)
+	}
+	o.lacing, o.body = lacing, body
+	return nil
+}
+
+// isOpusHeader 判断数据包是否为 Opus 的标识头或注释头，这两个数据包不是音频
+func isOpusHeader(packet []byte) bool {
+	return bytes.HasPrefix(packet, []byte("OpusHead")) || bytes.HasPrefix(packet, []byte("OpusTags"))
+}
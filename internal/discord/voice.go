@@ -0,0 +1,350 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// encryptionMode 是语音数据使用的加密方式
+	encryptionMode = "aead_aes256_gcm_rtpsize"
+	// frameDuration 是每个 Opus 帧的时长，frameSamples 是对应的 48kHz 采样数
+	frameDuration = 20 * time.Millisecond
+	frameSamples  = 960
+	// silenceFrames 是停止发送音频前补充的静音帧数，避免接收端插值产生杂音
+	silenceFrames = 5
+	voiceTimeout  = 10 * time.Second
+)
+
+// silenceFrame 是 Opus 的静音帧
+var silenceFrame = []byte{0xF8, 0xFF, 0xFE}
+
+// voiceConn 是一个服务器的语音连接：语音网关 WebSocket 用于信令，UDP 用于发送加密的 RTP 音频
+type voiceConn struct {
+	ws   *websocket.Conn
+	wsMu sync.Mutex
+	udp  *net.UDPConn
+	ssrc uint32
+	aead cipher.AEAD
+
+	// 以下字段只在 play 中使用，play 由每个服务器的朗读队列串行调用
+	sequence  uint16
+	timestamp uint32
+	nonce     uint32
+
+	seqMu   sync.Mutex
+	lastSeq int64 // 语音网关最后一条消息的序号，心跳时确认
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// voicePayload 是语音网关的消息
+type voicePayload struct {
+	Op  int             `json:"op"`
+	D   json.RawMessage `json:"d"`
+	Seq *int64          `json:"seq,omitempty"`
+}
+
+// dialVoice 连接语音网关，完成认证、UDP 地址发现与加密方式协商
+func dialVoice(ctx context.Context, endpoint, guildID, userID, sessionID, token string) (*voiceConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, voiceTimeout)
+	defer cancel()
+
+	endpoint = strings.TrimPrefix(endpoint, "wss://")
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, "wss://"+endpoint+"/?v=8", nil)
+	if err != nil {
+		return nil, fmt.Errorf("连接语音网关失败: %w", err)
+	}
+	v := &voiceConn{ws: ws, done: make(chan struct{}), lastSeq: -1}
+	ok := false
+	defer func() {
+		if !ok {
+			v.close()
+		}
+	}()
+	ws.SetReadDeadline(time.Now().Add(voiceTimeout))
+
+	var hello struct {
+		HeartbeatInterval float64 `json:"heartbeat_interval"`
+	}
+	if err := v.expect(8, &hello); err != nil {
+		return nil, err
+	}
+	if err := v.send(0, map[string]any{
+		"server_id":  guildID,
+		"user_id":    userID,
+		"session_id": sessionID,
+		"token":      token,
+		// 不支持端到端加密（DAVE）
+		"max_dave_protocol_version": 0,
+	}); err != nil {
+		return nil, err
+	}
+
+	var ready struct {
+		SSRC  uint32   `json:"ssrc"`
+		IP    string   `json:"ip"`
+		Port  int      `json:"port"`
+		Modes []string `json:"modes"`
+	}
+	if err := v.expect(2, &ready); err != nil {
+		return nil, err
+	}
+	if !contains(ready.Modes, encryptionMode) {
+		return nil, fmt.Errorf("语音服务器不支持 %s 加密", encryptionMode)
+	}
+	v.ssrc = ready.SSRC
+
+	addr := &net.UDPAddr{IP: net.ParseIP(ready.IP), Port: ready.Port}
+	if v.udp, err = net.DialUDP("udp", nil, addr); err != nil {
+		return nil, fmt.Errorf("连接语音服务器失败: %w", err)
+	}
+	ip, port, err := v.discoverAddress()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.send(1, map[string]any{
+		"protocol": "udp",
+		"data":     map[string]any{"address": ip, "port": port, "mode": encryptionMode},
+	}); err != nil {
+		return nil, err
+	}
+
+	var session struct {
+		Mode      string `json:"mode"`
+		SecretKey []int  `json:"secret_key"`
+	}
+	if err := v.expect(4, &session); err != nil {
+		return nil, err
+	}
+	key := make([]byte, len(session.SecretKey))
+	for i, b := range session.SecretKey {
+		key[i] = byte(b)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("语音密钥无效: %w", err)
+	}
+	if v.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+
+	ws.SetReadDeadline(time.Time{})
+	go v.heartbeat(time.Duration(hello.HeartbeatInterval * float64(time.Millisecond)))
+	go v.readLoop()
+	ok = true
+	return v, nil
+}
+
+// expect 读取语音网关的消息，直到收到操作码为 op 的消息并解析其数据
+func (v *voiceConn) expect(op int, d any) error {
+	for {
+		var p voicePayload
+		if err := v.ws.ReadJSON(&p); err != nil {
+			return fmt.Errorf("等待语音网关消息 %d 失败: %w", op, err)
+		}
+		v.track(p)
+		if p.Op == op {
+			return json.Unmarshal(p.D, d)
+		}
+	}
+}
+
+// track 记录消息的序号
+func (v *voiceConn) track(p voicePayload) {
+	if p.Seq != nil {
+		v.seqMu.Lock()
+		v.lastSeq = *p.Seq
+		v.seqMu.Unlock()
+	}
+}
+
+// send 向语音网关发送消息
+func (v *voiceConn) send(op int, d any) error {
+	v.wsMu.Lock()
+	defer v.wsMu.Unlock()
+	v.ws.SetWriteDeadline(time.Now().Add(voiceTimeout))
+	return v.ws.WriteJSON(map[string]any{"op": op, "d": d})
+}
+
+// discoverAddress 通过 UDP 向语音服务器查询本机的外部地址与端口
+func (v *voiceConn) discoverAddress() (string, int, error) {
+	packet := make([]byte, 74)
+	binary.BigEndian.PutUint16(packet[0:], 1)
+	binary.BigEndian.PutUint16(packet[2:], 70)
+	binary.BigEndian.PutUint32(packet[4:], v.ssrc)
+	if _, err := v.udp.Write(packet); err != nil {
+		return "", 0, fmt.Errorf("查询外部地址失败: %w", err)
+	}
+	v.udp.SetReadDeadline(time.Now().Add(voiceTimeout))
+	defer v.udp.SetReadDeadline(time.Time{})
+	if _, err := io.ReadFull(v.udp, packet); err != nil {
+		return "", 0, fmt.Errorf("查询外部地址失败: %w", err)
+	}
+	address := string(bytes.TrimRight(packet[8:72], "\x00"))
+	return address, int(binary.BigEndian.Uint16(packet[72:])), nil
+}
+
+// heartbeat 定期发送心跳并确认收到的最后一条消息
+func (v *voiceConn) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-v.done:
+			return
+		case <-ticker.C:
+			v.seqMu.Lock()
+			seq := v.lastSeq
+			v.seqMu.Unlock()
+			if err := v.send(3, map[string]any{"t": time.Now().UnixMilli(), "seq_ack": seq}); err != nil {
+				v.close()
+				return
+			}
+		}
+	}
+}
+
+// readLoop 读取语音网关的消息直到连接关闭，连接关闭后 done 被关闭
+func (v *voiceConn) readLoop() {
+	defer v.close()
+	for {
+		messageType, data, err := v.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		// 二进制消息用于端到端加密协商，不处理
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		var p voicePayload
+		if json.Unmarshal(data, &p) == nil {
+			v.track(p)
+		}
+	}
+}
+
+// closed 返回连接关闭时关闭的通道
+func (v *voiceConn) closed() <-chan struct{} {
+	return v.done
+}
+
+// close 关闭语音连接
+func (v *voiceConn) close() {
+	v.closeOnce.Do(func() {
+		close(v.done)
+		v.ws.Close()
+		if v.udp != nil {
+			v.udp.Close()
+		}
+	})
+}
+
+// speaking 设置说话状态
+func (v *voiceConn) speaking(on bool) error {
+	flag := 0
+	if on {
+		flag = 1 // 麦克风
+	}
+	return v.send(5, map[string]any{"speaking": flag, "delay": 0, "ssrc": v.ssrc})
+}
+
+// play 用 ffmpeg 把音频转换为 48kHz 立体声的 Opus，按帧时长发送；ctx 取消时停止
+func (v *voiceConn) play(ctx context.Context, audio []byte) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-i", "pipe:0", "-c:a", "libopus", "-b:a", "64k", "-ar", "48000", "-ac", "2",
+		"-frame_duration", "20", "-application", "voip", "-f", "ogg", "pipe:1")
+	cmd.Stdin = bytes.NewReader(audio)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 ffmpeg 失败: %w", err)
+	}
+	defer cmd.Wait()
+
+	if err := v.speaking(true); err != nil {
+		return err
+	}
+	defer v.speaking(false)
+
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+	packets := newOggReader(stdout)
+	for {
+		packet, err := packets.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取 Opus 音频失败: %w %s", err, strings.TrimSpace(stderr.String()))
+		}
+		if isOpusHeader(packet) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-v.done:
+			return fmt.Errorf("语音连接已断开")
+		case <-ticker.C:
+		}
+		if err := v.sendFrame(packet); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < silenceFrames; i++ {
+		<-ticker.C
+		if err := v.sendFrame(silenceFrame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendFrame 加密一个 Opus 帧并以 RTP 包发送：RTP 头作为附加数据，32 位递增的 nonce 附在包尾
+func (v *voiceConn) sendFrame(opus []byte) error {
+	header := make([]byte, 12, 12+len(opus)+v.aead.Overhead()+4)
+	header[0] = 0x80
+	header[1] = 0x78
+	binary.BigEndian.PutUint16(header[2:], v.sequence)
+	binary.BigEndian.PutUint32(header[4:], v.timestamp)
+	binary.BigEndian.PutUint32(header[8:], v.ssrc)
+
+	nonce := make([]byte, v.aead.NonceSize())
+	binary.BigEndian.PutUint32(nonce, v.nonce)
+	packet := v.aead.Seal(header, nonce, opus, header)
+	packet = append(packet, nonce[:4]...)
+
+	v.sequence++
+	v.timestamp += frameSamples
+	v.nonce++
+	_, err := v.udp.Write(packet)
+	return err
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,351 @@
+// Package doctor 实现 tts doctor：按配置检查 DNS、TLS、上游认证、各 TTS 服务的合成与目录的可写性，
+// 输出通过/失败报告，用于上线前检查与提交问题时附带环境信息
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/tts"
+	_ "tts/internal/tts/microsoft" // 注册 Microsoft TTS 服务
+	_ "tts/internal/tts/mock"      // 注册模拟服务
+	"tts/internal/utils"
+)
+
+const (
+	checkTimeout = 15 * time.Second
+	// authHost 是 Microsoft 服务获取认证信息的地址
+	authHost = "dev.microsofttranslator.com"
+	// sampleText 是合成检查使用的文本
+	sampleText = "测试"
+)
+
+// Status 是一项检查的结果
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+// statusLabels 是输出报告时各结果的名称
+var statusLabels = map[Status]string{Pass: "通过", Warn: "警告", Fail: "失败", Skip: "跳过"}
+
+// Result 是一项检查的结果
+type Result struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Detail     string `json:"detail"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report 是全部检查的结果
+type Report struct {
+	Config    string   `json:"config"`
+	GoVersion string   `json:"go_version"`
+	Platform  string   `json:"platform"`
+	Time      string   `json:"time"`
+	Results   []Result `json:"results"`
+}
+
+// Failed 返回失败的检查数
+func (r *Report) Failed() int {
+	n := 0
+	for _, result := range r.Results {
+		if result.Status == Fail {
+			n++
+		}
+	}
+	return n
+}
+
+// Run 运行自检并输出报告，args 为 doctor 之后的命令行参数；有检查失败时返回错误
+func Run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", "", "配置文件路径，默认按服务启动时的顺序查找")
+	env := fs.String("env", "", "配置环境名称，默认使用环境变量 TTS_ENV")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出报告")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	config.SetEnvironment(*env)
+	if *configPath == "" {
+		*configPath = config.FindFile()
+	}
+	if abs, err := filepath.Abs(*configPath); err == nil {
+		*configPath = abs
+	}
+
+	// 各组件的运行日志不输出到报告中
+	logOut := log.Writer()
+	log.SetOutput(io.Discard)
+	report := Check(*configPath)
+	log.SetOutput(logOut)
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		writeText(out, report)
+	}
+	if n := report.Failed(); n > 0 {
+		return fmt.Errorf("%d 项检查失败", n)
+	}
+	return nil
+}
+
+// Check 按配置文件运行全部检查，配置无法加载时只返回配置检查的结果
+func Check(configPath string) *Report {
+	report := &Report{
+		Config:    configPath,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Time:      time.Now().Format(time.RFC3339),
+	}
+	add := func(name string, check func() (Status, string)) {
+		start := time.Now()
+		status, detail := check()
+		report.Results = append(report.Results, Result{
+			Name: name, Status: status, Detail: detail, DurationMs: time.Since(start).Milliseconds(),
+		})
+	}
+
+	var cfg *config.Config
+	add("配置", func() (Status, string) {
+		var err error
+		if cfg, err = config.Reread(configPath); err != nil {
+			return Fail, err.Error()
+		}
+		return Pass, fmt.Sprintf("已加载，版本 %d", config.CurrentVersion)
+	})
+	if cfg == nil {
+		return report
+	}
+
+	providers := configuredProviders(cfg)
+	if contains(providers, "microsoft") {
+		proxied := cfg.TTS.Outbound.Proxy != ""
+		add("DNS", func() (Status, string) { return checkDNS(authHost, proxied) })
+		add("TLS", func() (Status, string) { return checkTLS(authHost, proxied) })
+		add("认证", checkAuth)
+	}
+	for _, name := range providers {
+		add("合成 "+name, func() (Status, string) { return checkSynthesis(cfg, name) })
+	}
+	for _, dir := range writableDirs(cfg) {
+		add("可写 "+dir.name, func() (Status, string) { return checkWritable(dir.path) })
+	}
+	add("ffmpeg", checkFFmpeg)
+	return report
+}
+
+// configuredProviders 返回配置中使用的 TTS 服务：tts.provider、polly.engines 与启用时的 shadow.provider
+func configuredProviders(cfg *config.Config) []string {
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name == "" {
+			name = tts.DefaultProvider
+		}
+		seen[name] = true
+	}
+	add(cfg.TTS.Provider)
+	for _, provider := range cfg.Polly.Engines {
+		if provider != "" {
+			add(provider)
+		}
+	}
+	if cfg.Shadow.Enabled {
+		add(cfg.Shadow.Provider)
+	}
+	providers := make([]string, 0, len(seen))
+	for name := range seen {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDNS 解析上游域名，配置了出站代理时由代理解析，只在本机无法解析时给出警告
+func checkDNS(host string, proxied bool) (Status, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		if proxied {
+			return Warn, fmt.Sprintf("本机无法解析 %s，已配置出站代理，由代理解析: %v", host, err)
+		}
+		return Fail, err.Error()
+	}
+	return Pass, host + " → " + strings.Join(addrs, ", ")
+}
+
+// checkTLS 与上游建立 TLS 连接，报告协议版本与证书有效期
+func checkTLS(host string, proxied bool) (Status, string) {
+	if proxied {
+		return Skip, "已配置出站代理，不直接连接"
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: checkTimeout}, Config: &tls.Config{ServerName: host}}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, "443"))
+	if err != nil {
+		return Fail, err.Error()
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+	detail := tls.VersionName(state.Version)
+	if len(state.PeerCertificates) > 0 {
+		expiry := state.PeerCertificates[0].NotAfter
+		detail += fmt.Sprintf("，证书有效期至 %s", expiry.Format("2006-01-02"))
+		if time.Until(expiry) < 7*24*time.Hour {
+			return Warn, detail
+		}
+	}
+	return Pass, detail
+}
+
+// checkAuth 获取 Microsoft 服务的认证信息
+func checkAuth() (Status, string) {
+	client := &http.Client{Timeout: checkTimeout}
+	endpoint, err := utils.GetEndpointWithClient(client)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	region, _ := endpoint["r"].(string)
+	return Pass, "已获取令牌，区域 " + region
+}
+
+// checkSynthesis 用服务合成一句短文本，不经过合成结果缓存与失败缓存
+func checkSynthesis(cfg *config.Config, name string) (Status, string) {
+	direct := *cfg
+	direct.Cache.Synthesis = false
+	direct.Cache.NegativeTTL = 0
+	service, err := tts.New(name, &direct)
+	if err != nil {
+		return Fail, err.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+	resp, err := service.SynthesizeSpeech(ctx, models.TTSRequest{Text: sampleText, Voice: cfg.TTS.DefaultVoice})
+	if err != nil {
+		return Fail, err.Error()
+	}
+	if len(resp.AudioContent) == 0 {
+		return Fail, "返回的音频为空"
+	}
+	return Pass, fmt.Sprintf("%s，%d 字节 %s", cfg.TTS.DefaultVoice, len(resp.AudioContent), resp.ContentType)
+}
+
+// dir 是需要写入的目录
+type dir struct {
+	name string // 配置项名称
+	path string
+}
+
+// writableDirs 返回配置中服务需要写入的目录
+func writableDirs(cfg *config.Config) []dir {
+	var dirs []dir
+	if cfg.Cache.Dir != "" {
+		dirs = append(dirs, dir{"cache.dir", cfg.Cache.Dir})
+	}
+	if cfg.Storage.Dir != "" {
+		dirs = append(dirs, dir{"storage.dir", cfg.Storage.Dir})
+	}
+	if cfg.Store.Path != "" {
+		dirs = append(dirs, dir{"store.path", filepath.Dir(cfg.Store.Path)})
+	}
+	if cfg.Jobs.Enabled && cfg.Jobs.Dir != "" {
+		dirs = append(dirs, dir{"jobs.dir", cfg.Jobs.Dir})
+	}
+	if path := cfg.Logging.Access.Path; path != "" && path != "stdout" {
+		dirs = append(dirs, dir{"logging.access.path", filepath.Dir(path)})
+	}
+	if cfg.Remote.Cache != "" {
+		dirs = append(dirs, dir{"remote.cache", filepath.Dir(cfg.Remote.Cache)})
+	}
+	return dirs
+}
+
+// checkWritable 在目录中创建并删除临时文件，目录不存在时按服务启动时的方式创建
+func checkWritable(path string) (Status, string) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return Fail, err.Error()
+	}
+	f, err := os.CreateTemp(path, ".doctor-*")
+	if err != nil {
+		return Fail, err.Error()
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return Pass, path
+}
+
+// checkFFmpeg 查找 ffmpeg，未安装时只影响需要 ffmpeg 的功能
+func checkFFmpeg() (Status, string) {
+	path, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return Warn, "未安装，静音补充、变速、音频拼接与 Wyoming 等功能不可用"
+	}
+	return Pass, path
+}
+
+// writeText 以文本格式输出报告
+func writeText(out io.Writer, report *Report) {
+	fmt.Fprintf(out, "TTS 服务自检\n配置: %s\n环境: %s %s，%s\n\n", report.Config, report.GoVersion, report.Platform, report.Time)
+	counts := map[Status]int{}
+	for _, r := range report.Results {
+		counts[r.Status]++
+		fmt.Fprintf(out, "  [%s] %s %s (%dms)\n", statusLabels[r.Status], pad(r.Name, nameWidth), r.Detail, r.DurationMs)
+	}
+	fmt.Fprintf(out, "\n%d 项通过，%d 项警告，%d 项失败，%d 项跳过\n", counts[Pass], counts[Warn], counts[Fail], counts[Skip])
+}
+
+// nameWidth 是报告中检查名称一栏的显示宽度
+const nameWidth = 24
+
+// pad 在 s 后补充空格到显示宽度 width，中文按两个字符宽计算
+func pad(s string, width int) string {
+	w := 0
+	for _, r := range s {
+		if r >= 0x2e80 {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	if w >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-w)
+}
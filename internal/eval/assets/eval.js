@@ -0,0 +1,97 @@
+// 评测页面：获取一组比较，播放 A 与 B 的音频，提交评分后获取下一组。页面不显示候选语音的名称
+(function () {
+    const params = new URLSearchParams(location.search);
+    const token = params.get('token') || '';
+    const run = params.get('run') || '';
+    // 页面地址为 {base_path}/eval/，接口地址按同样的前缀拼接
+    const base = location.pathname.replace(/\/eval\/.*$/, '');
+
+    // 评测人 ID 保存在浏览器中，同一评测人不会重复评同一组合
+    let rater = localStorage.getItem('tts-eval-rater');
+    if (!rater) {
+        rater = Math.random().toString(36).slice(2) + Date.now().toString(36);
+        localStorage.setItem('tts-eval-rater', rater);
+    }
+
+    const $ = (id) => document.getElementById(id);
+    const buttons = document.querySelectorAll('.actions button');
+    let pair = null;
+    let submitted = 0;
+
+    function query(extra) {
+        const q = new URLSearchParams(extra);
+        if (token) {
+            q.set('token', token);
+        }
+        const s = q.toString();
+        return s ? '?' + s : '';
+    }
+
+    function setStatus(text) {
+        $('status').textContent = text;
+    }
+
+    async function errorMessage(resp) {
+        try {
+            const body = await resp.json();
+            return (body.error && body.error.message) || resp.statusText;
+        } catch (e) {
+            return resp.statusText;
+        }
+    }
+
+    async function next() {
+        pair = null;
+        $('pair').hidden = true;
+        setStatus('正在加载…');
+        const resp = await fetch(base + '/v1/eval/pair' + query({run: run, rater: rater}));
+        if (resp.status === 404) {
+            setStatus(submitted > 0 ? '已完成全部比较，感谢参与！' : await errorMessage(resp));
+            return;
+        }
+        if (!resp.ok) {
+            setStatus('加载失败：' + await errorMessage(resp));
+            return;
+        }
+        pair = await resp.json();
+        $('prompt').textContent = pair.prompt;
+        $('audio-a').src = base + '/v1/eval/audio/' + encodeURIComponent(pair.run) + '/' + encodeURIComponent(pair.a) + query({});
+        $('audio-b').src = base + '/v1/eval/audio/' + encodeURIComponent(pair.run) + '/' + encodeURIComponent(pair.b) + query({});
+        $('score-a').value = '0';
+        $('score-b').value = '0';
+        buttons.forEach((b) => b.disabled = false);
+        $('pair').hidden = false;
+        setStatus('');
+    }
+
+    async function vote(preference) {
+        if (!pair) {
+            return;
+        }
+        buttons.forEach((b) => b.disabled = true);
+        $('audio-a').pause();
+        $('audio-b').pause();
+        const resp = await fetch(base + '/v1/eval/votes' + query({}), {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify({
+                pair: pair.id,
+                preference: preference,
+                score_a: parseInt($('score-a').value, 10),
+                score_b: parseInt($('score-b').value, 10),
+                rater: rater,
+            }),
+        });
+        if (!resp.ok) {
+            setStatus('提交失败：' + await errorMessage(resp));
+            buttons.forEach((b) => b.disabled = false);
+            return;
+        }
+        submitted++;
+        $('count').textContent = '已评 ' + submitted + ' 组';
+        next();
+    }
+
+    buttons.forEach((b) => b.addEventListener('click', () => vote(b.dataset.preference)));
+    next();
+})();
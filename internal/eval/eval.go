@@ -0,0 +1,306 @@
+// Package eval 实现语音质量评测：用各候选语音合成一组固定的提示句并保存，评测人在页面上盲听
+// 同一提示句的两个候选两两比较并按 1-5 分打分（MOS），汇总为报告供挑选语音参考。
+// 评测记录与评分保存在持久化存储中，音频保存在文件存储中。
+package eval
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/storage"
+	"tts/internal/store"
+	"tts/internal/tts"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// runsBucket 与 votesBucket 是保存评测记录与评分的存储桶，键为评测 ID
+	runsBucket  = "eval_runs"
+	votesBucket = "eval_votes"
+
+	// maxPrompts 与 maxCandidates 限制一次评测合成的音频数
+	maxPrompts    = 50
+	maxCandidates = 10
+)
+
+// 评测状态
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Candidate 是一个参与评测的候选语音
+type Candidate struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Voice    string `json:"voice"`
+	Rate     string `json:"rate,omitempty"`
+	Pitch    string `json:"pitch,omitempty"`
+	Style    string `json:"style,omitempty"`
+}
+
+// Sample 是一个候选语音朗读一个提示句的音频。ID 随机生成，音频地址中只出现 ID，评测人无法由地址得知候选
+type Sample struct {
+	ID        string `json:"id"`
+	Prompt    int    `json:"prompt"`
+	Candidate int    `json:"candidate"`
+	Size      int    `json:"size,omitempty"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Run 是一次评测
+type Run struct {
+	ID         string      `json:"id"`
+	Status     string      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	Created    time.Time   `json:"created"`
+	Finished   *time.Time  `json:"finished,omitempty"`
+	Prompts    []string    `json:"prompts"`
+	Candidates []Candidate `json:"candidates"`
+	Samples    []Sample    `json:"samples"`
+}
+
+// Evaluator 创建评测、分配比较组合并记录评分
+type Evaluator struct {
+	service  tts.Service // tts.provider 对应的服务
+	provider string
+	store    *store.Store
+	files    *storage.Storage
+	config   *config.Config
+
+	mu      sync.Mutex // 保护评测记录与评分的读取后写回
+	pending map[string]*pending
+}
+
+// New 创建评测器。上次运行时未完成的评测标记为失败
+func New(service tts.Service, st *store.Store, files *storage.Storage, cfg *config.Config) *Evaluator {
+	provider := cfg.TTS.Provider
+	if provider == "" {
+		provider = tts.DefaultProvider
+	}
+	e := &Evaluator{
+		service:  service,
+		provider: provider,
+		store:    st,
+		files:    files,
+		config:   cfg,
+		pending:  make(map[string]*pending),
+	}
+	for _, id := range st.Keys(runsBucket) {
+		run, ok := e.Run(id)
+		if ok && run.Status == StatusRunning {
+			run.Status, run.Error = StatusFailed, "服务重启，评测中断"
+			e.save(run)
+		}
+	}
+	return e
+}
+
+// Start 创建评测并在后台合成全部音频。prompts 与 candidates 为空时使用配置中的提示句与候选语音
+func (e *Evaluator) Start(prompts []string, candidates []Candidate) (Run, error) {
+	if len(prompts) == 0 {
+		prompts = e.config.Eval.Prompts
+	}
+	if len(candidates) == 0 {
+		for _, c := range e.config.Eval.Candidates {
+			candidates = append(candidates, Candidate{
+				Name: c.Name, Provider: c.Provider, Voice: c.Voice, Rate: c.Rate, Pitch: c.Pitch, Style: c.Style,
+			})
+		}
+	}
+	if len(prompts) == 0 || len(prompts) > maxPrompts {
+		return Run{}, apperr.Newf(apperr.CodeInvalidRequest, "提示句应为 1-%d 条", maxPrompts)
+	}
+	if len(candidates) < 2 || len(candidates) > maxCandidates {
+		return Run{}, apperr.Newf(apperr.CodeInvalidRequest, "候选语音应为 2-%d 个", maxCandidates)
+	}
+	for _, prompt := range prompts {
+		if prompt == "" {
+			return Run{}, apperr.New(apperr.CodeInvalidRequest, "提示句不能为空")
+		}
+	}
+	names := make(map[string]bool)
+	for i := range candidates {
+		c := &candidates[i]
+		if c.Provider == "" {
+			c.Provider = e.provider
+		}
+		if c.Voice == "" {
+			c.Voice = e.config.TTS.DefaultVoice
+		}
+		if c.Name == "" {
+			c.Name = c.Provider + "/" + c.Voice
+			if c.Rate != "" {
+				c.Name += " " + c.Rate
+			}
+		}
+		if names[c.Name] {
+			return Run{}, apperr.Newf(apperr.CodeInvalidRequest, "候选语音的名称重复: %s", c.Name)
+		}
+		names[c.Name] = true
+	}
+
+	run := Run{
+		ID:         uuid.NewString(),
+		Status:     StatusRunning,
+		Created:    time.Now().UTC(),
+		Prompts:    prompts,
+		Candidates: candidates,
+	}
+	for p := range prompts {
+		for c := range candidates {
+			run.Samples = append(run.Samples, Sample{ID: uuid.NewString(), Prompt: p, Candidate: c})
+		}
+	}
+	if err := e.save(run); err != nil {
+		return Run{}, apperr.Wrap(apperr.CodeInternal, "保存评测失败", err)
+	}
+	go e.synthesize(run)
+	return run, nil
+}
+
+// synthesize 合成评测的全部音频，任一候选的服务无法创建时评测失败；单个音频失败只记录错误，不参与比较
+func (e *Evaluator) synthesize(run Run) {
+	synthesizers := make(map[string]*ttspkg.Synthesizer)
+	for _, c := range run.Candidates {
+		if synthesizers[c.Provider] != nil {
+			continue
+		}
+		service := e.service
+		if c.Provider != e.provider {
+			var err error
+			if service, err = tts.New(c.Provider, e.config); err != nil {
+				e.finish(run, err)
+				return
+			}
+		}
+		synthesizers[c.Provider] = ttspkg.NewSynthesizer(service, ttspkg.NewSegmenter(&e.config.TTS), e.config.TTS.MaxConcurrent)
+	}
+
+	semaphore := make(chan struct{}, max(1, e.config.TTS.MaxConcurrent))
+	var wg sync.WaitGroup
+	for i := range run.Samples {
+		wg.Add(1)
+		go func(sample *Sample) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			c := run.Candidates[sample.Candidate]
+			start := time.Now()
+			resp, err := synthesizers[c.Provider].Synthesize(context.Background(), models.TTSRequest{
+				Text:  run.Prompts[sample.Prompt],
+				Voice: c.Voice,
+				Rate:  or(c.Rate, e.config.TTS.DefaultRate),
+				Pitch: or(c.Pitch, e.config.TTS.DefaultPitch),
+				Style: c.Style,
+			})
+			if err == nil {
+				sample.LatencyMs = time.Since(start).Milliseconds()
+				sample.Size = len(resp.AudioContent)
+				err = e.files.Put(sampleKey(run.ID, sample.ID), resp.AudioContent)
+			}
+			if err != nil {
+				log.Printf("评测 %s 合成失败 (%s): %v", run.ID, c.Name, err)
+				sample.Error = apperr.From(err).Message
+			}
+		}(&run.Samples[i])
+	}
+	wg.Wait()
+	e.finish(run, nil)
+}
+
+// finish 保存评测的结果
+func (e *Evaluator) finish(run Run, err error) {
+	now := time.Now().UTC()
+	run.Finished = &now
+	run.Status = StatusDone
+	if err != nil {
+		run.Status, run.Error = StatusFailed, err.Error()
+	}
+	failed := 0
+	for _, sample := range run.Samples {
+		if sample.Error != "" {
+			failed++
+		}
+	}
+	if err := e.save(run); err != nil {
+		log.Printf("保存评测 %s 失败: %v", run.ID, err)
+	}
+	log.Printf("评测 %s 合成完成: 状态 %s, 音频 %d, 失败 %d", run.ID, run.Status, len(run.Samples), failed)
+}
+
+// Run 返回评测记录
+func (e *Evaluator) Run(id string) (Run, bool) {
+	var run Run
+	found, err := e.store.Get(runsBucket, id, &run)
+	return run, found && err == nil
+}
+
+// Runs 返回全部评测，最近创建的在前
+func (e *Evaluator) Runs() []Run {
+	runs := []Run{}
+	for _, id := range e.store.Keys(runsBucket) {
+		if run, ok := e.Run(id); ok {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Created.After(runs[j].Created) })
+	return runs
+}
+
+// Latest 返回最近完成的评测，评测页面未指定评测时使用
+func (e *Evaluator) Latest() (Run, bool) {
+	for _, run := range e.Runs() {
+		if run.Status == StatusDone {
+			return run, true
+		}
+	}
+	return Run{}, false
+}
+
+// Audio 打开评测中的一个音频
+func (e *Evaluator) Audio(runID, sampleID string) (*storage.File, error) {
+	run, ok := e.Run(runID)
+	if !ok {
+		return nil, apperr.Newf(apperr.CodeNotFound, "评测不存在: %s", runID)
+	}
+	for _, sample := range run.Samples {
+		if sample.ID == sampleID && sample.Error == "" {
+			file, err := e.files.Open(sampleKey(runID, sampleID))
+			if err != nil {
+				return nil, apperr.Wrap(apperr.CodeNotFound, "音频不存在", err)
+			}
+			return file, nil
+		}
+	}
+	return nil, apperr.New(apperr.CodeNotFound, "音频不存在")
+}
+
+func (e *Evaluator) save(run Run) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.store.Put(runsBucket, run.ID, run)
+}
+
+// sampleKey 返回音频在文件存储中的键
+func sampleKey(runID, sampleID string) string {
+	return fmt.Sprintf("eval/%s/%s.mp3", runID, sampleID)
+}
+
+func or(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,26 @@
+package eval
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// PageHandler 返回评测页面的静态文件，路由参数 filepath 为文件路径，"/" 对应 index.html。
+// 页面本身不包含任何数据，由地址中的 token 获取比较组合与音频，因此页面不需要认证
+func PageHandler() gin.HandlerFunc {
+	files, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	root := http.FS(files)
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+		c.FileFromFS(c.Param("filepath"), root)
+	}
+}
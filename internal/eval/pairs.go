@@ -0,0 +1,161 @@
+package eval
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	"tts/internal/apperr"
+)
+
+// pairTTL 是分配的比较组合等待评分的时间，过期后不能再提交
+const pairTTL = time.Hour
+
+// Pair 是分配给评测人的一组比较：同一提示句的两个候选，A、B 的顺序随机
+type Pair struct {
+	ID     string `json:"id"`
+	Run    string `json:"run"`
+	Prompt string `json:"prompt"`
+	A      string `json:"a"` // 音频的 ID
+	B      string `json:"b"`
+}
+
+// pending 是等待评分的比较组合
+type pending struct {
+	run     string
+	prompt  int
+	a, b    int // 候选的序号
+	expires time.Time
+}
+
+// Vote 是一次评分。Preference 为 a、b 或 tie，ScoreA、ScoreB 为 1-5 分，0 表示未打分
+type Vote struct {
+	Prompt     int       `json:"prompt"`
+	A          int       `json:"a"`
+	B          int       `json:"b"`
+	Preference string    `json:"preference"`
+	ScoreA     int       `json:"score_a,omitempty"`
+	ScoreB     int       `json:"score_b,omitempty"`
+	Rater      string    `json:"rater,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// NextPair 为评测人分配下一组比较：优先选择评分最少的组合，跳过该评测人已经评过的组合，
+// 全部评过时返回 not_found
+func (e *Evaluator) NextPair(runID, rater string) (Pair, error) {
+	run, ok := e.Run(runID)
+	if !ok {
+		return Pair{}, apperr.Newf(apperr.CodeNotFound, "评测不存在: %s", runID)
+	}
+	if run.Status != StatusDone {
+		return Pair{}, apperr.Newf(apperr.CodeConflict, "评测尚未完成合成: %s", run.Status)
+	}
+
+	// 每个提示句可用的音频，按候选序号索引
+	samples := make([]map[int]string, len(run.Prompts))
+	for i := range samples {
+		samples[i] = make(map[int]string)
+	}
+	for _, sample := range run.Samples {
+		if sample.Error == "" {
+			samples[sample.Prompt][sample.Candidate] = sample.ID
+		}
+	}
+
+	type combo struct{ prompt, a, b int }
+	counts := make(map[combo]int)
+	rated := make(map[combo]bool)
+	for _, vote := range e.Votes(runID) {
+		key := combo{vote.Prompt, min(vote.A, vote.B), max(vote.A, vote.B)}
+		counts[key]++
+		if rater != "" && vote.Rater == rater {
+			rated[key] = true
+		}
+	}
+
+	var candidates []combo
+	fewest := -1
+	for p := range run.Prompts {
+		for a := range run.Candidates {
+			for b := a + 1; b < len(run.Candidates); b++ {
+				key := combo{p, a, b}
+				if samples[p][a] == "" || samples[p][b] == "" || rated[key] {
+					continue
+				}
+				switch n := counts[key]; {
+				case fewest < 0 || n < fewest:
+					fewest, candidates = n, []combo{key}
+				case n == fewest:
+					candidates = append(candidates, key)
+				}
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return Pair{}, apperr.New(apperr.CodeNotFound, "没有需要评分的组合")
+	}
+
+	chosen := candidates[rand.IntN(len(candidates))]
+	a, b := chosen.a, chosen.b
+	if rand.IntN(2) == 0 {
+		a, b = b, a
+	}
+	pair := Pair{ID: uuid.NewString(), Run: runID, Prompt: run.Prompts[chosen.prompt], A: samples[chosen.prompt][a], B: samples[chosen.prompt][b]}
+
+	e.mu.Lock()
+	now := time.Now()
+	for id, p := range e.pending {
+		if now.After(p.expires) {
+			delete(e.pending, id)
+		}
+	}
+	e.pending[pair.ID] = &pending{run: runID, prompt: chosen.prompt, a: a, b: b, expires: now.Add(pairTTL)}
+	e.mu.Unlock()
+	return pair, nil
+}
+
+// Submit 记录一组比较的评分，每组比较只能提交一次
+func (e *Evaluator) Submit(pairID, preference string, scoreA, scoreB int, rater string) error {
+	if preference != "a" && preference != "b" && preference != "tie" {
+		return apperr.New(apperr.CodeInvalidRequest, "preference 应为 a、b 或 tie")
+	}
+	if scoreA < 0 || scoreA > 5 || scoreB < 0 || scoreB > 5 {
+		return apperr.New(apperr.CodeInvalidRequest, "分数应为 1-5，0 表示不打分")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p, ok := e.pending[pairID]
+	if !ok || time.Now().After(p.expires) {
+		return apperr.New(apperr.CodeNotFound, "比较组合不存在或已过期")
+	}
+	delete(e.pending, pairID)
+
+	var votes []Vote
+	if _, err := e.store.Get(votesBucket, p.run, &votes); err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "读取评分失败", err)
+	}
+	votes = append(votes, Vote{
+		Prompt:     p.prompt,
+		A:          p.a,
+		B:          p.b,
+		Preference: preference,
+		ScoreA:     scoreA,
+		ScoreB:     scoreB,
+		Rater:      rater,
+		Time:       time.Now().UTC(),
+	})
+	if err := e.store.Put(votesBucket, p.run, votes); err != nil {
+		return apperr.Wrap(apperr.CodeInternal, "保存评分失败", err)
+	}
+	return nil
+}
+
+// Votes 返回评测的全部评分
+func (e *Evaluator) Votes(runID string) []Vote {
+	var votes []Vote
+	if found, err := e.store.Get(votesBucket, runID, &votes); err != nil || !found {
+		return []Vote{}
+	}
+	return votes
+}
@@ -0,0 +1,180 @@
+package eval
+
+import (
+	"math"
+	"sort"
+
+	"tts/internal/apperr"
+)
+
+// Report 是评测的汇总结果
+type Report struct {
+	Run        string            `json:"run"`
+	Status     string            `json:"status"`
+	Votes      int               `json:"votes"`
+	Raters     int               `json:"raters"`
+	Candidates []CandidateReport `json:"candidates"` // 按胜率从高到低排列
+	Pairs      []PairReport      `json:"pairs"`      // 按配置中候选的顺序排列
+}
+
+// CandidateReport 是一个候选语音的结果。WinRate 把平局计为半场胜利；
+// MOS 为评测人打分（1-5）的平均值，MOSCI95 为其 95% 置信区间的半宽
+type CandidateReport struct {
+	Name         string  `json:"name"`
+	Provider     string  `json:"provider"`
+	Voice        string  `json:"voice"`
+	Comparisons  int     `json:"comparisons"`
+	Wins         int     `json:"wins"`
+	Losses       int     `json:"losses"`
+	Ties         int     `json:"ties"`
+	WinRate      float64 `json:"win_rate"`
+	Ratings      int     `json:"ratings"`
+	MOS          float64 `json:"mos,omitempty"`
+	MOSCI95      float64 `json:"mos_ci95,omitempty"`
+	AvgLatencyMs int64   `json:"avg_latency_ms"`
+	Failed       int     `json:"failed"` // 合成失败、不参与比较的提示句数
+}
+
+// PairReport 是两个候选之间的比较结果
+type PairReport struct {
+	A     string `json:"a"`
+	B     string `json:"b"`
+	AWins int    `json:"a_wins"`
+	BWins int    `json:"b_wins"`
+	Ties  int    `json:"ties"`
+}
+
+// Report 汇总评测的评分
+func (e *Evaluator) Report(runID string) (Report, error) {
+	run, ok := e.Run(runID)
+	if !ok {
+		return Report{}, apperr.Newf(apperr.CodeNotFound, "评测不存在: %s", runID)
+	}
+	votes := e.Votes(runID)
+
+	n := len(run.Candidates)
+	results := make([]CandidateReport, n)
+	scores := make([][]float64, n)
+	latency := make([]int64, n)
+	synthesized := make([]int64, n)
+	for i, c := range run.Candidates {
+		results[i] = CandidateReport{Name: c.Name, Provider: c.Provider, Voice: c.Voice}
+	}
+	for _, sample := range run.Samples {
+		if sample.Error != "" {
+			results[sample.Candidate].Failed++
+			continue
+		}
+		latency[sample.Candidate] += sample.LatencyMs
+		synthesized[sample.Candidate]++
+	}
+
+	type key struct{ a, b int }
+	pairs := make(map[key]*PairReport)
+	raters := make(map[string]bool)
+	for _, vote := range votes {
+		if vote.A >= n || vote.B >= n {
+			continue
+		}
+		raters[vote.Rater] = true
+		a, b := &results[vote.A], &results[vote.B]
+		a.Comparisons++
+		b.Comparisons++
+
+		// 组合按候选序号保存，与 A、B 的展示顺序无关
+		lo, hi := min(vote.A, vote.B), max(vote.A, vote.B)
+		pair, ok := pairs[key{lo, hi}]
+		if !ok {
+			pair = &PairReport{A: run.Candidates[lo].Name, B: run.Candidates[hi].Name}
+			pairs[key{lo, hi}] = pair
+		}
+		winner := -1
+		switch vote.Preference {
+		case "a":
+			winner = vote.A
+			a.Wins++
+			b.Losses++
+		case "b":
+			winner = vote.B
+			b.Wins++
+			a.Losses++
+		default:
+			a.Ties++
+			b.Ties++
+			pair.Ties++
+		}
+		switch winner {
+		case lo:
+			pair.AWins++
+		case hi:
+			pair.BWins++
+		}
+
+		if vote.ScoreA > 0 {
+			scores[vote.A] = append(scores[vote.A], float64(vote.ScoreA))
+		}
+		if vote.ScoreB > 0 {
+			scores[vote.B] = append(scores[vote.B], float64(vote.ScoreB))
+		}
+	}
+
+	for i := range results {
+		r := &results[i]
+		if r.Comparisons > 0 {
+			r.WinRate = round((float64(r.Wins) + float64(r.Ties)/2) / float64(r.Comparisons))
+		}
+		if synthesized[i] > 0 {
+			r.AvgLatencyMs = latency[i] / synthesized[i]
+		}
+		r.Ratings = len(scores[i])
+		r.MOS, r.MOSCI95 = meanCI(scores[i])
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].WinRate != results[j].WinRate {
+			return results[i].WinRate > results[j].WinRate
+		}
+		return results[i].MOS > results[j].MOS
+	})
+
+	keys := make([]key, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].a != keys[j].a {
+			return keys[i].a < keys[j].a
+		}
+		return keys[i].b < keys[j].b
+	})
+	report := Report{Run: run.ID, Status: run.Status, Votes: len(votes), Raters: len(raters), Candidates: results, Pairs: []PairReport{}}
+	for _, k := range keys {
+		report.Pairs = append(report.Pairs, *pairs[k])
+	}
+	return report, nil
+}
+
+// meanCI 返回平均值与 95% 置信区间的半宽（正态近似），少于两个分数时半宽为 0
+func meanCI(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return round(mean), 0
+	}
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	stddev := math.Sqrt(squares / float64(len(values)-1))
+	return round(mean), round(1.96 * stddev / math.Sqrt(float64(len(values))))
+}
+
+// round 保留三位小数
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,174 @@
+// Package feature 实现功能开关：实验性功能按开关逐步开放，可以全局开启、按 API 密钥单独开启，
+// 也可以通过管理接口在运行时切换，不需要重启。
+package feature
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+
+	"tts/internal/config"
+)
+
+// Flag 是一个功能开关
+type Flag struct {
+	Name        string
+	Description string
+	Default     bool // 配置与管理接口都未设置时的状态
+}
+
+var (
+	// Verbalize 控制是否按语言包展开文本，需要同时开启 verbalize.enabled
+	Verbalize = Define("verbalize", "合成前按语言包展开数字、日期、单位与缩写（需要 verbalize.enabled）", true)
+	// TimeStretch 控制是否对超出上游范围的语速变速处理，需要同时开启 tts.time_stretch.enabled
+	TimeStretch = Define("time_stretch", "语速超出上游范围时变速补足（需要 tts.time_stretch.enabled）", true)
+	// Pacing 控制是否按句子的难度调整语速，需要同时开启 ssml.pacing.enabled
+	Pacing = Define("pacing", "按句子的难度调整语速：难句放慢、简单句加快（需要 ssml.pacing.enabled）", true)
+)
+
+var (
+	mu        sync.RWMutex
+	flags     = map[string]*Flag{}
+	global    map[string]bool                // features 配置
+	keys      map[string]map[string]bool     // 各密钥 features 配置
+	overrides = map[string]map[string]bool{} // 管理接口设置的状态，键为密钥名称，全局状态的键为空字符串
+)
+
+// Define 定义功能开关，同名开关只定义一次
+func Define(name, description string, defaultValue bool) *Flag {
+	mu.Lock()
+	defer mu.Unlock()
+	if f, ok := flags[name]; ok {
+		return f
+	}
+	f := &Flag{Name: name, Description: description, Default: defaultValue}
+	flags[name] = f
+	return f
+}
+
+// Lookup 返回名为 name 的功能开关
+func Lookup(name string) (*Flag, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := flags[name]
+	return f, ok
+}
+
+// Configure 读取 features 与 keys 中各密钥的 features 配置，未定义的开关名称记录警告后忽略。
+// 管理接口设置的状态保留
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	global = known(cfg.Features, "features")
+	keys = make(map[string]map[string]bool, len(cfg.Keys))
+	for _, key := range cfg.Keys {
+		if len(key.Features) > 0 {
+			keys[key.Name] = known(key.Features, "keys "+key.Name+" features")
+		}
+	}
+}
+
+// known 返回配置中已定义的开关，调用方需持有 mu
+func known(values map[string]bool, field string) map[string]bool {
+	result := make(map[string]bool, len(values))
+	for name, on := range values {
+		if _, ok := flags[name]; !ok {
+			log.Printf("%s 中的功能开关不存在: %s", field, name)
+			continue
+		}
+		result[name] = on
+	}
+	return result
+}
+
+// Enabled 返回功能开关对上下文所属请求的状态，按以下顺序使用第一个设置了的值：
+// 管理接口为该密钥设置的状态、密钥的 features 配置、管理接口设置的全局状态、features 配置、开关的默认状态
+func (f *Flag) Enabled(ctx context.Context) bool {
+	tenant := config.TenantFromContext(ctx)
+	mu.RLock()
+	defer mu.RUnlock()
+	if tenant != "" {
+		if on, ok := overrides[tenant][f.Name]; ok {
+			return on
+		}
+		if on, ok := keys[tenant][f.Name]; ok {
+			return on
+		}
+	}
+	if on, ok := overrides[""][f.Name]; ok {
+		return on
+	}
+	if on, ok := global[f.Name]; ok {
+		return on
+	}
+	return f.Default
+}
+
+// Set 在运行时设置开关的状态，key 为空时设置全局状态；enabled 为 nil 时清除设置，恢复使用配置。
+// 运行时的设置在重启后失效
+func (f *Flag) Set(key string, enabled *bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if enabled == nil {
+		delete(overrides[key], f.Name)
+		if len(overrides[key]) == 0 {
+			delete(overrides, key)
+		}
+		return
+	}
+	if overrides[key] == nil {
+		overrides[key] = map[string]bool{}
+	}
+	overrides[key][f.Name] = *enabled
+}
+
+// Status 是功能开关的当前状态
+type Status struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Default     bool            `json:"default"`
+	Enabled     bool            `json:"enabled"`                // 未使用 keys 中密钥的请求的状态
+	Configured  *bool           `json:"configured,omitempty"`   // features 中配置的状态
+	Override    *bool           `json:"override,omitempty"`     // 管理接口设置的全局状态
+	Keys        map[string]bool `json:"keys,omitempty"`         // 各密钥的状态，只列出单独设置了的密钥
+	KeyOverride map[string]bool `json:"key_override,omitempty"` // 其中由管理接口设置的部分
+}
+
+// All 返回所有功能开关的状态，按名称排序
+func All() []Status {
+	mu.RLock()
+	defer mu.RUnlock()
+	statuses := make([]Status, 0, len(flags))
+	for _, f := range flags {
+		s := Status{Name: f.Name, Description: f.Description, Default: f.Default, Enabled: f.Default}
+		if on, ok := global[f.Name]; ok {
+			s.Configured, s.Enabled = &on, on
+		}
+		if on, ok := overrides[""][f.Name]; ok {
+			s.Override, s.Enabled = &on, on
+		}
+		for key, values := range keys {
+			if on, ok := values[f.Name]; ok {
+				if s.Keys == nil {
+					s.Keys = map[string]bool{}
+				}
+				s.Keys[key] = on
+			}
+		}
+		for key, values := range overrides {
+			if on, ok := values[f.Name]; ok && key != "" {
+				if s.Keys == nil {
+					s.Keys = map[string]bool{}
+				}
+				if s.KeyOverride == nil {
+					s.KeyOverride = map[string]bool{}
+				}
+				s.Keys[key], s.KeyOverride[key] = on, on
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -0,0 +1,215 @@
+// Package health 记录每个上游服务最近的请求结果，汇总出错误率、延迟与认证令牌状态，
+// 供运维看板与切换服务时参考。同名服务的所有实例共享一个记录器。
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"tts/internal/apperr"
+)
+
+const (
+	// window 是统计的时间窗口，更早的请求不计入
+	window = 5 * time.Minute
+	// maxSamples 是每个服务最多保留的请求数
+	maxSamples = 1024
+
+	// degradedRate 与 unhealthyRate 是判定服务降级与不可用的错误率
+	degradedRate  = 0.05
+	unhealthyRate = 0.5
+)
+
+// 服务状态
+const (
+	StateIdle      = "idle"      // 窗口内没有请求
+	StateHealthy   = "healthy"   // 错误率低于 5%
+	StateDegraded  = "degraded"  // 错误率在 5% 与 50% 之间
+	StateUnhealthy = "unhealthy" // 错误率不低于 50%
+)
+
+// TokenReporter 由使用认证令牌的服务实现，返回当前令牌所属的区域与到期时间，尚未获取令牌时到期时间为零值
+type TokenReporter interface {
+	TokenStatus() (region string, expiry time.Time)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*Monitor{}
+)
+
+// sample 是一次请求的结果
+type sample struct {
+	at        time.Time
+	latency   time.Duration
+	failed    bool
+	throttled bool
+}
+
+// Monitor 记录一个服务最近的请求结果
+type Monitor struct {
+	name string
+
+	mu        sync.Mutex
+	samples   []sample // 环形缓冲区
+	next      int
+	lastError string
+	lastAt    time.Time
+	token     TokenReporter
+}
+
+// Status 是一个服务的健康状况
+type Status struct {
+	Name           string     `json:"name"`
+	State          string     `json:"state"`
+	Region         string     `json:"region,omitempty"`
+	Requests       int        `json:"requests"` // 窗口内的请求数
+	Errors         int        `json:"errors"`
+	Throttled      int        `json:"throttled"`
+	ErrorRate      float64    `json:"error_rate"`
+	P50LatencyMs   int64      `json:"p50_latency_ms"`
+	P95LatencyMs   int64      `json:"p95_latency_ms"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastErrorAt    *time.Time `json:"last_error_at,omitempty"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty"`
+}
+
+// Get 返回名为 name 的记录器，不存在时创建
+func Get(name string) *Monitor {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if m, ok := registry[name]; ok {
+		return m
+	}
+	m := &Monitor{name: name}
+	registry[name] = m
+	return m
+}
+
+// All 返回所有服务的健康状况，按名称排序
+func All() []Status {
+	registryMu.Lock()
+	monitors := make([]*Monitor, 0, len(registry))
+	for _, m := range registry {
+		monitors = append(monitors, m)
+	}
+	registryMu.Unlock()
+
+	statuses := make([]Status, 0, len(monitors))
+	for _, m := range monitors {
+		statuses = append(statuses, m.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Name 返回记录器对应的服务名称
+func (m *Monitor) Name() string {
+	return m.name
+}
+
+// Attach 记录服务的令牌来源，服务没有实现 TokenReporter 时忽略
+func (m *Monitor) Attach(service any) {
+	reporter, ok := service.(TokenReporter)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = reporter
+}
+
+// Record 记录一次请求的耗时与结果。客户端取消与请求本身无效导致的错误不反映上游状况，不计入
+func (m *Monitor) Record(latency time.Duration, err error) {
+	if err != nil && !Upstream(err) {
+		return
+	}
+	s := sample{at: time.Now(), latency: latency, failed: err != nil}
+	s.throttled = s.failed && apperr.CodeOf(err) == apperr.CodeProviderThrottled
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.samples) < maxSamples {
+		m.samples = append(m.samples, s)
+	} else {
+		m.samples[m.next] = s
+		m.next = (m.next + 1) % maxSamples
+	}
+	if err != nil {
+		m.lastError = err.Error()
+		m.lastAt = s.at
+	}
+}
+
+// Status 汇总窗口内的请求结果
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	status := Status{Name: m.name, State: StateIdle}
+	since := time.Now().Add(-window)
+	latencies := make([]time.Duration, 0, len(m.samples))
+	for _, s := range m.samples {
+		if s.at.Before(since) {
+			continue
+		}
+		latencies = append(latencies, s.latency)
+		if s.failed {
+			status.Errors++
+		}
+		if s.throttled {
+			status.Throttled++
+		}
+	}
+	if m.lastError != "" {
+		at := m.lastAt
+		status.LastError, status.LastErrorAt = m.lastError, &at
+	}
+	token := m.token
+	m.mu.Unlock()
+
+	if token != nil {
+		region, expiry := token.TokenStatus()
+		status.Region = region
+		if !expiry.IsZero() {
+			status.TokenExpiresAt = &expiry
+		}
+	}
+
+	status.Requests = len(latencies)
+	if status.Requests == 0 {
+		return status
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	status.P50LatencyMs = quantile(latencies, 0.5).Milliseconds()
+	status.P95LatencyMs = quantile(latencies, 0.95).Milliseconds()
+	status.ErrorRate = float64(status.Errors) / float64(status.Requests)
+	switch {
+	case status.ErrorRate >= unhealthyRate:
+		status.State = StateUnhealthy
+	case status.ErrorRate >= degradedRate:
+		status.State = StateDegraded
+	default:
+		status.State = StateHealthy
+	}
+	return status
+}
+
+// quantile 返回已排序耗时的分位数
+func quantile(sorted []time.Duration, q float64) time.Duration {
+	i := int(q*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// Upstream 判断错误是否由上游服务或本服务内部引起，客户端取消与请求本身无效导致的错误返回 false
+func Upstream(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch apperr.CodeOf(err) {
+	case apperr.CodeProviderThrottled, apperr.CodeProviderAuthFailed, apperr.CodeProviderError, apperr.CodeInternal:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tts/internal/apikey"
+	"tts/internal/apperr"
+	"tts/internal/cache"
+	"tts/internal/config"
+	"tts/internal/feature"
+	"tts/internal/health"
+	"tts/internal/http/middleware"
+	"tts/internal/jobs"
+	"tts/internal/metrics"
+	"tts/internal/pool"
+	"tts/internal/schedule"
+	"tts/internal/watermark"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 处理管理接口请求
+type AdminHandler struct {
+	scheduler *schedule.Scheduler
+	jobs      *jobs.Manager
+	cache     *cache.Cache
+	config    *config.Config
+	started   time.Time
+}
+
+// NewAdminHandler 创建管理接口处理器，未启用定时任务时 scheduler 为 nil，未启用异步合成任务时 jobManager 为 nil
+func NewAdminHandler(scheduler *schedule.Scheduler, jobManager *jobs.Manager, audioCache *cache.Cache, cfg *config.Config) *AdminHandler {
+	return &AdminHandler{scheduler: scheduler, jobs: jobManager, cache: audioCache, config: cfg, started: time.Now()}
+}
+
+// maxInspectSize 是水印检查接口接受的音频大小上限
+const maxInspectSize = 64 << 20
+
+// HandleWatermark 读取请求体中音频的水印信息
+func (h *AdminHandler) HandleWatermark(c *gin.Context) {
+	audio, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxInspectSize))
+	if err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "读取音频失败", err))
+		return
+	}
+	info, found := watermark.Read(audio)
+	if !found {
+		c.JSON(http.StatusOK, gin.H{"found": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"found": true, "watermark": info})
+}
+
+// queryLimit 读取 limit 参数，未提供时返回 fallback，不是正整数时返回错误
+func queryLimit(c *gin.Context, fallback int) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, apperr.New(apperr.CodeInvalidRequest, "limit 必须是正整数")
+	}
+	return n, nil
+}
+
+// HandleJobs 返回所有定时任务的状态
+func (h *AdminHandler) HandleJobs(c *gin.Context) {
+	if h.scheduler == nil {
+		c.JSON(http.StatusOK, gin.H{"jobs": []schedule.JobStatus{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": h.scheduler.Jobs()})
+}
+
+// HandleJobHistory 返回定时任务的执行记录，可通过 job 参数筛选，limit 限制数量（默认 50）
+func (h *AdminHandler) HandleJobHistory(c *gin.Context) {
+	limit, err := queryLimit(c, 50)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	if h.scheduler == nil {
+		c.JSON(http.StatusOK, gin.H{"runs": []schedule.Run{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": h.scheduler.History(c.Query("job"), limit)})
+}
+
+// HandleRunJob 立即执行指定的定时任务并返回执行记录
+func (h *AdminHandler) HandleRunJob(c *gin.Context) {
+	if h.scheduler == nil {
+		apperr.Abort(c, apperr.Newf(apperr.CodeNotFound, "任务不存在: %s", c.Param("name")))
+		return
+	}
+	run, err := h.scheduler.Trigger(c.Request.Context(), c.Param("name"))
+	if run == nil {
+		apperr.Abort(c, err)
+		return
+	}
+	// 执行失败也返回记录，错误详情见 error 字段
+	c.JSON(http.StatusOK, run)
+}
+
+// HandlePools 返回所有并发池的上限、进行中与排队的请求数
+func (h *AdminHandler) HandlePools(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"pools": pool.All()})
+}
+
+// HandleResizePool 调整并发池的上限，请求体为 {"limit": 16}，0 表示不限制
+func (h *AdminHandler) HandleResizePool(c *gin.Context) {
+	p, ok := pool.Lookup(c.Param("name"))
+	if !ok {
+		apperr.Abort(c, apperr.Newf(apperr.CodeNotFound, "并发池不存在: %s", c.Param("name")))
+		return
+	}
+	var body struct {
+		Limit *int `json:"limit"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Limit == nil || *body.Limit < 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "limit 必须是非负整数"))
+		return
+	}
+	p.Resize(*body.Limit)
+	c.JSON(http.StatusOK, p.Stats())
+}
+
+// keyUsage 是一个密钥累计合成的字符数
+type keyUsage struct {
+	Key        string  `json:"key"`
+	Characters float64 `json:"characters"`
+}
+
+// HandleStats 汇总管理页面展示的运行状态：请求数、各密钥用量、缓存命中、服务健康状况、并发池与异步任务。
+// 计数均为本实例启动以来的累计值，管理页面按两次读取的差值计算速率
+func (h *AdminHandler) HandleStats(c *gin.Context) {
+	var requests, serverErrors float64
+	for _, sample := range metrics.Samples("tts_http_requests_total") {
+		requests += sample.Value
+		if status, _ := strconv.Atoi(sample.Labels["status"]); status >= 500 {
+			serverErrors += sample.Value
+		}
+	}
+
+	usage := []keyUsage{}
+	for _, sample := range metrics.Samples("tts_characters_total") {
+		usage = append(usage, keyUsage{Key: sample.Labels["key"], Characters: sample.Value})
+	}
+
+	var hits, misses float64
+	for _, sample := range metrics.Samples("tts_synthesis_cache_total") {
+		switch sample.Labels["result"] {
+		case "hit":
+			hits += sample.Value
+		case "miss":
+			misses += sample.Value
+		}
+	}
+
+	stats := gin.H{
+		"started":        h.started,
+		"uptime_seconds": int64(time.Since(h.started).Seconds()),
+		"requests":       gin.H{"total": requests, "server_errors": serverErrors},
+		"usage":          usage,
+		"cache":          gin.H{"entries": h.cache.Len(), "hits": hits, "misses": misses},
+		"providers":      health.All(),
+		"pools":          pool.All(),
+	}
+	if h.jobs != nil {
+		stats["jobs"] = h.jobs.Counts()
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// HandleRequests 返回最近的请求，limit 限制数量（默认 50）
+func (h *AdminHandler) HandleRequests(c *gin.Context) {
+	limit, err := queryLimit(c, 50)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": middleware.RecentRequests(limit)})
+}
+
+// HandleSynthesisJobs 返回所有密钥的异步合成任务，可通过 status 参数筛选，limit 限制数量（默认 50）
+func (h *AdminHandler) HandleSynthesisJobs(c *gin.Context) {
+	limit, err := queryLimit(c, 50)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	if h.jobs == nil {
+		c.JSON(http.StatusOK, gin.H{"jobs": []jobs.Job{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": h.jobs.ListAll(c.Query("status"), limit)})
+}
+
+// HandleFeatures 返回所有功能开关的状态
+func (h *AdminHandler) HandleFeatures(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"features": feature.All()})
+}
+
+// HandleSetFeature 在运行时切换功能开关，请求体为 {"enabled": true, "key": "partner-a"}。
+// key 为空时设置全局状态，enabled 为 null 时清除运行时的设置；重启后恢复为配置文件中的状态
+func (h *AdminHandler) HandleSetFeature(c *gin.Context) {
+	flag, ok := feature.Lookup(c.Param("name"))
+	if !ok {
+		apperr.Abort(c, apperr.Newf(apperr.CodeNotFound, "功能开关不存在: %s", c.Param("name")))
+		return
+	}
+	var body struct {
+		Enabled *bool  `json:"enabled"`
+		Key     string `json:"key"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的请求格式", err))
+		return
+	}
+	if body.Key != "" && apikey.ByName(h.config, body.Key) == nil {
+		apperr.Abort(c, apperr.Newf(apperr.CodeNotFound, "密钥不存在: %s", body.Key))
+		return
+	}
+	flag.Set(body.Key, body.Enabled)
+	for _, status := range feature.All() {
+		if status.Name == flag.Name {
+			c.JSON(http.StatusOK, status)
+			return
+		}
+	}
+}
+
+// HandleExportCache 把缓存中的全部条目导出为 tar.gz，可以通过导入接口或 tts cache import 导入另一个实例
+func (h *AdminHandler) HandleExportCache(c *gin.Context) {
+	if h.config.Cache.Dir == "" {
+		apperr.Abort(c, apperr.New(apperr.CodeNotSupported, "未配置 cache.dir，无法导出缓存"))
+		return
+	}
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", `attachment; filename="tts-cache.tar.gz"`)
+	c.Status(http.StatusOK)
+	count, err := h.cache.Export(c.Writer)
+	if err != nil {
+		log.Printf("导出缓存失败: %v", err)
+		return
+	}
+	log.Printf("导出缓存完成: 条目数 %d", count)
+}
+
+// HandleImportCache 导入请求体中的缓存导出文件，返回导入的条目数
+func (h *AdminHandler) HandleImportCache(c *gin.Context) {
+	if h.config.Cache.Dir == "" {
+		apperr.Abort(c, apperr.New(apperr.CodeNotSupported, "未配置 cache.dir，无法导入缓存"))
+		return
+	}
+	count, err := h.cache.Import(c.Request.Body)
+	if err != nil {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "导入缓存失败（已导入 %d 条）: %v", count, err))
+		return
+	}
+	log.Printf("导入缓存完成: 条目数 %d", count)
+	c.JSON(http.StatusOK, gin.H{"imported": count})
+}
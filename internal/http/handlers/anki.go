@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"archive/zip"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"tts/internal/anki"
+	"tts/internal/apperr"
+	"tts/internal/models"
+	"tts/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAnkiCards 单次请求允许的最大卡片数
+const maxAnkiCards = 500
+
+// ankiDelimiters 是 delimiter 参数可以使用的分隔符
+var ankiDelimiters = map[string]rune{"tab": '\t', "comma": ',', "semicolon": ';'}
+
+// ankiTask 是一张卡片一面的合成任务
+type ankiTask struct {
+	card  int
+	back  bool
+	text  string
+	voice string
+	name  string
+	audio []byte
+	err   error
+}
+
+// HandleAnki 为 CSV/TSV 中的每张卡片合成音频，打包为 zip 返回：音频文件可以直接复制到 Anki 的
+// collection.media 文件夹，notes.txt 是附带声音标记、可以用“导入文件”导入的卡片列表
+func (h *TTSHandler) HandleAnki(c *gin.Context) {
+	startTime := time.Now()
+
+	side := c.DefaultQuery("side", "front")
+	if side != "front" && side != "back" && side != "both" {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "不支持的 side: %s", side))
+		return
+	}
+	var delimiter rune
+	if name := c.Query("delimiter"); name != "" {
+		var ok bool
+		if delimiter, ok = ankiDelimiters[name]; !ok {
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "不支持的分隔符: %s", name))
+			return
+		}
+	} else if c.ContentType() == "text/tab-separated-values" {
+		delimiter = '\t'
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "读取请求体失败", err))
+		return
+	}
+	cards, err := anki.Parse(body, delimiter)
+	if err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无法解析卡片列表", err))
+		return
+	}
+	if len(cards) == 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "卡片列表为空"))
+		return
+	}
+	if len(cards) > maxAnkiCards {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "卡片数量超过限制 (%d > %d)", len(cards), maxAnkiCards))
+		return
+	}
+
+	voice := c.Query("voice")
+	backVoice := c.DefaultQuery("back_voice", voice)
+	names := anki.NewNames(c.Query("prefix"))
+	var tasks []*ankiTask
+	for i, card := range cards {
+		if side != "back" && card.FrontText != "" {
+			tasks = append(tasks, &ankiTask{card: i, text: card.FrontText, voice: voice, name: names.Assign(card, i, "")})
+		}
+		if side != "front" && card.BackText != "" {
+			suffix := ""
+			if side == "both" {
+				suffix = "_back"
+			}
+			tasks = append(tasks, &ankiTask{card: i, back: true, text: card.BackText, voice: backVoice, name: names.Assign(card, i, suffix)})
+		}
+	}
+	if len(tasks) == 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "卡片中没有可以朗读的文本"))
+		return
+	}
+	for _, task := range tasks {
+		if length := utils.GraphemeCount(task.text); length > h.config.TTS.MaxTextLength {
+			apperr.Abort(c, apperr.Newf(apperr.CodeTextTooLong, "第 %d 行文本长度超过限制 (%d > %d)",
+				cards[task.card].Line, length, h.config.TTS.MaxTextLength))
+			return
+		}
+	}
+
+	h.renderCards(c, tasks)
+
+	frontSounds := make([]string, len(cards))
+	backSounds := make([]string, len(cards))
+	failed := 0
+	for _, task := range tasks {
+		switch {
+		case task.err != nil:
+			failed++
+		case task.back:
+			backSounds[task.card] = task.name
+		default:
+			frontSounds[task.card] = task.name
+		}
+	}
+	if failed == len(tasks) {
+		apperr.Abort(c, tasks[0].err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="anki.zip"`)
+	c.Header("X-Failed-Cards", strconv.Itoa(failed))
+	c.Status(http.StatusOK)
+	if err := writeAnkiZip(c.Writer, tasks, cards, frontSounds, backSounds); err != nil {
+		log.Printf("写入 Anki 压缩包失败: %v", err)
+		return
+	}
+
+	log.Printf("Anki 音频导出完成: 卡片数 %d, 音频数 %d, 失败 %d, 总耗时 %v", len(cards), len(tasks)-failed, failed, time.Since(startTime))
+}
+
+// renderCards 以有限并发合成各个任务
+func (h *TTSHandler) renderCards(c *gin.Context, tasks []*ankiTask) {
+	semaphore := make(chan struct{}, max(1, h.config.TTS.MaxConcurrent))
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task *ankiTask) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ttsReq := models.TTSRequest{
+				Text:  task.text,
+				Voice: task.voice,
+				Rate:  c.Query("rate"),
+				Pitch: c.Query("pitch"),
+				Style: c.Query("style"),
+			}
+			h.fillDefaultValues(&ttsReq)
+
+			resp, err := synthesize(c, h.synthesizer, ttsReq)
+			if err == nil {
+				task.audio, _, err = stampAudio(c, h.config, resp.AudioContent)
+			}
+			if err != nil {
+				log.Printf("卡片音频 %s 合成失败: %v", task.name, err)
+				task.err = err
+			}
+		}(task)
+	}
+	wg.Wait()
+}
+
+// writeAnkiZip 将各卡片的音频与 notes.txt 写入 zip 包
+func writeAnkiZip(w io.Writer, tasks []*ankiTask, cards []anki.Card, frontSounds, backSounds []string) error {
+	zw := zip.NewWriter(w)
+	for _, task := range tasks {
+		if task.err != nil {
+			continue
+		}
+		// MP3 已经压缩，直接存储
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: task.name, Method: zip.Store, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(task.audio); err != nil {
+			return err
+		}
+	}
+
+	notes, err := zw.CreateHeader(&zip.FileHeader{Name: "notes.txt", Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	if err := anki.WriteNotes(notes, cards, frontSounds, backSounds); err != nil {
+		return err
+	}
+	return zw.Close()
+}
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"strconv"
+
+	"tts/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChecksumHeader 是返回音频 SHA-256 的响应头，下游可以据此校验传输是否完整
+const ChecksumHeader = "X-Content-SHA256"
+
+// setChecksum 设置完整音频的 SHA-256 与字节长度响应头。显式设置 Content-Length，
+// 较大的音频不再使用分块传输编码，客户端可以据此判断下载是否完整
+func setChecksum(c *gin.Context, audio []byte) {
+	c.Header(ChecksumHeader, storage.Checksum(audio))
+	c.Header("Content-Length", strconv.Itoa(len(audio)))
+}
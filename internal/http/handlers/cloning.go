@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/customvoice"
+	"tts/internal/tts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 未配置时的语音克隆限制
+const (
+	defaultMaxCustomVoices = 10
+	defaultMaxSamples      = 10
+	defaultMaxSampleMB     = 10
+)
+
+// customVoiceName 匹配自定义语音的名称：字母、数字、下划线、点与连字符，最长 64 个字符
+var customVoiceName = regexp.MustCompile(`^[\p{L}\p{N}_.-]{1,64}$`)
+
+// CloningHandler 处理自定义语音的创建、列出与删除，语音按密钥保存
+type CloningHandler struct {
+	ttsService tts.Service
+	voices     *customvoice.Registry
+	config     *config.Config
+}
+
+// NewCloningHandler 创建语音克隆处理器
+func NewCloningHandler(service tts.Service, voices *customvoice.Registry, cfg *config.Config) *CloningHandler {
+	return &CloningHandler{ttsService: service, voices: voices, config: cfg}
+}
+
+// HandleList 返回请求方创建的自定义语音
+func (h *CloningHandler) HandleList(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"voices": h.voices.List(config.TenantFromContext(c.Request.Context()))})
+}
+
+// HandleCreate 用上传的参考音频在当前服务中创建自定义语音。请求为 multipart/form-data：
+// name 为语音名称，samples 为一段或多段参考音频，可选的 description、language 与 consent（说话人的同意声明录音）
+func (h *CloningHandler) HandleCreate(c *gin.Context) {
+	provider := h.config.TTS.Provider
+	if provider == "" {
+		provider = tts.DefaultProvider
+	}
+	cloner, ok := tts.ClonerFor(provider)
+	if !ok {
+		apperr.Abort(c, apperr.Newf(apperr.CodeNotSupported, "当前TTS服务 (%s) 不支持语音克隆", provider))
+		return
+	}
+
+	maxSampleBytes := int64(positive(h.config.Cloning.MaxSampleMB, defaultMaxSampleMB)) << 20
+	if err := c.Request.ParseMultipartForm(maxSampleBytes); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的表单请求", err))
+		return
+	}
+	form := c.Request.MultipartForm
+	defer form.RemoveAll()
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	if !customVoiceName.MatchString(name) {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "name 只能包含字母、数字、下划线、点与连字符，最长 64 个字符"))
+		return
+	}
+	tenant := config.TenantFromContext(c.Request.Context())
+	if _, exists := h.voices.Lookup(tenant, name); exists {
+		apperr.Abort(c, apperr.Newf(apperr.CodeConflict, "自定义语音已存在: %s", name))
+		return
+	}
+	if h.isProviderVoice(c, name) {
+		apperr.Abort(c, apperr.Newf(apperr.CodeConflict, "名称与服务中的语音相同: %s", name))
+		return
+	}
+	if maxVoices := positive(h.config.Cloning.MaxVoices, defaultMaxCustomVoices); len(h.voices.List(tenant)) >= maxVoices {
+		apperr.Abort(c, apperr.Newf(apperr.CodeConflict, "自定义语音数量已达上限 (%d)", maxVoices))
+		return
+	}
+
+	headers := form.File["samples"]
+	if len(headers) == 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "至少需要上传一段参考音频 (samples)"))
+		return
+	}
+	if maxSamples := positive(h.config.Cloning.MaxSamples, defaultMaxSamples); len(headers) > maxSamples {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "参考音频数量超过限制 (%d > %d)", len(headers), maxSamples))
+		return
+	}
+	req := tts.CloneRequest{
+		Name:        name,
+		Description: c.PostForm("description"),
+		Language:    c.PostForm("language"),
+	}
+	for _, header := range headers {
+		sample, err := readSample(header, maxSampleBytes)
+		if err != nil {
+			apperr.Abort(c, err)
+			return
+		}
+		req.Samples = append(req.Samples, sample)
+	}
+	if consent := form.File["consent"]; len(consent) > 0 {
+		sample, err := readSample(consent[0], maxSampleBytes)
+		if err != nil {
+			apperr.Abort(c, err)
+			return
+		}
+		req.Consent = &sample
+	}
+
+	voiceID, err := cloner.CloneVoice(c.Request.Context(), req)
+	if err != nil {
+		log.Printf("创建自定义语音失败: %v", err)
+		apperr.Abort(c, err)
+		return
+	}
+	voice := customvoice.Voice{
+		Name:        name,
+		Provider:    provider,
+		VoiceID:     voiceID,
+		Language:    req.Language,
+		Description: req.Description,
+		Created:     time.Now().UTC(),
+	}
+	added, err := h.voices.Add(tenant, voice)
+	if err != nil || !added {
+		// 同名语音在创建期间被并发创建，或保存失败，删除上游的语音避免遗留
+		if deleteErr := cloner.DeleteVoice(c.Request.Context(), voiceID); deleteErr != nil {
+			log.Printf("删除上游自定义语音 %s 失败: %v", voiceID, deleteErr)
+		}
+		if err != nil {
+			apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "保存自定义语音失败", err))
+		} else {
+			apperr.Abort(c, apperr.Newf(apperr.CodeConflict, "自定义语音已存在: %s", name))
+		}
+		return
+	}
+	log.Printf("已创建自定义语音: %s (%s %s)", name, provider, voiceID)
+	c.JSON(http.StatusCreated, voice)
+}
+
+// HandleDelete 删除自定义语音，同时删除上游服务中的语音
+func (h *CloningHandler) HandleDelete(c *gin.Context) {
+	tenant := config.TenantFromContext(c.Request.Context())
+	voice, ok := h.voices.Lookup(tenant, c.Param("name"))
+	if !ok {
+		apperr.Abort(c, apperr.Newf(apperr.CodeNotFound, "自定义语音不存在: %s", c.Param("name")))
+		return
+	}
+	if cloner, ok := tts.ClonerFor(voice.Provider); ok {
+		if err := cloner.DeleteVoice(c.Request.Context(), voice.VoiceID); err != nil {
+			log.Printf("删除上游自定义语音 %s 失败: %v", voice.VoiceID, err)
+			apperr.Abort(c, err)
+			return
+		}
+	} else {
+		// 创建语音的服务已不再使用，只删除记录
+		log.Printf("服务 %s 不可用，只删除自定义语音 %s 的记录", voice.Provider, voice.Name)
+	}
+	if _, _, err := h.voices.Remove(tenant, voice.Name); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "删除自定义语音失败", err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// isProviderVoice 判断名称是否与服务中的语音相同，避免自定义语音遮盖已有的语音；获取列表失败时不检查
+func (h *CloningHandler) isProviderVoice(c *gin.Context, name string) bool {
+	voices, err := h.ttsService.ListVoices(c.Request.Context(), "")
+	if err != nil {
+		return false
+	}
+	for _, voice := range voices {
+		if strings.EqualFold(voice.ShortName, name) || strings.EqualFold(voice.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// readSample 读取上传的一段音频
+func readSample(header *multipart.FileHeader, maxBytes int64) (tts.Sample, error) {
+	if header.Size > maxBytes {
+		return tts.Sample{}, apperr.Newf(apperr.CodePayloadTooLarge, "参考音频 %s 超过 %d MB", header.Filename, maxBytes>>20)
+	}
+	f, err := header.Open()
+	if err != nil {
+		return tts.Sample{}, apperr.Wrap(apperr.CodeInvalidRequest, "读取参考音频失败", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return tts.Sample{}, apperr.Wrap(apperr.CodeInvalidRequest, "读取参考音频失败", err)
+	}
+	return tts.Sample{Filename: header.Filename, ContentType: header.Header.Get("Content-Type"), Data: data}, nil
+}
+
+// positive 返回 value，value 不大于 0 时返回 fallback
+func positive(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
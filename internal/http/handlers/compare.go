@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/models"
+	"tts/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCompareVoices 单次对比请求允许的最大语音数
+const maxCompareVoices = 10
+
+// unsafeFileChars 匹配文件名中不安全的字符
+var unsafeFileChars = regexp.MustCompile(`[^\w.-]+`)
+
+// HandleCompare 使用多个语音并发合成同一段文本，打包返回用于挑选朗读语音
+func (h *TTSHandler) HandleCompare(c *gin.Context) {
+	startTime := time.Now()
+
+	var req models.CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+		return
+	}
+	if req.Text == "" {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "必须提供文本参数"))
+		return
+	}
+	if len(req.Voices) == 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "voices不能为空"))
+		return
+	}
+	if len(req.Voices) > maxCompareVoices {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "voices数量超过限制 (%d > %d)", len(req.Voices), maxCompareVoices))
+		return
+	}
+	if req.Format == "" {
+		req.Format = "zip"
+	}
+	if req.Format != "zip" && req.Format != "json" {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "不支持的格式: %s", req.Format))
+		return
+	}
+	if length := utils.GraphemeCount(req.Text); length > h.config.TTS.MaxTextLength {
+		apperr.Abort(c, apperr.Newf(apperr.CodeTextTooLong, "文本长度超过限制 (%d > %d)", length, h.config.TTS.MaxTextLength))
+		return
+	}
+
+	results, audio := h.renderVoices(c, req)
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed == len(results) {
+		apperr.Abort(c, apperr.New(apperr.CodeProviderError, "所有语音均合成失败"))
+		return
+	}
+
+	if req.Format == "json" {
+		for i := range results {
+			if audio[i] != nil {
+				results[i].URL = "data:audio/mpeg;base64," + base64.StdEncoding.EncodeToString(audio[i])
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"results": results})
+	} else {
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", `attachment; filename="voices.zip"`)
+		c.Status(http.StatusOK)
+		if err := writeCompareZip(c.Writer, results, audio); err != nil {
+			log.Printf("写入对比压缩包失败: %v", err)
+			return
+		}
+	}
+
+	log.Printf("多语音对比完成: 语音数 %d, 失败 %d, 总耗时 %v", len(results), failed, time.Since(startTime))
+}
+
+// renderVoices 以有限并发使用每个语音合成文本，结果顺序与请求一致
+func (h *TTSHandler) renderVoices(c *gin.Context, req models.CompareRequest) ([]models.CompareResult, [][]byte) {
+	results := make([]models.CompareResult, len(req.Voices))
+	audio := make([][]byte, len(req.Voices))
+
+	semaphore := make(chan struct{}, max(1, h.config.TTS.MaxConcurrent))
+	var wg sync.WaitGroup
+	for i, voice := range req.Voices {
+		wg.Add(1)
+		go func(index int, voice string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ttsReq := models.TTSRequest{
+				Text:  req.Text,
+				Voice: voice,
+				Rate:  req.Rate,
+				Pitch: req.Pitch,
+				Style: req.Style,
+			}
+			h.fillDefaultValues(&ttsReq)
+
+			start := time.Now()
+			resp, err := synthesize(c, h.synthesizer, ttsReq)
+			results[index] = models.CompareResult{
+				Voice:     ttsReq.Voice,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				log.Printf("语音 %s 合成失败: %v", ttsReq.Voice, err)
+				results[index].Error = apperr.From(err).Message
+				return
+			}
+			stamped, _, err := stampAudio(c, h.config, resp.AudioContent)
+			if err != nil {
+				log.Printf("语音 %s 添加水印失败: %v", ttsReq.Voice, err)
+				results[index].Error = apperr.From(err).Message
+				return
+			}
+			results[index].File = fmt.Sprintf("%02d-%s.mp3", index+1, unsafeFileChars.ReplaceAllString(ttsReq.Voice, "_"))
+			results[index].Size = len(stamped)
+			audio[index] = stamped
+		}(i, voice)
+	}
+	wg.Wait()
+	return results, audio
+}
+
+// writeCompareZip 将各语音的音频与 manifest.json 写入 zip 包
+func writeCompareZip(w http.ResponseWriter, results []models.CompareResult, audio [][]byte) error {
+	zw := zip.NewWriter(w)
+	for i, result := range results {
+		if audio[i] == nil {
+			continue
+		}
+		// MP3 已经压缩，直接存储
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: result.File, Method: zip.Store, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(audio[i]); err != nil {
+			return err
+		}
+	}
+
+	manifest, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifest)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(results); err != nil {
+		return err
+	}
+	return zw.Close()
+}
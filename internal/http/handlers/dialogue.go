@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/audio"
+	"tts/internal/models"
+	"tts/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxDialogueTurns 单次对话请求允许的最大轮数
+	maxDialogueTurns = 200
+	// defaultDialogueGap 未指定 gap_ms 时轮次之间的停顿
+	defaultDialogueGap = 300 * time.Millisecond
+	// maxDialogueGap 轮次之间停顿的上限
+	maxDialogueGap = 5 * time.Second
+	// dialogueSpread 自动分配声像时说话人分布的范围，两端不完全偏到一侧，耳机收听不致疲劳
+	dialogueSpread = 0.6
+)
+
+// HandleDialogue 合成多人对话：各轮以有限并发使用各自的语音合成，按顺序拼接并在轮次之间插入停顿；
+// stereo 为 true 时输出立体声，不同说话人放在不同的声像位置，便于分辨
+func (h *TTSHandler) HandleDialogue(c *gin.Context) {
+	startTime := time.Now()
+
+	var req models.DialogueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+		return
+	}
+	if len(req.Turns) == 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "turns不能为空"))
+		return
+	}
+	if len(req.Turns) > maxDialogueTurns {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "turns数量超过限制 (%d > %d)", len(req.Turns), maxDialogueTurns))
+		return
+	}
+	gap := defaultDialogueGap
+	if req.GapMs != nil {
+		gap = time.Duration(*req.GapMs) * time.Millisecond
+		if gap < 0 || gap > maxDialogueGap {
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "gap_ms 需要在 0～%d 之间", maxDialogueGap.Milliseconds()))
+			return
+		}
+	}
+	for voice, pan := range req.Pan {
+		if pan < -1 || pan > 1 {
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "语音 %s 的声像位置需要在 -1～1 之间", voice))
+			return
+		}
+	}
+
+	turns := make([]models.TTSRequest, len(req.Turns))
+	total := 0
+	for i, turn := range req.Turns {
+		if turn.Text == "" {
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "第 %d 轮的 text 不能为空", i+1))
+			return
+		}
+		total += utils.GraphemeCount(turn.Text)
+		turns[i] = models.TTSRequest{Text: turn.Text, Voice: turn.Voice, Rate: req.Rate, Pitch: req.Pitch, Style: turn.Style}
+		h.fillDefaultValues(&turns[i])
+	}
+	if total > h.config.TTS.MaxTextLength {
+		apperr.Abort(c, apperr.Newf(apperr.CodeTextTooLong, "文本长度超过限制 (%d > %d)", total, h.config.TTS.MaxTextLength))
+		return
+	}
+
+	tracks, err := h.renderTurns(c, turns)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	if req.Stereo {
+		pans := dialoguePans(turns, req.Pan)
+		for i := range tracks {
+			tracks[i].Pan = pans[turns[i].Voice]
+		}
+	}
+	merged, err := audio.Concat(tracks, gap, req.Stereo)
+	if err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "音频拼接失败", err))
+		return
+	}
+
+	stamped, ok := writeStamped(c, h.config, merged)
+	if !ok {
+		return
+	}
+	c.Header("Content-Type", "audio/mpeg")
+	if _, err := c.Writer.Write(stamped); err != nil {
+		log.Printf("写入响应失败: %v", err)
+		return
+	}
+	log.Printf("对话合成完成: 轮数 %d, 立体声 %t, 音频大小: %s, 总耗时 %v",
+		len(turns), req.Stereo, utils.FormatFileSize(len(stamped)), time.Since(startTime))
+}
+
+// renderTurns 以有限并发合成对话的各轮，结果顺序与请求一致，任一轮失败时返回其错误
+func (h *TTSHandler) renderTurns(c *gin.Context, turns []models.TTSRequest) ([]audio.Track, error) {
+	tracks := make([]audio.Track, len(turns))
+	errs := make([]error, len(turns))
+
+	semaphore := make(chan struct{}, max(1, h.config.TTS.MaxConcurrent))
+	var wg sync.WaitGroup
+	for i := range turns {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			resp, err := synthesize(c, h.synthesizer, turns[index])
+			if err != nil {
+				errs[index] = err
+				return
+			}
+			tracks[index].Data = resp.AudioContent
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("对话第 %d 轮合成失败: %v", i+1, err)
+			return nil, err
+		}
+	}
+	return tracks, nil
+}
+
+// dialoguePans 为每个说话人确定声像位置：请求中指定的优先，其余按首次出现的顺序在左右之间均匀分布，
+// 只有一个说话人时居中
+func dialoguePans(turns []models.TTSRequest, fixed map[string]float64) map[string]float64 {
+	var speakers []string
+	seen := make(map[string]bool)
+	for _, turn := range turns {
+		if !seen[turn.Voice] {
+			seen[turn.Voice] = true
+			speakers = append(speakers, turn.Voice)
+		}
+	}
+	pans := make(map[string]float64, len(speakers))
+	for i, voice := range speakers {
+		if pan, ok := fixed[voice]; ok {
+			pans[voice] = pan
+		} else if len(speakers) > 1 {
+			pans[voice] = -dialogueSpread + 2*dialogueSpread*float64(i)/float64(len(speakers)-1)
+		}
+	}
+	return pans
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/eval"
+)
+
+// EvalHandler 处理语音质量评测：评测页面获取比较组合、音频与提交评分使用 eval.token，
+// 创建评测与查看报告的接口使用管理令牌
+type EvalHandler struct {
+	evaluator *eval.Evaluator
+	config    *config.Config
+}
+
+// NewEvalHandler 创建评测处理器
+func NewEvalHandler(evaluator *eval.Evaluator, cfg *config.Config) *EvalHandler {
+	return &EvalHandler{evaluator: evaluator, config: cfg}
+}
+
+// evalStartRequest 是创建评测的请求，字段为空时使用配置中的提示句与候选语音
+type evalStartRequest struct {
+	Prompts    []string         `json:"prompts"`
+	Candidates []eval.Candidate `json:"candidates"`
+}
+
+// evalVoteRequest 是评测页面提交的评分
+type evalVoteRequest struct {
+	Pair       string `json:"pair" binding:"required"`
+	Preference string `json:"preference" binding:"required"`
+	ScoreA     int    `json:"score_a"`
+	ScoreB     int    `json:"score_b"`
+	Rater      string `json:"rater"`
+}
+
+// HandleStart 创建评测并在后台合成音频，返回 202 与评测记录
+func (h *EvalHandler) HandleStart(c *gin.Context) {
+	var req evalStartRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+			return
+		}
+	}
+	run, err := h.evaluator.Start(req.Prompts, req.Candidates)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, run)
+}
+
+// HandleRuns 返回全部评测，最近创建的在前
+func (h *EvalHandler) HandleRuns(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"runs": h.evaluator.Runs()})
+}
+
+// HandleReport 返回评测的汇总报告
+func (h *EvalHandler) HandleReport(c *gin.Context) {
+	report, err := h.evaluator.Report(c.Param("id"))
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// HandlePair 为评测人分配下一组比较。run 查询参数为空时使用最近完成的评测
+func (h *EvalHandler) HandlePair(c *gin.Context) {
+	if !h.authorized(c) {
+		return
+	}
+	runID := c.Query("run")
+	if runID == "" {
+		run, ok := h.evaluator.Latest()
+		if !ok {
+			apperr.Abort(c, apperr.New(apperr.CodeNotFound, "没有已完成的评测"))
+			return
+		}
+		runID = run.ID
+	}
+	pair, err := h.evaluator.NextPair(runID, c.Query("rater"))
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, pair)
+}
+
+// HandleVote 记录一组比较的评分
+func (h *EvalHandler) HandleVote(c *gin.Context) {
+	if !h.authorized(c) {
+		return
+	}
+	var req evalVoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+		return
+	}
+	if err := h.evaluator.Submit(req.Pair, req.Preference, req.ScoreA, req.ScoreB, req.Rater); err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// HandleAudio 返回评测中的一个音频
+func (h *EvalHandler) HandleAudio(c *gin.Context) {
+	if !h.authorized(c) {
+		return
+	}
+	file, err := h.evaluator.Audio(c.Param("run"), c.Param("sample"))
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "audio/mpeg")
+	c.Header("Cache-Control", "private, max-age=3600")
+	http.ServeContent(c.Writer, c.Request, file.Name(), file.ModTime(), file)
+}
+
+// authorized 检查 token 查询参数，未配置 eval.token 时不认证
+func (h *EvalHandler) authorized(c *gin.Context) bool {
+	token := h.config.Eval.Token
+	if token == "" || subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(token)) == 1 {
+		return true
+	}
+	apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, "令牌无效"))
+	return false
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"tts/internal/apperr"
+	"tts/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FilesHandler 提供文件存储中已保存音频的下载
+type FilesHandler struct {
+	files *storage.Storage
+}
+
+// NewFilesHandler 创建文件下载处理器
+func NewFilesHandler(files *storage.Storage) *FilesHandler {
+	return &FilesHandler{files: files}
+}
+
+// HandleFile 返回指定文件，支持 Range 请求以便播放器拖动进度、中断的下载从断点续传；
+// 分块保存的文件作为一个整体返回，各部分与清单也可以单独下载
+func (h *FilesHandler) HandleFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	file, err := h.files.Open(key)
+	if err != nil {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "文件不存在"))
+		return
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(key, ".mp3") {
+		c.Header("Content-Type", "audio/mpeg")
+	}
+	// 哈希对应完整文件，Range 请求也返回完整文件的哈希，便于下载完成后校验
+	if sum, err := h.files.Checksum(key); err == nil {
+		c.Header(ChecksumHeader, sum)
+	}
+	http.ServeContent(c.Writer, c.Request, file.Name(), file.ModTime(), file)
+}
+
+// HandleContent 按内容哈希返回已保存的文件。地址中的哈希决定内容，响应可以被浏览器与 CDN 永久缓存，
+// 文件被覆盖为其他内容后原地址返回 404，不需要清除缓存
+func (h *FilesHandler) HandleContent(c *gin.Context) {
+	name := c.Param("name")
+	sum := strings.TrimSuffix(name, path.Ext(name))
+	file, key, err := h.files.OpenContent(sum)
+	if err != nil {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "文件不存在"))
+		return
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(key, ".mp3") {
+		c.Header("Content-Type", "audio/mpeg")
+	}
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", `"`+sum+`"`)
+	c.Header(ChecksumHeader, sum)
+	http.ServeContent(c.Writer, c.Request, file.Name(), file.ModTime(), file)
+}
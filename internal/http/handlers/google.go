@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/utils"
+	ttspkg "tts/pkg/tts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// googleStatuses 将错误码映射为 Google API 的错误状态
+var googleStatuses = map[apperr.Code]string{
+	apperr.CodeInvalidRequest:     "INVALID_ARGUMENT",
+	apperr.CodeInvalidVoice:       "INVALID_ARGUMENT",
+	apperr.CodeTextTooLong:        "INVALID_ARGUMENT",
+	apperr.CodePayloadTooLarge:    "INVALID_ARGUMENT",
+	apperr.CodeSSMLInvalid:        "INVALID_ARGUMENT",
+	apperr.CodeNotFound:           "NOT_FOUND",
+	apperr.CodeNotSupported:       "UNIMPLEMENTED",
+	apperr.CodeUnauthorized:       "UNAUTHENTICATED",
+	apperr.CodeRateLimited:        "RESOURCE_EXHAUSTED",
+	apperr.CodeProviderThrottled:  "UNAVAILABLE",
+	apperr.CodeProviderAuthFailed: "UNAVAILABLE",
+	apperr.CodeProviderError:      "UNAVAILABLE",
+}
+
+// GoogleHandler 提供 Google Cloud TTS 兼容的 text:synthesize 接口
+type GoogleHandler struct {
+	synthesizer *ttspkg.Synthesizer
+	config      *config.Config
+}
+
+// NewGoogleHandler 创建 Google Cloud TTS 兼容接口处理器
+func NewGoogleHandler(synthesizer *ttspkg.Synthesizer, cfg *config.Config) *GoogleHandler {
+	return &GoogleHandler{
+		synthesizer: synthesizer,
+		config:      cfg,
+	}
+}
+
+// HandleText 处理 POST /v1/text:synthesize，路由参数 action 为 ":synthesize"
+func (h *GoogleHandler) HandleText(c *gin.Context) {
+	if c.Param("action") != ":synthesize" {
+		googleAbort(c, apperr.New(apperr.CodeNotFound, "Method not found"))
+		return
+	}
+
+	var googleReq models.GoogleSynthesizeRequest
+	if err := c.ShouldBindJSON(&googleReq); err != nil {
+		googleAbort(c, apperr.Wrap(apperr.CodeInvalidRequest, "Invalid JSON payload", err))
+		return
+	}
+
+	text := googleReq.Input.Text
+	if googleReq.Input.SSML != "" {
+		text = innerSSML(googleReq.Input.SSML)
+	}
+	if text == "" {
+		googleAbort(c, apperr.New(apperr.CodeInvalidRequest, "Either input.text or input.ssml must be set"))
+		return
+	}
+	if length := utils.GraphemeCount(text); length > h.config.TTS.MaxTextLength {
+		googleAbort(c, apperr.Newf(apperr.CodeTextTooLong, "Input is too long (%d > %d)", length, h.config.TTS.MaxTextLength))
+		return
+	}
+	if encoding := strings.ToUpper(googleReq.AudioConfig.AudioEncoding); encoding != "" && encoding != "MP3" {
+		googleAbort(c, apperr.Newf(apperr.CodeInvalidRequest, "Unsupported audioEncoding: %s, only MP3 is supported", googleReq.AudioConfig.AudioEncoding))
+		return
+	}
+
+	voice, err := h.resolveVoice(c, googleReq)
+	if err != nil {
+		googleAbort(c, err)
+		return
+	}
+
+	req := models.TTSRequest{
+		Text:  text,
+		Voice: voice,
+		Rate:  h.config.TTS.DefaultRate,
+		Pitch: h.config.TTS.DefaultPitch,
+	}
+	// speakingRate 为倍速，pitch 为半音，都换算为 Azure 的百分比
+	if rate := googleReq.AudioConfig.SpeakingRate; rate > 0 {
+		req.Rate = fmt.Sprintf("%+.0f", (rate-1)*100)
+	}
+	if pitch := googleReq.AudioConfig.Pitch; pitch != 0 {
+		req.Pitch = fmt.Sprintf("%+.0f", (math.Pow(2, pitch/12)-1)*100)
+	}
+
+	log.Printf("Google TTS请求: voice=%s/%s → %s, rate=%s, pitch=%s, 文本长度=%d",
+		googleReq.Voice.LanguageCode, googleReq.Voice.Name, req.Voice, req.Rate, req.Pitch, utils.GraphemeCount(text))
+
+	resp, err := synthesize(c, h.synthesizer, req)
+	if err != nil {
+		log.Printf("Google TTS请求合成失败: %v", err)
+		googleAbort(c, err)
+		return
+	}
+	audio, watermarkID, err := stampAudio(c, h.config, resp.AudioContent)
+	if err != nil {
+		googleAbort(c, err)
+		return
+	}
+	if watermarkID != "" {
+		c.Header(WatermarkHeader, watermarkID)
+	}
+	c.JSON(http.StatusOK, models.GoogleSynthesizeResponse{
+		AudioContent: base64.StdEncoding.EncodeToString(audio),
+	})
+}
+
+// resolveVoice 选择实际使用的语音：优先按 google.voice_mapping 映射名称，
+// 名称本身是微软语音时直接使用，否则按语言与性别从语音列表中选择
+func (h *GoogleHandler) resolveVoice(c *gin.Context, googleReq models.GoogleSynthesizeRequest) (string, error) {
+	name := googleReq.Voice.Name
+	for id, voice := range h.config.Google.VoiceMapping {
+		if strings.EqualFold(id, name) && voice != "" {
+			return voice, nil
+		}
+	}
+	if strings.HasSuffix(name, "Neural") {
+		return name, nil
+	}
+
+	locale := googleLocale(googleReq.Voice.LanguageCode)
+	if locale == "" {
+		return h.config.TTS.DefaultVoice, nil
+	}
+	voices, err := h.synthesizer.ListVoices(c.Request.Context(), locale)
+	if err != nil {
+		return "", err
+	}
+	if len(voices) == 0 {
+		return "", apperr.Newf(apperr.CodeInvalidVoice, "No voice available for languageCode %s", googleReq.Voice.LanguageCode)
+	}
+	gender := strings.ToLower(googleReq.Voice.SSMLGender)
+	for _, voice := range voices {
+		if (gender == "male" || gender == "female") && !strings.EqualFold(voice.Gender, gender) {
+			continue
+		}
+		return voice.ShortName, nil
+	}
+	return voices[0].ShortName, nil
+}
+
+// googleLocale 将 Google 的语言代码转换为 Azure 区域，如 cmn-CN → zh-CN
+func googleLocale(languageCode string) string {
+	lang, region, found := strings.Cut(languageCode, "-")
+	switch strings.ToLower(lang) {
+	case "cmn":
+		lang = "zh"
+	case "yue":
+		lang, region = "zh", "HK"
+	}
+	if !found && region == "" {
+		return lang
+	}
+	return lang + "-" + region
+}
+
+// googleAbort 以 Google API 的错误格式中止请求
+func googleAbort(c *gin.Context, err error) {
+	appErr := apperr.From(err)
+	status, ok := googleStatuses[appErr.Code]
+	if !ok {
+		status = "INTERNAL"
+	}
+	message := appErr.Message
+	if appErr.Code == apperr.CodeInternal {
+		message = "Internal error encountered."
+	}
+	c.AbortWithStatusJSON(appErr.Code.Status(), gin.H{"error": gin.H{
+		"code":    appErr.Code.Status(),
+		"message": message,
+		"status":  status,
+	}})
+}
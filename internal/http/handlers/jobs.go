@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/jobs"
+	"tts/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobsHandler 处理异步合成任务的提交、查询、取消与重试，每个密钥只能看到自己提交的任务
+type JobsHandler struct {
+	manager *jobs.Manager
+	config  *config.Config
+}
+
+// NewJobsHandler 创建异步合成任务处理器
+func NewJobsHandler(manager *jobs.Manager, cfg *config.Config) *JobsHandler {
+	return &JobsHandler{manager: manager, config: cfg}
+}
+
+// jobRequest 是提交任务的请求，未指定的语音参数使用配置的默认值
+type jobRequest struct {
+	models.TTSRequest
+	Priority int `json:"priority"` // 数值大的先执行，默认 0
+}
+
+// HandleSubmit 提交任务，立即返回 202 与任务状态
+func (h *JobsHandler) HandleSubmit(c *gin.Context) {
+	var req jobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的请求格式", err))
+		return
+	}
+	if req.Text == "" {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "text 不能为空"))
+		return
+	}
+	if !config.ValidURLMode(req.URLMode) {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "url_mode 无效: %s", req.URLMode))
+		return
+	}
+	if !config.ValidQuoteMode(req.Quotes) {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "quotes 无效: %s", req.Quotes))
+		return
+	}
+	if req.Voice == "" {
+		req.Voice = h.config.TTS.DefaultVoice
+	}
+	if req.Rate == "" {
+		req.Rate = h.config.TTS.DefaultRate
+	}
+	if req.Pitch == "" {
+		req.Pitch = h.config.TTS.DefaultPitch
+	}
+
+	job, err := h.manager.Submit(config.TenantFromContext(c.Request.Context()), req.TTSRequest, req.Priority)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// HandleList 返回当前密钥提交的任务，支持 status 与 limit 参数
+func (h *JobsHandler) HandleList(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", jobs.StatusQueued, jobs.StatusRunning, jobs.StatusSucceeded, jobs.StatusFailed, jobs.StatusCanceled:
+	default:
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "无效的 status: %s", status))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	c.JSON(http.StatusOK, gin.H{"jobs": h.manager.List(config.TenantFromContext(c.Request.Context()), status, limit)})
+}
+
+// HandleGet 返回任务状态
+func (h *JobsHandler) HandleGet(c *gin.Context) {
+	h.respond(c, http.StatusOK, h.manager.Get)
+}
+
+// HandleCancel 取消排队中或执行中的任务
+func (h *JobsHandler) HandleCancel(c *gin.Context) {
+	h.respond(c, http.StatusOK, h.manager.Cancel)
+}
+
+// HandleRetry 重新排队失败或已取消的任务
+func (h *JobsHandler) HandleRetry(c *gin.Context) {
+	h.respond(c, http.StatusAccepted, h.manager.Retry)
+}
+
+// respond 以当前密钥与路径中的任务ID调用 action 并返回任务状态
+func (h *JobsHandler) respond(c *gin.Context, status int, action func(tenant, id string) (jobs.Job, error)) {
+	job, err := action(config.TenantFromContext(c.Request.Context()), c.Param("id"))
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.JSON(status, job)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/live"
+	"tts/internal/models"
+	ttspkg "tts/pkg/tts"
+)
+
+// HandleLive 把请求升级为实时会话的 WebSocket 连接：客户端陆续发送文本，服务按句合成并推送音频，
+// 可以随时发送 stop 打断。语音参数来自 v、r、p、s 查询参数，未指定时沿用会话中的设置
+func (h *TTSHandler) HandleLive(c *gin.Context) {
+	req := models.TTSRequest{
+		Voice:   c.Query("v"),
+		Rate:    c.Query("r"),
+		Pitch:   c.Query("p"),
+		Style:   c.Query("s"),
+		URLMode: c.Query("url_mode"),
+		Quotes:  c.Query("quotes"),
+	}
+	if !config.ValidURLMode(req.URLMode) {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "未知的 url_mode: %s，可选 remove、domain、text、keep", req.URLMode))
+		return
+	}
+	if !config.ValidQuoteMode(req.Quotes) {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "未知的 quotes: %s，可选 off、voice、prosody", req.Quotes))
+		return
+	}
+
+	key := sessionKey(c, c.Param("id"))
+	settings, created := h.sessions.Settings(key)
+	if created {
+		h.applySession(c, &req, settings)
+	}
+	h.fillDefaultValues(&req)
+
+	// Record 在合成协程中调用，gin.Context 在请求结束后会被复用，只捕获密钥名称与请求上下文
+	keyName, ctx := usageKey(c), c.Request.Context()
+	opts := live.Options{
+		Synthesizer: h.synthesizer,
+		Request:     req,
+		MaxBuffer:   h.config.Sessions.LiveMaxBuffer,
+		Record: func(usage *ttspkg.Usage, req models.TTSRequest) {
+			recordCharacters(ctx, keyName, usage, req)
+		},
+	}
+	if created {
+		opts.Replace = func(text string) string { return h.sessions.Replace(key, text) }
+	}
+	live.Serve(c.Writer, c.Request, opts)
+}
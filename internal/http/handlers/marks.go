@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"tts/internal/apperr"
+	"tts/internal/models"
+	"tts/internal/tts"
+	"tts/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleSpeechMarks 导出文本的语音标记，格式由 format 查询参数选择：polly（默认）或 csv
+func (h *TTSHandler) HandleSpeechMarks(c *gin.Context) {
+	format := c.DefaultQuery("format", tts.MarkFormatPolly)
+	contentType := tts.MarkContentType(format)
+	if contentType == "" {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "不支持的语音标记格式: %s", format))
+		return
+	}
+
+	var req models.TTSRequest
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+			return
+		}
+	} else {
+		req = models.TTSRequest{
+			Text:  c.Query("t"),
+			Voice: c.Query("v"),
+			Rate:  c.Query("r"),
+			Pitch: c.Query("p"),
+			Style: c.Query("s"),
+		}
+	}
+	if req.Text == "" {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "必须提供文本参数"))
+		return
+	}
+	h.fillDefaultValues(&req)
+	if length := utils.GraphemeCount(req.Text); length > h.config.TTS.MaxTextLength {
+		apperr.Abort(c, apperr.Newf(apperr.CodeTextTooLong, "文本长度超过限制 (%d > %d)", length, h.config.TTS.MaxTextLength))
+		return
+	}
+
+	provider, ok := h.synthesizer.Provider().(tts.MarkProvider)
+	if !ok {
+		apperr.Abort(c, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记"))
+		return
+	}
+	marks, err := provider.SpeechMarks(c.Request.Context(), req)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	if err := tts.WriteMarks(c.Writer, marks, format); err != nil {
+		log.Printf("写入语音标记失败: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
+	"tts/internal/overlay"
+)
+
+// OverlayHandler 处理直播播报：提交与管理消息的接口使用 TTS 接口的认证，
+// 浏览器源页面的 WebSocket 与音频使用 overlay.token
+type OverlayHandler struct {
+	overlay *overlay.Overlay
+}
+
+// NewOverlayHandler 创建直播播报处理器
+func NewOverlayHandler(o *overlay.Overlay) *OverlayHandler {
+	return &OverlayHandler{overlay: o}
+}
+
+// HandleSubmit 把聊天消息或打赏加入播报队列，返回消息与排在它之前的消息数
+func (h *OverlayHandler) HandleSubmit(c *gin.Context) {
+	var msg overlay.Message
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+		return
+	}
+	item, position, err := h.overlay.Enqueue(msg)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"item": item, "position": position})
+}
+
+// HandleState 返回正在播放与排队的消息
+func (h *OverlayHandler) HandleState(c *gin.Context) {
+	c.JSON(http.StatusOK, h.overlay.State())
+}
+
+// HandleSkip 跳过正在播放的消息
+func (h *OverlayHandler) HandleSkip(c *gin.Context) {
+	if !h.overlay.Skip() {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "没有正在播放的消息"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// HandleRemove 删除排队的消息，或跳过正在播放的该消息
+func (h *OverlayHandler) HandleRemove(c *gin.Context) {
+	if !h.overlay.Remove(c.Param("id")) {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "消息不存在或已播放"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// HandleWS 建立浏览器源页面的 WebSocket 连接
+func (h *OverlayHandler) HandleWS(c *gin.Context) {
+	if !h.authorized(c) {
+		return
+	}
+	h.overlay.ServeWS(c.Writer, c.Request)
+}
+
+// HandleAudio 返回正在播放或排队的消息的音频
+func (h *OverlayHandler) HandleAudio(c *gin.Context) {
+	if !h.authorized(c) {
+		return
+	}
+	audio, ok := h.overlay.Audio(c.Param("id"))
+	if !ok {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "消息不存在或已播放"))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "audio/mpeg", audio)
+}
+
+// authorized 检查 token 查询参数，未配置 overlay.token 时不认证
+func (h *OverlayHandler) authorized(c *gin.Context) bool {
+	token := h.overlay.Token()
+	if token == "" || subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(token)) == 1 {
+		return true
+	}
+	apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, "令牌无效"))
+	return false
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/audio"
+	"tts/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxPadMs 是开头或末尾补充静音的上限
+	maxPadMs = 10000
+	// maxAlignMs 是时长对齐单位的上限
+	maxAlignMs = 60000
+)
+
+// bindPadding 从 GET 参数读取静音补充选项
+func bindPadding(c *gin.Context, req *models.TTSRequest) error {
+	for _, field := range []struct {
+		name  string
+		value *int
+	}{
+		{"pad_start_ms", &req.PadStartMs},
+		{"pad_end_ms", &req.PadEndMs},
+		{"align_ms", &req.AlignMs},
+	} {
+		raw := c.Query(field.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return apperr.Newf(apperr.CodeInvalidRequest, "无效的 %s: %s", field.name, raw)
+		}
+		*field.value = value
+	}
+	return nil
+}
+
+// validatePadding 检查静音补充选项的范围
+func validatePadding(req models.TTSRequest) error {
+	if req.PadStartMs < 0 || req.PadStartMs > maxPadMs || req.PadEndMs < 0 || req.PadEndMs > maxPadMs {
+		return apperr.Newf(apperr.CodeInvalidRequest, "pad_start_ms 与 pad_end_ms 需要在 0～%d 之间", maxPadMs)
+	}
+	if req.AlignMs < 0 || req.AlignMs > maxAlignMs {
+		return apperr.Newf(apperr.CodeInvalidRequest, "align_ms 需要在 0～%d 之间", maxAlignMs)
+	}
+	return nil
+}
+
+// hasPadding 判断请求是否需要补充静音
+func hasPadding(req models.TTSRequest) bool {
+	return req.PadStartMs > 0 || req.PadEndMs > 0 || req.AlignMs > 0
+}
+
+// padAudio 按请求补充静音，不需要时原样返回
+func padAudio(req models.TTSRequest, data []byte) ([]byte, error) {
+	if !hasPadding(req) {
+		return data, nil
+	}
+	padded, err := audio.Pad(data,
+		time.Duration(req.PadStartMs)*time.Millisecond,
+		time.Duration(req.PadEndMs)*time.Millisecond,
+		time.Duration(req.AlignMs)*time.Millisecond)
+	if err != nil {
+		return nil, apperr.Wrap(apperr.CodeInternal, "补充静音失败", err)
+	}
+	return padded, nil
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
 	"tts/internal/config"
 )
 
@@ -43,7 +44,7 @@ func (h *PagesHandler) HandleIndex(c *gin.Context) {
 
 	// 渲染模板
 	if err := h.templates.ExecuteTemplate(c.Writer, "index.html", data); err != nil {
-		c.AbortWithStatusJSON(500, gin.H{"error": "模板渲染失败: " + err.Error()})
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "模板渲染失败", err))
 		return
 	}
 }
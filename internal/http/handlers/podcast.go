@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/podcast"
+	"tts/internal/storage"
+	"tts/internal/store"
+	"tts/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PodcastHandler 发布由订阅源生成的播客 RSS
+type PodcastHandler struct {
+	store  *store.Store
+	files  *storage.Storage
+	config *config.Config
+}
+
+// NewPodcastHandler 创建播客处理器
+func NewPodcastHandler(st *store.Store, files *storage.Storage, cfg *config.Config) *PodcastHandler {
+	return &PodcastHandler{store: st, files: files, config: cfg}
+}
+
+// HandleFeed 返回播客 RSS。请求带有 api_key 时，音频地址也会附带同样的参数，
+// 使播客应用无需额外配置即可下载
+func (h *PodcastHandler) HandleFeed(c *gin.Context) {
+	base := utils.GetBaseURL(c) + h.config.Server.BasePath
+	query := ""
+	if key := c.Query("api_key"); key != "" {
+		query = "?api_key=" + url.QueryEscape(key)
+	}
+	fileURL := func(key string) string {
+		u := h.files.URL(key)
+		// 按内容哈希的地址不需要认证，也不附带 api_key，便于 CDN 缓存
+		if h.config.Storage.ContentURLs {
+			if sum, err := h.files.Checksum(key); err == nil {
+				u, query = h.files.ContentURL(key, sum), ""
+			}
+		}
+		if strings.HasPrefix(u, "/") {
+			u = base + u
+		}
+		return u + query
+	}
+
+	c.Header("Content-Type", "application/rss+xml; charset=utf-8")
+	episodes := podcast.Episodes(h.store)
+	if err := podcast.WriteRSS(c.Writer, h.config.Podcast, base+"/podcast.xml", episodes, fileURL); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "生成播客失败", err))
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/tts"
+	"tts/internal/utils"
+	"tts/internal/voicemap"
+	ttspkg "tts/pkg/tts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pollyErrorTypes 将错误码映射为 Polly 的异常类型
+var pollyErrorTypes = map[apperr.Code]string{
+	apperr.CodeInvalidRequest:    "ValidationException",
+	apperr.CodeInvalidVoice:      "ValidationException",
+	apperr.CodeTextTooLong:       "TextLengthExceededException",
+	apperr.CodePayloadTooLarge:   "TextLengthExceededException",
+	apperr.CodeSSMLInvalid:       "InvalidSsmlException",
+	apperr.CodeNotSupported:      "ValidationException",
+	apperr.CodeProviderThrottled: "ThrottlingException",
+	apperr.CodeRateLimited:       "ThrottlingException",
+	apperr.CodeUnauthorized:      "UnrecognizedClientException",
+}
+
+// PollyHandler 提供 Amazon Polly 兼容的 SynthesizeSpeech 接口
+type PollyHandler struct {
+	synthesizer *ttspkg.Synthesizer            // 默认合成器
+	engines     map[string]*ttspkg.Synthesizer // Polly Engine → 合成器
+	voices      *voicemap.Mapper
+	config      *config.Config
+}
+
+// NewPollyHandler 创建 Polly 兼容接口处理器，engines 的键为小写的 Polly Engine
+func NewPollyHandler(synthesizer *ttspkg.Synthesizer, engines map[string]*ttspkg.Synthesizer, cfg *config.Config) *PollyHandler {
+	return &PollyHandler{
+		synthesizer: synthesizer,
+		engines:     engines,
+		voices:      voicemap.New(&cfg.TTS),
+		config:      cfg,
+	}
+}
+
+// HandleSynthesizeSpeech 处理 POST /v1/speech
+func (h *PollyHandler) HandleSynthesizeSpeech(c *gin.Context) {
+	startTime := time.Now()
+
+	var pollyReq models.PollyRequest
+	if err := c.ShouldBindJSON(&pollyReq); err != nil {
+		pollyAbort(c, apperr.Wrap(apperr.CodeInvalidRequest, "Invalid request body", err))
+		return
+	}
+	if pollyReq.Text == "" {
+		pollyAbort(c, apperr.New(apperr.CodeInvalidRequest, "Text must not be empty"))
+		return
+	}
+	if pollyReq.VoiceId == "" {
+		pollyAbort(c, apperr.New(apperr.CodeInvalidRequest, "VoiceId must not be empty"))
+		return
+	}
+	format := strings.ToLower(pollyReq.OutputFormat)
+	if format == "" {
+		format = "mp3"
+	}
+	if format != "mp3" && format != "json" {
+		pollyAbort(c, apperr.Newf(apperr.CodeNotSupported, "OutputFormat %s is not supported", pollyReq.OutputFormat))
+		return
+	}
+
+	text := pollyReq.Text
+	if strings.EqualFold(pollyReq.TextType, "ssml") {
+		text = innerSSML(text)
+	}
+	characters := utils.GraphemeCount(text)
+	if characters > h.config.TTS.MaxTextLength {
+		pollyAbort(c, apperr.Newf(apperr.CodeTextTooLong, "Maximum text length has been exceeded (%d > %d)", characters, h.config.TTS.MaxTextLength))
+		return
+	}
+
+	req := models.TTSRequest{
+		Text:  text,
+		Voice: h.resolveVoice(pollyReq.VoiceId, c.ClientIP()),
+		Rate:  h.config.TTS.DefaultRate,
+		Pitch: h.config.TTS.DefaultPitch,
+	}
+	synthesizer := h.synthesizer
+	if engine, ok := h.engines[strings.ToLower(pollyReq.Engine)]; ok {
+		synthesizer = engine
+	}
+
+	log.Printf("Polly请求: engine=%s, voice=%s → %s, format=%s, 文本长度=%d",
+		pollyReq.Engine, pollyReq.VoiceId, req.Voice, format, characters)
+
+	c.Header("x-amzn-RequestCharacters", strconv.Itoa(characters))
+	if format == "json" {
+		h.writeSpeechMarks(c, synthesizer, req, pollyReq.SpeechMarkTypes)
+		return
+	}
+
+	resp, err := synthesize(c, synthesizer, req)
+	if err != nil {
+		log.Printf("Polly请求合成失败: %v", err)
+		pollyAbort(c, err)
+		return
+	}
+	audio, watermarkID, err := stampAudio(c, h.config, resp.AudioContent)
+	if err != nil {
+		pollyAbort(c, err)
+		return
+	}
+	if watermarkID != "" {
+		c.Header(WatermarkHeader, watermarkID)
+	}
+	setChecksum(c, audio)
+	c.Data(http.StatusOK, "audio/mpeg", audio)
+	log.Printf("Polly请求总耗时: %v, 音频大小: %s", time.Since(startTime), utils.FormatFileSize(len(audio)))
+}
+
+// writeSpeechMarks 以 Polly 的 JSON Lines 格式返回语音标记
+func (h *PollyHandler) writeSpeechMarks(c *gin.Context, synthesizer *ttspkg.Synthesizer, req models.TTSRequest, types []string) {
+	if len(types) == 0 {
+		pollyAbort(c, apperr.New(apperr.CodeInvalidRequest, "SpeechMarkTypes must be specified when OutputFormat is json"))
+		return
+	}
+	provider, ok := synthesizer.Provider().(tts.MarkProvider)
+	if !ok {
+		pollyAbort(c, apperr.New(apperr.CodeNotSupported, "Speech marks are not supported by this engine"))
+		return
+	}
+	marks, err := provider.SpeechMarks(c.Request.Context(), req)
+	if err != nil {
+		pollyAbort(c, err)
+		return
+	}
+
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[strings.ToLower(t)] = true
+	}
+	filtered := marks[:0]
+	for _, mark := range marks {
+		if wanted[mark.Type] {
+			filtered = append(filtered, mark)
+		}
+	}
+
+	c.Header("Content-Type", tts.MarkContentType(tts.MarkFormatPolly))
+	c.Status(http.StatusOK)
+	if err := tts.WriteMarks(c.Writer, filtered, tts.MarkFormatPolly); err != nil {
+		log.Printf("写入语音标记失败: %v", err)
+	}
+}
+
+// resolveVoice 将 Polly VoiceId 解析为实际语音，优先使用 polly.voice_mapping
+func (h *PollyHandler) resolveVoice(voiceID, stickyKey string) string {
+	for id, voice := range h.config.Polly.VoiceMapping {
+		if strings.EqualFold(id, voiceID) && voice != "" {
+			return voice
+		}
+	}
+	return h.voices.Resolve(voiceID, stickyKey)
+}
+
+// innerSSML 去掉外层的 <speak> 元素，只保留其中的内容，外层元素由服务生成
+func innerSSML(ssml string) string {
+	ssml = strings.TrimSpace(ssml)
+	if start := strings.Index(ssml, ">"); strings.HasPrefix(ssml, "<speak") && start >= 0 && strings.HasSuffix(ssml, "</speak>") {
+		return ssml[start+1 : len(ssml)-len("</speak>")]
+	}
+	return ssml
+}
+
+// pollyAbort 以 Polly 的错误格式中止请求
+func pollyAbort(c *gin.Context, err error) {
+	appErr := apperr.From(err)
+	errType, ok := pollyErrorTypes[appErr.Code]
+	if !ok {
+		errType = "ServiceFailureException"
+	}
+	message := appErr.Message
+	if appErr.Code == apperr.CodeInternal {
+		message = "Internal error"
+	}
+	c.Header("x-amzn-ErrorType", errType)
+	c.AbortWithStatusJSON(appErr.Code.Status(), gin.H{"message": message})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"tts/internal/health"
+	"tts/internal/pool"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProvidersHandler 处理上游服务状态查询
+type ProvidersHandler struct{}
+
+// NewProvidersHandler 创建上游服务状态处理器
+func NewProvidersHandler() *ProvidersHandler {
+	return &ProvidersHandler{}
+}
+
+// providerStatus 是单个上游服务的状态，配置了并发池时附带池的状态
+type providerStatus struct {
+	health.Status
+	Pool *pool.Stats `json:"pool,omitempty"`
+}
+
+// HandleStatus 返回每个已创建的上游服务最近 5 分钟的错误率、延迟、令牌到期时间与并发池状态
+func (h *ProvidersHandler) HandleStatus(c *gin.Context) {
+	statuses := health.All()
+	providers := make([]providerStatus, 0, len(statuses))
+	for _, status := range statuses {
+		provider := providerStatus{Status: status}
+		if p, ok := pool.Lookup(status.Name); ok {
+			stats := p.Stats()
+			provider.Pool = &stats
+		}
+		providers = append(providers, provider)
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/readaloud"
+	"tts/internal/utils"
+	"tts/internal/voicemap"
+	ttspkg "tts/pkg/tts"
+)
+
+// maxChunksPerRequest 是一次最多提交的分段数
+const maxChunksPerRequest = 200
+
+// ReadAloudHandler 处理浏览器扩展的朗读会话：按顺序提交页面文本分段，按分段获取音频，按位置跳转
+type ReadAloudHandler struct {
+	store       *readaloud.Store
+	synthesizer *ttspkg.Synthesizer
+	voices      *voicemap.Mapper
+	config      *config.Config
+}
+
+// NewReadAloudHandler 创建朗读会话处理器
+func NewReadAloudHandler(store *readaloud.Store, synthesizer *ttspkg.Synthesizer, cfg *config.Config) *ReadAloudHandler {
+	return &ReadAloudHandler{
+		store:       store,
+		synthesizer: synthesizer,
+		voices:      voicemap.New(&cfg.TTS),
+		config:      cfg,
+	}
+}
+
+// HandleCreate 创建朗读会话，请求体中的语音设置用于会话中的所有分段
+func (h *ReadAloudHandler) HandleCreate(c *gin.Context) {
+	var settings models.ReadSettings
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+			return
+		}
+	}
+
+	id := uuid.New().String()
+	resp := h.store.Create(sessionKey(c, id), settings)
+	resp.ID = id
+	c.JSON(http.StatusCreated, resp)
+}
+
+// HandleGet 返回会话的设置与已提交的分段
+func (h *ReadAloudHandler) HandleGet(c *gin.Context) {
+	id := c.Param("id")
+	resp, ok := h.store.Get(sessionKey(c, id))
+	if !ok {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "朗读会话不存在或已过期"))
+		return
+	}
+	resp.ID = id
+	h.setAudioURLs(c, id, resp.Chunks)
+	c.JSON(http.StatusOK, resp)
+}
+
+// HandleDelete 删除会话
+func (h *ReadAloudHandler) HandleDelete(c *gin.Context) {
+	if !h.store.Delete(sessionKey(c, c.Param("id"))) {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "朗读会话不存在或已过期"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// HandleChunks 按顺序提交页面文本分段，返回各分段的位置与音频地址
+func (h *ReadAloudHandler) HandleChunks(c *gin.Context) {
+	var req models.ReadChunksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+		return
+	}
+	if len(req.Chunks) == 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "chunks 不能为空"))
+		return
+	}
+	if len(req.Chunks) > maxChunksPerRequest {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "一次最多提交 %d 段", maxChunksPerRequest))
+		return
+	}
+	for i, text := range req.Chunks {
+		if text == "" {
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "第 %d 段为空", i))
+			return
+		}
+		if length := utils.GraphemeCount(text); length > h.config.TTS.MaxTextLength {
+			apperr.Abort(c, apperr.Newf(apperr.CodeTextTooLong, "第 %d 段超过长度限制 (%d > %d)", i, length, h.config.TTS.MaxTextLength))
+			return
+		}
+	}
+	start := -1
+	if req.Start != nil {
+		if *req.Start < 0 {
+			apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "start 不能小于 0"))
+			return
+		}
+		start = *req.Start
+	}
+
+	id := c.Param("id")
+	chunks, length, err := h.store.Put(sessionKey(c, id), start, req.Chunks)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	h.setAudioURLs(c, id, chunks)
+	c.JSON(http.StatusOK, models.ReadChunksResponse{Chunks: chunks, Length: length})
+}
+
+// HandleSeek 返回页面位置 offset 所在的分段与分段内开始朗读的位置，音频地址从该位置所在的句子开始
+func (h *ReadAloudHandler) HandleSeek(c *gin.Context) {
+	offset, err := strconv.Atoi(c.Query("offset"))
+	if err != nil || offset < 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "offset 应为非负整数"))
+		return
+	}
+	id := c.Param("id")
+	chunk, from, err := h.store.Seek(sessionKey(c, id), offset)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	chunks := []models.ReadChunk{chunk}
+	h.setAudioURLs(c, id, chunks)
+	resp := models.ReadSeekResponse{ReadChunk: chunks[0], From: from}
+	if from > 0 {
+		resp.AudioURL += "?from=" + strconv.Itoa(from)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// HandleAudio 合成并返回一个分段的音频，可以重复请求以重新朗读之前的分段；
+// from 查询参数指定从分段内的位置开始（回退到所在句子的开头），实际开始的位置通过 X-Read-From 响应头返回
+func (h *ReadAloudHandler) HandleAudio(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "分段序号应为整数"))
+		return
+	}
+	from := 0
+	if value := c.Query("from"); value != "" {
+		if from, err = strconv.Atoi(value); err != nil || from < 0 {
+			apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "from 应为非负整数"))
+			return
+		}
+	}
+
+	text, settings, err := h.store.Chunk(sessionKey(c, c.Param("id")), index)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	start, startUnits := readaloud.SentenceStart(text, from)
+	c.Header("X-Read-From", strconv.Itoa(startUnits))
+	text = strings.TrimSpace(text[start:])
+	if text == "" {
+		// 只有空白的分段没有可朗读的内容
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	req := models.TTSRequest{Text: text, Rate: settings.Rate, Pitch: settings.Pitch, Style: settings.Style}
+	if settings.Voice != "" {
+		req.Voice = h.voices.Resolve(settings.Voice, stickyKey(c))
+	}
+	if req.Voice == "" {
+		req.Voice = h.config.TTS.DefaultVoice
+	}
+	if req.Rate == "" {
+		req.Rate = h.config.TTS.DefaultRate
+	}
+	if req.Pitch == "" {
+		req.Pitch = h.config.TTS.DefaultPitch
+	}
+
+	resp, err := synthesize(c, h.synthesizer, req)
+	if err != nil {
+		log.Printf("朗读分段合成失败: %v", err)
+		apperr.Abort(c, err)
+		return
+	}
+	audio, ok := writeStamped(c, h.config, resp.AudioContent)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "audio/mpeg", audio)
+}
+
+// setAudioURLs 填写各分段的音频地址
+func (h *ReadAloudHandler) setAudioURLs(c *gin.Context, id string, chunks []models.ReadChunk) {
+	base := utils.GetBaseURL(c) + h.config.Server.BasePath
+	for i := range chunks {
+		chunks[i].AudioURL = fmt.Sprintf("%s/v1/read/%s/chunks/%d/audio", base, id, chunks[i].Index)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"tts/internal/apikey"
+	"tts/internal/apperr"
+	"tts/internal/models"
+	"tts/internal/session"
+)
+
+// SessionsHandler 处理会话的创建、查询与删除
+type SessionsHandler struct {
+	sessions *session.Store
+}
+
+// NewSessionsHandler 创建会话处理器
+func NewSessionsHandler(sessions *session.Store) *SessionsHandler {
+	return &SessionsHandler{sessions: sessions}
+}
+
+// HandleCreate 创建会话，后续请求通过 X-Session-ID 引用会话即可沿用其中的设置
+func (h *SessionsHandler) HandleCreate(c *gin.Context) {
+	var settings models.SessionSettings
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+			return
+		}
+	}
+
+	id := uuid.New().String()
+	resp := h.sessions.Create(sessionKey(c, id), settings)
+	resp.ID = id
+	c.JSON(http.StatusCreated, resp)
+}
+
+// HandleGet 返回会话的设置与进度
+func (h *SessionsHandler) HandleGet(c *gin.Context) {
+	id := c.Param("id")
+	resp, ok := h.sessions.Get(sessionKey(c, id))
+	if !ok {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "会话不存在或已过期"))
+		return
+	}
+	resp.ID = id
+	c.JSON(http.StatusOK, resp)
+}
+
+// HandleDelete 删除会话
+func (h *SessionsHandler) HandleDelete(c *gin.Context) {
+	if !h.sessions.Delete(sessionKey(c, c.Param("id"))) {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "会话不存在或已过期"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// sessionKey 返回会话在存储中的键，不同API密钥的会话互相隔离
+func sessionKey(c *gin.Context, id string) string {
+	return apikey.Presented(c) + "\x00" + strings.TrimSpace(id)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/slack"
+	"tts/internal/storage"
+	"tts/internal/utils"
+	"tts/internal/voicemap"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
+)
+
+// slackReplyTimeout 是合成并回复一条斜杠命令的时间上限
+const slackReplyTimeout = 2 * time.Minute
+
+// slackHelp 是斜杠命令不带文本时的回复，%s 为命令名
+const slackHelp = "用法: `%s 要朗读的文本`，语音会发送到当前频道"
+
+// SlackHandler 处理 Slack 斜杠命令：Slack 要求 3 秒内响应，先回复只有发送者可见的提示，
+// 合成完成后把音频上传到频道，未配置机器人令牌时通过 response_url 回复音频链接
+type SlackHandler struct {
+	synthesizer *ttspkg.Synthesizer
+	voices      *voicemap.Mapper
+	files       *storage.Storage
+	client      *slack.Client
+	config      *config.Config
+}
+
+// NewSlackHandler 创建 Slack 斜杠命令处理器
+func NewSlackHandler(synthesizer *ttspkg.Synthesizer, files *storage.Storage, cfg *config.Config) *SlackHandler {
+	return &SlackHandler{
+		synthesizer: synthesizer,
+		voices:      voicemap.New(&cfg.TTS),
+		files:       files,
+		client:      slack.New(cfg.Slack.APIURL, cfg.Slack.BotToken),
+		config:      cfg,
+	}
+}
+
+// HandleCommand 处理斜杠命令，请求签名已由 SlackAuth 验证
+func (h *SlackHandler) HandleCommand(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的表单请求", err))
+		return
+	}
+	cmd := slack.ParseCommand(c.Request.PostForm)
+	if cmd.Text == "" || cmd.Text == "help" {
+		c.JSON(http.StatusOK, slack.Message{ResponseType: "ephemeral", Text: fmt.Sprintf(slackHelp, cmd.Command)})
+		return
+	}
+	if length := utils.GraphemeCount(cmd.Text); length > h.config.TTS.MaxTextLength {
+		c.JSON(http.StatusOK, slack.Message{
+			ResponseType: "ephemeral",
+			Text:         fmt.Sprintf("文本长度超过限制 (%d > %d)", length, h.config.TTS.MaxTextLength),
+		})
+		return
+	}
+
+	go h.reply(cmd, utils.GetBaseURL(c)+h.config.Server.BasePath)
+	c.JSON(http.StatusOK, slack.Message{ResponseType: "ephemeral", Text: "正在合成语音…"})
+}
+
+// reply 合成命令的文本并发送到频道，失败时回复只有发送者可见的错误
+func (h *SlackHandler) reply(cmd slack.Command, baseURL string) {
+	ctx, cancel := context.WithTimeout(context.Background(), slackReplyTimeout)
+	defer cancel()
+
+	audio, err := h.speak(ctx, cmd)
+	if err != nil {
+		log.Printf("Slack 合成失败: %v", err)
+		appErr := apperr.From(err)
+		message := appErr.Message
+		if appErr.Code == apperr.CodeInternal {
+			message = "服务器内部错误"
+		}
+		h.respond(ctx, cmd, slack.Message{ResponseType: "ephemeral", Text: "合成失败: " + message})
+		return
+	}
+
+	comment := fmt.Sprintf("<@%s>: %s", cmd.UserID, slack.Escape(cmd.Text))
+	if h.client.CanUpload() {
+		title := []rune(cmd.Text)
+		if len(title) > 50 {
+			title = append(title[:50], '…')
+		}
+		if err := h.client.Upload(ctx, cmd.ChannelID, "tts.mp3", string(title), comment, audio); err != nil {
+			log.Printf("Slack 上传音频失败: %v", err)
+			h.respond(ctx, cmd, slack.Message{ResponseType: "ephemeral", Text: "上传音频失败，请确认机器人已加入频道"})
+		}
+		return
+	}
+
+	key := fmt.Sprintf("slack/%s/%s.mp3", strings.ToLower(cmd.TeamID), uuid.New().String())
+	if err := h.files.Put(key, audio); err != nil {
+		log.Printf("Slack 保存音频失败: %v", err)
+		h.respond(ctx, cmd, slack.Message{ResponseType: "ephemeral", Text: "合成失败: 服务器内部错误"})
+		return
+	}
+	link := h.files.URL(key)
+	if h.config.Storage.ContentURLs {
+		link = h.files.ContentURL(key, storage.Checksum(audio))
+	}
+	if strings.HasPrefix(link, "/") {
+		link = baseURL + link
+	}
+	h.respond(ctx, cmd, slack.Message{ResponseType: "in_channel", Text: fmt.Sprintf("%s\n<%s|▶ 播放>", comment, link)})
+}
+
+// speak 按工作区的语音设置合成文本并添加水印
+func (h *SlackHandler) speak(ctx context.Context, cmd slack.Command) ([]byte, error) {
+	chat := config.ChatVoice{Voice: h.config.Slack.Voice, Language: h.config.Slack.Language}
+	// 配置中的键被转换为小写
+	if workspace, ok := h.config.Slack.Workspaces[strings.ToLower(cmd.TeamID)]; ok {
+		chat = workspace
+	}
+	voice, err := h.voices.Choose(ctx, h.synthesizer, chat, cmd.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	if voice == "" {
+		voice = h.config.TTS.DefaultVoice
+	}
+	resp, err := h.synthesizer.Synthesize(ctx, models.TTSRequest{
+		Text:  cmd.Text,
+		Voice: voice,
+		Rate:  h.config.TTS.DefaultRate,
+		Pitch: h.config.TTS.DefaultPitch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watermark.Stamp(h.config, resp.AudioContent, "slack")
+}
+
+func (h *SlackHandler) respond(ctx context.Context, cmd slack.Command, msg slack.Message) {
+	if err := h.client.Respond(ctx, cmd.ResponseURL, msg); err != nil {
+		log.Printf("Slack %v", err)
+	}
+}
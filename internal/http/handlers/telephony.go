@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/telephony"
+	"tts/internal/utils"
+	"tts/internal/voicemap"
+	ttspkg "tts/pkg/tts"
+)
+
+// TelephonyHandler 为 Asterisk、FreeSWITCH 等电话系统提供语音提示：参数都在 URL 中，
+// 格式由文件扩展名决定，响应带有完整的长度与缓存头，可以直接作为 Playback 的地址
+type TelephonyHandler struct {
+	synthesizer *ttspkg.Synthesizer
+	voices      *voicemap.Mapper
+	config      *config.Config
+}
+
+// NewTelephonyHandler 创建电话语音提示处理器
+func NewTelephonyHandler(synthesizer *ttspkg.Synthesizer, cfg *config.Config) *TelephonyHandler {
+	return &TelephonyHandler{
+		synthesizer: synthesizer,
+		voices:      voicemap.New(&cfg.TTS),
+		config:      cfg,
+	}
+}
+
+// HandlePrompt 合成语音提示，如 GET /v1/telephony/welcome.wav?t=您好。
+// 参数与 GET /tts 相同；文件名只用于确定格式，PBX 按扩展名识别下载的文件。
+// 同样的参数得到同样的 ETag，PBX 带 If-None-Match 重新验证时不再合成
+func (h *TelephonyHandler) HandlePrompt(c *gin.Context) {
+	name := c.Param("file")
+	format, ok := telephony.Lookup(strings.TrimPrefix(path.Ext(name), "."))
+	if !ok {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "不支持的文件格式: %s，可选 %s", name, strings.Join(telephony.Names(), "、")))
+		return
+	}
+
+	req := models.TTSRequest{
+		Text:  strings.TrimSpace(c.Query("t")),
+		Voice: c.Query("v"),
+		Rate:  c.Query("r"),
+		Pitch: c.Query("p"),
+		Style: c.Query("s"),
+	}
+	if req.Text == "" {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "必须提供文本参数"))
+		return
+	}
+	if length := utils.GraphemeCount(req.Text); length > h.config.TTS.MaxTextLength {
+		apperr.Abort(c, apperr.Newf(apperr.CodeTextTooLong, "文本长度超过限制 (%d > %d)", length, h.config.TTS.MaxTextLength))
+		return
+	}
+	if req.Voice != "" {
+		req.Voice = h.voices.Resolve(req.Voice, stickyKey(c))
+	}
+	if req.Voice == "" {
+		req.Voice = h.config.Telephony.Voice
+	}
+	if req.Voice == "" {
+		req.Voice = h.config.TTS.DefaultVoice
+	}
+	if req.Rate == "" {
+		req.Rate = h.config.TTS.DefaultRate
+	}
+	if req.Pitch == "" {
+		req.Pitch = h.config.TTS.DefaultPitch
+	}
+
+	etag := telephony.ETag(format, req)
+	c.Header("ETag", etag)
+	if h.config.Telephony.MaxAge > 0 {
+		c.Header("Cache-Control", fmt.Sprintf("max-age=%d", h.config.Telephony.MaxAge))
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+	if match := c.GetHeader("If-None-Match"); match != "" && (match == "*" || strings.Contains(match, etag)) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	resp, err := synthesize(c, h.synthesizer, req)
+	if err != nil {
+		log.Printf("电话语音提示合成失败: %v", err)
+		apperr.Abort(c, err)
+		return
+	}
+	stamped, id, err := stampAudio(c, h.config, resp.AudioContent)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	audio, err := telephony.Encode(stamped, format)
+	if err != nil {
+		log.Printf("电话语音提示转换失败: %v", err)
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "音频格式转换失败", err))
+		return
+	}
+	if id != "" {
+		c.Header(WatermarkHeader, id)
+	}
+	setChecksum(c, audio)
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="prompt.%s"`, format.Name))
+	c.Data(http.StatusOK, format.ContentType, audio)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/models"
+	"tts/internal/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reservedTemplateParams 是 GET 模板请求中不作为模板变量的查询参数
+var reservedTemplateParams = map[string]bool{"v": true, "r": true, "p": true, "s": true, "api_key": true}
+
+// HandleTemplates 返回所有可用的模板
+func (h *TTSHandler) HandleTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"templates": h.templates.List()})
+}
+
+// HandleTemplate 使用请求提供的变量渲染命名模板并合成语音。
+// POST 从 JSON 请求体读取变量；GET 时除 v/r/p/s/api_key 外的查询参数均作为变量
+func (h *TTSHandler) HandleTemplate(c *gin.Context) {
+	startTime := time.Now()
+
+	var req models.TemplateRequest
+	switch c.Request.Method {
+	case http.MethodPost:
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+				return
+			}
+		}
+	default:
+		req = models.TemplateRequest{
+			Voice:     c.Query("v"),
+			Rate:      c.Query("r"),
+			Pitch:     c.Query("p"),
+			Style:     c.Query("s"),
+			Variables: map[string]any{},
+		}
+		for key, values := range c.Request.URL.Query() {
+			if !reservedTemplateParams[key] && len(values) > 0 {
+				req.Variables[key] = values[0]
+			}
+		}
+	}
+
+	tmpl, err := h.templates.Get(c.Param("name"))
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	text, err := tmpl.Render(req.Variables)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+
+	voice := req.Voice
+	if voice == "" {
+		voice = h.templateVoice(c.Request.Context(), tmpl)
+	}
+	ttsReq := models.TTSRequest{
+		Text:  text,
+		Voice: voice,
+		Rate:  firstNonEmpty(req.Rate, tmpl.Rate),
+		Pitch: firstNonEmpty(req.Pitch, tmpl.Pitch),
+		Style: firstNonEmpty(req.Style, tmpl.Style),
+	}
+	parseTime := time.Since(startTime)
+	h.processTTSRequest(c, ttsReq, startTime, parseTime, "模板("+tmpl.Name+")")
+}
+
+// templateVoice 返回模板的默认语音：优先使用模板配置的语音；只配置了区域时，
+// 默认语音属于该区域则使用默认语音，否则使用该区域的第一个语音
+func (h *TTSHandler) templateVoice(ctx context.Context, tmpl *templates.Template) string {
+	if tmpl.Voice != "" || tmpl.Locale == "" {
+		return tmpl.Voice
+	}
+	if strings.HasPrefix(strings.ToLower(h.config.TTS.DefaultVoice), strings.ToLower(tmpl.Locale)+"-") {
+		return h.config.TTS.DefaultVoice
+	}
+	voices, err := h.synthesizer.ListVoices(ctx, tmpl.Locale)
+	if err != nil || len(voices) == 0 {
+		return ""
+	}
+	return voices[0].ShortName
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/policy"
+	"tts/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TermsHandler 处理使用条款的查询与确认
+type TermsHandler struct {
+	store  *store.Store
+	config *config.Config
+}
+
+// NewTermsHandler 创建使用条款处理器
+func NewTermsHandler(st *store.Store, cfg *config.Config) *TermsHandler {
+	return &TermsHandler{store: st, config: cfg}
+}
+
+// termsRequest 是确认条款的请求体
+type termsRequest struct {
+	Version string `json:"version"`
+}
+
+// HandleTerms 返回当前条款版本，以及请求方是否已经确认
+func (h *TermsHandler) HandleTerms(c *gin.Context) {
+	acceptance := policy.Accepted(h.store, policy.Identity(c))
+	accepted := acceptance != nil && acceptance.Version == h.config.Policy.TermsVersion
+	response := gin.H{
+		"version":            h.config.Policy.TermsVersion,
+		"url":                h.config.Policy.TermsURL,
+		"require_acceptance": h.config.Policy.RequireAcceptance,
+		"accepted":           accepted,
+	}
+	if accepted {
+		response["accepted_at"] = acceptance.AcceptedAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleAccept 记录请求方确认了当前版本的条款，请求中的版本必须与当前版本一致
+func (h *TermsHandler) HandleAccept(c *gin.Context) {
+	var req termsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
+		return
+	}
+	if req.Version != h.config.Policy.TermsVersion {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "条款版本不匹配，当前版本为 %s", h.config.Policy.TermsVersion))
+		return
+	}
+	acceptance, err := policy.Accept(h.store, c, req.Version)
+	if err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "保存确认记录失败", err))
+		return
+	}
+	log.Printf("使用条款已确认: %s, 版本 %s", acceptance.Identity, acceptance.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"version":     acceptance.Version,
+		"accepted_at": acceptance.AcceptedAt,
+	})
+}
@@ -6,16 +6,19 @@ import (
 	"github.com/google/uuid"
 	"log"
 	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
+	"tts/internal/apikey"
+	"tts/internal/apperr"
 	"tts/internal/config"
 	"tts/internal/models"
-	"tts/internal/tts"
+	"tts/internal/session"
+	"tts/internal/templates"
 	"tts/internal/utils"
+	"tts/internal/voicemap"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
 	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
@@ -23,91 +26,26 @@ import (
 
 var cfg = config.Get()
 
-// truncateForLog 截断文本用于日志显示，同时显示开头和结尾
-func truncateForLog(text string, maxLength int) string {
-	// 先去除换行符
-	text = strings.ReplaceAll(text, "\n", " ")
-	text = strings.ReplaceAll(text, "\r", " ")
-
-	runes := []rune(text)
-	if len(runes) <= maxLength {
-		return text
-	}
-	// 计算开头和结尾各显示多少字符
-	halfLength := maxLength / 2
-	return string(runes[:halfLength]) + "..." + string(runes[len(runes)-halfLength:])
-}
-
-// audioMerge 音频合并
-func audioMerge(audioSegments [][]byte) ([]byte, error) {
-	if len(audioSegments) == 0 {
-		return nil, fmt.Errorf("没有音频片段可合并")
-	}
-
-	// 使用 ffmpeg 合并音频
-	tempDir, err := os.MkdirTemp("", "audio_merge_")
-	if err != nil {
-		return nil, err
-	}
-	defer os.RemoveAll(tempDir)
-
-	listFile := filepath.Join(tempDir, "concat.txt")
-	lf, err := os.Create(listFile)
-	if err != nil {
-		return nil, err
-	}
-
-	for i, seg := range audioSegments {
-		segFile := filepath.Join(tempDir, fmt.Sprintf("seg_%d.mp3", i))
-		if err := os.WriteFile(segFile, seg, 0644); err != nil {
-			return nil, err
-		}
-		if _, err := lf.WriteString(fmt.Sprintf("file '%s'\n", segFile)); err != nil {
-			return nil, err
-		}
-	}
-	lf.Close()
-
-	outputFile := filepath.Join(tempDir, "output.mp3")
-
-	cmd := exec.Command("ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", listFile, "-c", "copy", outputFile)
-	if err := cmd.Run(); err != nil {
-		return nil, err
-	}
-
-	mergedData, err := os.ReadFile(outputFile)
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("使用ffmpeg合并完成，总大小: %s", formatFileSize(len(mergedData)))
-	return mergedData, nil
-}
-
-// formatFileSize 格式化文件大小
-func formatFileSize(size int) string {
-	switch {
-	case size < 1024:
-		return fmt.Sprintf("%d B", size)
-	case size < 1024*1024:
-		return fmt.Sprintf("%.2f KB", float64(size)/1024.0)
-	case size < 1024*1024*1024:
-		return fmt.Sprintf("%.2f MB", float64(size)/(1024.0*1024.0))
-	default:
-		return fmt.Sprintf("%.2f GB", float64(size)/(1024.0*1024.0*1024.0))
-	}
-}
+// maxPreviewSeconds 是 preview_seconds 的上限
+const maxPreviewSeconds = 300
 
 // TTSHandler 处理TTS请求
 type TTSHandler struct {
-	ttsService tts.Service
-	config     *config.Config
+	synthesizer *ttspkg.Synthesizer
+	voices      *voicemap.Mapper
+	templates   *templates.Set
+	config      *config.Config
+	sessions    *session.Store // 未启用 sessions 时为 nil
 }
 
 // NewTTSHandler 创建一个新的TTS处理器
-func NewTTSHandler(service tts.Service, cfg *config.Config) *TTSHandler {
+func NewTTSHandler(synthesizer *ttspkg.Synthesizer, textTemplates *templates.Set, sessions *session.Store, cfg *config.Config) *TTSHandler {
 	return &TTSHandler{
-		ttsService: service,
-		config:     cfg,
+		synthesizer: synthesizer,
+		voices:      voicemap.New(&cfg.TTS),
+		templates:   textTemplates,
+		config:      cfg,
+		sessions:    sessions,
 	}
 }
 
@@ -116,43 +54,123 @@ func (h *TTSHandler) processTTSRequest(c *gin.Context, req models.TTSRequest, st
 	// 验证必要参数
 	if req.Text == "" {
 		log.Print("错误: 未提供文本参数")
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "必须提供文本参数"})
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "必须提供文本参数"))
+		return
+	}
+	if !config.ValidURLMode(req.URLMode) {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "未知的 url_mode: %s，可选 remove、domain、text、keep", req.URLMode))
+		return
+	}
+	if !config.ValidQuoteMode(req.Quotes) {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "未知的 quotes: %s，可选 off、voice、prosody", req.Quotes))
+		return
+	}
+	if req.Latency != "" && req.Latency != ttspkg.LatencyLow && req.Latency != ttspkg.LatencyNormal {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "未知的 latency: %s，可选 low、normal", req.Latency))
+		return
+	}
+	if err := validatePadding(req); err != nil {
+		apperr.Abort(c, err)
 		return
 	}
+	if req.PreviewSeconds < 0 || req.PreviewSeconds > maxPreviewSeconds {
+		apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "preview_seconds 需要在 0～%d 之间", maxPreviewSeconds))
+		return
+	}
+
+	// 通过 /v1/sessions 创建的会话中的设置作为未指定参数的默认值
+	sessionID, final := h.sessionID(c)
+	if sessionID != "" && req.PreviewSeconds > 0 {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "会话请求不支持 preview_seconds"))
+		return
+	}
+	settings, created := models.SessionSettings{}, false
+	if sessionID != "" {
+		settings, created = h.sessions.Settings(sessionID)
+		if created {
+			h.applySession(c, &req, settings)
+		}
+	}
 
 	// 使用默认值填充空白参数
 	h.fillDefaultValues(&req)
 
-	// 检查文本长度
-	reqTextLength := utf8.RuneCountInString(req.Text)
+	// 增量模式只合成相对上次提交新增的部分，未创建过的会话默认使用增量模式
+	incremental := sessionID != "" && (!created || settings.Incremental)
+	var delta session.Delta
+	if incremental {
+		delta = h.sessions.Delta(sessionID, req.Text, final)
+		c.Header("X-Session-Spoken", strconv.Itoa(delta.Spoken))
+		if delta.Reset {
+			c.Header("X-Session-Reset", "true")
+		}
+		if delta.Text == "" {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		req.Text = delta.Text
+	}
+	if created {
+		req.Text = h.sessions.Replace(sessionID, req.Text)
+	}
+
+	// 试听只合成开头的一部分，在长度检查之前截取，超过长度限制的长文档也可以试听
+	if req.PreviewSeconds > 0 {
+		preview := h.synthesizer.Segmenter().Preview(req.Text, req.Rate, ttspkg.LocaleOf(req.Voice), req.PreviewSeconds)
+		c.Header("X-Preview-Truncated", strconv.FormatBool(preview != req.Text))
+		req.Text = preview
+	}
+
+	// 检查文本长度，按用户可见字符计数而不是字节
+	reqTextLength := utils.GraphemeCount(req.Text)
 	if reqTextLength > h.config.TTS.MaxTextLength {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "文本长度超过限制"})
+		apperr.Abort(c, apperr.Newf(apperr.CodeTextTooLong, "文本长度超过限制 (%d > %d)", reqTextLength, h.config.TTS.MaxTextLength))
 		return
 	}
 
-	// 检查是否需要分段处理
-	segmentThreshold := h.config.TTS.SegmentThreshold
-	if reqTextLength > segmentThreshold && reqTextLength <= h.config.TTS.MaxTextLength {
-		log.Printf("文本长度 %d 超过阈值 %d，使用分段处理", reqTextLength, segmentThreshold)
-		h.handleSegmentedTTS(c, req)
+	// 流式返回时边合成边写出；水印与静音补充需要完整的音频，启用水印的密钥与补充静音的请求仍一次性返回
+	if stream, _ := strconv.ParseBool(c.Query("stream")); stream && !watermark.Enabled(h.config, apikey.FromContext(c)) && !hasPadding(req) {
+		synthStart := time.Now()
+		if !streamSynthesize(c, h.synthesizer, req, h.config.TTS.StreamBuffer) {
+			return
+		}
+		if incremental {
+			h.sessions.Commit(sessionID, delta.Upto)
+		}
+		log.Printf("%s流式请求总耗时: %v (解析: %v, 合成与写入: %v)", requestType, time.Since(startTime), parseTime, time.Since(synthStart))
 		return
 	}
 
+	// 合成器会在超过分段阈值时自动分段处理
 	synthStart := time.Now()
-	resp, err := h.ttsService.SynthesizeSpeech(c.Request.Context(), req)
+	resp, err := synthesize(c, h.synthesizer, req)
 	synthTime := time.Since(synthStart)
 	log.Printf("TTS合成耗时: %v, 文本长度: %d", synthTime, reqTextLength)
 
 	if err != nil {
 		log.Printf("TTS合成失败: %v", err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "语音合成失败: " + err.Error()})
+		apperr.Abort(c, err)
+		return
+	}
+	if incremental {
+		h.sessions.Commit(sessionID, delta.Upto)
+	}
+
+	// 按请求补充静音，再按密钥设置添加水印
+	padded, err := padAudio(req, resp.AudioContent)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	audio, ok := writeStamped(c, h.config, padded)
+	if !ok {
 		return
 	}
 
 	// 设置响应
 	c.Header("Content-Type", "audio/mpeg")
 	writeStart := time.Now()
-	if _, err := c.Writer.Write(resp.AudioContent); err != nil {
+	if _, err := c.Writer.Write(audio); err != nil {
 		log.Printf("写入响应失败: %v", err)
 		return
 	}
@@ -161,7 +179,45 @@ func (h *TTSHandler) processTTSRequest(c *gin.Context, req models.TTSRequest, st
 	// 记录总耗时
 	totalTime := time.Since(startTime)
 	log.Printf("%s请求总耗时: %v (解析: %v, 合成: %v, 写入: %v), 音频大小: %s",
-		requestType, totalTime, parseTime, synthTime, writeTime, formatFileSize(len(resp.AudioContent)))
+		requestType, totalTime, parseTime, synthTime, writeTime, utils.FormatFileSize(len(audio)))
+}
+
+// sessionID 返回请求的会话在存储中的键与消息是否已经结束，未启用 sessions 或未指定会话时返回空字符串。
+// 会话标识来自 X-Session-ID 请求头或 session_id 查询参数，按API密钥隔离；
+// X-Session-Final 请求头或 final 查询参数为 true 时增量模式合成剩余的全部文本。
+func (h *TTSHandler) sessionID(c *gin.Context) (string, bool) {
+	if h.sessions == nil {
+		return "", false
+	}
+	id := c.GetHeader("X-Session-ID")
+	if id == "" {
+		id = c.Query("session_id")
+	}
+	if id == "" {
+		return "", false
+	}
+	final := c.GetHeader("X-Session-Final")
+	if final == "" {
+		final = c.Query("final")
+	}
+	done, _ := strconv.ParseBool(final)
+	return sessionKey(c, id), done
+}
+
+// applySession 用会话中的设置填充请求中未指定的参数，会话中的语音同样按 voice_mapping 解析
+func (h *TTSHandler) applySession(c *gin.Context, req *models.TTSRequest, settings models.SessionSettings) {
+	if req.Voice == "" && settings.Voice != "" {
+		req.Voice = h.voices.Resolve(settings.Voice, stickyKey(c))
+	}
+	if req.Rate == "" {
+		req.Rate = settings.Rate
+	}
+	if req.Pitch == "" {
+		req.Pitch = settings.Pitch
+	}
+	if req.Style == "" {
+		req.Style = settings.Style
+	}
 }
 
 // fillDefaultValues 填充默认值
@@ -185,7 +241,7 @@ func (h *TTSHandler) HandleTTS(c *gin.Context) {
 	case http.MethodPost:
 		h.HandleTTSPost(c)
 	default:
-		c.AbortWithStatusJSON(http.StatusMethodNotAllowed, gin.H{"error": "仅支持GET和POST请求"})
+		apperr.Abort(c, apperr.New(apperr.CodeMethodNotAllowed, "仅支持GET和POST请求"))
 	}
 }
 
@@ -195,13 +251,30 @@ func (h *TTSHandler) HandleTTSGet(c *gin.Context) {
 
 	// 从URL参数获取
 	req := models.TTSRequest{
-		Text:  c.Query("t"),
-		Voice: c.Query("v"),
-		Rate:  c.Query("r"),
-		Pitch: c.Query("p"),
-		Style: c.Query("s"),
+		Text:    c.Query("t"),
+		Voice:   c.Query("v"),
+		Rate:    c.Query("r"),
+		Pitch:   c.Query("p"),
+		Style:   c.Query("s"),
+		URLMode: c.Query("url_mode"),
+		Quotes:  c.Query("quotes"),
+		Latency: c.Query("latency"),
+	}
+	if err := bindPadding(c, &req); err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	if value := c.Query("preview_seconds"); value != "" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "无效的 preview_seconds: %s", value))
+			return
+		}
+		req.PreviewSeconds = seconds
 	}
 
+	// v 可以使用 voice_mapping 中的别名，并按 voice_rollout 灰度切换
+	req.Voice = h.voices.Resolve(req.Voice, stickyKey(c))
 	parseTime := time.Since(startTime)
 	h.processTTSRequest(c, req, startTime, parseTime, "TTS GET")
 }
@@ -218,18 +291,20 @@ func (h *TTSHandler) HandleTTSPost(c *gin.Context) {
 		err = c.ShouldBindJSON(&req)
 		if err != nil {
 			log.Printf("JSON解析错误: %v", err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "无效的JSON请求"})
+			apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
 			return
 		}
 	} else {
 		err = c.ShouldBind(&req)
 		if err != nil {
 			log.Printf("表单解析错误: %v", err)
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "无法解析表单数据"})
+			apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无法解析表单数据", err))
 			return
 		}
 	}
 
+	// v 可以使用 voice_mapping 中的别名，并按 voice_rollout 灰度切换
+	req.Voice = h.voices.Resolve(req.Voice, stickyKey(c))
 	parseTime := time.Since(startTime)
 	h.processTTSRequest(c, req, startTime, parseTime, "TTS POST")
 }
@@ -240,14 +315,14 @@ func (h *TTSHandler) HandleOpenAITTS(c *gin.Context) {
 
 	// 只支持POST请求
 	if c.Request.Method != http.MethodPost {
-		c.AbortWithStatusJSON(http.StatusMethodNotAllowed, gin.H{"error": "仅支持POST请求"})
+		apperr.Abort(c, apperr.New(apperr.CodeMethodNotAllowed, "仅支持POST请求"))
 		return
 	}
 
 	// 解析请求
 	var openaiReq models.OpenAIRequest
 	if err := c.ShouldBindJSON(&openaiReq); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "无效的JSON请求: " + err.Error()})
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的JSON请求", err))
 		return
 	}
 
@@ -255,12 +330,12 @@ func (h *TTSHandler) HandleOpenAITTS(c *gin.Context) {
 
 	// 检查必需字段
 	if openaiReq.Input == "" {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "input字段不能为空"})
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidRequest, "input字段不能为空"))
 		return
 	}
 
 	// 创建内部TTS请求
-	req := h.convertOpenAIRequest(openaiReq)
+	req := h.convertOpenAIRequest(openaiReq, stickyKey(c))
 
 	log.Printf("OpenAI TTS请求: model=%s, voice=%s → %s, speed=%.2f → %s, 文本长度=%d",
 		openaiReq.Model, openaiReq.Voice, req.Voice, openaiReq.Speed, req.Rate, utf8.RuneCountInString(req.Text))
@@ -268,16 +343,24 @@ func (h *TTSHandler) HandleOpenAITTS(c *gin.Context) {
 	h.processTTSRequest(c, req, startTime, parseTime, "OpenAI TTS")
 }
 
-// convertOpenAIRequest 将OpenAI请求转换为内部请求格式
-func (h *TTSHandler) convertOpenAIRequest(openaiReq models.OpenAIRequest) models.TTSRequest {
-	// 映射OpenAI声音到Microsoft声音
-	msVoice := openaiReq.Voice
-	if openaiReq.Voice != "" && h.config.TTS.VoiceMapping[openaiReq.Voice] != "" {
-		msVoice = h.config.TTS.VoiceMapping[openaiReq.Voice]
+// stickyKey 返回用于语音灰度分组的键，优先使用API密钥，其次是客户端IP
+func stickyKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		return auth
+	}
+	if key := c.Query("api_key"); key != "" {
+		return key
 	}
+	return c.ClientIP()
+}
 
-	// 转换速度参数到微软格式
-	msRate := h.config.TTS.DefaultRate
+// convertOpenAIRequest 将OpenAI请求转换为内部请求格式
+func (h *TTSHandler) convertOpenAIRequest(openaiReq models.OpenAIRequest, stickyKey string) models.TTSRequest {
+	// 映射OpenAI声音到Microsoft声音，灰度规则优先
+	msVoice := h.voices.Resolve(openaiReq.Voice, stickyKey)
+
+	// 转换速度参数到微软格式，未指定的参数留空，由会话设置或默认值填充
+	msRate := ""
 	if openaiReq.Speed != 0 {
 		speedPercentage := (openaiReq.Speed - 1.0) * 100
 		if speedPercentage >= 0 {
@@ -291,169 +374,10 @@ func (h *TTSHandler) convertOpenAIRequest(openaiReq models.OpenAIRequest) models
 		Text:  openaiReq.Input,
 		Voice: msVoice,
 		Rate:  msRate,
-		Pitch: h.config.TTS.DefaultPitch,
 		Style: openaiReq.Model,
 	}
 }
 
-// Add this struct to store synthesis results
-type sentenceSynthesisResult struct {
-	index     int
-	length    int
-	audioSize int
-	content   string
-	duration  time.Duration
-}
-
-// Modify the handleSegmentedTTS function to collect and display results in a table
-func (h *TTSHandler) handleSegmentedTTS(c *gin.Context, req models.TTSRequest) {
-	segmentStart := time.Now()
-	text := req.Text
-
-	// 开始计时：分割文本
-	splitStart := time.Now()
-	sentences := splitTextBySentences(text)
-	segmentCount := len(sentences)
-	splitTime := time.Since(splitStart)
-
-	log.Printf("分割文本耗时: %v, 文本总长度: %d, 分段数: %d, 平均句子长度: %.2f",
-		splitTime, utf8.RuneCountInString(text), segmentCount, float64(utf8.RuneCountInString(text))/float64(segmentCount))
-
-	// 创建用于存储每段音频的切片
-	results := make([][]byte, segmentCount)
-	// 创建用于收集合成结果信息的切片
-	synthResults := make([]sentenceSynthesisResult, segmentCount)
-
-	errChan := make(chan error, 1)
-	var wg sync.WaitGroup
-	var synthMutex sync.Mutex
-
-	// 限制并发数量
-	maxConcurrent := h.config.TTS.MaxConcurrent
-	semaphore := make(chan struct{}, maxConcurrent)
-
-	// 合成阶段开始时间
-	synthesisStart := time.Now()
-
-	// 并发处理每一个句子
-	for i := 0; i < segmentCount; i++ {
-		wg.Add(1)
-		go func(index int) {
-			defer wg.Done()
-
-			select {
-			case semaphore <- struct{}{}: // 获取信号量
-				defer func() { <-semaphore }() // 释放信号量
-			case <-c.Request.Context().Done():
-				select {
-				case errChan <- c.Request.Context().Err():
-				default:
-				}
-				return
-			}
-
-			// 创建该句的请求
-			segReq := models.TTSRequest{
-				Text:  sentences[index],
-				Voice: req.Voice,
-				Rate:  req.Rate,
-				Pitch: req.Pitch,
-				Style: req.Style,
-			}
-
-			startTime := time.Now()
-			// 合成该段音频
-			resp, err := h.ttsService.SynthesizeSpeech(c.Request.Context(), segReq)
-			synthDuration := time.Since(startTime)
-
-			if err != nil {
-				select {
-				case errChan <- fmt.Errorf("句子 %d 合成失败: %w", index+1, err):
-				default:
-				}
-				return
-			}
-
-			// 收集合成结果信息，而不是立即打印
-			result := sentenceSynthesisResult{
-				index:     index,
-				length:    utf8.RuneCountInString(sentences[index]),
-				audioSize: len(resp.AudioContent),
-				content:   truncateForLog(sentences[index], 20),
-				duration:  synthDuration,
-			}
-
-			synthMutex.Lock()
-			synthResults[index] = result
-			results[index] = resp.AudioContent
-			synthMutex.Unlock()
-		}(i)
-	}
-
-	// 等待所有goroutine完成或出错
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		// 所有goroutine正常完成
-	case err := <-errChan:
-		// 发生错误
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	case <-c.Request.Context().Done():
-		// 请求被取消
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "请求被取消"})
-		return
-	}
-
-	// 打印表格格式的合成结果
-	log.Println("句子合成结果表:")
-	log.Println("-------------------------------------------------------------")
-	log.Println("序号 | 长度  |    音频大小   |    耗时    | 内容")
-	log.Println("-------------------------------------------------------------")
-	for i := 0; i < segmentCount; i++ {
-		result := synthResults[i]
-		log.Printf("#%-3d | %4d | %12s | %10v | %s",
-			i+1,
-			result.length,
-			formatFileSize(result.audioSize),
-			result.duration.Round(time.Millisecond),
-			result.content)
-	}
-	log.Println("-------------------------------------------------------------")
-
-	// 记录合成总耗时
-	synthesisTime := time.Since(synthesisStart)
-	log.Printf("所有分段合成总耗时: %v, 平均每段耗时: %v",
-		synthesisTime, synthesisTime/time.Duration(segmentCount))
-
-	// 合并音频
-	writeStart := time.Now()
-	audioData, err := audioMerge(results)
-	if err != nil {
-		log.Printf("合并音频失败: %v", err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "音频合并失败: " + err.Error()})
-		return
-	}
-
-	// 设置响应内容类型并写入数据
-	c.Header("Content-Type", "audio/mpeg")
-	if _, err := c.Writer.Write(audioData); err != nil {
-		log.Printf("写入响应失败: %v", err)
-		return
-	}
-
-	// 记录写入耗时和总耗时
-	writeTime := time.Since(writeStart)
-	totalTime := time.Since(segmentStart)
-	log.Printf("分段TTS请求总耗时: %v (分割: %v, 合成: %v, 写入: %v), 总音频大小: %s",
-		totalTime, splitTime, synthesisTime, writeTime, formatFileSize(len(audioData)))
-}
-
 // HandleReader 返回 reader 可导入的格式
 func (h *TTSHandler) HandleReader(context *gin.Context) {
 	// 从URL参数获取
@@ -469,7 +393,7 @@ func (h *TTSHandler) HandleReader(context *gin.Context) {
 	baseUrl := utils.GetBaseURL(context)
 	basePath, err := utils.JoinURL(baseUrl, cfg.Server.BasePath)
 	if err != nil {
-		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperr.Abort(context, apperr.Wrap(apperr.CodeInternal, "生成URL失败", err))
 		return
 	}
 
@@ -520,7 +444,7 @@ func (h *TTSHandler) HandleIFreeTime(context *gin.Context) {
 	baseUrl := utils.GetBaseURL(context)
 	basePath, err := utils.JoinURL(baseUrl, cfg.Server.BasePath)
 	if err != nil {
-		context.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		apperr.Abort(context, apperr.Wrap(apperr.CodeInternal, "生成URL失败", err))
 		return
 	}
 
@@ -585,21 +509,3 @@ func (h *TTSHandler) HandleIFreeTime(context *gin.Context) {
 	context.Header("Content-Type", "application/json")
 	context.JSON(http.StatusOK, response)
 }
-
-// splitTextBySentences 将文本按句子分割
-func splitTextBySentences(text string) []string {
-	// 如果文本过短，直接作为一个句子返回
-	if utf8.RuneCountInString(text) < 100 {
-		return []string{text}
-	}
-
-	maxLen := cfg.TTS.MaxSentenceLength
-	minLen := cfg.TTS.MinSentenceLength
-
-	// 第一次分割：按标点和长度限制分割
-	sentences := utils.SplitAndFilterEmptyLines(text)
-	// 第二次处理：合并过短的句子
-	shortSentences := utils.MergeStringsWithLimit(sentences, minLen, maxLen)
-	log.Printf("分割后的句子数: %d → %d", len(sentences), len(shortSentences))
-	return shortSentences
-}
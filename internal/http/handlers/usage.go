@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/apperr"
+	"tts/internal/metrics"
+	"tts/internal/models"
+	"tts/internal/utils"
+	"tts/internal/voiceprefs"
+	ttspkg "tts/pkg/tts"
+)
+
+var (
+	charactersTotal = metrics.NewCounter("tts_characters_total",
+		"实际合成的字符数，客户端中途断开时只计入已经合成的片段", "key")
+	disconnectsTotal = metrics.NewCounter("tts_client_disconnects_total",
+		"合成完成前客户端已断开的请求数")
+)
+
+// synthesize 合成语音并按实际合成的字符数记录用量。
+// 客户端在合成完成前断开时，请求上下文被取消，剩余片段不再合成，只记录已经合成的字符数。
+func synthesize(c *gin.Context, synthesizer *ttspkg.Synthesizer, req models.TTSRequest) (*models.TTSResponse, error) {
+	ctx, usage := ttspkg.WithUsage(c.Request.Context())
+	resp, err := synthesizer.Synthesize(ctx, req)
+	recordUsage(c, usage, req)
+	if err == nil {
+		c.Set(voiceprefs.ContextKey, req.Voice)
+	}
+	return resp, err
+}
+
+// streamSynthesize 以流式方式合成语音，每个片段合成完成即写给客户端，用量记录方式与 synthesize 相同。
+// 开始写出之前的错误中止请求并返回错误响应，之后的错误只能中断连接。
+func streamSynthesize(c *gin.Context, synthesizer *ttspkg.Synthesizer, req models.TTSRequest, buffer int) bool {
+	ctx, usage := ttspkg.WithUsage(c.Request.Context())
+	c.Header("Content-Type", "audio/mpeg")
+	err := synthesizer.Stream(ctx, req, c.Writer, buffer)
+	recordUsage(c, usage, req)
+	if err == nil {
+		c.Set(voiceprefs.ContextKey, req.Voice)
+		return true
+	}
+
+	log.Printf("流式合成失败: %v", err)
+	if c.Writer.Written() {
+		c.Abort()
+	} else {
+		apperr.Abort(c, err)
+	}
+	return false
+}
+
+// recordUsage 按请求所用密钥记录实际合成的字符数，客户端已断开时记录断开次数
+func recordUsage(c *gin.Context, usage *ttspkg.Usage, req models.TTSRequest) {
+	recordCharacters(c.Request.Context(), usageKey(c), usage, req)
+}
+
+// usageKey 返回记录用量使用的密钥名称，未使用密钥时为 anonymous
+func usageKey(c *gin.Context) string {
+	if profile := apikey.FromContext(c); profile != nil && profile.Name != "" {
+		return profile.Name
+	}
+	return "anonymous"
+}
+
+// recordCharacters 记录 key 实际合成的字符数，ctx 已取消时记录断开次数。
+// 不引用 gin.Context，可以在请求处理函数之外的协程中调用
+func recordCharacters(ctx context.Context, key string, usage *ttspkg.Usage, req models.TTSRequest) {
+	charactersTotal.Add(float64(usage.Characters()), key)
+
+	if ctx.Err() != nil {
+		disconnectsTotal.Inc()
+		log.Printf("客户端已断开，取消剩余片段，已合成 %d/%d 字", usage.Characters(), utils.GraphemeCount(req.Text))
+	}
+}
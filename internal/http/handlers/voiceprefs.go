@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tts/internal/apperr"
+	"tts/internal/policy"
+	"tts/internal/store"
+	"tts/internal/tts"
+	"tts/internal/voiceprefs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VoicePrefsHandler 处理按 API 密钥记录的最近使用与收藏语音
+type VoicePrefsHandler struct {
+	store      *store.Store
+	ttsService tts.Service
+}
+
+// NewVoicePrefsHandler 创建最近使用与收藏语音处理器
+func NewVoicePrefsHandler(st *store.Store, service tts.Service) *VoicePrefsHandler {
+	return &VoicePrefsHandler{store: st, ttsService: service}
+}
+
+// HandleRecent 返回请求方最近使用的语音，最近的在前，?limit= 限制数量
+func (h *VoicePrefsHandler) HandleRecent(c *gin.Context) {
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "无效的 limit: %s", value))
+			return
+		}
+		limit = parsed
+	}
+	c.JSON(http.StatusOK, gin.H{"voices": voiceprefs.Recent(h.store, policy.Identity(c), limit)})
+}
+
+// HandleFavorites 返回请求方收藏的语音
+func (h *VoicePrefsHandler) HandleFavorites(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"voices": voiceprefs.Favorites(h.store, policy.Identity(c))})
+}
+
+// HandleAddFavorite 收藏语音，语音必须存在，重复收藏不报错
+func (h *VoicePrefsHandler) HandleAddFavorite(c *gin.Context) {
+	voice, err := h.findVoice(c, c.Param("name"))
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	favorite, err := voiceprefs.AddFavorite(h.store, policy.Identity(c), voice)
+	if err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "保存收藏失败", err))
+		return
+	}
+	c.JSON(http.StatusOK, favorite)
+}
+
+// HandleRemoveFavorite 取消收藏语音
+func (h *VoicePrefsHandler) HandleRemoveFavorite(c *gin.Context) {
+	removed, err := voiceprefs.RemoveFavorite(h.store, policy.Identity(c), c.Param("name"))
+	if err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "保存收藏失败", err))
+		return
+	}
+	if !removed {
+		apperr.Abort(c, apperr.Newf(apperr.CodeNotFound, "未收藏语音: %s", c.Param("name")))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// findVoice 按简称或全名查找语音，返回其简称
+func (h *VoicePrefsHandler) findVoice(c *gin.Context, name string) (string, error) {
+	voices, err := h.ttsService.ListVoices(c.Request.Context(), "")
+	if err != nil {
+		return "", err
+	}
+	for _, voice := range voices {
+		if strings.EqualFold(voice.ShortName, name) || strings.EqualFold(voice.Name, name) {
+			return voice.ShortName, nil
+		}
+	}
+	return "", apperr.Newf(apperr.CodeNotFound, "语音不存在: %s", name)
+}
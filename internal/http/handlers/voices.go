@@ -1,21 +1,39 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"net/http"
+	"strings"
+	"tts/internal/apperr"
+	"tts/internal/cache"
+	"tts/internal/config"
+	"tts/internal/models"
 	"tts/internal/tts"
 
 	"github.com/gin-gonic/gin"
 )
 
+// defaultPreviewText 未配置示例句子时的试听文本
+const defaultPreviewText = "Hello, this is a sample of my voice."
+
+// voicesCacheControl 是语音列表的缓存策略：客户端 5 分钟内直接使用本地副本，之后凭 ETag 重新验证
+const voicesCacheControl = "public, max-age=300"
+
 // VoicesHandler 处理语音列表请求
 type VoicesHandler struct {
 	ttsService tts.Service
+	config     *config.Config
+	cache      *cache.Cache
 }
 
 // NewVoicesHandler 创建一个新的语音列表处理器
-func NewVoicesHandler(service tts.Service) *VoicesHandler {
+func NewVoicesHandler(service tts.Service, cfg *config.Config, audioCache *cache.Cache) *VoicesHandler {
 	return &VoicesHandler{
 		ttsService: service,
+		config:     cfg,
+		cache:      audioCache,
 	}
 }
 
@@ -27,10 +45,128 @@ func (h *VoicesHandler) HandleVoices(c *gin.Context) {
 	// 获取语音列表
 	voices, err := h.ttsService.ListVoices(c.Request.Context(), locale)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "获取语音列表失败: " + err.Error()})
+		apperr.Abort(c, err)
+		return
+	}
+	if tag := c.Query("tag"); tag != "" {
+		voices = filterByTag(voices, tag)
+	}
+
+	body, err := json.Marshal(voices)
+	if err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInternal, "序列化语音列表失败", err))
+		return
+	}
+
+	// 语音列表很少变化，按内容生成 ETag，内容未变时返回 304，轮询的前端无需重新下载完整列表
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", voicesCacheControl)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// filterByTag 返回带有指定标签（tts.voices 中配置）的语音，标签不区分大小写
+func filterByTag(voices []models.Voice, tag string) []models.Voice {
+	filtered := make([]models.Voice, 0)
+	for _, voice := range voices {
+		for _, t := range voice.Tags {
+			if strings.EqualFold(t, tag) {
+				filtered = append(filtered, voice)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// etagMatches 判断 If-None-Match 是否包含 etag，按弱比较忽略 W/ 前缀
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// HandlePreview 返回指定语音朗读示例句子的试听音频，结果会被缓存。
+// 示例句子优先使用 tts.voices 中为该语音配置的句子
+func (h *VoicesHandler) HandlePreview(c *gin.Context) {
+	voice, err := h.findVoice(c, c.Param("name"))
+	if err != nil {
+		apperr.Abort(c, err)
 		return
 	}
 
-	// 返回JSON响应
-	c.JSON(http.StatusOK, voices)
+	text := voice.SampleText
+	if text == "" {
+		text = h.previewText(voice.Locale)
+	}
+	key := cache.Key("preview", voice.ShortName, text)
+	// 接口需要认证，响应不应被共享缓存保存
+	c.Header("Cache-Control", "private, max-age=86400")
+
+	if audio, ok := h.cache.Get(key); ok {
+		c.Header("X-Cache", "HIT")
+		setChecksum(c, audio)
+		c.Data(http.StatusOK, "audio/mpeg", audio)
+		return
+	}
+
+	resp, err := h.ttsService.SynthesizeSpeech(c.Request.Context(), models.TTSRequest{
+		Text:  text,
+		Voice: voice.ShortName,
+		Rate:  h.config.TTS.DefaultRate,
+		Pitch: h.config.TTS.DefaultPitch,
+	})
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	h.cache.Set(key, resp.AudioContent)
+
+	c.Header("X-Cache", "MISS")
+	setChecksum(c, resp.AudioContent)
+	c.Data(http.StatusOK, "audio/mpeg", resp.AudioContent)
+}
+
+// findVoice 在语音列表中按简称或全名查找语音，避免为不存在的语音生成并缓存音频
+func (h *VoicesHandler) findVoice(c *gin.Context, name string) (*models.Voice, error) {
+	voices, err := h.ttsService.ListVoices(c.Request.Context(), "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range voices {
+		if strings.EqualFold(voices[i].ShortName, name) || strings.EqualFold(voices[i].Name, name) {
+			return &voices[i], nil
+		}
+	}
+	return nil, apperr.Newf(apperr.CodeNotFound, "语音不存在: %s", name)
+}
+
+// previewText 按区域、语言、default 的顺序选择示例句子
+func (h *VoicesHandler) previewText(locale string) string {
+	texts := make(map[string]string, len(h.config.TTS.PreviewTexts))
+	for key, text := range h.config.TTS.PreviewTexts {
+		texts[strings.ToLower(key)] = text
+	}
+	locale = strings.ToLower(locale)
+	if text, ok := texts[locale]; ok {
+		return text
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		if text, ok := texts[lang]; ok {
+			return text
+		}
+	}
+	if text, ok := texts["default"]; ok {
+		return text
+	}
+	return defaultPreviewText
 }
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"tts/internal/apperr"
+	"tts/internal/cache"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/warm"
+	ttspkg "tts/pkg/tts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWarmItems 是一次预热请求最多包含的内容条数
+const maxWarmItems = 1000
+
+// WarmHandler 处理缓存预热与按内容导出缓存的请求
+type WarmHandler struct {
+	queue       *warm.Queue // 未启用 cache.synthesis 时为 nil
+	synthesizer *ttspkg.Synthesizer
+	config      *config.Config
+}
+
+// NewWarmHandler 创建缓存预热处理器
+func NewWarmHandler(queue *warm.Queue, synthesizer *ttspkg.Synthesizer, cfg *config.Config) *WarmHandler {
+	return &WarmHandler{queue: queue, synthesizer: synthesizer, config: cfg}
+}
+
+// warmRequest 是预热请求，每条内容未指定的语音参数使用配置的默认值
+type warmRequest struct {
+	Items []models.TTSRequest `json:"items"`
+}
+
+// normalize 校验各条内容，并为未指定的语音参数填入配置的默认值
+func (r *warmRequest) normalize(cfg *config.Config) error {
+	if len(r.Items) == 0 {
+		return apperr.New(apperr.CodeInvalidRequest, "items 不能为空")
+	}
+	if len(r.Items) > maxWarmItems {
+		return apperr.Newf(apperr.CodeInvalidRequest, "items 最多 %d 条", maxWarmItems)
+	}
+	for i := range r.Items {
+		item := &r.Items[i]
+		if item.Text == "" {
+			return apperr.Newf(apperr.CodeInvalidRequest, "第 %d 条的 text 不能为空", i+1)
+		}
+		if !config.ValidURLMode(item.URLMode) {
+			return apperr.Newf(apperr.CodeInvalidRequest, "第 %d 条的 url_mode 无效: %s", i+1, item.URLMode)
+		}
+		if !config.ValidQuoteMode(item.Quotes) {
+			return apperr.Newf(apperr.CodeInvalidRequest, "第 %d 条的 quotes 无效: %s", i+1, item.Quotes)
+		}
+		if item.Voice == "" {
+			item.Voice = cfg.TTS.DefaultVoice
+		}
+		if item.Rate == "" {
+			item.Rate = cfg.TTS.DefaultRate
+		}
+		if item.Pitch == "" {
+			item.Pitch = cfg.TTS.DefaultPitch
+		}
+	}
+	return nil
+}
+
+// HandleSubmit 提交预热任务，立即返回 202 与任务状态，合成在后台逐条进行
+func (h *WarmHandler) HandleSubmit(c *gin.Context) {
+	if h.queue == nil {
+		apperr.Abort(c, apperr.New(apperr.CodeNotSupported, "未启用合成结果缓存 (cache.synthesis)，无法预热"))
+		return
+	}
+	var req warmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的请求格式", err))
+		return
+	}
+	if err := req.normalize(h.config); err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+
+	task, err := h.queue.Submit(config.TenantFromContext(c.Request.Context()), req.Items)
+	if err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, task)
+}
+
+// HandleStatus 返回预热任务的进度
+func (h *WarmHandler) HandleStatus(c *gin.Context) {
+	if h.queue == nil {
+		apperr.Abort(c, apperr.New(apperr.CodeNotSupported, "未启用合成结果缓存 (cache.synthesis)，无法预热"))
+		return
+	}
+	task, ok := h.queue.Get(c.Param("id"))
+	if !ok {
+		apperr.Abort(c, apperr.Newf(apperr.CodeNotFound, "预热任务不存在: %s", c.Param("id")))
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// HandleExport 把一组内容的合成结果缓存导出为 tar.gz，供离线设备导入后直接使用。
+// 请求格式与预热相同，未缓存的内容先合成；任一条合成失败时整个请求失败，避免导出不完整的内容
+func (h *WarmHandler) HandleExport(c *gin.Context) {
+	if !h.config.Cache.Synthesis {
+		apperr.Abort(c, apperr.New(apperr.CodeNotSupported, "未启用合成结果缓存 (cache.synthesis)，无法导出"))
+		return
+	}
+	var req warmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无效的请求格式", err))
+		return
+	}
+	if err := req.normalize(h.config); err != nil {
+		apperr.Abort(c, err)
+		return
+	}
+
+	// 经过合成结果缓存的每个片段都记录在 recorder 中，长文本导出的是各片段的条目
+	ctx, recorder := cache.WithRecorder(c.Request.Context())
+	errs := make([]error, len(req.Items))
+	semaphore := make(chan struct{}, max(1, h.config.TTS.MaxConcurrent))
+	var wg sync.WaitGroup
+	for i, item := range req.Items {
+		wg.Add(1)
+		go func(index int, item models.TTSRequest) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			_, errs[index] = h.synthesizer.Synthesize(ctx, item)
+		}(i, item)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			appErr := apperr.From(err)
+			apperr.Abort(c, apperr.Wrap(appErr.Code, fmt.Sprintf("第 %d 条合成失败: %s", i+1, appErr.Message), err))
+			return
+		}
+	}
+
+	entries := recorder.Entries()
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", `attachment; filename="tts-cache.tar.gz"`)
+	c.Status(http.StatusOK)
+	if err := cache.WriteArchive(c.Writer, entries); err != nil {
+		log.Printf("导出缓存失败: %v", err)
+		return
+	}
+	log.Printf("导出缓存完成: 内容 %d 条, 缓存条目 %d", len(req.Items), len(entries))
+}
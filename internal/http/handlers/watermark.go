@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/watermark"
+)
+
+// WatermarkHeader 是返回水印ID的响应头
+const WatermarkHeader = "X-Watermark-Id"
+
+// stampAudio 按请求所用密钥的设置为音频添加水印，未启用时原样返回，水印ID为空
+func stampAudio(c *gin.Context, cfg *config.Config, audio []byte) ([]byte, string, error) {
+	profile := apikey.FromContext(c)
+	if !watermark.Enabled(cfg, profile) {
+		return audio, "", nil
+	}
+	source := ""
+	if profile != nil {
+		source = profile.Name
+	}
+	stamped, info, err := watermark.Apply(audio, cfg.Watermark, source)
+	if err != nil {
+		return nil, "", apperr.Wrap(apperr.CodeInternal, "添加水印失败", err)
+	}
+	return stamped, info.ID, nil
+}
+
+// writeStamped 添加水印并设置水印与校验和响应头，失败时中止请求并返回 false
+func writeStamped(c *gin.Context, cfg *config.Config, audio []byte) ([]byte, bool) {
+	stamped, id, err := stampAudio(c, cfg, audio)
+	if err != nil {
+		apperr.Abort(c, err)
+		return nil, false
+	}
+	if id != "" {
+		c.Header(WatermarkHeader, id)
+	}
+	setChecksum(c, stamped)
+	return stamped, true
+}
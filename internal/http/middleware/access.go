@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/config"
+	"tts/internal/privacy"
+)
+
+// clfTime 是 Common Log Format 使用的时间格式
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// accessFile 是访问日志文件，收到 SIGHUP 时重新打开，配合 logrotate 等工具轮转
+type accessFile struct {
+	mu   sync.Mutex
+	path string
+	out  io.Writer
+	file *os.File
+}
+
+var (
+	accessMu  sync.Mutex
+	accessOut *accessFile
+)
+
+// openAccessFile 打开访问日志，path 为 stdout 时写入标准输出
+func openAccessFile(path string) (*accessFile, error) {
+	if path == "stdout" {
+		return &accessFile{path: path, out: os.Stdout}, nil
+	}
+	f := &accessFile{path: path}
+	if err := f.reopen(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// reopen 重新打开日志文件，失败时继续写入原来的文件
+func (f *accessFile) reopen() error {
+	if f.path == "stdout" {
+		return nil
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开访问日志失败: %w", err)
+	}
+	f.mu.Lock()
+	old := f.file
+	f.file, f.out = file, file
+	f.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// write 写入一行日志
+func (f *accessFile) write(line string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := io.WriteString(f.out, line); err != nil {
+		log.Printf("写入访问日志失败: %v", err)
+	}
+}
+
+// ReopenAccessLog 重新打开访问日志文件，未启用访问日志时不做任何事
+func ReopenAccessLog() {
+	accessMu.Lock()
+	f := accessOut
+	accessMu.Unlock()
+	if f == nil {
+		return
+	}
+	if err := f.reopen(); err != nil {
+		log.Printf("%v，继续写入原文件", err)
+	}
+}
+
+// AccessLog 以 Common/Combined Log Format 记录每个请求，用户名字段为 keys 中密钥的名称。
+// 查询参数中的密钥被隐藏，隐私模式的请求只记录路径
+func AccessLog(cfg config.AccessLogConfig) gin.HandlerFunc {
+	f, err := openAccessFile(cfg.Path)
+	if err != nil {
+		log.Printf("%v，不记录访问日志", err)
+		return nil
+	}
+	accessMu.Lock()
+	accessOut = f
+	accessMu.Unlock()
+
+	combined := true
+	switch cfg.Format {
+	case "", "combined":
+	case "common":
+		combined = false
+	default:
+		log.Printf("logging.access.format 无效: %s，使用 combined", cfg.Format)
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		target := c.Request.URL.EscapedPath()
+		if c.Request.URL.RawQuery != "" && !privacy.Enabled(c.Request.Context()) {
+			target += "?" + maskQuery(c.Request.URL.Query())
+		}
+		user := "-"
+		if profile := apikey.FromContext(c); profile != nil && profile.Name != "" {
+			user = clfEscape(profile.Name)
+		}
+		size := "-"
+		if n := c.Writer.Size(); n > 0 {
+			size = strconv.Itoa(n)
+		}
+
+		var line strings.Builder
+		fmt.Fprintf(&line, "%s - %s [%s] \"%s %s %s\" %d %s",
+			c.ClientIP(), user, start.Format(clfTime),
+			clfEscape(c.Request.Method), clfEscape(target), clfEscape(c.Request.Proto),
+			c.Writer.Status(), size)
+		if combined {
+			fmt.Fprintf(&line, " \"%s\" \"%s\"", clfField(c.Request.Referer()), clfField(c.Request.UserAgent()))
+		}
+		if cfg.Duration {
+			fmt.Fprintf(&line, " %.3f", time.Since(start).Seconds())
+		}
+		line.WriteByte('\n')
+		f.write(line.String())
+	}
+}
+
+// clfField 返回引号内的字段，空值记录为 -
+func clfField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return clfEscape(value)
+}
+
+// clfEscape 按 Nginx 的方式转义引号、反斜杠与控制字符，防止伪造日志行
+func clfEscape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if ch == '"' || ch == '\\' || ch < 0x20 || ch == 0x7f {
+			fmt.Fprintf(&b, "\\x%02X", ch)
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
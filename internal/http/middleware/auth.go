@@ -1,9 +1,14 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/apperr"
+	"tts/internal/config"
 )
 
 // OpenAIAuth 中间件验证 OpenAI API 请求的令牌
@@ -15,23 +20,29 @@ func OpenAIAuth(apiToken string) gin.HandlerFunc {
 			return
 		}
 
+		// keys 中配置的密钥同样有效
+		if apikey.FromContext(c) != nil {
+			c.Next()
+			return
+		}
+
 		// 获取请求头中的 Authorization
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(401, gin.H{"error": "未提供授权令牌"})
+			apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, "未提供授权令牌"))
 			return
 		}
 
 		// 验证格式是否为 "Bearer {token}"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(401, gin.H{"error": "授权格式无效"})
+			apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, "授权格式无效"))
 			return
 		}
 
 		// 验证令牌是否正确
 		if parts[1] != apiToken {
-			c.AbortWithStatusJSON(401, gin.H{"error": "令牌无效"})
+			apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, "令牌无效"))
 			return
 		}
 
@@ -40,15 +51,57 @@ func OpenAIAuth(apiToken string) gin.HandlerFunc {
 	}
 }
 
+// AnyAuth 接受任意一种有效的密钥：tts.api_key、openai.api_key、keys 中配置的密钥或 Polly 的 SigV4 签名，
+// 用于与具体接口无关的功能，例如确认使用条款。都未配置时不验证
+func AnyAuth(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Polly 客户端使用 SigV4 签名，配置了访问密钥时必须通过签名校验，使条款确认记录在已验证的访问密钥下
+		if strings.HasPrefix(c.GetHeader("Authorization"), sigV4Algorithm+" ") && cfg.Polly.AccessKeyID != "" && cfg.Polly.SecretAccessKey != "" {
+			if msg := verifySigV4(c.Request, cfg.Polly.AccessKeyID, cfg.Polly.SecretAccessKey); msg != "" {
+				apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, msg))
+				return
+			}
+			c.Next()
+			return
+		}
+		if apikey.FromContext(c) != nil || (cfg.TTS.ApiKey == "" && cfg.OpenAI.ApiKey == "" && len(cfg.Keys) == 0) {
+			c.Next()
+			return
+		}
+		key := apikey.Presented(c)
+		if key == "" || (key != cfg.TTS.ApiKey && key != cfg.OpenAI.ApiKey) {
+			apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, "令牌无效"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// adminKey 标记通过了管理接口认证的请求
+const adminKey = "admin"
+
+// AdminAuth 验证管理接口的 Bearer 令牌。与其他接口不同，未配置令牌时拒绝所有请求
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 按常量时间比较，避免从响应耗时推测令牌
+		if token == "" || subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, "令牌无效"))
+			return
+		}
+		c.Set(adminKey, true)
+		c.Next()
+	}
+}
+
 // TTSAuth 是用于验证 TTS API 接口的中间件
 func TTSAuth(apiKey string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从查询参数中获取 api_key
 		queryKey := c.Query("api_key")
 
-		// 如果 apiKey 配置为空字符串，表示不需要验证
-		if apiKey != "" && queryKey != apiKey {
-			c.AbortWithStatusJSON(401, gin.H{"error": "未授权访问: 无效的 API 密钥"})
+		// 如果 apiKey 配置为空字符串，表示不需要验证；keys 中配置的密钥同样有效
+		if apiKey != "" && queryKey != apiKey && apikey.FromContext(c) == nil {
+			apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, "未授权访问: 无效的 API 密钥"))
 			return
 		}
 
@@ -56,3 +109,32 @@ func TTSAuth(apiKey string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// GoogleAuth 验证 Google Cloud TTS 兼容接口的 API 密钥，
+// 支持 key 查询参数、X-Goog-Api-Key 请求头与 Bearer 令牌，失败时返回 Google 风格的错误
+func GoogleAuth(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			c.Next()
+			return
+		}
+
+		key := c.Query("key")
+		if key == "" {
+			key = c.GetHeader("X-Goog-Api-Key")
+		}
+		if key == "" {
+			key = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		if key != apiKey && apikey.FromContext(c) == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": "Request had invalid authentication credentials.",
+				"status":  "UNAUTHENTICATED",
+			}})
+			return
+		}
+
+		c.Next()
+	}
+}
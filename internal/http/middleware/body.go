@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
+)
+
+// defaultMaxBodyMB 是未配置 server.max_body_mb 时请求体的大小上限（MB）
+const defaultMaxBodyMB = 64
+
+// RequestBody 限制请求体大小并解压 gzip、deflate 编码的请求体。
+// 上限作用于解压后的内容，声明的 Content-Length 已超过上限时直接返回 413，
+// 读取过程中超过上限时处理器收到的错误同样转换为 413
+func RequestBody(maxMB int) gin.HandlerFunc {
+	if maxMB <= 0 {
+		maxMB = defaultMaxBodyMB
+	}
+	limit := int64(maxMB) << 20
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			apperr.Abort(c, apperr.Newf(apperr.CodePayloadTooLarge, "请求体超过 %d 字节的上限", limit))
+			return
+		}
+
+		body := c.Request.Body
+		encoding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+		switch encoding {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			reader, err := gzip.NewReader(body)
+			if err != nil {
+				apperr.Abort(c, apperr.Wrap(apperr.CodeInvalidRequest, "无法解压 gzip 请求体", err))
+				return
+			}
+			body = readCloser{reader, body}
+		case "deflate":
+			body = readCloser{flate.NewReader(body), body}
+		default:
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "不支持的请求体编码: %s", encoding))
+			return
+		}
+		if encoding != "" && encoding != "identity" {
+			// 解压后长度未知，处理器按流读取
+			c.Request.Header.Del("Content-Encoding")
+			c.Request.Header.Del("Content-Length")
+			c.Request.ContentLength = -1
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, body, limit)
+		c.Next()
+	}
+}
+
+// readCloser 读取解压后的内容，关闭时同时关闭原始请求体
+type readCloser struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+// Close 关闭原始请求体
+func (r readCloser) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"log"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/config"
+	"tts/internal/privacy"
+	"tts/internal/sentry"
+)
+
+// Factory 根据配置创建中间件，返回 nil 表示该中间件不生效
+type Factory func(cfg *config.Config) gin.HandlerFunc
+
+// Definition 描述一个可插拔的中间件
+type Definition struct {
+	Name    string  // 名称，对应配置 middleware.enabled 中的键
+	Enabled bool    // 未在配置中指定时的默认启用状态
+	Factory Factory // 中间件构造函数
+}
+
+// Chain 是按顺序组合的中间件链，顺序由代码决定，启用状态由配置决定
+type Chain struct {
+	cfg         *config.Config
+	definitions []Definition
+}
+
+var (
+	extensionsMu sync.Mutex
+	extensions   []Definition
+)
+
+// Register 注册第三方中间件，它们会按注册顺序追加到全局链的末尾
+func Register(def Definition) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	extensions = append(extensions, def)
+}
+
+// NewChain 创建一个空的中间件链
+func NewChain(cfg *config.Config) *Chain {
+	return &Chain{cfg: cfg}
+}
+
+// Use 向链尾追加中间件
+func (c *Chain) Use(defs ...Definition) *Chain {
+	c.definitions = append(c.definitions, defs...)
+	return c
+}
+
+// enabled 判断中间件是否启用
+func (c *Chain) enabled(def Definition) bool {
+	if enabled, ok := c.cfg.Middleware.Enabled[def.Name]; ok {
+		return enabled
+	}
+	return def.Enabled
+}
+
+// Handlers 按顺序返回所有启用的中间件
+func (c *Chain) Handlers() []gin.HandlerFunc {
+	var handlers []gin.HandlerFunc
+	for _, def := range c.definitions {
+		if !c.enabled(def) {
+			log.Printf("中间件 %s 已禁用", def.Name)
+			continue
+		}
+		if h := def.Factory(c.cfg); h != nil {
+			handlers = append(handlers, h)
+		}
+	}
+	return handlers
+}
+
+// Then 返回链中的中间件并在末尾追加最终处理器，便于注册路由
+func (c *Chain) Then(handler gin.HandlerFunc) []gin.HandlerFunc {
+	return append(c.Handlers(), handler)
+}
+
+// Global 返回应用于所有路由的默认中间件链
+func Global(cfg *config.Config) *Chain {
+	chain := NewChain(cfg).Use(
+		Definition{Name: "request_id", Enabled: true, Factory: func(*config.Config) gin.HandlerFunc { return RequestID() }},
+		Definition{Name: "recovery", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+			return Recovery(cfg.TTS.ApiKey, cfg.OpenAI.ApiKey)
+		}},
+		Definition{Name: "logger", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc { return Logger(cfg) }},
+		Definition{Name: "access_log", Enabled: cfg.Logging.Access.Path != "", Factory: func(cfg *config.Config) gin.HandlerFunc {
+			return AccessLog(cfg.Logging.Access)
+		}},
+		Definition{Name: "metrics", Enabled: true, Factory: func(*config.Config) gin.HandlerFunc { return Metrics() }},
+		Definition{Name: "request_body", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+			return RequestBody(cfg.Server.MaxBodyMB)
+		}},
+		Definition{Name: "compression", Enabled: true, Factory: func(*config.Config) gin.HandlerFunc { return Compress() }},
+		Definition{Name: "cors", Enabled: true, Factory: func(*config.Config) gin.HandlerFunc { return CORS() }},
+		Definition{Name: "policy_headers", Enabled: len(cfg.Policy.Headers) > 0, Factory: func(cfg *config.Config) gin.HandlerFunc {
+			return PolicyHeaders(cfg.Policy.Headers)
+		}},
+		Definition{Name: "api_keys", Enabled: len(cfg.Keys) > 0, Factory: func(cfg *config.Config) gin.HandlerFunc {
+			return apikey.Identify(cfg)
+		}},
+		Definition{Name: "privacy", Enabled: privacy.AnyKey(cfg), Factory: func(cfg *config.Config) gin.HandlerFunc {
+			return privacy.Middleware(cfg)
+		}},
+		Definition{Name: "error_reporting", Enabled: cfg.Sentry.DSN != "", Factory: func(*config.Config) gin.HandlerFunc {
+			return sentry.Middleware()
+		}},
+		Definition{Name: "rate_limit", Enabled: cfg.Middleware.RateLimit.RequestsPerSecond > 0, Factory: func(cfg *config.Config) gin.HandlerFunc {
+			return RateLimit(cfg.Middleware.RateLimit)
+		}},
+	)
+
+	extensionsMu.Lock()
+	chain.Use(extensions...)
+	extensionsMu.Unlock()
+
+	return chain
+}
+
+// TTSAuthChain 返回 /tts 等接口使用的认证链
+func TTSAuthChain(cfg *config.Config) *Chain {
+	return NewChain(cfg).Use(Definition{Name: "auth", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+		return TTSAuth(cfg.TTS.ApiKey)
+	}})
+}
+
+// OpenAIAuthChain 返回 OpenAI 兼容接口使用的认证链
+func OpenAIAuthChain(cfg *config.Config) *Chain {
+	return NewChain(cfg).Use(Definition{Name: "auth", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+		return OpenAIAuth(cfg.OpenAI.ApiKey)
+	}})
+}
+
+// PollyAuthChain 返回 Polly 兼容接口使用的认证链
+func PollyAuthChain(cfg *config.Config) *Chain {
+	return NewChain(cfg).Use(Definition{Name: "auth", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+		return PollyAuth(cfg.Polly.AccessKeyID, cfg.Polly.SecretAccessKey)
+	}})
+}
+
+// GoogleAuthChain 返回 Google Cloud TTS 兼容接口使用的认证链
+func GoogleAuthChain(cfg *config.Config) *Chain {
+	return NewChain(cfg).Use(Definition{Name: "auth", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+		return GoogleAuth(cfg.Google.APIKey)
+	}})
+}
+
+// SlackAuthChain 返回 Slack 斜杠命令使用的认证链
+func SlackAuthChain(cfg *config.Config) *Chain {
+	return NewChain(cfg).Use(Definition{Name: "auth", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+		return SlackAuth(cfg.Slack.SigningSecret)
+	}})
+}
+
+// AdminAuthChain 返回管理接口使用的认证链，与其他接口的认证分开配置，
+// 避免关闭 auth 时管理接口随之开放
+func AdminAuthChain(cfg *config.Config) *Chain {
+	return NewChain(cfg).Use(Definition{Name: "admin_auth", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+		return AdminAuth(cfg.Admin.Token)
+	}})
+}
+
+// AnyAuthChain 返回接受任意有效密钥的认证链
+func AnyAuthChain(cfg *config.Config) *Chain {
+	return NewChain(cfg).Use(Definition{Name: "auth", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+		return AnyAuth(cfg)
+	}})
+}
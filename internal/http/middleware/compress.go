@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// minCompressSize 是压缩响应的最小字节数，更短的响应压缩后反而可能变大
+const minCompressSize = 1024
+
+// brotliLevel 是 brotli 的压缩级别，动态生成的响应使用中等级别，压缩率已明显高于 gzip 且耗时相近
+const brotliLevel = 5
+
+// encoder 是 gzip 与 brotli 压缩流的共同接口
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// encoders 按编码名称复用压缩流
+var encoders = map[string]*sync.Pool{
+	"br": {New: func() any {
+		return brotli.NewWriterLevel(nil, brotliLevel)
+	}},
+	"gzip": {New: func() any {
+		gz, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return gz
+	}},
+}
+
+// Compress 按 Accept-Encoding 用 brotli 或 gzip 压缩 JSON、字幕、XML 等文本响应，两者都接受时优先使用 brotli。
+// 音频等已压缩的内容、已设置 Content-Encoding 的响应与不足 1KB 的响应原样返回
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if c.Request.Method == "HEAD" || encoding == "" {
+			c.Next()
+			return
+		}
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+		c.Writer = w
+		// 结束后恢复原始的 ResponseWriter，gin 在中间件之后写出的 404 与 recovery 写出的错误响应不经过压缩
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// compressWriter 缓冲响应的前 1KB，据此与响应头决定是否压缩
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string // 协商得到的编码：br 或 gzip
+	decided  bool
+	buf      []byte
+	enc      encoder
+}
+
+// Write 未决定时先缓冲，缓冲满 1KB 后按响应头决定是否压缩
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	if !w.compressible() {
+		w.decided = true
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < minCompressSize {
+		return len(p), nil
+	}
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteString 实现 gin.ResponseWriter
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 在流式响应中立即写出已缓冲的内容
+func (w *compressWriter) Flush() {
+	if !w.decided && len(w.buf) > 0 {
+		if err := w.start(); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start 开始压缩并写出已缓冲的内容
+func (w *compressWriter) start() error {
+	w.decided = true
+	header := w.Header()
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	// 压缩后的内容与原内容字节不同，强 ETag 改为弱 ETag
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	w.enc = encoders[w.encoding].Get().(encoder)
+	w.enc.Reset(w.ResponseWriter)
+	buf := w.buf
+	w.buf = nil
+	_, err := w.enc.Write(buf)
+	return err
+}
+
+// finish 在请求结束时写出不足 1KB 的缓冲内容，或结束压缩流
+func (w *compressWriter) finish() {
+	if !w.decided && len(w.buf) > 0 {
+		w.decided = true
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		encoders[w.encoding].Put(w.enc)
+		w.enc = nil
+	}
+}
+
+// compressible 根据响应头判断内容是否值得压缩
+func (w *compressWriter) compressible() bool {
+	header := w.Header()
+	// 范围请求的偏移按未压缩的内容计算
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < minCompressSize {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-subrip", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// negotiateEncoding 按 Accept-Encoding 选择压缩编码：q 值最高者优先，相同时 br 优先于 gzip，
+// q=0 表示拒绝，* 匹配未单独列出的编码。都不接受时返回空字符串
+func negotiateEncoding(accept string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			q, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		}
+		qualities[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"br", "gzip"} {
+		q, ok := qualities[coding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
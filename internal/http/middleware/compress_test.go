@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, deflate, br", "br"},
+		{"gzip;q=1.0, br;q=0.5", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"GZIP;q=0.8", "gzip"},
+		{"gzip;q=0, br;q=0", ""},
+		{"*", "br"},
+		{"br;q=0, *", "gzip"},
+		{"*;q=0", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.accept); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := strings.Repeat(`{"name":"zh-CN-XiaoxiaoNeural","locale":"zh-CN"},`, 100)
+	r := gin.New()
+	r.Use(Compress())
+	r.GET("/json", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(body)) })
+	r.GET("/audio", func(c *gin.Context) { c.Data(http.StatusOK, "audio/mpeg", []byte(body)) })
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"":     func(r io.Reader) (io.Reader, error) { return r, nil },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+	tests := []struct {
+		path, accept, encoding string
+	}{
+		{"/json", "gzip, deflate, br", "br"},
+		{"/json", "gzip", "gzip"},
+		{"/json", "", ""},
+		{"/audio", "br", ""},
+	}
+	// 每种编码请求两次，确认从池中复用的压缩流已正确重置
+	for _, tt := range append(tests, tests...) {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s (%s): Content-Encoding = %q, want %q", tt.path, tt.accept, got, tt.encoding)
+			continue
+		}
+		reader, err := decoders[tt.encoding](w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil || string(data) != body {
+			t.Errorf("%s (%s): 解压后的内容不一致: %v", tt.path, tt.accept, err)
+		}
+	}
+}
@@ -1,28 +1,234 @@
 package middleware
 
 import (
+	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/config"
+	"tts/internal/privacy"
 )
 
-// Logger 是一个HTTP中间件，记录请求的详细信息
-func Logger() gin.HandlerFunc {
+// logLevel 是请求日志的级别，数值越大记录越详细
+type logLevel int
+
+const (
+	levelOff logLevel = iota
+	levelError
+	levelInfo
+	levelDebug
+)
+
+var logLevels = map[string]logLevel{
+	"off":   levelOff,
+	"error": levelError,
+	"info":  levelInfo,
+	"debug": levelDebug,
+}
+
+// defaultMaxBodyBytes 是 debug 级别默认记录的请求体字节数
+const defaultMaxBodyBytes = 1024
+
+// sensitiveParams 是记录查询参数时隐藏值的参数
+var sensitiveParams = []string{"api_key", "key", "token", "X-Amz-Signature", "X-Amz-Credential", "X-Amz-Security-Token"}
+
+// parseLevel 解析日志级别，为空时返回 fallback，无法识别时记录警告并返回 fallback
+func parseLevel(name string, fallback logLevel, field string) logLevel {
+	if name == "" {
+		return fallback
+	}
+	level, ok := logLevels[strings.ToLower(name)]
+	if !ok {
+		log.Printf("%s 的日志级别无效: %s，使用默认级别", field, name)
+		return fallback
+	}
+	return level
+}
+
+// endpointLevel 是解析后的接口日志级别
+type endpointLevel struct {
+	path   string
+	prefix bool
+	level  logLevel
+}
+
+// matches 判断路由路径是否匹配该规则
+func (e endpointLevel) matches(path string) bool {
+	if e.prefix {
+		return strings.HasPrefix(path, e.path)
+	}
+	return path == e.path
+}
+
+// keyLogging 是一个密钥单独设置的日志级别与是否记录请求体
+type keyLogging struct {
+	level    logLevel
+	hasLevel bool
+	body     bool
+}
+
+// bodyRecorder 在处理器读取请求体的同时保留开头的部分，不额外读取请求体
+type bodyRecorder struct {
+	io.ReadCloser
+	head  []byte
+	max   int
+	total int
+}
+
+func (r *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.total += n
+	if room := r.max - len(r.head); room > 0 {
+		r.head = append(r.head, p[:min(n, room)]...)
+	}
+	return n, err
+}
+
+// Logger 是一个HTTP中间件，记录请求的详细信息。
+// 日志级别按 密钥的 log_level、logging.endpoints、logging.level 的顺序确定
+func Logger(cfg *config.Config) gin.HandlerFunc {
+	lc := cfg.Logging
+	base := parseLevel(lc.Level, levelInfo, "logging.level")
+	debugging := base == levelDebug
+
+	endpoints := make([]endpointLevel, 0, len(lc.Endpoints))
+	for _, e := range lc.Endpoints {
+		rule := endpointLevel{path: e.Path, level: parseLevel(e.Level, base, "logging.endpoints "+e.Path)}
+		if strings.HasSuffix(rule.path, "*") {
+			rule.path, rule.prefix = strings.TrimSuffix(rule.path, "*"), true
+		}
+		endpoints = append(endpoints, rule)
+		debugging = debugging || rule.level == levelDebug
+	}
+
+	keys := make(map[string]keyLogging)
+	for _, key := range cfg.Keys {
+		settings := keyLogging{body: lc.Body}
+		if key.LogLevel != "" {
+			settings.level, settings.hasLevel = parseLevel(key.LogLevel, base, "keys "+key.Name+" log_level"), true
+			debugging = debugging || settings.level == levelDebug
+		}
+		if key.LogBody != nil {
+			settings.body = *key.LogBody
+		}
+		keys[key.Name] = settings
+	}
+
+	maxBody := lc.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = defaultMaxBodyBytes
+	}
+	basePath := strings.TrimSuffix(cfg.Server.BasePath, "/")
+	secrets := []string{cfg.TTS.ApiKey, cfg.OpenAI.ApiKey, cfg.Admin.Token, cfg.Overlay.Token}
+	for _, key := range cfg.Keys {
+		secrets = append(secrets, key.Key)
+	}
+
 	return func(c *gin.Context) {
 		start := time.Now()
 
+		// 只有可能使用 debug 级别时才记录请求体
+		var body *bodyRecorder
+		if debugging && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body = &bodyRecorder{ReadCloser: c.Request.Body, max: maxBody}
+			c.Request.Body = body
+		}
+
 		// 处理请求
 		c.Next()
 
+		// 确定日志级别：密钥 > 接口 > 默认
+		level := base
+		path := strings.TrimPrefix(c.FullPath(), basePath)
+		if path == "" {
+			path = strings.TrimPrefix(c.Request.URL.Path, basePath)
+		}
+		for _, e := range endpoints {
+			if e.matches(path) {
+				level = e.level
+				break
+			}
+		}
+		logBody := lc.Body
+		keyName := "-"
+		if profile := apikey.FromContext(c); profile != nil {
+			keyName = profile.Name
+			if settings, ok := keys[profile.Name]; ok {
+				if settings.hasLevel {
+					level = settings.level
+				}
+				logBody = settings.body
+			}
+		}
+
+		status := c.Writer.Status()
+		if level == levelOff || (level == levelError && status < http.StatusBadRequest) {
+			return
+		}
+
 		// 记录请求信息
 		duration := time.Since(start)
-		log.Printf("[%s] %s %s %d %s",
+		if level < levelDebug {
+			log.Printf("[%s] %s %s %d %s",
+				c.Request.Method,
+				c.Request.URL.Path,
+				c.ClientIP(),
+				status,
+				duration,
+			)
+			return
+		}
+
+		content := "-"
+		switch {
+		case privacy.Enabled(c.Request.Context()):
+			content = "[隐私模式]"
+		case logBody:
+			content = requestContent(c.Request.URL.Query(), body, secrets)
+		}
+		log.Printf("[%s] %s %s %d %s key=%s request_id=%s %s",
 			c.Request.Method,
 			c.Request.URL.Path,
 			c.ClientIP(),
-			c.Writer.Status(),
+			status,
 			duration,
+			keyName,
+			GetRequestID(c),
+			content,
 		)
 	}
 }
+
+// maskQuery 隐藏密钥类参数的值并返回编码后的查询参数
+func maskQuery(query url.Values) string {
+	for _, name := range sensitiveParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+		}
+	}
+	return query.Encode()
+}
+
+// requestContent 返回写入 debug 日志的查询参数与请求体，密钥类参数的值与出现在其中的已配置密钥替换为 REDACTED
+func requestContent(query url.Values, body *bodyRecorder, secrets []string) string {
+	content := "query=" + maskQuery(query)
+	if body != nil && body.total > 0 {
+		text := strings.ToValidUTF8(string(body.head), "")
+		if body.total > len(body.head) {
+			text += fmt.Sprintf("...(共 %d 字节)", body.total)
+		}
+		content += " body=" + fmt.Sprintf("%q", text)
+	}
+	for _, secret := range secrets {
+		if secret != "" {
+			content = strings.ReplaceAll(content, secret, "REDACTED")
+		}
+	}
+	return content
+}
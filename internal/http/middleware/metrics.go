@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/metrics"
+)
+
+var (
+	httpRequestsTotal = metrics.NewCounter("tts_http_requests_total",
+		"HTTP请求总数", "method", "path", "status")
+	httpRequestDuration = metrics.NewHistogram("tts_http_request_duration_seconds",
+		"HTTP请求耗时（秒）", nil, "path")
+)
+
+// Metrics 记录每个请求的计数与耗时，并保留最近的请求供管理页面查看
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// 使用路由模板而非实际路径，避免标签数量失控
+		path := c.FullPath()
+		if path == "" {
+			path = "unmatched"
+		}
+		httpRequestsTotal.Inc(c.Request.Method, path, strconv.Itoa(c.Writer.Status()))
+		httpRequestDuration.Observe(time.Since(start).Seconds(), path)
+		remember(c, start)
+	}
+}
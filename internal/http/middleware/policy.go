@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/policy"
+	"tts/internal/privacy"
+	"tts/internal/store"
+	"tts/internal/voiceprefs"
+)
+
+// PolicyHeaders 在所有响应中添加 policy.headers 中配置的使用政策响应头
+func PolicyHeaders(headers map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for name, value := range headers {
+			c.Header(name, value)
+		}
+		c.Next()
+	}
+}
+
+// TermsGate 返回要求请求方先确认当前版本使用条款的中间件定义，
+// 可追加到各接口的认证链之后
+func TermsGate(st *store.Store) Definition {
+	return Definition{Name: "terms", Enabled: true, Factory: func(cfg *config.Config) gin.HandlerFunc {
+		if !cfg.Policy.RequireAcceptance {
+			return nil
+		}
+		version := cfg.Policy.TermsVersion
+		return func(c *gin.Context) {
+			acceptance := policy.Accepted(st, policy.Identity(c))
+			if acceptance == nil || acceptance.Version != version {
+				apperr.Abort(c, apperr.Newf(apperr.CodeTermsNotAccepted, "请先确认使用条款 (版本 %s)", version))
+				return
+			}
+			c.Next()
+		}
+	}}
+}
+
+// VoiceUsage 返回在合成成功后把所用语音记录到请求方最近使用列表的中间件定义，
+// 隐私模式下不记录；可追加到各合成接口的认证链之后
+func VoiceUsage(st *store.Store) Definition {
+	return Definition{Name: "voice_usage", Enabled: true, Factory: func(*config.Config) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Next()
+			voice := c.GetString(voiceprefs.ContextKey)
+			if voice == "" || privacy.Enabled(c.Request.Context()) {
+				return
+			}
+			if err := voiceprefs.RecordUse(st, policy.Identity(c), voice); err != nil {
+				log.Printf("记录最近使用的语音失败: %v", err)
+			}
+		}
+	}}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
+	"tts/internal/config"
+)
+
+// bucket 是单个客户端的令牌桶
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter 按客户端维护令牌桶
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+// allow 判断客户端是否还有可用令牌
+func (l *rateLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	// 按经过的时间补充令牌
+	b.tokens += now.Sub(b.lastSeen).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// cleanup 清理长时间未活跃的客户端
+func (l *rateLimiter) cleanup(idle time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cutoff := time.Now().Add(-idle)
+	for key, b := range l.buckets {
+		if b.lastSeen.Before(cutoff) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit 按客户端IP进行令牌桶限流
+func RateLimit(cfg config.RateLimitConfig) gin.HandlerFunc {
+	if cfg.RequestsPerSecond <= 0 {
+		return nil
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = int(cfg.RequestsPerSecond)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+
+	limiter := &rateLimiter{
+		rate:    cfg.RequestsPerSecond,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+
+	// 定期清理过期的令牌桶，避免内存无限增长
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			limiter.cleanup(10 * time.Minute)
+		}
+	}()
+
+	return func(c *gin.Context) {
+		if !limiter.allow(c.ClientIP(), time.Now()) {
+			apperr.Abort(c, apperr.New(apperr.CodeRateLimited, "请求过于频繁，请稍后再试"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+)
+
+// recentSize 是保留的最近请求数
+const recentSize = 200
+
+// RequestRecord 是一条最近请求的摘要，不包含请求参数与正文
+type RequestRecord struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	DurationMs int64     `json:"duration_ms"`
+	Key        string    `json:"key,omitempty"` // keys 中密钥的名称
+	ClientIP   string    `json:"client_ip"`
+}
+
+var (
+	recentMu   sync.Mutex
+	recent     = make([]RequestRecord, recentSize)
+	recentNext int
+	recentLen  int
+)
+
+// remember 将请求加入最近请求的环形缓冲区，管理接口的请求不记录，避免管理页面的轮询挤掉业务请求
+func remember(c *gin.Context, start time.Time) {
+	if c.GetBool(adminKey) {
+		return
+	}
+	record := RequestRecord{
+		Time:       start,
+		RequestID:  c.GetString(requestIDKey),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Status:     c.Writer.Status(),
+		DurationMs: time.Since(start).Milliseconds(),
+		ClientIP:   c.ClientIP(),
+	}
+	if profile := apikey.FromContext(c); profile != nil {
+		record.Key = profile.Name
+	}
+
+	recentMu.Lock()
+	recent[recentNext] = record
+	recentNext = (recentNext + 1) % recentSize
+	if recentLen < recentSize {
+		recentLen++
+	}
+	recentMu.Unlock()
+}
+
+// RecentRequests 返回最近的请求，新的在前，limit 不大于 0 时返回全部保留的请求
+func RecentRequests(limit int) []RequestRecord {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	if limit <= 0 || limit > recentLen {
+		limit = recentLen
+	}
+	records := make([]RequestRecord, 0, limit)
+	for i := 1; i <= limit; i++ {
+		records = append(records, recent[(recentNext-i+recentSize)%recentSize])
+	}
+	return records
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"runtime/debug"
+	"strings"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apperr"
+	"tts/internal/sentry"
+	"tts/internal/utils"
+)
+
+// Recovery 捕获处理器中的 panic，记录堆栈并返回带请求ID的 500 响应。
+// secrets 中的值（如 API 密钥）会从日志中的 panic 信息里移除。开启 sentry.dsn 时同时上报到 Sentry。
+func Recovery(secrets ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			requestID := GetRequestID(c)
+			message := utils.SanitizeMessage(fmt.Sprint(recovered), secrets...)
+
+			// 客户端已断开连接时无法再写入响应
+			if isBrokenPipe(recovered) {
+				log.Printf("[%s] 客户端连接已断开: %s", requestID, message)
+				c.Abort()
+				return
+			}
+
+			log.Printf("[%s] 请求处理发生panic: %s %s: %s\n%s",
+				requestID, c.Request.Method, c.Request.URL.Path, message, debug.Stack())
+			sentry.CapturePanic(c.Request.Context(), message)
+
+			// 响应体中只包含通用信息和请求ID，不暴露任何内部细节
+			apperr.Abort(c, apperr.New(apperr.CodeInternal, "服务器内部错误"))
+		}()
+		c.Next()
+	}
+}
+
+// isBrokenPipe 判断 panic 是否由客户端断开连接引起
+func isBrokenPipe(recovered any) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if errors.As(opErr, &syscallErr) {
+		return errors.Is(syscallErr.Err, syscall.EPIPE) || errors.Is(syscallErr.Err, syscall.ECONNRESET)
+	}
+	msg := strings.ToLower(opErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader 是传递请求ID的请求/响应头
+	RequestIDHeader = "X-Request-ID"
+	// requestIDKey 是请求ID在 gin.Context 中的键
+	requestIDKey = "request_id"
+)
+
+// validRequestID 限制客户端传入的请求ID格式，防止日志注入
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID 为每个请求分配唯一ID，并写入响应头
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.New().String()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// GetRequestID 返回当前请求的ID，未分配时返回空字符串
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
@@ -1,23 +1,67 @@
 package routes
 
 import (
+	"log"
+	"strings"
+	"time"
+
+	"tts/internal/announce"
+	"tts/internal/cache"
 	"tts/internal/config"
+	"tts/internal/customvoice"
+	"tts/internal/dashboard"
+	"tts/internal/eval"
 	"tts/internal/http/handlers"
 	"tts/internal/http/middleware"
+	"tts/internal/jobs"
+	"tts/internal/metrics"
+	"tts/internal/overlay"
+	"tts/internal/readaloud"
+	"tts/internal/schedule"
+	"tts/internal/session"
+	"tts/internal/storage"
+	"tts/internal/store"
+	"tts/internal/templates"
 	"tts/internal/tts"
-	"tts/internal/tts/microsoft"
+	_ "tts/internal/tts/microsoft" // 注册 Microsoft TTS 服务
+	_ "tts/internal/tts/mock"      // 注册模拟服务
+	"tts/internal/warm"
+	ttspkg "tts/pkg/tts"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes 配置所有API路由
-func SetupRoutes(cfg *config.Config, ttsService tts.Service) (*gin.Engine, error) {
+func SetupRoutes(cfg *config.Config, ttsService tts.Service, st *store.Store, files *storage.Storage, scheduler *schedule.Scheduler, warmer *warm.Queue, jobManager *jobs.Manager) (*gin.Engine, error) {
 	// 创建Gin路由
 	router := gin.New()
 
 	// 创建处理器
-	ttsHandler := handlers.NewTTSHandler(ttsService, cfg)
-	voicesHandler := handlers.NewVoicesHandler(ttsService)
+	synthesizer := ttspkg.NewSynthesizer(ttsService, ttspkg.NewSegmenter(&cfg.TTS), cfg.TTS.MaxConcurrent)
+	textTemplates, err := templates.New(cfg.Templates)
+	if err != nil {
+		return nil, err
+	}
+	var sessions *session.Store
+	if cfg.Sessions.Enabled {
+		sessions = session.New(time.Duration(cfg.Sessions.TTL)*time.Minute, cfg.Sessions.MaxSessions)
+	}
+	ttsHandler := handlers.NewTTSHandler(synthesizer, textTemplates, sessions, cfg)
+	engines, err := engineSynthesizers(cfg, synthesizer)
+	if err != nil {
+		return nil, err
+	}
+	pollyHandler := handlers.NewPollyHandler(synthesizer, engines, cfg)
+	googleHandler := handlers.NewGoogleHandler(synthesizer, cfg)
+	audioCache := cache.Open(cfg.Cache.Dir, cfg.Cache.MaxEntries)
+	voicesHandler := handlers.NewVoicesHandler(ttsService, cfg, audioCache)
+	filesHandler := handlers.NewFilesHandler(files)
+	podcastHandler := handlers.NewPodcastHandler(st, files, cfg)
+	adminHandler := handlers.NewAdminHandler(scheduler, jobManager, audioCache, cfg)
+	termsHandler := handlers.NewTermsHandler(st, cfg)
+	providersHandler := handlers.NewProvidersHandler()
+	voicePrefsHandler := handlers.NewVoicePrefsHandler(st, ttsService)
+	warmHandler := handlers.NewWarmHandler(warmer, synthesizer, cfg)
 
 	// 创建页面处理器
 	pagesHandler, err := handlers.NewPagesHandler("./web/templates", cfg)
@@ -25,9 +69,8 @@ func SetupRoutes(cfg *config.Config, ttsService tts.Service) (*gin.Engine, error
 		return nil, err
 	}
 
-	// 应用中间件
-	router.Use(middleware.Logger()) // 日志中间件
-	router.Use(middleware.CORS())   // CORS中间件
+	// 应用全局中间件链，顺序在 middleware.Global 中定义，启用状态由配置控制
+	router.Use(middleware.Global(cfg).Handlers()...)
 
 	// 应用基础路径前缀
 	var baseRouter gin.IRoutes
@@ -43,28 +86,251 @@ func SetupRoutes(cfg *config.Config, ttsService tts.Service) (*gin.Engine, error
 	// 设置主页路由
 	baseRouter.GET("/", pagesHandler.HandleIndex)
 
+	// 使用条款的查询与确认，启用 policy.require_acceptance 后合成接口需要先确认条款
+	terms := middleware.TermsGate(st)
+	anyAuth := middleware.AnyAuthChain(cfg)
+	baseRouter.GET("/v1/terms", anyAuth.Then(termsHandler.HandleTerms)...)
+	baseRouter.POST("/v1/terms/accept", anyAuth.Then(termsHandler.HandleAccept)...)
+
+	// 上游服务状态，供运维看板与切换服务参考
+	baseRouter.GET("/v1/providers/status", anyAuth.Then(providersHandler.HandleStatus)...)
+
 	// 设置TTS API路由 - 添加认证中间件
+	// 合成成功后把所用语音记录到请求方的最近使用列表；多语音对比不计入
+	voiceUsage := middleware.VoiceUsage(st)
+	ttsAuth := middleware.TTSAuthChain(cfg).Use(terms, voiceUsage)
+	baseRouter.POST("/tts", ttsAuth.Then(ttsHandler.HandleTTS)...)
+	baseRouter.GET("/tts", ttsAuth.Then(ttsHandler.HandleTTS)...)
+	baseRouter.GET("/tts/marks", ttsAuth.Then(ttsHandler.HandleSpeechMarks)...)
+	baseRouter.POST("/tts/marks", ttsAuth.Then(ttsHandler.HandleSpeechMarks)...)
+	baseRouter.POST("/tts/compare", middleware.TTSAuthChain(cfg).Use(terms).Then(ttsHandler.HandleCompare)...)
+	baseRouter.POST("/tts/dialogue", ttsAuth.Then(ttsHandler.HandleDialogue)...)
+	baseRouter.POST("/tts/anki", ttsAuth.Then(ttsHandler.HandleAnki)...)
+	baseRouter.GET("/tts/templates", ttsAuth.Then(ttsHandler.HandleTemplates)...)
+	baseRouter.GET("/tts/templates/:name", ttsAuth.Then(ttsHandler.HandleTemplate)...)
+	baseRouter.POST("/tts/templates/:name", ttsAuth.Then(ttsHandler.HandleTemplate)...)
+	baseRouter.GET("/reader.json", ttsAuth.Then(ttsHandler.HandleReader)...)
+	baseRouter.GET("ifreetime.json", ttsAuth.Then(ttsHandler.HandleIFreeTime)...)
+
+	// 缓存预热：提前合成预计会被请求的内容，由后台队列逐条执行；导出：把指定内容的缓存打包，供离线设备导入
+	baseRouter.POST("/v1/cache/warm", ttsAuth.Then(warmHandler.HandleSubmit)...)
+	baseRouter.GET("/v1/cache/warm/:id", ttsAuth.Then(warmHandler.HandleStatus)...)
+	baseRouter.POST("/v1/cache/export", ttsAuth.Then(warmHandler.HandleExport)...)
 
-	baseRouter.POST("/tts", middleware.TTSAuth(cfg.TTS.ApiKey), ttsHandler.HandleTTS)
-	baseRouter.GET("/tts", middleware.TTSAuth(cfg.TTS.ApiKey), ttsHandler.HandleTTS)
-	baseRouter.GET("/reader.json", middleware.TTSAuth(cfg.TTS.ApiKey), ttsHandler.HandleReader)
-	baseRouter.GET("ifreetime.json", middleware.TTSAuth(cfg.TTS.ApiKey), ttsHandler.HandleIFreeTime)
+	// 异步合成任务：长文本提交后在后台按优先级合成，每个密钥只能查看与操作自己的任务
+	if jobManager != nil {
+		jobsHandler := handlers.NewJobsHandler(jobManager, cfg)
+		baseRouter.POST("/v1/jobs", ttsAuth.Then(jobsHandler.HandleSubmit)...)
+		baseRouter.GET("/v1/jobs", ttsAuth.Then(jobsHandler.HandleList)...)
+		baseRouter.GET("/v1/jobs/:id", ttsAuth.Then(jobsHandler.HandleGet)...)
+		baseRouter.DELETE("/v1/jobs/:id", ttsAuth.Then(jobsHandler.HandleCancel)...)
+		baseRouter.POST("/v1/jobs/:id/retry", ttsAuth.Then(jobsHandler.HandleRetry)...)
+	}
+
+	// 设置会话接口，会话中的语音与文本处理设置由后续请求沿用
+	if sessions != nil {
+		sessionsHandler := handlers.NewSessionsHandler(sessions)
+		baseRouter.POST("/v1/sessions", ttsAuth.Then(sessionsHandler.HandleCreate)...)
+		baseRouter.GET("/v1/sessions/:id", ttsAuth.Then(sessionsHandler.HandleGet)...)
+		baseRouter.DELETE("/v1/sessions/:id", ttsAuth.Then(sessionsHandler.HandleDelete)...)
+		// 实时会话：WebSocket 上陆续发送文本、按句推送音频，可以随时打断
+		baseRouter.GET("/v1/sessions/:id/live", ttsAuth.Then(ttsHandler.HandleLive)...)
+	}
+
+	// 浏览器扩展的朗读会话：按顺序提交页面文本分段，按分段获取音频，按位置跳转
+	if cfg.ReadAloud.Enabled {
+		store := readaloud.New(time.Duration(cfg.ReadAloud.TTL)*time.Minute, cfg.ReadAloud.MaxSessions, cfg.ReadAloud.MaxChunks)
+		readAloudHandler := handlers.NewReadAloudHandler(store, synthesizer, cfg)
+		baseRouter.POST("/v1/read", ttsAuth.Then(readAloudHandler.HandleCreate)...)
+		baseRouter.GET("/v1/read/:id", ttsAuth.Then(readAloudHandler.HandleGet)...)
+		baseRouter.DELETE("/v1/read/:id", ttsAuth.Then(readAloudHandler.HandleDelete)...)
+		baseRouter.POST("/v1/read/:id/chunks", ttsAuth.Then(readAloudHandler.HandleChunks)...)
+		baseRouter.GET("/v1/read/:id/seek", ttsAuth.Then(readAloudHandler.HandleSeek)...)
+		baseRouter.GET("/v1/read/:id/chunks/:index/audio", ttsAuth.Then(readAloudHandler.HandleAudio)...)
+	}
+
+	// 直播播报：聊天机器人提交消息，OBS 浏览器源页面通过 WebSocket 依次播放
+	if cfg.Overlay.Enabled {
+		overlayHandler := handlers.NewOverlayHandler(overlay.New(cfg, synthesizer))
+		baseRouter.POST("/v1/overlay/messages", ttsAuth.Then(overlayHandler.HandleSubmit)...)
+		baseRouter.GET("/v1/overlay/queue", ttsAuth.Then(overlayHandler.HandleState)...)
+		baseRouter.POST("/v1/overlay/skip", ttsAuth.Then(overlayHandler.HandleSkip)...)
+		baseRouter.DELETE("/v1/overlay/messages/:id", ttsAuth.Then(overlayHandler.HandleRemove)...)
+		baseRouter.GET("/v1/overlay/ws", overlayHandler.HandleWS)
+		baseRouter.GET("/v1/overlay/audio/:id", overlayHandler.HandleAudio)
+		baseRouter.GET("/overlay/*filepath", overlay.PageHandler())
+	}
+
+	// 电话系统的语音提示：Asterisk、FreeSWITCH 按扩展名识别格式，HEAD 与 GET 返回相同的响应头
+	if cfg.Telephony.Enabled {
+		telephonyHandler := handlers.NewTelephonyHandler(synthesizer, cfg)
+		baseRouter.GET("/v1/telephony/:file", ttsAuth.Then(telephonyHandler.HandlePrompt)...)
+		baseRouter.HEAD("/v1/telephony/:file", ttsAuth.Then(telephonyHandler.HandlePrompt)...)
+	}
+
+	// Slack 斜杠命令：请求由 Signing Secret 签名认证，不使用 TTS 接口的密钥
+	if cfg.Slack.Enabled {
+		slackHandler := handlers.NewSlackHandler(synthesizer, files, cfg)
+		baseRouter.POST("/v1/slack/command", middleware.SlackAuthChain(cfg).Then(slackHandler.HandleCommand)...)
+	}
 
 	// 设置语音列表API路由
 	baseRouter.GET("/voices", voicesHandler.HandleVoices)
+	baseRouter.GET("/v1/voices/:name/preview", ttsAuth.Then(voicesHandler.HandlePreview)...)
+
+	// 按密钥记录的最近使用与收藏语音
+	baseRouter.GET("/v1/voices/recent", anyAuth.Then(voicePrefsHandler.HandleRecent)...)
+	baseRouter.GET("/v1/voices/favorites", anyAuth.Then(voicePrefsHandler.HandleFavorites)...)
+	baseRouter.PUT("/v1/voices/favorites/:name", anyAuth.Then(voicePrefsHandler.HandleAddFavorite)...)
+	baseRouter.DELETE("/v1/voices/favorites/:name", anyAuth.Then(voicePrefsHandler.HandleRemoveFavorite)...)
+
+	// 语音克隆：自定义语音按密钥保存，之后以名称作为 voice 参数合成
+	if cfg.Cloning.Enabled {
+		cloningHandler := handlers.NewCloningHandler(ttsService, customvoice.New(st), cfg)
+		baseRouter.GET("/v1/voices/custom", ttsAuth.Then(cloningHandler.HandleList)...)
+		baseRouter.POST("/v1/voices/custom", ttsAuth.Then(cloningHandler.HandleCreate)...)
+		baseRouter.DELETE("/v1/voices/custom/:name", ttsAuth.Then(cloningHandler.HandleDelete)...)
+	}
+
+	// 语音质量评测：评测页面盲听两两比较，创建评测与查看报告的接口在管理接口中
+	var evalHandler *handlers.EvalHandler
+	if cfg.Eval.Enabled {
+		evalHandler = handlers.NewEvalHandler(eval.New(ttsService, st, files, cfg), cfg)
+		baseRouter.GET("/v1/eval/pair", evalHandler.HandlePair)
+		baseRouter.POST("/v1/eval/votes", evalHandler.HandleVote)
+		baseRouter.GET("/v1/eval/audio/:run/:sample", evalHandler.HandleAudio)
+		baseRouter.GET("/eval/*filepath", eval.PageHandler())
+	}
 
 	// 设置OpenAI兼容接口的处理器，添加验证中间件
-	openAIHandler := middleware.OpenAIAuth(cfg.OpenAI.ApiKey)
-	baseRouter.POST("/v1/audio/speech", openAIHandler, ttsHandler.HandleOpenAITTS)
-	baseRouter.POST("/audio/speech", openAIHandler, ttsHandler.HandleOpenAITTS)
+	openAIAuth := middleware.OpenAIAuthChain(cfg).Use(terms, voiceUsage)
+	baseRouter.POST("/v1/audio/speech", openAIAuth.Then(ttsHandler.HandleOpenAITTS)...)
+	baseRouter.POST("/audio/speech", openAIAuth.Then(ttsHandler.HandleOpenAITTS)...)
+
+	// 设置 Amazon Polly 兼容接口
+	baseRouter.POST("/v1/speech", middleware.PollyAuthChain(cfg).Use(terms, voiceUsage).Then(pollyHandler.HandleSynthesizeSpeech)...)
+
+	// 设置 Google Cloud TTS 兼容接口，gin 不支持路径中的冒号，由处理器校验 :synthesize
+	googleAuth := middleware.GoogleAuthChain(cfg).Use(terms, voiceUsage)
+	baseRouter.POST("/v1/text:action", googleAuth.Then(googleHandler.HandleText)...)
+	baseRouter.POST("/v1beta1/text:action", googleAuth.Then(googleHandler.HandleText)...)
+
+	// 提供 MQTT 播报保存的音频
+	if cfg.MQTT.Enabled && cfg.MQTT.OutputDir != "" {
+		baseRouter.Static(announce.AudioPath, cfg.MQTT.OutputDir)
+	}
+
+	// 提供文件存储中的音频与播客 RSS
+	baseRouter.GET(storage.PublicPath+"/*key", ttsAuth.Then(filesHandler.HandleFile)...)
+	// 按内容哈希访问的地址不可猜测且内容不变，不需要认证，便于 CDN 缓存
+	if cfg.Storage.ContentURLs {
+		baseRouter.GET(storage.ContentPath+"/:name", filesHandler.HandleContent)
+	}
+	if cfg.Podcast.Enabled {
+		baseRouter.GET("/podcast.xml", ttsAuth.Then(podcastHandler.HandleFeed)...)
+	}
+
+	// 设置管理接口，未配置 admin.token 时不开放
+	if cfg.Admin.Token != "" {
+		adminAuth := middleware.AdminAuthChain(cfg)
+		baseRouter.GET("/admin/jobs", adminAuth.Then(adminHandler.HandleJobs)...)
+		baseRouter.GET("/admin/jobs/history", adminAuth.Then(adminHandler.HandleJobHistory)...)
+		baseRouter.POST("/admin/jobs/:name/run", adminAuth.Then(adminHandler.HandleRunJob)...)
+		baseRouter.POST("/admin/watermark", adminAuth.Then(adminHandler.HandleWatermark)...)
+		baseRouter.GET("/admin/pools", adminAuth.Then(adminHandler.HandlePools)...)
+		baseRouter.PUT("/admin/pools/:name", adminAuth.Then(adminHandler.HandleResizePool)...)
+		baseRouter.GET("/admin/stats", adminAuth.Then(adminHandler.HandleStats)...)
+		baseRouter.GET("/admin/requests", adminAuth.Then(adminHandler.HandleRequests)...)
+		baseRouter.GET("/admin/async-jobs", adminAuth.Then(adminHandler.HandleSynthesisJobs)...)
+		baseRouter.GET("/admin/features", adminAuth.Then(adminHandler.HandleFeatures)...)
+		baseRouter.PUT("/admin/features/:name", adminAuth.Then(adminHandler.HandleSetFeature)...)
+		baseRouter.GET("/admin/cache/export", adminAuth.Then(adminHandler.HandleExportCache)...)
+		baseRouter.POST("/admin/cache/import", adminAuth.Then(adminHandler.HandleImportCache)...)
+		if evalHandler != nil {
+			baseRouter.POST("/admin/eval/runs", adminAuth.Then(evalHandler.HandleStart)...)
+			baseRouter.GET("/admin/eval/runs", adminAuth.Then(evalHandler.HandleRuns)...)
+			baseRouter.GET("/admin/eval/runs/:id/report", adminAuth.Then(evalHandler.HandleReport)...)
+		}
+		// 管理页面编译进二进制，页面中输入令牌后调用上面的接口
+		baseRouter.GET("/admin/ui/*filepath", dashboard.Handler())
+	}
+
+	// 设置指标导出路由：指标标签中有密钥名称，默认与管理接口一样需要 admin.token
+	switch {
+	case cfg.Metrics.Public:
+		baseRouter.GET("/metrics", gin.WrapH(metrics.Handler()))
+	case cfg.Admin.Token != "":
+		baseRouter.GET("/metrics", middleware.AdminAuthChain(cfg).Then(gin.WrapH(metrics.Handler()))...)
+	default:
+		log.Println("未配置 admin.token 且 metrics.public 为 false，不开放 /metrics")
+	}
 
 	return router, nil
 }
 
+// engineSynthesizers 按 polly.engines 为每个 Polly Engine 创建合成器，
+// 与 tts.provider 相同的服务直接复用主合成器
+func engineSynthesizers(cfg *config.Config, synthesizer *ttspkg.Synthesizer) (map[string]*ttspkg.Synthesizer, error) {
+	current := cfg.TTS.Provider
+	if current == "" {
+		current = tts.DefaultProvider
+	}
+	engines := make(map[string]*ttspkg.Synthesizer, len(cfg.Polly.Engines))
+	for engine, provider := range cfg.Polly.Engines {
+		if provider == "" || provider == current {
+			engines[strings.ToLower(engine)] = synthesizer
+			continue
+		}
+		service, err := tts.New(provider, cfg)
+		if err != nil {
+			return nil, err
+		}
+		engines[strings.ToLower(engine)] = ttspkg.NewSynthesizer(service, ttspkg.NewSegmenter(&cfg.TTS), cfg.TTS.MaxConcurrent)
+	}
+	return engines, nil
+}
+
 // InitializeServices 初始化所有服务
 func InitializeServices(cfg *config.Config) (tts.Service, error) {
-	// 创建Microsoft TTS客户端
-	ttsClient := microsoft.NewClient(cfg)
+	// 按配置创建TTS服务，默认使用 Microsoft
+	ttsService, err := tts.New(cfg.TTS.Provider, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// 调试模式：将请求同时发送到影子服务进行对比
+	if cfg.Shadow.Enabled {
+		shadowService, err := tts.NewShadowService(ttsService, cfg.TTS.Provider, cfg)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("已启用影子对比模式，影子服务: %s, 采样比例: %.2f", cfg.Shadow.Provider, cfg.Shadow.SampleRate)
+		ttsService = shadowService
+	}
+
+	// 按语言包展开数字、日期、单位与缩写
+	if cfg.Verbalize.Enabled {
+		verbalizeService, err := tts.NewVerbalizeService(ttsService, cfg)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("已启用语言包: %v", verbalizeService.Packs().Locales())
+		ttsService = verbalizeService
+	}
+
+	// 把本地配置的显示名称、标签与示例句子合并到语音列表
+	if len(cfg.TTS.Voices) > 0 {
+		ttsService = tts.NewCatalogService(ttsService, cfg.TTS.Voices)
+	}
+
+	// 合成前替换个人信息，放在最外层使影子服务也只收到替换后的文本
+	if cfg.Redact.Enabled {
+		redactService, err := tts.NewRedactService(ttsService, cfg.Redact)
+		if err != nil {
+			return nil, err
+		}
+		ttsService = redactService
+	}
 
-	return ttsClient, nil
+	return ttsService, nil
 }
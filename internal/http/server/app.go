@@ -8,14 +8,44 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	"tts/internal/announce"
 	"tts/internal/config"
+	"tts/internal/customvoice"
+	"tts/internal/discord"
+	"tts/internal/feature"
+	"tts/internal/http/middleware"
 	"tts/internal/http/routes"
+	"tts/internal/jobs"
+	"tts/internal/matrix"
+	"tts/internal/notify"
+	"tts/internal/podcast"
+	"tts/internal/privacy"
+	"tts/internal/schedule"
+	"tts/internal/sentry"
+	"tts/internal/storage"
+	"tts/internal/store"
+	"tts/internal/telegram"
+	"tts/internal/tts"
+	"tts/internal/warm"
+	"tts/internal/wyoming"
+	ttspkg "tts/pkg/tts"
 )
 
 // App 表示整个TTS应用程序
 type App struct {
-	server *Server
-	cfg    *config.Config
+	server     *Server
+	configPath string
+	cfg        *config.Config
+	ttsService tts.Service
+	store      *store.Store
+	files      *storage.Storage
+
+	synthesizer *ttspkg.Synthesizer
+	scheduler   *schedule.Scheduler
+	warmer      *warm.Queue
+	jobs        *jobs.Manager
+
+	quit chan os.Signal // 收到退出信号或调用 Stop 时开始优雅关闭
 }
 
 // NewApp 创建一个新的应用程序实例
@@ -26,14 +56,78 @@ func NewApp(configPath string) (*App, error) {
 		return nil, fmt.Errorf("加载配置失败: %w", err)
 	}
 
+	// 隐私模式需要在创建缓存与各入口之前生效
+	privacy.Configure(cfg)
+
+	// 功能开关
+	feature.Configure(cfg)
+
+	// 错误上报
+	if err := sentry.Configure(cfg.Sentry); err != nil {
+		return nil, err
+	}
+
 	// 初始化服务
 	ttsService, err := routes.InitializeServices(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("初始化服务失败: %w", err)
 	}
 
+	// 打开持久化存储
+	st, err := store.Open(cfg.Store.Path)
+	if err != nil {
+		return nil, fmt.Errorf("打开存储失败: %w", err)
+	}
+
+	// 自定义语音按密钥保存在存储中，合成时把名称替换为上游服务中的语音 ID
+	if cfg.Cloning.Enabled {
+		ttsService = tts.NewCustomVoiceService(ttsService, cfg.TTS.Provider, customvoice.New(st))
+	}
+
+	// 创建音频文件存储
+	files, err := storage.New(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	// 非 HTTP 入口与后台任务共用的合成器
+	synthesizer := ttspkg.NewSynthesizer(ttsService, ttspkg.NewSegmenter(&cfg.TTS), cfg.TTS.MaxConcurrent)
+
+	// 后台任务完成与失败的通知，未配置渠道时为 nil
+	notifier, err := notify.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("创建通知渠道失败: %w", err)
+	}
+
+	// 创建定时任务调度器
+	var scheduler *schedule.Scheduler
+	if len(cfg.Schedule.Jobs) > 0 {
+		if scheduler, err = schedule.New(synthesizer, st, files, notifier, cfg); err != nil {
+			return nil, fmt.Errorf("创建定时任务失败: %w", err)
+		}
+	}
+
+	// 缓存预热队列，预热结果写入合成结果缓存，未启用 cache.synthesis 时不创建
+	var warmer *warm.Queue
+	if cfg.Cache.Synthesis {
+		warmer = warm.New(synthesizer, notifier)
+	}
+
+	// 异步合成任务
+	var jobManager *jobs.Manager
+	if cfg.Jobs.Enabled {
+		if jobManager, err = jobs.New(synthesizer, files, notifier, cfg); err != nil {
+			return nil, fmt.Errorf("创建合成任务失败: %w", err)
+		}
+	}
+
+	// Slack 斜杠命令只通过签名认证
+	if cfg.Slack.Enabled && cfg.Slack.SigningSecret == "" {
+		return nil, fmt.Errorf("启用 Slack 斜杠命令需要配置 slack.signing_secret")
+	}
+
 	// 设置Gin路由
-	router, err := routes.SetupRoutes(cfg, ttsService)
+	router, err := routes.SetupRoutes(cfg, ttsService, st, files, scheduler, warmer, jobManager)
 	if err != nil {
 		return nil, fmt.Errorf("设置路由失败: %w", err)
 	}
@@ -42,23 +136,116 @@ func NewApp(configPath string) (*App, error) {
 	server := New(cfg, router)
 
 	return &App{
-		server: server,
-		cfg:    cfg,
+		server:     server,
+		configPath: configPath,
+		cfg:        cfg,
+		ttsService: ttsService,
+		store:      st,
+		files:      files,
+
+		synthesizer: synthesizer,
+		scheduler:   scheduler,
+		warmer:      warmer,
+		jobs:        jobManager,
+
+		quit: make(chan os.Signal, 1),
 	}, nil
 }
 
 // Start 启动应用程序
 func (a *App) Start() error {
+	// 后台任务随应用退出而停止
+	bgCtx, cancelBackground := context.WithCancel(context.Background())
+	defer cancelBackground()
+
+	// 预热并保持与上游服务的连接
+	if a.cfg.TTS.KeepAlive.Enabled {
+		tts.StartKeepAlive(bgCtx, a.ttsService, a.cfg.TTS.KeepAlive.IntervalDuration())
+	}
+
+	synthesizer := a.synthesizer
+
+	// 启动 Wyoming 协议服务
+	if a.cfg.Wyoming.Enabled {
+		address := a.cfg.Wyoming.Address
+		if address == "" {
+			address = ":10200"
+		}
+		go func() {
+			if err := wyoming.NewServer(synthesizer, a.cfg).ListenAndServe(bgCtx, address); err != nil {
+				log.Printf("Wyoming 服务出错: %v", err)
+			}
+		}()
+	}
+
+	// 启动 MQTT 语音播报
+	if a.cfg.MQTT.Enabled {
+		if err := announce.New(synthesizer, a.cfg).Start(bgCtx); err != nil {
+			return err
+		}
+	}
+
+	// 启动 Telegram 机器人
+	if a.cfg.Telegram.Enabled {
+		if a.cfg.Telegram.Token == "" {
+			return fmt.Errorf("启用 Telegram 机器人需要配置 telegram.token")
+		}
+		go telegram.New(synthesizer, a.store, a.cfg).Run(bgCtx)
+	}
+
+	// 启动 Discord 机器人
+	if a.cfg.Discord.Enabled {
+		if a.cfg.Discord.Token == "" {
+			return fmt.Errorf("启用 Discord 机器人需要配置 discord.token")
+		}
+		go discord.New(synthesizer, a.store, a.cfg).Run(bgCtx)
+	}
+
+	// 启动 Matrix 机器人
+	if a.cfg.Matrix.Enabled {
+		if a.cfg.Matrix.Homeserver == "" || a.cfg.Matrix.AccessToken == "" {
+			return fmt.Errorf("启用 Matrix 机器人需要配置 matrix.homeserver 与 matrix.access_token")
+		}
+		go matrix.New(synthesizer, a.store, a.cfg).Run(bgCtx)
+	}
+
+	// 启动 RSS 转播客
+	if a.cfg.Podcast.Enabled {
+		generator, err := podcast.New(synthesizer, a.store, a.files, a.cfg)
+		if err != nil {
+			return err
+		}
+		go generator.Run(bgCtx)
+	}
+
+	// 启动定时任务
+	if a.scheduler != nil {
+		a.scheduler.Run(bgCtx)
+	}
+
+	// 启动缓存预热队列
+	if a.warmer != nil {
+		go a.warmer.Run(bgCtx)
+	}
+
+	// 启动异步合成任务
+	if a.jobs != nil {
+		a.jobs.Run(bgCtx)
+	}
+
 	// 创建一个错误通道
 	errChan := make(chan error, 1)
 
-	// 创建一个退出信号通道
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	// 监听退出信号
+	signal.Notify(a.quit, syscall.SIGINT, syscall.SIGTERM)
+
+	// 收到 SIGHUP 时重新打开访问日志并重新加载 SSML 处理器
+	go a.reloadOnHangup(bgCtx)
+	// 远程配置变化时同样重新加载
+	go config.WatchRemote(bgCtx, a.cfg.Remote, a.reload)
 
 	// 在一个goroutine中启动服务器
 	go func() {
-		log.Printf("启动TTS服务，监听端口 %d...\n", a.cfg.Server.Port)
 		errChan <- a.server.Start()
 	}()
 
@@ -66,7 +253,7 @@ func (a *App) Start() error {
 	select {
 	case err := <-errChan:
 		return err
-	case <-quit:
+	case <-a.quit:
 		// 创建一个超时上下文用于优雅关闭
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -80,3 +267,45 @@ func (a *App) Start() error {
 		return nil
 	}
 }
+
+// Stop 使 Start 优雅关闭服务器后返回，用于没有退出信号的运行方式（如 Windows 服务）
+func (a *App) Stop() {
+	select {
+	case a.quit <- syscall.SIGTERM:
+	default:
+	}
+}
+
+// reloadOnHangup 在收到 SIGHUP 时重新打开访问日志文件并重新加载配置
+func (a *App) reloadOnHangup(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+		middleware.ReopenAccessLog()
+		a.reload()
+	}
+}
+
+// reload 重新读取配置文件与远程配置，替换功能开关、ssml.preserve_tags、ssml.urls 与 keys 中各密钥的 ssml 设置。
+// 其他配置项需要重启才能生效；新配置无效时保留原有设置
+func (a *App) reload() {
+	cfg, err := config.Reread(a.configPath)
+	if err != nil {
+		log.Printf("重新加载配置失败: %v", err)
+		return
+	}
+	feature.Configure(cfg)
+	version, err := config.ReloadProcessors(cfg)
+	if err != nil {
+		log.Printf("重新加载 SSML 处理器失败，继续使用原有设置: %v", err)
+		return
+	}
+	log.Printf("已重新加载 SSML 处理器，版本 %d", version)
+}
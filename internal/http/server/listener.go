@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"tts/internal/config"
+)
+
+const (
+	// unixPrefix 是 server.listen 中 unix 域套接字地址的前缀
+	unixPrefix = "unix:"
+	// listenSystemd 表示使用 systemd 套接字激活传入的监听套接字
+	listenSystemd = "systemd"
+	// systemdFirstFD 是 systemd 传入的第一个文件描述符，见 sd_listen_fds(3)
+	systemdFirstFD = 3
+)
+
+// listen 按 server.listen 创建监听：为空时监听 TCP 端口 server.port，
+// unix:/path 监听 unix 域套接字，systemd 使用 systemd 套接字激活传入的套接字
+func listen(cfg config.ServerConfig) (net.Listener, string, error) {
+	switch {
+	case cfg.Listen == "":
+		addr := fmt.Sprintf(":%d", cfg.Port)
+		l, err := net.Listen("tcp", addr)
+		return l, "端口 " + strconv.Itoa(cfg.Port), err
+	case cfg.Listen == listenSystemd:
+		l, err := systemdListener()
+		return l, "systemd 传入的套接字", err
+	case strings.HasPrefix(cfg.Listen, unixPrefix):
+		path := strings.TrimPrefix(cfg.Listen, unixPrefix)
+		l, err := unixListener(path, cfg.SocketMode)
+		return l, "套接字 " + path, err
+	}
+	return nil, "", fmt.Errorf("server.listen 无效: %q，应为空、unix:/path 或 systemd", cfg.Listen)
+}
+
+// unixListener 监听 unix 域套接字，先删除上次运行遗留的套接字文件，mode 非空时按八进制设置文件权限
+func unixListener(path, mode string) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("server.listen 缺少套接字路径")
+	}
+	var perm os.FileMode
+	if mode != "" {
+		parsed, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("server.socket_mode 无效: %q", mode)
+		}
+		perm = os.FileMode(parsed)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s 已存在且不是套接字", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("删除遗留的套接字失败: %w", err)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("设置套接字权限失败: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// systemdListener 返回 systemd 套接字激活传入的第一个套接字，并清除相关环境变量，避免子进程误用
+func systemdListener() (net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, fmt.Errorf("没有 systemd 传入的套接字 (LISTEN_PID 与当前进程不符)")
+	}
+	if fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS")); err != nil || fds < 1 {
+		return nil, fmt.Errorf("没有 systemd 传入的套接字 (LISTEN_FDS=%q)", os.Getenv("LISTEN_FDS"))
+	}
+	file := os.NewFile(systemdFirstFD, "systemd-socket")
+	defer file.Close()
+	l, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("使用 systemd 传入的套接字失败: %w", err)
+	}
+	return l, nil
+}
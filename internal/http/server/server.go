@@ -3,6 +3,8 @@ package server
 import (
 	"context"
 	"fmt"
+	"log"
+
 	"github.com/gin-gonic/gin"
 	"tts/internal/config"
 )
@@ -11,7 +13,7 @@ import (
 type Server struct {
 	router   *gin.Engine
 	basePath string
-	port     int
+	cfg      config.ServerConfig
 }
 
 // New 创建新的HTTP服务器
@@ -19,14 +21,18 @@ func New(cfg *config.Config, router *gin.Engine) *Server {
 	return &Server{
 		router:   router,
 		basePath: cfg.Server.BasePath,
-		port:     cfg.Server.Port,
+		cfg:      cfg.Server,
 	}
 }
 
-// Start 启动HTTP服务器
+// Start 按 server.listen 创建监听并启动HTTP服务器
 func (s *Server) Start() error {
-	addr := fmt.Sprintf(":%d", s.port)
-	return s.router.Run(addr)
+	l, addr, err := listen(s.cfg)
+	if err != nil {
+		return fmt.Errorf("创建监听失败: %w", err)
+	}
+	log.Printf("启动TTS服务，监听%s...\n", addr)
+	return s.router.RunListener(l)
 }
 
 // Shutdown 优雅关闭服务器
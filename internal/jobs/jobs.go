@@ -0,0 +1,618 @@
+// Package jobs 实现异步合成任务：提交长文本（如有声书）后立即返回任务ID，由后台工作协程按优先级合成，
+// 音频保存到文件存储。任务记录、文本与已合成的片段（检查点）保存在任务目录中，
+// 服务重启或重试后未完成的任务从最后完成的片段继续。多个实例共享任务目录时通过租约保证每个任务只由一个实例执行。
+package jobs
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"tts/internal/apikey"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/notify"
+	"tts/internal/privacy"
+	"tts/internal/storage"
+	"tts/internal/utils"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
+)
+
+// 任务状态
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+)
+
+// 优先级范围，数值大的先执行
+const (
+	MinPriority = -10
+	MaxPriority = 10
+)
+
+const (
+	defaultMaxQueued     = 100
+	defaultMaxTextLength = 1000000
+	defaultKeepHours     = 168
+	defaultLeaseSeconds  = 60
+	defaultPollSeconds   = 5
+)
+
+// Job 是一个异步合成任务
+type Job struct {
+	ID         string     `json:"id"`
+	Tenant     string     `json:"tenant,omitempty"` // 提交方的密钥名称
+	Status     string     `json:"status"`
+	Priority   int        `json:"priority"`
+	Voice      string     `json:"voice"`
+	Rate       string     `json:"rate,omitempty"`
+	Pitch      string     `json:"pitch,omitempty"`
+	Style      string     `json:"style,omitempty"`
+	URLMode    string     `json:"url_mode,omitempty"`
+	Quotes     string     `json:"quotes,omitempty"`
+	TextLength int        `json:"text_length"`
+	Segments   int        `json:"segments,omitempty"`           // 文本切分的片段数，开始执行后确定
+	Completed  int        `json:"completed_segments,omitempty"` // 已合成并保存的片段数
+	Attempts   int        `json:"attempts"`                     // 开始执行的次数，重试与重启恢复后增加
+	Worker     string     `json:"worker,omitempty"`             // 执行任务的实例
+	Error      string     `json:"error,omitempty"`
+	File       string     `json:"file,omitempty"` // 音频在文件存储中的路径
+	URL        string     `json:"url,omitempty"`
+	Size       int        `json:"size,omitempty"`
+	SHA256     string     `json:"sha256,omitempty"`
+	Created    time.Time  `json:"created"`
+	Started    *time.Time `json:"started,omitempty"`
+	Finished   *time.Time `json:"finished,omitempty"`
+}
+
+// Done 判断任务是否已结束
+func (j *Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCanceled
+}
+
+// Manager 管理任务的排队、执行、取消与重试
+type Manager struct {
+	synthesizer   *ttspkg.Synthesizer
+	files         *storage.Storage
+	notifier      *notify.Notifier
+	config        *config.Config
+	dir           string
+	workers       int
+	maxQueued     int
+	maxTextLength int
+	keep          time.Duration
+
+	// 多实例共享任务目录时的协调设置
+	shared   bool
+	instance string        // 本实例的名称，写入任务记录与租约
+	lease    time.Duration // 租约有效期，执行中的任务每隔三分之一有效期续约一次
+	poll     time.Duration // 扫描其他实例提交与租约过期任务的间隔
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	pending queue
+	seq     uint64
+	cancels map[string]context.CancelFunc // 执行中任务的取消函数
+	leases  map[string]int                // 本实例执行中任务持有的租约代数
+	wake    chan struct{}
+}
+
+// New 创建任务管理器并载入任务目录中的任务记录，需要调用 Run 开始执行。单实例时排队中与执行中的任务重新排队；
+// 多实例共享任务目录时执行中的任务由租约过期后的扫描回收
+func New(synthesizer *ttspkg.Synthesizer, files *storage.Storage, notifier *notify.Notifier, cfg *config.Config) (*Manager, error) {
+	jc := cfg.Jobs
+	m := &Manager{
+		synthesizer:   synthesizer,
+		files:         files,
+		notifier:      notifier,
+		config:        cfg,
+		dir:           jc.Dir,
+		workers:       max(jc.Workers, 1),
+		maxQueued:     jc.MaxQueued,
+		maxTextLength: jc.MaxTextLength,
+		keep:          time.Duration(jc.KeepHours) * time.Hour,
+		shared:        jc.Shared,
+		instance:      instanceName(),
+		lease:         time.Duration(jc.LeaseSeconds) * time.Second,
+		poll:          time.Duration(jc.PollSeconds) * time.Second,
+		jobs:          make(map[string]*Job),
+		cancels:       make(map[string]context.CancelFunc),
+		leases:        make(map[string]int),
+		wake:          make(chan struct{}, 1),
+	}
+	if m.dir == "" {
+		m.dir = "./data/jobs"
+	}
+	if m.maxQueued <= 0 {
+		m.maxQueued = defaultMaxQueued
+	}
+	if m.maxTextLength <= 0 {
+		m.maxTextLength = defaultMaxTextLength
+	}
+	if m.keep <= 0 {
+		m.keep = defaultKeepHours * time.Hour
+	}
+	if m.lease <= 0 {
+		m.lease = defaultLeaseSeconds * time.Second
+	}
+	if m.poll <= 0 {
+		m.poll = defaultPollSeconds * time.Second
+	}
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建任务目录失败: %w", err)
+	}
+
+	var requeued []*Job
+	for _, job := range m.loadAll() {
+		m.jobs[job.ID] = job
+		if job.Status == StatusQueued || (job.Status == StatusRunning && !m.shared) {
+			requeued = append(requeued, job)
+		}
+	}
+	// 按提交时间重新排队，保持相同优先级任务的先后顺序
+	sort.Slice(requeued, func(i, j int) bool { return requeued[i].Created.Before(requeued[j].Created) })
+	for _, job := range requeued {
+		if job.Status == StatusRunning {
+			job.Status, job.Started, job.Worker = StatusQueued, nil, ""
+			m.save(job)
+		}
+		m.enqueue(job)
+	}
+	if len(requeued) > 0 {
+		log.Printf("重新排队 %d 个未完成的合成任务", len(requeued))
+	}
+	return m, nil
+}
+
+// Submit 提交任务，tenant 为提交方的密钥名称，合成时沿用其 SSML、水印与隐私设置
+func (m *Manager) Submit(tenant string, req models.TTSRequest, priority int) (Job, error) {
+	if priority < MinPriority || priority > MaxPriority {
+		return Job{}, apperr.Newf(apperr.CodeInvalidRequest, "priority 需要在 %d 到 %d 之间", MinPriority, MaxPriority)
+	}
+	length := utils.GraphemeCount(req.Text)
+	if length > m.maxTextLength {
+		return Job{}, apperr.Newf(apperr.CodeTextTooLong, "文本长度 %d 超过任务上限 %d", length, m.maxTextLength)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	if m.pending.Len() >= m.maxQueued {
+		return Job{}, apperr.New(apperr.CodeRateLimited, "排队的任务过多，请稍后再试")
+	}
+	job := &Job{
+		ID:         uuid.NewString(),
+		Tenant:     tenant,
+		Status:     StatusQueued,
+		Priority:   priority,
+		Voice:      req.Voice,
+		Rate:       req.Rate,
+		Pitch:      req.Pitch,
+		Style:      req.Style,
+		URLMode:    req.URLMode,
+		Quotes:     req.Quotes,
+		TextLength: length,
+		Created:    time.Now().UTC(),
+	}
+	if err := os.WriteFile(m.textPath(job.ID), []byte(req.Text), 0600); err != nil {
+		return Job{}, apperr.Wrap(apperr.CodeInternal, "保存任务文本失败", err)
+	}
+	m.jobs[job.ID] = job
+	m.save(job)
+	m.enqueue(job)
+	return *job, nil
+}
+
+// Get 返回任务，任务不存在或不属于 tenant 时返回 CodeNotFound
+func (m *Manager) Get(tenant, id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sync(id)
+	job, err := m.lookup(tenant, id)
+	if err != nil {
+		return Job{}, err
+	}
+	return *job, nil
+}
+
+// List 返回 tenant 提交的任务，按提交时间从新到旧排列。status 为空时不按状态筛选，limit 为 0 时不限制数量
+func (m *Manager) List(tenant, status string, limit int) []Job {
+	return m.filter(func(job *Job) bool { return job.Tenant == tenant }, status, limit)
+}
+
+// ListAll 返回所有密钥提交的任务，供管理接口使用，参数与 List 相同
+func (m *Manager) ListAll(status string, limit int) []Job {
+	return m.filter(func(*Job) bool { return true }, status, limit)
+}
+
+// Counts 返回各状态的任务数
+func (m *Manager) Counts() map[string]int {
+	if m.shared {
+		m.scan()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := map[string]int{StatusQueued: 0, StatusRunning: 0, StatusSucceeded: 0, StatusFailed: 0, StatusCanceled: 0}
+	for _, job := range m.jobs {
+		counts[job.Status]++
+	}
+	return counts
+}
+
+// filter 返回 match 选中且状态为 status 的任务，按提交时间从新到旧排列
+func (m *Manager) filter(match func(*Job) bool, status string, limit int) []Job {
+	if m.shared {
+		m.scan()
+	}
+	m.mu.Lock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if match(job) && (status == "" || job.Status == status) {
+			jobs = append(jobs, *job)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.After(jobs[j].Created) })
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs
+}
+
+// Cancel 取消排队中或执行中的任务，执行中的合成随之中止；已结束的任务返回 CodeConflict。
+// 任务在其他实例上执行时，由该实例在下次续约时发现并中止
+func (m *Manager) Cancel(tenant, id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sync(id)
+	job, err := m.lookup(tenant, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.Done() {
+		return Job{}, apperr.Newf(apperr.CodeConflict, "任务已结束: %s", job.Status)
+	}
+	m.pending.remove(id)
+	// 只通知执行中的合成中止，取消函数由 execute 在本次执行退出后移除，Retry 据此判断是否仍在执行
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+	}
+	now := time.Now().UTC()
+	job.Status, job.Finished = StatusCanceled, &now
+	m.save(job)
+	log.Printf("合成任务 %s 已取消", id)
+	return *job, nil
+}
+
+// Retry 重新排队失败或已取消的任务，沿用原来的文本与参数，已保存的片段不会重新合成。
+// 已取消但本实例上的执行还没有退出时返回 CodeConflict，避免两次执行同时写入检查点目录
+func (m *Manager) Retry(tenant, id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sync(id)
+	job, err := m.lookup(tenant, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.Status != StatusFailed && job.Status != StatusCanceled {
+		return Job{}, apperr.Newf(apperr.CodeConflict, "只能重试失败或已取消的任务，当前状态: %s", job.Status)
+	}
+	if _, running := m.cancels[id]; running {
+		return Job{}, apperr.New(apperr.CodeConflict, "任务仍在停止中，请稍后重试")
+	}
+	if _, err := os.Stat(m.textPath(id)); err != nil {
+		return Job{}, apperr.New(apperr.CodeConflict, "任务文本已删除，无法重试")
+	}
+	if m.pending.Len() >= m.maxQueued {
+		return Job{}, apperr.New(apperr.CodeRateLimited, "排队的任务过多，请稍后再试")
+	}
+	job.Status, job.Error, job.Worker = StatusQueued, "", ""
+	job.Started, job.Finished = nil, nil
+	m.save(job)
+	m.enqueue(job)
+	return *job, nil
+}
+
+// Run 启动工作协程执行任务，直到 ctx 结束。服务退出时执行中的任务保持原状态，
+// 单实例时下次启动重新排队，多实例时租约过期后由其他实例回收
+func (m *Manager) Run(ctx context.Context) {
+	log.Printf("合成任务已启动，实例: %s, 工作协程数: %d, 排队中: %d", m.instance, m.workers, m.pending.Len())
+	if m.shared {
+		go m.coordinate(ctx)
+	}
+	for i := 0; i < m.workers; i++ {
+		go m.work(ctx)
+	}
+}
+
+// work 逐个取出优先级最高的任务执行
+func (m *Manager) work(ctx context.Context) {
+	for {
+		job, jobCtx := m.next(ctx)
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.wake:
+			}
+			continue
+		}
+		m.execute(ctx, jobCtx, job)
+	}
+}
+
+// next 取出优先级最高的任务并标记为执行中，返回任务副本与可被 Cancel 取消的上下文；没有任务时返回 nil。
+// 多实例时先获取租约，已被其他实例取走或取消的任务跳过
+func (m *Manager) next(ctx context.Context) (*Job, context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var job *Job
+	for job == nil {
+		if ctx.Err() != nil || m.pending.Len() == 0 {
+			return nil, nil
+		}
+		e := heap.Pop(&m.pending).(entry)
+		m.sync(e.id)
+		candidate := m.jobs[e.id]
+		if candidate == nil || candidate.Status != StatusQueued {
+			continue
+		}
+		if m.shared {
+			gen, ok := m.acquire(e.id)
+			if !ok {
+				continue
+			}
+			m.leases[e.id] = gen
+		}
+		job = candidate
+	}
+	// 还有排队的任务时唤醒其他空闲的工作协程
+	if m.pending.Len() > 0 {
+		m.signal()
+	}
+	now := time.Now().UTC()
+	job.Status, job.Started, job.Worker = StatusRunning, &now, m.instance
+	job.Attempts++
+	m.save(job)
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	m.cancels[job.ID] = cancel
+	snapshot := *job
+	return &snapshot, jobCtx
+}
+
+// execute 合成任务文本并保存音频
+func (m *Manager) execute(ctx, jobCtx context.Context, job *Job) {
+	profile := apikey.ByName(m.config, job.Tenant)
+	private := privacy.ForKey(m.config, profile)
+	jobCtx = config.WithTenant(jobCtx, job.Tenant)
+	jobCtx = privacy.NewContext(jobCtx, private)
+
+	result, err := m.synthesize(jobCtx, job, profile)
+	// 服务退出导致的中断不记录结果，下次启动时重新排队
+	if ctx.Err() != nil {
+		return
+	}
+
+	m.mu.Lock()
+	if cancel, ok := m.cancels[job.ID]; ok {
+		cancel()
+		delete(m.cancels, job.ID)
+	}
+	if m.shared {
+		gen := m.leases[job.ID]
+		delete(m.leases, job.ID)
+		if !m.owns(job.ID, gen) {
+			// 租约已被其他实例回收，由新的执行者记录结果
+			m.mu.Unlock()
+			log.Printf("合成任务 %s 的租约已被回收，放弃本次执行结果", job.ID)
+			return
+		}
+		defer m.release(job.ID)
+		m.sync(job.ID)
+	}
+	current := m.jobs[job.ID]
+	if current == nil || current.Done() {
+		// 执行期间已被取消
+		m.mu.Unlock()
+		if result != nil {
+			m.files.Delete(result.File)
+		}
+		return
+	}
+	now := time.Now().UTC()
+	current.Finished = &now
+	if err != nil {
+		current.Status, current.Error = StatusFailed, apperr.From(err).Message
+	} else {
+		current.Status = StatusSucceeded
+		current.File, current.URL, current.Size, current.SHA256 = result.File, result.URL, result.Size, result.SHA256
+	}
+	m.save(current)
+	finished := *current
+	m.mu.Unlock()
+
+	if finished.Status == StatusSucceeded {
+		log.Printf("合成任务 %s 完成, 文本长度 %d, 音频大小 %s, 耗时 %v", finished.ID, finished.TextLength,
+			utils.FormatFileSize(finished.Size), finished.Finished.Sub(*finished.Started).Round(time.Millisecond))
+		// 隐私模式下完成后不保留文本
+		if private {
+			os.Remove(m.textPath(finished.ID))
+		}
+	} else {
+		log.Printf("合成任务 %s 失败: %v", finished.ID, err)
+	}
+	m.notify(finished)
+}
+
+// synthesize 读取任务文本，逐批合成各片段并保存到检查点目录，全部完成后合并并保存音频，
+// 返回记录了音频位置的任务副本。重启或重试时已保存的片段直接复用
+func (m *Manager) synthesize(ctx context.Context, job *Job, profile *config.APIKey) (*Job, error) {
+	text, err := os.ReadFile(m.textPath(job.ID))
+	if err != nil {
+		return nil, fmt.Errorf("读取任务文本失败: %w", err)
+	}
+	req := models.TTSRequest{
+		Voice:   job.Voice,
+		Rate:    job.Rate,
+		Pitch:   job.Pitch,
+		Style:   job.Style,
+		URLMode: job.URLMode,
+		Quotes:  job.Quotes,
+	}
+	segments := m.synthesizer.Segmenter().Plan(string(text), req.Rate, ttspkg.LocaleOf(req.Voice))
+	dir := m.partsDir(job.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建检查点目录失败: %w", err)
+	}
+
+	parts := make([]string, len(segments))
+	var missing []int
+	for i, segment := range segments {
+		parts[i] = filepath.Join(dir, partName(req, segment))
+		if _, err := os.Stat(parts[i]); err != nil {
+			missing = append(missing, i)
+		}
+	}
+	if resumed := len(segments) - len(missing); resumed > 0 && len(missing) > 0 {
+		log.Printf("合成任务 %s 从检查点恢复: 已完成 %d/%d 段", job.ID, resumed, len(segments))
+	}
+	m.progress(job.ID, len(segments), len(segments)-len(missing))
+
+	// 每批的片段并发合成，整批完成后保存，中断时最多重新合成一批
+	batch := max(m.config.TTS.MaxConcurrent, 1)
+	for start := 0; start < len(missing); start += batch {
+		indexes := missing[start:min(start+batch, len(missing))]
+		texts := make([]string, len(indexes))
+		for i, index := range indexes {
+			texts[i] = segments[index]
+		}
+		audio, _, err := m.synthesizer.SynthesizeSegments(ctx, req, texts)
+		if err != nil {
+			return nil, err
+		}
+		for i, index := range indexes {
+			if err := m.writeFile(parts[index], audio[i]); err != nil {
+				return nil, fmt.Errorf("保存检查点失败: %w", err)
+			}
+		}
+		m.progress(job.ID, len(segments), len(segments)-len(missing)+start+len(indexes))
+	}
+
+	audioParts := make([][]byte, len(parts))
+	for i, part := range parts {
+		if audioParts[i], err = os.ReadFile(part); err != nil {
+			return nil, fmt.Errorf("读取检查点失败: %w", err)
+		}
+	}
+	audio := audioParts[0]
+	if len(audioParts) > 1 {
+		if audio, err = ttspkg.MergeAudio(audioParts); err != nil {
+			return nil, apperr.Wrap(apperr.CodeInternal, "音频合并失败", err)
+		}
+	}
+	if watermark.Enabled(m.config, profile) {
+		if audio, _, err = watermark.Apply(audio, m.config.Watermark, job.Tenant); err != nil {
+			return nil, fmt.Errorf("添加水印失败: %w", err)
+		}
+	}
+
+	result := &Job{File: "jobs/" + job.ID + ".mp3", Size: len(audio), SHA256: storage.Checksum(audio)}
+	if err := m.files.Put(result.File, audio); err != nil {
+		return nil, fmt.Errorf("保存音频失败: %w", err)
+	}
+	result.URL = m.files.URL(result.File)
+	if m.config.Storage.ContentURLs {
+		result.URL = m.files.ContentURL(result.File, result.SHA256)
+	}
+	os.RemoveAll(dir)
+	return result, nil
+}
+
+// progress 记录任务的片段进度
+func (m *Manager) progress(id string, segments, completed int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sync(id)
+	job := m.jobs[id]
+	if job == nil || job.Done() {
+		// 已被取消（多实例时可能由其他实例取消）
+		if cancel, ok := m.cancels[id]; ok {
+			cancel()
+		}
+		return
+	}
+	job.Segments, job.Completed = segments, completed
+	m.save(job)
+}
+
+// notify 按提交方密钥的设置发送任务完成或失败的通知
+func (m *Manager) notify(job Job) {
+	event := notify.Event{
+		Kind:     "job",
+		Name:     job.ID,
+		Status:   notify.StatusSuccess,
+		Summary:  fmt.Sprintf("文本长度 %d, 音频大小 %s", job.TextLength, utils.FormatFileSize(job.Size)),
+		Error:    job.Error,
+		Started:  *job.Started,
+		Finished: *job.Finished,
+	}
+	if job.Status == StatusFailed {
+		event.Status, event.Summary = notify.StatusFailed, fmt.Sprintf("文本长度 %d", job.TextLength)
+	}
+	if strings.HasPrefix(job.URL, "http") {
+		event.URL = job.URL
+	}
+	m.notifier.Notify(m.notifier.Tenant(job.Tenant), event)
+}
+
+// lookup 查找属于 tenant 的任务，调用方需持有锁
+func (m *Manager) lookup(tenant, id string) (*Job, error) {
+	job, ok := m.jobs[id]
+	if !ok || job.Tenant != tenant {
+		return nil, apperr.Newf(apperr.CodeNotFound, "任务不存在: %s", id)
+	}
+	return job, nil
+}
+
+// enqueue 把任务加入队列并唤醒工作协程，调用方需持有锁（New 中除外）
+func (m *Manager) enqueue(job *Job) {
+	m.seq++
+	heap.Push(&m.pending, entry{id: job.ID, priority: job.Priority, seq: m.seq})
+	m.signal()
+}
+
+// signal 唤醒一个空闲的工作协程
+func (m *Manager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// prune 删除结束超过保留时长的任务记录、文本、检查点与音频，调用方需持有锁
+func (m *Manager) prune() {
+	for id, job := range m.jobs {
+		if !job.Done() || job.Finished == nil || time.Since(*job.Finished) < m.keep {
+			continue
+		}
+		delete(m.jobs, id)
+		m.remove(id)
+		if job.File != "" {
+			m.files.Delete(job.File)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/storage"
+	ttspkg "tts/pkg/tts"
+)
+
+// slowProvider 第一次合成时通知 started，等到 release 关闭才返回（模拟取消后迟迟不退出的上游请求），之后立即返回
+type slowProvider struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *slowProvider) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return nil, nil
+}
+
+func (p *slowProvider) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	if p.calls.Add(1) == 1 {
+		close(p.started)
+		<-p.release
+		return nil, ctx.Err()
+	}
+	return &models.TTSResponse{AudioContent: []byte{0xFF, 0xF3}, ContentType: "audio/mpeg"}, nil
+}
+
+func TestRetryWaitsForCanceledRun(t *testing.T) {
+	provider := &slowProvider{started: make(chan struct{}), release: make(chan struct{})}
+	synthesizer := ttspkg.NewSynthesizer(provider, ttspkg.NewSegmenter(&ttspkg.TTSConfig{SegmentThreshold: 100}), 1)
+	files, err := storage.New(config.StorageConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(synthesizer, files, nil, &config.Config{Jobs: config.JobsConfig{Dir: t.TempDir()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Run(ctx)
+
+	job, err := m.Submit("", models.TTSRequest{Text: "你好。", Voice: "zh-CN-XiaoxiaoNeural"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-provider.started
+	if _, err := m.Cancel("", job.ID); err != nil {
+		t.Fatal(err)
+	}
+	// 被取消的执行还没有退出，重试会与它同时写入检查点目录
+	if _, err := m.Retry("", job.ID); apperr.From(err).Code != apperr.CodeConflict {
+		t.Fatalf("执行退出前重试: %v, want conflict", err)
+	}
+
+	close(provider.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = m.Retry("", job.ID); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("执行退出后仍无法重试: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 重试的执行不受被取消的执行影响，正常完成
+	for {
+		job, err = m.Get("", job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Done() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("重试的任务没有结束: %s", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job.Status != StatusSucceeded || job.Attempts != 2 {
+		t.Errorf("重试后状态 = %s (%s), 执行次数 = %d", job.Status, job.Error, job.Attempts)
+	}
+}
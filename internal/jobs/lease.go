@@ -0,0 +1,173 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// leaseInfix 连接任务ID与租约代数，租约文件名如 {id}.lease.3
+const leaseInfix = ".lease."
+
+// instanceName 返回本实例的名称：主机名加随机后缀，同一主机上的多个实例互不相同
+func instanceName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "tts"
+	}
+	return host + "-" + uuid.NewString()[:8]
+}
+
+// acquire 获取任务的租约，成功时返回租约代数；已有未过期的租约时返回 false。
+// 每次获取都以独占方式创建更高一代的租约文件，多个实例同时回收过期租约时只有一个能成功
+func (m *Manager) acquire(id string) (int, bool) {
+	gen, renewed := m.latestLease(id)
+	if gen > 0 && time.Since(renewed) < m.lease {
+		return 0, false
+	}
+	f, err := os.OpenFile(m.leasePath(id, gen+1), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, false
+	}
+	fmt.Fprintln(f, m.instance)
+	f.Close()
+	if gen > 0 {
+		os.Remove(m.leasePath(id, gen))
+	}
+	return gen + 1, true
+}
+
+// owns 判断本实例是否仍持有任务第 gen 代的租约
+func (m *Manager) owns(id string, gen int) bool {
+	latest, _ := m.latestLease(id)
+	return gen > 0 && latest == gen
+}
+
+// renew 续约，租约已被其他实例回收时返回 false
+func (m *Manager) renew(id string, gen int) bool {
+	if !m.owns(id, gen) {
+		return false
+	}
+	now := time.Now()
+	return os.Chtimes(m.leasePath(id, gen), now, now) == nil
+}
+
+// expired 判断执行中任务的租约是否已过期，没有租约时视为过期
+func (m *Manager) expired(id string) bool {
+	gen, renewed := m.latestLease(id)
+	return gen == 0 || time.Since(renewed) >= m.lease
+}
+
+// release 删除任务的所有租约
+func (m *Manager) release(id string) {
+	paths, _ := filepath.Glob(filepath.Join(m.dir, id+leaseInfix+"*"))
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+// latestLease 返回任务最新一代租约的代数与最后续约时间，没有租约时代数为 0
+func (m *Manager) latestLease(id string) (int, time.Time) {
+	paths, _ := filepath.Glob(filepath.Join(m.dir, id+leaseInfix+"*"))
+	var (
+		latest  int
+		renewed time.Time
+	)
+	for _, path := range paths {
+		gen, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), id+leaseInfix))
+		if err != nil || gen <= latest {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		latest, renewed = gen, info.ModTime()
+	}
+	return latest, renewed
+}
+
+// leasePath 返回任务第 gen 代租约的文件路径
+func (m *Manager) leasePath(id string, gen int) string {
+	return filepath.Join(m.dir, id+leaseInfix+strconv.Itoa(gen))
+}
+
+// coordinate 在多实例共享任务目录时定期续约本实例执行中的任务，并扫描其他实例提交的任务与租约过期的任务
+func (m *Manager) coordinate(ctx context.Context) {
+	heartbeat := time.NewTicker(m.lease / 3)
+	defer heartbeat.Stop()
+	poll := time.NewTicker(m.poll)
+	defer poll.Stop()
+	m.scan()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			m.heartbeat()
+		case <-poll.C:
+			m.scan()
+		}
+	}
+}
+
+// heartbeat 续约本实例执行中的任务。租约已被回收或任务已被其他实例取消时中止执行
+func (m *Manager) heartbeat() {
+	m.mu.Lock()
+	leases := maps.Clone(m.leases)
+	m.mu.Unlock()
+	for id, gen := range leases {
+		lost := !m.renew(id, gen)
+		job, err := m.load(id)
+		canceled := err == nil && job.Status == StatusCanceled
+		if !lost && !canceled {
+			continue
+		}
+		if lost {
+			log.Printf("合成任务 %s 的租约已被其他实例回收，中止执行", id)
+		}
+		m.mu.Lock()
+		if cancel, ok := m.cancels[id]; ok {
+			cancel()
+		}
+		m.mu.Unlock()
+	}
+}
+
+// scan 读取任务目录：排队中的任务加入本实例的队列，租约过期的执行中任务重新排队，已被删除的任务移出缓存
+func (m *Manager) scan() {
+	records := m.loadAll()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := make(map[string]bool, len(records))
+	for _, job := range records {
+		seen[job.ID] = true
+		m.jobs[job.ID] = job
+		if _, running := m.cancels[job.ID]; running {
+			continue
+		}
+		switch {
+		case job.Status == StatusQueued && !m.pending.contains(job.ID):
+			m.enqueue(job)
+		case job.Status == StatusRunning && m.expired(job.ID):
+			log.Printf("合成任务 %s 的租约已过期（实例 %s），重新排队", job.ID, job.Worker)
+			job.Status, job.Started, job.Worker = StatusQueued, nil, ""
+			m.save(job)
+			m.enqueue(job)
+		}
+	}
+	for id := range m.jobs {
+		if !seen[id] {
+			delete(m.jobs, id)
+			m.pending.remove(id)
+		}
+	}
+}
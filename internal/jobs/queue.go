@@ -0,0 +1,54 @@
+package jobs
+
+import "container/heap"
+
+// entry 是排队中的任务，优先级高的先执行，相同优先级按提交顺序执行
+type entry struct {
+	id       string
+	priority int
+	seq      uint64
+}
+
+// queue 是按优先级排列的待执行任务，实现 heap.Interface
+type queue []entry
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x any) { *q = append(*q, x.(entry)) }
+
+func (q *queue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// remove 从队列中删除任务，任务不在队列中时返回 false
+func (q *queue) remove(id string) bool {
+	for i, e := range *q {
+		if e.id == id {
+			heap.Remove(q, i)
+			return true
+		}
+	}
+	return false
+}
+
+// contains 判断任务是否在队列中
+func (q queue) contains(id string) bool {
+	for _, e := range q {
+		if e.id == id {
+			return true
+		}
+	}
+	return false
+}
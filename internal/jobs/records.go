@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tts/internal/models"
+)
+
+// recordExt 是任务记录文件的扩展名
+const recordExt = ".json"
+
+// save 保存任务记录，调用方需持有锁
+func (m *Manager) save(job *Job) {
+	data, err := json.Marshal(job)
+	if err == nil {
+		err = m.writeFile(m.recordPath(job.ID), data)
+	}
+	if err != nil {
+		log.Printf("保存任务记录失败: %v", err)
+	}
+}
+
+// load 读取任务记录
+func (m *Manager) load(id string) (*Job, error) {
+	data, err := os.ReadFile(m.recordPath(id))
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// loadAll 读取任务目录中的所有任务记录，无法解析的记录跳过
+func (m *Manager) loadAll() []*Job {
+	paths, _ := filepath.Glob(filepath.Join(m.dir, "*"+recordExt))
+	jobs := make([]*Job, 0, len(paths))
+	for _, path := range paths {
+		job, err := m.load(strings.TrimSuffix(filepath.Base(path), recordExt))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("读取任务记录 %s 失败: %v", path, err)
+			}
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// sync 多实例共享任务目录时从磁盘重新读取任务记录，其他实例的提交、取消与执行结果以磁盘为准，调用方需持有锁
+func (m *Manager) sync(id string) {
+	if !m.shared {
+		return
+	}
+	job, err := m.load(id)
+	if errors.Is(err, os.ErrNotExist) {
+		delete(m.jobs, id)
+		return
+	}
+	if err != nil {
+		return
+	}
+	m.jobs[id] = job
+}
+
+// remove 删除任务的记录、文本、检查点与租约
+func (m *Manager) remove(id string) {
+	for _, path := range []string{m.recordPath(id), m.textPath(id)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("删除任务文件失败: %v", err)
+		}
+	}
+	os.RemoveAll(m.partsDir(id))
+	m.release(id)
+}
+
+// recordPath 返回任务记录的保存路径
+func (m *Manager) recordPath(id string) string {
+	return filepath.Join(m.dir, id+recordExt)
+}
+
+// textPath 返回任务文本的保存路径
+func (m *Manager) textPath(id string) string {
+	return filepath.Join(m.dir, id+".txt")
+}
+
+// partsDir 返回任务已合成片段的检查点目录
+func (m *Manager) partsDir(id string) string {
+	return filepath.Join(m.dir, id+".parts")
+}
+
+// partName 返回片段检查点的文件名。文件名由片段文本与语音参数决定，
+// 分段配置或参数变化后不会误用旧的片段
+func partName(req models.TTSRequest, segment string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{req.Voice, req.Rate, req.Pitch, req.Style, req.URLMode, req.Quotes, segment}, "\x00")))
+	return hex.EncodeToString(sum[:12]) + ".mp3"
+}
+
+// writeFile 先写临时文件再重命名，中途退出不会留下不完整的文件；
+// 临时文件名带有实例名称，多个实例共享任务目录时互不覆盖
+func (m *Manager) writeFile(path string, data []byte) error {
+	tmp := path + "." + m.instance + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
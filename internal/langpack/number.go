@@ -0,0 +1,230 @@
+package langpack
+
+import (
+	"strconv"
+	"strings"
+)
+
+// numberPattern 匹配整数、小数与带千位分隔符的数字
+const numberPattern = `\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?`
+
+// defaultMaxDigits 是未配置 max_digits 时按数值朗读的最大位数
+const defaultMaxDigits = 12
+
+// Cardinal 返回数字的读法，支持千位分隔符与小数。
+// 以 0 开头或位数过多的整数（如编号、账号）逐位朗读。
+func (p *Pack) Cardinal(s string) string {
+	s = strings.ReplaceAll(s, ",", "")
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+
+	maxDigits := p.Number.MaxDigits
+	if maxDigits <= 0 {
+		maxDigits = defaultMaxDigits
+	}
+	var words string
+	if len(intPart) <= maxDigits && (len(intPart) == 1 || intPart[0] != '0') {
+		if n, err := strconv.ParseUint(intPart, 10, 64); err == nil {
+			words = p.integer(n)
+		}
+	}
+	if words == "" {
+		words = p.Digits(intPart)
+	}
+	if hasFrac {
+		words = p.join(words, p.Number.Decimal, p.Digits(frac))
+	}
+	return words
+}
+
+// Digits 逐位朗读数字，小数点读作 number.decimal，其他字符忽略
+func (p *Pack) Digits(s string) string {
+	var words []string
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			words = append(words, p.Number.Digits[c-'0'])
+		case c == '.':
+			words = append(words, p.Number.Decimal)
+		}
+	}
+	return p.join(words...)
+}
+
+// Phone 逐位朗读电话号码，使用 number.phone 中的读法，其他字符忽略
+func (p *Pack) Phone(s string) string {
+	digits := p.Number.Phone
+	if len(digits) != 10 {
+		digits = p.Number.Digits
+	}
+	var words []string
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= '0' && c <= '9' {
+			words = append(words, digits[c-'0'])
+		}
+	}
+	return p.join(words...)
+}
+
+// Clock 朗读时间中的分、秒：两位数中以 0 开头的逐位朗读，如 05 读作 零五
+func (p *Pack) Clock(s string) string {
+	if len(s) == 2 && s[0] == '0' {
+		return p.Digits(s)
+	}
+	return p.Cardinal(s)
+}
+
+// Year 返回年份的读法：中文逐位朗读，英文按两位一组朗读（如 nineteen ninety-nine）
+func (p *Pack) Year(s string) string {
+	if p.Number.System == "chinese" {
+		return p.Digits(s)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || len(s) != 4 {
+		return p.Cardinal(s)
+	}
+	hi, lo := n/100, n%100
+	if lo < 10 || (n >= 2000 && n < 2010) {
+		return p.Cardinal(s)
+	}
+	return p.join(p.integer(uint64(hi)), p.integer(uint64(lo)))
+}
+
+// Month 返回月份名称，没有配置 months 或不是 1-12 时按数字朗读
+func (p *Pack) Month(s string) string {
+	if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= 12 && len(p.Months) == 12 {
+		return p.Months[n-1]
+	}
+	return p.Cardinal(s)
+}
+
+// integer 按 number.system 朗读整数，超出 large_units 范围时返回空字符串
+func (p *Pack) integer(n uint64) string {
+	if n == 0 {
+		return p.Number.Digits[0]
+	}
+	if p.Number.System == "chinese" {
+		return p.chinese(n)
+	}
+	return p.western(n)
+}
+
+// chinese 按万进位朗读整数，如 10305 读作 一万零三百零五
+func (p *Pack) chinese(n uint64) string {
+	num := p.Number
+	var groups []int
+	for ; n > 0; n /= 10000 {
+		groups = append(groups, int(n%10000))
+	}
+	if len(groups) > max(len(num.LargeUnits), 1) {
+		return ""
+	}
+
+	var sb strings.Builder
+	zero := false
+	for i := len(groups) - 1; i >= 0; i-- {
+		g := groups[i]
+		if g == 0 {
+			zero = sb.Len() > 0
+			continue
+		}
+		// 中间的空位读一个零，如 一万零五、一亿零五十万
+		if zero || (sb.Len() > 0 && g < 1000) {
+			sb.WriteString(num.Digits[0])
+		}
+		zero = false
+		if g == 2 && i > 0 && num.Two != "" {
+			sb.WriteString(num.Two) // 两万、两亿
+		} else {
+			sb.WriteString(p.chineseSection(g, sb.Len() == 0))
+		}
+		if i < len(num.LargeUnits) {
+			sb.WriteString(num.LargeUnits[i])
+		}
+	}
+	return sb.String()
+}
+
+// chineseSection 朗读万以内的一组数字，最高位的 10-19 省略“一”，读作 十二 而不是 一十二
+func (p *Pack) chineseSection(g int, top bool) string {
+	num := p.Number
+	var sb strings.Builder
+	pendingZero, started := false, false
+	for pos, div := 3, 1000; pos >= 0; pos, div = pos-1, div/10 {
+		d := g / div % 10
+		if d == 0 {
+			pendingZero = started
+			continue
+		}
+		if pendingZero {
+			sb.WriteString(num.Digits[0])
+			pendingZero = false
+		}
+		switch {
+		case top && !started && pos == 1 && d == 1:
+		case pos == 3 && d == 2 && num.Two != "":
+			sb.WriteString(num.Two) // 两千
+		default:
+			sb.WriteString(num.Digits[d])
+		}
+		sb.WriteString(num.SmallUnits[pos])
+		started = true
+	}
+	return sb.String()
+}
+
+// western 按千进位朗读整数，如 2024 读作 two thousand twenty-four
+func (p *Pack) western(n uint64) string {
+	var groups []int
+	for ; n > 0; n /= 1000 {
+		groups = append(groups, int(n%1000))
+	}
+	if len(groups) > max(len(p.Number.LargeUnits), 1) {
+		return ""
+	}
+
+	var words []string
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i] == 0 {
+			continue
+		}
+		words = append(words, p.westernHundreds(groups[i]))
+		if i < len(p.Number.LargeUnits) {
+			words = append(words, p.Number.LargeUnits[i])
+		}
+	}
+	return p.join(words...)
+}
+
+// westernHundreds 朗读千以内的一组数字
+func (p *Pack) westernHundreds(g int) string {
+	num := p.Number
+	var words []string
+	if g >= 100 {
+		words = append(words, num.Digits[g/100], num.Hundred)
+		g %= 100
+	}
+	switch {
+	case g >= 20:
+		word := num.Tens[g/10]
+		if g%10 != 0 {
+			word += num.TensJoin + num.Digits[g%10]
+		}
+		words = append(words, word)
+	case g >= 10:
+		words = append(words, num.Teens[g-10])
+	case g > 0:
+		words = append(words, num.Digits[g])
+	}
+	return p.join(words...)
+}
+
+// join 用语言包的分隔符连接非空的词
+func (p *Pack) join(words ...string) string {
+	nonEmpty := words[:0:0]
+	for _, w := range words {
+		if w != "" {
+			nonEmpty = append(nonEmpty, w)
+		}
+	}
+	return strings.Join(nonEmpty, p.Separator)
+}
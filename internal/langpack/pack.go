@@ -0,0 +1,293 @@
+// Package langpack 加载按语言组织的朗读规则（数字、日期、单位与缩写的展开方式），
+// 语言包是 YAML 或 JSON 数据文件，新增语言无需重新编译。内置 zh-CN、zh-TW 与 en-US 三个语言包。
+package langpack
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed packs/*.yaml
+var builtinPacks embed.FS
+
+// Pack 是一种语言的朗读规则
+type Pack struct {
+	Locale        string            `yaml:"locale" json:"locale"`               // 区域，如 zh-CN
+	Aliases       []string          `yaml:"aliases" json:"aliases"`             // 同样使用该语言包的其他区域，如 zh-SG
+	Separator     string            `yaml:"separator" json:"separator"`         // 词之间的分隔符，中文为空，英文为空格
+	Script        string            `yaml:"script" json:"script"`               // 中文字形：simplified 或 traditional，合成前把文本转换为该字形
+	Number        Number            `yaml:"number" json:"number"`               // 数字读法
+	Months        []string          `yaml:"months" json:"months"`               // 12 个月份名称，供 {n:month} 使用
+	Units         map[string]string `yaml:"units" json:"units"`                 // 数字后的单位，值中的 {n} 会替换为数字读法
+	Abbreviations map[string]string `yaml:"abbreviations" json:"abbreviations"` // 缩写的完整读法
+	Rules         []Rule            `yaml:"rules" json:"rules"`                 // 按顺序应用的正则替换规则
+	Lexicon       []LexiconEntry    `yaml:"lexicon" json:"lexicon"`             // 需要指定读音的词，如轻声与儿化
+
+	rules         []compiledRule
+	units         *regexp.Regexp
+	abbreviations *regexp.Regexp
+	lexicon       *regexp.Regexp
+	entries       map[string]LexiconEntry
+}
+
+// LexiconEntry 是词典中的一个词，phoneme 与 alias 二选一
+type LexiconEntry struct {
+	Word     string `yaml:"word" json:"word"`
+	Phoneme  string `yaml:"phoneme" json:"phoneme"`   // 读音，生成 <phoneme> 标签，如 "ming 2 bai 5"
+	Alphabet string `yaml:"alphabet" json:"alphabet"` // 读音使用的音标，默认 sapi
+	Alias    string `yaml:"alias" json:"alias"`       // 替换读法，生成 <sub> 标签
+}
+
+// Number 描述数字的读法
+type Number struct {
+	System     string   `yaml:"system" json:"system"`           // chinese: 按万进位（中文、日文），western: 按千进位
+	Digits     []string `yaml:"digits" json:"digits"`           // 0-9 的读法
+	SmallUnits []string `yaml:"small_units" json:"small_units"` // chinese: 个、十、百、千位的单位
+	LargeUnits []string `yaml:"large_units" json:"large_units"` // 每组（万或千）的单位，如 万、亿 或 thousand、million
+	Teens      []string `yaml:"teens" json:"teens"`             // western: 10-19 的读法
+	Tens       []string `yaml:"tens" json:"tens"`               // western: 整十的读法，下标为十位数字
+	TensJoin   string   `yaml:"tens_join" json:"tens_join"`     // western: 十位与个位之间的连接符，如 twenty-four 中的 -
+	Hundred    string   `yaml:"hundred" json:"hundred"`         // western: 百的读法
+	Decimal    string   `yaml:"decimal" json:"decimal"`         // 小数点的读法
+	Two        string   `yaml:"two" json:"two"`                 // chinese: 千、万、亿前的 2 的读法，如 两千
+	Phone      []string `yaml:"phone" json:"phone"`             // 电话号码逐位朗读时 0-9 的读法，如 1 读作 幺，未配置时使用 digits
+	Negative   string   `yaml:"negative" json:"negative"`       // 负号的读法
+	MaxDigits  int      `yaml:"max_digits" json:"max_digits"`   // 超过该位数的整数逐位朗读，默认 12
+}
+
+// Rule 是一条正则替换规则，替换模板中的 {1}、{2:digits} 等引用捕获组
+type Rule struct {
+	Name    string `yaml:"name" json:"name"`
+	Pattern string `yaml:"pattern" json:"pattern"`
+	Replace string `yaml:"replace" json:"replace"`
+}
+
+// compiledRule 是编译后的替换规则
+type compiledRule struct {
+	name    string
+	regex   *regexp.Regexp
+	replace string
+}
+
+// Set 是按区域索引的一组语言包
+type Set struct {
+	packs map[string]*Pack // 键为小写的区域、别名或语言
+}
+
+// Load 加载内置语言包与 dir 中的语言包，dir 中的语言包会覆盖相同区域的内置语言包
+func Load(dir string) (*Set, error) {
+	set := &Set{packs: make(map[string]*Pack)}
+
+	var packs []*Pack
+	entries, _ := fs.ReadDir(builtinPacks, "packs")
+	for _, entry := range entries {
+		data, err := builtinPacks.ReadFile("packs/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		pack, err := parse(entry.Name(), data)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("读取语言包目录失败: %w", err)
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			pack, err := parse(entry.Name(), data)
+			if err != nil {
+				return nil, err
+			}
+			packs = append(packs, pack)
+		}
+	}
+
+	// 后加载的语言包覆盖先加载的；语言级别的键只在未被占用时注册
+	for _, pack := range packs {
+		for _, locale := range append([]string{pack.Locale}, pack.Aliases...) {
+			set.packs[strings.ToLower(locale)] = pack
+		}
+	}
+	for _, pack := range packs {
+		if lang, _, found := strings.Cut(strings.ToLower(pack.Locale), "-"); found {
+			if _, ok := set.packs[lang]; !ok {
+				set.packs[lang] = pack
+			}
+		}
+	}
+	return set, nil
+}
+
+// Lookup 按区域、语言的顺序查找语言包，找不到时返回 nil
+func (s *Set) Lookup(locale string) *Pack {
+	locale = strings.ToLower(locale)
+	if pack, ok := s.packs[locale]; ok && locale != "" {
+		return pack
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		return s.packs[lang]
+	}
+	return nil
+}
+
+// Locales 返回已加载语言包的区域，按字母排序
+func (s *Set) Locales() []string {
+	seen := make(map[string]bool)
+	var locales []string
+	for _, pack := range s.packs {
+		if !seen[pack.Locale] {
+			seen[pack.Locale] = true
+			locales = append(locales, pack.Locale)
+		}
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// parse 按扩展名解析并校验语言包
+func parse(name string, data []byte) (*Pack, error) {
+	var pack Pack
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = json.Unmarshal(data, &pack)
+	} else {
+		err = yaml.Unmarshal(data, &pack)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析语言包 %s 失败: %w", name, err)
+	}
+	if err := pack.compile(); err != nil {
+		return nil, fmt.Errorf("语言包 %s: %w", name, err)
+	}
+	return &pack, nil
+}
+
+// compile 校验语言包并预编译正则
+func (p *Pack) compile() error {
+	if p.Locale == "" {
+		return fmt.Errorf("缺少 locale")
+	}
+	n := p.Number
+	if len(n.Digits) != 10 {
+		return fmt.Errorf("number.digits 必须包含 10 项")
+	}
+	switch n.System {
+	case "chinese":
+		if len(n.SmallUnits) != 4 {
+			return fmt.Errorf("number.small_units 必须包含 4 项")
+		}
+	case "western":
+		if len(n.Teens) != 10 || len(n.Tens) != 10 {
+			return fmt.Errorf("number.teens 与 number.tens 必须各包含 10 项")
+		}
+	default:
+		return fmt.Errorf("未知的 number.system: %s", n.System)
+	}
+	if len(n.Phone) != 0 && len(n.Phone) != 10 {
+		return fmt.Errorf("number.phone 必须包含 10 项")
+	}
+	if len(p.Months) != 0 && len(p.Months) != 12 {
+		return fmt.Errorf("months 必须包含 12 项")
+	}
+	switch p.Script {
+	case "", "simplified", "traditional":
+	default:
+		return fmt.Errorf("未知的 script: %s", p.Script)
+	}
+
+	for i, rule := range p.Rules {
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("编译规则 %d (%s) 失败: %w", i+1, rule.Name, err)
+		}
+		p.rules = append(p.rules, compiledRule{name: rule.Name, regex: regex, replace: rule.Replace})
+	}
+	for _, m := range []map[string]string{p.Units, p.Abbreviations} {
+		if _, ok := m[""]; ok {
+			return fmt.Errorf("units 与 abbreviations 的键不能为空")
+		}
+	}
+	if len(p.Units) > 0 {
+		p.units = regexp.MustCompile(`(` + numberPattern + `)[ \t]?(` + alternation(keys(p.Units), false) + `)`)
+	}
+	if len(p.Abbreviations) > 0 {
+		p.abbreviations = regexp.MustCompile(alternation(keys(p.Abbreviations), true))
+	}
+
+	if len(p.Lexicon) > 0 {
+		p.entries = make(map[string]LexiconEntry, len(p.Lexicon))
+		for i, entry := range p.Lexicon {
+			if entry.Word == "" || (entry.Phoneme == "") == (entry.Alias == "") {
+				return fmt.Errorf("lexicon 第 %d 项需要 word，以及 phoneme 或 alias 之一", i+1)
+			}
+			if entry.Alphabet == "" {
+				entry.Alphabet = "sapi"
+			}
+			p.entries[entry.Word] = entry
+		}
+		words := make([]string, 0, len(p.entries))
+		for word := range p.entries {
+			words = append(words, word)
+		}
+		p.lexicon = regexp.MustCompile(alternation(words, true))
+	}
+	return nil
+}
+
+// keys 返回映射的键
+func keys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
+
+// alternation 把词组成正则的多选分支，长词优先；以字母数字结尾的词加上单词边界，
+// leading 为 true 时开头也加上边界（单位紧跟数字，开头不能加）
+func alternation(words []string, leading bool) string {
+	sort.Slice(words, func(i, j int) bool {
+		if len(words[i]) != len(words[j]) {
+			return len(words[i]) > len(words[j])
+		}
+		return words[i] < words[j]
+	})
+	parts := make([]string, len(words))
+	for i, w := range words {
+		part := regexp.QuoteMeta(w)
+		if leading && isWordByte(w[0]) {
+			part = `\b` + part
+		}
+		if isWordByte(w[len(w)-1]) {
+			part += `\b`
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, "|")
+}
+
+// isWordByte 判断是否为 ASCII 字母、数字或下划线
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
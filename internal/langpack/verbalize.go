@@ -0,0 +1,189 @@
+package langpack
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"tts/internal/ssml"
+)
+
+var (
+	// tagRegex 匹配SSML标签与 {文字|读音} 形式的读音提示，它们的内容不做展开
+	tagRegex = regexp.MustCompile(`<[^<>]+>|` + ssml.HintPattern.String())
+	// pronunciationTag 匹配指定读法的 <phoneme>、<sub> 开始或结束标签
+	pronunciationTag = regexp.MustCompile(`^<(/?)(phoneme|sub)\b[^>]*?(/?)>$`)
+	// numberRegex 匹配文本中的数字
+	numberRegex = regexp.MustCompile(numberPattern)
+	// placeholderRegex 匹配替换模板中的 {1}、{2:digits}、{n} 等占位符
+	placeholderRegex = regexp.MustCompile(`\{(\d+|n)(?::(\w+))?\}`)
+)
+
+// Verbalize 按语言包展开文本中的数字、日期、单位与缩写，SSML标签原样保留。
+// 先转换中文字形，再依次应用 rules、units、abbreviations，朗读剩余的数字，最后为词典中的词标注读音。
+func (p *Pack) Verbalize(text string) string {
+	var sb strings.Builder
+	last := 0
+	// 已用 <phoneme> 或 <sub> 指定读法的内容保持原样
+	inside := ""
+	for _, loc := range tagRegex.FindAllStringIndex(text, -1) {
+		if inside == "" {
+			sb.WriteString(p.verbalize(text[last:loc[0]]))
+		} else {
+			sb.WriteString(text[last:loc[0]])
+		}
+		tag := text[loc[0]:loc[1]]
+		sb.WriteString(tag)
+		last = loc[1]
+
+		if m := pronunciationTag.FindStringSubmatch(tag); m != nil {
+			switch {
+			case m[1] == "/" && m[2] == inside:
+				inside = ""
+			case m[1] == "" && m[3] == "" && inside == "":
+				inside = m[2]
+			}
+		}
+	}
+	if inside == "" {
+		sb.WriteString(p.verbalize(text[last:]))
+	} else {
+		sb.WriteString(text[last:])
+	}
+	return sb.String()
+}
+
+// verbalize 展开一段不含标签的文本
+func (p *Pack) verbalize(s string) string {
+	if s == "" {
+		return s
+	}
+	s = convertScript(s, p.Script)
+	for _, rule := range p.rules {
+		s = replaceFunc(rule.regex, s, func(groups []string) string {
+			return p.expand(rule.replace, groups)
+		})
+	}
+	if p.units != nil {
+		s = replaceFunc(p.units, s, func(groups []string) string {
+			word := p.Units[groups[2]]
+			if strings.Contains(word, "{n}") {
+				return p.expand(word, groups[:2])
+			}
+			return p.join(p.Cardinal(groups[1]), word)
+		})
+	}
+	if p.abbreviations != nil {
+		s = p.abbreviations.ReplaceAllStringFunc(s, func(m string) string {
+			return p.Abbreviations[m]
+		})
+	}
+	return p.annotate(p.numbers(s))
+}
+
+// annotate 为词典中的词添加 <phoneme> 或 <sub> 标签
+func (p *Pack) annotate(s string) string {
+	if p.lexicon == nil {
+		return s
+	}
+	return p.lexicon.ReplaceAllStringFunc(s, func(word string) string {
+		entry := p.entries[word]
+		if entry.Alias != "" {
+			return `<sub alias="` + html.EscapeString(entry.Alias) + `">` + word + `</sub>`
+		}
+		return `<phoneme alphabet="` + html.EscapeString(entry.Alphabet) + `" ph="` + html.EscapeString(entry.Phoneme) + `">` + word + `</phoneme>`
+	})
+}
+
+// numbers 朗读剩余的数字，与字母相连的数字（如 MP3、H264）保持不变
+func (p *Pack) numbers(s string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range numberRegex.FindAllStringIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		if (start > 0 && isWordByte(s[start-1])) || (end < len(s) && isWordByte(s[end])) {
+			continue
+		}
+		words := p.Cardinal(s[start:end])
+		// 前面是独立的减号时读作负数，如 -5，而 3-5 中的 - 保持不变
+		if p.Number.Negative != "" && start > 0 && s[start-1] == '-' && (start == 1 || !isWordByte(s[start-2])) {
+			start--
+			words = p.join(p.Number.Negative, words)
+		}
+		sb.WriteString(s[last:start])
+		sb.WriteString(words)
+		last = end
+	}
+	if last == 0 {
+		return s
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// expand 用捕获组替换模板中的占位符，{0} 引用完整匹配，{n} 引用第一个捕获组。
+// 修饰符：digits 逐位朗读，phone 按电话号码逐位朗读，clock 时间中的分秒，
+// year 年份读法，month 月份名称，raw 保持原样，省略时按数值朗读。
+func (p *Pack) expand(template string, groups []string) string {
+	return placeholderRegex.ReplaceAllStringFunc(template, func(m string) string {
+		sub := placeholderRegex.FindStringSubmatch(m)
+		index := 1
+		if sub[1] != "n" {
+			index, _ = strconv.Atoi(sub[1])
+		}
+		if index >= len(groups) || groups[index] == "" {
+			return ""
+		}
+		value := groups[index]
+		switch sub[2] {
+		case "raw":
+			return value
+		case "phone":
+			return p.Phone(value)
+		case "clock":
+			return p.Clock(value)
+		case "digits":
+			return p.Digits(value)
+		case "year":
+			return p.Year(value)
+		case "month":
+			return p.Month(value)
+		default:
+			if numberRegex.FindString(value) != value {
+				return value
+			}
+			// 模板中的数字按数值朗读，如日期中的 03 读作 三
+			if trimmed := strings.TrimLeft(value, "0"); trimmed != value {
+				if trimmed == "" || trimmed[0] == '.' {
+					trimmed = "0" + trimmed
+				}
+				value = trimmed
+			}
+			return p.Cardinal(value)
+		}
+	})
+}
+
+// replaceFunc 替换正则的所有匹配，fn 接收完整匹配与各捕获组
+func replaceFunc(re *regexp.Regexp, s string, fn func(groups []string) string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	var sb strings.Builder
+	last := 0
+	for _, loc := range matches {
+		groups := make([]string, len(loc)/2)
+		for i := range groups {
+			if loc[2*i] >= 0 {
+				groups[i] = s[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		sb.WriteString(s[last:loc[0]])
+		sb.WriteString(fn(groups))
+		last = loc[1]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+package langpack
+
+import "strings"
+
+// zhPairs 是常用简体字与繁体字的对照，每两个字符为一组（简、繁）。
+// 只收录一一对应的字，发/髮、后/後、里/裡 等一简对多繁的字按最常用的写法处理，
+// 干、面、只、台 等含义依赖上下文的字不做转换。
+var zhPairs = "" +
+	"万萬与與专專业業丛叢东東丝絲两兩严嚴丧喪个個丰豐临臨为為丽麗举舉么麼义義乌烏乐樂乔喬习習乡鄉书書买買乱亂争爭于於亏虧云雲" +
+	"亚亞产產亩畝亲親亿億仅僅从從仑侖仓倉仪儀们們价價众眾优優会會伞傘伟偉传傳伤傷伦倫伪偽体體佣傭侠俠侣侶侦偵侧側侨僑俭儉债債" +
+	"倾傾偿償储儲儿兒兑兌党黨兰蘭关關兴興养養兽獸内內冈岡册冊写寫军軍农農冯馮决決况況冻凍净淨凉涼减減凑湊凤鳳凭憑凯凱击擊刘劉" +
+	"则則刚剛创創删刪别別剂劑剑劍剧劇劝勸办辦务務动動励勵劲勁劳勞势勢勋勳匀勻区區医醫华華协協单單卖賣卢盧卫衛却卻厂廠厅廳历歷" +
+	"厉厲压壓厌厭厕廁厢廂厦廈厨廚县縣参參双雙发發变變叙敘叠疊号號叹嘆吓嚇吕呂吗嗎吨噸听聽启啟吴吳员員呜嗚咏詠响響哑啞哗嘩唤喚" +
+	"啸嘯喷噴嘱囑团團园園围圍国國图圖圆圓圣聖场場坏壞块塊坚堅坛壇坝壩坟墳坠墜垄壟垒壘垦墾执執扩擴扫掃扬揚扰擾抚撫抛拋抢搶护護" +
+	"报報担擔拟擬拢攏拣揀拥擁拦攔拧擰拨撥择擇挂掛挚摯挟挾挠撓挡擋挣掙挤擠挥揮捞撈损損捡撿换換捣搗据據掷擲掺摻揽攬搀攙搁擱搂摟" +
+	"搅攪携攜摄攝摆擺摇搖摊攤撑撐敌敵数數斋齋断斷无無旧舊时時旷曠显顯晋晉晒曬晓曉晕暈暂暫术術机機杀殺杂雜权權条條来來杨楊极極" +
+	"构構枪槍枫楓柜櫃标標栈棧栋棟栏欄树樹样樣桥橋桩樁梦夢检檢楼樓横橫欢歡欧歐岁歲归歸残殘毁毀毕畢毙斃气氣汇匯汉漢汤湯沟溝没沒" +
+	"沦淪沪滬泪淚泽澤洁潔浅淺测測济濟浓濃涂塗涛濤润潤涨漲渊淵渐漸温溫湾灣湿濕满滿滤濾滥濫滨濱滩灘潜潛灭滅灯燈灵靈灾災灿燦炉爐" +
+	"点點炼煉烂爛烛燭烟煙烦煩烧燒热熱焕煥爱愛爷爺牵牽犹猶狭狹独獨狮獅猎獵猫貓献獻环環现現玛瑪琐瑣电電画畫畅暢疗療疮瘡疯瘋盏盞" +
+	"盐鹽监監盖蓋盘盤矫矯矿礦码碼砖磚础礎确確礼禮祸禍离離种種积積称稱稳穩穷窮窃竊窍竅窝窩竞競笔筆笋筍笼籠筑築签簽简簡粮糧紧緊" +
+	"纠糾红紅约約级級纪紀纯純纲綱纳納纵縱纷紛纸紙纹紋纺紡线線练練组組细細织織终終绍紹经經绑綁结結绕繞绘繪给給络絡绝絕统統继繼" +
+	"绩績绪緒续續维維绵綿综綜绿綠缓緩编編缘緣缩縮缴繳网網罗羅罚罰职職联聯聪聰肃肅肠腸肤膚肿腫胀脹胆膽胜勝胶膠脉脈脑腦脚腳脱脫" +
+	"脸臉腊臘舰艦舱艙艰艱艺藝节節芦蘆苏蘇苹蘋茎莖荐薦药藥荣榮莱萊获獲营營萧蕭蓝藍虑慮虚虛虫蟲虽雖蚀蝕蚁蟻蛮蠻补補衬襯袜襪装裝" +
+	"览覽观觀规規视視觉覺见見触觸计計订訂认認讨討让讓训訓议議讯訊记記讲講许許论論设設访訪证證评評识識诉訴词詞译譯试試诗詩诚誠" +
+	"话話询詢该該详詳语語误誤说說请請诸諸读讀课課谁誰调調谈談谊誼谋謀谓謂谢謝谨謹谱譜贝貝负負贡貢财財责責贤賢败敗货貨质質贩販" +
+	"贪貪购購贯貫贴貼贵貴贷貸贸貿费費贺賀资資赋賦赌賭赏賞赔賠赖賴赚賺赛賽赞贊赠贈赵趙赶趕趋趨跃躍践踐踪蹤轨軌转轉轮輪软軟轻輕" +
+	"载載较較辅輔辆輛辈輩辉輝输輸辞辭边邊辽遼达達迁遷过過迈邁运運还還这這进進远遠违違连連迟遲适適选選逊遜递遞逻邏遗遺邓鄧邮郵" +
+	"邻鄰郑鄭酱醬释釋鉴鑒针針钟鐘钢鋼钱錢钻鑽铁鐵铃鈴铜銅银銀铺鋪链鏈销銷锁鎖锅鍋错錯锡錫锦錦键鍵镇鎮镜鏡长長门門闭閉问問闯闖" +
+	"闲閒间間闷悶闹鬧闻聞阀閥阁閣阅閱队隊阳陽阴陰阵陣阶階际際陆陸陈陳险險随隨隐隱隶隸难難雾霧静靜顶頂项項顺順须須顽頑顾顧顿頓" +
+	"预預领領频頻题題颜顏额額风風飞飛饭飯饮飲饰飾饱飽饼餅馆館驱驅驾駕验驗骑騎骗騙鱼魚鲁魯鲜鮮鸟鳥鸡雞鸣鳴鸭鴨麦麥黄黃齐齊齿齒" +
+	"龙龍龟龜几幾尽盡当當对對开開车車页頁马馬学學应應实實张張总總处處层層戏戲声聲币幣账帳户戶带帶头頭妈媽孙孫猪豬虾蝦广廣庆慶" +
+	"宁寧闽閩赣贛陕陝琼瓊钥鑰馈饋愿願态態恋戀惊驚惯慣忆憶怀懷忧憂恶惡悬懸惧懼惨慘愤憤懒懶类類轰轟鸿鴻寻尋导導寿壽将將尔爾尘塵" +
+	"尝嘗岂豈岛島岭嶺峡峽帅帥师師帮幫庄莊库庫庙廟废廢异異弃棄弯彎弹彈强強录錄彻徹径徑怜憐恳懇恼惱悦悅惩懲惭慚惮憚懑懣战戰" +
+	"扑撲扪捫颁頒颂頌颈頸颗顆颤顫饥飢饿餓馒饅驶駛驻駐驼駝骂罵骄驕骚騷骤驟鲸鯨鸽鴿鹅鵝鹤鶴鹰鷹黾黽齑齏龄齡"
+
+var (
+	toTraditional = map[rune]rune{}
+	toSimplified  = map[rune]rune{}
+)
+
+func init() {
+	runes := []rune(zhPairs)
+	for i := 0; i+1 < len(runes); i += 2 {
+		toTraditional[runes[i]] = runes[i+1]
+		toSimplified[runes[i+1]] = runes[i]
+	}
+}
+
+// convertScript 把文本转换为指定的中文字形，script 为 simplified 或 traditional，其他值不转换
+func convertScript(text, script string) string {
+	var table map[rune]rune
+	switch script {
+	case "simplified":
+		table = toSimplified
+	case "traditional":
+		table = toTraditional
+	default:
+		return text
+	}
+	return strings.Map(func(r rune) rune {
+		if mapped, ok := table[r]; ok {
+			return mapped
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,327 @@
+// Package live 实现实时会话：客户端通过 WebSocket 陆续发送文本（如大模型逐步输出的回复），
+// 服务按完整的句子依次合成并推送音频。客户端可以随时发送 stop 打断朗读（barge-in）：
+// 服务立即停止推送，取消正在合成与排队的句子，适合语音助手在用户开口时停止说话。
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+
+	"tts/internal/apperr"
+	"tts/internal/metrics"
+	"tts/internal/models"
+	"tts/internal/session"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pingPeriod = 30 * time.Second
+	// maxClientMessage 是客户端发来的消息的大小上限
+	maxClientMessage = 64 * 1024
+	// defaultMaxBuffer 是未配置时未成句文本的字符数上限，超过后不等句末直接合成
+	defaultMaxBuffer = 200
+)
+
+var segmentsTotal = metrics.NewCounter("tts_live_segments_total",
+	"实时会话合成的句子数，result 为 sent、dropped（被打断）、failed", "result")
+
+// upgrader 接受任意来源的连接，连接由API密钥认证
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// Options 是实时会话的合成设置
+type Options struct {
+	Synthesizer *ttspkg.Synthesizer
+	Request     models.TTSRequest   // 各句使用的语音参数，Text 不使用
+	Replace     func(string) string // 合成前替换文本，如会话中的 replacements，可以为 nil
+	MaxBuffer   int                 // 未成句的文本超过该字符数时不等句末直接合成
+	// Record 在每句合成后调用，用于记录用量，被打断或连接断开而作废的句子不调用。可以为 nil
+	Record func(usage *ttspkg.Usage, req models.TTSRequest)
+}
+
+// event 是 WebSocket 上收发的 JSON 消息。
+// 客户端发给服务：
+//   - {"type": "text", "text": "...", "final": false}：追加文本，完整的句子进入合成队列；final 为 true 表示回复结束，剩余文本全部合成
+//   - {"type": "stop"}（或 "flush"）：打断，丢弃未成句的文本、排队与正在合成的句子
+//
+// 服务发给客户端：
+//   - {"type": "audio", "seq": 1, "text": "...", "size": 12345}：紧接着的二进制消息是该句的 MP3 音频
+//   - {"type": "error", "seq": 1, "message": "..."}：该句合成失败，之后的句子继续合成
+//   - {"type": "done"}：final 之前的文本全部推送完毕
+//   - {"type": "stopped", "dropped": 2}：打断完成，此后不会再收到打断之前的句子
+type event struct {
+	Type    string `json:"type"`
+	Text    string `json:"text,omitempty"`
+	Final   bool   `json:"final,omitempty"`
+	Seq     int    `json:"seq,omitempty"`
+	Size    int    `json:"size,omitempty"`
+	Message string `json:"message,omitempty"`
+	Dropped *int   `json:"dropped,omitempty"`
+}
+
+// segment 是合成队列中的一段文本
+type segment struct {
+	gen  int
+	seq  int
+	text string
+}
+
+// conn 是一个实时会话的连接
+type conn struct {
+	ws   *websocket.Conn
+	opts Options
+	ctx  context.Context // 连接断开时取消
+
+	// writeMu 保证写出的顺序：打断时在持有 writeMu 期间作废之前的句子并写出 stopped，
+	// 之后写出的音频都属于打断之后的文本
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	gen     int                // 打断的次数，每次打断后之前的句子全部作废
+	genCtx  context.Context    // 当前一代句子的合成上下文，打断时取消
+	cancel  context.CancelFunc // 取消 genCtx
+	buffer  string             // 尚未成句的文本
+	queue   []segment
+	seq     int
+	busy    bool // 是否有句子正在合成
+	pending bool // 已收到 final，队列合成完毕后发送 done
+	wake    chan struct{}
+}
+
+// Serve 把请求升级为 WebSocket 连接并处理实时会话，连接断开且合成协程退出后返回
+func Serve(w http.ResponseWriter, r *http.Request, opts Options) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 已向客户端返回错误
+		return
+	}
+	if opts.MaxBuffer <= 0 {
+		opts.MaxBuffer = defaultMaxBuffer
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	c := &conn{ws: ws, opts: opts, ctx: ctx, wake: make(chan struct{}, 1)}
+	c.genCtx, c.cancel = context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.synthesizeLoop()
+	}()
+	go func() {
+		defer wg.Done()
+		c.pingLoop()
+	}()
+	c.readLoop()
+	// 取消正在进行的合成并等待协程退出，之后不会再调用 Record
+	cancel()
+	wg.Wait()
+	log.Printf("实时会话已断开，共 %d 句，打断 %d 次", c.seq, c.gen)
+}
+
+// readLoop 读取客户端发来的消息，连接断开时返回
+func (c *conn) readLoop() {
+	defer c.ws.Close()
+	c.ws.SetReadLimit(maxClientMessage)
+	c.ws.SetReadDeadline(time.Now().Add(pingPeriod + writeWait))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(pingPeriod + writeWait))
+	})
+	for {
+		var e event
+		if err := c.ws.ReadJSON(&e); err != nil {
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(pingPeriod + writeWait))
+		switch e.Type {
+		case "text":
+			c.append(e.Text, e.Final)
+		case "stop", "flush":
+			c.stop()
+		default:
+			c.send(event{Type: "error", Message: "未知的消息类型: " + e.Type})
+		}
+	}
+}
+
+// append 追加文本，完整的句子进入合成队列
+func (c *conn) append(text string, final bool) {
+	c.mu.Lock()
+	c.buffer += text
+	end := len(c.buffer)
+	if !final {
+		end = session.SentenceBoundary(c.buffer)
+		if end == 0 && utf8.RuneCountInString(c.buffer) > c.opts.MaxBuffer {
+			end = len(c.buffer)
+		}
+	}
+	ready := strings.TrimSpace(c.buffer[:end])
+	c.buffer = c.buffer[end:]
+	if ready != "" {
+		c.seq++
+		c.queue = append(c.queue, segment{gen: c.gen, seq: c.seq, text: ready})
+		select {
+		case c.wake <- struct{}{}:
+		default:
+		}
+	}
+	idle := final && !c.busy && len(c.queue) == 0
+	if final && !idle {
+		c.pending = true
+	}
+	c.mu.Unlock()
+
+	if idle {
+		c.send(event{Type: "done"})
+	}
+}
+
+// stop 打断朗读：作废之前的全部句子并取消正在进行的合成，然后告知客户端
+func (c *conn) stop() {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.mu.Lock()
+	dropped := len(c.queue)
+	if c.busy {
+		dropped++
+	}
+	c.gen++
+	c.cancel()
+	c.genCtx, c.cancel = context.WithCancel(c.ctx)
+	c.queue = nil
+	c.buffer = ""
+	c.pending = false
+	c.mu.Unlock()
+
+	segmentsTotal.Add(float64(dropped), "dropped")
+	c.writeLocked(event{Type: "stopped", Dropped: &dropped})
+}
+
+// synthesizeLoop 依次合成队列中的句子
+func (c *conn) synthesizeLoop() {
+	for {
+		seg, ctx, ok := c.next()
+		if !ok {
+			return
+		}
+		req := c.opts.Request
+		req.Text = seg.text
+		if c.opts.Replace != nil {
+			req.Text = c.opts.Replace(req.Text)
+		}
+		ctx, usage := ttspkg.WithUsage(ctx)
+		resp, err := c.opts.Synthesizer.Synthesize(ctx, req)
+		if c.opts.Record != nil && !c.dropped(seg) {
+			c.opts.Record(usage, req)
+		}
+		c.deliver(seg, resp, err)
+	}
+}
+
+// dropped 判断句子是否已被打断或因连接断开而作废
+func (c *conn) dropped(seg segment) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return seg.gen != c.gen || c.ctx.Err() != nil
+}
+
+// next 等待并取出下一句，连接断开时返回 false
+func (c *conn) next() (segment, context.Context, bool) {
+	for {
+		c.mu.Lock()
+		if len(c.queue) > 0 {
+			seg := c.queue[0]
+			c.queue = c.queue[1:]
+			c.busy = true
+			ctx := c.genCtx
+			c.mu.Unlock()
+			return seg, ctx, true
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-c.wake:
+		case <-c.ctx.Done():
+			return segment{}, nil, false
+		}
+	}
+}
+
+// deliver 推送一句的合成结果，打断之前的句子直接丢弃
+func (c *conn) deliver(seg segment, resp *models.TTSResponse, err error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.mu.Lock()
+	c.busy = false
+	current := seg.gen == c.gen
+	done := current && c.pending && len(c.queue) == 0
+	if done {
+		c.pending = false
+	}
+	c.mu.Unlock()
+	if !current {
+		return
+	}
+
+	if err != nil {
+		segmentsTotal.Inc("failed")
+		log.Printf("实时会话合成失败: %v", err)
+		c.writeLocked(event{Type: "error", Seq: seg.seq, Message: apperr.From(err).Message})
+	} else {
+		segmentsTotal.Inc("sent")
+		if c.writeLocked(event{Type: "audio", Seq: seg.seq, Text: seg.text, Size: len(resp.AudioContent)}) == nil {
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			c.ws.WriteMessage(websocket.BinaryMessage, resp.AudioContent)
+		}
+	}
+	if done {
+		c.writeLocked(event{Type: "done"})
+	}
+}
+
+// pingLoop 定期发送 ping 保持连接
+func (c *conn) pingLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.writeMu.Lock()
+			c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			err := c.ws.WriteMessage(websocket.PingMessage, nil)
+			c.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// send 写出一条消息
+func (c *conn) send(e event) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.writeLocked(e)
+}
+
+// writeLocked 在持有 writeMu 时写出一条消息，写出失败时连接会在读取时断开
+func (c *conn) writeLocked(e event) error {
+	data, _ := json.Marshal(e)
+	c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.ws.WriteMessage(websocket.TextMessage, data)
+}
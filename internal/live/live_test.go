@@ -0,0 +1,91 @@
+package live
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"tts/internal/models"
+	ttspkg "tts/pkg/tts"
+)
+
+// blockingProvider 合成“慢”开头的句子时一直等到被取消，其余句子立即返回
+type blockingProvider struct {
+	started chan struct{}
+}
+
+func (p *blockingProvider) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return nil, nil
+}
+
+func (p *blockingProvider) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	if strings.HasPrefix(req.Text, "慢") {
+		p.started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &models.TTSResponse{AudioContent: []byte{0}, ContentType: "audio/mpeg"}, nil
+}
+
+func TestStopSkipsRecordAndServeWaits(t *testing.T) {
+	provider := &blockingProvider{started: make(chan struct{}, 1)}
+	synthesizer := ttspkg.NewSynthesizer(provider, ttspkg.NewSegmenter(&ttspkg.TTSConfig{SegmentThreshold: 100}), 1)
+
+	var mu sync.Mutex
+	var recorded []string
+	served := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(served)
+		Serve(w, r, Options{
+			Synthesizer: synthesizer,
+			Record: func(usage *ttspkg.Usage, req models.TTSRequest) {
+				mu.Lock()
+				recorded = append(recorded, req.Text)
+				mu.Unlock()
+			},
+		})
+	}))
+	defer server.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	defer ws.Close()
+
+	ws.WriteJSON(event{Type: "text", Text: "慢一点说。", Final: true})
+	<-provider.started
+	ws.WriteJSON(event{Type: "stop"})
+	var e event
+	if err := ws.ReadJSON(&e); err != nil || e.Type != "stopped" || e.Dropped == nil || *e.Dropped != 1 {
+		t.Fatalf("打断响应 = %+v, %v", e, err)
+	}
+
+	ws.WriteJSON(event{Type: "text", Text: "你好。", Final: true})
+	if err := ws.ReadJSON(&e); err != nil || e.Type != "audio" {
+		t.Fatalf("音频响应 = %+v, %v", e, err)
+	}
+	ws.ReadMessage() // 音频数据
+	if err := ws.ReadJSON(&e); err != nil || e.Type != "done" {
+		t.Fatalf("结束响应 = %+v, %v", e, err)
+	}
+
+	// 断开时 Serve 等合成协程退出后才返回，此后不会再调用 Record
+	ws.Close()
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("连接断开后 Serve 没有返回")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(recorded) != 1 || recorded[0] != "你好。" {
+		t.Errorf("记录的句子 = %q，被打断的句子不应记录", recorded)
+	}
+}
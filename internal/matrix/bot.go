@@ -0,0 +1,349 @@
+// Package matrix 提供内置的 Matrix 机器人：房间中以命令前缀开头的消息被合成为语音，以音频消息回复，
+// 每个房间的语音在配置中设置。
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/store"
+	"tts/internal/utils"
+	"tts/internal/voicemap"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// defaultPrefix 是默认的命令前缀
+	defaultPrefix = "!tts"
+	// stateBucket 是保存同步位置的存储桶，重启后从上次的位置继续，不重复回复
+	stateBucket = "matrix"
+	// syncTimeout 是长轮询的超时时间
+	syncTimeout = 30 * time.Second
+	// replyTimeout 是合成并回复一条消息的时间上限
+	replyTimeout = 2 * time.Minute
+)
+
+// syncFilter 只同步房间中的消息，不同步在线状态与账号数据
+const syncFilter = `{"presence":{"types":[]},"account_data":{"types":[]},` +
+	`"room":{"timeline":{"types":["m.room.message"]},"state":{"types":[]},"ephemeral":{"types":[]},"account_data":{"types":[]}}}`
+
+// helpText 是 help 命令的回复，%s 为命令前缀
+const helpText = "用法: %s 要朗读的文本"
+
+// Bot 是 Matrix 机器人
+type Bot struct {
+	synthesizer *ttspkg.Synthesizer
+	store       *store.Store
+	config      *config.Config
+	voices      *voicemap.Mapper
+	client      *http.Client
+	homeserver  string
+	prefix      string
+	userID      string
+	txn         atomic.Int64
+}
+
+// event 是房间时间线中的事件
+type event struct {
+	Type    string `json:"type"`
+	EventID string `json:"event_id"`
+	Sender  string `json:"sender"`
+	Content struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+	} `json:"content"`
+}
+
+// syncResponse 是 /sync 响应中用到的字段
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+// New 创建 Matrix 机器人
+func New(synthesizer *ttspkg.Synthesizer, st *store.Store, cfg *config.Config) *Bot {
+	prefix := strings.TrimSpace(cfg.Matrix.Prefix)
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	b := &Bot{
+		synthesizer: synthesizer,
+		store:       st,
+		config:      cfg,
+		voices:      voicemap.New(&cfg.TTS),
+		client:      &http.Client{Timeout: syncTimeout + 30*time.Second},
+		homeserver:  strings.TrimRight(cfg.Matrix.Homeserver, "/"),
+		prefix:      prefix,
+	}
+	b.txn.Store(time.Now().UnixNano())
+	return b
+}
+
+// Run 以长轮询方式同步房间消息，直到 ctx 结束
+func (b *Bot) Run(ctx context.Context) {
+	for b.userID == "" {
+		var whoami struct {
+			UserID string `json:"user_id"`
+		}
+		if err := b.call(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &whoami); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Matrix 获取账号信息失败: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		b.userID = whoami.UserID
+	}
+	log.Printf("Matrix 机器人已启动: %s", b.userID)
+
+	var since string
+	if _, err := b.store.Get(stateBucket, "since", &since); err != nil {
+		log.Printf("读取 Matrix 同步位置失败: %v", err)
+	}
+	for ctx.Err() == nil {
+		query := url.Values{"filter": {syncFilter}, "timeout": {strconv.FormatInt(syncTimeout.Milliseconds(), 10)}}
+		if since != "" {
+			query.Set("since", since)
+		} else {
+			query.Set("timeout", "0")
+		}
+		var resp syncResponse
+		if err := b.call(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+query.Encode(), nil, &resp); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Matrix 同步失败: %v", err)
+				time.Sleep(5 * time.Second)
+			}
+			continue
+		}
+
+		for roomID := range resp.Rooms.Invite {
+			b.join(ctx, roomID)
+		}
+		// 首次同步返回的是之前的消息，不回复
+		if since != "" {
+			for roomID, room := range resp.Rooms.Join {
+				for _, e := range room.Timeline.Events {
+					if e.Type == "m.room.message" && e.Sender != b.userID {
+						go b.handle(ctx, roomID, e)
+					}
+				}
+			}
+		}
+		since = resp.NextBatch
+		if err := b.store.Put(stateBucket, "since", since); err != nil {
+			log.Printf("保存 Matrix 同步位置失败: %v", err)
+		}
+	}
+}
+
+// room 返回房间的语音设置，配置了 rooms 时不在其中的房间不能使用
+func (b *Bot) room(roomID string) (config.ChatVoice, bool) {
+	if len(b.config.Matrix.Rooms) == 0 {
+		return config.ChatVoice{Voice: b.config.Matrix.Voice, Language: b.config.Matrix.Language}, true
+	}
+	for _, room := range b.config.Matrix.Rooms {
+		if room.ID != roomID {
+			continue
+		}
+		if room.Voice == "" && room.Language == "" {
+			return config.ChatVoice{Voice: b.config.Matrix.Voice, Language: b.config.Matrix.Language}, true
+		}
+		return room.ChatVoice, true
+	}
+	return config.ChatVoice{}, false
+}
+
+// join 接受房间邀请
+func (b *Bot) join(ctx context.Context, roomID string) {
+	if !b.config.Matrix.AutoJoin {
+		return
+	}
+	if _, ok := b.room(roomID); !ok {
+		log.Printf("Matrix 忽略未授权房间的邀请: %s", roomID)
+		return
+	}
+	if err := b.call(ctx, http.MethodPost, "/_matrix/client/v3/join/"+url.PathEscape(roomID), map[string]any{}, nil); err != nil {
+		log.Printf("Matrix 加入房间失败: %v", err)
+		return
+	}
+	log.Printf("Matrix 已加入房间: %s", roomID)
+}
+
+// handle 处理一条消息，以命令前缀开头的文本消息被合成为语音回复
+func (b *Bot) handle(ctx context.Context, roomID string, e event) {
+	if e.Content.MsgType != "m.text" {
+		return
+	}
+	text := stripReplyFallback(e.Content.Body)
+	if text != b.prefix && !strings.HasPrefix(text, b.prefix+" ") {
+		return
+	}
+	chat, ok := b.room(roomID)
+	if !ok {
+		return
+	}
+	text = strings.TrimSpace(strings.TrimPrefix(text, b.prefix))
+
+	ctx, cancel := context.WithTimeout(ctx, replyTimeout)
+	defer cancel()
+	if text == "" || text == "help" {
+		b.notice(ctx, roomID, e, fmt.Sprintf(helpText, b.prefix))
+		return
+	}
+	if length := utils.GraphemeCount(text); length > b.config.TTS.MaxTextLength {
+		b.notice(ctx, roomID, e, fmt.Sprintf("文本长度超过限制 (%d > %d)", length, b.config.TTS.MaxTextLength))
+		return
+	}
+
+	audio, err := b.speak(ctx, roomID, chat, text)
+	if err != nil {
+		log.Printf("Matrix 合成失败: %v", err)
+		appErr := apperr.From(err)
+		message := appErr.Message
+		if appErr.Code == apperr.CodeInternal {
+			message = "服务器内部错误"
+		}
+		b.notice(ctx, roomID, e, "合成失败: "+message)
+		return
+	}
+	if err := b.sendAudio(ctx, roomID, e, audio); err != nil {
+		log.Printf("Matrix 发送语音失败: %v", err)
+		return
+	}
+	log.Printf("Matrix 语音已发送: 房间 %s, 文本长度 %d, 音频大小 %s",
+		roomID, utils.GraphemeCount(text), utils.FormatFileSize(len(audio)))
+}
+
+// speak 按房间的语音设置合成文本并添加水印
+func (b *Bot) speak(ctx context.Context, roomID string, chat config.ChatVoice, text string) ([]byte, error) {
+	voice, err := b.voices.Choose(ctx, b.synthesizer, chat, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if voice == "" {
+		voice = b.config.TTS.DefaultVoice
+	}
+	resp, err := b.synthesizer.Synthesize(ctx, models.TTSRequest{
+		Text:  text,
+		Voice: voice,
+		Rate:  b.config.TTS.DefaultRate,
+		Pitch: b.config.TTS.DefaultPitch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watermark.Stamp(b.config, resp.AudioContent, "matrix")
+}
+
+// stripReplyFallback 去掉回复消息开头引用原消息的 "> " 行
+func stripReplyFallback(body string) string {
+	if !strings.HasPrefix(body, "> ") {
+		return strings.TrimSpace(body)
+	}
+	lines := strings.Split(body, "\n")
+	i := 0
+	for i < len(lines) && strings.HasPrefix(lines[i], ">") {
+		i++
+	}
+	return strings.TrimSpace(strings.Join(lines[i:], "\n"))
+}
+
+// sendAudio 上传音频并以音频消息回复
+func (b *Bot) sendAudio(ctx context.Context, roomID string, e event, audio []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		b.homeserver+"/_matrix/media/v3/upload?filename=tts.mp3", bytes.NewReader(audio))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "audio/mpeg")
+	var upload struct {
+		ContentURI string `json:"content_uri"`
+	}
+	if err := b.do(req, &upload); err != nil {
+		return fmt.Errorf("上传音频失败: %w", err)
+	}
+	return b.send(ctx, roomID, e, map[string]any{
+		"msgtype": "m.audio",
+		"body":    "tts.mp3",
+		"url":     upload.ContentURI,
+		"info":    map[string]any{"mimetype": "audio/mpeg", "size": len(audio)},
+	})
+}
+
+// notice 以通知消息回复
+func (b *Bot) notice(ctx context.Context, roomID string, e event, text string) {
+	if err := b.send(ctx, roomID, e, map[string]any{"msgtype": "m.notice", "body": text}); err != nil {
+		log.Printf("Matrix 发送消息失败: %v", err)
+	}
+}
+
+// send 在房间中发送回复 e 的消息
+func (b *Bot) send(ctx context.Context, roomID string, e event, content map[string]any) error {
+	content["m.relates_to"] = map[string]any{"m.in_reply_to": map[string]string{"event_id": e.EventID}}
+	path := fmt.Sprintf("/_matrix/client/v3/rooms/%s/send/m.room.message/tts%d", url.PathEscape(roomID), b.txn.Add(1))
+	return b.call(ctx, http.MethodPut, path, content, nil)
+}
+
+// call 以 JSON 请求体调用客户端接口
+func (b *Bot) call(ctx context.Context, method, path string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, b.homeserver+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return b.do(req, result)
+}
+
+// do 带访问令牌发送请求并解析结果
+func (b *Bot) do(req *http.Request, result any) error {
+	req.Header.Set("Authorization", "Bearer "+b.config.Matrix.AccessToken)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var matrixErr struct {
+			ErrCode string `json:"errcode"`
+			Error   string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&matrixErr)
+		return fmt.Errorf("HTTP %d %s %s", resp.StatusCode, matrixErr.ErrCode, matrixErr.Error)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
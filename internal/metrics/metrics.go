@@ -0,0 +1,332 @@
+// Package metrics 提供轻量级的指标收集，并以 Prometheus 文本格式导出
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// collector 是所有指标类型的公共接口
+type collector interface {
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]collector{}
+	order      []string
+)
+
+// register 注册指标，同名指标只注册一次
+func register(name string, c collector) collector {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if existing, ok := registry[name]; ok {
+		return existing
+	}
+	registry[name] = c
+	order = append(order, name)
+	return c
+}
+
+// labelKey 将标签值拼接为内部使用的键
+func labelKey(values []string) string {
+	return strings.Join(values, "\x00")
+}
+
+// formatLabels 生成 Prometheus 标签字符串
+func formatLabels(names []string, key string, extra ...string) string {
+	var pairs []string
+	if len(names) > 0 {
+		values := strings.Split(key, "\x00")
+		for i, name := range names {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			pairs = append(pairs, fmt.Sprintf("%s=%q", name, value))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sortedKeys 返回排序后的键，保证输出稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counter 是只增不减的计数器
+type Counter struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter 创建并注册一个计数器
+func NewCounter(name, help string, labels ...string) *Counter {
+	return register(name, &Counter{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: map[string]float64{},
+	}).(*Counter)
+}
+
+// Inc 计数加一
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 增加指定值
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.mu.Lock()
+	c.values[labelKey(labelValues)] += v
+	c.mu.Unlock()
+}
+
+// Value 返回当前值
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[labelKey(labelValues)]
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %g\n", c.name, formatLabels(c.labels, key), c.values[key])
+	}
+}
+
+// Gauge 是可增可减的仪表
+type Gauge struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewGauge 创建并注册一个仪表
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return register(name, &Gauge{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: map[string]float64{},
+	}).(*Gauge)
+}
+
+// Set 设置当前值
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.mu.Lock()
+	g.values[labelKey(labelValues)] = v
+	g.mu.Unlock()
+}
+
+// Add 增加指定值，可为负数
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.mu.Lock()
+	g.values[labelKey(labelValues)] += v
+	g.mu.Unlock()
+}
+
+// Value 返回当前值
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.values[labelKey(labelValues)]
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s%s %g\n", g.name, formatLabels(g.labels, key), g.values[key])
+	}
+}
+
+// DefaultBuckets 是适用于请求耗时（秒）的默认分桶
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// histogramValue 保存单组标签的分布数据
+type histogramValue struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Histogram 记录观测值的分布
+type Histogram struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+// NewHistogram 创建并注册一个直方图，buckets 为空时使用 DefaultBuckets
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return register(name, &Histogram{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  map[string]*histogramValue{},
+	}).(*Histogram)
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := labelKey(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+// Quantile 根据分桶估算分位数，没有数据时返回 0
+func (h *Histogram) Quantile(q float64, labelValues ...string) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[labelKey(labelValues)]
+	if !ok || hv.count == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q * float64(hv.count)))
+	for i, bound := range h.buckets {
+		if hv.counts[i] >= target {
+			return bound
+		}
+	}
+	return math.Inf(1)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", fmt.Sprintf("%g", bound)), hv.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", h.name, formatLabels(h.labels, key), hv.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key), hv.count)
+	}
+}
+
+// WriteText 以 Prometheus 文本格式输出所有指标
+func WriteText(w io.Writer) {
+	registryMu.Lock()
+	names := append([]string(nil), order...)
+	collectors := make([]collector, len(names))
+	for i, name := range names {
+		collectors[i] = registry[name]
+	}
+	registryMu.Unlock()
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Sample 是指标在一组标签下的当前值，直方图的值为观测次数
+type Sample struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+}
+
+// sampler 由可以按标签列出当前值的指标实现
+type sampler interface {
+	samples() []Sample
+}
+
+// Samples 返回名为 name 的指标在各组标签下的当前值，指标不存在时返回 nil，供管理页面汇总使用
+func Samples(name string) []Sample {
+	registryMu.Lock()
+	c, ok := registry[name]
+	registryMu.Unlock()
+	if !ok {
+		return nil
+	}
+	return c.(sampler).samples()
+}
+
+// samplesOf 将各组标签的值转换为 Sample，按标签排序
+func samplesOf[V any](names []string, values map[string]V, value func(V) float64) []Sample {
+	samples := make([]Sample, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		labels := make(map[string]string, len(names))
+		parts := strings.Split(key, "\x00")
+		for i, name := range names {
+			if i < len(parts) {
+				labels[name] = parts[i]
+			}
+		}
+		samples = append(samples, Sample{Labels: labels, Value: value(values[key])})
+	}
+	return samples
+}
+
+func (c *Counter) samples() []Sample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return samplesOf(c.labels, c.values, func(v float64) float64 { return v })
+}
+
+func (g *Gauge) samples() []Sample {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return samplesOf(g.labels, g.values, func(v float64) float64 { return v })
+}
+
+func (h *Histogram) samples() []Sample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return samplesOf(h.labels, h.values, func(v *histogramValue) float64 { return float64(v.count) })
+}
+
+// Handler 返回导出指标的 HTTP 处理器
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(w)
+	})
+}
@@ -1,12 +1,36 @@
 package models
 
+import "time"
+
 // TTSRequest 表示一个语音合成请求
 type TTSRequest struct {
-	Text  string `json:"text"`  // 要转换的文本
-	Voice string `json:"voice"` // 语音ID
-	Rate  string `json:"rate"`  // 语速 (-100% 到 +100%)
-	Pitch string `json:"pitch"` // 语调 (-100% 到 +100%)
-	Style string `json:"style"` // 说话风格
+	Text    string `json:"text"`     // 要转换的文本
+	Voice   string `json:"voice"`    // 语音ID
+	Rate    string `json:"rate"`     // 语速 (-100% 到 +100%)
+	Pitch   string `json:"pitch"`    // 语调 (-100% 到 +100%)
+	Style   string `json:"style"`    // 说话风格
+	URLMode string `json:"url_mode"` // 网址的朗读方式，覆盖 ssml.urls.mode
+	Quotes  string `json:"quotes"`   // 引号中对话的朗读方式（off、voice、prosody），覆盖 ssml.quotes.mode
+
+	// 输出音频的静音补充，合成后处理，不影响缓存与合并
+	PadStartMs int `json:"pad_start_ms"` // 开头补充的静音毫秒数
+	PadEndMs   int `json:"pad_end_ms"`   // 末尾补充的静音毫秒数
+	AlignMs    int `json:"align_ms"`     // 在末尾继续补充静音，使总时长为该毫秒数的整数倍，如 1000 表示补齐到整秒
+
+	// PreviewSeconds 大于 0 时只合成文本开头预计朗读约该秒数的部分，用于长文档的快速试听
+	PreviewSeconds float64 `json:"preview_seconds"`
+
+	// Latency 为 low 时流式合成的第一段尽量短，normal 时按正常大小分段，为空时按 tts.first_chunk.enabled；只影响分段，不影响缓存
+	Latency string `json:"latency"`
+}
+
+// TemplateRequest 是模板合成请求，未指定的语音参数使用模板中的配置
+type TemplateRequest struct {
+	Variables map[string]any `json:"variables"` // 模板变量
+	Voice     string         `json:"voice"`
+	Rate      string         `json:"rate"`
+	Pitch     string         `json:"pitch"`
+	Style     string         `json:"style"`
 }
 
 // TTSResponse 表示一个语音合成响应
@@ -16,6 +40,108 @@ type TTSResponse struct {
 	CacheHit     bool   `json:"cache_hit"`     // 是否命中缓存
 }
 
+// SpeechMark 是一条语音标记，字段与 Amazon Polly 的 speech marks 一致
+type SpeechMark struct {
+	Time  int64  `json:"time"`  // 相对音频开始的毫秒数
+	Type  string `json:"type"`  // 标记类型: sentence, word, ssml
+	Start int    `json:"start"` // 在输入文本中的起始字节偏移
+	End   int    `json:"end"`   // 在输入文本中的结束字节偏移
+	Value string `json:"value"` // 标记对应的文本
+}
+
+// PollyRequest 是 Amazon Polly SynthesizeSpeech 的请求体
+type PollyRequest struct {
+	Engine          string   `json:"Engine"`
+	LanguageCode    string   `json:"LanguageCode"`
+	LexiconNames    []string `json:"LexiconNames"`
+	OutputFormat    string   `json:"OutputFormat"` // mp3 或 json（语音标记）
+	SampleRate      string   `json:"SampleRate"`
+	SpeechMarkTypes []string `json:"SpeechMarkTypes"`
+	Text            string   `json:"Text"`
+	TextType        string   `json:"TextType"` // text 或 ssml
+	VoiceId         string   `json:"VoiceId"`
+}
+
+// GoogleSynthesizeRequest 是 Google Cloud TTS text:synthesize 的请求体
+type GoogleSynthesizeRequest struct {
+	Input struct {
+		Text string `json:"text"`
+		SSML string `json:"ssml"`
+	} `json:"input"`
+	Voice struct {
+		LanguageCode string `json:"languageCode"`
+		Name         string `json:"name"`
+		SSMLGender   string `json:"ssmlGender"` // MALE, FEMALE, NEUTRAL
+	} `json:"voice"`
+	AudioConfig struct {
+		AudioEncoding   string  `json:"audioEncoding"` // 仅支持 MP3
+		SpeakingRate    float64 `json:"speakingRate"`  // 0.25 到 4.0，1.0 为正常语速
+		Pitch           float64 `json:"pitch"`         // -20.0 到 20.0 个半音
+		VolumeGainDb    float64 `json:"volumeGainDb"`
+		SampleRateHertz int     `json:"sampleRateHertz"`
+	} `json:"audioConfig"`
+}
+
+// GoogleSynthesizeResponse 是 Google Cloud TTS text:synthesize 的响应体
+type GoogleSynthesizeResponse struct {
+	AudioContent string `json:"audioContent"` // base64 编码的音频
+}
+
+// CompareRequest 使用多个语音合成同一段文本的请求
+type CompareRequest struct {
+	Text   string   `json:"text"`   // 要转换的文本
+	Voices []string `json:"voices"` // 参与对比的语音列表
+	Rate   string   `json:"rate"`   // 语速
+	Pitch  string   `json:"pitch"`  // 语调
+	Style  string   `json:"style"`  // 说话风格
+	Format string   `json:"format"` // 返回格式：zip（默认）或 json
+}
+
+// CompareResult 单个语音的对比合成结果
+type CompareResult struct {
+	Voice     string `json:"voice"`
+	File      string `json:"file,omitempty"` // zip 包中的文件名
+	URL       string `json:"url,omitempty"`  // json 格式下为 data URL
+	Size      int    `json:"size"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DialogueRequest 是多人对话合成请求，各轮使用各自的语音合成后按顺序拼接
+type DialogueRequest struct {
+	Turns  []DialogueTurn     `json:"turns"`  // 对话的各轮
+	Rate   string             `json:"rate"`   // 语速
+	Pitch  string             `json:"pitch"`  // 语调
+	GapMs  *int               `json:"gap_ms"` // 轮次之间的停顿毫秒数，未指定时为 300
+	Stereo bool               `json:"stereo"` // 输出立体声，不同说话人放在不同的声像位置
+	Pan    map[string]float64 `json:"pan"`    // 说话人（语音）→ 声像位置，-1 最左～1 最右，未指定的自动分配
+}
+
+// DialogueTurn 是对话中的一轮
+type DialogueTurn struct {
+	Voice string `json:"voice"` // 说话人的语音，未指定时使用默认语音
+	Text  string `json:"text"`
+	Style string `json:"style"` // 说话风格
+}
+
+// SessionSettings 是会话中的请求默认使用的语音与文本处理设置，请求中指定的参数优先
+type SessionSettings struct {
+	Voice        string            `json:"voice,omitempty"`        // 语音ID，支持 voice_mapping 中的别名
+	Rate         string            `json:"rate,omitempty"`         // 语速
+	Pitch        string            `json:"pitch,omitempty"`        // 语调
+	Style        string            `json:"style,omitempty"`        // 说话风格
+	Incremental  bool              `json:"incremental"`            // 只合成相对上次提交新增的完整句子
+	Replacements map[string]string `json:"replacements,omitempty"` // 合成前替换的词，如人名的读法
+}
+
+// SessionResponse 是会话的查询结果
+type SessionResponse struct {
+	ID        string          `json:"id"`
+	Settings  SessionSettings `json:"settings"`
+	Spoken    int             `json:"spoken"`     // 增量模式下已朗读的字符数
+	ExpiresAt time.Time       `json:"expires_at"` // 空闲过期时间，每次使用后顺延
+}
+
 // OpenAIRequest OpenAI TTS请求结构体
 type OpenAIRequest struct {
 	Model string  `json:"model"`
@@ -71,3 +197,46 @@ type IFreeTimeTtsHandle struct {
 	Params           map[string]string      `json:"params"`
 	HttpConfigs      IFreeTimeHttpConfig    `json:"httpConfigs"`
 }
+
+// ReadSettings 是朗读会话中各分段使用的语音设置
+type ReadSettings struct {
+	Voice string `json:"voice,omitempty"` // 语音ID，支持 voice_mapping 中的别名
+	Rate  string `json:"rate,omitempty"`
+	Pitch string `json:"pitch,omitempty"`
+	Style string `json:"style,omitempty"`
+}
+
+// ReadChunk 是朗读会话中的一段文本，位置与长度按 UTF-16 码元计算，与 JavaScript 字符串的下标一致
+type ReadChunk struct {
+	Index    int    `json:"index"`
+	Offset   int    `json:"offset"` // 在页面文本中的起始位置
+	Length   int    `json:"length"`
+	AudioURL string `json:"audio_url"`
+}
+
+// ReadChunksRequest 是按顺序提交的页面文本分段
+type ReadChunksRequest struct {
+	Start  *int     `json:"start"` // 第一段的序号，省略时追加在已有分段之后；小于已有分段数时替换该序号及之后的分段
+	Chunks []string `json:"chunks"`
+}
+
+// ReadChunksResponse 是提交分段的结果
+type ReadChunksResponse struct {
+	Chunks []ReadChunk `json:"chunks"`
+	Length int         `json:"length"` // 已提交的页面文本总长度
+}
+
+// ReadSessionResponse 是朗读会话的查询结果
+type ReadSessionResponse struct {
+	ID        string       `json:"id"`
+	Settings  ReadSettings `json:"settings"`
+	Chunks    []ReadChunk  `json:"chunks"`
+	Length    int          `json:"length"`
+	ExpiresAt time.Time    `json:"expires_at"` // 空闲过期时间，每次使用后顺延
+}
+
+// ReadSeekResponse 是按页面位置定位的结果
+type ReadSeekResponse struct {
+	ReadChunk
+	From int `json:"from"` // 分段内开始朗读的位置，已回退到所在句子的开头
+}
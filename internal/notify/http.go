@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"tts/internal/config"
+)
+
+var client = &http.Client{Timeout: sendTimeout}
+
+// slack 通过 Incoming Webhook 发送到 Slack 频道
+type slack struct {
+	url string
+}
+
+func newSlack(sc config.NotifySink) (sink, error) {
+	if sc.URL == "" {
+		return nil, fmt.Errorf("slack 需要配置 url")
+	}
+	return &slack{url: sc.URL}, nil
+}
+
+func (s *slack) send(ctx context.Context, e Event) error {
+	body, _ := json.Marshal(map[string]string{"text": e.Text()})
+	return post(ctx, s.url, "application/json", body, nil)
+}
+
+// ntfy 发布到 ntfy 主题，失败的任务使用高优先级
+type ntfy struct {
+	url   string
+	token string
+}
+
+func newNtfy(sc config.NotifySink) (sink, error) {
+	if sc.URL == "" {
+		return nil, fmt.Errorf("ntfy 需要配置主题地址 url")
+	}
+	return &ntfy{url: sc.URL, token: sc.Token}, nil
+}
+
+func (n *ntfy) send(ctx context.Context, e Event) error {
+	// 请求头只能使用 ASCII，标题按 RFC 2047 编码，ntfy 会自动解码
+	header := http.Header{"Title": {mime.BEncoding.Encode("UTF-8", e.Title())}}
+	if e.Failed() {
+		header.Set("Priority", "high")
+		header.Set("Tags", "x")
+	} else {
+		header.Set("Tags", "white_check_mark")
+	}
+	if e.URL != "" {
+		header.Set("Click", e.URL)
+	}
+	if n.token != "" {
+		header.Set("Authorization", "Bearer "+n.token)
+	}
+	body := strings.TrimPrefix(e.Text(), e.Title()+"\n")
+	return post(ctx, n.url, "text/plain; charset=utf-8", []byte(body), header)
+}
+
+// webhook 把事件以 JSON 发送到任意地址
+type webhook struct {
+	url string
+}
+
+func newWebhook(sc config.NotifySink) (sink, error) {
+	if sc.URL == "" {
+		return nil, fmt.Errorf("webhook 需要配置 url")
+	}
+	return &webhook{url: sc.URL}, nil
+}
+
+func (w *webhook) send(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.url, "application/json", body, nil)
+}
+
+// post 发送请求，非 2xx 响应视为失败
+func post(ctx context.Context, url, contentType string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package notify 在定时任务、缓存预热、异步合成等后台任务完成或失败时通过 Slack、邮件、ntfy 或 webhook 发送通知。
+// 通知在后台发送，失败只记录日志，不影响任务本身。
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tts/internal/config"
+	"tts/internal/metrics"
+)
+
+// sendTimeout 是发送一条通知的超时时间
+const sendTimeout = 30 * time.Second
+
+// 任务结果
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+var sentTotal = metrics.NewCounter("tts_notifications_total",
+	"发送的任务通知数", "sink", "result")
+
+// Event 是一次任务完成或失败的通知内容
+type Event struct {
+	Kind     string    `json:"kind"` // schedule, warm 或 job
+	Name     string    `json:"name"` // 任务名称或任务ID
+	Status   string    `json:"status"`
+	Summary  string    `json:"summary,omitempty"` // 结果摘要，如音频大小或成功条数
+	Error    string    `json:"error,omitempty"`
+	URL      string    `json:"url,omitempty"` // 生成的音频地址
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// Failed 判断任务是否失败
+func (e Event) Failed() bool {
+	return e.Status == StatusFailed
+}
+
+// Title 返回通知标题
+func (e Event) Title() string {
+	kind := map[string]string{"schedule": "定时任务", "warm": "缓存预热任务", "job": "合成任务"}[e.Kind]
+	if kind == "" {
+		kind = "任务"
+	}
+	result := "完成"
+	if e.Failed() {
+		result = "失败"
+	}
+	return fmt.Sprintf("%s %s 执行%s", kind, e.Name, result)
+}
+
+// Text 返回通知正文
+func (e Event) Text() string {
+	lines := []string{e.Title()}
+	if e.Summary != "" {
+		lines = append(lines, e.Summary)
+	}
+	if e.Error != "" {
+		lines = append(lines, "错误: "+e.Error)
+	}
+	if e.URL != "" {
+		lines = append(lines, "音频: "+e.URL)
+	}
+	lines = append(lines, "耗时: "+e.Finished.Sub(e.Started).Round(time.Millisecond).String())
+	return strings.Join(lines, "\n")
+}
+
+// sink 是一个通知渠道
+type sink interface {
+	send(ctx context.Context, e Event) error
+}
+
+// channel 是配置中的一个通知渠道
+type channel struct {
+	name        string
+	failureOnly bool
+	sink        sink
+}
+
+// Notifier 按名称把通知发送到配置的渠道
+type Notifier struct {
+	channels map[string]*channel
+	defaults []string
+	tenants  map[string][]string // 单独设置了 notify 的密钥
+}
+
+// New 根据配置创建通知器，并检查任务与密钥引用的渠道是否存在。没有配置任何渠道时返回 nil，
+// nil 通知器的方法不做任何事
+func New(cfg *config.Config) (*Notifier, error) {
+	if len(cfg.Notify.Sinks) == 0 {
+		return nil, nil
+	}
+	n := &Notifier{
+		channels: make(map[string]*channel),
+		defaults: cfg.Notify.Default,
+		tenants:  make(map[string][]string),
+	}
+	for i, sc := range cfg.Notify.Sinks {
+		if sc.Name == "" {
+			return nil, fmt.Errorf("notify.sinks[%d] 必须配置 name", i)
+		}
+		if _, ok := n.channels[sc.Name]; ok {
+			return nil, fmt.Errorf("notify.sinks 中的名称重复: %s", sc.Name)
+		}
+		s, err := newSink(sc)
+		if err != nil {
+			return nil, fmt.Errorf("通知渠道 %s: %w", sc.Name, err)
+		}
+		switch sc.On {
+		case "", "all", "failure":
+		default:
+			return nil, fmt.Errorf("通知渠道 %s: 无效的 on: %q", sc.Name, sc.On)
+		}
+		n.channels[sc.Name] = &channel{name: sc.Name, failureOnly: sc.On == "failure", sink: s}
+	}
+
+	if err := n.check("notify.default", cfg.Notify.Default); err != nil {
+		return nil, err
+	}
+	for _, job := range cfg.Schedule.Jobs {
+		if err := n.check("任务 "+job.Name, job.Notify); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range cfg.Keys {
+		if len(key.Notify) == 0 {
+			continue
+		}
+		if err := n.check("密钥 "+key.Name, key.Notify); err != nil {
+			return nil, err
+		}
+		n.tenants[key.Name] = key.Notify
+	}
+	return n, nil
+}
+
+// newSink 按类型创建通知渠道
+func newSink(sc config.NotifySink) (sink, error) {
+	switch sc.Type {
+	case "slack":
+		return newSlack(sc)
+	case "ntfy":
+		return newNtfy(sc)
+	case "webhook":
+		return newWebhook(sc)
+	case "email":
+		return newEmail(sc)
+	}
+	return nil, fmt.Errorf("不支持的类型: %q", sc.Type)
+}
+
+// check 检查引用的渠道是否都已配置
+func (n *Notifier) check(owner string, names []string) error {
+	for _, name := range names {
+		if _, ok := n.channels[name]; !ok {
+			return fmt.Errorf("%s 引用了不存在的通知渠道: %s", owner, name)
+		}
+	}
+	return nil
+}
+
+// Tenant 返回密钥提交的后台任务使用的通知渠道，没有单独设置时返回 nil（即使用默认渠道）
+func (n *Notifier) Tenant(tenant string) []string {
+	if n == nil {
+		return nil
+	}
+	return n.tenants[tenant]
+}
+
+// Notify 在后台向 names 指定的渠道发送通知，names 为空时使用默认渠道
+func (n *Notifier) Notify(names []string, e Event) {
+	if n == nil {
+		return
+	}
+	if len(names) == 0 {
+		names = n.defaults
+	}
+	for _, name := range names {
+		ch := n.channels[name]
+		if ch == nil || (ch.failureOnly && !e.Failed()) {
+			continue
+		}
+		go ch.deliver(e)
+	}
+}
+
+// deliver 发送一条通知并记录结果
+func (ch *channel) deliver(e Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := ch.sink.send(ctx, e); err != nil {
+		sentTotal.Inc(ch.name, "error")
+		log.Printf("发送通知到 %s 失败: %v", ch.name, err)
+		return
+	}
+	sentTotal.Inc(ch.name, "ok")
+}
@@ -0,0 +1,96 @@
+// 直播播报页面：通过 WebSocket 接收队列状态，播放正在播报的消息，播放结束后通知服务播放下一条
+(function () {
+    const params = new URLSearchParams(location.search);
+    const token = params.get('token') || '';
+    const showQueue = params.get('queue') !== '0';
+    const maxQueue = parseInt(params.get('max') || '5', 10);
+    // 页面地址为 {base_path}/overlay/，接口地址按同样的前缀拼接
+    const base = location.pathname.replace(/\/overlay\/.*$/, '');
+    const query = token ? '?token=' + encodeURIComponent(token) : '';
+
+    const audio = new Audio();
+    let socket = null;
+    let playing = null; // 正在播放的消息 ID
+
+    const $ = (id) => document.getElementById(id);
+
+    function setStatus(text) {
+        $('status').textContent = text;
+        $('status').hidden = !text;
+    }
+
+    function ended(id) {
+        if (socket && socket.readyState === WebSocket.OPEN) {
+            socket.send(JSON.stringify({type: 'ended', id: id}));
+        }
+    }
+
+    audio.addEventListener('ended', () => ended(playing));
+    audio.addEventListener('error', () => ended(playing));
+
+    function play(item) {
+        playing = item.id;
+        audio.src = base + '/v1/overlay/audio/' + encodeURIComponent(item.id) + query;
+        audio.play().then(() => setStatus('')).catch(() => {
+            // 普通浏览器需要先与页面交互才能自动播放，OBS 浏览器源不受限制
+            setStatus('浏览器阻止了自动播放，请点击页面');
+            document.addEventListener('click', () => audio.play(), {once: true});
+        });
+    }
+
+    function render(state) {
+        const current = state.current;
+        $('current').hidden = !current;
+        if (current) {
+            $('current').className = 'card ' + current.kind;
+            $('current-user').textContent = current.user;
+            $('current-amount').textContent = current.amount || '';
+            $('current-amount').hidden = !current.amount;
+            $('current-text').textContent = current.text;
+        }
+
+        const list = $('queue');
+        list.replaceChildren();
+        if (showQueue) {
+            for (const item of state.queue.slice(0, maxQueue)) {
+                const li = document.createElement('li');
+                li.className = item.ready ? item.kind : item.kind + ' pending';
+                li.textContent = item.user + (item.amount ? ' ' + item.amount : '') + '：' + item.text;
+                list.appendChild(li);
+            }
+            if (state.queue.length > maxQueue) {
+                const li = document.createElement('li');
+                li.textContent = '还有 ' + (state.queue.length - maxQueue) + ' 条';
+                list.appendChild(li);
+            }
+        }
+
+        if (!current) {
+            // 正在播放的消息被跳过
+            if (playing) {
+                audio.pause();
+                playing = null;
+            }
+        } else if (current.id !== playing) {
+            play(current);
+        }
+    }
+
+    function connect() {
+        const scheme = location.protocol === 'https:' ? 'wss://' : 'ws://';
+        socket = new WebSocket(scheme + location.host + base + '/v1/overlay/ws' + query);
+        socket.onopen = () => setStatus('');
+        socket.onmessage = (e) => {
+            const event = JSON.parse(e.data);
+            if (event.type === 'state') {
+                render(event.state);
+            }
+        };
+        socket.onclose = () => {
+            setStatus('与服务的连接已断开，正在重新连接…');
+            setTimeout(connect, 3000);
+        };
+    }
+
+    connect();
+})();
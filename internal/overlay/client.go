@@ -0,0 +1,140 @@
+package overlay
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// sendBuffer 是每个页面最多积压的状态消息数，积压已满的页面会被断开
+	sendBuffer = 16
+	writeWait  = 10 * time.Second
+	pingPeriod = 30 * time.Second
+	// maxClientMessage 是页面发来的消息的大小上限
+	maxClientMessage = 1024
+)
+
+// upgrader 接受任意来源的连接：OBS 浏览器源没有固定的 Origin，连接由 token 认证
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// client 是一个连接的页面
+type client struct {
+	conn *websocket.Conn
+	send chan []byte
+}
+
+// event 是 WebSocket 上收发的消息。
+// 服务发给页面：{"type": "state", "state": {...}}，队列变化时发送；
+// 页面发给服务：{"type": "ended", "id": "..."}，正在播放的消息播放结束或无法播放时发送
+type event struct {
+	Type  string `json:"type"`
+	State *State `json:"state,omitempty"`
+	ID    string `json:"id,omitempty"`
+}
+
+// ServeWS 把请求升级为 WebSocket 连接，连接期间向页面推送队列状态并接收播放结束的通知
+func (o *Overlay) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 已向客户端返回错误
+		return
+	}
+	c := &client{conn: conn, send: make(chan []byte, sendBuffer)}
+
+	o.mu.Lock()
+	o.clients[c] = struct{}{}
+	// 页面连接之前已合成的消息此时开始播放，新连接的页面同时收到当前状态
+	o.advanceLocked()
+	o.broadcastLocked()
+	o.mu.Unlock()
+	log.Printf("直播播报页面已连接，当前 %d 个页面", o.clientCount())
+
+	go c.writeLoop()
+	o.readLoop(c)
+
+	o.mu.Lock()
+	if _, ok := o.clients[c]; ok {
+		delete(o.clients, c)
+		close(c.send)
+	}
+	o.mu.Unlock()
+	log.Printf("直播播报页面已断开，当前 %d 个页面", o.clientCount())
+}
+
+func (o *Overlay) clientCount() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.clients)
+}
+
+// readLoop 读取页面发来的消息，连接断开时返回
+func (o *Overlay) readLoop(c *client) {
+	defer c.conn.Close()
+	c.conn.SetReadLimit(maxClientMessage)
+	c.conn.SetReadDeadline(time.Now().Add(pingPeriod + writeWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pingPeriod + writeWait))
+	})
+	for {
+		var e event
+		if err := c.conn.ReadJSON(&e); err != nil {
+			return
+		}
+		if e.Type == "ended" {
+			o.Ended(e.ID)
+		}
+	}
+}
+
+// writeLoop 把状态消息写给页面，并定期发送 ping 保持连接
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	defer c.conn.Close()
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, nil)
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// broadcastLocked 把队列状态发给所有页面，积压已满的页面断开连接，页面重新连接后会收到最新状态
+func (o *Overlay) broadcastLocked() {
+	if len(o.clients) == 0 {
+		return
+	}
+	data := o.encodeLocked()
+	for c := range o.clients {
+		select {
+		case c.send <- data:
+		default:
+			delete(o.clients, c)
+			close(c.send)
+		}
+	}
+}
+
+func (o *Overlay) encodeLocked() []byte {
+	state := o.stateLocked()
+	data, _ := json.Marshal(event{Type: "state", State: &state})
+	return data
+}
@@ -0,0 +1,414 @@
+// Package overlay 实现直播播报：聊天机器人提交观众的聊天消息与打赏，经屏蔽词过滤与按用户限流后进入播报队列，
+// 按用户选择语音提前合成；OBS 浏览器源页面通过 WebSocket 接收队列状态，依次播放并显示排队的消息。
+package overlay
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"tts/internal/apperr"
+	"tts/internal/audio"
+	"tts/internal/config"
+	"tts/internal/metrics"
+	"tts/internal/models"
+	"tts/internal/voicemap"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	defaultFormat         = "{user}说：{text}"
+	defaultDonationFormat = "{user}打赏了{amount}：{text}"
+	defaultMaxQueue       = 50
+	defaultMaxLength      = 200
+	defaultReplacement    = "哔"
+
+	// KindChat 与 KindDonation 是消息的类型
+	KindChat     = "chat"
+	KindDonation = "donation"
+
+	synthesisTimeout = 30 * time.Second
+	// playbackGrace 是音频时长之外等待页面报告播放结束的时间，超时后自动播放下一条，避免页面异常时队列停滞
+	playbackGrace = 5 * time.Second
+	// unknownDuration 是无法识别音频时长时等待播放结束的时间
+	unknownDuration = time.Minute
+)
+
+var messagesTotal = metrics.NewCounter("tts_overlay_messages_total",
+	"直播播报收到的消息数，result 为 queued、filtered、rate_limited、queue_full、failed", "result")
+
+// Message 是提交的聊天消息或打赏
+type Message struct {
+	User   string `json:"user"`
+	Text   string `json:"text"`
+	Kind   string `json:"kind"`   // chat（默认）或 donation
+	Amount string `json:"amount"` // 打赏金额，原样朗读与显示，如 "30 元"
+	Voice  string `json:"voice"`  // 指定语音，优先于 overlay.voices 中的设置
+}
+
+// Item 是播报队列中的一条消息
+type Item struct {
+	ID     string `json:"id"`
+	User   string `json:"user"`
+	Text   string `json:"text"` // 过滤后的消息，供页面显示
+	Kind   string `json:"kind"`
+	Amount string `json:"amount,omitempty"`
+	Ready  bool   `json:"ready"` // 是否已合成完成
+
+	speech   string // 朗读的文本
+	voice    string
+	audio    []byte
+	duration time.Duration
+}
+
+// State 是发给页面的队列状态
+type State struct {
+	Current *Item   `json:"current"` // 正在播放的消息，没有时为 null
+	Queue   []*Item `json:"queue"`   // 排队等待播放的消息
+}
+
+// Overlay 维护播报队列与连接的页面
+type Overlay struct {
+	cfg         config.OverlayConfig
+	tts         config.TTSConfig
+	synthesizer *ttspkg.Synthesizer
+	voices      *voicemap.Mapper
+	blocked     *regexp.Regexp // 没有屏蔽词时为 nil
+	voiceByUser map[string]string
+
+	mu       sync.Mutex
+	queue    []*Item
+	current  *Item
+	timer    *time.Timer
+	lastChat map[string]time.Time // 各用户上一条聊天消息的时间
+	clients  map[*client]struct{}
+	wake     chan struct{}
+}
+
+// New 创建播报队列并启动后台合成
+func New(cfg *config.Config, synthesizer *ttspkg.Synthesizer) *Overlay {
+	o := &Overlay{
+		cfg:         cfg.Overlay,
+		tts:         cfg.TTS,
+		synthesizer: synthesizer,
+		voices:      voicemap.New(&cfg.TTS),
+		voiceByUser: make(map[string]string),
+		lastChat:    make(map[string]time.Time),
+		clients:     make(map[*client]struct{}),
+		wake:        make(chan struct{}, 1),
+	}
+	if o.cfg.Format == "" {
+		o.cfg.Format = defaultFormat
+	}
+	if o.cfg.DonationFormat == "" {
+		o.cfg.DonationFormat = defaultDonationFormat
+	}
+	if o.cfg.MaxQueue <= 0 {
+		o.cfg.MaxQueue = defaultMaxQueue
+	}
+	if o.cfg.MaxLength <= 0 {
+		o.cfg.MaxLength = defaultMaxLength
+	}
+	if o.cfg.Replacement == "" {
+		o.cfg.Replacement = defaultReplacement
+	}
+	// 配置中的用户名已被转为小写，查找时同样按小写匹配
+	for user, voice := range o.cfg.Voices {
+		o.voiceByUser[strings.ToLower(user)] = voice
+	}
+	var words []string
+	for _, word := range o.cfg.BlockedWords {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+	}
+	if len(words) > 0 {
+		o.blocked = regexp.MustCompile("(?i)" + strings.Join(words, "|"))
+	}
+	go o.synthesizeLoop()
+	return o
+}
+
+// Token 返回页面使用的令牌
+func (o *Overlay) Token() string {
+	return o.cfg.Token
+}
+
+// Enqueue 过滤消息并加入队列，返回加入的消息与排在它之前的消息数
+func (o *Overlay) Enqueue(m Message) (*Item, int, error) {
+	m.User = strings.TrimSpace(m.User)
+	m.Text = strings.TrimSpace(m.Text)
+	if m.Kind == "" {
+		m.Kind = KindChat
+	}
+	if m.Kind != KindChat && m.Kind != KindDonation {
+		return nil, 0, apperr.Newf(apperr.CodeInvalidRequest, "未知的消息类型: %s，应为 chat 或 donation", m.Kind)
+	}
+	if m.User == "" || m.Text == "" && m.Kind == KindChat {
+		return nil, 0, apperr.New(apperr.CodeInvalidRequest, "user 与 text 不能为空")
+	}
+
+	user, text, ok := o.filter(m.User), o.filter(m.Text), true
+	if o.cfg.Filter == "drop" {
+		ok = user == m.User && text == m.Text
+	}
+	if !ok {
+		messagesTotal.Inc("filtered")
+		return nil, 0, apperr.New(apperr.CodeInvalidRequest, "消息包含屏蔽词")
+	}
+	text = truncate(text, o.cfg.MaxLength)
+
+	format := o.cfg.Format
+	if m.Kind == KindDonation {
+		format = o.cfg.DonationFormat
+	}
+	item := &Item{
+		ID:     uuid.New().String(),
+		User:   user,
+		Text:   text,
+		Kind:   m.Kind,
+		Amount: m.Amount,
+		speech: strings.NewReplacer("{user}", user, "{text}", text, "{amount}", m.Amount).Replace(format),
+		voice:  o.voiceFor(m),
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	key := strings.ToLower(m.User)
+	now := time.Now()
+	if m.Kind == KindChat && o.cfg.UserInterval > 0 {
+		if last, ok := o.lastChat[key]; ok && now.Sub(last) < time.Duration(o.cfg.UserInterval)*time.Second {
+			messagesTotal.Inc("rate_limited")
+			return nil, 0, apperr.Newf(apperr.CodeRateLimited, "%s 发送过于频繁，每 %d 秒最多播报一条", m.User, o.cfg.UserInterval)
+		}
+	}
+	if len(o.queue) >= o.cfg.MaxQueue {
+		messagesTotal.Inc("queue_full")
+		return nil, 0, apperr.New(apperr.CodeRateLimited, "播报队列已满，请稍后再试")
+	}
+	if m.Kind == KindChat {
+		o.lastChat[key] = now
+		o.pruneLocked(now)
+	}
+	o.queue = append(o.queue, item)
+	messagesTotal.Inc("queued")
+	o.broadcastLocked()
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return item, len(o.queue) - 1, nil
+}
+
+// Remove 从队列中删除未播放的消息，或跳过正在播放的消息，消息不存在时返回 false
+func (o *Overlay) Remove(id string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.current != nil && o.current.ID == id {
+		o.finishLocked()
+		return true
+	}
+	for i, item := range o.queue {
+		if item.ID == id {
+			o.queue = append(o.queue[:i], o.queue[i+1:]...)
+			o.broadcastLocked()
+			return true
+		}
+	}
+	return false
+}
+
+// Skip 跳过正在播放的消息，没有正在播放的消息时返回 false
+func (o *Overlay) Skip() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.current == nil {
+		return false
+	}
+	o.finishLocked()
+	return true
+}
+
+// State 返回当前的队列状态
+func (o *Overlay) State() State {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.stateLocked()
+}
+
+// Audio 返回正在播放或排队的消息的音频
+func (o *Overlay) Audio(id string) ([]byte, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.current != nil && o.current.ID == id {
+		return o.current.audio, true
+	}
+	for _, item := range o.queue {
+		if item.ID == id && item.Ready {
+			return item.audio, true
+		}
+	}
+	return nil, false
+}
+
+// voiceFor 返回消息使用的语音：消息中指定的语音、overlay.voices 中为用户设置的语音、overlay.voice、tts.default_voice。
+// 用户名作为 voice_mapping 灰度分流的键，同一用户总是使用同一语音
+func (o *Overlay) voiceFor(m Message) string {
+	voice := m.Voice
+	if voice == "" {
+		voice = o.voiceByUser[strings.ToLower(m.User)]
+	}
+	if voice == "" {
+		voice = o.cfg.Voice
+	}
+	if voice == "" {
+		return o.tts.DefaultVoice
+	}
+	return o.voices.Resolve(voice, m.User)
+}
+
+// filter 把屏蔽词替换为 replacement
+func (o *Overlay) filter(text string) string {
+	if o.blocked == nil {
+		return text
+	}
+	return o.blocked.ReplaceAllLiteralString(text, o.cfg.Replacement)
+}
+
+// truncate 把 text 截断为最多 n 个字符
+func truncate(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	return string([]rune(text)[:n]) + "…"
+}
+
+// pruneLocked 删除已经超过限流间隔的用户记录
+func (o *Overlay) pruneLocked(now time.Time) {
+	if len(o.lastChat) < 1000 {
+		return
+	}
+	interval := time.Duration(o.cfg.UserInterval) * time.Second
+	for user, last := range o.lastChat {
+		if now.Sub(last) >= interval {
+			delete(o.lastChat, user)
+		}
+	}
+}
+
+// synthesizeLoop 按队列顺序提前合成消息，合成失败的消息从队列中删除
+func (o *Overlay) synthesizeLoop() {
+	for range o.wake {
+		for {
+			item := o.nextPending()
+			if item == nil {
+				break
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), synthesisTimeout)
+			resp, err := o.synthesizer.Synthesize(ctx, models.TTSRequest{
+				Text:  item.speech,
+				Voice: item.voice,
+				Rate:  o.tts.DefaultRate,
+				Pitch: o.tts.DefaultPitch,
+			})
+			cancel()
+			o.mu.Lock()
+			if err != nil {
+				messagesTotal.Inc("failed")
+				o.removeLocked(item)
+			} else {
+				item.audio = resp.AudioContent
+				item.duration = duration(resp.AudioContent)
+				item.Ready = true
+				o.advanceLocked()
+			}
+			o.broadcastLocked()
+			o.mu.Unlock()
+		}
+	}
+}
+
+// nextPending 返回队列中第一条未合成的消息
+func (o *Overlay) nextPending() *Item {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, item := range o.queue {
+		if !item.Ready {
+			return item
+		}
+	}
+	return nil
+}
+
+func (o *Overlay) removeLocked(target *Item) {
+	for i, item := range o.queue {
+		if item == target {
+			o.queue = append(o.queue[:i], o.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// advanceLocked 在没有正在播放的消息、有页面连接且队首已合成时开始播放队首的消息。
+// 页面报告播放结束或超过音频时长仍未报告时播放下一条
+func (o *Overlay) advanceLocked() {
+	if o.current != nil || len(o.clients) == 0 || len(o.queue) == 0 || !o.queue[0].Ready {
+		return
+	}
+	o.current = o.queue[0]
+	o.queue = o.queue[1:]
+	wait := o.current.duration + playbackGrace
+	if o.current.duration == 0 {
+		wait = unknownDuration
+	}
+	id := o.current.ID
+	o.timer = time.AfterFunc(wait, func() { o.Ended(id) })
+}
+
+// Ended 处理页面报告的播放结束，id 不是正在播放的消息时忽略（多个页面会分别报告）
+func (o *Overlay) Ended(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.current != nil && o.current.ID == id {
+		o.finishLocked()
+	}
+}
+
+// finishLocked 结束正在播放的消息并开始下一条
+func (o *Overlay) finishLocked() {
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	o.current = nil
+	o.advanceLocked()
+	o.broadcastLocked()
+}
+
+// stateLocked 返回队列状态的副本，可以在释放锁之后编码
+func (o *Overlay) stateLocked() State {
+	state := State{Queue: make([]*Item, len(o.queue))}
+	if o.current != nil {
+		current := *o.current
+		state.Current = &current
+	}
+	for i, item := range o.queue {
+		copied := *item
+		state.Queue[i] = &copied
+	}
+	return state
+}
+
+// duration 按 MP3 的比特率估算音频时长，无法识别时返回 0
+func duration(data []byte) time.Duration {
+	format, ok := audio.ProbeMP3(data)
+	if !ok || format.Bitrate == 0 {
+		return 0
+	}
+	return time.Duration(len(data)) * 8 * time.Millisecond / time.Duration(format.Bitrate)
+}
@@ -0,0 +1,163 @@
+package podcast
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// article 是从订阅源中解析出的一篇文章
+type article struct {
+	GUID      string
+	Title     string
+	Link      string
+	Content   string // HTML 正文
+	Published time.Time
+}
+
+// rssItem 是 RSS 2.0 / RSS 1.0 的条目
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+// atomText 是 Atom 的文本结构，type 为 xhtml 时内容是内嵌的 XML 元素
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html 返回文本结构中的 HTML 内容
+func (t atomText) html() string {
+	if t.Type == "xhtml" {
+		return t.Inner
+	}
+	return t.Text
+}
+
+// atomEntry 是 Atom 的条目
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   atomText `xml:"summary"`
+	Content   atomText `xml:"content"`
+	Published string   `xml:"published"`
+	Updated   string   `xml:"updated"`
+}
+
+// document 同时覆盖 RSS 2.0（rss/channel/item）、RSS 1.0（rdf:RDF/item）与 Atom（feed/entry）
+type document struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// dateLayouts 是订阅源中常见的日期格式
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// parseDate 解析日期，无法识别时返回零值
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseFeed 解析 RSS 或 Atom 订阅源，文章按发布时间从新到旧排列
+func parseFeed(r io.Reader) ([]article, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+
+	var doc document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析订阅源失败: %w", err)
+	}
+	if doc.XMLName.Local != "rss" && doc.XMLName.Local != "RDF" && doc.XMLName.Local != "feed" {
+		return nil, fmt.Errorf("不支持的订阅源格式: %s", doc.XMLName.Local)
+	}
+
+	var articles []article
+	for _, item := range append(doc.Channel.Items, doc.Items...) {
+		content := item.Content
+		if content == "" {
+			content = item.Description
+		}
+		published := item.PubDate
+		if published == "" {
+			published = item.Date
+		}
+		articles = append(articles, article{
+			GUID:      firstNonEmpty(item.GUID, item.Link, item.Title),
+			Title:     strings.TrimSpace(item.Title),
+			Link:      strings.TrimSpace(item.Link),
+			Content:   content,
+			Published: parseDate(published),
+		})
+	}
+	for _, entry := range doc.Entries {
+		var link string
+		for _, l := range entry.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				link = l.Href
+				break
+			}
+		}
+		content := entry.Content.html()
+		if strings.TrimSpace(content) == "" {
+			content = entry.Summary.html()
+		}
+		articles = append(articles, article{
+			GUID:      firstNonEmpty(entry.ID, link, entry.Title),
+			Title:     strings.TrimSpace(entry.Title),
+			Link:      link,
+			Content:   content,
+			Published: parseDate(firstNonEmpty(entry.Published, entry.Updated)),
+		})
+	}
+
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].Published.After(articles[j].Published)
+	})
+	return articles, nil
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,285 @@
+// Package podcast 定时抓取 RSS/Atom 订阅源，将新文章读成音频保存到文件存储，
+// 并生成带音频附件的播客 RSS，把订阅变成可以在播客应用中收听的节目。
+package podcast
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/privacy"
+	"tts/internal/ssml"
+	"tts/internal/storage"
+	"tts/internal/store"
+	"tts/internal/utils"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// episodesBucket 是保存节目记录的存储桶
+	episodesBucket = "podcast_episodes"
+	// summaryLength 是节目简介的最大字数
+	summaryLength = 200
+	// maxFeedSize 是订阅源内容的大小上限
+	maxFeedSize = 10 << 20
+)
+
+// unsafeNameChars 匹配订阅源名称中不能用于文件路径的字符
+var unsafeNameChars = regexp.MustCompile(`[^\w.-]+`)
+
+// Episode 是一期已生成的节目
+type Episode struct {
+	ID        string    `json:"id"`
+	Feed      string    `json:"feed"`
+	GUID      string    `json:"guid"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Summary   string    `json:"summary"`
+	Published time.Time `json:"published"`
+	Created   time.Time `json:"created"`
+	File      string    `json:"file"` // 文件存储中的键
+	Size      int       `json:"size"`
+	SHA256    string    `json:"sha256,omitempty"`
+	Duration  int       `json:"duration"` // 估算的时长（秒）
+}
+
+// Podcast 定时检查订阅源并生成节目
+type Podcast struct {
+	synthesizer *ttspkg.Synthesizer
+	store       *store.Store
+	files       *storage.Storage
+	config      *config.Config
+	client      *http.Client
+}
+
+// New 创建播客生成器，检查订阅源配置
+func New(synthesizer *ttspkg.Synthesizer, st *store.Store, files *storage.Storage, cfg *config.Config) (*Podcast, error) {
+	names := make(map[string]bool, len(cfg.Podcast.Feeds))
+	for i, feed := range cfg.Podcast.Feeds {
+		if feed.Name == "" || feed.URL == "" {
+			return nil, fmt.Errorf("podcast.feeds[%d] 必须配置 name 与 url", i)
+		}
+		if names[feed.Name] {
+			return nil, fmt.Errorf("podcast.feeds 中的名称重复: %s", feed.Name)
+		}
+		names[feed.Name] = true
+	}
+	return &Podcast{
+		synthesizer: synthesizer,
+		store:       st,
+		files:       files,
+		config:      cfg,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Run 立即检查一次所有订阅源，之后按 poll_interval 定时检查，直到 ctx 结束
+func (p *Podcast) Run(ctx context.Context) {
+	interval := time.Duration(p.config.Podcast.PollInterval) * time.Minute
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+	log.Printf("播客生成已启动，订阅源数: %d, 检查间隔: %v", len(p.config.Podcast.Feeds), interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, feed := range p.config.Podcast.Feeds {
+			if err := p.pollFeed(ctx, feed); err != nil && ctx.Err() == nil {
+				log.Printf("检查订阅源 %s 失败: %v", feed.Name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollFeed 抓取订阅源，为最新的几篇文章中尚未生成的文章生成节目
+func (p *Podcast) pollFeed(ctx context.Context, feed config.PodcastFeed) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("订阅源返回状态码 %d", resp.StatusCode)
+	}
+	articles, err := parseFeed(http.MaxBytesReader(nil, resp.Body, maxFeedSize))
+	if err != nil {
+		return err
+	}
+
+	// 只处理最新的几篇，避免首次订阅时把全部历史文章读一遍
+	maxItems := feed.MaxItems
+	if maxItems <= 0 {
+		maxItems = 3
+	}
+	if len(articles) > maxItems {
+		articles = articles[:maxItems]
+	}
+
+	created := 0
+	for _, a := range articles {
+		id := episodeID(feed.Name, a.GUID)
+		if found, _ := p.store.Get(episodesBucket, id, &Episode{}); found {
+			continue
+		}
+		if err := p.createEpisode(ctx, feed, id, a); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			log.Printf("生成节目失败: %s / %s: %v", feed.Name, privacy.LogText(ctx, a.Title, 40), err)
+			continue
+		}
+		created++
+	}
+	if created > 0 {
+		p.prune(feed.Name, maxItems)
+	}
+	return nil
+}
+
+// createEpisode 合成文章并保存节目
+func (p *Podcast) createEpisode(ctx context.Context, feed config.PodcastFeed, id string, a article) error {
+	body := utils.HTMLToText(utils.ConvertRuby(a.Content, p.config.SSML.Ruby))
+	text := strings.TrimSpace(a.Title + "\n" + body)
+	if body == "" {
+		return fmt.Errorf("文章没有正文")
+	}
+	if limit := p.config.TTS.MaxTextLength; limit > 0 && utils.GraphemeCount(text) > limit {
+		text = truncate(text, limit)
+	}
+
+	rate := feed.Rate
+	if rate == "" {
+		rate = p.config.TTS.DefaultRate
+	}
+	voice := feed.Voice
+	if voice == "" {
+		voice = p.config.TTS.DefaultVoice
+	}
+
+	start := time.Now()
+	resp, err := p.synthesizer.Synthesize(ctx, models.TTSRequest{
+		Text:  text,
+		Voice: voice,
+		Rate:  rate,
+		Pitch: p.config.TTS.DefaultPitch,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.AudioContent, err = watermark.Stamp(p.config, resp.AudioContent, "podcast"); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("podcast/%s/%s.mp3", unsafeNameChars.ReplaceAllString(feed.Name, "_"), id[strings.IndexByte(id, ':')+1:])
+	if err := p.files.Put(key, resp.AudioContent); err != nil {
+		return fmt.Errorf("保存音频失败: %w", err)
+	}
+
+	published := a.Published
+	if published.IsZero() {
+		published = time.Now()
+	}
+	episode := Episode{
+		ID:        id,
+		Feed:      feed.Name,
+		GUID:      a.GUID,
+		Title:     a.Title,
+		Link:      a.Link,
+		Summary:   truncate(strings.ReplaceAll(body, "\n", " "), summaryLength),
+		Published: published,
+		Created:   time.Now(),
+		File:      key,
+		Size:      len(resp.AudioContent),
+		SHA256:    storage.Checksum(resp.AudioContent),
+		Duration:  int(ssml.EstimateDuration(text, rate, p.config.TTS.EstimatedCharsPerSecond).Seconds()),
+	}
+	if err := p.store.Put(episodesBucket, id, episode); err != nil {
+		return err
+	}
+	log.Printf("已生成节目: %s / %s, 文本长度 %d, 音频大小 %s, 耗时 %v",
+		feed.Name, privacy.LogText(ctx, a.Title, 40), utils.GraphemeCount(text),
+		utils.FormatFileSize(len(resp.AudioContent)), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// prune 删除超出 max_episodes 的旧节目，保留数不少于每次处理的文章数，
+// 以免刚删除的节目在下次检查时被重新生成
+func (p *Podcast) prune(feed string, minKeep int) {
+	keep := p.config.Podcast.MaxEpisodes
+	if keep <= 0 {
+		keep = 50
+	}
+	if keep < minKeep {
+		keep = minKeep
+	}
+
+	var episodes []Episode
+	for _, episode := range Episodes(p.store) {
+		if episode.Feed == feed {
+			episodes = append(episodes, episode)
+		}
+	}
+	if len(episodes) <= keep {
+		return
+	}
+	for _, episode := range episodes[keep:] {
+		if err := p.files.Delete(episode.File); err != nil {
+			log.Printf("删除节目音频失败: %v", err)
+		}
+		if err := p.store.Delete(episodesBucket, episode.ID); err != nil {
+			log.Printf("删除节目记录失败: %v", err)
+		}
+	}
+}
+
+// Episodes 返回所有节目，按发布时间从新到旧排列
+func Episodes(st *store.Store) []Episode {
+	var episodes []Episode
+	for _, key := range st.Keys(episodesBucket) {
+		var episode Episode
+		if found, err := st.Get(episodesBucket, key, &episode); err != nil || !found {
+			continue
+		}
+		episodes = append(episodes, episode)
+	}
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].Published.After(episodes[j].Published)
+	})
+	return episodes
+}
+
+// episodeID 根据订阅源名称与文章 GUID 生成节目ID
+func episodeID(feed, guid string) string {
+	sum := sha1.Sum([]byte(guid))
+	return feed + ":" + hex.EncodeToString(sum[:8])
+}
+
+// truncate 按字素截断文本
+func truncate(text string, limit int) string {
+	end, count := 0, 0
+	for end < len(text) && count < limit {
+		end += utils.NextGrapheme(text[end:])
+		count++
+	}
+	return text[:end]
+}
@@ -0,0 +1,89 @@
+package podcast
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"tts/internal/config"
+)
+
+// rssDocument 是发布的播客 RSS
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Itunes  string     `xml:"xmlns:itunes,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Generator   string       `xml:"generator"`
+	Explicit    string       `xml:"itunes:explicit"`
+	Items       []rssEpisode `xml:"item"`
+}
+
+type rssEpisode struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link,omitempty"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	Description string       `xml:"description"`
+	Enclosure   rssEnclosure `xml:"enclosure"`
+	Duration    int          `xml:"itunes:duration,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// WriteRSS 输出播客 RSS，fileURL 将文件存储中的键转换为音频下载地址
+func WriteRSS(w io.Writer, cfg config.PodcastConfig, link string, episodes []Episode, fileURL func(key string) string) error {
+	title := cfg.Title
+	if title == "" {
+		title = "TTS Podcast"
+	}
+	doc := rssDocument{
+		Version: "2.0",
+		Itunes:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        link,
+			Description: cfg.Description,
+			Generator:   "tts",
+			Explicit:    "false",
+		},
+	}
+	for _, episode := range episodes {
+		doc.Channel.Items = append(doc.Channel.Items, rssEpisode{
+			Title:       fmt.Sprintf("[%s] %s", episode.Feed, episode.Title),
+			Link:        episode.Link,
+			GUID:        rssGUID{Value: episode.ID},
+			PubDate:     episode.Published.UTC().Format(time.RFC1123Z),
+			Description: episode.Summary,
+			Enclosure: rssEnclosure{
+				URL:    fileURL(episode.File),
+				Length: episode.Size,
+				Type:   "audio/mpeg",
+			},
+			Duration: episode.Duration,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(doc)
+}
@@ -0,0 +1,86 @@
+// Package policy 记录 API 密钥对使用条款的确认，用于满足合成语音使用政策的合规要求。
+// 确认记录保存在持久化存储中，只保存密钥名称或密钥的哈希，不保存密钥本身。
+package policy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/store"
+)
+
+// acceptanceBucket 是保存条款确认记录的存储桶
+const acceptanceBucket = "terms_acceptance"
+
+// Acceptance 是一条条款确认记录
+type Acceptance struct {
+	Identity   string    `json:"identity"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	ClientIP   string    `json:"client_ip"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// Identity 返回请求方的标识：keys 中配置的密钥使用其名称，其他密钥使用哈希，
+// 使用 SigV4 签名的请求（Polly 兼容接口）使用访问密钥 ID 的哈希，未携带密钥时为 anonymous
+func Identity(c *gin.Context) string {
+	if profile := apikey.FromContext(c); profile != nil {
+		return "key:" + profile.Name
+	}
+	if key := apikey.Presented(c); key != "" {
+		return "sha256:" + shortHash(key)
+	}
+	if accessKeyID := sigV4AccessKeyID(c.GetHeader("Authorization")); accessKeyID != "" {
+		return "sigv4:" + shortHash(accessKeyID)
+	}
+	return "anonymous"
+}
+
+// shortHash 返回 SHA-256 哈希的前 8 字节的十六进制编码
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// sigV4AccessKeyID 返回 SigV4 签名 Credential 中的访问密钥 ID，不是 SigV4 签名时返回空字符串
+func sigV4AccessKeyID(auth string) string {
+	fields, ok := strings.CutPrefix(auth, "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return ""
+	}
+	for _, part := range strings.Split(fields, ",") {
+		if credential, ok := strings.CutPrefix(strings.TrimSpace(part), "Credential="); ok {
+			accessKeyID, _, _ := strings.Cut(credential, "/")
+			return accessKeyID
+		}
+	}
+	return ""
+}
+
+// Accepted 返回请求方对条款的确认记录，未确认时返回 nil
+func Accepted(st *store.Store, identity string) *Acceptance {
+	var acceptance Acceptance
+	if found, err := st.Get(acceptanceBucket, identity, &acceptance); err != nil || !found {
+		return nil
+	}
+	return &acceptance
+}
+
+// Accept 记录请求方确认了指定版本的条款
+func Accept(st *store.Store, c *gin.Context, version string) (*Acceptance, error) {
+	acceptance := &Acceptance{
+		Identity:   Identity(c),
+		Version:    version,
+		AcceptedAt: time.Now().UTC(),
+		ClientIP:   c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	}
+	if err := st.Put(acceptanceBucket, acceptance.Identity, acceptance); err != nil {
+		return nil, err
+	}
+	return acceptance, nil
+}
@@ -0,0 +1,34 @@
+package policy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sigV4 := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20261015/us-east-1/polly/aws4_request, SignedHeaders=host;x-amz-date, Signature=abc"
+	tests := []struct {
+		name string
+		auth string
+		want string
+	}{
+		{"bearer", "Bearer secret", "sha256:" + shortHash("secret")},
+		{"sigv4", sigV4, "sigv4:" + shortHash("AKIDEXAMPLE")},
+		{"malformed sigv4", "AWS4-HMAC-SHA256 Signature=abc", "anonymous"},
+		{"none", "", "anonymous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/speech", nil)
+			c.Request.Header.Set("Authorization", tt.auth)
+			if got := Identity(c); got != tt.want {
+				t.Errorf("Identity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
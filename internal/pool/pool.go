@@ -0,0 +1,276 @@
+// Package pool 限制同时发往上游服务的请求数。每个服务（按名称）有一个全局共享的并发池，
+// 池满时请求按租户（API 密钥名称）排队，空出的名额在有请求等待的租户之间轮流分配，
+// 避免一个租户的大量分段请求占满名额；上限可以在运行时通过管理接口调整。
+// 启用自适应后，上游限流时生效上限减半，之后每完成一轮请求加一，逐步恢复到配置的上限 (AIMD)。
+package pool
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"tts/internal/metrics"
+)
+
+var (
+	poolActive = metrics.NewGauge("tts_pool_active",
+		"并发池中正在进行的请求数", "pool")
+	poolWaiting = metrics.NewGauge("tts_pool_waiting",
+		"并发池中排队等待的请求数", "pool")
+	poolLimit = metrics.NewGauge("tts_pool_limit",
+		"并发池当前生效的并发上限（自适应时随上游限流变化），0 表示不限制", "pool")
+	poolThrottled = metrics.NewCounter("tts_pool_throttled_total",
+		"上游限流导致并发池降低上限的次数", "pool")
+)
+
+// throttleCooldown 是两次降低上限之间的最短间隔，同一波限流中多个进行中请求的失败只降低一次
+const throttleCooldown = time.Second
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*Pool{}
+)
+
+// Pool 是一个支持按租户公平排队、可以动态调整上限的并发池
+type Pool struct {
+	name string
+
+	mu     sync.Mutex
+	limit  int // 配置的上限，<= 0 表示不限制
+	active int
+	queues map[string][]chan struct{} // 各租户按到达顺序排队的请求
+	order  []string                   // 有请求排队的租户，按轮转顺序
+
+	// 自适应并发：effective 是当前生效的上限，在 [minimum, limit] 之间变化
+	adaptive  bool
+	minimum   int
+	effective int
+	successes int       // 上次调整以来成功的请求数
+	lastCut   time.Time // 上次降低上限的时间
+}
+
+// Stats 是并发池的当前状态
+type Stats struct {
+	Name      string         `json:"name"`
+	Limit     int            `json:"limit"`
+	Effective int            `json:"effective"` // 当前生效的上限，未启用自适应时等于 limit
+	Active    int            `json:"active"`
+	Waiting   int            `json:"waiting"`
+	Tenants   map[string]int `json:"tenants,omitempty"` // 各租户排队的请求数
+}
+
+// Get 返回名为 name 的并发池，不存在时按 limit 创建；同名的池在所有调用方之间共享
+func Get(name string, limit int) *Pool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if p, ok := registry[name]; ok {
+		return p
+	}
+	p := &Pool{name: name, limit: limit, effective: limit, queues: make(map[string][]chan struct{})}
+	registry[name] = p
+	p.updateLocked()
+	return p
+}
+
+// Lookup 返回已创建的并发池
+func Lookup(name string) (*Pool, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	p, ok := registry[name]
+	return p, ok
+}
+
+// All 返回所有并发池的状态，按名称排序
+func All() []Stats {
+	registryMu.Lock()
+	pools := make([]*Pool, 0, len(registry))
+	for _, p := range registry {
+		pools = append(pools, p)
+	}
+	registryMu.Unlock()
+
+	stats := make([]Stats, 0, len(pools))
+	for _, p := range pools {
+		stats = append(stats, p.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Acquire 为租户 tenant 获取一个名额，池满时排队等待，ctx 取消时放弃等待并返回其错误。
+// 成功时返回的 release 必须调用且只能调用一次
+func (p *Pool) Acquire(ctx context.Context, tenant string) (release func(), err error) {
+	p.mu.Lock()
+	if p.effective <= 0 || (p.active < p.effective && len(p.order) == 0) {
+		p.active++
+		p.updateLocked()
+		p.mu.Unlock()
+		return p.release, nil
+	}
+	ready := make(chan struct{}, 1)
+	if len(p.queues[tenant]) == 0 {
+		p.order = append(p.order, tenant)
+	}
+	p.queues[tenant] = append(p.queues[tenant], ready)
+	p.updateLocked()
+	p.mu.Unlock()
+
+	select {
+	case <-ready:
+		return p.release, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		if p.removeLocked(tenant, ready) {
+			p.updateLocked()
+			p.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		p.mu.Unlock()
+		// 取消的同时已经分配到名额，归还给其他请求
+		p.release()
+		return nil, ctx.Err()
+	}
+}
+
+// Resize 调整并发上限，<= 0 表示不限制；调大时立即放行排队的请求，调小时等进行中的请求结束后生效。
+// 启用自适应时生效上限重置为新的上限
+func (p *Pool) Resize(limit int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limit = limit
+	p.effective = limit
+	p.successes = 0
+	p.dispatchLocked()
+	p.updateLocked()
+}
+
+// Adaptive 启用自适应并发，上游限流时生效上限最低降到 minimum（至少为 1）。配置的上限为 0（不限制）时无法自适应
+func (p *Pool) Adaptive(minimum int) {
+	if minimum < 1 {
+		minimum = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.adaptive = true
+	p.minimum = minimum
+}
+
+// Throttled 报告上游返回了限流响应：启用自适应时生效上限减半，不低于最小值
+func (p *Pool) Throttled() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.adaptive || p.limit <= 0 || time.Since(p.lastCut) < throttleCooldown {
+		return
+	}
+	p.lastCut = time.Now()
+	p.successes = 0
+	poolThrottled.Inc(p.name)
+	if next := max(p.effective/2, min(p.minimum, p.limit)); next < p.effective {
+		log.Printf("上游限流，并发池 %s 的上限从 %d 降至 %d", p.name, p.effective, next)
+		p.effective = next
+		p.updateLocked()
+	}
+}
+
+// Succeeded 报告一个请求成功完成：启用自适应且低于配置的上限时，每成功一轮（等于当前上限的请求数）上限加一
+func (p *Pool) Succeeded() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.adaptive || p.effective >= p.limit {
+		return
+	}
+	p.successes++
+	if p.successes < p.effective {
+		return
+	}
+	p.successes = 0
+	p.effective++
+	if p.effective == p.limit {
+		log.Printf("并发池 %s 已恢复到上限 %d", p.name, p.limit)
+	}
+	p.dispatchLocked()
+	p.updateLocked()
+}
+
+// Stats 返回并发池的当前状态
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := Stats{Name: p.name, Limit: p.limit, Effective: p.effective, Active: p.active}
+	for tenant, queue := range p.queues {
+		if len(queue) == 0 {
+			continue
+		}
+		if stats.Tenants == nil {
+			stats.Tenants = make(map[string]int)
+		}
+		stats.Tenants[tenant] = len(queue)
+		stats.Waiting += len(queue)
+	}
+	return stats
+}
+
+// release 归还一个名额并放行排队的请求
+func (p *Pool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active--
+	p.dispatchLocked()
+	p.updateLocked()
+}
+
+// dispatchLocked 在有空闲名额时按租户轮转放行排队的请求，每个租户每轮放行一个
+func (p *Pool) dispatchLocked() {
+	for len(p.order) > 0 && (p.effective <= 0 || p.active < p.effective) {
+		tenant := p.order[0]
+		queue := p.queues[tenant]
+		ready := queue[0]
+		if len(queue) == 1 {
+			delete(p.queues, tenant)
+			p.order = p.order[1:]
+		} else {
+			p.queues[tenant] = queue[1:]
+			// 放行后移到队尾，下一个名额给其他租户
+			p.order = append(p.order[1:], tenant)
+		}
+		p.active++
+		ready <- struct{}{}
+	}
+}
+
+// removeLocked 把放弃等待的请求移出队列，请求已被放行时返回 false
+func (p *Pool) removeLocked(tenant string, ready chan struct{}) bool {
+	queue := p.queues[tenant]
+	for i, waiter := range queue {
+		if waiter != ready {
+			continue
+		}
+		queue = append(queue[:i:i], queue[i+1:]...)
+		if len(queue) > 0 {
+			p.queues[tenant] = queue
+			return true
+		}
+		delete(p.queues, tenant)
+		for j, name := range p.order {
+			if name == tenant {
+				p.order = append(p.order[:j:j], p.order[j+1:]...)
+				break
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// updateLocked 更新指标
+func (p *Pool) updateLocked() {
+	waiting := 0
+	for _, queue := range p.queues {
+		waiting += len(queue)
+	}
+	poolActive.Set(float64(p.active), p.name)
+	poolLimit.Set(float64(p.effective), p.name)
+	poolWaiting.Set(float64(waiting), p.name)
+}
@@ -0,0 +1,122 @@
+// Package privacy 实现隐私模式：开启后输入文本不会写入日志或录制夹具，
+// 缓存键使用加盐哈希，记录中只保留字符数。可全局开启，也可按 API 密钥开启。
+package privacy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/config"
+	"tts/internal/utils"
+)
+
+// contextKey 是标记请求处于隐私模式的上下文键
+type contextKey struct{}
+
+var (
+	mu     sync.RWMutex
+	global bool
+	salt   []byte
+)
+
+// Configure 根据配置设置全局隐私模式与缓存键使用的盐，应在创建其他组件之前调用。
+// 只要有任何请求可能处于隐私模式，缓存键就会加盐；未配置盐时每次启动随机生成。
+func Configure(cfg *config.Config) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	global = cfg.Privacy.Enabled
+	salt = nil
+	if !global && !AnyKey(cfg) {
+		return
+	}
+	if cfg.Privacy.Salt != "" {
+		salt = []byte(cfg.Privacy.Salt)
+	} else {
+		salt = make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			log.Printf("生成缓存键盐失败: %v", err)
+		}
+		log.Println("隐私模式未配置 privacy.salt，使用随机盐，磁盘缓存在重启后不会命中")
+	}
+	if global {
+		log.Println("隐私模式已全局开启，输入文本不会写入日志")
+	}
+}
+
+// AnyKey 判断是否有密钥单独设置了隐私模式
+func AnyKey(cfg *config.Config) bool {
+	for _, key := range cfg.Keys {
+		if key.Privacy != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Global 返回是否全局开启了隐私模式
+func Global() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return global
+}
+
+// ForKey 返回使用该密钥的请求是否处于隐私模式，密钥未单独设置时使用 privacy.enabled
+func ForKey(cfg *config.Config, profile *config.APIKey) bool {
+	if profile != nil && profile.Privacy != nil {
+		return *profile.Privacy
+	}
+	return cfg.Privacy.Enabled
+}
+
+// NewContext 返回标记了是否处于隐私模式的上下文，优先于全局设置
+func NewContext(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, contextKey{}, enabled)
+}
+
+// Enabled 返回上下文所属的请求是否处于隐私模式
+func Enabled(ctx context.Context) bool {
+	if ctx != nil {
+		if on, ok := ctx.Value(contextKey{}).(bool); ok {
+			return on
+		}
+	}
+	return Global()
+}
+
+// Middleware 返回按请求密钥标记隐私模式的中间件，需放在 api_keys 之后
+func Middleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), ForKey(cfg, apikey.FromContext(c))))
+		c.Next()
+	}
+}
+
+// LogText 返回可写入日志的文本：隐私模式下只输出字符数，否则截断到 maxLength
+func LogText(ctx context.Context, text string, maxLength int) string {
+	if Enabled(ctx) {
+		return fmt.Sprintf("[已隐藏 %d 字]", utils.GraphemeCount(text))
+	}
+	return utils.TruncateForLog(text, maxLength)
+}
+
+// Salt 返回缓存键使用的盐，未开启隐私模式时返回 nil
+func Salt() []byte {
+	mu.RLock()
+	defer mu.RUnlock()
+	return salt
+}
+
+// Hash 使用盐计算 HMAC-SHA256，用于替代直接对文本求哈希
+func Hash(data []byte) []byte {
+	mac := hmac.New(sha256.New, Salt())
+	mac.Write(data)
+	return mac.Sum(nil)
+}
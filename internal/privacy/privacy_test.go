@@ -0,0 +1,200 @@
+package privacy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"tts/internal/cache"
+	"tts/internal/config"
+	"tts/internal/http/middleware"
+	"tts/internal/models"
+	"tts/internal/privacy"
+	"tts/internal/vcr"
+	ttspkg "tts/pkg/tts"
+)
+
+// secret 出现在请求文本的每一句中，隐私模式下不应出现在任何输出里
+const secret = "绝密口令"
+
+var secretText = strings.Repeat(secret+"是蓝色海豚。", 15)
+
+// upstreamProvider 把文本作为请求体经 VCR 传输层发给上游，模拟调用语音合成服务
+type upstreamProvider struct {
+	client *http.Client
+	url    string
+
+	mu   sync.Mutex
+	keys []string // 各次合成的缓存键
+}
+
+func (p *upstreamProvider) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return nil, nil
+}
+
+func (p *upstreamProvider) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	p.mu.Lock()
+	p.keys = append(p.keys, cache.Key(req.Text, req.Voice))
+	p.mu.Unlock()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, strings.NewReader(req.Text))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	audio, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TTSResponse{AudioContent: audio, ContentType: "audio/mpeg"}, nil
+}
+
+// setup 按密钥开启隐私模式（全局关闭），返回捕获的日志、合成服务与夹具目录
+func setup(t *testing.T) (*bytes.Buffer, *upstreamProvider, string) {
+	t.Helper()
+	on := true
+	privacy.Configure(&config.Config{
+		Privacy: config.PrivacyConfig{Salt: "test-salt"},
+		Keys:    []config.APIKey{{Name: "private", Privacy: &on}},
+	})
+	t.Cleanup(func() { privacy.Configure(&config.Config{}) })
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write([]byte{0xFF, 0xF3, 0x44, 0xC4})
+	}))
+	t.Cleanup(upstream.Close)
+
+	dir := t.TempDir()
+	transport, err := vcr.New(vcr.ModeRecord, dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("vcr.New: %v", err)
+	}
+	return &buf, &upstreamProvider{client: &http.Client{Transport: transport}, url: upstream.URL}, dir
+}
+
+// serve 经请求日志中间件处理一个合成请求，private 决定请求是否标记为隐私模式
+func serve(t *testing.T, provider *upstreamProvider, private bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Logging: config.LoggingConfig{Level: "debug", Body: true}}
+	synthesizer := ttspkg.NewSynthesizer(provider, ttspkg.NewSegmenter(&ttspkg.TTSConfig{
+		SegmentThreshold:  100,
+		MinSentenceLength: 1,
+		MaxSentenceLength: 40,
+	}), 2)
+
+	r := gin.New()
+	r.Use(middleware.Logger(cfg), func(c *gin.Context) {
+		c.Request = c.Request.WithContext(privacy.NewContext(c.Request.Context(), private))
+		c.Next()
+	})
+	r.POST("/tts", func(c *gin.Context) {
+		text, _ := io.ReadAll(c.Request.Body)
+		// 分段结果表在合并音频之前写入日志，测试环境没有 ffmpeg 导致合并失败不影响检查
+		resp, err := synthesizer.Synthesize(c.Request.Context(), models.TTSRequest{Text: string(text), Voice: "zh-CN-XiaoxiaoNeural"})
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, resp.ContentType, resp.AudioContent)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/tts", strings.NewReader(secretText)))
+}
+
+// fixtures 返回目录中全部夹具文件的内容
+func fixtures(t *testing.T, dir string) string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("没有录制夹具: %v", err)
+	}
+	var sb strings.Builder
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb.Write(data)
+	}
+	return sb.String()
+}
+
+func TestPrivacyHidesText(t *testing.T) {
+	logs, provider, dir := setup(t)
+	serve(t, provider, true)
+
+	if strings.Contains(logs.String(), secret) {
+		t.Errorf("日志包含请求文本:\n%s", logs.String())
+	}
+	// 确认日志确实经过了会输出文本的位置：请求体与分段结果表
+	for _, want := range []string{"[隐私模式]", "[已隐藏"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("日志中没有 %q:\n%s", want, logs.String())
+		}
+	}
+
+	if len(provider.keys) < 2 {
+		t.Fatalf("文本没有分段合成，缓存键 %d 个", len(provider.keys))
+	}
+	for _, key := range provider.keys {
+		if strings.Contains(key, secret) {
+			t.Errorf("缓存键包含请求文本: %s", key)
+		}
+	}
+	// 加盐后不能通过对猜测的文本求哈希得到缓存键
+	sum := sha256.Sum256([]byte(secret + "是蓝色海豚。\x00zh-CN-XiaoxiaoNeural"))
+	for _, key := range provider.keys {
+		if key == hex.EncodeToString(sum[:16]) {
+			t.Errorf("缓存键未加盐: %s", key)
+		}
+	}
+
+	if content := fixtures(t, dir); strings.Contains(content, secret) {
+		t.Errorf("夹具包含请求文本:\n%s", content)
+	}
+}
+
+// TestPrivacyOffKeepsText 对照：未标记隐私模式的请求会把文本写入日志与夹具，说明上面的检查是有效的
+func TestPrivacyOffKeepsText(t *testing.T) {
+	logs, provider, dir := setup(t)
+	serve(t, provider, false)
+
+	if !strings.Contains(logs.String(), secret) {
+		t.Errorf("日志中没有请求文本:\n%s", logs.String())
+	}
+	if !strings.Contains(fixtures(t, dir), secret) {
+		t.Error("夹具中没有请求文本")
+	}
+}
+
+func TestLogText(t *testing.T) {
+	ctx := privacy.NewContext(context.Background(), true)
+	if got := privacy.LogText(ctx, secretText, 20); strings.Contains(got, secret) || got != "[已隐藏 150 字]" {
+		t.Errorf("LogText = %q", got)
+	}
+	ctx = privacy.NewContext(context.Background(), false)
+	if got := privacy.LogText(ctx, secretText, 20); !strings.HasPrefix(got, secret) {
+		t.Errorf("LogText = %q", got)
+	}
+}
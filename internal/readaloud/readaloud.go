@@ -0,0 +1,258 @@
+// Package readaloud 管理浏览器扩展的朗读会话：扩展把页面文本按顺序分段提交到会话中，
+// 每段有独立的音频地址与在页面中的位置，可以按位置跳转，也可以重新朗读之前的分段。
+// 位置按 UTF-16 码元计算，与 JavaScript 字符串的下标一致，扩展可以直接用来高亮页面中的文本。
+package readaloud
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"tts/internal/apperr"
+	"tts/internal/models"
+)
+
+const (
+	// defaultTTL 未配置时会话的空闲过期时间
+	defaultTTL = 60 * time.Minute
+	// defaultMaxSessions 未配置时最多同时保存的会话数
+	defaultMaxSessions = 1000
+	// defaultMaxChunks 未配置时每个会话最多保存的分段数
+	defaultMaxChunks = 2000
+)
+
+// sentenceEnds 是判断句子结束的标点，与 session 包一致
+const sentenceEnds = "。！？；…!?;\n"
+
+// Store 是线程安全的朗读会话存储，会话只保存在内存中，重启后丢失
+type Store struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxSessions int
+	maxChunks   int
+	sessions    map[string]*session
+}
+
+// session 是一个朗读会话
+type session struct {
+	settings models.ReadSettings
+	chunks   []chunk
+	updated  time.Time
+}
+
+// chunk 是会话中的一段文本
+type chunk struct {
+	text   string
+	offset int // 在页面文本中的起始位置（UTF-16 码元）
+	length int // 长度（UTF-16 码元）
+}
+
+// New 创建朗读会话存储，ttl 为会话的空闲过期时间
+func New(ttl time.Duration, maxSessions, maxChunks int) *Store {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+	if maxChunks <= 0 {
+		maxChunks = defaultMaxChunks
+	}
+	return &Store{ttl: ttl, maxSessions: maxSessions, maxChunks: maxChunks, sessions: make(map[string]*session)}
+}
+
+// Create 创建会话，已有同名会话时覆盖
+func (s *Store) Create(id string, settings models.ReadSettings) models.ReadSessionResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok && len(s.sessions) >= s.maxSessions {
+		s.evictLocked()
+	}
+	sess := &session{settings: settings, updated: time.Now()}
+	s.sessions[id] = sess
+	return s.responseLocked(sess)
+}
+
+// Get 返回会话的设置与分段，会话不存在或已过期时返回 false
+func (s *Store) Get(id string) (models.ReadSessionResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.liveLocked(id)
+	if !ok {
+		return models.ReadSessionResponse{}, false
+	}
+	return s.responseLocked(sess), true
+}
+
+// Put 从序号 start 开始保存分段，start 小于 0 时追加在已有分段之后；
+// start 小于已有分段数时替换该序号及之后的分段（如页面内容发生了变化），大于已有分段数时返回错误。
+// 返回本次保存的分段与页面文本的总长度
+func (s *Store) Put(id string, start int, texts []string) ([]models.ReadChunk, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.liveLocked(id)
+	if !ok {
+		return nil, 0, errNotFound
+	}
+	if start < 0 {
+		start = len(sess.chunks)
+	}
+	if start > len(sess.chunks) {
+		return nil, 0, apperr.Newf(apperr.CodeConflict, "分段需要按顺序提交，下一段的序号应为 %d", len(sess.chunks))
+	}
+	if start+len(texts) > s.maxChunks {
+		return nil, 0, apperr.Newf(apperr.CodeInvalidRequest, "每个会话最多 %d 段", s.maxChunks)
+	}
+
+	chunks := sess.chunks[:start:start]
+	offset := 0
+	if start > 0 {
+		last := chunks[start-1]
+		offset = last.offset + last.length
+	}
+	result := make([]models.ReadChunk, 0, len(texts))
+	for i, text := range texts {
+		c := chunk{text: text, offset: offset, length: utf16Len(text)}
+		chunks = append(chunks, c)
+		result = append(result, models.ReadChunk{Index: start + i, Offset: c.offset, Length: c.length})
+		offset += c.length
+	}
+	sess.chunks = chunks
+	sess.updated = time.Now()
+	return result, offset, nil
+}
+
+// Chunk 返回分段的文本与会话的设置，并顺延会话的过期时间
+func (s *Store) Chunk(id string, index int) (string, models.ReadSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.liveLocked(id)
+	if !ok {
+		return "", models.ReadSettings{}, errNotFound
+	}
+	if index < 0 || index >= len(sess.chunks) {
+		return "", models.ReadSettings{}, apperr.Newf(apperr.CodeNotFound, "分段 %d 不存在，会话共 %d 段", index, len(sess.chunks))
+	}
+	sess.updated = time.Now()
+	return sess.chunks[index].text, sess.settings, nil
+}
+
+// Seek 返回页面位置 offset 所在的分段，以及分段内该位置所在句子的开头
+func (s *Store) Seek(id string, offset int) (models.ReadChunk, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.liveLocked(id)
+	if !ok {
+		return models.ReadChunk{}, 0, errNotFound
+	}
+	for i, c := range sess.chunks {
+		if offset >= c.offset && offset < c.offset+c.length {
+			sess.updated = time.Now()
+			_, from := SentenceStart(c.text, offset-c.offset)
+			return models.ReadChunk{Index: i, Offset: c.offset, Length: c.length}, from, nil
+		}
+	}
+	return models.ReadChunk{}, 0, apperr.Newf(apperr.CodeInvalidRequest, "位置 %d 超出已提交的文本", offset)
+}
+
+// Delete 删除会话，会话不存在时返回 false
+func (s *Store) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.liveLocked(id)
+	delete(s.sessions, id)
+	return ok
+}
+
+var errNotFound = apperr.New(apperr.CodeNotFound, "朗读会话不存在或已过期")
+
+// liveLocked 返回未过期的会话，过期的会话会被删除
+func (s *Store) liveLocked(id string) (*session, bool) {
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(sess.updated) >= s.ttl {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	return sess, true
+}
+
+// evictLocked 删除过期的会话，仍然超过上限时淘汰最久未使用的会话
+func (s *Store) evictLocked() {
+	var oldestID string
+	var oldest time.Time
+	for id, sess := range s.sessions {
+		if time.Since(sess.updated) >= s.ttl {
+			delete(s.sessions, id)
+			continue
+		}
+		if oldestID == "" || sess.updated.Before(oldest) {
+			oldestID, oldest = id, sess.updated
+		}
+	}
+	if len(s.sessions) >= s.maxSessions && oldestID != "" {
+		delete(s.sessions, oldestID)
+	}
+}
+
+// responseLocked 生成会话的查询结果，不含会话标识与音频地址
+func (s *Store) responseLocked(sess *session) models.ReadSessionResponse {
+	resp := models.ReadSessionResponse{
+		Settings:  sess.settings,
+		Chunks:    make([]models.ReadChunk, len(sess.chunks)),
+		ExpiresAt: sess.updated.Add(s.ttl),
+	}
+	for i, c := range sess.chunks {
+		resp.Chunks[i] = models.ReadChunk{Index: i, Offset: c.offset, Length: c.length}
+		resp.Length = c.offset + c.length
+	}
+	return resp
+}
+
+// SentenceStart 返回 text 中位置 from（UTF-16 码元）所在句子开头的字节位置与 UTF-16 位置，
+// 跳转到句子中间时从句首开始朗读，避免读出半句话
+func SentenceStart(text string, from int) (int, int) {
+	start, startUnits := 0, 0
+	units := 0
+	for i, r := range text {
+		if units >= from {
+			break
+		}
+		units += runeUnits(r)
+		if strings.ContainsRune(sentenceEnds, r) || r == '.' && i+1 < len(text) && (text[i+1] == ' ' || text[i+1] == '\n') {
+			start, startUnits = i+utf8.RuneLen(r), units
+		}
+	}
+	// 跳过句首的空白
+	for start < len(text) && (text[start] == ' ' || text[start] == '\n' || text[start] == '\t') {
+		start++
+		startUnits++
+	}
+	return start, startUnits
+}
+
+// utf16Len 返回 text 的 UTF-16 码元数
+func utf16Len(text string) int {
+	n := 0
+	for _, r := range text {
+		n += runeUnits(r)
+	}
+	return n
+}
+
+// runeUnits 返回字符的 UTF-16 码元数
+func runeUnits(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
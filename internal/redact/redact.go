@@ -0,0 +1,145 @@
+// Package redact 在合成前识别并替换文本中的个人信息，
+// 如电话号码、邮箱、身份证号与银行卡号，适用于朗读客服通话记录等场景。
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"tts/internal/config"
+)
+
+// detector 是一种个人信息的识别规则
+type detector struct {
+	name        string
+	regex       *regexp.Regexp
+	valid       func(match string) bool // 为 nil 时正则匹配即视为命中
+	replacement string
+}
+
+// builtin 是内置的识别规则，按顺序依次替换：
+// 身份证号与银行卡号先于电话号码，避免长数字串被部分识别为电话
+var builtin = []struct {
+	name        string
+	pattern     string
+	valid       func(string) bool
+	replacement string
+}{
+	{"email", `[\w.+-]+@[\w-]+(?:\.[\w-]+)+`, nil, "邮箱已省略"},
+	{"id_card", `\b\d{6}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`, validIDCard, "证件号已省略"},
+	{"bank_card", `\b\d(?:[ -]?\d){12,18}\b`, validBankCard, "卡号已省略"},
+	{"phone", `(?:(?:\+|00)86[ -]?|\b)1[3-9]\d(?:[ -]?\d{4}){2}\b|\b0\d{2,3}-\d{7,8}\b|\+\d{1,3}(?:[ -]?\d){6,14}\b`, nil, "电话号码已省略"},
+}
+
+// Redactor 按配置替换文本中的个人信息
+type Redactor struct {
+	detectors []detector
+}
+
+// New 根据配置创建 Redactor，types 为空时启用所有内置规则
+func New(cfg config.RedactConfig) (*Redactor, error) {
+	enabled := make(map[string]bool, len(cfg.Types))
+	for _, t := range cfg.Types {
+		enabled[strings.ToLower(t)] = true
+	}
+	known := make(map[string]bool, len(builtin))
+
+	r := &Redactor{}
+	for _, b := range builtin {
+		known[b.name] = true
+		if len(enabled) > 0 && !enabled[b.name] {
+			continue
+		}
+		replacement := b.replacement
+		// viper 会把映射的键转为小写
+		if custom, ok := cfg.Replacements[b.name]; ok {
+			replacement = custom
+		}
+		r.detectors = append(r.detectors, detector{
+			name:        b.name,
+			regex:       regexp.MustCompile(b.pattern),
+			valid:       b.valid,
+			replacement: replacement,
+		})
+	}
+	for t := range enabled {
+		if !known[t] {
+			return nil, fmt.Errorf("redact.types 包含未知的类型: %s", t)
+		}
+	}
+
+	for i, p := range cfg.Patterns {
+		if p.Name == "" || p.Pattern == "" {
+			return nil, fmt.Errorf("redact.patterns 第 %d 项缺少名称或模式", i+1)
+		}
+		regex, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("编译 redact.patterns '%s' 失败: %w", p.Name, err)
+		}
+		if regex.MatchString("") {
+			return nil, fmt.Errorf("redact.patterns '%s' 的模式不能匹配空字符串", p.Name)
+		}
+		r.detectors = append(r.detectors, detector{name: p.Name, regex: regex, replacement: p.Replacement})
+	}
+	return r, nil
+}
+
+// Redact 返回替换个人信息后的文本与各类型的替换次数
+func (r *Redactor) Redact(text string) (string, map[string]int) {
+	var counts map[string]int
+	for _, d := range r.detectors {
+		text = d.regex.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			if counts == nil {
+				counts = make(map[string]int)
+			}
+			counts[d.name]++
+			return d.replacement
+		})
+	}
+	return text, counts
+}
+
+// digits 去掉分隔符，只保留数字与末尾的校验字符
+func digits(s string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(s)
+}
+
+// validIDCard 按 GB 11643 校验18位身份证号的校验码
+func validIDCard(s string) bool {
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	const checks = "10X98765432"
+	sum := 0
+	for i, w := range weights {
+		sum += int(s[i]-'0') * w
+	}
+	return strings.ToUpper(s[17:]) == string(checks[sum%11])
+}
+
+// mobileWithPrefix 匹配带国家代码的手机号，它们可能恰好通过 Luhn 校验
+var mobileWithPrefix = regexp.MustCompile(`^(?:00)?861[3-9]\d{9}$`)
+
+// validBankCard 使用 Luhn 算法校验银行卡号，避免把普通的长数字误判为卡号
+func validBankCard(s string) bool {
+	s = digits(s)
+	if len(s) < 13 || len(s) > 19 || mobileWithPrefix.MatchString(s) {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		d := int(s[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,417 @@
+// Package schedule 按 cron 表达式定时执行合成任务，例如每天早上 7 点抓取天气接口并生成播报音频。
+// 每次执行的结果保存在持久化存储中，可通过管理接口查看。
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/notify"
+	"tts/internal/storage"
+	"tts/internal/store"
+	"tts/internal/utils"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// historyBucket 是保存执行记录的存储桶
+	historyBucket = "schedule_history"
+	// maxFetchSize 是抓取内容的大小上限
+	maxFetchSize = 1 << 20
+)
+
+// unsafeNameChars 匹配任务名称中不能用于文件路径的字符
+var unsafeNameChars = regexp.MustCompile(`[^\w.-]+`)
+
+// Run 是一次任务执行的记录
+type Run struct {
+	ID         string    `json:"id"`
+	Job        string    `json:"job"`
+	Trigger    string    `json:"trigger"` // schedule 或 manual
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Status     string    `json:"status"` // success 或 failed
+	Error      string    `json:"error,omitempty"`
+	TextLength int       `json:"text_length,omitempty"`
+	File       string    `json:"file,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	Size       int       `json:"size,omitempty"`
+	SHA256     string    `json:"sha256,omitempty"`
+	ContentURL string    `json:"content_url,omitempty"` // 按内容哈希访问的地址，启用 storage.content_urls 时提供
+}
+
+// JobStatus 是任务的当前状态
+type JobStatus struct {
+	Name    string    `json:"name"`
+	Cron    string    `json:"cron"`
+	NextRun time.Time `json:"next_run"`
+	Running bool      `json:"running"`
+	LastRun *Run      `json:"last_run,omitempty"`
+}
+
+// job 是解析后的任务
+type job struct {
+	config.ScheduledJob
+	cron     *Cron
+	template *template.Template
+}
+
+// Scheduler 定时执行合成任务
+type Scheduler struct {
+	synthesizer *ttspkg.Synthesizer
+	store       *store.Store
+	files       *storage.Storage
+	config      *config.Config
+	client      *http.Client
+	location    *time.Location
+	notifier    *notify.Notifier
+	jobs        []*job
+
+	mu      sync.Mutex
+	running map[string]bool
+	next    map[string]time.Time
+}
+
+// New 创建定时任务调度器，解析所有任务的 cron 表达式与文本模板
+func New(synthesizer *ttspkg.Synthesizer, st *store.Store, files *storage.Storage, notifier *notify.Notifier, cfg *config.Config) (*Scheduler, error) {
+	location := time.Local
+	if cfg.Schedule.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Schedule.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时区 %q: %w", cfg.Schedule.Timezone, err)
+		}
+		location = loc
+	}
+
+	s := &Scheduler{
+		synthesizer: synthesizer,
+		store:       st,
+		files:       files,
+		config:      cfg,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		location:    location,
+		notifier:    notifier,
+		running:     map[string]bool{},
+		next:        map[string]time.Time{},
+	}
+	names := map[string]bool{}
+	for i, jc := range cfg.Schedule.Jobs {
+		if jc.Name == "" {
+			return nil, fmt.Errorf("schedule.jobs[%d] 必须配置 name", i)
+		}
+		if names[jc.Name] {
+			return nil, fmt.Errorf("schedule.jobs 中的名称重复: %s", jc.Name)
+		}
+		names[jc.Name] = true
+		if jc.Text == "" && jc.URL == "" {
+			return nil, fmt.Errorf("任务 %s 必须配置 text 或 url", jc.Name)
+		}
+		cron, err := ParseCron(jc.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("任务 %s: %w", jc.Name, err)
+		}
+		j := &job{ScheduledJob: jc, cron: cron}
+		if jc.Text != "" {
+			if j.template, err = template.New(jc.Name).Parse(jc.Text); err != nil {
+				return nil, fmt.Errorf("任务 %s 的文本模板无效: %w", jc.Name, err)
+			}
+		}
+		s.jobs = append(s.jobs, j)
+	}
+	return s, nil
+}
+
+// Run 为每个任务启动定时循环，直到 ctx 结束
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("定时任务已启动，任务数: %d, 时区: %s", len(s.jobs), s.location)
+	for _, j := range s.jobs {
+		go s.loop(ctx, j)
+	}
+}
+
+// loop 等待任务的下一个触发时间并执行
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	for {
+		next := j.cron.Next(time.Now().In(s.location))
+		if next.IsZero() {
+			log.Printf("任务 %s 没有下一次执行时间", j.Name)
+			return
+		}
+		s.mu.Lock()
+		s.next[j.Name] = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if _, err := s.execute(ctx, j, "schedule"); err != nil {
+			log.Printf("定时任务 %s 执行失败: %v", j.Name, err)
+		}
+	}
+}
+
+// Trigger 立即执行指定任务，返回执行记录
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*Run, error) {
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return s.execute(ctx, j, "manual")
+		}
+	}
+	return nil, apperr.Newf(apperr.CodeNotFound, "任务不存在: %s", name)
+}
+
+// Jobs 返回所有任务的状态
+func (s *Scheduler) Jobs() []JobStatus {
+	last := map[string]*Run{}
+	for _, run := range s.History("", 0) {
+		if _, ok := last[run.Job]; !ok {
+			run := run
+			last[run.Job] = &run
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, JobStatus{
+			Name:    j.Name,
+			Cron:    j.Cron,
+			NextRun: s.next[j.Name],
+			Running: s.running[j.Name],
+			LastRun: last[j.Name],
+		})
+	}
+	return statuses
+}
+
+// History 返回执行记录，按开始时间从新到旧排列。job 为空时返回所有任务的记录，limit 为 0 时不限制数量
+func (s *Scheduler) History(jobName string, limit int) []Run {
+	var runs []Run
+	for _, key := range s.store.Keys(historyBucket) {
+		var run Run
+		if found, err := s.store.Get(historyBucket, key, &run); err != nil || !found {
+			continue
+		}
+		if jobName == "" || run.Job == jobName {
+			runs = append(runs, run)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Started.After(runs[j].Started) })
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs
+}
+
+// execute 执行一次任务并保存执行记录，同一任务不会并发执行
+func (s *Scheduler) execute(ctx context.Context, j *job, trigger string) (*Run, error) {
+	s.mu.Lock()
+	if s.running[j.Name] {
+		s.mu.Unlock()
+		return nil, apperr.Newf(apperr.CodeConflict, "任务 %s 正在执行", j.Name)
+	}
+	s.running[j.Name] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, j.Name)
+		s.mu.Unlock()
+	}()
+
+	run := &Run{
+		Job:     j.Name,
+		Trigger: trigger,
+		Started: time.Now(),
+	}
+	run.ID = fmt.Sprintf("%s-%s", run.Started.UTC().Format("20060102T150405.000"), j.Name)
+
+	err := s.synthesize(ctx, j, run)
+	run.Finished = time.Now()
+	run.Status = "success"
+	if err != nil {
+		run.Status = "failed"
+		run.Error = err.Error()
+	} else {
+		log.Printf("定时任务 %s 执行完成, 文本长度 %d, 音频大小 %s, 耗时 %v",
+			j.Name, run.TextLength, utils.FormatFileSize(run.Size), run.Finished.Sub(run.Started).Round(time.Millisecond))
+	}
+
+	if err := s.store.Put(historyBucket, run.ID, run); err != nil {
+		log.Printf("保存任务执行记录失败: %v", err)
+	}
+	s.pruneHistory()
+	s.notify(j, run)
+	return run, err
+}
+
+// notify 发送任务完成或失败的通知
+func (s *Scheduler) notify(j *job, run *Run) {
+	event := notify.Event{
+		Kind:     "schedule",
+		Name:     j.Name,
+		Status:   run.Status,
+		Error:    run.Error,
+		Started:  run.Started,
+		Finished: run.Finished,
+	}
+	if run.Status == "success" {
+		event.Summary = fmt.Sprintf("文本长度 %d, 音频大小 %s", run.TextLength, utils.FormatFileSize(run.Size))
+		// 未配置 storage.base_url 时只有相对路径，通知中无法打开，不附带
+		event.URL = run.ContentURL
+		if event.URL == "" {
+			event.URL = run.URL
+		}
+		if !strings.HasPrefix(event.URL, "http") {
+			event.URL = ""
+		}
+	}
+	s.notifier.Notify(j.Notify, event)
+}
+
+// synthesize 生成任务文本、合成语音并保存音频
+func (s *Scheduler) synthesize(ctx context.Context, j *job, run *Run) error {
+	text, err := s.render(ctx, j)
+	if err != nil {
+		return err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("生成的文本为空")
+	}
+	run.TextLength = utils.GraphemeCount(text)
+
+	voice := j.Voice
+	if voice == "" {
+		voice = s.config.TTS.DefaultVoice
+	}
+	rate := j.Rate
+	if rate == "" {
+		rate = s.config.TTS.DefaultRate
+	}
+	resp, err := s.synthesizer.Synthesize(ctx, models.TTSRequest{
+		Text:  text,
+		Voice: voice,
+		Rate:  rate,
+		Pitch: s.config.TTS.DefaultPitch,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.AudioContent, err = watermark.Stamp(s.config, resp.AudioContent, "schedule"); err != nil {
+		return err
+	}
+
+	// 同时保存带时间戳的文件与 latest.mp3，后者便于音箱等设备固定地址播放
+	dir := "schedule/" + unsafeNameChars.ReplaceAllString(j.Name, "_")
+	run.File = fmt.Sprintf("%s/%s.mp3", dir, run.Started.In(s.location).Format("20060102-150405"))
+	if err := s.files.Put(run.File, resp.AudioContent); err != nil {
+		return fmt.Errorf("保存音频失败: %w", err)
+	}
+	if err := s.files.Put(dir+"/latest.mp3", resp.AudioContent); err != nil {
+		return fmt.Errorf("保存音频失败: %w", err)
+	}
+	run.URL = s.files.URL(run.File)
+	run.Size = len(resp.AudioContent)
+	run.SHA256 = storage.Checksum(resp.AudioContent)
+	if s.config.Storage.ContentURLs {
+		run.ContentURL = s.files.ContentURL(run.File, run.SHA256)
+	}
+	return nil
+}
+
+// render 抓取任务的 URL 并渲染文本模板。模板中可使用 .Now 与 .Data，
+// .Data 在返回 JSON 时为解析后的对象，否则为纯文本；未配置模板时直接朗读抓取的文本
+func (s *Scheduler) render(ctx context.Context, j *job) (string, error) {
+	data := map[string]any{"Now": time.Now().In(s.location)}
+	if j.URL != "" {
+		fetched, err := s.fetch(ctx, j.URL)
+		if err != nil {
+			return "", err
+		}
+		data["Data"] = fetched
+		if j.template == nil {
+			text, ok := fetched.(string)
+			if !ok {
+				return "", fmt.Errorf("URL 返回了 JSON，需要配置 text 模板")
+			}
+			return text, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := j.template.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染文本模板失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// fetch 抓取 URL 内容，JSON 会被解析，HTML 会被转换为纯文本
+func (s *Scheduler) fetch(ctx context.Context, url string) (any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("抓取 URL 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("抓取 URL 返回状态码 %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取 URL 内容失败: %w", err)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	switch {
+	case strings.Contains(contentType, "json"):
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil, fmt.Errorf("解析 JSON 失败: %w", err)
+		}
+		return v, nil
+	case strings.Contains(contentType, "html"):
+		return utils.HTMLToText(utils.ConvertRuby(string(body), s.config.SSML.Ruby)), nil
+	default:
+		return string(body), nil
+	}
+}
+
+// pruneHistory 删除超出 history_size 的旧执行记录
+func (s *Scheduler) pruneHistory() {
+	limit := s.config.Schedule.HistorySize
+	if limit <= 0 {
+		limit = 100
+	}
+	keys := s.store.Keys(historyBucket)
+	// 记录ID以开始时间开头，按字典序即为时间顺序
+	for len(keys) > limit {
+		if err := s.store.Delete(historyBucket, keys[0]); err != nil {
+			log.Printf("删除任务执行记录失败: %v", err)
+			return
+		}
+		keys = keys[1:]
+	}
+}
@@ -0,0 +1,80 @@
+package sentry
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"tts/internal/apikey"
+	"tts/internal/privacy"
+)
+
+// reportedHeaders 是随事件上报的请求头，认证相关的请求头不会上报
+var reportedHeaders = []string{"User-Agent", "Content-Type", "Content-Length", "Accept", "Referer"}
+
+// hiddenParams 是上报查询参数时隐藏值的参数
+var hiddenParams = []string{"api_key", "key", "X-Amz-Signature", "X-Amz-Credential", "X-Amz-Security-Token"}
+
+// Request 是事件中的请求信息
+type Request struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	QueryString string            `json:"query_string,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// scope 是附加在请求上下文中的上报信息
+type scope struct {
+	request   Request
+	requestID string
+	key       string
+}
+
+type scopeKey struct{}
+
+func scopeFromContext(ctx context.Context) *scope {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(scopeKey{}).(*scope)
+	return s
+}
+
+// Middleware 在请求上下文中记录上报所需的请求信息，需放在 request_id、api_keys 与 privacy 之后
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s := &scope{
+			request: Request{
+				URL:     c.Request.URL.Path,
+				Method:  c.Request.Method,
+				Headers: map[string]string{},
+			},
+			requestID: c.Writer.Header().Get("X-Request-ID"),
+		}
+		if c.Request.Host != "" {
+			scheme := "http"
+			if c.Request.TLS != nil {
+				scheme = "https"
+			}
+			s.request.URL = scheme + "://" + c.Request.Host + c.Request.URL.Path
+		}
+		if c.Request.URL.RawQuery != "" && !privacy.Enabled(c.Request.Context()) {
+			query := c.Request.URL.Query()
+			for _, name := range hiddenParams {
+				if query.Has(name) {
+					query.Set(name, "REDACTED")
+				}
+			}
+			s.request.QueryString = query.Encode()
+		}
+		for _, name := range reportedHeaders {
+			if value := c.GetHeader(name); value != "" {
+				s.request.Headers[name] = value
+			}
+		}
+		if profile := apikey.FromContext(c); profile != nil {
+			s.key = profile.Name
+		}
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), scopeKey{}, s))
+		c.Next()
+	}
+}
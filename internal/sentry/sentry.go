@@ -0,0 +1,327 @@
+// Package sentry 把 panic 与上游服务错误上报到 Sentry，附带请求的方法、路径、密钥名称与请求ID。
+// 直接调用 Sentry 的 envelope 接口，不依赖官方 SDK；隐私模式的请求不上报查询参数与输入文本
+package sentry
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/metrics"
+	"tts/internal/privacy"
+)
+
+const (
+	// queueSize 是等待发送的事件数上限，Sentry 无法访问时多余的事件直接丢弃
+	queueSize   = 100
+	sendTimeout = 5 * time.Second
+	sdkName     = "tts.sentry"
+	sdkVersion  = "1.0"
+)
+
+var eventsTotal = metrics.NewCounter("tts_sentry_events_total",
+	"上报到 Sentry 的事件数，result 为 sent、failed、dropped 或 rate_limited", "result")
+
+// reporter 是按配置创建的上报器
+type reporter struct {
+	endpoint    string
+	auth        string
+	environment string
+	release     string
+	sampleRate  float64
+	serverName  string
+	queue       chan *event
+
+	mu           sync.Mutex
+	blockedUntil time.Time // 收到 429 后暂停上报的截止时间
+}
+
+var (
+	current atomic.Pointer[reporter]
+	client  = &http.Client{Timeout: sendTimeout}
+)
+
+// Configure 按配置开启错误上报，dsn 为空时关闭。应在创建其他组件之前调用
+func Configure(sc config.SentryConfig) error {
+	if sc.DSN == "" {
+		current.Store(nil)
+		return nil
+	}
+	endpoint, auth, err := parseDSN(sc.DSN)
+	if err != nil {
+		return err
+	}
+	sampleRate := sc.SampleRate
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	host, _ := os.Hostname()
+	r := &reporter{
+		endpoint:    endpoint,
+		auth:        auth,
+		environment: sc.Environment,
+		release:     sc.Release,
+		sampleRate:  sampleRate,
+		serverName:  host,
+		queue:       make(chan *event, queueSize),
+	}
+	go r.run()
+	current.Store(r)
+	log.Printf("已开启 Sentry 错误上报: %s", endpoint)
+	return nil
+}
+
+// Enabled 返回是否开启了错误上报
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// parseDSN 解析形如 https://{public_key}@{host}/{project_id} 的 DSN，返回 envelope 接口地址与认证头
+func parseDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("sentry.dsn 无效: %w", err)
+	}
+	project := strings.TrimPrefix(u.Path, "/")
+	prefix := ""
+	if i := strings.LastIndex(project, "/"); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.User == nil || u.User.Username() == "" || project == "" {
+		return "", "", fmt.Errorf("sentry.dsn 格式应为 https://{key}@{host}/{project}")
+	}
+	endpoint := fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project)
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s/%s, sentry_key=%s", sdkName, sdkVersion, u.User.Username())
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+	return endpoint, auth, nil
+}
+
+// CapturePanic 上报处理请求时发生的 panic，message 应已移除密钥。
+// 应在 recover 所在的 defer 中调用，以便记录 panic 发生处的调用栈
+func CapturePanic(ctx context.Context, message string) {
+	r := current.Load()
+	if r == nil {
+		return
+	}
+	e := r.newEvent(ctx, "fatal")
+	e.Exception = exceptions("panic", message, 4)
+	r.enqueue(e)
+}
+
+// CaptureError 上报错误，tags 与 extra 附加到事件中，extra 中的 text 在隐私模式下不上报。
+// 上游服务错误按 sentry.sample_rate 采样
+func CaptureError(ctx context.Context, err error, tags map[string]string, extra map[string]any) {
+	r := current.Load()
+	if r == nil || err == nil {
+		return
+	}
+	if r.sampleRate < 1 && rand.Float64() >= r.sampleRate {
+		return
+	}
+	e := r.newEvent(ctx, "error")
+	kind := fmt.Sprintf("%T", err)
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		kind = string(appErr.Code)
+	}
+	e.Exception = exceptions(kind, err.Error(), 2)
+	for k, v := range tags {
+		e.Tags[k] = v
+	}
+	for k, v := range extra {
+		if k == "text" && e.private {
+			continue
+		}
+		e.Extra[k] = v
+	}
+	r.enqueue(e)
+}
+
+// event 是 Sentry 事件中使用到的字段
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Exception   map[string]any    `json:"exception,omitempty"`
+	Request     *Request          `json:"request,omitempty"`
+	User        map[string]string `json:"user,omitempty"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]any    `json:"extra"`
+	SDK         map[string]string `json:"sdk"`
+
+	private bool // 隐私模式的请求，不上报输入文本
+}
+
+// newEvent 创建事件并附加上下文中的请求信息
+func (r *reporter) newEvent(ctx context.Context, level string) *event {
+	e := &event{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       level,
+		Logger:      "tts",
+		ServerName:  r.serverName,
+		Environment: r.environment,
+		Release:     r.release,
+		Tags:        map[string]string{},
+		Extra:       map[string]any{},
+		SDK:         map[string]string{"name": sdkName, "version": sdkVersion},
+		private:     privacy.Enabled(ctx),
+	}
+	if scope := scopeFromContext(ctx); scope != nil {
+		req := scope.request
+		e.Request = &req
+		if scope.requestID != "" {
+			e.Tags["request_id"] = scope.requestID
+		}
+		if scope.key != "" {
+			e.User = map[string]string{"id": scope.key}
+			e.Tags["key"] = scope.key
+		}
+	}
+	if tenant := config.TenantFromContext(ctx); tenant != "" {
+		e.Tags["tenant"] = tenant
+	}
+	return e
+}
+
+// exceptions 返回事件的 exception 字段，调用栈从调用方往上 skip 层开始。
+// 在 recover 中调用时多跳过 defer 函数与 runtime.gopanic 两层，从 panic 发生处开始
+func exceptions(kind, message string, skip int) map[string]any {
+	return map[string]any{"values": []map[string]any{{
+		"type":       kind,
+		"value":      message,
+		"stacktrace": map[string]any{"frames": stackFrames(skip + 1)},
+	}}}
+}
+
+// stackFrames 返回当前调用栈，Sentry 要求最早的调用在前
+func stackFrames(skip int) []map[string]any {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var list []map[string]any
+	for {
+		frame, more := frames.Next()
+		list = append(list, map[string]any{
+			"function": frame.Function,
+			"abs_path": frame.File,
+			"lineno":   frame.Line,
+			"in_app":   strings.HasPrefix(frame.Function, "tts/"),
+		})
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+// newEventID 生成 32 位十六进制的事件ID
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// enqueue 将事件放入发送队列，队列已满时丢弃
+func (r *reporter) enqueue(e *event) {
+	select {
+	case r.queue <- e:
+	default:
+		eventsTotal.Inc("dropped")
+	}
+}
+
+// run 依次发送队列中的事件
+func (r *reporter) run() {
+	for e := range r.queue {
+		r.send(e)
+	}
+}
+
+// send 以 envelope 格式发送事件，收到 429 时按 Retry-After 暂停上报
+func (r *reporter) send(e *event) {
+	r.mu.Lock()
+	blocked := time.Now().Before(r.blockedUntil)
+	r.mu.Unlock()
+	if blocked {
+		eventsTotal.Inc("rate_limited")
+		return
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("序列化 Sentry 事件失败: %v", err)
+		return
+	}
+	var body bytes.Buffer
+	header, _ := json.Marshal(map[string]string{"event_id": e.EventID, "sent_at": time.Now().UTC().Format(time.RFC3339)})
+	body.Write(header)
+	body.WriteString("\n")
+	fmt.Fprintf(&body, `{"type":"event","length":%d}`+"\n", len(payload))
+	body.Write(payload)
+	body.WriteString("\n")
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		log.Printf("创建 Sentry 请求失败: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+	resp, err := client.Do(req)
+	if err != nil {
+		eventsTotal.Inc("failed")
+		log.Printf("上报 Sentry 失败: %v", err)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retry, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+		if err != nil || retry <= 0 {
+			retry = 60
+		}
+		r.mu.Lock()
+		r.blockedUntil = time.Now().Add(time.Duration(retry) * time.Second)
+		r.mu.Unlock()
+		eventsTotal.Inc("rate_limited")
+	case resp.StatusCode >= 300:
+		eventsTotal.Inc("failed")
+		log.Printf("上报 Sentry 失败: HTTP %d", resp.StatusCode)
+	default:
+		eventsTotal.Inc("sent")
+	}
+}
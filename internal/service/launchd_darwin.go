@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// plistPath 返回当前用户的 launchd agent 文件路径
+func plistPath(name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Library", "LaunchAgents", name+".plist"), nil
+}
+
+// domain 返回当前用户的 launchd 域
+func domain() string {
+	return "gui/" + strconv.Itoa(os.Getuid())
+}
+
+// launchctl 运行 launchctl，输出写入 out
+func launchctl(out io.Writer, args ...string) error {
+	cmd := exec.Command("launchctl", args...)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("launchctl %s 失败: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// plist 返回 launchd agent 的内容：登录后启动，异常退出时重新启动，日志写入 ~/Library/Logs
+func plist(sp spec) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	logPath := filepath.Join(home, "Library", "Logs", sp.name+".log")
+	var args strings.Builder
+	for _, arg := range append([]string{sp.executable}, sp.args...) {
+		args.WriteString("\t\t<string>" + xmlEscape(arg) + "</string>\n")
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>WorkingDirectory</key>
+	<string>%s</string>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>StandardOutPath</key>
+	<string>%s</string>
+	<key>StandardErrorPath</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, xmlEscape(sp.name), args.String(), xmlEscape(sp.workingDir), xmlEscape(logPath), xmlEscape(logPath)), nil
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func install(sp spec, out io.Writer) error {
+	path, err := plistPath(sp.name)
+	if err != nil {
+		return err
+	}
+	content, err := plist(sp)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	fmt.Fprintf(out, "已写入 %s，下次登录时自动启动，日志写入 ~/Library/Logs/%s.log\n", path, sp.name)
+	return nil
+}
+
+func uninstall(name string, out io.Writer) error {
+	path, err := plistPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("服务 %s 未注册: %s 不存在", name, path)
+	}
+	// 未运行时 bootout 会失败，忽略
+	launchctl(io.Discard, "bootout", domain()+"/"+name)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("删除 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+func start(name string, out io.Writer) error {
+	path, err := plistPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("服务 %s 未注册，请先运行 tts service install", name)
+	}
+	return launchctl(out, "bootstrap", domain(), path)
+}
+
+func stop(name string, out io.Writer) error {
+	return launchctl(out, "bootout", domain()+"/"+name)
+}
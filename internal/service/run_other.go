@@ -0,0 +1,8 @@
+//go:build !windows
+
+package service
+
+// RunApp 运行应用，直接调用 start；Windows 上由服务控制管理器启动时按服务协议运行
+func RunApp(start func() error, stop func()) error {
+	return start()
+}
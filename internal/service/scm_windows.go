@@ -0,0 +1,188 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// stopTimeout 是等待服务停止的时间
+const stopTimeout = 20 * time.Second
+
+// connect 连接服务控制管理器
+func connect() (*mgr.Mgr, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("连接服务控制管理器失败，请以管理员身份运行: %w", err)
+	}
+	return m, nil
+}
+
+// open 打开已注册的服务
+func open(m *mgr.Mgr, name string) (*mgr.Service, error) {
+	s, err := m.OpenService(name)
+	if err != nil {
+		return nil, fmt.Errorf("服务 %s 未注册: %w", name, err)
+	}
+	return s, nil
+}
+
+func install(sp spec, out io.Writer) error {
+	m, err := connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	if s, err := m.OpenService(sp.name); err == nil {
+		s.Close()
+		return fmt.Errorf("服务 %s 已存在", sp.name)
+	}
+	// 服务控制管理器启动程序时工作目录为 system32，通过 -workdir 切换到执行 install 时的目录
+	args := append(append([]string{}, sp.args...), "-workdir", sp.workingDir)
+	s, err := m.CreateService(sp.name, sp.executable, mgr.Config{
+		DisplayName: description,
+		Description: description,
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("注册服务失败: %w", err)
+	}
+	defer s.Close()
+	// 异常退出后 5 秒重新启动
+	recovery := []mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: 5 * time.Second}}
+	if err := s.SetRecoveryActions(recovery, uint32((24 * time.Hour).Seconds())); err != nil {
+		fmt.Fprintf(out, "设置失败后重新启动失败: %v\n", err)
+	}
+	fmt.Fprintf(out, "服务开机自动启动，日志写入 %s\n", filepath.Join(sp.workingDir, sp.name+".log"))
+	return nil
+}
+
+func uninstall(name string, out io.Writer) error {
+	m, err := connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := open(m, name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+		if err := stopService(s); err != nil {
+			return err
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("删除服务失败: %w", err)
+	}
+	return nil
+}
+
+func start(name string, out io.Writer) error {
+	m, err := connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := open(m, name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("启动服务失败: %w", err)
+	}
+	return nil
+}
+
+func stop(name string, out io.Writer) error {
+	m, err := connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := open(m, name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	return stopService(s)
+}
+
+// stopService 请求停止服务并等待停止
+func stopService(s *mgr.Service) error {
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return fmt.Errorf("停止服务失败: %w", err)
+	}
+	deadline := time.Now().Add(stopTimeout)
+	for status.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待服务停止超时")
+		}
+		time.Sleep(300 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return fmt.Errorf("查询服务状态失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// RunApp 运行应用：由服务控制管理器启动时按服务协议运行，日志写入工作目录下的 tts.log，
+// 收到停止或关机请求时调用 stop 并等待 start 返回；否则直接调用 start
+func RunApp(start func() error, stop func()) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return start()
+	}
+	if f, err := os.OpenFile(DefaultName+".log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
+		log.SetOutput(f)
+		os.Stdout, os.Stderr = f, f
+	}
+	return svc.Run(DefaultName, &handler{start: start, stop: stop})
+}
+
+// handler 实现 svc.Handler
+type handler struct {
+	start func() error
+	stop  func()
+}
+
+// Execute 启动应用并处理服务控制请求
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	done := make(chan error, 1)
+	go func() { done <- h.start() }()
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				log.Printf("应用运行出错: %v", err)
+				return true, 1
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				changes <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				h.stop()
+				if err := <-done; err != nil {
+					log.Printf("应用运行出错: %v", err)
+					return true, 1
+				}
+				return false, 0
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package service 实现 tts service：把程序注册为系统服务，Linux 使用 systemd，macOS 使用 launchd，
+// Windows 使用服务控制管理器，适合不使用容器的家庭用户开机自动运行
+package service
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"tts/internal/config"
+)
+
+// DefaultName 是默认的服务名称
+const DefaultName = "tts"
+
+// description 是服务的说明
+const description = "TTS 语音合成服务"
+
+// spec 描述要注册的服务
+type spec struct {
+	name       string
+	executable string   // 程序的绝对路径
+	args       []string // 启动参数
+	workingDir string   // 工作目录，配置中的相对路径按该目录解析
+}
+
+// Run 执行服务管理子命令，args 为 service 之后的命令行参数：install、uninstall、start 或 stop
+func Run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: tts service install|uninstall|start|stop [-name tts] [-config path]")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("service "+action, flag.ContinueOnError)
+	fs.SetOutput(out)
+	name := fs.String("name", DefaultName, "服务名称，launchd 中为 Label")
+	configPath := fs.String("config", "", "服务使用的配置文件，默认按服务启动时的顺序查找（只用于 install）")
+	env := fs.String("env", "", "服务使用的配置环境名称（只用于 install）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch action {
+	case "install":
+		s, err := newSpec(*name, *configPath, *env)
+		if err != nil {
+			return err
+		}
+		if err := install(s, out); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "已注册服务 %s，配置文件 %s，工作目录 %s\n", s.name, s.args[1], s.workingDir)
+	case "uninstall":
+		if err := uninstall(*name, out); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "已删除服务 %s\n", *name)
+	case "start":
+		if err := start(*name, out); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "已启动服务 %s\n", *name)
+	case "stop":
+		if err := stop(*name, out); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "已停止服务 %s\n", *name)
+	default:
+		return fmt.Errorf("未知的操作: %s，应为 install、uninstall、start 或 stop", action)
+	}
+	return nil
+}
+
+// newSpec 按当前程序与配置文件创建服务描述，工作目录为执行 install 时的当前目录
+func newSpec(name, configPath, env string) (spec, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return spec{}, fmt.Errorf("获取程序路径失败: %w", err)
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return spec{}, fmt.Errorf("获取程序路径失败: %w", err)
+	}
+	if configPath == "" {
+		configPath = config.FindFile()
+	}
+	if configPath, err = filepath.Abs(configPath); err != nil {
+		return spec{}, err
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		return spec{}, fmt.Errorf("配置文件不存在: %s，可先运行 tts init 生成", configPath)
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return spec{}, err
+	}
+	args := []string{"-config", configPath}
+	if env != "" {
+		args = append(args, "-env", env)
+	}
+	return spec{name: name, executable: executable, args: args, workingDir: workingDir}, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// systemd 以 root 运行时注册为系统服务，否则注册为当前用户的服务（systemctl --user）
+type systemd struct {
+	user bool
+}
+
+func newSystemd() systemd {
+	return systemd{user: os.Geteuid() != 0}
+}
+
+// unitPath 返回服务单元文件的路径
+func (s systemd) unitPath(name string) (string, error) {
+	if !s.user {
+		return filepath.Join("/etc/systemd/system", name+".service"), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "systemd", "user", name+".service"), nil
+}
+
+// systemctl 运行 systemctl，输出写入 out
+func (s systemd) systemctl(out io.Writer, args ...string) error {
+	if s.user {
+		args = append([]string{"--user"}, args...)
+	}
+	cmd := exec.Command("systemctl", args...)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("systemctl %s 失败: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// unit 返回服务单元文件的内容，收到 systemctl reload 时发送 SIGHUP 重新加载配置
+func (s systemd) unit(sp spec) string {
+	target := "multi-user.target"
+	if s.user {
+		target = "default.target"
+	}
+	command := []string{systemdQuote(sp.executable)}
+	for _, arg := range sp.args {
+		command = append(command, systemdQuote(arg))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=%s
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+ExecStart=%s
+ExecReload=/bin/kill -HUP $MAINPID
+WorkingDirectory=%s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=%s
+`, description, strings.Join(command, " "), strings.ReplaceAll(sp.workingDir, "%", "%%"), target)
+}
+
+// systemdQuote 按 systemd 的规则给含空格等字符的参数加引号
+func systemdQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\$%") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `$$`, `%`, `%%`)
+	return `"` + r.Replace(arg) + `"`
+}
+
+func install(sp spec, out io.Writer) error {
+	s := newSystemd()
+	path, err := s.unitPath(sp.name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(s.unit(sp)), 0644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	fmt.Fprintf(out, "已写入 %s\n", path)
+	if err := s.systemctl(out, "daemon-reload"); err != nil {
+		return err
+	}
+	if s.user {
+		fmt.Fprintln(out, "用户服务在登录后运行；需要开机即运行时执行 loginctl enable-linger")
+	}
+	return s.systemctl(out, "enable", sp.name)
+}
+
+func uninstall(name string, out io.Writer) error {
+	s := newSystemd()
+	path, err := s.unitPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("服务 %s 未注册: %s 不存在", name, path)
+	}
+	if err := s.systemctl(out, "disable", "--now", name); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("删除 %s 失败: %w", path, err)
+	}
+	return s.systemctl(out, "daemon-reload")
+}
+
+func start(name string, out io.Writer) error {
+	return newSystemd().systemctl(out, "start", name)
+}
+
+func stop(name string, out io.Writer) error {
+	return newSystemd().systemctl(out, "stop", name)
+}
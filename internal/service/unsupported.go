@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !windows
+
+package service
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+func unsupported() error {
+	return fmt.Errorf("不支持在 %s 上注册服务", runtime.GOOS)
+}
+
+func install(sp spec, out io.Writer) error { return unsupported() }
+
+func uninstall(name string, out io.Writer) error { return unsupported() }
+
+func start(name string, out io.Writer) error { return unsupported() }
+
+func stop(name string, out io.Writer) error { return unsupported() }
@@ -0,0 +1,256 @@
+// Package session 管理合成会话：会话可以保存语音、语速等默认设置，供后续请求沿用；
+// 也可以记录已经朗读过的文本，用于大模型逐步输出回复的场景：
+// 客户端每次提交完整的、不断增长的消息，服务只合成新增的部分，避免重复朗读。
+package session
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"tts/internal/models"
+)
+
+const (
+	// defaultTTL 未配置时会话的空闲过期时间
+	defaultTTL = 30 * time.Minute
+	// defaultMaxSessions 未配置时最多同时保存的会话数
+	defaultMaxSessions = 10000
+)
+
+// sentenceEnds 是判断句子结束的标点，英文句点需要后跟空白才算句末，避免切开 3.14
+const sentenceEnds = "。！？；…!?;\n"
+
+// closingMarks 是句末标点之后仍属于本句的引号与括号
+const closingMarks = "”’」』）)\"'"
+
+// Store 是线程安全的会话存储，会话只保存在内存中，重启后丢失
+type Store struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxSessions int
+	sessions    map[string]*session
+}
+
+// session 记录一个会话的设置与已经朗读的文本
+type session struct {
+	settings *models.SessionSettings // 通过 Create 创建的会话才有设置
+	replacer *strings.Replacer
+	spoken   string
+	updated  time.Time
+}
+
+// Delta 是一次提交中需要合成的部分
+type Delta struct {
+	Text   string // 需要合成的文本，为空表示没有新的完整句子
+	Upto   string // 合成成功后应记录为已朗读的文本，传给 Commit
+	Reset  bool   // 提交的文本不是上次文本的延续（如重新生成了回复），从头开始朗读
+	Spoken int    // 本次之前已朗读的字符数
+}
+
+// New 创建会话存储，ttl 为会话的空闲过期时间
+func New(ttl time.Duration, maxSessions int) *Store {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+	return &Store{ttl: ttl, maxSessions: maxSessions, sessions: make(map[string]*session)}
+}
+
+// Create 创建带有设置的会话，已有同名会话时覆盖
+func (s *Store) Create(id string, settings models.SessionSettings) models.SessionResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok && len(s.sessions) >= s.maxSessions {
+		s.evictLocked()
+	}
+	sess := &session{settings: &settings, replacer: newReplacer(settings.Replacements), updated: time.Now()}
+	s.sessions[id] = sess
+	return s.responseLocked(id, sess)
+}
+
+// Get 返回会话的设置与进度，会话不存在或已过期时返回 false
+func (s *Store) Get(id string) (models.SessionResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.liveLocked(id)
+	if !ok {
+		return models.SessionResponse{}, false
+	}
+	return s.responseLocked(id, sess), true
+}
+
+// Settings 返回通过 Create 创建的会话的设置并顺延过期时间，没有设置的会话返回 false
+func (s *Store) Settings(id string) (models.SessionSettings, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.liveLocked(id)
+	if !ok || sess.settings == nil {
+		return models.SessionSettings{}, false
+	}
+	sess.updated = time.Now()
+	return *sess.settings, true
+}
+
+// Replace 按会话设置中的 replacements 替换文本
+func (s *Store) Replace(id, text string) string {
+	s.mu.Lock()
+	sess, ok := s.liveLocked(id)
+	s.mu.Unlock()
+	if !ok || sess.replacer == nil {
+		return text
+	}
+	return sess.replacer.Replace(text)
+}
+
+// Delta 计算会话 id 提交 text 时需要合成的部分。
+// final 为 false 时只合成到最后一个完整的句子，末尾未说完的半句留到下次提交；
+// final 为 true 表示消息已经结束，剩余的部分全部合成。
+func (s *Store) Delta(id, text string, final bool) Delta {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var d Delta
+	start := 0
+	if sess, ok := s.liveLocked(id); ok {
+		if strings.HasPrefix(text, sess.spoken) {
+			start = len(sess.spoken)
+			d.Spoken = utf8.RuneCountInString(sess.spoken)
+		} else {
+			d.Reset = true
+		}
+	}
+
+	remaining := text[start:]
+	if !final {
+		remaining = remaining[:SentenceBoundary(remaining)]
+	}
+	d.Upto = text[:start+len(remaining)]
+	d.Text = strings.TrimSpace(remaining)
+	return d
+}
+
+// Commit 在合成成功后把 upto 记录为会话已朗读的文本，会话不存在时创建没有设置的会话
+func (s *Store) Commit(id, upto string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.liveLocked(id)
+	if !ok {
+		if len(s.sessions) >= s.maxSessions {
+			s.evictLocked()
+		}
+		sess = &session{}
+		s.sessions[id] = sess
+	}
+	sess.spoken = upto
+	sess.updated = time.Now()
+}
+
+// Delete 删除会话，会话不存在时返回 false
+func (s *Store) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.liveLocked(id)
+	delete(s.sessions, id)
+	return ok
+}
+
+// liveLocked 返回未过期的会话，过期的会话会被删除
+func (s *Store) liveLocked(id string) (*session, bool) {
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(sess.updated) >= s.ttl {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	return sess, true
+}
+
+// responseLocked 生成会话的查询结果
+func (s *Store) responseLocked(id string, sess *session) models.SessionResponse {
+	resp := models.SessionResponse{
+		ID:        id,
+		Spoken:    utf8.RuneCountInString(sess.spoken),
+		ExpiresAt: sess.updated.Add(s.ttl),
+	}
+	if sess.settings != nil {
+		resp.Settings = *sess.settings
+	}
+	return resp
+}
+
+// newReplacer 按 replacements 创建替换器，较长的词优先匹配
+func newReplacer(replacements map[string]string) *strings.Replacer {
+	if len(replacements) == 0 {
+		return nil
+	}
+	words := make([]string, 0, len(replacements))
+	for word := range replacements {
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if len(words[i]) != len(words[j]) {
+			return len(words[i]) > len(words[j])
+		}
+		return words[i] < words[j]
+	})
+	pairs := make([]string, 0, 2*len(words))
+	for _, word := range words {
+		pairs = append(pairs, word, replacements[word])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// evictLocked 删除过期的会话，仍然超过上限时删除最久未使用的会话
+func (s *Store) evictLocked() {
+	var oldestID string
+	var oldest time.Time
+	for id, sess := range s.sessions {
+		if time.Since(sess.updated) >= s.ttl {
+			delete(s.sessions, id)
+			continue
+		}
+		if oldestID == "" || sess.updated.Before(oldest) {
+			oldestID, oldest = id, sess.updated
+		}
+	}
+	if len(s.sessions) >= s.maxSessions && oldestID != "" {
+		delete(s.sessions, oldestID)
+	}
+}
+
+// SentenceBoundary 返回 text 中最后一个完整句子结束的字节位置，没有完整句子时返回 0
+func SentenceBoundary(text string) int {
+	end := 0
+	for i, r := range text {
+		next := i + utf8.RuneLen(r)
+		switch {
+		case strings.ContainsRune(sentenceEnds, r):
+		case r == '.' && next < len(text) && (text[next] == ' ' || text[next] == '\n' || text[next] == '\t'):
+		default:
+			continue
+		}
+		for next < len(text) {
+			c, size := utf8.DecodeRuneInString(text[next:])
+			if !strings.ContainsRune(closingMarks, c) {
+				break
+			}
+			next += size
+		}
+		end = next
+	}
+	return end
+}
@@ -0,0 +1,279 @@
+// Package setup 实现 tts init：交互式询问区域、TTS 服务与接口密钥，测试上游连接并列出可用的语音，
+// 以随程序发布的示例配置为模板写入配置文件
+package setup
+
+import (
+	"bufio"
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"tts/configs"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/tts/microsoft"
+)
+
+const (
+	// listedVoices 是列出供选择的语音数
+	listedVoices = 10
+	testTimeout  = 20 * time.Second
+)
+
+// answers 是向导收集的设置
+type answers struct {
+	port     int
+	provider string
+	region   string
+	apiKey   string
+	voice    string
+}
+
+// prompter 从输入中逐行读取回答
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask 显示问题并返回回答，直接回车时返回默认值
+func (p *prompter) ask(question, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	line, err := p.in.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		if err != nil {
+			fmt.Fprintln(p.out)
+		}
+		return defaultValue
+	}
+	return line
+}
+
+// confirm 显示是非问题，直接回车时返回默认值
+func (p *prompter) confirm(question string, defaultValue bool) bool {
+	hint := "y/N"
+	if defaultValue {
+		hint = "Y/n"
+	}
+	switch strings.ToLower(p.ask(question+" ("+hint+")", "")) {
+	case "y", "yes", "是":
+		return true
+	case "n", "no", "否":
+		return false
+	default:
+		return defaultValue
+	}
+}
+
+// Run 运行配置向导，args 为 init 之后的命令行参数
+func Run(args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", "./configs/config.yaml", "写入的配置文件路径")
+	force := fs.Bool("force", false, "配置文件已存在时直接覆盖")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	p := &prompter{in: bufio.NewReader(in), out: out}
+	fmt.Fprintln(out, "TTS 服务配置向导，直接回车使用方括号中的默认值。")
+
+	if _, err := os.Stat(*configPath); err == nil && !*force {
+		if !p.confirm(fmt.Sprintf("%s 已存在，是否覆盖？", *configPath), false) {
+			return fmt.Errorf("已取消，配置文件未修改")
+		}
+	}
+
+	template, err := config.Parse(configs.Default)
+	if err != nil {
+		return err
+	}
+	a := answers{
+		port:     template.Server.Port,
+		provider: template.TTS.Provider,
+		region:   template.TTS.Region,
+		voice:    template.TTS.DefaultVoice,
+	}
+
+	for {
+		port, err := strconv.Atoi(p.ask("监听端口", strconv.Itoa(a.port)))
+		if err == nil && port > 0 && port < 65536 {
+			a.port = port
+			break
+		}
+		fmt.Fprintln(out, "端口应为 1-65535 之间的整数")
+	}
+	for {
+		a.provider = p.ask("TTS 服务（microsoft，或 mock：不访问上游，输出静音，适合开发测试）", a.provider)
+		if a.provider == "microsoft" || a.provider == "mock" {
+			break
+		}
+		fmt.Fprintln(out, "请输入 microsoft 或 mock")
+	}
+	if a.provider == "microsoft" {
+		a.region = p.ask("Azure 区域", a.region)
+	}
+	if p.confirm("是否生成接口认证密钥？未设置时任何人都可以调用接口", true) {
+		a.apiKey = newKey()
+	} else {
+		a.apiKey = p.ask("接口认证密钥（留空不认证）", "")
+	}
+
+	if a.provider == "microsoft" {
+		locale := p.ask("语音语言", localeOf(a.voice))
+		voices, err := testConnection(template, locale)
+		if err != nil {
+			fmt.Fprintf(out, "连接上游失败: %v\n", err)
+			if !p.confirm("仍然写入配置？", true) {
+				return fmt.Errorf("已取消，配置文件未修改")
+			}
+		} else {
+			a.voice = chooseVoice(p, voices, a.voice)
+		}
+	}
+
+	data := render(configs.Default, a)
+	if _, err := config.Parse(data); err != nil {
+		return fmt.Errorf("生成的配置无效: %w", err)
+	}
+	if dir := filepath.Dir(*configPath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建配置目录失败: %w", err)
+		}
+	}
+	// 配置中包含接口密钥，只允许所有者读取
+	if err := os.WriteFile(*configPath, data, 0600); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+
+	fmt.Fprintf(out, "\n已写入 %s，启动服务:\n\n  ./tts -config %s\n\n", *configPath, *configPath)
+	auth := ""
+	if a.apiKey != "" {
+		fmt.Fprintf(out, "接口认证密钥: %s\n\n", a.apiKey)
+		auth = fmt.Sprintf(" -H 'Authorization: Bearer %s'", a.apiKey)
+	}
+	fmt.Fprintf(out, "试用:\n\n  curl%s 'http://localhost:%d/tts?t=你好' -o hello.mp3\n\n", auth, a.port)
+	fmt.Fprintln(out, "其他配置项的说明见配置文件中的注释。")
+	return nil
+}
+
+// testConnection 获取上游认证信息并列出 locale 的语音，期间不输出客户端的日志
+func testConnection(cfg *config.Config, locale string) ([]models.Voice, error) {
+	logOut := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(logOut)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	return microsoft.NewClient(cfg).ListVoices(ctx, locale)
+}
+
+// chooseVoice 列出部分语音供选择默认语音，也可以直接输入语音名称
+func chooseVoice(p *prompter, voices []models.Voice, current string) string {
+	if len(voices) == 0 {
+		fmt.Fprintln(p.out, "连接成功，但该语言没有可用的语音")
+		return current
+	}
+	fmt.Fprintf(p.out, "连接成功，共 %d 个语音:\n", len(voices))
+	defaultChoice := "1"
+	for i, v := range voices {
+		if i < listedVoices {
+			fmt.Fprintf(p.out, "  %2d) %-28s %s %s\n", i+1, v.ShortName, v.LocalName, v.Gender)
+		}
+		if v.ShortName == current {
+			defaultChoice = current
+		}
+	}
+	if len(voices) > listedVoices {
+		fmt.Fprintf(p.out, "  …（其余 %d 个可通过 GET /voices 查看）\n", len(voices)-listedVoices)
+	}
+	for {
+		choice := p.ask("默认语音（序号或名称）", defaultChoice)
+		if n, err := strconv.Atoi(choice); err == nil && n >= 1 && n <= len(voices) {
+			return voices[n-1].ShortName
+		}
+		for _, v := range voices {
+			if strings.EqualFold(v.ShortName, choice) {
+				return v.ShortName
+			}
+		}
+		fmt.Fprintln(p.out, "没有该语音")
+	}
+}
+
+// localeOf 返回语音名称中的语言区域，如 zh-CN-XiaoxiaoNeural 返回 zh-CN
+func localeOf(voice string) string {
+	parts := strings.SplitN(voice, "-", 3)
+	if len(parts) < 3 {
+		return "zh-CN"
+	}
+	return parts[0] + "-" + parts[1]
+}
+
+// newKey 生成随机的接口认证密钥
+func newKey() string {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return "sk-" + hex.EncodeToString(b)
+}
+
+// render 将回答写入模板中对应的配置项，保留模板的注释与格式
+func render(template []byte, a answers) []byte {
+	text := string(template)
+	text = setValue(text, "server", "port", strconv.Itoa(a.port))
+	text = setValue(text, "tts", "provider", strconv.Quote(a.provider))
+	text = setValue(text, "tts", "region", strconv.Quote(a.region))
+	text = setValue(text, "tts", "default_voice", strconv.Quote(a.voice))
+	text = setValue(text, "tts", "api_key", strconv.Quote(a.apiKey))
+	text = setValue(text, "openai", "api_key", strconv.Quote(a.apiKey))
+	return []byte(text)
+}
+
+// setValue 替换顶层配置 section 下第一层配置项 key 的值，保留行尾注释；找不到配置项时不做修改
+func setValue(text, section, key, value string) string {
+	lines := strings.Split(text, "\n")
+	inSection := false
+	for i, line := range lines {
+		if line != "" && line[0] != ' ' && line[0] != '#' {
+			inSection = strings.HasPrefix(line, section+":")
+			continue
+		}
+		prefix := "  " + key + ":"
+		if !inSection || !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		rest := line[len(prefix):]
+		comment := ""
+		if j := strings.Index(rest, " #"); j >= 0 {
+			comment = rest[j:]
+			rest = rest[:j]
+		}
+		updated := prefix + " " + value
+		if comment != "" {
+			// 保持行尾注释的对齐
+			padding := len(prefix) + len(rest) - len(updated)
+			if padding < 0 {
+				padding = 0
+			}
+			updated += strings.Repeat(" ", padding) + comment
+		}
+		lines[i] = updated
+		break
+	}
+	return strings.Join(lines, "\n")
+}
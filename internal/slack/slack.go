@@ -0,0 +1,174 @@
+// Package slack 提供 Slack 斜杠命令的回复：通过 response_url 回复消息，配置了机器人令牌时把音频上传到频道。
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tts/internal/utils"
+)
+
+// defaultAPIURL 是 Slack Web API 的默认地址
+const defaultAPIURL = "https://slack.com/api"
+
+// Command 是斜杠命令请求中用到的字段
+type Command struct {
+	TeamID      string
+	ChannelID   string
+	UserID      string
+	Command     string
+	Text        string
+	ResponseURL string
+}
+
+// ParseCommand 解析斜杠命令的表单
+func ParseCommand(form url.Values) Command {
+	return Command{
+		TeamID:      form.Get("team_id"),
+		ChannelID:   form.Get("channel_id"),
+		UserID:      form.Get("user_id"),
+		Command:     form.Get("command"),
+		Text:        strings.TrimSpace(form.Get("text")),
+		ResponseURL: form.Get("response_url"),
+	}
+}
+
+// Message 是回复的消息，ResponseType 为 ephemeral 时只有发送命令的用户可见，in_channel 时频道中所有人可见
+type Message struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Client 调用 Slack 的接口
+type Client struct {
+	apiURL   string
+	botToken string
+	client   *http.Client
+}
+
+// New 创建客户端，botToken 为空时不能上传文件
+func New(apiURL, botToken string) *Client {
+	apiURL = strings.TrimRight(apiURL, "/")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	return &Client{apiURL: apiURL, botToken: botToken, client: &http.Client{Timeout: time.Minute}}
+}
+
+// CanUpload 判断是否配置了上传文件需要的机器人令牌
+func (c *Client) CanUpload() bool {
+	return c.botToken != ""
+}
+
+// Respond 通过命令的 response_url 回复消息，response_url 在 30 分钟内可以使用 5 次
+func (c *Client) Respond(ctx context.Context, responseURL string, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("回复消息失败: HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// Upload 把文件上传到频道：先获取上传地址，上传内容后完成上传并分享到频道，comment 作为附带的消息。
+// 机器人需要 files:write 权限并已加入频道
+func (c *Client) Upload(ctx context.Context, channelID, filename, title, comment string, data []byte) error {
+	var upload struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	form := url.Values{"filename": {filename}, "length": {strconv.Itoa(len(data))}}
+	if err := c.call(ctx, "files.getUploadURLExternal", "application/x-www-form-urlencoded",
+		strings.NewReader(form.Encode()), &upload); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upload.UploadURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("上传文件失败: %s", c.sanitize(err))
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("上传文件失败: HTTP %d", resp.StatusCode)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"files":           []map[string]string{{"id": upload.FileID, "title": title}},
+		"channel_id":      channelID,
+		"initial_comment": comment,
+	})
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, "files.completeUploadExternal", "application/json; charset=utf-8", bytes.NewReader(body), nil)
+}
+
+// call 调用 Web API 并解析结果，ok 为 false 时返回其中的错误码
+func (c *Client) call(ctx context.Context, method, contentType string, body io.Reader, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL+"/"+method, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.botToken)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s 失败: %s", method, c.sanitize(err))
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("%s 失败: %s", method, status.Error)
+	}
+	if result != nil {
+		return json.Unmarshal(data, result)
+	}
+	return nil
+}
+
+// sanitize 从错误信息中移除机器人令牌
+func (c *Client) sanitize(err error) string {
+	return utils.SanitizeMessage(err.Error(), c.botToken)
+}
+
+// Escape 转义消息文本中的 &、<、>，避免被解析为链接或提及
+func Escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
@@ -0,0 +1,60 @@
+package ssml
+
+import (
+	"regexp"
+	"strings"
+)
+
+// defaultAcronymMaxLength 是未配置 max_length 时逐个字母朗读的最大长度
+const defaultAcronymMaxLength = 5
+
+// acronymPattern 匹配至少包含两个大写字母的全大写词，可以带数字（如 MP3）与复数后缀 s（如 APIs）
+var acronymPattern = regexp.MustCompile(`\b([A-Z][A-Z0-9]*[A-Z][A-Z0-9]*)(s?)\b`)
+
+// Acronyms 按词表朗读全大写的缩写词
+type Acronyms struct {
+	words     map[string]string // 键为大写的缩写词
+	spell     bool
+	maxLength int
+}
+
+// NewAcronyms 创建缩写词处理器，words 的键不区分大小写。
+// spell 为 true 时，不在词表中且不超过 maxLength 个字符的缩写词逐个字母朗读，否则保持原样。
+func NewAcronyms(words map[string]string, spell bool, maxLength int) *Acronyms {
+	if maxLength <= 0 {
+		maxLength = defaultAcronymMaxLength
+	}
+	a := &Acronyms{words: make(map[string]string, len(words)), spell: spell, maxLength: maxLength}
+	for word, reading := range words {
+		a.words[strings.ToUpper(word)] = reading
+	}
+	return a
+}
+
+// Expand 为文本中的缩写词添加读法：词表中的词生成 <sub alias>，如 SQL 读作 sequel；
+// 其他缩写词按配置生成 <say-as interpret-as="characters"> 逐个字母朗读。
+// 已有的标签原样保留，<say-as>、<phoneme>、<sub> 内部的内容不做处理。
+func (a *Acronyms) Expand(text string) string {
+	if a == nil {
+		return text
+	}
+	return mapText(text, a.expand)
+}
+
+// expand 处理一段不含标签的文本
+func (a *Acronyms) expand(s string) string {
+	return acronymPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := acronymPattern.FindStringSubmatch(m)
+		word, plural := sub[1], sub[2]
+		if reading, ok := a.words[word+plural]; ok {
+			return Render(Sub(reading, m))
+		}
+		if reading, ok := a.words[word]; ok {
+			return Render(Sub(reading+plural, m))
+		}
+		if !a.spell || len(word) > a.maxLength {
+			return m
+		}
+		return Render(SayAs("characters", "", word)) + plural
+	})
+}
@@ -0,0 +1,250 @@
+package ssml
+
+import (
+	"strings"
+)
+
+// Node 是 SSML 文档中的一个节点
+type Node interface {
+	render(sb *strings.Builder)
+}
+
+// Render 将节点序列化为 SSML 字符串
+func Render(node Node) string {
+	var sb strings.Builder
+	node.render(&sb)
+	return sb.String()
+}
+
+// attrEscaper 转义属性值
+var attrEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+// textEscaper 转义文本节点
+var textEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+// attr 是一个属性，值为空时不输出
+type attr struct {
+	name  string
+	value string
+}
+
+// element 输出一个元素及其子节点，没有子节点时输出自闭合标签
+func element(sb *strings.Builder, name string, attrs []attr, children []Node) {
+	sb.WriteByte('<')
+	sb.WriteString(name)
+	for _, a := range attrs {
+		if a.value == "" {
+			continue
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(a.name)
+		sb.WriteString(`="`)
+		sb.WriteString(attrEscaper.Replace(a.value))
+		sb.WriteByte('"')
+	}
+	if len(children) == 0 {
+		sb.WriteString("/>")
+		return
+	}
+	sb.WriteByte('>')
+	for _, child := range children {
+		child.render(sb)
+	}
+	sb.WriteString("</")
+	sb.WriteString(name)
+	sb.WriteByte('>')
+}
+
+// Text 是需要转义的纯文本
+type Text string
+
+func (t Text) render(sb *strings.Builder) {
+	sb.WriteString(textEscaper.Replace(string(t)))
+}
+
+// Raw 是已经转义的 SSML 片段（例如保留了用户标签的文本），原样输出
+type Raw string
+
+func (r Raw) render(sb *strings.Builder) {
+	sb.WriteString(string(r))
+}
+
+// Group 是不产生元素的节点列表
+type Group []Node
+
+func (g Group) render(sb *strings.Builder) {
+	for _, child := range g {
+		child.render(sb)
+	}
+}
+
+// SpeakNode 是文档根元素
+type SpeakNode struct {
+	Lang     string
+	Children []Node
+}
+
+// Speak 创建根元素
+func Speak(lang string, children ...Node) *SpeakNode {
+	return &SpeakNode{Lang: lang, Children: children}
+}
+
+func (n *SpeakNode) render(sb *strings.Builder) {
+	element(sb, "speak", []attr{
+		{"version", "1.0"},
+		{"xmlns", NamespaceSynthesis},
+		{"xmlns:mstts", NamespaceMSTTS},
+		{"xml:lang", n.Lang},
+	}, n.Children)
+}
+
+// VoiceNode 指定朗读使用的语音
+type VoiceNode struct {
+	Name     string
+	Children []Node
+}
+
+// Voice 创建语音元素
+func Voice(name string, children ...Node) *VoiceNode {
+	return &VoiceNode{Name: name, Children: children}
+}
+
+func (n *VoiceNode) render(sb *strings.Builder) {
+	element(sb, "voice", []attr{{"name", n.Name}}, n.Children)
+}
+
+// ProsodyNode 调整语速、音调与音量
+type ProsodyNode struct {
+	Rate     string
+	Pitch    string
+	Volume   string
+	Children []Node
+}
+
+// Prosody 创建韵律元素
+func Prosody(rate, pitch, volume string, children ...Node) *ProsodyNode {
+	return &ProsodyNode{Rate: rate, Pitch: pitch, Volume: volume, Children: children}
+}
+
+func (n *ProsodyNode) render(sb *strings.Builder) {
+	element(sb, "prosody", []attr{{"rate", n.Rate}, {"pitch", n.Pitch}, {"volume", n.Volume}}, n.Children)
+}
+
+// BreakNode 插入停顿
+type BreakNode struct {
+	Time     string
+	Strength string
+}
+
+// Break 创建指定时长的停顿，如 "500ms"
+func Break(time string) *BreakNode {
+	return &BreakNode{Time: time}
+}
+
+func (n *BreakNode) render(sb *strings.Builder) {
+	element(sb, "break", []attr{{"time", n.Time}, {"strength", n.Strength}}, nil)
+}
+
+// ExpressAsNode 指定说话风格（Azure 扩展）
+type ExpressAsNode struct {
+	Style       string
+	StyleDegree string
+	Role        string
+	Children    []Node
+}
+
+// ExpressAs 创建说话风格元素
+func ExpressAs(style, styleDegree, role string, children ...Node) *ExpressAsNode {
+	return &ExpressAsNode{Style: style, StyleDegree: styleDegree, Role: role, Children: children}
+}
+
+func (n *ExpressAsNode) render(sb *strings.Builder) {
+	element(sb, "mstts:express-as", []attr{{"style", n.Style}, {"styledegree", n.StyleDegree}, {"role", n.Role}}, n.Children)
+}
+
+// PhonemeNode 指定文本的发音
+type PhonemeNode struct {
+	Alphabet string
+	Ph       string
+	Text     string
+}
+
+// Phoneme 创建发音元素，如 Phoneme("sapi", "chong 2 qing 4", "重庆")
+func Phoneme(alphabet, ph, text string) *PhonemeNode {
+	return &PhonemeNode{Alphabet: alphabet, Ph: ph, Text: text}
+}
+
+func (n *PhonemeNode) render(sb *strings.Builder) {
+	element(sb, "phoneme", []attr{{"alphabet", n.Alphabet}, {"ph", n.Ph}}, []Node{Text(n.Text)})
+}
+
+// SubNode 使用别名替换朗读内容
+type SubNode struct {
+	Alias string
+	Text  string
+}
+
+// Sub 创建替换元素
+func Sub(alias, text string) *SubNode {
+	return &SubNode{Alias: alias, Text: text}
+}
+
+func (n *SubNode) render(sb *strings.Builder) {
+	element(sb, "sub", []attr{{"alias", n.Alias}}, []Node{Text(n.Text)})
+}
+
+// SayAsNode 指定内容的解读方式
+type SayAsNode struct {
+	InterpretAs string
+	Format      string
+	Text        string
+}
+
+// SayAs 创建解读方式元素，如 SayAs("date", "ymd", "2024-01-02")
+func SayAs(interpretAs, format, text string) *SayAsNode {
+	return &SayAsNode{InterpretAs: interpretAs, Format: format, Text: text}
+}
+
+func (n *SayAsNode) render(sb *strings.Builder) {
+	element(sb, "say-as", []attr{{"interpret-as", n.InterpretAs}, {"format", n.Format}}, []Node{Text(n.Text)})
+}
+
+// EmphasisNode 强调内容
+type EmphasisNode struct {
+	Level    string
+	Children []Node
+}
+
+// Emphasis 创建强调元素，level 可为 strong、moderate、reduced
+func Emphasis(level string, children ...Node) *EmphasisNode {
+	return &EmphasisNode{Level: level, Children: children}
+}
+
+func (n *EmphasisNode) render(sb *strings.Builder) {
+	element(sb, "emphasis", []attr{{"level", n.Level}}, n.Children)
+}
+
+// LangNode 在多语言语音中切换朗读的语言
+type LangNode struct {
+	Lang     string
+	Children []Node
+}
+
+// Lang 创建语言元素，如 Lang("ar-SA", Text("مرحبا"))
+func Lang(lang string, children ...Node) *LangNode {
+	return &LangNode{Lang: lang, Children: children}
+}
+
+func (n *LangNode) render(sb *strings.Builder) {
+	element(sb, "lang", []attr{{"xml:lang", n.Lang}}, n.Children)
+}
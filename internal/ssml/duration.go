@@ -0,0 +1,73 @@
+package ssml
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tts/internal/utils"
+)
+
+var (
+	// breakTagRegex 匹配文本中的停顿标签
+	breakTagRegex = regexp.MustCompile(`<break\b[^>]*>`)
+	// breakTimeRegex 提取停顿标签的 time 属性
+	breakTimeRegex = regexp.MustCompile(`time\s*=\s*["']\s*([\d.]+)\s*(ms|s)\s*["']`)
+	// breakStrengthRegex 提取停顿标签的 strength 属性
+	breakStrengthRegex = regexp.MustCompile(`strength\s*=\s*["']\s*([\w-]+)\s*["']`)
+	// tagRegex 匹配任意标签，用于估算时去除标记
+	tagRegex = regexp.MustCompile(`<[^>]*>`)
+)
+
+// breakStrengths 是各停顿强度对应的时长（Azure 文档给出的默认值）
+var breakStrengths = map[string]time.Duration{
+	"none":     0,
+	"x-weak":   250 * time.Millisecond,
+	"weak":     500 * time.Millisecond,
+	"medium":   750 * time.Millisecond,
+	"strong":   1000 * time.Millisecond,
+	"x-strong": 1250 * time.Millisecond,
+}
+
+// BreakDuration 返回文本中所有停顿标签的总时长
+func BreakDuration(text string) time.Duration {
+	var total time.Duration
+	for _, tag := range breakTagRegex.FindAllString(text, -1) {
+		if m := breakTimeRegex.FindStringSubmatch(tag); m != nil {
+			value, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				continue
+			}
+			if m[2] == "s" {
+				value *= 1000
+			}
+			total += time.Duration(value * float64(time.Millisecond))
+			continue
+		}
+		strength := "medium"
+		if m := breakStrengthRegex.FindStringSubmatch(tag); m != nil {
+			strength = strings.ToLower(m[1])
+		}
+		total += breakStrengths[strength]
+	}
+	return total
+}
+
+// EstimateDuration 估算文本合成后的音频时长：朗读时长按可见字符数与语速估算，再加上停顿时长。
+// rate 为 Azure 语速百分比（如 "-20"），charsPerSecond 为 0% 语速下每秒朗读的字符数。
+func EstimateDuration(text, rate string, charsPerSecond float64) time.Duration {
+	if charsPerSecond <= 0 {
+		return BreakDuration(text)
+	}
+	speed := 1.0
+	if r, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(rate), "%"), 64); err == nil {
+		speed = 1 + r/100
+	}
+	if speed < 0.1 {
+		speed = 0.1
+	}
+	chars := utils.GraphemeCount(strings.TrimSpace(tagRegex.ReplaceAllString(text, "")))
+	spoken := time.Duration(float64(chars) / (charsPerSecond * speed) * float64(time.Second))
+	return spoken + BreakDuration(text)
+}
@@ -0,0 +1,90 @@
+package ssml
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// HintPattern 匹配行内读音提示 {文字|读音}，如 {重庆|chóng qìng}、{AI|人工智能}
+var HintPattern = regexp.MustCompile(`\{([^{}|\n]+)\|([^{}\n]+)\}`)
+
+// toneMarks 把带声调符号的元音映射为不带声调的字母与声调
+var toneMarks = map[rune]struct {
+	base rune
+	tone byte
+}{
+	'ā': {'a', '1'}, 'á': {'a', '2'}, 'ǎ': {'a', '3'}, 'à': {'a', '4'},
+	'ē': {'e', '1'}, 'é': {'e', '2'}, 'ě': {'e', '3'}, 'è': {'e', '4'},
+	'ī': {'i', '1'}, 'í': {'i', '2'}, 'ǐ': {'i', '3'}, 'ì': {'i', '4'},
+	'ō': {'o', '1'}, 'ó': {'o', '2'}, 'ǒ': {'o', '3'}, 'ò': {'o', '4'},
+	'ū': {'u', '1'}, 'ú': {'u', '2'}, 'ǔ': {'u', '3'}, 'ù': {'u', '4'},
+	'ǖ': {'v', '1'}, 'ǘ': {'v', '2'}, 'ǚ': {'v', '3'}, 'ǜ': {'v', '4'},
+	'ń': {'n', '2'}, 'ň': {'n', '3'}, 'ǹ': {'n', '4'}, 'ḿ': {'m', '2'},
+}
+
+// ExpandHints 把行内读音提示转换为SSML标签：
+// 读音是拼音时生成 <phoneme alphabet="sapi">，如 {重庆|chóng qìng} 生成 ph="chong 2 qing 4"；
+// 否则生成 <sub>，如 {AI|人工智能}。
+func ExpandHints(text string) string {
+	if !strings.Contains(text, "|") {
+		return text
+	}
+	return HintPattern.ReplaceAllStringFunc(text, func(m string) string {
+		sub := HintPattern.FindStringSubmatch(m)
+		word, hint := sub[1], strings.TrimSpace(sub[2])
+		if ph, ok := PinyinToSAPI(hint); ok {
+			return `<phoneme alphabet="sapi" ph="` + html.EscapeString(ph) + `">` + word + `</phoneme>`
+		}
+		return `<sub alias="` + html.EscapeString(hint) + `">` + word + `</sub>`
+	})
+}
+
+// PinyinToSAPI 把带声调符号或数字的拼音转换为 SAPI 格式，如 "chóng qìng" 或 "chong2 qing4" 转换为 "chong 2 qing 4"。
+// 音节之间用空格或 ' 分隔，没有声调的音节按轻声 (5) 处理，ü 写作 v。
+// 不是拼音或所有音节都没有声调时返回 false。
+func PinyinToSAPI(pinyin string) (string, bool) {
+	syllables := strings.FieldsFunc(strings.ToLower(pinyin), func(r rune) bool {
+		return r == ' ' || r == '\'' || r == '’' || r == '-'
+	})
+	if len(syllables) == 0 {
+		return "", false
+	}
+
+	toned := false
+	parts := make([]string, 0, len(syllables))
+	for _, syllable := range syllables {
+		var letters strings.Builder
+		var tone byte
+		for _, r := range syllable {
+			switch {
+			case r >= 'a' && r <= 'z':
+				letters.WriteRune(r)
+			case r == 'ü':
+				letters.WriteRune('v')
+			case r >= '1' && r <= '5' && tone == 0:
+				tone = byte(r)
+			default:
+				mark, ok := toneMarks[r]
+				if !ok || tone != 0 {
+					return "", false
+				}
+				letters.WriteRune(mark.base)
+				tone = mark.tone
+			}
+		}
+		if letters.Len() == 0 {
+			return "", false
+		}
+		if tone == 0 {
+			tone = '5'
+		} else {
+			toned = true
+		}
+		parts = append(parts, letters.String()+" "+string(tone))
+	}
+	if !toned {
+		return "", false
+	}
+	return strings.Join(parts, " "), true
+}
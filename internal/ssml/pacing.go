@@ -0,0 +1,152 @@
+package ssml
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 未配置时按难度调整语速的参数
+const (
+	defaultPacingSlowRate        = "-15%"
+	defaultPacingFastRate        = "+8%"
+	defaultPacingDenseThreshold  = 0.25
+	defaultPacingSimpleThreshold = 0.05
+	defaultPacingLongWord        = 12
+	defaultPacingMinWords        = 6
+)
+
+var (
+	// pacingSentence 匹配一个句子：到句末标点（英文句点需后接空白或位于末尾，避免切开 3.14、example.com）或换行为止
+	pacingSentence = regexp.MustCompile(`(?s).*?(?:[。！？!?；;…]+["'”’）)]*|\.(?:\s|$)|\n|$)`)
+	// pacingToken 匹配拉丁字母、数字组成的词，可以由 . _ : / - 连接（如 os.Getenv、max_concurrent、v1.2.3），可以带 ()
+	pacingToken = regexp.MustCompile(`[A-Za-z0-9_]+(?:(?:::|[._:/-])[A-Za-z0-9_]+)*(?:\(\))?`)
+	// pacingCamel 匹配驼峰命名中小写字母后的大写字母，如 maxConcurrent、HTTPServer 中的 pS
+	pacingCamel = regexp.MustCompile(`[a-z0-9][A-Z]`)
+	// pacingMember 匹配以点连接的成员访问，如 os.Getenv、config.yaml，不匹配 e.g 等缩写
+	pacingMember = regexp.MustCompile(`[A-Za-z_]\w*\.[A-Za-z_]\w+`)
+)
+
+// Pacing 按句子的难度调整语速：数字、长单词与代码标识符密集的句子放慢，便于听清；
+// 不含这些内容的普通叙述句稍微加快，整体时长变化不大
+type Pacing struct {
+	slowRate        string
+	fastRate        string
+	denseThreshold  float64
+	simpleThreshold float64
+	longWord        int
+	minWords        int
+}
+
+// PacingOptions 是按难度调整语速的参数，零值使用默认值
+type PacingOptions struct {
+	SlowRate        string  // 难句的语速，默认 -15%
+	FastRate        string  // 简单句的语速，默认 +8%，"0%" 表示不加快
+	DenseThreshold  float64 // 难度不低于该值的句子放慢，默认 0.25
+	SimpleThreshold float64 // 难度不高于该值的句子加快，默认 0.05
+	LongWord        int     // 达到该长度的英文单词算作长单词，默认 12
+	MinWords        int     // 少于该词数的短句（如“好的。”）不加快，默认 6
+}
+
+// NewPacing 创建按难度调整语速的处理器
+func NewPacing(opts PacingOptions) *Pacing {
+	p := &Pacing{
+		slowRate:        or(opts.SlowRate, defaultPacingSlowRate),
+		fastRate:        or(opts.FastRate, defaultPacingFastRate),
+		denseThreshold:  opts.DenseThreshold,
+		simpleThreshold: opts.SimpleThreshold,
+		longWord:        opts.LongWord,
+		minWords:        opts.MinWords,
+	}
+	if p.denseThreshold <= 0 {
+		p.denseThreshold = defaultPacingDenseThreshold
+	}
+	if p.simpleThreshold <= 0 {
+		p.simpleThreshold = defaultPacingSimpleThreshold
+	}
+	if p.longWord <= 0 {
+		p.longWord = defaultPacingLongWord
+	}
+	if p.minWords <= 0 {
+		p.minWords = defaultPacingMinWords
+	}
+	return p
+}
+
+// Apply 把难句与简单句分别包在 <prosody rate> 中，语速相对于请求的语速调整，其他句子保持原样。
+// 文本已包含 <prosody> 时由调用方控制语速，不做处理；含有其他标签的句子也保持原样，避免包装后标签嵌套错乱
+func (p *Pacing) Apply(text string) string {
+	if p == nil || strings.Contains(text, "<prosody") {
+		return text
+	}
+	var sb strings.Builder
+	for _, sentence := range pacingSentence.FindAllString(text, -1) {
+		sb.WriteString(p.wrap(sentence))
+	}
+	return sb.String()
+}
+
+// wrap 按难度包装一个句子，句首与句尾的空白留在 <prosody> 外
+func (p *Pacing) wrap(sentence string) string {
+	body := strings.TrimSpace(sentence)
+	if body == "" || strings.ContainsAny(body, "<>") {
+		return sentence
+	}
+	score, words := Difficulty(body, p.longWord)
+	var rate string
+	switch {
+	case score >= p.denseThreshold:
+		rate = p.slowRate
+	case score <= p.simpleThreshold && words >= p.minWords:
+		rate = p.fastRate
+	}
+	if rate == "" || rate == "0%" {
+		return sentence
+	}
+	start := strings.Index(sentence, body)
+	return sentence[:start] + `<prosody rate="` + attrEscaper.Replace(rate) + `">` + body + "</prosody>" + sentence[start+len(body):]
+}
+
+// Difficulty 返回句子的难度与词数。难度为数字、长单词与代码标识符（标识符计两次）在词中的占比，
+// 中文每两个汉字计为一个词
+func Difficulty(sentence string, longWord int) (float64, int) {
+	han := 0
+	for _, r := range sentence {
+		if unicode.Is(unicode.Han, r) {
+			han++
+		}
+	}
+	words := (han + 1) / 2
+	dense := 0
+	for _, token := range pacingToken.FindAllString(sentence, -1) {
+		words++
+		switch {
+		case isIdentifier(token):
+			dense += 2
+		case token[0] >= '0' && token[0] <= '9':
+			dense++
+		case utf8.RuneCountInString(token) >= longWord:
+			dense++
+		}
+	}
+	if words == 0 {
+		return 0, 0
+	}
+	return float64(dense) / float64(words), words
+}
+
+// isIdentifier 判断词是否像代码标识符：包含下划线、::、()、斜杠或成员访问的点，或为驼峰命名
+func isIdentifier(token string) bool {
+	if strings.ContainsAny(token, "_/") || strings.Contains(token, "::") || strings.HasSuffix(token, "()") {
+		return true
+	}
+	return pacingMember.MatchString(token) || pacingCamel.MatchString(token)
+}
+
+func or(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
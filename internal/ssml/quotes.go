@@ -0,0 +1,208 @@
+package ssml
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// quoteMaxLength 是一段对话的最大长度（字符），更长的引号通常是不成对的引号
+	quoteMaxLength = 500
+	// attributionWindow 是在引号前后查找“说”“said”等引述词的范围（字符）
+	attributionWindow = 12
+)
+
+// quotePairs 是识别的引号，键为开引号，值为对应的闭引号。单引号容易与英文的撇号混淆，不识别
+var quotePairs = map[rune]rune{
+	'“': '”',
+	'「': '」',
+	'『': '』',
+	'"': '"',
+}
+
+// defaultAttributionWords 是默认的引述词：中文按子串匹配，英文按整词匹配（不区分大小写）。
+// “道”“叫”“称”单独出现时多是“知道”“叫做”“称为”，只识别“笑道”等组合
+var defaultAttributionWords = []string{
+	"说", "问", "答", "喊", "嚷", "表示", "回答", "笑道", "叹道", "叫道", "骂道", "写道",
+	"said", "says", "say", "asked", "asks", "replied", "replies", "answered", "shouted",
+	"whispered", "cried", "added", "told", "explained", "continued",
+}
+
+// QuoteRun 是按引号切分的一段文本，Quote 为 true 时是对话（包括引号本身）
+type QuoteRun struct {
+	Text  string
+	Quote bool
+}
+
+// Quotes 识别文本中引号内的对话：引号前后有引述词（他说：“……”、"……," she said），
+// 或引号内容以句末标点结尾（“你好！”）时认为是对话；强调用的引号（所谓“智能”）保持为叙述
+type Quotes struct {
+	words              []string
+	requireAttribution bool
+}
+
+// NewQuotes 创建对话识别器。words 追加到默认的引述词之后；
+// requireAttribution 为 true 时只有前后有引述词的引号才是对话
+func NewQuotes(words []string, requireAttribution bool) *Quotes {
+	q := &Quotes{words: append([]string{}, defaultAttributionWords...), requireAttribution: requireAttribution}
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			q.words = append(q.words, word)
+		}
+	}
+	return q
+}
+
+// Split 把文本切分为叙述与对话交替的片段，没有对话时返回整段文本。
+// 只在标签之外识别引号，切分点不会落在元素内部，每个片段中的标签仍然成对
+func (q *Quotes) Split(text string) []QuoteRun {
+	var runs []QuoteRun
+	last := 0
+	depth := 0
+	for i := 0; i < len(text); {
+		if text[i] == '<' {
+			if end, delta, ok := scanElement(text, i); ok {
+				depth = max(0, depth+delta)
+				i = end
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		closing, ok := quotePairs[r]
+		if !ok || depth != 0 {
+			i += size
+			continue
+		}
+		end, ok := findClosing(text, i+size, closing)
+		if !ok {
+			i += size
+			continue
+		}
+		if !q.dialogue(text[:i], text[i+size:end-utf8.RuneLen(closing)], text[end:]) {
+			// 整个引号跳过，避免英文引号的闭引号被当作下一个开引号
+			i = end
+			continue
+		}
+		if i > last {
+			runs = append(runs, QuoteRun{Text: text[last:i]})
+		}
+		runs = append(runs, QuoteRun{Text: text[i:end], Quote: true})
+		last, i = end, end
+	}
+	if last == 0 {
+		return []QuoteRun{{Text: text}}
+	}
+	if last < len(text) {
+		runs = append(runs, QuoteRun{Text: text[last:]})
+	}
+	return runs
+}
+
+// scanElement 识别从 i 开始的标签，返回标签之后的位置与嵌套深度的变化：开始标签 +1，结束标签 -1，自闭合标签 0。
+// 文本中的 < 不是标签时返回 false
+func scanElement(text string, i int) (int, int, bool) {
+	end := strings.IndexAny(text[i+1:], "<>")
+	if end < 0 || text[i+1+end] != '>' {
+		return 0, 0, false
+	}
+	tag := text[i : i+1+end+1]
+	c := tag[1]
+	switch {
+	case c == '/':
+		return i + len(tag), -1, true
+	case !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'):
+		return 0, 0, false
+	case strings.HasSuffix(tag, "/>"):
+		return i + len(tag), 0, true
+	}
+	return i + len(tag), 1, true
+}
+
+// findClosing 从 start 开始查找同一段落中的闭引号，返回闭引号之后的位置。
+// 引号内有标签、换行或超过最大长度时不认为是对话，不构成标签的 < 按普通字符处理
+func findClosing(text string, start int, closing rune) (int, bool) {
+	count := 0
+	for i := start; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == closing:
+			return i + size, i > start
+		case r == '\n':
+			return 0, false
+		case r == '<':
+			if _, _, ok := scanElement(text, i); ok {
+				return 0, false
+			}
+		}
+		count++
+		if count > quoteMaxLength {
+			return 0, false
+		}
+		i += size
+	}
+	return 0, false
+}
+
+// dialogue 判断引号中的内容是否是对话
+func (q *Quotes) dialogue(before, content, after string) bool {
+	if q.attributed(tail(before, attributionWindow)) || q.attributed(head(after, attributionWindow)) {
+		return true
+	}
+	if q.requireAttribution {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(strings.TrimSpace(content))
+	return strings.ContainsRune("。！？!?…,，.~～", last)
+}
+
+// attributed 判断一段文本中是否有引述词
+func (q *Quotes) attributed(text string) bool {
+	text = strings.ToLower(text)
+	var fields []string
+	for _, word := range q.words {
+		if word[0] >= utf8.RuneSelf {
+			if strings.Contains(text, word) {
+				return true
+			}
+			continue
+		}
+		if fields == nil {
+			fields = strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) })
+		}
+		for _, field := range fields {
+			if field == word {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tail 返回文本末尾的 n 个字符，从最近的段落或句子开始，去掉标签
+func tail(text string, n int) string {
+	if i := strings.LastIndexAny(text, "\n。！？!?"); i >= 0 {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		text = text[i+size:]
+	}
+	if i := strings.LastIndex(text, ". "); i >= 0 {
+		text = text[i+2:]
+	}
+	text = tagPattern.ReplaceAllString(text, "")
+	if count := utf8.RuneCountInString(text); count > n {
+		text = string([]rune(text)[count-n:])
+	}
+	return text
+}
+
+// head 返回文本开头的 n 个字符，到最近的段落、句子结尾或下一个引号为止，去掉标签
+func head(text string, n int) string {
+	text = tagPattern.ReplaceAllString(text, "")
+	if i := strings.IndexAny(text, "\n。！？!?“「『\""); i >= 0 {
+		text = text[:i]
+	}
+	if utf8.RuneCountInString(text) > n {
+		text = string([]rune(text)[:n])
+	}
+	return text
+}
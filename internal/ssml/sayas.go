@@ -0,0 +1,124 @@
+package ssml
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SayAsRules 控制自动添加 <say-as> 的实体类型
+type SayAsRules struct {
+	Ordinal   bool // 英文序数词，如 1st、22nd
+	Fraction  bool // 分数，如 3/4
+	Telephone bool // 电话号码，如 138-0013-8000、(555) 123-4567
+	Address   bool // 门牌地址，如 221 Baker Street、中山路88号
+	Date      bool // ISO 日期，如 2024-05-01
+	Currency  bool // 金额，如 $9.99、¥100、20 USD
+}
+
+// Any 判断是否启用了任一实体类型
+func (r SayAsRules) Any() bool {
+	return r.Ordinal || r.Fraction || r.Telephone || r.Address || r.Date || r.Currency
+}
+
+// sayAsRule 是一种实体的识别规则
+type sayAsRule struct {
+	enabled     func(SayAsRules) bool
+	interpretAs string
+	format      string
+	regex       *regexp.Regexp
+	// isolated 中的字符不能紧挨着匹配的内容，如分数前后不能是数字或 /，避免把 12/25/2024 当作分数
+	isolated string
+}
+
+// sayAsRules 按优先级排列，先匹配的实体不会再被后面的规则处理，如 ISO 日期不会被当作电话号码
+var sayAsRules = []sayAsRule{
+	{
+		enabled:     func(r SayAsRules) bool { return r.Date },
+		interpretAs: "date",
+		format:      "ymd",
+		regex:       regexp.MustCompile(`\b\d{4}-(?:0[1-9]|1[0-2])-(?:0[1-9]|[12]\d|3[01])\b`),
+	},
+	{
+		enabled:     func(r SayAsRules) bool { return r.Currency },
+		interpretAs: "currency",
+		regex:       regexp.MustCompile(`[$€£¥￥]\s?\d{1,3}(?:,\d{3})*(?:\.\d+)?|\b\d{1,3}(?:,\d{3})*(?:\.\d+)?\s?(?:USD|EUR|GBP|CNY|RMB|JPY|HKD)\b`),
+	},
+	{
+		enabled:     func(r SayAsRules) bool { return r.Telephone },
+		interpretAs: "telephone",
+		regex: regexp.MustCompile(`(?:\+\d{1,3}[ -]?)?(?:` +
+			`\(\d{3}\) ?\d{3}-\d{4}|` + // (555) 123-4567
+			`\b1[3-9]\d-?\d{4}-?\d{4}|` + // 13800138000、138-0013-8000
+			`\b0\d{2,3}-\d{7,8}|` + // 010-12345678
+			`\b\d{3}-\d{3}-\d{4}` + // 555-123-4567
+			`)\b`),
+	},
+	{
+		enabled:     func(r SayAsRules) bool { return r.Address },
+		interpretAs: "address",
+		regex: regexp.MustCompile(`\b\d{1,5} (?:[A-Z][a-z]+ ){1,3}(?:Street|St\.|Avenue|Ave\.|Road|Rd\.|Boulevard|Blvd\.|Lane|Ln\.|Drive|Dr\.|Way|Court|Ct\.)|` +
+			`\p{Han}{1,8}(?:路|街|大道|巷|胡同)\d{1,5}号`),
+	},
+	{
+		enabled:     func(r SayAsRules) bool { return r.Fraction },
+		interpretAs: "fraction",
+		regex:       regexp.MustCompile(`\d{1,3}/\d{1,3}`),
+		isolated:    "0123456789/",
+	},
+	{
+		enabled:     func(r SayAsRules) bool { return r.Ordinal },
+		interpretAs: "ordinal",
+		regex:       regexp.MustCompile(`\b\d+(?:st|nd|rd|th)\b`),
+	},
+}
+
+// InferSayAs 识别文本中的日期、金额、电话号码、地址、分数与序数词，按 rules 为启用的类型添加 <say-as> 标签。
+// 已有的标签原样保留，<say-as>、<phoneme>、<sub> 内部的内容不做处理。
+func InferSayAs(text string, rules SayAsRules) string {
+	if !rules.Any() {
+		return text
+	}
+	return mapText(text, func(s string) string {
+		return inferSayAs(s, rules)
+	})
+}
+
+// inferSayAs 在一段不含标签的文本中依次应用各规则，已标注的实体作为整体跳过
+func inferSayAs(s string, rules SayAsRules) string {
+	if s == "" || !strings.ContainsAny(s, "0123456789") {
+		return s
+	}
+	// pieces 中奇数下标是已生成的 <say-as> 标签，不再参与匹配
+	pieces := []string{s}
+	for _, rule := range sayAsRules {
+		if !rule.enabled(rules) {
+			continue
+		}
+		var next []string
+		for i, piece := range pieces {
+			if i%2 == 1 {
+				next = append(next, piece)
+				continue
+			}
+			next = append(next, rule.apply(piece)...)
+		}
+		pieces = next
+	}
+	return strings.Join(pieces, "")
+}
+
+// apply 把文本切分为 文本、标签、文本、标签……、文本 交替的片段
+func (r sayAsRule) apply(s string) []string {
+	var pieces []string
+	last := 0
+	for _, loc := range r.regex.FindAllStringIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		if r.isolated != "" && ((start > 0 && strings.IndexByte(r.isolated, s[start-1]) >= 0) ||
+			(end < len(s) && strings.IndexByte(r.isolated, s[end]) >= 0)) {
+			continue
+		}
+		pieces = append(pieces, s[last:start], Render(SayAs(r.interpretAs, r.format, s[start:end])))
+		last = end
+	}
+	return append(pieces, s[last:])
+}
@@ -0,0 +1,45 @@
+package ssml
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// tagPattern 匹配任意标签
+	tagPattern = regexp.MustCompile(`<[^<>]+>`)
+	// protectedElements 中的内容已经指定了读法
+	protectedElements = regexp.MustCompile(`^<(/?)(say-as|phoneme|sub)\b[^>]*?(/?)>$`)
+)
+
+// mapText 对标签之间的文本调用 fn，标签原样保留，<say-as>、<phoneme>、<sub> 内部的内容不做处理
+func mapText(text string, fn func(string) string) string {
+	var sb strings.Builder
+	last := 0
+	inside := ""
+	for _, loc := range tagPattern.FindAllStringIndex(text, -1) {
+		if inside == "" {
+			sb.WriteString(fn(text[last:loc[0]]))
+		} else {
+			sb.WriteString(text[last:loc[0]])
+		}
+		tag := text[loc[0]:loc[1]]
+		sb.WriteString(tag)
+		last = loc[1]
+
+		if m := protectedElements.FindStringSubmatch(tag); m != nil {
+			switch {
+			case m[1] == "/" && m[2] == inside:
+				inside = ""
+			case m[1] == "" && m[3] == "" && inside == "":
+				inside = m[2]
+			}
+		}
+	}
+	if inside == "" {
+		sb.WriteString(fn(text[last:]))
+	} else {
+		sb.WriteString(text[last:])
+	}
+	return sb.String()
+}
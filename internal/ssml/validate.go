@@ -0,0 +1,155 @@
+// Package ssml 负责 SSML 文档的构建与校验。
+package ssml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// NamespaceSynthesis 是 SSML 的默认命名空间
+	NamespaceSynthesis = "http://www.w3.org/2001/10/synthesis"
+	// NamespaceMSTTS 是 Azure 扩展元素的命名空间
+	NamespaceMSTTS = "http://www.w3.org/2001/mstts"
+	// namespaceXML 是 xml: 前缀对应的命名空间
+	namespaceXML = "http://www.w3.org/XML/1998/namespace"
+)
+
+// allowedElements 列出 Azure 支持的元素及其允许的属性
+var allowedElements = map[string]map[string]bool{
+	"speak":                  attrs("version", "xml:lang", "xmlns", "xmlns:mstts", "xmlns:emo"),
+	"voice":                  attrs("name", "effect", "xml:lang"),
+	"prosody":                attrs("rate", "pitch", "volume", "contour", "range"),
+	"break":                  attrs("time", "strength"),
+	"emphasis":               attrs("level"),
+	"say-as":                 attrs("interpret-as", "format", "detail"),
+	"phoneme":                attrs("alphabet", "ph"),
+	"audio":                  attrs("src"),
+	"p":                      attrs("xml:lang"),
+	"s":                      attrs("xml:lang"),
+	"sub":                    attrs("alias"),
+	"lang":                   attrs("xml:lang"),
+	"lexicon":                attrs("uri"),
+	"bookmark":               attrs("mark"),
+	"mstts:express-as":       attrs("style", "styledegree", "role"),
+	"mstts:silence":          attrs("type", "value"),
+	"mstts:viseme":           attrs("type"),
+	"mstts:backgroundaudio":  attrs("src", "volume", "fadein", "fadeout"),
+	"mstts:audioduration":    attrs("value"),
+	"mstts:ttsembedding":     attrs("speakerProfileId"),
+	"mstts:voiceconversion":  attrs("url"),
+	"mstts:dialog":           attrs(),
+	"mstts:turn":             attrs("speaker"),
+	"mstts:paralinguistics":  attrs(),
+	"mstts:prosodycontrol":   attrs(),
+	"mstts:express-as-group": attrs(),
+}
+
+func attrs(names ...string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, name := range names {
+		m[name] = true
+	}
+	return m
+}
+
+// ValidationError 描述 SSML 校验失败的位置与原因
+type ValidationError struct {
+	Line    int    // 出错的行号（从 1 开始）
+	Column  int    // 出错的列号（从 1 开始）
+	Tag     string // 出错的元素名，语法错误时为空
+	Message string
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	if e.Tag != "" {
+		return fmt.Sprintf("第 %d 行第 %d 列 <%s>: %s", e.Line, e.Column, e.Tag, e.Message)
+	}
+	return fmt.Sprintf("第 %d 行第 %d 列: %s", e.Line, e.Column, e.Message)
+}
+
+// qualifiedName 将解析后的名称还原为带前缀的形式
+func qualifiedName(name xml.Name) string {
+	switch name.Space {
+	case "", NamespaceSynthesis:
+		return name.Local
+	case NamespaceMSTTS, "mstts":
+		return "mstts:" + name.Local
+	case namespaceXML, "xml":
+		return "xml:" + name.Local
+	case "xmlns":
+		return "xmlns:" + name.Local
+	default:
+		return name.Space + ":" + name.Local
+	}
+}
+
+// Validate 解析完整的 SSML 文档，检查 XML 是否合法、根元素是否为 speak，
+// 以及所有元素和属性是否在 Azure 支持的范围内。
+func Validate(doc string) error {
+	decoder := xml.NewDecoder(strings.NewReader(doc))
+	depth := 0
+	sawRoot := false
+
+	for {
+		line, column := decoder.InputPos()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line, column = decoder.InputPos()
+				return &ValidationError{Line: syntaxErr.Line, Column: column, Message: syntaxErr.Msg}
+			}
+			return &ValidationError{Line: line, Column: column, Message: err.Error()}
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := qualifiedName(t.Name)
+			if depth == 0 {
+				if sawRoot {
+					return &ValidationError{Line: line, Column: column, Tag: name, Message: "文档只能有一个根元素"}
+				}
+				if name != "speak" {
+					return &ValidationError{Line: line, Column: column, Tag: name, Message: "根元素必须是 speak"}
+				}
+				sawRoot = true
+			} else if name == "speak" {
+				return &ValidationError{Line: line, Column: column, Tag: name, Message: "speak 只能作为根元素"}
+			}
+
+			allowed, ok := allowedElements[name]
+			if !ok {
+				return &ValidationError{Line: line, Column: column, Tag: name, Message: "不支持的元素"}
+			}
+			for _, attr := range t.Attr {
+				attrName := qualifiedName(attr.Name)
+				if !allowed[attrName] {
+					return &ValidationError{Line: line, Column: column, Tag: name,
+						Message: fmt.Sprintf("不支持的属性 %s", attrName)}
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && strings.TrimSpace(string(t)) != "" {
+				return &ValidationError{Line: line, Column: column, Message: "根元素之外不能有文本"}
+			}
+		case xml.Directive:
+			return &ValidationError{Line: line, Column: column, Message: "不允许使用 DOCTYPE 等指令"}
+		}
+	}
+
+	if !sawRoot {
+		return &ValidationError{Line: 1, Column: 1, Message: "缺少 speak 根元素"}
+	}
+	return nil
+}
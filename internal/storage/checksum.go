@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sync"
+	"time"
+)
+
+// checksumCacheSize 是内存中最多保存的文件哈希数，超过时清空重新计算
+const checksumCacheSize = 1024
+
+// Checksum 返回数据的 SHA-256 十六进制字符串
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checksums 缓存已计算的文件哈希，文件的大小或修改时间变化时重新计算
+type checksums struct {
+	mu      sync.Mutex
+	entries map[string]checksumEntry
+}
+
+// checksumEntry 是一个文件的哈希与计算时的大小、修改时间
+type checksumEntry struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+// Checksum 返回文件完整内容的 SHA-256，分块保存的文件直接使用清单中的值，其他文件读取计算后缓存
+func (s *Storage) Checksum(key string) (string, error) {
+	if manifest, ok := s.manifest(key); ok && manifest.SHA256 != "" {
+		return manifest.SHA256, nil
+	}
+	file, err := s.Open(key)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	s.sums.mu.Lock()
+	entry, ok := s.sums.entries[key]
+	s.sums.mu.Unlock()
+	if ok && entry.size == file.Size() && entry.modTime.Equal(file.ModTime()) {
+		return entry.sum, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	s.sums.mu.Lock()
+	defer s.sums.mu.Unlock()
+	if s.sums.entries == nil || len(s.sums.entries) >= checksumCacheSize {
+		s.sums.entries = make(map[string]checksumEntry)
+	}
+	s.sums.entries[key] = checksumEntry{size: file.Size(), modTime: file.ModTime(), sum: sum}
+	return sum, nil
+}
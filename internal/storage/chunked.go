@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+const (
+	// ManifestSuffix 是分块保存的文件清单的键后缀，清单与文件同名，如 book.mp3.manifest.json
+	ManifestSuffix = ".manifest.json"
+	// partsSuffix 是保存各部分的目录的键后缀
+	partsSuffix = ".parts"
+)
+
+// Manifest 是分块保存的文件清单，各部分按顺序拼接即为完整文件
+type Manifest struct {
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"` // 完整文件的哈希
+	ChunkSize int64     `json:"chunk_size"`
+	Created   time.Time `json:"created"`
+	Parts     []Part    `json:"parts"`
+}
+
+// Part 是分块保存的文件的一部分
+type Part struct {
+	Key    string `json:"key"`
+	URL    string `json:"url"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// putChunked 把文件按 chunkSize 分块保存，最后写入清单，sum 为完整文件的哈希。之前不分块保存的同名文件在清单写入后才删除，
+// 之前分块保存的同名文件先删除，避免留下多余的部分
+func (s *Storage) putChunked(key string, data []byte, sum string) error {
+	if err := s.removeChunks(key); err != nil {
+		return err
+	}
+	manifest := Manifest{Key: key, Size: int64(len(data)), SHA256: sum, ChunkSize: s.chunkSize, Created: time.Now().UTC()}
+	for offset := int64(0); offset < manifest.Size; offset += s.chunkSize {
+		end := min(offset+s.chunkSize, manifest.Size)
+		partKey := path.Join(key+partsSuffix, fmt.Sprintf("%05d", len(manifest.Parts)))
+		if err := s.putFile(partKey, data[offset:end]); err != nil {
+			return err
+		}
+		manifest.Parts = append(manifest.Parts, Part{
+			Key: partKey, URL: s.URL(partKey), Offset: offset, Size: end - offset, SHA256: Checksum(data[offset:end]),
+		})
+	}
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.putFile(key+ManifestSuffix, encoded); err != nil {
+		return err
+	}
+	// 之前不分块保存的同名文件
+	return s.removeFile(key)
+}
+
+// manifest 读取文件的分块清单，文件没有分块保存时返回 false
+func (s *Storage) manifest(key string) (*Manifest, bool) {
+	file, err := s.path(key + ManifestSuffix)
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, false
+	}
+	return &manifest, true
+}
+
+// openParts 打开分块保存的文件的所有部分
+func (s *Storage) openParts(manifest *Manifest, modTime time.Time) (*File, error) {
+	reader := &partsReader{size: manifest.Size}
+	for _, part := range manifest.Parts {
+		file, err := s.path(part.Key)
+		if err == nil {
+			var f *os.File
+			if f, err = os.Open(file); err == nil {
+				reader.files = append(reader.files, f)
+				reader.offsets = append(reader.offsets, part.Offset)
+				continue
+			}
+		}
+		reader.Close()
+		return nil, fmt.Errorf("打开文件的第 %d 部分失败: %w", len(reader.files)+1, err)
+	}
+	return &File{ReadSeekCloser: reader, name: path.Base(manifest.Key), size: manifest.Size, modTime: modTime}, nil
+}
+
+// partsReader 把按顺序排列的多个部分当作一个文件读取
+type partsReader struct {
+	files   []*os.File
+	offsets []int64 // 各部分在完整文件中的起始位置
+	size    int64
+	pos     int64
+}
+
+// Read 从当前位置读取，跨越部分边界时只读到当前部分的末尾
+func (r *partsReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	i := len(r.offsets) - 1
+	for i > 0 && r.offsets[i] > r.pos {
+		i--
+	}
+	n, err := r.files[i].ReadAt(p, r.pos-r.offsets[i])
+	r.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek 移动读取位置
+func (r *partsReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("无效的 whence: %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("无效的位置: %d", offset)
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+// Close 关闭所有部分
+func (r *partsReader) Close() error {
+	for _, f := range r.files {
+		f.Close()
+	}
+	return nil
+}
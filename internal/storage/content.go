@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// ContentPath 是按内容哈希提供已保存文件的路径前缀，地址中的哈希决定内容，可以永久缓存
+	ContentPath = "/cdn"
+	// contentDir 是记录哈希对应的键的目录
+	contentDir = ".content"
+)
+
+// indexContent 记录哈希对应的键，之后可以通过 ContentURL 按哈希访问
+func (s *Storage) indexContent(key, sum string) error {
+	file := filepath.Join(s.dir, contentDir, sum)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	// 相同内容的文件可能被并发保存，临时文件名唯一，避免互相覆盖或重命名失败
+	tmp, err := os.CreateTemp(filepath.Dir(file), sum+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(key)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// ContentURL 返回按内容哈希访问文件的地址，扩展名与键相同。未配置 base_url 时返回以 ContentPath 开头的相对路径
+func (s *Storage) ContentURL(key, sum string) string {
+	return s.baseURL + ContentPath + "/" + sum + path.Ext(key)
+}
+
+// OpenContent 按内容哈希打开文件，返回文件与其键。哈希对应的键已被删除或覆盖为其他内容时返回 os.ErrNotExist
+func (s *Storage) OpenContent(sum string) (*File, string, error) {
+	if !validChecksum(sum) {
+		return nil, "", fmt.Errorf("无效的哈希: %q", sum)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, contentDir, sum))
+	if err != nil {
+		return nil, "", err
+	}
+	key := string(data)
+	if current, err := s.Checksum(key); err != nil || current != sum {
+		return nil, "", os.ErrNotExist
+	}
+	file, err := s.Open(key)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, key, nil
+}
+
+// validChecksum 判断是否为小写十六进制的 SHA-256
+func validChecksum(sum string) bool {
+	return len(sum) == 64 && strings.Trim(sum, "0123456789abcdef") == ""
+}
@@ -0,0 +1,184 @@
+// Package storage 将生成的音频保存在本地目录中，并通过 HTTP 以 PublicPath 为前缀提供下载，
+// 供播客、定时任务等需要长期保存音频的功能使用。
+// 配置了 chunk_mb 时超过该大小的文件分块保存，并附带列出各部分的清单，大文件可以按部分下载与续传。
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tts/internal/config"
+)
+
+// PublicPath 是通过 HTTP 提供已保存文件的路径前缀
+const PublicPath = "/files"
+
+// Storage 是基于本地目录的文件存储，键为以 / 分隔的相对路径
+type Storage struct {
+	dir       string
+	baseURL   string
+	chunkSize int64 // 超过该大小的文件分块保存，0 表示不分块
+	sums      checksums
+}
+
+// File 是打开用于读取的存储文件，分块保存的文件按顺序读取各部分
+type File struct {
+	io.ReadSeekCloser
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// Name 返回文件名
+func (f *File) Name() string { return f.name }
+
+// Size 返回文件大小
+func (f *File) Size() int64 { return f.size }
+
+// ModTime 返回文件的修改时间
+func (f *File) ModTime() time.Time { return f.modTime }
+
+// New 创建文件存储，目录不存在时自动创建
+func New(cfg config.StorageConfig) (*Storage, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = "./data/files"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	return &Storage{
+		dir:       dir,
+		baseURL:   strings.TrimRight(cfg.BaseURL, "/"),
+		chunkSize: int64(cfg.ChunkMB) << 20,
+	}, nil
+}
+
+// Dir 返回存储目录
+func (s *Storage) Dir() string {
+	return s.dir
+}
+
+// Put 保存文件，先写临时文件再重命名，保证下载时文件始终完整；超过分块大小时分块保存。
+// 同时记录内容哈希，文件也可以通过 ContentURL 访问
+func (s *Storage) Put(key string, data []byte) error {
+	sum := Checksum(data)
+	if s.chunkSize > 0 && int64(len(data)) > s.chunkSize {
+		if err := s.putChunked(key, data, sum); err != nil {
+			return err
+		}
+	} else {
+		if err := s.putFile(key, data); err != nil {
+			return err
+		}
+		// 之前分块保存的同名文件
+		if err := s.removeChunks(key); err != nil {
+			return err
+		}
+	}
+	return s.indexContent(key, sum)
+}
+
+// Get 读取文件内容，分块保存的文件拼接各部分
+func (s *Storage) Get(key string) ([]byte, error) {
+	file, err := s.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// Open 打开文件用于读取，分块保存的文件作为一个整体读取
+func (s *Storage) Open(key string) (*File, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if err == nil {
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			f.Close()
+			return nil, os.ErrNotExist
+		}
+		return &File{ReadSeekCloser: f, name: info.Name(), size: info.Size(), modTime: info.ModTime()}, nil
+	}
+	manifest, ok := s.manifest(key)
+	if !ok {
+		return nil, err
+	}
+	info, statErr := os.Stat(file + ManifestSuffix)
+	if statErr != nil {
+		return nil, statErr
+	}
+	return s.openParts(manifest, info.ModTime())
+}
+
+// Delete 删除文件，分块保存时同时删除各部分与清单，文件不存在时不报错
+func (s *Storage) Delete(key string) error {
+	if err := s.removeFile(key); err != nil {
+		return err
+	}
+	return s.removeChunks(key)
+}
+
+// putFile 先写临时文件再重命名，保证读取时文件始终完整
+func (s *Storage) putFile(key string, data []byte) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// removeFile 删除单个文件，文件不存在时不报错
+func (s *Storage) removeFile(key string) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// removeChunks 删除文件的分块清单与各部分，先删除清单，读取方不会看到缺少部分的文件
+func (s *Storage) removeChunks(key string) error {
+	if err := s.removeFile(key + ManifestSuffix); err != nil {
+		return err
+	}
+	dir, err := s.path(key + partsSuffix)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// URL 返回文件的下载地址。未配置 base_url 时返回以 PublicPath 开头的相对路径，
+// 调用方可以根据当前请求补全
+func (s *Storage) URL(key string) string {
+	return s.baseURL + path.Join(PublicPath, key)
+}
+
+// path 将键转换为存储目录中的文件路径，拒绝越出存储目录的键
+func (s *Storage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || clean != "/"+key {
+		return "", fmt.Errorf("无效的存储键: %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(clean[1:])), nil
+}
@@ -0,0 +1,109 @@
+// Package store 提供简单的持久化键值存储：数据按桶分组，以 JSON 文件保存，
+// 适合用户偏好、任务记录等少量状态。路径为空时只保存在内存中。
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store 是线程安全的键值存储
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	data map[string]map[string]json.RawMessage
+}
+
+// Open 打开存储文件，文件不存在时创建空存储
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, data: map[string]map[string]json.RawMessage{}}
+	if path == "" {
+		return s, nil
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取存储文件失败: %w", err)
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &s.data); err != nil {
+			return nil, fmt.Errorf("解析存储文件失败: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// Get 读取键对应的值到 v，键不存在时返回 false
+func (s *Store) Get(bucket, key string, v any) (bool, error) {
+	s.mu.RLock()
+	raw, ok := s.data[bucket][key]
+	s.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Put 写入键值并持久化
+func (s *Store) Put(bucket, key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data[bucket] == nil {
+		s.data[bucket] = map[string]json.RawMessage{}
+	}
+	s.data[bucket][key] = raw
+	return s.flush()
+}
+
+// Delete 删除键并持久化
+func (s *Store) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[bucket][key]; !ok {
+		return nil
+	}
+	delete(s.data[bucket], key)
+	return s.flush()
+}
+
+// Keys 返回桶中按字典序排列的所有键
+func (s *Store) Keys(bucket string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.data[bucket]))
+	for key := range s.data[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// flush 将全部数据写入文件，调用方需持有写锁。
+// 先写临时文件再重命名，保证文件始终完整。
+func (s *Store) flush() error {
+	if s.path == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
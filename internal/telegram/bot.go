@@ -0,0 +1,361 @@
+// Package telegram 提供内置的 Telegram 机器人：将收到或转发的文字消息合成为语音消息，
+// 每个会话的语音偏好保存在持久化存储中。
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/store"
+	"tts/internal/utils"
+	"tts/internal/watermark"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// defaultAPIURL 是 Telegram Bot API 的默认地址
+	defaultAPIURL = "https://api.telegram.org"
+	// prefsBucket 是保存会话偏好的存储桶
+	prefsBucket = "telegram_prefs"
+	// pollTimeout 是长轮询的超时时间
+	pollTimeout = 30 * time.Second
+	// maxListedVoices 是 /voices 命令最多列出的语音数
+	maxListedVoices = 50
+)
+
+// helpText 是 /start 与 /help 的回复
+const helpText = `发送或转发文字消息，我会把它读成语音。
+
+/voice - 查看当前语音
+/voice <名称> - 设置语音，如 /voice zh-CN-YunxiNeural
+/voices [区域] - 列出可用语音，如 /voices en-US
+/rate <-100..100> - 设置语速`
+
+// Preferences 是单个会话的语音偏好
+type Preferences struct {
+	Voice string `json:"voice"`
+	Rate  string `json:"rate"`
+}
+
+// Bot 是 Telegram 机器人
+type Bot struct {
+	synthesizer *ttspkg.Synthesizer
+	store       *store.Store
+	config      *config.Config
+	client      *http.Client
+	apiURL      string
+	allowed     map[int64]bool
+}
+
+// update 是 getUpdates 返回的一条更新
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message"`
+}
+
+// message 是 Telegram 消息中用到的字段
+type message struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Caption   string `json:"caption"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+}
+
+// apiResponse 是 Bot API 的通用响应
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+// New 创建 Telegram 机器人
+func New(synthesizer *ttspkg.Synthesizer, st *store.Store, cfg *config.Config) *Bot {
+	apiURL := strings.TrimRight(cfg.Telegram.APIURL, "/")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	allowed := make(map[int64]bool, len(cfg.Telegram.AllowedChats))
+	for _, id := range cfg.Telegram.AllowedChats {
+		allowed[id] = true
+	}
+	return &Bot{
+		synthesizer: synthesizer,
+		store:       st,
+		config:      cfg,
+		client:      &http.Client{Timeout: pollTimeout + 30*time.Second},
+		apiURL:      apiURL,
+		allowed:     allowed,
+	}
+}
+
+// Run 以长轮询方式接收消息，直到 ctx 结束
+func (b *Bot) Run(ctx context.Context) {
+	log.Printf("Telegram 机器人已启动")
+	var offset int64
+	for ctx.Err() == nil {
+		var updates []update
+		err := b.call(ctx, "getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         int(pollTimeout.Seconds()),
+			"allowed_updates": []string{"message"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Telegram 获取消息失败: %s", b.sanitize(err))
+				time.Sleep(5 * time.Second)
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message != nil {
+				go b.handle(ctx, u.Message)
+			}
+		}
+	}
+}
+
+// handle 处理一条消息
+func (b *Bot) handle(ctx context.Context, msg *message) {
+	chatID := msg.Chat.ID
+	if len(b.allowed) > 0 && !b.allowed[chatID] {
+		log.Printf("Telegram 忽略未授权会话的消息: %d", chatID)
+		return
+	}
+
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		text = strings.TrimSpace(msg.Caption)
+	}
+	if text == "" {
+		return
+	}
+
+	if strings.HasPrefix(text, "/") {
+		b.command(ctx, msg, text)
+		return
+	}
+	b.speak(ctx, msg, text)
+}
+
+// command 处理机器人命令
+func (b *Bot) command(ctx context.Context, msg *message, text string) {
+	fields := strings.Fields(text)
+	// 群组中的命令形如 /voice@bot_name
+	name, _, _ := strings.Cut(fields[0], "@")
+	args := fields[1:]
+	prefs := b.preferences(msg.Chat.ID)
+
+	switch name {
+	case "/start", "/help":
+		b.reply(ctx, msg, helpText)
+	case "/voice":
+		if len(args) == 0 {
+			b.reply(ctx, msg, "当前语音: "+prefs.Voice)
+			return
+		}
+		voice, err := b.findVoice(ctx, args[0])
+		if err != nil {
+			b.reply(ctx, msg, apperr.From(err).Message)
+			return
+		}
+		prefs.Voice = voice
+		b.savePreferences(ctx, msg, prefs, "语音已设置为 "+voice)
+	case "/voices":
+		locale := "zh-CN"
+		if len(args) > 0 {
+			locale = args[0]
+		}
+		voices, err := b.synthesizer.ListVoices(ctx, locale)
+		if err != nil {
+			b.reply(ctx, msg, "获取语音列表失败")
+			return
+		}
+		names := make([]string, 0, len(voices))
+		for i, voice := range voices {
+			if i == maxListedVoices {
+				names = append(names, "...")
+				break
+			}
+			names = append(names, voice.ShortName)
+		}
+		if len(names) == 0 {
+			b.reply(ctx, msg, "没有找到 "+locale+" 的语音")
+			return
+		}
+		b.reply(ctx, msg, strings.Join(names, "\n"))
+	case "/rate":
+		if len(args) == 0 {
+			b.reply(ctx, msg, "当前语速: "+prefs.Rate)
+			return
+		}
+		rate, err := strconv.Atoi(strings.TrimSuffix(args[0], "%"))
+		if err != nil || rate < -100 || rate > 100 {
+			b.reply(ctx, msg, "语速必须是 -100 到 100 之间的整数")
+			return
+		}
+		prefs.Rate = strconv.Itoa(rate)
+		b.savePreferences(ctx, msg, prefs, "语速已设置为 "+prefs.Rate)
+	default:
+		b.reply(ctx, msg, helpText)
+	}
+}
+
+// speak 合成文本并以语音消息回复
+func (b *Bot) speak(ctx context.Context, msg *message, text string) {
+	if length := utils.GraphemeCount(text); length > b.config.TTS.MaxTextLength {
+		b.reply(ctx, msg, fmt.Sprintf("文本长度超过限制 (%d > %d)", length, b.config.TTS.MaxTextLength))
+		return
+	}
+	prefs := b.preferences(msg.Chat.ID)
+	resp, err := b.synthesizer.Synthesize(ctx, models.TTSRequest{
+		Text:  text,
+		Voice: prefs.Voice,
+		Rate:  prefs.Rate,
+		Pitch: b.config.TTS.DefaultPitch,
+	})
+	if err != nil {
+		log.Printf("Telegram 合成失败: %v", err)
+		appErr := apperr.From(err)
+		message := appErr.Message
+		if appErr.Code == apperr.CodeInternal {
+			message = "服务器内部错误"
+		}
+		b.reply(ctx, msg, "合成失败: "+message)
+		return
+	}
+	if resp.AudioContent, err = watermark.Stamp(b.config, resp.AudioContent, "telegram"); err != nil {
+		log.Printf("Telegram %v", err)
+		b.reply(ctx, msg, "合成失败: 服务器内部错误")
+		return
+	}
+	if err := b.sendVoice(ctx, msg, resp.AudioContent); err != nil {
+		log.Printf("Telegram 发送语音失败: %s", b.sanitize(err))
+		return
+	}
+	log.Printf("Telegram 语音已发送: 会话 %d, 文本长度 %d, 音频大小 %s",
+		msg.Chat.ID, utils.GraphemeCount(text), utils.FormatFileSize(len(resp.AudioContent)))
+}
+
+// findVoice 按名称查找语音，忽略大小写
+func (b *Bot) findVoice(ctx context.Context, name string) (string, error) {
+	voices, err := b.synthesizer.ListVoices(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	for _, voice := range voices {
+		if strings.EqualFold(voice.ShortName, name) {
+			return voice.ShortName, nil
+		}
+	}
+	return "", apperr.Newf(apperr.CodeInvalidVoice, "语音不存在: %s，可用 /voices 查看", name)
+}
+
+// preferences 读取会话偏好，未设置的字段使用默认值
+func (b *Bot) preferences(chatID int64) Preferences {
+	var prefs Preferences
+	if _, err := b.store.Get(prefsBucket, strconv.FormatInt(chatID, 10), &prefs); err != nil {
+		log.Printf("读取 Telegram 会话偏好失败: %v", err)
+	}
+	if prefs.Voice == "" {
+		prefs.Voice = b.config.TTS.DefaultVoice
+	}
+	if prefs.Rate == "" {
+		prefs.Rate = b.config.TTS.DefaultRate
+	}
+	return prefs
+}
+
+// savePreferences 保存会话偏好并回复结果
+func (b *Bot) savePreferences(ctx context.Context, msg *message, prefs Preferences, reply string) {
+	if err := b.store.Put(prefsBucket, strconv.FormatInt(msg.Chat.ID, 10), prefs); err != nil {
+		log.Printf("保存 Telegram 会话偏好失败: %v", err)
+		b.reply(ctx, msg, "保存设置失败")
+		return
+	}
+	b.reply(ctx, msg, reply)
+}
+
+// reply 以文本回复消息
+func (b *Bot) reply(ctx context.Context, msg *message, text string) {
+	err := b.call(ctx, "sendMessage", map[string]any{
+		"chat_id":          msg.Chat.ID,
+		"text":             text,
+		"reply_parameters": map[string]any{"message_id": msg.MessageID},
+	}, nil)
+	if err != nil {
+		log.Printf("Telegram 发送消息失败: %s", b.sanitize(err))
+	}
+}
+
+// sendVoice 以语音消息回复
+func (b *Bot) sendVoice(ctx context.Context, msg *message, audio []byte) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("chat_id", strconv.FormatInt(msg.Chat.ID, 10))
+	writer.WriteField("reply_parameters", fmt.Sprintf(`{"message_id":%d}`, msg.MessageID))
+	part, err := writer.CreateFormFile("voice", "voice.mp3")
+	if err != nil {
+		return err
+	}
+	part.Write(audio)
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return b.do(ctx, "sendVoice", writer.FormDataContentType(), &body, nil)
+}
+
+// call 以 JSON 请求体调用 Bot API
+func (b *Bot) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return b.do(ctx, method, "application/json", bytes.NewReader(body), result)
+}
+
+// do 调用 Bot API 并解析结果
+func (b *Bot) do(ctx context.Context, method, contentType string, body io.Reader, result any) error {
+	url := fmt.Sprintf("%s/bot%s/%s", b.apiURL, b.config.Telegram.Token, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", method, err)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("%s 失败: %s", method, apiResp.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(apiResp.Result, result)
+	}
+	return nil
+}
+
+// sanitize 从错误信息中移除机器人令牌
+func (b *Bot) sanitize(err error) string {
+	return utils.SanitizeMessage(err.Error(), b.config.Telegram.Token)
+}
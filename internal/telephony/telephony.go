@@ -0,0 +1,137 @@
+// Package telephony 把合成的 MP3 转换为电话系统（Asterisk、FreeSWITCH）可以直接播放的 8kHz/16kHz 单声道格式
+package telephony
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	"sort"
+	"strings"
+
+	"tts/internal/models"
+	ttspkg "tts/pkg/tts"
+)
+
+// Format 是一种电话系统的音频格式，Name 同时是 Asterisk 与 FreeSWITCH 识别的文件扩展名
+type Format struct {
+	Name        string
+	ContentType string
+	SampleRate  int
+	encode      func(pcm []byte, sampleRate int) []byte
+}
+
+// formats 是支持的格式：wav 为 16 位 PCM 的 WAV 文件，sln 为不带文件头的 16 位小端 PCM，
+// ulaw 与 alaw 为 G.711 编码的 8kHz 裸数据
+var formats = map[string]Format{
+	"wav":   {Name: "wav", ContentType: "audio/wav", SampleRate: 8000, encode: wav},
+	"wav16": {Name: "wav16", ContentType: "audio/wav", SampleRate: 16000, encode: wav},
+	"sln":   {Name: "sln", ContentType: "application/octet-stream", SampleRate: 8000, encode: raw},
+	"sln16": {Name: "sln16", ContentType: "application/octet-stream", SampleRate: 16000, encode: raw},
+	"ulaw":  {Name: "ulaw", ContentType: "audio/basic", SampleRate: 8000, encode: g711(linearToULaw)},
+	"alaw":  {Name: "alaw", ContentType: "audio/x-alaw-basic", SampleRate: 8000, encode: g711(linearToALaw)},
+}
+
+// Lookup 按扩展名查找格式，忽略大小写
+func Lookup(name string) (Format, bool) {
+	format, ok := formats[strings.ToLower(name)]
+	return format, ok
+}
+
+// Names 返回支持的格式名称，按字母顺序
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encode 把 MP3 解码为单声道 PCM 并按格式编码
+func Encode(mp3 []byte, format Format) ([]byte, error) {
+	pcm, err := ttspkg.DecodePCM(mp3, format.SampleRate)
+	if err != nil {
+		return nil, err
+	}
+	return format.encode(pcm, format.SampleRate), nil
+}
+
+// ETag 返回由格式与合成参数决定的实体标签，参数相同的请求得到相同的音频
+func ETag(format Format, req models.TTSRequest) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{format.Name, req.Voice, req.Rate, req.Pitch, req.Style, req.Text}, "\x00")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// wav 为 PCM 加上 44 字节的标准 WAV 文件头，各长度字段为实际长度，不包含其他数据块
+func wav(pcm []byte, sampleRate int) []byte {
+	out := make([]byte, 44, 44+len(pcm))
+	copy(out[0:], "RIFF")
+	binary.LittleEndian.PutUint32(out[4:], uint32(36+len(pcm)))
+	copy(out[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(out[16:], 16)                   // fmt 块长度
+	binary.LittleEndian.PutUint16(out[20:], 1)                    // PCM
+	binary.LittleEndian.PutUint16(out[22:], 1)                    // 单声道
+	binary.LittleEndian.PutUint32(out[24:], uint32(sampleRate))   // 采样率
+	binary.LittleEndian.PutUint32(out[28:], uint32(sampleRate*2)) // 每秒字节数
+	binary.LittleEndian.PutUint16(out[32:], 2)                    // 每帧字节数
+	binary.LittleEndian.PutUint16(out[34:], 16)                   // 位深
+	copy(out[36:], "data")
+	binary.LittleEndian.PutUint32(out[40:], uint32(len(pcm)))
+	return append(out, pcm...)
+}
+
+func raw(pcm []byte, _ int) []byte {
+	return pcm
+}
+
+// g711 返回把 16 位 PCM 逐个采样压缩为 8 位的编码函数
+func g711(compress func(int16) byte) func([]byte, int) []byte {
+	return func(pcm []byte, _ int) []byte {
+		out := make([]byte, len(pcm)/2)
+		for i := range out {
+			out[i] = compress(int16(binary.LittleEndian.Uint16(pcm[2*i:])))
+		}
+		return out
+	}
+}
+
+// linearToULaw 按 G.711 μ-law 压缩一个采样，先截为 14 位再查找所在的段
+func linearToULaw(sample int16) byte {
+	value := int(sample) >> 2
+	mask := 0xFF
+	if value < 0 {
+		value = -value
+		mask = 0x7F
+	}
+	if value > 8159 {
+		value = 8159
+	}
+	value += 0x21
+	segment := max(bits.Len(uint(value))-6, 0)
+	if segment >= 8 {
+		return byte(0x7F ^ mask)
+	}
+	return byte((segment<<4 | (value>>(segment+1))&0x0F) ^ mask)
+}
+
+// linearToALaw 按 G.711 A-law 压缩一个采样
+func linearToALaw(sample int16) byte {
+	value := int(sample)
+	sign := 0x80
+	if value < 0 {
+		value = -value - 1
+		sign = 0
+	}
+	var compressed int
+	if value >= 256 {
+		exponent := 7
+		for mask := 0x4000; value&mask == 0 && exponent > 1; mask >>= 1 {
+			exponent--
+		}
+		compressed = exponent<<4 | (value>>(exponent+3))&0x0F
+	} else {
+		compressed = value >> 4
+	}
+	return byte(compressed|sign) ^ 0x55
+}
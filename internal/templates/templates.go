@@ -0,0 +1,89 @@
+// Package templates 管理配置中的命名文本模板（Go text/template），
+// 合成前用请求提供的变量渲染，例如取餐叫号等固定句式的播报。
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+)
+
+// Template 是解析后的命名模板
+type Template struct {
+	Name   string `json:"name"`
+	Text   string `json:"text"`
+	Voice  string `json:"voice,omitempty"`
+	Locale string `json:"locale,omitempty"`
+	Rate   string `json:"rate,omitempty"`
+	Pitch  string `json:"pitch,omitempty"`
+	Style  string `json:"style,omitempty"`
+
+	tmpl *template.Template
+}
+
+// Set 是一组命名模板
+type Set struct {
+	templates map[string]*Template
+}
+
+// New 解析配置中的所有模板，模板名称不区分大小写
+func New(cfg map[string]config.TextTemplate) (*Set, error) {
+	set := &Set{templates: make(map[string]*Template, len(cfg))}
+	for name, tc := range cfg {
+		if tc.Text == "" {
+			return nil, fmt.Errorf("模板 %s 的 text 不能为空", name)
+		}
+		// 缺少变量时报错，而不是读出 "<no value>"
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(tc.Text)
+		if err != nil {
+			return nil, fmt.Errorf("模板 %s 解析失败: %w", name, err)
+		}
+		set.templates[strings.ToLower(name)] = &Template{
+			Name:   name,
+			Text:   tc.Text,
+			Voice:  tc.Voice,
+			Locale: tc.Locale,
+			Rate:   tc.Rate,
+			Pitch:  tc.Pitch,
+			Style:  tc.Style,
+			tmpl:   tmpl,
+		}
+	}
+	return set, nil
+}
+
+// Get 按名称查找模板
+func (s *Set) Get(name string) (*Template, error) {
+	t, ok := s.templates[strings.ToLower(name)]
+	if !ok {
+		return nil, apperr.Newf(apperr.CodeNotFound, "模板不存在: %s", name)
+	}
+	return t, nil
+}
+
+// List 返回按名称排序的所有模板
+func (s *Set) List() []*Template {
+	list := make([]*Template, 0, len(s.templates))
+	for _, t := range s.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Render 使用变量渲染模板
+func (t *Template) Render(variables map[string]any) (string, error) {
+	if variables == nil {
+		variables = map[string]any{}
+	}
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, variables); err != nil {
+		return "", apperr.Wrap(apperr.CodeInvalidRequest, fmt.Sprintf("模板 %s 渲染失败，请检查变量", t.Name), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,68 @@
+package tts
+
+import (
+	"context"
+
+	"tts/internal/apperr"
+	"tts/internal/cache"
+	"tts/internal/config"
+	"tts/internal/metrics"
+	"tts/internal/models"
+)
+
+var synthesisCacheTotal = metrics.NewCounter("tts_synthesis_cache_total",
+	"合成结果缓存的查询次数", "provider", "result")
+
+// CachedService 缓存合成结果，相同的请求（按片段）直接返回缓存的音频，
+// 预热接口提前合成的内容也通过它写入缓存
+type CachedService struct {
+	next  Service
+	name  string
+	cache *cache.Cache
+}
+
+// NewCachedService 创建缓存合成结果的服务
+func NewCachedService(next Service, name string, c *cache.Cache) *CachedService {
+	return &CachedService{next: next, name: name, cache: c}
+}
+
+// ListVoices 获取底层服务的语音列表
+func (s *CachedService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 命中缓存时直接返回，否则合成后写入缓存。
+// 缓存键包含服务名称与租户，按密钥设置的 SSML 处理方式不同时互不影响
+func (s *CachedService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	key := cache.Key("tts", s.name, config.TenantFromContext(ctx), req.Text, req.Voice, req.Rate, req.Pitch, req.Style, req.URLMode, req.Quotes)
+	if audio, ok := s.cache.Get(key); ok {
+		synthesisCacheTotal.Inc(s.name, "hit")
+		cache.Record(ctx, key, audio)
+		return &models.TTSResponse{AudioContent: audio, ContentType: "audio/mpeg", CacheHit: true}, nil
+	}
+	synthesisCacheTotal.Inc(s.name, "miss")
+	resp, err := s.next.SynthesizeSpeech(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(key, resp.AudioContent)
+	cache.Record(ctx, key, resp.AudioContent)
+	return resp, nil
+}
+
+// SpeechMarks 获取底层服务的语音标记，不缓存
+func (s *CachedService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	return provider.SpeechMarks(ctx, req)
+}
+
+// Warm 预热底层服务
+func (s *CachedService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
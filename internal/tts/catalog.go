@@ -0,0 +1,83 @@
+package tts
+
+import (
+	"context"
+	"strings"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+)
+
+// CatalogService 把 tts.voices 中配置的本地信息（显示名称、标签、示例句子）合并到语音列表，
+// 并从列表中去掉停用的语音，便于前端按标签构建精选的语音选择器
+type CatalogService struct {
+	next    Service
+	entries map[string]config.VoiceEntry // 键为小写的语音简称或全名
+}
+
+// NewCatalogService 创建合并本地语音信息的服务
+func NewCatalogService(next Service, entries map[string]config.VoiceEntry) *CatalogService {
+	normalized := make(map[string]config.VoiceEntry, len(entries))
+	for name, entry := range entries {
+		normalized[strings.ToLower(name)] = entry
+	}
+	return &CatalogService{next: next, entries: normalized}
+}
+
+// ListVoices 获取底层服务的语音列表并合并本地信息，返回新的切片，不修改底层服务缓存的列表
+func (s *CatalogService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	voices, err := s.next.ListVoices(ctx, locale)
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]models.Voice, 0, len(voices))
+	for _, voice := range voices {
+		entry, ok := s.lookup(voice)
+		if !ok {
+			merged = append(merged, voice)
+			continue
+		}
+		if entry.Enabled != nil && !*entry.Enabled {
+			continue
+		}
+		if entry.DisplayName != "" {
+			voice.DisplayName = entry.DisplayName
+		}
+		voice.Tags = entry.Tags
+		voice.SampleText = entry.SampleText
+		merged = append(merged, voice)
+	}
+	return merged, nil
+}
+
+// SynthesizeSpeech 直接交给底层服务，停用的语音只是不在列表中展示，仍可按名称合成
+func (s *CatalogService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	return s.next.SynthesizeSpeech(ctx, req)
+}
+
+// SpeechMarks 获取底层服务的语音标记
+func (s *CatalogService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	return provider.SpeechMarks(ctx, req)
+}
+
+// Warm 预热底层服务
+func (s *CatalogService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// lookup 按简称或全名查找语音的本地信息
+func (s *CatalogService) lookup(voice models.Voice) (config.VoiceEntry, bool) {
+	if entry, ok := s.entries[strings.ToLower(voice.ShortName)]; ok {
+		return entry, true
+	}
+	entry, ok := s.entries[strings.ToLower(voice.Name)]
+	return entry, ok
+}
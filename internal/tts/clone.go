@@ -0,0 +1,124 @@
+package tts
+
+import (
+	"context"
+	"sync"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/customvoice"
+	"tts/internal/models"
+)
+
+// Sample 是一段上传的音频
+type Sample struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// CloneRequest 是用参考音频创建自定义语音的请求
+type CloneRequest struct {
+	Name        string
+	Description string
+	Language    string
+	Samples     []Sample // 说话人的参考音频
+	// Consent 是说话人同意使用其声音的录音，部分服务（如 Azure 个人语音）要求提供，其他服务忽略
+	Consent *Sample
+}
+
+// VoiceCloner 由支持用参考音频创建自定义语音的服务实现（如 ElevenLabs、OpenAI、Azure 个人语音）。
+// 创建的语音以返回的 ID 作为 TTSRequest.Voice 合成
+type VoiceCloner interface {
+	// CloneVoice 创建自定义语音，返回上游服务中的语音 ID
+	CloneVoice(ctx context.Context, req CloneRequest) (string, error)
+	// DeleteVoice 删除创建的自定义语音，语音已不存在时不返回错误
+	DeleteVoice(ctx context.Context, voiceID string) error
+}
+
+var (
+	clonersMu sync.RWMutex
+	cloners   = map[string]VoiceCloner{}
+)
+
+// registerCloner 记录服务的语音克隆实现。克隆直接调用上游服务，不经过并发池、缓存等包装
+func registerCloner(name string, service Service) {
+	if cloner, ok := service.(VoiceCloner); ok {
+		clonersMu.Lock()
+		cloners[name] = cloner
+		clonersMu.Unlock()
+	}
+}
+
+// ClonerFor 返回服务的语音克隆实现，服务不支持或尚未创建时返回 false
+func ClonerFor(name string) (VoiceCloner, bool) {
+	if name == "" {
+		name = DefaultProvider
+	}
+	clonersMu.RLock()
+	defer clonersMu.RUnlock()
+	cloner, ok := cloners[name]
+	return cloner, ok
+}
+
+// CustomVoiceService 把请求中的自定义语音名称替换为上游服务中的语音 ID，
+// 自定义语音按租户保存，租户之间的同名语音互不影响
+type CustomVoiceService struct {
+	next     Service
+	provider string
+	voices   *customvoice.Registry
+}
+
+// NewCustomVoiceService 创建解析自定义语音的服务，provider 为当前使用的服务名称
+func NewCustomVoiceService(next Service, provider string, voices *customvoice.Registry) *CustomVoiceService {
+	if provider == "" {
+		provider = DefaultProvider
+	}
+	return &CustomVoiceService{next: next, provider: provider, voices: voices}
+}
+
+// ListVoices 获取底层服务的语音列表，自定义语音通过 /v1/voices/custom 列出
+func (s *CustomVoiceService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 解析自定义语音后合成
+func (s *CustomVoiceService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	if err := s.resolve(ctx, &req); err != nil {
+		return nil, err
+	}
+	return s.next.SynthesizeSpeech(ctx, req)
+}
+
+// SpeechMarks 解析自定义语音后获取语音标记
+func (s *CustomVoiceService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	if err := s.resolve(ctx, &req); err != nil {
+		return nil, err
+	}
+	return provider.SpeechMarks(ctx, req)
+}
+
+// Warm 预热底层服务
+func (s *CustomVoiceService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// resolve 请求的语音是租户的自定义语音时替换为语音 ID，语音属于其他服务时返回错误
+func (s *CustomVoiceService) resolve(ctx context.Context, req *models.TTSRequest) error {
+	voice, ok := s.voices.Lookup(config.TenantFromContext(ctx), req.Voice)
+	if !ok {
+		return nil
+	}
+	if voice.Provider != s.provider {
+		return apperr.Newf(apperr.CodeInvalidVoice, "自定义语音 %s 由 %s 创建，当前服务 %s 无法使用", voice.Name, voice.Provider, s.provider)
+	}
+	req.Voice = voice.VoiceID
+	return nil
+}
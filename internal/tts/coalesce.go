@@ -0,0 +1,109 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/metrics"
+	"tts/internal/models"
+)
+
+var coalescedTotal = metrics.NewCounter("tts_coalesced_requests_total",
+	"与进行中的相同请求合并、没有单独发往上游的合成请求数", "provider")
+
+// CoalescingService 合并同时到达的相同合成请求：第一个请求发往上游，其余请求等待并共享其结果，
+// 避免热门文章等同时到达的大量相同请求各自调用一次上游。
+// 上游请求在所有等待者都取消后才取消，不会因为第一个请求的客户端断开而让其他等待者失败。
+type CoalescingService struct {
+	next Service
+	name string
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight 是一个进行中的上游请求
+type flight struct {
+	done    chan struct{}
+	resp    *models.TTSResponse
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// NewCoalescingService 创建合并相同请求的服务
+func NewCoalescingService(next Service, name string) *CoalescingService {
+	return &CoalescingService{next: next, name: name, flights: make(map[string]*flight)}
+}
+
+// ListVoices 获取底层服务的语音列表
+func (s *CoalescingService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 与进行中的相同请求合并，每个调用方得到各自的音频副本
+func (s *CoalescingService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	key := strings.Join([]string{config.TenantFromContext(ctx), req.Text, req.Voice, req.Rate, req.Pitch, req.Style, req.URLMode, req.Quotes}, "\x00")
+
+	s.mu.Lock()
+	f, shared := s.flights[key]
+	if shared {
+		f.waiters++
+		coalescedTotal.Inc(s.name)
+	} else {
+		// 上游请求保留调用方上下文中的值，但不随单个调用方取消
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		s.flights[key] = f
+		go s.run(flightCtx, key, f, req)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		s.mu.Lock()
+		if f.waiters--; f.waiters == 0 {
+			f.cancel()
+		}
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := *f.resp
+	resp.AudioContent = bytes.Clone(f.resp.AudioContent)
+	return &resp, nil
+}
+
+// SpeechMarks 获取底层服务的语音标记，不合并
+func (s *CoalescingService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	return provider.SpeechMarks(ctx, req)
+}
+
+// Warm 预热底层服务
+func (s *CoalescingService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// run 发出上游请求，完成后移除记录，之后到达的相同请求重新发往上游
+func (s *CoalescingService) run(ctx context.Context, key string, f *flight, req models.TTSRequest) {
+	defer f.cancel()
+	f.resp, f.err = s.next.SynthesizeSpeech(ctx, req)
+	s.mu.Lock()
+	delete(s.flights, key)
+	s.mu.Unlock()
+	close(f.done)
+}
@@ -0,0 +1,74 @@
+package tts
+
+import (
+	"context"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/health"
+	"tts/internal/models"
+	"tts/internal/sentry"
+	"tts/internal/utils"
+)
+
+// maxReportedText 是上报错误时附带的文本长度上限（字符）
+const maxReportedText = 200
+
+// MonitoredService 把每次合成的耗时与结果记录到服务的健康记录器，供 /v1/providers/status 汇总，
+// 开启 sentry.dsn 时同时上报上游服务错误
+type MonitoredService struct {
+	next    Service
+	monitor *health.Monitor
+}
+
+// NewMonitoredService 创建记录健康状况的服务，底层服务实现了 health.TokenReporter 时一并上报令牌状态
+func NewMonitoredService(next Service, m *health.Monitor) *MonitoredService {
+	m.Attach(next)
+	return &MonitoredService{next: next, monitor: m}
+}
+
+// ListVoices 获取底层服务的语音列表，不计入统计
+func (s *MonitoredService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 合成并记录耗时与结果
+func (s *MonitoredService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	start := time.Now()
+	resp, err := s.next.SynthesizeSpeech(ctx, req)
+	s.monitor.Record(time.Since(start), err)
+	s.report(ctx, "synthesize", req, err)
+	return resp, err
+}
+
+// SpeechMarks 获取语音标记并记录耗时与结果
+func (s *MonitoredService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	start := time.Now()
+	marks, err := provider.SpeechMarks(ctx, req)
+	s.monitor.Record(time.Since(start), err)
+	s.report(ctx, "speech_marks", req, err)
+	return marks, err
+}
+
+// report 上报上游服务错误，附带语音参数与文本，隐私模式下不上报文本
+func (s *MonitoredService) report(ctx context.Context, operation string, req models.TTSRequest, err error) {
+	if err == nil || !health.Upstream(err) {
+		return
+	}
+	sentry.CaptureError(ctx, err,
+		map[string]string{"provider": s.monitor.Name(), "operation": operation, "code": string(apperr.CodeOf(err))},
+		map[string]any{"voice": req.Voice, "rate": req.Rate, "pitch": req.Pitch, "style": req.Style,
+			"text_length": utils.GraphemeCount(req.Text), "text": utils.TruncateForLog(req.Text, maxReportedText)})
+}
+
+// Warm 预热底层服务
+func (s *MonitoredService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package tts
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Warmer 由支持预热的服务实现，用于保持 TLS 连接与认证令牌处于可用状态
+type Warmer interface {
+	// Warm 刷新即将过期的令牌，并向上游发送一次轻量请求以保持连接
+	Warm(ctx context.Context) error
+}
+
+// StartKeepAlive 立即预热一次服务，然后按间隔定期发送保活请求，直到 ctx 结束。
+// 服务未实现 Warmer 时直接返回。
+func StartKeepAlive(ctx context.Context, service Service, interval time.Duration) {
+	warmer, ok := service.(Warmer)
+	if !ok {
+		log.Printf("TTS服务不支持预热，跳过保活")
+		return
+	}
+
+	go func() {
+		warm := func() {
+			warmCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			start := time.Now()
+			if err := warmer.Warm(warmCtx); err != nil {
+				log.Printf("TTS服务预热失败: %v", err)
+				return
+			}
+			log.Printf("TTS服务预热完成，耗时: %v", time.Since(start))
+		}
+
+		warm()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				warm()
+			}
+		}
+	}()
+}
@@ -0,0 +1,67 @@
+package tts
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"tts/internal/models"
+)
+
+// 语音标记的导出格式
+const (
+	MarkFormatPolly = "polly" // Amazon Polly 的 JSON Lines 格式
+	MarkFormatCSV   = "csv"   // time,type,start,end,value
+)
+
+// MarkProvider 由能够提供时间信息的服务实现
+type MarkProvider interface {
+	// SpeechMarks 返回文本合成后各句子与词语的时间标记
+	SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error)
+}
+
+// MarkContentType 返回导出格式对应的 Content-Type，不支持的格式返回空字符串
+func MarkContentType(format string) string {
+	switch format {
+	case MarkFormatPolly:
+		return "application/x-json-stream"
+	case MarkFormatCSV:
+		return "text/csv; charset=utf-8"
+	}
+	return ""
+}
+
+// WriteMarks 按指定格式导出语音标记
+func WriteMarks(w io.Writer, marks []models.SpeechMark, format string) error {
+	if format == MarkFormatCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"time", "type", "start", "end", "value"}); err != nil {
+			return err
+		}
+		for _, mark := range marks {
+			record := []string{
+				strconv.FormatInt(mark.Time, 10),
+				mark.Type,
+				strconv.Itoa(mark.Start),
+				strconv.Itoa(mark.End),
+				mark.Value,
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, mark := range marks {
+		if err := encoder.Encode(mark); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package microsoft
+
+import (
+	"strings"
+
+	ssmlpkg "tts/internal/ssml"
+	"tts/internal/utils"
+)
+
+// voiceNodes 生成朗读已转义文本的 <voice> 元素。
+// 启用 ssml.bidi 时，文本按逻辑顺序切分为阿拉伯文、希伯来文与其他文字的片段，
+// 与请求语音语言不同的片段使用 ssml.bidi.voices 中配置的语音朗读；
+// 请求的语音是多语言语音时不切换语音，而是用 <lang> 标注片段的语言。
+func (c *Client) voiceNodes(voice, style, rate, pitch, text string) []ssmlpkg.Node {
+	if !c.bidi.Enabled || len(c.bidi.Voices) == 0 || !utils.ContainsRTL(text) {
+		return []ssmlpkg.Node{voiceNode(voice, style, rate, pitch, ssmlpkg.Raw(text))}
+	}
+
+	lang, _, _ := strings.Cut(strings.ToLower(voice), "-")
+	multilingual := strings.Contains(voice, "Multilingual")
+
+	var nodes []ssmlpkg.Node
+	var children []ssmlpkg.Node
+	current := voice
+	flush := func() {
+		if len(children) == 0 {
+			return
+		}
+		// 切换到的语音不一定支持请求的说话风格，只保留语速与音调
+		runStyle := style
+		if current != voice {
+			runStyle = ""
+		}
+		nodes = append(nodes, voiceNode(current, runStyle, rate, pitch, children...))
+		children = nil
+	}
+	for _, run := range utils.SplitScriptRuns(text) {
+		target := c.bidi.Voices[run.Script]
+		if run.Script == "" || run.Script == lang || target == "" {
+			target = voice
+		}
+		if target != voice && multilingual {
+			children = append(children, ssmlpkg.Lang(localeOf(target), ssmlpkg.Raw(run.Text)))
+			continue
+		}
+		if target != current {
+			flush()
+			current = target
+		}
+		children = append(children, ssmlpkg.Raw(run.Text))
+	}
+	flush()
+	return nodes
+}
+
+// voiceNode 生成一个 <voice> 元素，style 为空时不添加 <mstts:express-as>
+func voiceNode(voice, style, rate, pitch string, children ...ssmlpkg.Node) ssmlpkg.Node {
+	var content ssmlpkg.Node = ssmlpkg.Prosody(rate+"%", pitch+"%", "medium", children...)
+	if style != "" {
+		content = ssmlpkg.ExpressAs(style, "1.0", "default", content)
+	}
+	return ssmlpkg.Voice(voice, content)
+}
+
+// localeOf 从语音名称中提取区域，如 ar-SA-HamedNeural 返回 ar-SA
+func localeOf(voice string) string {
+	parts := strings.Split(voice, "-")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "-" + parts[1]
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,24 +12,22 @@ import (
 	"sync"
 	"time"
 
+	"tts/internal/apperr"
 	"tts/internal/config"
+	"tts/internal/feature"
 	"tts/internal/models"
+	ssmlpkg "tts/internal/ssml"
+	"tts/internal/tts"
 	"tts/internal/utils"
+	"tts/internal/vcr"
 )
 
 const (
 	userAgent      = "okhttp/4.5.0"
 	voicesEndpoint = "https://%s.tts.speech.microsoft.com/cognitiveservices/voices/list"
 	ttsEndpoint    = "https://%s.tts.speech.microsoft.com/cognitiveservices/v1"
-	ssmlTemplate   = `<speak version='1.0' xmlns='http://www.w3.org/2001/10/synthesis' xmlns:mstts="http://www.w3.org/2001/mstts" xml:lang='%s'>
-    <voice name='%s'>
-        <mstts:express-as style="%s" styledegree="1.0" role="default">
-            <prosody rate='%s%%' pitch='%s%%' volume="medium">
-                %s
-            </prosody>
-        </mstts:express-as>
-    </voice>
-</speak>`
+	// tokenRefreshMargin 保活时提前刷新令牌的时间窗口
+	tokenRefreshMargin = 5 * time.Minute
 )
 
 // Client 是Microsoft TTS API的客户端实现
@@ -49,16 +46,35 @@ type Client struct {
 	endpoint       map[string]interface{}
 	endpointMu     sync.RWMutex
 	endpointExpiry time.Time
-	ssmProcessor   *config.SSMLProcessor
+	processors     *config.ProcessorRegistry
+	validateSSML   bool
+	inlineHints    bool
+	rubyMode       string
+	bidi           config.BidiConfig
+	sayAs          ssmlpkg.SayAsRules
+	acronyms       *ssmlpkg.Acronyms
+	pacing         *ssmlpkg.Pacing
+	quotes         *ssmlpkg.Quotes
+	quoteConfig    config.QuotesConfig
 }
 
 // NewClient 创建一个新的Microsoft TTS客户端
 func NewClient(cfg *config.Config) *Client {
-	// 从Viper配置中创建SSML处理器
-	ssmProcessor, err := config.NewSSMLProcessor(&cfg.SSML)
+	// 从Viper配置中创建SSML处理器，收到 SIGHUP 时随配置重新加载
+	processors, err := config.NewProcessorRegistry(cfg)
 	if err != nil {
 		log.Fatalf("创建SSML处理器失败: %v", err)
 	}
+	// 按配置设置出站代理与请求头
+	outbound, err := newOutboundTransport(cfg.TTS.Outbound, newTransport(cfg))
+	if err != nil {
+		log.Fatalf("创建出站传输层失败: %v", err)
+	}
+	// 按配置启用录制/回放
+	transport, err := vcr.New(vcr.Mode(cfg.TTS.VCR.Mode), cfg.TTS.VCR.Dir, outbound)
+	if err != nil {
+		log.Fatalf("创建VCR传输层失败: %v", err)
+	}
 	client := &Client{
 		defaultVoice:  cfg.TTS.DefaultVoice,
 		defaultRate:   cfg.TTS.DefaultRate,
@@ -66,16 +82,81 @@ func NewClient(cfg *config.Config) *Client {
 		defaultFormat: cfg.TTS.DefaultFormat,
 		maxTextLength: cfg.TTS.MaxTextLength,
 		httpClient: &http.Client{
-			Timeout: time.Duration(cfg.TTS.RequestTimeout) * time.Second,
+			Timeout:   time.Duration(cfg.TTS.RequestTimeout) * time.Second,
+			Transport: transport,
 		},
 		voicesCacheExpiry: time.Time{}, // 初始时缓存为空
 		endpointExpiry:    time.Time{}, // 初始时端点为空
-		ssmProcessor:      ssmProcessor,
+		processors:        processors,
+		validateSSML:      cfg.SSML.Validate,
+		inlineHints:       cfg.SSML.InlineHints,
+		rubyMode:          cfg.SSML.Ruby,
+		bidi:              cfg.SSML.Bidi,
+		sayAs:             ssmlpkg.SayAsRules(cfg.SSML.SayAs),
+		quotes:            ssmlpkg.NewQuotes(cfg.SSML.Quotes.Words, cfg.SSML.Quotes.RequireAttribution),
+		quoteConfig:       cfg.SSML.Quotes,
+	}
+
+	if cfg.SSML.Acronyms.Enabled {
+		acronyms := cfg.SSML.Acronyms
+		client.acronyms = ssmlpkg.NewAcronyms(acronyms.Words, acronyms.Unknown == "spell", acronyms.MaxLength)
+	}
+	if cfg.SSML.Pacing.Enabled {
+		pacing := cfg.SSML.Pacing
+		client.pacing = ssmlpkg.NewPacing(ssmlpkg.PacingOptions{
+			SlowRate:        pacing.SlowRate,
+			FastRate:        pacing.FastRate,
+			DenseThreshold:  pacing.DenseThreshold,
+			SimpleThreshold: pacing.SimpleThreshold,
+			LongWord:        pacing.LongWord,
+			MinWords:        pacing.MinWords,
+		})
 	}
 
 	return client
 }
 
+// newTransport 创建HTTP传输层，启用保活时延长空闲连接的保留时间
+func newTransport(cfg *config.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TTS.KeepAlive.Enabled {
+		// 空闲连接保留时间需大于保活间隔，否则连接会在两次保活之间被关闭
+		transport.IdleConnTimeout = cfg.TTS.KeepAlive.IntervalDuration() * 2
+	}
+	return transport
+}
+
+// Warm 刷新即将过期的认证令牌，并向合成端点发送轻量请求以保持TLS连接
+func (c *Client) Warm(ctx context.Context) error {
+	// 令牌将在 tokenRefreshMargin 内过期时提前刷新，避免首个请求等待认证
+	c.endpointMu.Lock()
+	if !c.endpointExpiry.IsZero() && time.Until(c.endpointExpiry) < tokenRefreshMargin {
+		c.endpointExpiry = time.Time{}
+	}
+	c.endpointMu.Unlock()
+
+	endpoint, err := c.getEndpoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, fmt.Sprintf(ttsEndpoint, endpoint["r"]), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", endpoint["t"].(string))
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return apperr.Wrap(apperr.CodeProviderError, "TTS服务保活请求失败", err)
+	}
+	// 只关心连接是否建立，状态码无需处理
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
 // getEndpoint 获取或刷新认证端点
 func (c *Client) getEndpoint(ctx context.Context) (map[string]interface{}, error) {
 	c.endpointMu.RLock()
@@ -87,10 +168,10 @@ func (c *Client) getEndpoint(ctx context.Context) (map[string]interface{}, error
 	c.endpointMu.RUnlock()
 
 	// 获取新的端点信息
-	endpoint, err := utils.GetEndpoint()
+	endpoint, err := utils.GetEndpointWithClient(c.httpClient)
 	if err != nil {
 		log.Printf("获取认证信息失败: %v\n", err)
-		return nil, err
+		return nil, apperr.Wrap(apperr.CodeProviderAuthFailed, "获取认证信息失败", err)
 	}
 	log.Printf("获取认证信息成功: %v\n", endpoint)
 
@@ -98,7 +179,7 @@ func (c *Client) getEndpoint(ctx context.Context) (map[string]interface{}, error
 	jwt := endpoint["t"].(string)
 	exp := utils.GetExp(jwt)
 	if exp == 0 {
-		return nil, errors.New("jwt 中缺少 exp 字段")
+		return nil, apperr.New(apperr.CodeProviderAuthFailed, "jwt 中缺少 exp 字段")
 	}
 	expTime := time.Unix(exp, 0)
 	log.Println("jwt  距到期时间:", expTime.Sub(time.Now()))
@@ -155,13 +236,13 @@ func (c *Client) ListVoices(ctx context.Context, locale string) ([]models.Voice,
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, apperr.Wrap(apperr.CodeProviderError, "获取语音列表失败", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s, status: %d", string(body), resp.StatusCode)
+		return nil, statusError("获取语音列表失败", resp.StatusCode, string(body))
 	}
 
 	var msVoices []MicrosoftVoice
@@ -213,8 +294,8 @@ func (c *Client) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*
 	}
 	defer resp.Body.Close()
 
-	// 读取音频数据
-	audio, err := io.ReadAll(resp.Body)
+	// 读取音频数据，已知长度时一次分配到位
+	audio, err := utils.ReadAll(resp.Body, resp.ContentLength)
 	if err != nil {
 		return nil, err
 	}
@@ -230,11 +311,11 @@ func (c *Client) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*
 func (c *Client) createTTSRequest(ctx context.Context, req models.TTSRequest) (*http.Response, error) {
 	// 参数验证
 	if req.Text == "" {
-		return nil, errors.New("文本不能为空")
+		return nil, apperr.New(apperr.CodeInvalidRequest, "文本不能为空")
 	}
 
-	if len(req.Text) > c.maxTextLength {
-		return nil, fmt.Errorf("文本长度超过限制 (%d > %d)", len(req.Text), c.maxTextLength)
+	if length := utils.GraphemeCount(req.Text); length > c.maxTextLength {
+		return nil, apperr.Newf(apperr.CodeTextTooLong, "文本长度超过限制 (%d > %d)", length, c.maxTextLength)
 	}
 
 	// 使用默认值填充空白参数
@@ -265,12 +346,37 @@ func (c *Client) createTTSRequest(ctx context.Context, req models.TTSRequest) (*
 		locale = parts[0] + "-" + parts[1]
 	}
 
-	// 先清理 Markdown，再进行 HTML 转义，防止在语音中读出格式符
-	cleanText := c.ssmProcessor.StripMarkdown(req.Text)
-	escapedText := c.ssmProcessor.EscapeSSML(cleanText)
-
-	// 准备SSML内容
-	ssml := fmt.Sprintf(ssmlTemplate, locale, voice, style, rate, pitch, escapedText)
+	// 按请求密钥选择处理器，密钥单独设置了允许的标签或网址朗读方式时与全局不同
+	processor := c.processors.Get(config.TenantFromContext(ctx))
+	// 先按配置转换 <ruby> 注音并清理 Markdown（网址按请求或配置的方式处理），再进行 HTML 转义，防止在语音中读出格式符
+	cleanText := processor.StripMarkdownMode(utils.ConvertRuby(req.Text, c.rubyMode), req.URLMode)
+	// 双向文本控制符只影响显示，删除后按逻辑顺序朗读
+	if c.bidi.Enabled {
+		cleanText = utils.StripBidiControls(cleanText)
+	}
+	// 按句子的难度调整语速，在生成其他标签之前处理，句子中只有请求自带的标签
+	if feature.Pacing.Enabled(ctx) {
+		cleanText = c.pacing.Apply(cleanText)
+	}
+	// 读音提示转换为 <phoneme>、<sub> 标签后随其他保留标签一起通过转义
+	if c.inlineHints {
+		cleanText = ssmlpkg.ExpandHints(cleanText)
+	}
+	// 为日期、金额、电话号码等实体添加 <say-as>，已指定读法的内容不受影响
+	cleanText = ssmlpkg.InferSayAs(cleanText, c.sayAs)
+	// 按词表朗读缩写词，未知的缩写词按配置逐个字母朗读
+	cleanText = c.acronyms.Expand(cleanText)
+
+	// 准备SSML内容：引号中的对话按请求或配置换用第二个语音或调整语调，
+	// 启用 ssml.bidi 时阿拉伯文、希伯来文片段可能使用其他语音
+	ssml := ssmlpkg.Render(ssmlpkg.Speak(locale, c.dialogueNodes(processor, cleanText, req.Quotes, voice, style, rate, pitch)...))
+
+	// 发送前校验SSML，给出精确的出错位置，而不是Azure返回的笼统400
+	if c.validateSSML {
+		if err := ssmlpkg.Validate(ssml); err != nil {
+			return nil, apperr.Wrap(apperr.CodeSSMLInvalid, "SSML校验失败", err)
+		}
+	}
 
 	// 获取端点信息
 	endpoint, err := c.getEndpoint(ctx)
@@ -296,7 +402,7 @@ func (c *Client) createTTSRequest(ctx context.Context, req models.TTSRequest) (*
 	resp, err := c.httpClient.Do(httpReq)
 
 	if err != nil {
-		return nil, err
+		return nil, apperr.Wrap(apperr.CodeProviderError, "请求TTS服务失败", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -304,8 +410,39 @@ func (c *Client) createTTSRequest(ctx context.Context, req models.TTSRequest) (*
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		log.Printf("TTS API错误: %s, 状态码: %d", string(body), resp.StatusCode)
-		return nil, fmt.Errorf("TTS API错误: %s, 状态码: %d", string(body), resp.StatusCode)
+		return nil, statusError("TTS API错误", resp.StatusCode, string(body))
 	}
 
 	return resp, nil
 }
+
+// statusError 将 Azure 的 HTTP 状态码映射为带错误码的应用错误
+func statusError(message string, status int, body string) error {
+	err := fmt.Errorf("%s, 状态码: %d", body, status)
+	switch {
+	case status == http.StatusTooManyRequests:
+		return apperr.Wrap(apperr.CodeProviderThrottled, "TTS服务限流，请稍后重试", err)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return apperr.Wrap(apperr.CodeProviderAuthFailed, "TTS服务认证失败", err)
+	case status == http.StatusBadRequest && strings.Contains(strings.ToLower(body), "voice"):
+		return apperr.Wrap(apperr.CodeInvalidVoice, "语音不存在或不可用", err)
+	case status == http.StatusBadRequest:
+		return apperr.Wrap(apperr.CodeSSMLInvalid, "SSML文档无效", err)
+	default:
+		return apperr.Wrap(apperr.CodeProviderError, message, err)
+	}
+}
+
+func init() {
+	tts.Register("microsoft", func(cfg *config.Config) (tts.Service, error) {
+		return NewClient(cfg), nil
+	})
+}
+
+// TokenStatus 返回当前认证令牌分配的区域与到期时间（即提前刷新的时间），尚未获取令牌时到期时间为零值
+func (c *Client) TokenStatus() (string, time.Time) {
+	c.endpointMu.RLock()
+	defer c.endpointMu.RUnlock()
+	region, _ := c.endpoint["r"].(string)
+	return region, c.endpointExpiry
+}
@@ -0,0 +1,90 @@
+package microsoft
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"tts/internal/config"
+	ssmlpkg "tts/internal/ssml"
+)
+
+// 未配置时 prosody 方式下对话相对于请求的语速与音调（百分比）
+const (
+	defaultQuoteRate  = "0%"
+	defaultQuotePitch = "+10%"
+)
+
+// dialogueNodes 生成朗读文本的 <voice> 元素：按请求或 ssml.quotes.mode 识别引号中的对话，
+// 对话换用第二个语音或调整语速与音调。文本按对话切分后各片段分别转义，每段中的标签保持成对
+func (c *Client) dialogueNodes(processor *config.SSMLProcessor, text, mode, voice, style, rate, pitch string) []ssmlpkg.Node {
+	if mode == "" {
+		mode = c.quoteConfig.Mode
+	}
+	if mode == "" || mode == config.QuotesOff {
+		return c.voiceNodes(voice, style, rate, pitch, processor.EscapeSSML(text))
+	}
+	runs := c.quotes.Split(text)
+	if len(runs) == 1 {
+		return c.voiceNodes(voice, style, rate, pitch, processor.EscapeSSML(text))
+	}
+
+	quoteVoice, quoteRate, quotePitch := "", rate, pitch
+	if mode == config.QuotesVoice {
+		quoteVoice = c.dialogueVoice(voice)
+	}
+	if quoteVoice == "" {
+		quoteVoice = voice
+		quoteRate = addPercent(rate, c.quoteConfig.Rate, defaultQuoteRate)
+		quotePitch = addPercent(pitch, c.quoteConfig.Pitch, defaultQuotePitch)
+	}
+
+	var nodes []ssmlpkg.Node
+	for _, run := range runs {
+		escaped := processor.EscapeSSML(run.Text)
+		switch {
+		case !run.Quote:
+			nodes = append(nodes, c.voiceNodes(voice, style, rate, pitch, escaped)...)
+		case quoteVoice != voice:
+			// 第二个语音不一定支持请求的说话风格，只保留语速与音调
+			nodes = append(nodes, c.voiceNodes(quoteVoice, "", rate, pitch, escaped)...)
+		default:
+			nodes = append(nodes, c.voiceNodes(voice, style, quoteRate, quotePitch, escaped)...)
+		}
+	}
+	return nodes
+}
+
+// dialogueVoice 按请求语音的区域或语言从 ssml.quotes.voices 中选择朗读对话的语音，
+// 没有配置或与请求的语音相同时返回空字符串
+func (c *Client) dialogueVoice(voice string) string {
+	locale := localeOf(voice)
+	lang, _, _ := strings.Cut(locale, "-")
+	for _, key := range []string{locale, lang} {
+		if key == "" {
+			continue
+		}
+		for k, v := range c.quoteConfig.Voices {
+			if strings.EqualFold(k, key) && !strings.EqualFold(v, voice) {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// addPercent 把 delta（如 +10%，为空时使用 fallback）叠加到不带 % 的请求语速或音调上，无法解析时返回原值
+func addPercent(value, delta, fallback string) string {
+	if delta == "" {
+		delta = fallback
+	}
+	base, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return value
+	}
+	d, err := strconv.ParseFloat(strings.TrimSuffix(delta, "%"), 64)
+	if err != nil {
+		return value
+	}
+	return fmt.Sprintf("%+g", base+d)
+}
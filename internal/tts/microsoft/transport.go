@@ -0,0 +1,50 @@
+package microsoft
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"tts/internal/config"
+)
+
+// headerTransport 为每个发往 Azure 的请求（包括获取认证信息）设置配置的 User-Agent 与额外请求头
+type headerTransport struct {
+	next      http.RoundTripper
+	userAgent string
+	headers   http.Header
+}
+
+// RoundTrip 复制请求后写入请求头，不修改调用方的请求
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.next.RoundTrip(req)
+}
+
+// newOutboundTransport 按 tts.outbound 配置出站代理与请求头，未配置时与直接使用 transport 相同
+func newOutboundTransport(cfg config.OutboundConfig, transport *http.Transport) (http.RoundTripper, error) {
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("tts.outbound.proxy 无效: %q", cfg.Proxy)
+		}
+		if cfg.ProxyUsername != "" {
+			proxy.User = url.UserPassword(cfg.ProxyUsername, cfg.ProxyPassword)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if cfg.UserAgent == "" && len(cfg.Headers) == 0 {
+		return transport, nil
+	}
+	headers := make(http.Header, len(cfg.Headers))
+	for name, value := range cfg.Headers {
+		headers.Set(name, value)
+	}
+	return &headerTransport{next: transport, userAgent: cfg.UserAgent, headers: headers}, nil
+}
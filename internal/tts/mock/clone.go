@@ -0,0 +1,28 @@
+package mock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"tts/internal/apperr"
+	"tts/internal/tts"
+)
+
+// CloneVoice 模拟创建自定义语音，语音 ID 由名称与参考音频的哈希生成，相同的输入得到相同的 ID
+func (c *Client) CloneVoice(ctx context.Context, req tts.CloneRequest) (string, error) {
+	if len(req.Samples) == 0 {
+		return "", apperr.New(apperr.CodeInvalidRequest, "至少需要一段参考音频")
+	}
+	h := sha256.New()
+	h.Write([]byte(req.Name))
+	for _, sample := range req.Samples {
+		h.Write(sample.Data)
+	}
+	return "mock-clone-" + hex.EncodeToString(h.Sum(nil)[:6]), nil
+}
+
+// DeleteVoice 模拟删除自定义语音
+func (c *Client) DeleteVoice(ctx context.Context, voiceID string) error {
+	return nil
+}
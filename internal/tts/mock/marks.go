@@ -0,0 +1,75 @@
+package mock
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"tts/internal/apperr"
+	"tts/internal/models"
+)
+
+// sentenceEnds 是句末标点
+const sentenceEnds = "。！？!?.\n"
+
+// SpeechMarks 按模拟语速为句子与词语生成时间标记，与 SynthesizeSpeech 的音频时长一致。
+// 汉字逐字作为一个词，其他文字按连续的字母与数字划分。
+func (c *Client) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	if req.Text == "" {
+		return nil, apperr.New(apperr.CodeInvalidRequest, "文本不能为空")
+	}
+	text := req.Text
+
+	// at 返回从字节偏移 offset 处开始朗读的时间（毫秒）
+	at := func(offset int) int64 {
+		return int64(float64(utf8.RuneCountInString(text[:offset])) / c.charsPerSecond * 1000)
+	}
+	mark := func(kind string, start, end int) models.SpeechMark {
+		return models.SpeechMark{Time: at(start), Type: kind, Start: start, End: end, Value: text[start:end]}
+	}
+
+	var marks []models.SpeechMark
+	sentenceStart, wordStart := -1, -1
+	for i, r := range text {
+		end := i + utf8.RuneLen(r)
+		isHan := unicode.Is(unicode.Han, r)
+		isWord := !isHan && (unicode.IsLetter(r) || unicode.IsDigit(r))
+
+		if !isWord && wordStart >= 0 {
+			marks = append(marks, mark("word", wordStart, i))
+			wordStart = -1
+		}
+		if isWord && wordStart < 0 {
+			wordStart = i
+		}
+		if isHan {
+			marks = append(marks, mark("word", i, end))
+		}
+
+		if sentenceStart < 0 && !unicode.IsSpace(r) {
+			sentenceStart = i
+		}
+		if sentenceStart >= 0 && strings.ContainsRune(sentenceEnds, r) {
+			sentenceEnd := len(strings.TrimRightFunc(text[:end], unicode.IsSpace))
+			marks = append(marks, mark("sentence", sentenceStart, sentenceEnd))
+			sentenceStart = -1
+		}
+	}
+	if wordStart >= 0 {
+		marks = append(marks, mark("word", wordStart, len(text)))
+	}
+	if sentenceStart >= 0 {
+		marks = append(marks, mark("sentence", sentenceStart, len(strings.TrimRightFunc(text, unicode.IsSpace))))
+	}
+
+	// 与 Polly 一致：按起始位置排序，同一位置的句子标记排在词语标记之前
+	sort.SliceStable(marks, func(i, j int) bool {
+		if marks[i].Start != marks[j].Start {
+			return marks[i].Start < marks[j].Start
+		}
+		return marks[i].Type == "sentence" && marks[j].Type != "sentence"
+	})
+	return marks, nil
+}
@@ -0,0 +1,125 @@
+// Package mock 提供无需 Azure 凭据的模拟语音合成服务，
+// 输出与文本长度成正比的静音 MP3，结果完全确定，适用于开发、CI 与集成测试。
+package mock
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/tts"
+	"tts/internal/utils"
+)
+
+const (
+	// frameDuration 是每个 MPEG-2 Layer III 帧的时长（576 采样 / 24kHz）
+	frameDuration = 24 * time.Millisecond
+	// frameSize 是 48kbps、24kHz 单声道帧的字节数（72 * 48000 / 24000）
+	frameSize = 144
+	// defaultCharsPerSecond 是未配置时的模拟语速
+	defaultCharsPerSecond = 5.0
+)
+
+// silentFrame 是一个静音 MP3 帧：帧头之后的边信息与主数据全为 0，解码结果为静音
+var silentFrame = func() []byte {
+	frame := make([]byte, frameSize)
+	// 同步字 + MPEG-2 + Layer III + 无CRC
+	frame[0], frame[1] = 0xFF, 0xF3
+	// 比特率索引 6 (48kbps) + 采样率索引 1 (24kHz) + 无填充
+	frame[2] = 0x64
+	// 单声道
+	frame[3] = 0xC0
+	return frame
+}()
+
+// voices 是模拟服务提供的语音列表
+var voices = []models.Voice{
+	{Name: "mock-zh-CN-Female", DisplayName: "Mock Female", LocalName: "模拟女声", ShortName: "zh-CN-MockFemaleNeural", Gender: "Female", Locale: "zh-CN", LocaleName: "中文(中国)", SampleRateHertz: "24000"},
+	{Name: "mock-zh-CN-Male", DisplayName: "Mock Male", LocalName: "模拟男声", ShortName: "zh-CN-MockMaleNeural", Gender: "Male", Locale: "zh-CN", LocaleName: "中文(中国)", SampleRateHertz: "24000"},
+	{Name: "mock-en-US-Female", DisplayName: "Mock Female", LocalName: "Mock Female", ShortName: "en-US-MockFemaleNeural", Gender: "Female", Locale: "en-US", LocaleName: "English (United States)", SampleRateHertz: "24000"},
+}
+
+// Client 是模拟语音合成服务
+type Client struct {
+	charsPerSecond float64
+	latency        time.Duration
+	maxTextLength  int
+}
+
+// NewClient 创建模拟服务
+func NewClient(cfg *config.Config) *Client {
+	cps := cfg.TTS.Mock.CharsPerSecond
+	if cps <= 0 {
+		cps = defaultCharsPerSecond
+	}
+	return &Client{
+		charsPerSecond: cps,
+		latency:        time.Duration(cfg.TTS.Mock.LatencyMs) * time.Millisecond,
+		maxTextLength:  cfg.TTS.MaxTextLength,
+	}
+}
+
+func init() {
+	tts.Register("mock", func(cfg *config.Config) (tts.Service, error) {
+		return NewClient(cfg), nil
+	})
+}
+
+// ListVoices 返回模拟语音列表
+func (c *Client) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	var result []models.Voice
+	for _, voice := range voices {
+		if locale == "" || strings.HasPrefix(voice.Locale, locale) {
+			result = append(result, voice)
+		}
+	}
+	return result, nil
+}
+
+// SynthesizeSpeech 生成时长与文本长度成正比的静音音频
+func (c *Client) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	if req.Text == "" {
+		return nil, apperr.New(apperr.CodeInvalidRequest, "文本不能为空")
+	}
+	if length := utils.GraphemeCount(req.Text); c.maxTextLength > 0 && length > c.maxTextLength {
+		return nil, apperr.Newf(apperr.CodeTextTooLong, "文本长度超过限制 (%d > %d)", length, c.maxTextLength)
+	}
+
+	// 模拟上游延迟
+	if c.latency > 0 {
+		select {
+		case <-time.After(c.latency):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return &models.TTSResponse{
+		AudioContent: Silence(c.Duration(req.Text)),
+		ContentType:  "audio/mpeg",
+	}, nil
+}
+
+// Duration 返回文本对应的模拟音频时长
+func (c *Client) Duration(text string) time.Duration {
+	seconds := float64(utf8.RuneCountInString(text)) / c.charsPerSecond
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Silence 生成指定时长的静音 MP3，至少包含一帧
+func Silence(d time.Duration) []byte {
+	frames := int(math.Ceil(float64(d) / float64(frameDuration)))
+	if frames < 1 {
+		frames = 1
+	}
+	audio := make([]byte, 0, frames*frameSize)
+	for i := 0; i < frames; i++ {
+		audio = append(audio, silentFrame...)
+	}
+	return audio
+}
@@ -0,0 +1,120 @@
+package tts
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/metrics"
+	"tts/internal/models"
+)
+
+var negativeHitsTotal = metrics.NewCounter("tts_negative_cache_hits_total",
+	"命中失败缓存、没有重新校验或发往上游就返回错误的请求数", "provider", "code")
+
+// maxNegativeEntries 是最多记录的失败请求数，超过时不再记录新的失败
+const maxNegativeEntries = 4096
+
+// NegativeCachedService 在一段时间内记住输入本身导致的确定性失败（SSML 无效、语音不存在、文本过长），
+// 客户端出错后反复发送的相同请求直接返回同一个错误，不再重复校验或调用上游
+type NegativeCachedService struct {
+	next Service
+	name string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]negativeEntry
+}
+
+// negativeEntry 是一个被记住的失败
+type negativeEntry struct {
+	err     error
+	expires time.Time
+}
+
+// NewNegativeCachedService 创建缓存确定性失败的服务，ttl 为失败被记住的时长
+func NewNegativeCachedService(next Service, name string, ttl time.Duration) *NegativeCachedService {
+	return &NegativeCachedService{next: next, name: name, ttl: ttl, entries: make(map[string]negativeEntry)}
+}
+
+// ListVoices 获取底层服务的语音列表
+func (s *NegativeCachedService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 相同的请求最近确定性失败过时直接返回该错误，否则交给底层服务并记录确定性失败
+func (s *NegativeCachedService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	// 按密钥设置的 SSML 处理方式不同，同一文本对不同租户的校验结果可能不同
+	key := strings.Join([]string{config.TenantFromContext(ctx), req.Text, req.Voice, req.Rate, req.Pitch, req.Style, req.URLMode, req.Quotes}, "\x00")
+	if err := s.lookup(key); err != nil {
+		negativeHitsTotal.Inc(s.name, string(apperr.CodeOf(err)))
+		return nil, err
+	}
+	resp, err := s.next.SynthesizeSpeech(ctx, req)
+	if err != nil && deterministic(err) {
+		s.remember(key, err)
+	}
+	return resp, err
+}
+
+// SpeechMarks 获取底层服务的语音标记，不缓存
+func (s *NegativeCachedService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	return provider.SpeechMarks(ctx, req)
+}
+
+// Warm 预热底层服务
+func (s *NegativeCachedService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// lookup 返回未过期的失败，过期的记录顺便删除
+func (s *NegativeCachedService) lookup(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(e.expires) {
+		delete(s.entries, key)
+		return nil
+	}
+	return e.err
+}
+
+// remember 记录一个失败，记录已满时先清理过期的记录，仍然满时放弃记录
+func (s *NegativeCachedService) remember(key string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if len(s.entries) >= maxNegativeEntries {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		if len(s.entries) >= maxNegativeEntries {
+			return
+		}
+	}
+	s.entries[key] = negativeEntry{err: err, expires: now.Add(s.ttl)}
+}
+
+// deterministic 判断错误是否只取决于请求内容，相同请求重试必然得到相同结果
+func deterministic(err error) bool {
+	switch apperr.CodeOf(err) {
+	case apperr.CodeSSMLInvalid, apperr.CodeInvalidVoice, apperr.CodeTextTooLong:
+		return true
+	}
+	return false
+}
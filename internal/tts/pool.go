@@ -0,0 +1,83 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/pool"
+)
+
+// PooledService 通过服务的并发池限制同时发往上游的请求数，
+// 所有入口与分段合成的每个片段共享同一个池，池满时按租户轮流放行
+type PooledService struct {
+	next Service
+	pool *pool.Pool
+}
+
+// NewPooledService 创建受并发池限制的服务
+func NewPooledService(next Service, p *pool.Pool) *PooledService {
+	return &PooledService{next: next, pool: p}
+}
+
+// ListVoices 获取底层服务的语音列表，不占用名额
+func (s *PooledService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 获取名额后合成
+func (s *PooledService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := s.next.SynthesizeSpeech(ctx, req)
+	s.report(err)
+	return resp, err
+}
+
+// SpeechMarks 获取名额后获取语音标记
+func (s *PooledService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	release, err := s.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	marks, err := provider.SpeechMarks(ctx, req)
+	s.report(err)
+	return marks, err
+}
+
+// Warm 预热底层服务，不占用名额
+func (s *PooledService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// acquire 按请求的租户获取名额
+func (s *PooledService) acquire(ctx context.Context) (func(), error) {
+	release, err := s.pool.Acquire(ctx, config.TenantFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("等待并发名额时请求被取消: %w", err)
+	}
+	return release, nil
+}
+
+// report 把上游的限流与成功反馈给并发池，用于自适应调整上限
+func (s *PooledService) report(err error) {
+	switch {
+	case err == nil:
+		s.pool.Succeeded()
+	case apperr.CodeOf(err) == apperr.CodeProviderThrottled:
+		s.pool.Throttled()
+	}
+}
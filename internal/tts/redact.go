@@ -0,0 +1,65 @@
+package tts
+
+import (
+	"context"
+	"log"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/redact"
+)
+
+// RedactService 在把请求交给底层服务之前替换文本中的个人信息，
+// 所有入口（HTTP、Wyoming、MQTT 等）与分段合成的每个片段都会经过它。
+type RedactService struct {
+	next     Service
+	redactor *redact.Redactor
+}
+
+// NewRedactService 创建替换个人信息的服务
+func NewRedactService(next Service, cfg config.RedactConfig) (*RedactService, error) {
+	redactor, err := redact.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &RedactService{next: next, redactor: redactor}, nil
+}
+
+// ListVoices 获取底层服务的语音列表
+func (s *RedactService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 替换个人信息后合成
+func (s *RedactService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	req.Text = s.redactText(req.Text)
+	return s.next.SynthesizeSpeech(ctx, req)
+}
+
+// SpeechMarks 替换个人信息后获取语音标记，保证标记与合成的音频一致
+func (s *RedactService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	req.Text = s.redactText(req.Text)
+	return provider.SpeechMarks(ctx, req)
+}
+
+// Warm 预热底层服务
+func (s *RedactService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// redactText 替换文本中的个人信息，日志只记录各类型的次数
+func (s *RedactService) redactText(text string) string {
+	text, counts := s.redactor.Redact(text)
+	if len(counts) > 0 {
+		log.Printf("已替换个人信息: %v", counts)
+	}
+	return text
+}
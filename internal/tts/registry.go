@@ -0,0 +1,92 @@
+package tts
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"tts/internal/cache"
+	"tts/internal/config"
+	"tts/internal/health"
+	"tts/internal/pool"
+)
+
+// Factory 根据配置创建一个语音合成服务
+type Factory func(cfg *config.Config) (Service, error)
+
+// DefaultProvider 是未配置 provider 时使用的服务名称
+const DefaultProvider = "microsoft"
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{}
+)
+
+// Register 注册一个语音合成服务实现
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+// New 按名称创建语音合成服务，名称为空时使用默认服务。请求的耗时与结果记录到服务的健康记录器，
+// tts.pools 中配置了该服务时套上并发池（排队时间不计入耗时），同时到达的相同请求合并为一次上游请求；
+// 启用 tts.time_stretch 时超出范围的语速通过变速补足；
+// 启用 cache.synthesis 时缓存合成结果，配置 cache.negative_ttl 时短时间内记住输入导致的确定性失败
+func New(name string, cfg *config.Config) (Service, error) {
+	if name == "" {
+		name = DefaultProvider
+	}
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的TTS服务: %s (可用: %v)", name, Providers())
+	}
+	service, err := factory(cfg)
+	if err != nil {
+		return nil, err
+	}
+	registerCloner(name, service)
+	service = NewMonitoredService(service, health.Get(name))
+	// 配置了并发池的服务，同名服务的所有实例共享一个池；启用自适应并发时未配置的服务以 max_concurrent 为上限
+	limit, ok := cfg.TTS.Pools[name]
+	if !ok && cfg.TTS.Adaptive.Enabled {
+		limit, ok = cfg.TTS.MaxConcurrent, true
+	}
+	if ok {
+		p := pool.Get(name, limit)
+		if cfg.TTS.Adaptive.Enabled {
+			p.Adaptive(cfg.TTS.Adaptive.Min)
+		}
+		service = NewPooledService(service, p)
+	}
+	// 合并同时到达的相同请求，重复的请求不占用并发池名额
+	service = NewCoalescingService(service, name)
+	// 语速超出上游范围时变速补足，放在缓存之内，缓存的是变速后的音频
+	if cfg.TTS.TimeStretch.Enabled {
+		service = NewStretchService(service, name, cfg.TTS.TimeStretch)
+	}
+	// 启用 cache.synthesis 时缓存合成结果，命中缓存的请求不再合并或排队
+	if cfg.Cache.Synthesis {
+		service = NewCachedService(service, name, cache.Open(cfg.Cache.Dir, cfg.Cache.MaxEntries))
+	}
+	// 短时间内记住输入导致的确定性失败，出错的客户端反复重试时不再校验或调用上游
+	if cfg.Cache.NegativeTTL > 0 {
+		service = NewNegativeCachedService(service, name, time.Duration(cfg.Cache.NegativeTTL)*time.Second)
+	}
+	return service, nil
+}
+
+// Providers 返回所有已注册的服务名称
+func Providers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,202 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/metrics"
+	"tts/internal/models"
+)
+
+// defaultShadowInFlight 是未配置 max_in_flight 时同时进行的影子请求上限
+const defaultShadowInFlight = 4
+
+var shadowDroppedTotal = metrics.NewCounter("tts_shadow_dropped_total",
+	"同时进行的影子请求已达上限而丢弃的影子请求数")
+
+// ShadowService 在正常返回主服务结果的同时，把相同请求异步发送给影子服务，
+// 并保存两份音频与耗时信息，用于评估服务或语音迁移，不影响主响应。
+type ShadowService struct {
+	primary     Service
+	shadow      Service
+	primaryName string
+	shadowName  string
+	cfg         config.ShadowConfig
+	slots       chan struct{} // 限制同时进行的影子请求数
+}
+
+// shadowResult 记录一次合成的结果
+type shadowResult struct {
+	Provider  string  `json:"provider"`
+	Voice     string  `json:"voice"`
+	LatencyMs int64   `json:"latency_ms"`
+	AudioSize int     `json:"audio_size"`
+	SizeRatio float64 `json:"size_ratio,omitempty"` // 影子音频与主音频大小之比，可粗略反映时长差异
+	Error     string  `json:"error,omitempty"`
+}
+
+// shadowRecord 是保存在 meta.json 中的对比记录
+type shadowRecord struct {
+	ID         string       `json:"id"`
+	Time       time.Time    `json:"time"`
+	TextLength int          `json:"text_length"`
+	Rate       string       `json:"rate"`
+	Pitch      string       `json:"pitch"`
+	Style      string       `json:"style"`
+	Primary    shadowResult `json:"primary"`
+	Shadow     shadowResult `json:"shadow"`
+}
+
+// NewShadowService 创建影子对比服务
+func NewShadowService(primary Service, primaryName string, cfg *config.Config) (*ShadowService, error) {
+	shadow, err := New(cfg.Shadow.Provider, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if primaryName == "" {
+		primaryName = DefaultProvider
+	}
+	shadowName := cfg.Shadow.Provider
+	if shadowName == "" {
+		shadowName = DefaultProvider
+	}
+	if err := os.MkdirAll(cfg.Shadow.OutputDir, 0755); err != nil {
+		return nil, err
+	}
+	inFlight := cfg.Shadow.MaxInFlight
+	if inFlight <= 0 {
+		inFlight = defaultShadowInFlight
+	}
+	return &ShadowService{
+		primary:     primary,
+		shadow:      shadow,
+		primaryName: primaryName,
+		shadowName:  shadowName,
+		cfg:         cfg.Shadow,
+		slots:       make(chan struct{}, inFlight),
+	}, nil
+}
+
+// ListVoices 获取主服务的语音列表
+func (s *ShadowService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.primary.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 使用主服务合成，并按采样比例触发影子请求。
+// 同时进行的影子请求已达上限时直接丢弃，不等待，也不影响主响应
+func (s *ShadowService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	start := time.Now()
+	resp, err := s.primary.SynthesizeSpeech(ctx, req)
+	latency := time.Since(start)
+
+	if s.cfg.SampleRate > 0 && rand.Float64() < s.cfg.SampleRate {
+		select {
+		case s.slots <- struct{}{}:
+			// 主请求返回后 ctx 会被取消，影子请求只沿用其中的值（隐私模式、密钥等）
+			shadowCtx := context.WithoutCancel(ctx)
+			go func() {
+				defer func() { <-s.slots }()
+				s.runShadow(shadowCtx, req, resp, err, latency)
+			}()
+		default:
+			shadowDroppedTotal.Inc()
+		}
+	}
+
+	return resp, err
+}
+
+// runShadow 向影子服务发送请求并保存对比结果
+func (s *ShadowService) runShadow(ctx context.Context, req models.TTSRequest, primaryResp *models.TTSResponse, primaryErr error, primaryLatency time.Duration) {
+	timeout := time.Duration(s.cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	shadowReq := req
+	if voice, ok := s.cfg.VoiceMapping[req.Voice]; ok && voice != "" {
+		shadowReq.Voice = voice
+	}
+
+	start := time.Now()
+	shadowResp, shadowErr := s.shadow.SynthesizeSpeech(ctx, shadowReq)
+	shadowLatency := time.Since(start)
+
+	record := shadowRecord{
+		ID:         uuid.New().String(),
+		Time:       time.Now(),
+		TextLength: utf8.RuneCountInString(req.Text),
+		Rate:       req.Rate,
+		Pitch:      req.Pitch,
+		Style:      req.Style,
+		Primary:    shadowResult{Provider: s.primaryName, Voice: req.Voice, LatencyMs: primaryLatency.Milliseconds()},
+		Shadow:     shadowResult{Provider: s.shadowName, Voice: shadowReq.Voice, LatencyMs: shadowLatency.Milliseconds()},
+	}
+
+	dir := filepath.Join(s.cfg.OutputDir, record.Time.Format("20060102"), record.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("创建影子对比目录失败: %v", err)
+		return
+	}
+
+	if primaryErr != nil {
+		record.Primary.Error = primaryErr.Error()
+	} else {
+		record.Primary.AudioSize = len(primaryResp.AudioContent)
+		s.writeFile(filepath.Join(dir, "primary.mp3"), primaryResp.AudioContent)
+	}
+	if shadowErr != nil {
+		record.Shadow.Error = shadowErr.Error()
+	} else {
+		record.Shadow.AudioSize = len(shadowResp.AudioContent)
+		s.writeFile(filepath.Join(dir, "shadow.mp3"), shadowResp.AudioContent)
+	}
+	if record.Primary.AudioSize > 0 && record.Shadow.AudioSize > 0 {
+		record.Shadow.SizeRatio = float64(record.Shadow.AudioSize) / float64(record.Primary.AudioSize)
+	}
+
+	meta, _ := json.MarshalIndent(record, "", "  ")
+	s.writeFile(filepath.Join(dir, "meta.json"), meta)
+
+	log.Printf("影子对比完成: %s, 主服务 %s 耗时 %v, 影子服务 %s 耗时 %v",
+		record.ID, s.primaryName, primaryLatency, s.shadowName, shadowLatency)
+}
+
+// writeFile 写入文件，失败时只记录日志
+func (s *ShadowService) writeFile(path string, data []byte) {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Printf("保存影子对比文件失败: %v", err)
+	}
+}
+
+// Warm 同时预热主服务与影子服务
+func (s *ShadowService) Warm(ctx context.Context) error {
+	if warmer, ok := s.shadow.(Warmer); ok {
+		if err := warmer.Warm(ctx); err != nil {
+			log.Printf("影子服务预热失败: %v", err)
+		}
+	}
+	if warmer, ok := s.primary.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// SpeechMarks 使用主服务获取语音标记
+func (s *ShadowService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	if provider, ok := s.primary.(MarkProvider); ok {
+		return provider.SpeechMarks(ctx, req)
+	}
+	return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+}
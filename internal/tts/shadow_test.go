@@ -0,0 +1,80 @@
+package tts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/privacy"
+)
+
+// stubService 立即返回固定音频；设置了 calls 时把每次调用的上下文发给 calls，并等到 release 关闭才返回
+type stubService struct {
+	calls   chan context.Context
+	release chan struct{}
+}
+
+func (s *stubService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return nil, nil
+}
+
+func (s *stubService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	if s.calls != nil {
+		s.calls <- ctx
+		<-s.release
+	}
+	return &models.TTSResponse{AudioContent: []byte{0}, ContentType: "audio/mpeg"}, nil
+}
+
+func TestShadowKeepsValuesAndDropsWhenFull(t *testing.T) {
+	shadow := &stubService{calls: make(chan context.Context, 2), release: make(chan struct{})}
+	s := &ShadowService{
+		primary:     &stubService{},
+		shadow:      shadow,
+		primaryName: "primary",
+		shadowName:  "shadow",
+		cfg:         config.ShadowConfig{SampleRate: 1, OutputDir: t.TempDir()},
+		slots:       make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithCancel(privacy.NewContext(context.Background(), true))
+	if _, err := s.SynthesizeSpeech(ctx, models.TTSRequest{Text: "你好", Voice: "v"}); err != nil {
+		t.Fatal(err)
+	}
+	// 主请求结束后上下文被取消，影子请求不受影响并保留隐私模式
+	cancel()
+	shadowCtx := <-shadow.calls
+	if shadowCtx.Err() != nil {
+		t.Errorf("影子请求随主请求取消: %v", shadowCtx.Err())
+	}
+	if !privacy.Enabled(shadowCtx) {
+		t.Error("影子请求没有沿用隐私模式")
+	}
+
+	// 唯一的名额被占用，新的影子请求直接丢弃
+	dropped := shadowDroppedTotal.Value()
+	if _, err := s.SynthesizeSpeech(context.Background(), models.TTSRequest{Text: "再见", Voice: "v"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := shadowDroppedTotal.Value() - dropped; got != 1 {
+		t.Errorf("丢弃数 = %v, want 1", got)
+	}
+	select {
+	case <-shadow.calls:
+		t.Error("名额已满时仍然发出了影子请求")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(shadow.release)
+	// 名额释放后可以继续发出影子请求
+	deadline := time.After(5 * time.Second)
+	for len(s.slots) > 0 {
+		select {
+		case <-deadline:
+			t.Fatal("影子请求结束后名额没有释放")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"tts/internal/apperr"
+	"tts/internal/audio"
+	"tts/internal/config"
+	"tts/internal/feature"
+	"tts/internal/metrics"
+	"tts/internal/models"
+)
+
+var stretchTotal = metrics.NewCounter("tts_time_stretch_total",
+	"语速超出上游范围、经过变速处理的合成请求数", "provider")
+
+// 未配置时 prosody 语速的默认范围（百分比），对应 Azure 朗读自然的 0.5～2 倍速
+const (
+	defaultStretchMaxRate = 100
+	defaultStretchMinRate = -50
+)
+
+// StretchService 处理超出上游语速范围的请求：语速按范围上下限合成，剩余的倍数通过变速（音调不变）补足，
+// 避免 2.5～3 倍速等极端语速由上游直接合成时失真
+type StretchService struct {
+	next    Service
+	name    string
+	maxRate float64
+	minRate float64
+}
+
+// NewStretchService 创建对超出范围的语速进行变速处理的服务
+func NewStretchService(next Service, name string, cfg config.TimeStretchConfig) *StretchService {
+	s := &StretchService{next: next, name: name, maxRate: defaultStretchMaxRate, minRate: defaultStretchMinRate}
+	if cfg.MaxRate > 0 {
+		s.maxRate = float64(cfg.MaxRate)
+	}
+	if cfg.MinRate < 0 && cfg.MinRate > -100 {
+		s.minRate = float64(cfg.MinRate)
+	}
+	return s
+}
+
+// ListVoices 获取底层服务的语音列表
+func (s *StretchService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 语速在范围内时直接交给底层服务，否则按范围上下限合成后变速
+func (s *StretchService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	factor, rate := s.split(ctx, req.Rate)
+	if factor == 1 {
+		return s.next.SynthesizeSpeech(ctx, req)
+	}
+	req.Rate = rate
+	resp, err := s.next.SynthesizeSpeech(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	stretched, err := audio.Stretch(resp.AudioContent, factor)
+	if err != nil {
+		// 变速失败时返回未变速的音频，语速按范围上下限，不影响收听
+		log.Printf("音频变速失败，返回语速 %s%% 的音频: %v", rate, err)
+		return resp, nil
+	}
+	stretchTotal.Inc(s.name)
+	result := *resp
+	result.AudioContent = stretched
+	return &result, nil
+}
+
+// SpeechMarks 按与合成相同的方式拆分语速，标记时间按变速倍数换算
+func (s *StretchService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	factor, rate := s.split(ctx, req.Rate)
+	req.Rate = rate
+	marks, err := provider.SpeechMarks(ctx, req)
+	if err != nil || factor == 1 {
+		return marks, err
+	}
+	for i := range marks {
+		marks[i].Time = int64(float64(marks[i].Time) / factor)
+	}
+	return marks, nil
+}
+
+// Warm 预热底层服务
+func (s *StretchService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// split 把请求的语速拆分为上游合成使用的语速与之后变速的倍数，
+// 语速在范围内、无法解析或请求所属密钥关闭了 time_stretch 功能开关时倍数为 1
+func (s *StretchService) split(ctx context.Context, rate string) (factor float64, upstream string) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(rate), "%"), 64)
+	if err != nil || !feature.TimeStretch.Enabled(ctx) {
+		return 1, rate
+	}
+	limit := value
+	switch {
+	case value > s.maxRate:
+		limit = s.maxRate
+	case value < s.minRate:
+		limit = s.minRate
+	default:
+		return 1, rate
+	}
+	// 语速低于 -100% 时按最慢的 0.1 倍计算
+	speed := max(1+value/100, 0.1)
+	return speed / (1 + limit/100), fmt.Sprintf("%+.0f", limit)
+}
@@ -0,0 +1,82 @@
+package tts
+
+import (
+	"context"
+	"strings"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/feature"
+	"tts/internal/langpack"
+	"tts/internal/models"
+)
+
+// VerbalizeService 在合成前按语音所属语言的语言包展开数字、日期、单位与缩写，
+// 没有对应语言包的语音保持原文。
+type VerbalizeService struct {
+	next         Service
+	packs        *langpack.Set
+	defaultVoice string
+}
+
+// NewVerbalizeService 加载语言包并创建展开服务
+func NewVerbalizeService(next Service, cfg *config.Config) (*VerbalizeService, error) {
+	packs, err := langpack.Load(cfg.Verbalize.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return &VerbalizeService{next: next, packs: packs, defaultVoice: cfg.TTS.DefaultVoice}, nil
+}
+
+// Packs 返回已加载的语言包
+func (s *VerbalizeService) Packs() *langpack.Set {
+	return s.packs
+}
+
+// ListVoices 获取底层服务的语音列表
+func (s *VerbalizeService) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return s.next.ListVoices(ctx, locale)
+}
+
+// SynthesizeSpeech 展开文本后合成，请求所属密钥关闭了 verbalize 功能开关时保持原文
+func (s *VerbalizeService) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	req.Text = s.verbalize(ctx, req)
+	return s.next.SynthesizeSpeech(ctx, req)
+}
+
+// SpeechMarks 展开文本后获取语音标记，保证标记与合成的音频一致
+func (s *VerbalizeService) SpeechMarks(ctx context.Context, req models.TTSRequest) ([]models.SpeechMark, error) {
+	provider, ok := s.next.(MarkProvider)
+	if !ok {
+		return nil, apperr.New(apperr.CodeNotSupported, "当前TTS服务不支持语音标记")
+	}
+	req.Text = s.verbalize(ctx, req)
+	return provider.SpeechMarks(ctx, req)
+}
+
+// Warm 预热底层服务
+func (s *VerbalizeService) Warm(ctx context.Context) error {
+	if warmer, ok := s.next.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// verbalize 使用请求语音所属语言的语言包展开文本
+func (s *VerbalizeService) verbalize(ctx context.Context, req models.TTSRequest) string {
+	if !feature.Verbalize.Enabled(ctx) {
+		return req.Text
+	}
+	voice := req.Voice
+	if voice == "" {
+		voice = s.defaultVoice
+	}
+	locale := ""
+	if parts := strings.Split(voice, "-"); len(parts) >= 2 {
+		locale = parts[0] + "-" + parts[1]
+	}
+	if pack := s.packs.Lookup(locale); pack != nil {
+		return pack.Verbalize(req.Text)
+	}
+	return req.Text
+}
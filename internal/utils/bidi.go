@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 从右向左书写的文字
+const (
+	ScriptArabic = "ar"
+	ScriptHebrew = "he"
+)
+
+// isBidiControl 判断是否为双向文本控制符：LRM、RLM、ALM 以及嵌入、覆盖和隔离符
+func isBidiControl(r rune) bool {
+	switch {
+	case r == '\u200e' || r == '\u200f' || r == '\u061c':
+		return true
+	case r >= '\u202a' && r <= '\u202e':
+		return true
+	case r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+// StripBidiControls 删除双向文本控制符。文本按逻辑顺序存储，这些不可见的控制符只影响显示，
+// 分段后可能成对地落在不同片段中，发送给语音服务时还可能被读出或打乱语序。
+func StripBidiControls(text string) string {
+	if strings.IndexFunc(text, isBidiControl) < 0 {
+		return text
+	}
+	return strings.Map(func(r rune) rune {
+		if isBidiControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// RTLScript 返回字符所属的从右向左文字（ar、he），其他字符返回空字符串
+func RTLScript(r rune) string {
+	switch {
+	case r >= 0x0590 && r <= 0x05FF, r >= 0xFB1D && r <= 0xFB4F:
+		return ScriptHebrew
+	case r >= 0x0600 && r <= 0x06FF, r >= 0x0750 && r <= 0x077F, r >= 0x08A0 && r <= 0x08FF,
+		r >= 0xFB50 && r <= 0xFDFF, r >= 0xFE70 && r <= 0xFEFF:
+		return ScriptArabic
+	}
+	return ""
+}
+
+// ContainsRTL 判断文本是否包含阿拉伯文或希伯来文字母
+func ContainsRTL(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) && RTLScript(r) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ScriptRun 是书写方向相同的一段连续文本
+type ScriptRun struct {
+	Script string // ar、he，从左向右书写的文字为空
+	Text   string
+}
+
+// SplitScriptRuns 按逻辑顺序把文本切分为阿拉伯文、希伯来文与其他文字的连续片段。
+// 只有字母决定片段的文字，空白、标点与数字跟随前一个片段；
+// 完整的 <...> 标签不会被拆开，嵌套在元素内部的内容也不会被切开，保证每个片段都是完整的SSML。
+func SplitScriptRuns(text string) []ScriptRun {
+	var runs []ScriptRun
+	start, depth := 0, 0
+	script, decided := "", false
+	for i := 0; i < len(text); {
+		size := nextUnit(text[i:])
+		unit := text[i : i+size]
+		if size > 1 && unit[0] == '<' && unit[size-1] == '>' {
+			switch {
+			case strings.HasPrefix(unit, "</"):
+				depth--
+			case !strings.HasSuffix(unit, "/>"):
+				depth++
+			}
+			i += size
+			continue
+		}
+
+		r, _ := utf8.DecodeRuneInString(unit)
+		if unicode.IsLetter(r) {
+			current := RTLScript(r)
+			switch {
+			case !decided:
+				script, decided = current, true
+			case current != script && depth <= 0:
+				runs = append(runs, ScriptRun{Script: script, Text: text[start:i]})
+				start, script = i, current
+			}
+		}
+		i += size
+	}
+	if start < len(text) {
+		runs = append(runs, ScriptRun{Script: script, Text: text[start:]})
+	}
+	return runs
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer 是放回缓冲池的缓冲区容量上限，超过的缓冲区直接丢弃，避免个别超长音频让池长期占用大块内存
+const maxPooledBuffer = 4 << 20
+
+// bufferPool 复用读取音频、收集 ffmpeg 输出时使用的缓冲区
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// GetBuffer 从缓冲池取出一个已清空的缓冲区，用完后通过 PutBuffer 归还
+func GetBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// PutBuffer 归还缓冲区，归还后不能再使用其中的数据
+func PutBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBuffer {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// ReadAll 读取 r 的全部内容，返回的切片只分配一次且长度与容量一致。
+// size 为已知的内容长度（如 Content-Length），未知时传 -1：此时先读入池中的缓冲区再复制一份，
+// 而不是像 io.ReadAll 那样随读取反复扩容
+func ReadAll(r io.Reader, size int64) ([]byte, error) {
+	if size > 0 {
+		data := make([]byte, size)
+		n, err := io.ReadFull(r, data)
+		if err == io.ErrUnexpectedEOF {
+			return data[:n:n], nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 内容可能比声明的长，剩余部分按未知长度读取
+		rest, err := ReadAll(r, -1)
+		if err != nil || len(rest) == 0 {
+			return data, err
+		}
+		return append(data, rest...), nil
+	}
+
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// BenchmarkReadAudio 对比读取约 400KB 音频（相当于几分钟的 48kbps MP3）时的内存分配，
+// HalfReader 模拟分块到达的响应体
+func BenchmarkReadAudio(b *testing.B) {
+	audio := bytes.Repeat([]byte{0xFF, 0xF3, 0x44, 0xC4}, 100_000)
+	for _, bm := range []struct {
+		name string
+		read func(io.Reader) ([]byte, error)
+	}{
+		{"io.ReadAll", io.ReadAll},
+		{"pooled", func(r io.Reader) ([]byte, error) { return ReadAll(r, -1) }},
+		{"sized", func(r io.Reader) ([]byte, error) { return ReadAll(r, int64(len(audio))) }},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bm.read(iotest.HalfReader(bytes.NewReader(audio))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// zeroWidthJoiner 用于连接多个 emoji 组成一个字符
+const zeroWidthJoiner = '\u200d'
+
+// extendsCluster 判断字符是否附着在前一个字符上，不能单独成为一个字素
+func extendsCluster(r rune) bool {
+	switch {
+	case r == zeroWidthJoiner:
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // 变体选择符
+		return true
+	case r >= 0xE0100 && r <= 0xE01EF: // 变体选择符补充
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // emoji 肤色修饰符
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // emoji 标签序列
+		return true
+	}
+	return false
+}
+
+// isRegionalIndicator 判断是否为国旗 emoji 使用的区域指示符
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// NextGrapheme 返回 s 开头第一个字素簇的字节长度。
+// 这是 Unicode 字素切分规则的简化实现，覆盖组合符号、变体选择符、
+// emoji 修饰符、ZWJ 序列、国旗与 CRLF，足以保证切分时不会破坏可见字符。
+func NextGrapheme(s string) int {
+	if s == "" {
+		return 0
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	i := size
+	if r == '\r' {
+		if i < len(s) && s[i] == '\n' {
+			i++
+		}
+		return i
+	}
+	if isRegionalIndicator(r) {
+		if next, n := utf8.DecodeRuneInString(s[i:]); isRegionalIndicator(next) {
+			i += n
+		}
+	}
+	prev := r
+	for i < len(s) {
+		next, n := utf8.DecodeRuneInString(s[i:])
+		if !extendsCluster(next) && prev != zeroWidthJoiner {
+			break
+		}
+		prev = next
+		i += n
+	}
+	return i
+}
+
+// GraphemeCount 返回文本中用户可见字符（字素簇）的数量
+func GraphemeCount(s string) int {
+	count := 0
+	for len(s) > 0 {
+		s = s[NextGrapheme(s):]
+		count++
+	}
+	return count
+}
+
+// SplitByDelimiters 在分句符号之后切分文本，符号保留在前一段末尾。
+// 如果切分点之前的文本以 protected 中的词结尾（包含或不包含该符号），或切分点落在该词内部（如 "e.g." 中的第一个句点），
+// 则不在此处切分。
+func SplitByDelimiters(text string, delimiters, protected []string) []string {
+	if len(delimiters) == 0 {
+		return []string{text}
+	}
+
+	var result []string
+	start := 0
+	for i := 0; i < len(text); {
+		matched := ""
+		for _, d := range delimiters {
+			if d != "" && len(d) > len(matched) && strings.HasPrefix(text[i:], d) {
+				matched = d
+			}
+		}
+		if matched == "" {
+			i += NextGrapheme(text[i:])
+			continue
+		}
+
+		end := i + len(matched)
+		if !endsWithAny(text[start:i], protected) && !endsWithAny(text[start:end], protected) && !withinProtected(text[start:], end-start, protected) {
+			if piece := strings.TrimSpace(text[start:end]); piece != "" {
+				result = append(result, piece)
+			}
+			start = end
+		}
+		i = end
+	}
+	if piece := strings.TrimSpace(text[start:]); piece != "" {
+		result = append(result, piece)
+	}
+	return result
+}
+
+// withinProtected 判断 text 在 end 处的切分点是否落在 protected 中某个词的内部
+func withinProtected(text string, end int, protected []string) bool {
+	for _, word := range protected {
+		for k := 1; k < len(word); k++ {
+			if strings.HasSuffix(text[:end], word[:k]) && strings.HasPrefix(text[end:], word[k:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// endsWithAny 判断文本是否以任一后缀结尾
+func endsWithAny(text string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if suffix != "" && strings.HasSuffix(text, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextUnit 返回开头第一个切分单位的字节长度：完整的 <...> 标签为一个单位，否则为一个字素
+func nextUnit(s string) int {
+	if s != "" && s[0] == '<' {
+		if end := strings.IndexAny(s[1:], "<>"); end >= 0 && s[1+end] == '>' {
+			return end + 2
+		}
+	}
+	return NextGrapheme(s)
+}
+
+// UnitCount 返回文本的切分单位数，即字素数，但每个完整的 <...> 标签只计为一个
+func UnitCount(s string) int {
+	count := 0
+	for len(s) > 0 {
+		s = s[nextUnit(s):]
+		count++
+	}
+	return count
+}
+
+// splitPreference 是切分长句时优先选择的断点，越靠前优先级越高
+var splitPreference = []string{"。！？!?؟۔\n", "；;؛", "，,、：:،", " \t"}
+
+// SplitByGraphemeLimit 将文本切分为每段不超过 maxLen 个单位（见 UnitCount）的片段。
+// 优先在句末标点处切分，其次是分号、逗号和空白，都没有时在字素边界硬切，
+// 保证不会拆开组合字符、emoji 序列或标签。
+func SplitByGraphemeLimit(text string, maxLen int) []string {
+	if maxLen <= 0 || UnitCount(text) <= maxLen {
+		return []string{text}
+	}
+
+	var result []string
+	for text != "" {
+		cut := CutByGraphemeLimit(text, maxLen)
+		if piece := strings.TrimSpace(text[:cut]); piece != "" {
+			result = append(result, piece)
+		}
+		text = text[cut:]
+	}
+	return result
+}
+
+// CutByGraphemeLimit 返回从文本开头切出不超过 maxLen 个单位的一段时的切分位置（字节偏移），
+// 断点的选择与 SplitByGraphemeLimit 相同；文本不超过 maxLen 或 maxLen <= 0 时返回 len(text)
+func CutByGraphemeLimit(text string, maxLen int) int {
+	if maxLen <= 0 {
+		return len(text)
+	}
+	// 找出前 maxLen 个字素的结束位置，并记录每类断点最后出现的位置
+	end := 0
+	breaks := make([]int, len(splitPreference))
+	for n := 0; n < maxLen && end < len(text); n++ {
+		size := nextUnit(text[end:])
+		cluster := text[end : end+size]
+		end += size
+		for level, chars := range splitPreference {
+			if strings.Contains(chars, cluster) {
+				breaks[level] = end
+			}
+		}
+	}
+
+	if end < len(text) {
+		for _, pos := range breaks {
+			if pos > 0 {
+				return pos
+			}
+		}
+	}
+	return end
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitByDelimiters(t *testing.T) {
+	zh := []string{"。", "！", "？", "；", "、", "……"}
+	en := []string{".", "?", "!", ";"}
+	tests := []struct {
+		name       string
+		text       string
+		delimiters []string
+		protected  []string
+		want       []string
+	}{
+		{"中文句号与感叹号", "你好。今天天气很好！我们走吧", zh, nil, []string{"你好。", "今天天气很好！", "我们走吧"}},
+		{"中文分号与顿号", "苹果、香蕉；橙子", zh, nil, []string{"苹果、", "香蕉；", "橙子"}},
+		{"多字符符号优先于单字符", "等一下……好的。", zh, nil, []string{"等一下……", "好的。"}},
+		{"ASCII 标点", "Hi. How are you? Fine! Thanks; bye", en, nil, []string{"Hi.", "How are you?", "Fine!", "Thanks;", "bye"}},
+		{"保护词不切分", "Mr. Smith met Dr. Lee. Then left.", en, []string{"Mr.", "Dr."}, []string{"Mr. Smith met Dr. Lee.", "Then left."}},
+		{"保护词不含符号", "二十世纪。新的开始", zh, []string{"世纪"}, []string{"二十世纪。新的开始"}},
+		{"缩写中的多个句点", "Use e.g. this one. Done", en, []string{"e.g."}, []string{"Use e.g. this one.", "Done"}},
+		{"没有分句符号", "no delimiters here", nil, nil, []string{"no delimiters here"}},
+		{"连续的符号不产生空片段", "真的！！？", zh, nil, []string{"真的！", "！", "？"}},
+		{"空文本", "", zh, nil, nil},
+		{"只有空白", "   \t ", zh, nil, nil},
+		{"只有符号与空白", " 。 。", zh, nil, []string{"。", "。"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitByDelimiters(tt.text, tt.delimiters, tt.protected)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitByDelimiters(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitByGraphemeLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		maxLen int
+		want   []string
+	}{
+		{"未超过上限", "你好世界", 10, []string{"你好世界"}},
+		{"上限为 0 不切分", "你好世界", 0, []string{"你好世界"}},
+		{"优先在句末切分", "一二三。四五，六七八", 8, []string{"一二三。", "四五，六七八"}},
+		{"其次在逗号切分", "一二，三四五六七八", 6, []string{"一二，", "三四五六七八"}},
+		{"没有标点时在空白切分", "one two three", 8, []string{"one two", "three"}},
+		{"没有断点时硬切", "一二三四五六七", 3, []string{"一二三", "四五六", "七"}},
+		{"不拆开组合字符", "ééé", 2, []string{"éé", "é"}},
+		{"标签计为一个单位", "<break time=\"1s\"/>一二三", 2, []string{"<break time=\"1s\"/>一", "二三"}},
+		{"只有空白", "      ", 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitByGraphemeLimit(tt.text, tt.maxLen)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitByGraphemeLimit(%q, %d) = %q, want %q", tt.text, tt.maxLen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCutByGraphemeLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		maxLen int
+		want   int
+	}{
+		{"未超过上限", "你好世界", 10, len("你好世界")},
+		{"上限为 0 不切分", "你好世界", 0, len("你好世界")},
+		{"在句末切分", "一二三。四五，六七八", 8, len("一二三。")},
+		{"在空白之后切分", "one two three", 8, len("one two ")},
+		{"硬切", "一二三四五", 3, len("一二三")},
+		{"只有空白", "      ", 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CutByGraphemeLimit(tt.text, tt.maxLen); got != tt.want {
+				t.Errorf("CutByGraphemeLimit(%q, %d) = %d, want %d", tt.text, tt.maxLen, got, tt.want)
+			}
+		})
+	}
+}
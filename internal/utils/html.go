@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// skippedElements 是转换为纯文本时整体丢弃的元素，其内容不适合朗读
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"iframe": true, "svg": true, "canvas": true, "nav": true,
+	"header": true, "footer": true, "aside": true, "form": true,
+	"button": true, "figcaption": true,
+}
+
+// blockElements 是前后需要换行的块级元素，保证段落在分段时仍然独立
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"blockquote": true, "pre": true, "section": true, "article": true,
+	"ul": true, "ol": true, "table": true, "hr": true, "dd": true, "dt": true,
+}
+
+// HTMLToText 将 HTML 转换为适合朗读的纯文本：丢弃脚本、导航等元素，
+// 解码实体，块级元素之间换行，并合并多余的空白
+func HTMLToText(input string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(input))
+	var sb strings.Builder
+	skipDepth := 0
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			return normalizeLines(sb.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if skippedElements[tag] {
+				// 自闭合的元素没有内容，也没有对应的结束标签
+				if tokenType == html.StartTagToken {
+					skipDepth++
+				}
+			} else if blockElements[tag] {
+				sb.WriteByte('\n')
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if skippedElements[tag] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			} else if blockElements[tag] {
+				sb.WriteByte('\n')
+			}
+		case html.TextToken:
+			if skipDepth == 0 {
+				sb.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+// normalizeLines 合并每行内的连续空白并去掉空行
+func normalizeLines(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 注音（<ruby>/<rt>）的朗读方式
+const (
+	RubyBase    = "base"    // 只读正文，如 漢字
+	RubyReading = "reading" // 只读注音，如 かんじ
+	RubyBoth    = "both"    // 正文后跟括号中的注音，如 漢字（かんじ）
+)
+
+var (
+	// rubyPattern 匹配一个完整的 <ruby> 元素
+	rubyPattern = regexp.MustCompile(`(?is)<ruby\b[^>]*>(.*?)</ruby\s*>`)
+	// rubyTagPattern 匹配 <ruby> 内部的 rb、rt、rtc、rp 标签
+	rubyTagPattern = regexp.MustCompile(`(?is)<(/?)(rb|rtc|rt|rp)\b[^>]*>`)
+)
+
+// ValidRubyMode 判断注音的朗读方式是否有效，空字符串表示不处理
+func ValidRubyMode(mode string) bool {
+	switch mode {
+	case "", RubyBase, RubyReading, RubyBoth:
+		return true
+	}
+	return false
+}
+
+// ConvertRuby 按 mode 把文本中的 <ruby> 注音转换为纯文本，<rp> 中的备用括号总是丢弃。
+// mode 为空时原样返回。
+func ConvertRuby(text, mode string) string {
+	if mode == "" || !strings.Contains(strings.ToLower(text), "<ruby") {
+		return text
+	}
+	return rubyPattern.ReplaceAllStringFunc(text, func(m string) string {
+		return convertRubyContent(rubyPattern.FindStringSubmatch(m)[1], mode)
+	})
+}
+
+// convertRubyContent 把一个 <ruby> 元素的内容按正文与注音配对后输出，
+// 支持 <ruby>漢<rt>かん</rt>字<rt>じ</rt></ruby> 这样逐字注音的写法
+func convertRubyContent(content, mode string) string {
+	var out, base, reading strings.Builder
+	flush := func() {
+		switch {
+		case mode == RubyReading && reading.Len() > 0:
+			out.WriteString(reading.String())
+		case mode == RubyBoth && reading.Len() > 0:
+			out.WriteString(base.String() + "（" + reading.String() + "）")
+		default:
+			out.WriteString(base.String())
+		}
+		base.Reset()
+		reading.Reset()
+	}
+
+	element := "" // 当前所在的 rt、rp，正文中为空
+	last := 0
+	write := func(s string) {
+		switch element {
+		case "rp":
+		case "rt", "rtc":
+			reading.WriteString(s)
+		default:
+			// 注音之后出现新的正文，开始下一组
+			if reading.Len() > 0 && strings.TrimSpace(s) != "" {
+				flush()
+			}
+			base.WriteString(s)
+		}
+	}
+	for _, loc := range rubyTagPattern.FindAllStringSubmatchIndex(content, -1) {
+		write(content[last:loc[0]])
+		last = loc[1]
+		closing := loc[3] > loc[2]
+		name := strings.ToLower(content[loc[4]:loc[5]])
+		switch {
+		case name == "rb":
+		case closing:
+			element = ""
+		default:
+			element = name
+		}
+	}
+	write(content[last:])
+	flush()
+	return out.String()
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// jwtPattern 匹配 JWT 形式的认证令牌
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	// bearerPattern 匹配 Authorization 头中的令牌
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"']+`)
+	// keyParamPattern 匹配 URL 或日志中的密钥参数
+	keyParamPattern = regexp.MustCompile(`(?i)((?:api[_-]?key|subscription-key|token)=)[^&\s"']+`)
+	// pathPattern 匹配独立出现的绝对文件路径（Unix 与 Windows），不匹配 URL 中的路径
+	pathPattern = regexp.MustCompile(`(^|[\s"'(\[])((?:[A-Za-z]:\\|/)(?:[\w.-]+[/\\])+[\w.-]*)`)
+)
+
+// SanitizeMessage 清理面向客户端的错误信息，移除密钥、令牌与内部文件路径
+func SanitizeMessage(msg string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret, redacted)
+		}
+	}
+	msg = jwtPattern.ReplaceAllString(msg, redacted)
+	msg = bearerPattern.ReplaceAllString(msg, "${1}"+redacted)
+	msg = keyParamPattern.ReplaceAllString(msg, "${1}"+redacted)
+	msg = pathPattern.ReplaceAllString(msg, "${1}[PATH]")
+	return msg
+}
@@ -13,7 +13,6 @@ import (
 	"net/url"
 	"strings"
 	"time"
-	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -45,6 +44,11 @@ func generateUserID() string {
 
 // GetEndpoint 获取语音合成服务的端点信息
 func GetEndpoint() (map[string]interface{}, error) {
+	return GetEndpointWithClient(client)
+}
+
+// GetEndpointWithClient 使用指定的HTTP客户端获取端点信息
+func GetEndpointWithClient(client *http.Client) (map[string]interface{}, error) {
 	signature := Sign(endpointURL)
 	userId := generateUserID()
 	traceId := uuid.New().String()
@@ -127,8 +131,8 @@ func SplitAndFilterEmptyLines(text string) []string {
 }
 
 // MergeStringsWithLimit 会将字符串切片依次累加，直到总长度 ≥ minLen。
-// 但如果再合并下一段后会超过 maxLen，则提前结束本段合并，放入结果。
-// 然后继续新的一段合并。
+// 但如果再合并下一段后会超过 1.2 × minLen 或 maxLen，则提前结束本段合并，放入结果。
+// 然后继续新的一段合并。长度均按字素计数。
 func MergeStringsWithLimit(strs []string, minLen int, maxLen int) []string {
 	var result []string
 
@@ -144,15 +148,15 @@ func MergeStringsWithLimit(strs []string, minLen int, maxLen int) []string {
 		i++
 
 		for i < len(strs) {
-			currentLen := utf8.RuneCountInString(currentBuilder.String())
+			currentLen := GraphemeCount(currentBuilder.String())
 			// 如果当前已达(或超过) minLen，先行结束本段合并
 			if currentLen >= minLen {
 				break
 			}
 
 			// 检查添加下一个段落后是否会超过 1.2 × minLen
-			nextLen := utf8.RuneCountInString(strs[i])
-			if currentLen+nextLen > int(float64(minLen)*1.2) {
+			nextLen := GraphemeCount(strs[i])
+			if currentLen+nextLen > int(float64(minLen)*1.2) || (maxLen > 0 && currentLen+nextLen+1 > maxLen) {
 				// 加上下一个会超标，则结束合并
 				break
 			}
@@ -221,3 +225,32 @@ func GetExp(s string) int64 {
 
 	return int64(exp)
 }
+
+// FormatFileSize 格式化文件大小
+func FormatFileSize(size int) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%d B", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.2f KB", float64(size)/1024.0)
+	case size < 1024*1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(size)/(1024.0*1024.0))
+	default:
+		return fmt.Sprintf("%.2f GB", float64(size)/(1024.0*1024.0*1024.0))
+	}
+}
+
+// TruncateForLog 截断文本用于日志显示，同时显示开头和结尾
+func TruncateForLog(text string, maxLength int) string {
+	// 先去除换行符
+	text = strings.ReplaceAll(text, "\n", " ")
+	text = strings.ReplaceAll(text, "\r", " ")
+
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	// 计算开头和结尾各显示多少字符
+	halfLength := maxLength / 2
+	return string(runes[:halfLength]) + "..." + string(runes[len(runes)-halfLength:])
+}
@@ -0,0 +1,203 @@
+// Package vcr 提供录制/回放 HTTP 交互的传输层，
+// 可将真实的上游响应保存为夹具文件，并在测试或演示时离线回放。
+package vcr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"tts/internal/privacy"
+)
+
+// Mode 是录制/回放模式
+type Mode string
+
+const (
+	ModeOff    Mode = ""       // 不录制也不回放
+	ModeRecord Mode = "record" // 总是请求上游并覆盖夹具
+	ModeReplay Mode = "replay" // 只从夹具回放，缺失时报错
+	ModeAuto   Mode = "auto"   // 有夹具时回放，否则请求上游并录制
+)
+
+// placeholderToken 在夹具中替换上游返回的认证令牌。它是过期时间为 2100 年的 JWT，回放时可以照常解析；
+// 头部与负载的 JSON 带有空格，编码后不以 eyJ 开头，夹具中出现 eyJ 即说明有真实令牌漏网
+const placeholderToken = "eyAiYWxnIjogIm5vbmUiIH0.eyAiZXhwIjogNDEwMjQ0NDgwMCB9.vcr-placeholder"
+
+// tokenPattern 匹配响应体中 JWT 形式的认证令牌，如 issueToken 的响应体与端点信息中的 t 字段
+var tokenPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+
+// Fixture 是一次录制的 HTTP 交互
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest 是录制的请求摘要
+type FixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// FixtureResponse 是录制的响应
+type FixtureResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    []byte      `json:"body"` // JSON 编码为 base64
+}
+
+// Transport 是支持录制与回放的 http.RoundTripper
+type Transport struct {
+	mode Mode
+	dir  string
+	next http.RoundTripper
+	mu   sync.Mutex
+}
+
+// New 创建录制/回放传输层，mode 为 ModeOff 时直接返回 next
+func New(mode Mode, dir string, next http.RoundTripper) (http.RoundTripper, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	switch mode {
+	case ModeOff:
+		return next, nil
+	case ModeRecord, ModeReplay, ModeAuto:
+	default:
+		return nil, fmt.Errorf("未知的VCR模式: %s", mode)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	log.Printf("VCR已启用，模式: %s, 目录: %s", mode, dir)
+	return &Transport{mode: mode, dir: dir, next: next}, nil
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	path := filepath.Join(t.dir, Key(req.Method, req.URL.String(), body)+".json")
+
+	if t.mode == ModeReplay || t.mode == ModeAuto {
+		fixture, err := t.load(path)
+		if err == nil {
+			return fixture.Response.toHTTP(req), nil
+		}
+		if t.mode == ModeReplay {
+			return nil, fmt.Errorf("VCR回放未找到录制: %s %s", req.Method, req.URL)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fixture := Fixture{
+		Request: FixtureRequest{Method: req.Method, URL: req.URL.String(), Body: string(body)},
+		Response: FixtureResponse{
+			Status:  resp.StatusCode,
+			Headers: resp.Header.Clone(),
+			Body:    respBody,
+		},
+	}
+	// 认证头与认证令牌不应写入夹具
+	fixture.Response.Headers.Del("Set-Cookie")
+	if redacted := redactTokens(resp.Header, respBody); !bytes.Equal(redacted, respBody) {
+		fixture.Response.Body = redacted
+		fixture.Response.Headers.Del("Content-Length")
+	}
+	// 隐私模式下请求体包含输入文本，不写入夹具
+	if privacy.Enabled(req.Context()) {
+		fixture.Request.Body = ""
+	}
+	if err := t.save(path, fixture); err != nil {
+		log.Printf("VCR保存夹具失败: %v", err)
+	}
+	return resp, nil
+}
+
+// redactTokens 把响应中的认证令牌替换为占位令牌，音频响应原样返回
+func redactTokens(header http.Header, body []byte) []byte {
+	if strings.HasPrefix(header.Get("Content-Type"), "audio/") {
+		return body
+	}
+	return tokenPattern.ReplaceAll(body, []byte(placeholderToken))
+}
+
+// Key 根据请求方法、URL 与请求体生成夹具文件名
+func Key(method, url string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(url))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// load 读取夹具文件
+func (t *Transport) load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, err
+	}
+	return &fixture, nil
+}
+
+// save 写入夹具文件
+func (t *Transport) save(path string, fixture Fixture) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// toHTTP 将录制的响应转换为 http.Response
+func (r FixtureResponse) toHTTP(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Headers.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
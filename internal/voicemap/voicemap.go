@@ -0,0 +1,87 @@
+// Package voicemap 负责将客户端请求的语音名称解析为实际使用的语音，
+// 包括 OpenAI 语音映射与按比例灰度切换的新语音。
+package voicemap
+
+import (
+	"context"
+	"hash/fnv"
+
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/metrics"
+	"tts/internal/models"
+)
+
+var rolloutTotal = metrics.NewCounter("tts_voice_rollout_total",
+	"按灰度比例切换到新语音的请求数，只统计实际选中新语音的请求", "voice", "target")
+
+// Mapper 解析语音名称
+type Mapper struct {
+	mapping  map[string]string
+	rollouts map[string]config.VoiceRollout
+}
+
+// New 根据TTS配置创建语音解析器
+func New(cfg *config.TTSConfig) *Mapper {
+	mapping := make(map[string]string, len(cfg.VoiceMapping))
+	for name, alias := range cfg.VoiceMapping {
+		mapping[name] = alias.Voice
+	}
+	return &Mapper{
+		mapping:  mapping,
+		rollouts: cfg.VoiceRollout,
+	}
+}
+
+// Resolve 返回请求语音对应的实际语音。
+// stickyKey 用于灰度分流（通常为API密钥或客户端IP），相同的键总是落入同一分组。
+func (m *Mapper) Resolve(voice, stickyKey string) string {
+	if voice == "" {
+		return ""
+	}
+
+	if rollout, ok := m.rollouts[voice]; ok && rollout.Voice != "" && rollout.Percent > 0 {
+		if bucket(stickyKey, voice) < rollout.Percent {
+			rolloutTotal.Inc(voice, rollout.Voice)
+			return rollout.Voice
+		}
+	}
+
+	if mapped := m.mapping[voice]; mapped != "" {
+		return mapped
+	}
+	return voice
+}
+
+// bucket 将键稳定地映射到 [0, 100) 区间
+func bucket(stickyKey, voice string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(stickyKey))
+	h.Write([]byte{0})
+	h.Write([]byte(voice))
+	return float64(h.Sum32()%10000) / 100
+}
+
+// Lister 列出可用的语音
+type Lister interface {
+	ListVoices(ctx context.Context, locale string) ([]models.Voice, error)
+}
+
+// Choose 返回聊天工具中工作区或房间使用的语音：优先使用设置的语音（解析别名与灰度），
+// 其次使用设置的语言的第一个语音；都未设置时返回空字符串，由调用方使用默认语音
+func (m *Mapper) Choose(ctx context.Context, voices Lister, chat config.ChatVoice, stickyKey string) (string, error) {
+	if chat.Voice != "" {
+		return m.Resolve(chat.Voice, stickyKey), nil
+	}
+	if chat.Language == "" {
+		return "", nil
+	}
+	list, err := voices.ListVoices(ctx, chat.Language)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return "", apperr.Newf(apperr.CodeInvalidVoice, "没有 %s 的语音", chat.Language)
+	}
+	return list[0].ShortName, nil
+}
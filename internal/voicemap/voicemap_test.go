@@ -0,0 +1,39 @@
+package voicemap
+
+import (
+	"testing"
+
+	"tts/internal/config"
+)
+
+func TestResolve(t *testing.T) {
+	m := New(&config.TTSConfig{
+		VoiceMapping: map[string]config.VoiceAlias{
+			"alloy": {Voice: "zh-CN-XiaoyiNeural"},
+			"echo":  {Voice: "zh-CN-YunxiNeural"},
+		},
+		VoiceRollout: map[string]config.VoiceRollout{
+			"alloy": {Voice: "zh-CN-XiaoxiaoMultilingualNeural", Percent: 100},
+			"echo":  {Voice: "zh-CN-YunyangNeural", Percent: 0},
+		},
+	})
+	tests := []struct {
+		voice, want string
+		rollout     float64 // 期望 tts_voice_rollout_total 增加的次数
+	}{
+		{"alloy", "zh-CN-XiaoxiaoMultilingualNeural", 1},
+		{"echo", "zh-CN-YunxiNeural", 0},
+		{"zh-CN-XiaoxiaoNeural", "zh-CN-XiaoxiaoNeural", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		target := tt.want
+		before := rolloutTotal.Value(tt.voice, target)
+		if got := m.Resolve(tt.voice, "key"); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.voice, got, tt.want)
+		}
+		if got := rolloutTotal.Value(tt.voice, target) - before; got != tt.rollout {
+			t.Errorf("Resolve(%q) 计入灰度 %v 次, want %v", tt.voice, got, tt.rollout)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package voiceprefs 按请求方（API 密钥）记录最近使用的语音与收藏的语音，保存在持久化存储中，
+// 供客户端界面展示个性化的语音列表。请求方标识与使用条款的确认记录相同。
+package voiceprefs
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"tts/internal/store"
+)
+
+const (
+	// recentBucket 与 favoritesBucket 是保存最近使用与收藏语音的存储桶
+	recentBucket    = "voice_recent"
+	favoritesBucket = "voice_favorites"
+
+	// maxRecent 是每个请求方保留的最近使用语音数
+	maxRecent = 20
+	// touchInterval 内重复使用最近一次的语音不再写入存储，避免每次合成都重写存储文件
+	touchInterval = time.Minute
+)
+
+// ContextKey 是 gin 上下文中保存本次合成所用语音的键，合成成功后由处理器设置
+const ContextKey = "voice_used"
+
+// mu 保护读取后写回的更新，避免同一请求方的并发请求互相覆盖
+var mu sync.Mutex
+
+// Use 是一条语音使用记录
+type Use struct {
+	Voice    string    `json:"voice"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Favorite 是一条收藏记录
+type Favorite struct {
+	Voice   string    `json:"voice"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// RecordUse 把语音移到请求方最近使用列表的最前面
+func RecordUse(st *store.Store, identity, voice string) error {
+	if voice == "" {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	recent := Recent(st, identity, maxRecent)
+	now := time.Now().UTC()
+	if len(recent) > 0 && strings.EqualFold(recent[0].Voice, voice) && now.Sub(recent[0].LastUsed) < touchInterval {
+		return nil
+	}
+
+	updated := make([]Use, 0, len(recent)+1)
+	updated = append(updated, Use{Voice: voice, LastUsed: now})
+	for _, previous := range recent {
+		if !strings.EqualFold(previous.Voice, voice) {
+			updated = append(updated, previous)
+		}
+	}
+	if len(updated) > maxRecent {
+		updated = updated[:maxRecent]
+	}
+	return st.Put(recentBucket, identity, updated)
+}
+
+// Recent 返回请求方最近使用的语音，最近的在前，最多 limit 个
+func Recent(st *store.Store, identity string, limit int) []Use {
+	var recent []Use
+	if found, err := st.Get(recentBucket, identity, &recent); err != nil || !found {
+		return []Use{}
+	}
+	if limit > 0 && len(recent) > limit {
+		recent = recent[:limit]
+	}
+	return recent
+}
+
+// Favorites 返回请求方收藏的语音，按收藏时间排列
+func Favorites(st *store.Store, identity string) []Favorite {
+	var favorites []Favorite
+	if found, err := st.Get(favoritesBucket, identity, &favorites); err != nil || !found {
+		return []Favorite{}
+	}
+	return favorites
+}
+
+// AddFavorite 收藏语音，已收藏时保持原有的收藏时间
+func AddFavorite(st *store.Store, identity, voice string) (Favorite, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	favorites := Favorites(st, identity)
+	for _, favorite := range favorites {
+		if strings.EqualFold(favorite.Voice, voice) {
+			return favorite, nil
+		}
+	}
+	favorite := Favorite{Voice: voice, AddedAt: time.Now().UTC()}
+	return favorite, st.Put(favoritesBucket, identity, append(favorites, favorite))
+}
+
+// RemoveFavorite 取消收藏，语音未收藏时返回 false
+func RemoveFavorite(st *store.Store, identity, voice string) (bool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	favorites := Favorites(st, identity)
+	for i, favorite := range favorites {
+		if !strings.EqualFold(favorite.Voice, voice) {
+			continue
+		}
+		favorites = append(favorites[:i:i], favorites[i+1:]...)
+		if len(favorites) == 0 {
+			return true, st.Delete(favoritesBucket, identity)
+		}
+		return true, st.Put(favoritesBucket, identity, favorites)
+	}
+	return false, nil
+}
@@ -0,0 +1,182 @@
+// Package warm 在后台提前合成预计会被请求的内容（如第二天的定时播报），写入合成结果缓存。
+// 预热任务由一个后台协程逐条顺序执行，对正常请求的影响与一个客户端相当。
+package warm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"tts/internal/apperr"
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/notify"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// queueSize 是最多排队的预热任务数
+	queueSize = 16
+	// maxErrors 是每个任务最多保留的错误信息条数
+	maxErrors = 10
+	// keepFinished 是已完成任务的状态保留时长
+	keepFinished = 24 * time.Hour
+)
+
+// 任务状态
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+)
+
+// Task 是一个预热任务的状态
+type Task struct {
+	ID       string     `json:"id"`
+	Status   string     `json:"status"`
+	Total    int        `json:"total"`
+	Done     int        `json:"done"`
+	Failed   int        `json:"failed"`
+	Errors   []string   `json:"errors,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// task 是排队中的任务
+type task struct {
+	Task
+	tenant string
+	items  []models.TTSRequest
+}
+
+// Queue 是预热任务队列
+type Queue struct {
+	synthesizer *ttspkg.Synthesizer
+	notifier    *notify.Notifier
+	pending     chan *task
+
+	mu    sync.Mutex
+	tasks map[string]*task
+}
+
+// New 创建预热队列，需要调用 Run 开始执行。任务完成时按提交方密钥的设置发送通知，notifier 可以为 nil
+func New(synthesizer *ttspkg.Synthesizer, notifier *notify.Notifier) *Queue {
+	return &Queue{
+		synthesizer: synthesizer,
+		notifier:    notifier,
+		pending:     make(chan *task, queueSize),
+		tasks:       make(map[string]*task),
+	}
+}
+
+// Submit 提交一组待合成的内容，tenant 为提交方的密钥名称，合成时沿用其 SSML 设置以命中相同的缓存键
+func (q *Queue) Submit(tenant string, items []models.TTSRequest) (Task, error) {
+	t := &task{
+		Task:   Task{ID: uuid.NewString(), Status: StatusQueued, Total: len(items), Created: time.Now().UTC()},
+		tenant: tenant,
+		items:  items,
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case q.pending <- t:
+	default:
+		return Task{}, apperr.New(apperr.CodeRateLimited, "预热队列已满，请稍后再试")
+	}
+	q.prune()
+	q.tasks[t.ID] = t
+	return t.snapshot(), nil
+}
+
+// Get 返回任务的当前状态
+func (q *Queue) Get(id string) (Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.tasks[id]
+	if !ok {
+		return Task{}, false
+	}
+	return t.snapshot(), true
+}
+
+// Run 逐条执行预热任务，直到 ctx 结束
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-q.pending:
+			q.run(ctx, t)
+		}
+	}
+}
+
+// run 执行一个任务，单条内容失败不影响其余内容
+func (q *Queue) run(ctx context.Context, t *task) {
+	q.update(t, func() { t.Status = StatusRunning })
+	// 与提交方使用相同的租户，SSML 设置与缓存键才与其之后的请求一致
+	ctx = config.WithTenant(ctx, t.tenant)
+	for i, item := range t.items {
+		if ctx.Err() != nil {
+			return
+		}
+		_, err := q.synthesizer.Synthesize(ctx, item)
+		q.update(t, func() {
+			if err == nil {
+				t.Done++
+				return
+			}
+			t.Failed++
+			if len(t.Errors) < maxErrors {
+				t.Errors = append(t.Errors, fmt.Sprintf("第 %d 条: %s", i+1, apperr.From(err).Message))
+			}
+		})
+	}
+	q.update(t, func() {
+		now := time.Now().UTC()
+		t.Status, t.Finished = StatusDone, &now
+	})
+	log.Printf("缓存预热任务 %s 完成: 成功 %d 条, 失败 %d 条", t.ID, t.Done, t.Failed)
+
+	// 有内容合成失败时按失败通知，便于只订阅失败的渠道发现问题
+	event := notify.Event{
+		Kind:     "warm",
+		Name:     t.ID,
+		Status:   notify.StatusSuccess,
+		Summary:  fmt.Sprintf("成功 %d 条, 失败 %d 条", t.Done, t.Failed),
+		Started:  t.Created,
+		Finished: *t.Finished,
+	}
+	if t.Failed > 0 {
+		event.Status = notify.StatusFailed
+		event.Error = strings.Join(t.Errors, "; ")
+	}
+	q.notifier.Notify(q.notifier.Tenant(t.tenant), event)
+}
+
+// update 在锁内修改任务状态
+func (q *Queue) update(t *task, change func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	change()
+}
+
+// prune 删除完成超过 keepFinished 的任务，调用方需持有锁
+func (q *Queue) prune() {
+	for id, t := range q.tasks {
+		if t.Finished != nil && time.Since(*t.Finished) > keepFinished {
+			delete(q.tasks, id)
+		}
+	}
+}
+
+// snapshot 返回任务状态的副本，调用方需持有锁
+func (t *task) snapshot() Task {
+	snapshot := t.Task
+	snapshot.Errors = append([]string(nil), t.Errors...)
+	return snapshot
+}
@@ -0,0 +1,184 @@
+// Package watermark 为生成的音频添加水印，以便事后识别合成内容：
+// id3 模式写入不影响播放的 ID3 标签，记录水印ID、生成来源与生成时间；
+// tone 模式在音频末尾追加一段可听的提示音（需要 ffmpeg）。
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"tts/internal/config"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// ModeID3 写入 ID3 标签
+	ModeID3 = "id3"
+	// ModeTone 追加提示音
+	ModeTone = "tone"
+	// ModeBoth 同时使用两种方式
+	ModeBoth = "both"
+)
+
+// ID3 标签中使用的自定义文本字段
+const (
+	fieldSynthetic = "SYNTHETIC_SPEECH"
+	fieldID        = "WATERMARK_ID"
+	fieldSource    = "GENERATED_BY"
+	fieldTime      = "GENERATED_AT"
+)
+
+// Info 是写入水印的信息
+type Info struct {
+	ID     string    `json:"id"`
+	Source string    `json:"source,omitempty"` // 生成来源：请求所用密钥的名称，或 podcast 等后台功能
+	Time   time.Time `json:"time"`
+}
+
+// Enabled 判断是否需要添加水印，密钥的单独设置优先于全局设置
+func Enabled(cfg *config.Config, profile *config.APIKey) bool {
+	if profile != nil && profile.Watermark != nil {
+		return *profile.Watermark
+	}
+	return cfg.Watermark.Enabled
+}
+
+// Apply 按配置的模式为 MP3 音频添加水印，返回新的音频与水印信息
+func Apply(audio []byte, cfg config.WatermarkConfig, source string) ([]byte, Info, error) {
+	info := Info{ID: uuid.New().String(), Source: source, Time: time.Now().UTC().Truncate(time.Second)}
+
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeID3
+	}
+	switch mode {
+	case ModeID3, ModeTone, ModeBoth:
+	default:
+		return nil, info, fmt.Errorf("未知的水印模式: %s", mode)
+	}
+
+	if mode == ModeTone || mode == ModeBoth {
+		frequency := cfg.ToneFrequency
+		if frequency <= 0 {
+			frequency = 1000
+		}
+		duration := cfg.ToneDuration
+		if duration <= 0 {
+			duration = 0.3
+		}
+		toned, err := ttspkg.AppendTone(audio, frequency, duration)
+		if err != nil {
+			return nil, info, err
+		}
+		audio = toned
+	}
+	if mode == ModeID3 || mode == ModeBoth {
+		audio = append(id3Tag(info), audio...)
+	}
+	return audio, info, nil
+}
+
+// Stamp 在全局启用水印时为后台功能生成的音频添加水印，source 为功能名称
+func Stamp(cfg *config.Config, audio []byte, source string) ([]byte, error) {
+	if !cfg.Watermark.Enabled {
+		return audio, nil
+	}
+	stamped, _, err := Apply(audio, cfg.Watermark, source)
+	if err != nil {
+		return nil, fmt.Errorf("添加水印失败: %w", err)
+	}
+	return stamped, nil
+}
+
+// Read 从音频开头的 ID3 标签中读取水印信息
+func Read(audio []byte) (Info, bool) {
+	fields := readID3(audio)
+	if fields[fieldSynthetic] != "true" || fields[fieldID] == "" {
+		return Info{}, false
+	}
+	info := Info{ID: fields[fieldID], Source: fields[fieldSource]}
+	info.Time, _ = time.Parse(time.RFC3339, fields[fieldTime])
+	return info, true
+}
+
+// id3Tag 生成包含水印字段的 ID3v2.4 标签
+func id3Tag(info Info) []byte {
+	var frames bytes.Buffer
+	writeTXXX(&frames, fieldSynthetic, "true")
+	writeTXXX(&frames, fieldID, info.ID)
+	if info.Source != "" {
+		writeTXXX(&frames, fieldSource, info.Source)
+	}
+	writeTXXX(&frames, fieldTime, info.Time.Format(time.RFC3339))
+
+	tag := make([]byte, 10, 10+frames.Len())
+	copy(tag, "ID3\x04\x00\x00")
+	putSyncSafe(tag[6:], frames.Len())
+	return append(tag, frames.Bytes()...)
+}
+
+// writeTXXX 写入一个 UTF-8 编码的自定义文本帧
+func writeTXXX(buf *bytes.Buffer, description, value string) {
+	body := make([]byte, 0, 2+len(description)+len(value))
+	body = append(body, 0x03) // UTF-8
+	body = append(body, description...)
+	body = append(body, 0x00)
+	body = append(body, value...)
+
+	header := make([]byte, 10)
+	copy(header, "TXXX")
+	putSyncSafe(header[4:], len(body))
+	buf.Write(header)
+	buf.Write(body)
+}
+
+// readID3 读取 ID3v2 标签中的 TXXX 帧
+func readID3(audio []byte) map[string]string {
+	fields := map[string]string{}
+	if len(audio) < 10 || string(audio[:3]) != "ID3" {
+		return fields
+	}
+	version := audio[3]
+	end := 10 + syncSafe(audio[6:10])
+	if end > len(audio) {
+		return fields
+	}
+	for pos := 10; pos+10 <= end; {
+		id := string(audio[pos : pos+4])
+		if id == "\x00\x00\x00\x00" {
+			break
+		}
+		// ID3v2.3 的帧大小是普通整数，v2.4 是同步安全整数
+		size := int(binary.BigEndian.Uint32(audio[pos+4 : pos+8]))
+		if version >= 4 {
+			size = syncSafe(audio[pos+4 : pos+8])
+		}
+		body := pos + 10
+		if size <= 0 || body+size > end {
+			break
+		}
+		if id == "TXXX" && audio[body] == 0x03 {
+			if desc, value, ok := bytes.Cut(audio[body+1:body+size], []byte{0}); ok {
+				fields[string(desc)] = string(value)
+			}
+		}
+		pos = body + size
+	}
+	return fields
+}
+
+// putSyncSafe 以同步安全整数（每字节 7 位）写入 n
+func putSyncSafe(b []byte, n int) {
+	b[0] = byte(n >> 21 & 0x7F)
+	b[1] = byte(n >> 14 & 0x7F)
+	b[2] = byte(n >> 7 & 0x7F)
+	b[3] = byte(n & 0x7F)
+}
+
+// syncSafe 读取同步安全整数
+func syncSafe(b []byte) int {
+	return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
@@ -0,0 +1,108 @@
+// Package wyoming 实现 Wyoming 协议的 TTS 服务端，
+// 使 Home Assistant 等智能家居系统可以通过局域网直接使用本服务合成语音。
+package wyoming
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// protocolVersion 是事件头中声明的协议版本
+const protocolVersion = "1.5.2"
+
+// maxHeaderSize 限制单个事件头的大小，防止恶意客户端占用内存
+const maxHeaderSize = 1 << 20
+
+// Event 是一个 Wyoming 事件：一行 JSON 事件头，随后是可选的 JSON 数据与二进制负载
+type Event struct {
+	Type    string
+	Data    map[string]any
+	Payload []byte
+}
+
+// header 是事件头
+type header struct {
+	Type          string         `json:"type"`
+	Version       string         `json:"version,omitempty"`
+	Data          map[string]any `json:"data,omitempty"`
+	DataLength    int            `json:"data_length,omitempty"`
+	PayloadLength int            `json:"payload_length,omitempty"`
+}
+
+// ReadEvent 从连接中读取一个事件
+func ReadEvent(r *bufio.Reader) (*Event, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	var h header
+	if err := json.Unmarshal(line, &h); err != nil {
+		return nil, fmt.Errorf("解析事件头失败: %w", err)
+	}
+	if h.DataLength < 0 || h.DataLength > maxHeaderSize || h.PayloadLength < 0 || h.PayloadLength > 64*maxHeaderSize {
+		return nil, fmt.Errorf("事件长度无效")
+	}
+
+	event := &Event{Type: h.Type, Data: h.Data}
+	if event.Data == nil {
+		event.Data = map[string]any{}
+	}
+	if h.DataLength > 0 {
+		data := make([]byte, h.DataLength)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &event.Data); err != nil {
+			return nil, fmt.Errorf("解析事件数据失败: %w", err)
+		}
+	}
+	if h.PayloadLength > 0 {
+		event.Payload = make([]byte, h.PayloadLength)
+		if _, err := io.ReadFull(r, event.Payload); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
+
+// WriteEvent 向连接写入一个事件
+func WriteEvent(w *bufio.Writer, event *Event) error {
+	h := header{Type: event.Type, Version: protocolVersion, PayloadLength: len(event.Payload)}
+	var data []byte
+	if len(event.Data) > 0 {
+		var err error
+		if data, err = json.Marshal(event.Data); err != nil {
+			return err
+		}
+		h.DataLength = len(data)
+	}
+	line, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	w.Write(line)
+	w.WriteByte('\n')
+	w.Write(data)
+	w.Write(event.Payload)
+	return w.Flush()
+}
+
+// readLine 读取一行，超过 maxHeaderSize 时返回错误
+func readLine(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxHeaderSize {
+			return nil, fmt.Errorf("事件头过长")
+		}
+		if !isPrefix {
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package wyoming
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"tts/internal/config"
+	"tts/internal/models"
+	"tts/internal/utils"
+	ttspkg "tts/pkg/tts"
+)
+
+const (
+	// defaultSampleRate 是未配置时输出 PCM 的采样率
+	defaultSampleRate = 24000
+	// sampleWidth 是每个采样的字节数（16 位）
+	sampleWidth = 2
+	// chunkSize 是每个 audio-chunk 事件携带的字节数
+	chunkSize = 4096
+	// idleTimeout 是连接空闲的最长时间
+	idleTimeout = 5 * time.Minute
+)
+
+// Server 是 Wyoming TTS 服务端
+type Server struct {
+	synthesizer *ttspkg.Synthesizer
+	config      *config.Config
+	sampleRate  int
+}
+
+// NewServer 创建 Wyoming 服务端
+func NewServer(synthesizer *ttspkg.Synthesizer, cfg *config.Config) *Server {
+	sampleRate := cfg.Wyoming.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = defaultSampleRate
+	}
+	return &Server{
+		synthesizer: synthesizer,
+		config:      cfg,
+		sampleRate:  sampleRate,
+	}
+}
+
+// ListenAndServe 监听地址并处理连接，直到 ctx 结束
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	log.Printf("Wyoming 服务已启动，监听 %s", address)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(ctx, conn)
+	}
+}
+
+// handleConn 处理一个客户端连接上的所有事件
+func (s *Server) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		conn.SetDeadline(time.Now().Add(idleTimeout))
+		event, err := ReadEvent(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("Wyoming 读取事件失败: %v", err)
+			}
+			return
+		}
+
+		switch event.Type {
+		case "describe":
+			err = s.describe(ctx, w)
+		case "synthesize":
+			err = s.synthesize(ctx, w, event)
+		case "ping":
+			err = WriteEvent(w, &Event{Type: "pong", Data: event.Data})
+		default:
+			log.Printf("Wyoming 忽略未知事件: %s", event.Type)
+		}
+		if err != nil {
+			log.Printf("Wyoming 处理事件 %s 失败: %v", event.Type, err)
+			return
+		}
+	}
+}
+
+// describe 返回服务信息与可用语音
+func (s *Server) describe(ctx context.Context, w *bufio.Writer) error {
+	voices, err := s.synthesizer.ListVoices(ctx, "")
+	if err != nil {
+		log.Printf("Wyoming 获取语音列表失败: %v", err)
+	}
+	attribution := map[string]any{"name": "Microsoft", "url": "https://azure.microsoft.com/products/ai-services/text-to-speech"}
+	voiceList := make([]map[string]any, 0, len(voices))
+	for _, voice := range voices {
+		voiceList = append(voiceList, map[string]any{
+			"name":        voice.ShortName,
+			"description": voice.LocalName,
+			"attribution": attribution,
+			"installed":   true,
+			"version":     nil,
+			"languages":   []string{voice.Locale},
+		})
+	}
+	return WriteEvent(w, &Event{Type: "info", Data: map[string]any{
+		"tts": []map[string]any{{
+			"name":        "tts",
+			"description": "Azure text to speech",
+			"attribution": attribution,
+			"installed":   true,
+			"version":     nil,
+			"voices":      voiceList,
+		}},
+	}})
+}
+
+// synthesize 合成语音并以 audio-start / audio-chunk / audio-stop 事件返回 PCM
+func (s *Server) synthesize(ctx context.Context, w *bufio.Writer, event *Event) error {
+	text, _ := event.Data["text"].(string)
+	req := models.TTSRequest{
+		Text:  strings.TrimSpace(text),
+		Voice: s.config.TTS.DefaultVoice,
+		Rate:  s.config.TTS.DefaultRate,
+		Pitch: s.config.TTS.DefaultPitch,
+	}
+	if voice, ok := event.Data["voice"].(map[string]any); ok {
+		if name, _ := voice["name"].(string); name != "" {
+			req.Voice = name
+		}
+	}
+
+	format := map[string]any{"rate": s.sampleRate, "width": sampleWidth, "channels": 1}
+	var pcm []byte
+	if req.Text != "" {
+		resp, err := s.synthesizer.Synthesize(ctx, req)
+		if err != nil {
+			return s.writeError(w, err)
+		}
+		if pcm, err = ttspkg.DecodePCM(resp.AudioContent, s.sampleRate); err != nil {
+			return s.writeError(w, err)
+		}
+	}
+
+	if err := WriteEvent(w, &Event{Type: "audio-start", Data: format}); err != nil {
+		return err
+	}
+	for start := 0; start < len(pcm); start += chunkSize {
+		end := min(start+chunkSize, len(pcm))
+		if err := WriteEvent(w, &Event{Type: "audio-chunk", Data: format, Payload: pcm[start:end]}); err != nil {
+			return err
+		}
+	}
+	return WriteEvent(w, &Event{Type: "audio-stop"})
+}
+
+// writeError 记录合成失败并通知客户端
+func (s *Server) writeError(w *bufio.Writer, err error) error {
+	log.Printf("Wyoming 合成失败: %v", err)
+	message := utils.SanitizeMessage(err.Error(), s.config.TTS.ApiKey, s.config.OpenAI.ApiKey)
+	return WriteEvent(w, &Event{Type: "error", Data: map[string]any{"text": message, "code": "synthesize_failed"}})
+}
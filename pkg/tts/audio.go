@@ -0,0 +1,38 @@
+package tts
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"tts/internal/audio"
+)
+
+// DecodePCM 使用 ffmpeg 将 MP3 解码为 16 位小端单声道 PCM
+func DecodePCM(data []byte, sampleRate int) ([]byte, error) {
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-f", "mp3", "-i", "pipe:0",
+		"-f", "s16le", "-acodec", "pcm_s16le", "-ac", "1", "-ar", strconv.Itoa(sampleRate),
+		"pipe:1")
+	cmd.Stdin = bytes.NewReader(data)
+	return audio.RunFFmpeg(cmd, "解码音频失败")
+}
+
+// AppendTone 使用 ffmpeg 在 MP3 末尾追加一段正弦提示音，输出保持原音频的采样率、声道与比特率
+func AppendTone(data []byte, frequency int, duration float64) ([]byte, error) {
+	format, ok := audio.ProbeMP3(data)
+	if !ok {
+		return nil, fmt.Errorf("无法识别的 MP3 音频")
+	}
+	tone := fmt.Sprintf("sine=frequency=%d:duration=%g:sample_rate=%d", frequency, duration, format.SampleRate)
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-f", "mp3", "-i", "pipe:0",
+		"-f", "lavfi", "-i", tone,
+		"-filter_complex", "[1:a]volume=0.3[tone];[0:a][tone]concat=n=2:v=0:a=1",
+		"-ac", strconv.Itoa(format.Channels), "-ar", strconv.Itoa(format.SampleRate),
+		"-b:a", fmt.Sprintf("%dk", format.Bitrate),
+		"-f", "mp3", "pipe:1")
+	cmd.Stdin = bytes.NewReader(data)
+	return audio.RunFFmpeg(cmd, "追加提示音失败")
+}
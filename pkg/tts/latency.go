@@ -0,0 +1,67 @@
+package tts
+
+import (
+	"sync"
+	"time"
+)
+
+// 未配置时低延迟模式的参数
+const (
+	defaultFirstChunkTarget    = 800 * time.Millisecond
+	defaultFirstChunkMinLength = 8
+	defaultFirstChunkMaxLength = 60
+	// defaultFirstChunkLength 是观测次数不足、尚无法估算合成速度时第一段的长度
+	defaultFirstChunkLength = 20
+
+	// latencyDecay 是历史观测值的衰减系数，越小越快适应上游速度的变化
+	latencyDecay = 0.95
+	// latencyWarmup 是按观测值估算第一段长度之前需要的合成次数
+	latencyWarmup = 5
+)
+
+// latencyModel 根据最近的合成耗时估算上游的合成速度：耗时 ≈ 固定开销 + 每字耗时 × 长度，
+// 用指数衰减的加权最小二乘拟合，近期的观测权重更高
+type latencyModel struct {
+	mu                  sync.Mutex
+	w, sx, sy, sxx, sxy float64
+	samples             int
+}
+
+// observe 记录一次合成的文本长度与耗时，命中缓存的合成不应记录
+func (m *latencyModel) observe(length int, elapsed time.Duration) {
+	if length <= 0 {
+		return
+	}
+	x, y := float64(length), float64(elapsed.Milliseconds())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.w = m.w*latencyDecay + 1
+	m.sx = m.sx*latencyDecay + x
+	m.sy = m.sy*latencyDecay + y
+	m.sxx = m.sxx*latencyDecay + x*x
+	m.sxy = m.sxy*latencyDecay + x*y
+	m.samples++
+}
+
+// length 返回预计在 target 内合成完毕的文本长度，观测次数不足时返回 false。
+// 各次合成的长度相近、无法区分固定开销与每字耗时时按平均每字耗时估算
+func (m *latencyModel) length(target time.Duration) (int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.samples < latencyWarmup {
+		return 0, false
+	}
+	meanX, meanY := m.sx/m.w, m.sy/m.w
+	variance := m.sxx/m.w - meanX*meanX
+	covariance := m.sxy/m.w - meanX*meanY
+
+	overhead, perUnit := 0.0, meanY/meanX
+	if variance > 1 && covariance > 0 {
+		perUnit = covariance / variance
+		overhead = max(0, meanY-perUnit*meanX)
+	}
+	if perUnit <= 0 {
+		return 0, false
+	}
+	return int((float64(target.Milliseconds()) - overhead) / perUnit), true
+}
@@ -0,0 +1,82 @@
+package tts
+
+import (
+	"tts/internal/config"
+	"tts/internal/ssml"
+	"tts/internal/utils"
+)
+
+// Preprocessor 负责合成前的文本清理与SSML转义
+type Preprocessor struct {
+	processor   *config.SSMLProcessor
+	inlineHints bool
+	rubyMode    string
+	bidi        bool
+	sayAs       ssml.SayAsRules
+	acronyms    *ssml.Acronyms
+	pacing      *ssml.Pacing
+}
+
+// NewPreprocessor 根据SSML配置创建预处理器
+func NewPreprocessor(cfg *SSMLConfig) (*Preprocessor, error) {
+	processor, err := config.NewSSMLProcessor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p := &Preprocessor{processor: processor, inlineHints: cfg.InlineHints, rubyMode: cfg.Ruby, bidi: cfg.Bidi.Enabled, sayAs: ssml.SayAsRules(cfg.SayAs)}
+	if cfg.Acronyms.Enabled {
+		p.acronyms = ssml.NewAcronyms(cfg.Acronyms.Words, cfg.Acronyms.Unknown == "spell", cfg.Acronyms.MaxLength)
+	}
+	if cfg.Pacing.Enabled {
+		p.pacing = ssml.NewPacing(ssml.PacingOptions{
+			SlowRate:        cfg.Pacing.SlowRate,
+			FastRate:        cfg.Pacing.FastRate,
+			DenseThreshold:  cfg.Pacing.DenseThreshold,
+			SimpleThreshold: cfg.Pacing.SimpleThreshold,
+			LongWord:        cfg.Pacing.LongWord,
+			MinWords:        cfg.Pacing.MinWords,
+		})
+	}
+	return p, nil
+}
+
+// StripMarkdown 清理 Markdown 标记
+func (p *Preprocessor) StripMarkdown(text string) string {
+	return p.processor.StripMarkdown(text)
+}
+
+// EscapeSSML 转义文本，但保留配置的SSML标签
+func (p *Preprocessor) EscapeSSML(text string) string {
+	return p.processor.EscapeSSML(text)
+}
+
+// ExpandHints 把 {重庆|chóng qìng} 形式的读音提示转换为 <phoneme>、<sub> 标签
+func (p *Preprocessor) ExpandHints(text string) string {
+	return ssml.ExpandHints(text)
+}
+
+// InferSayAs 按 say_as 配置为日期、金额、电话号码等实体添加 <say-as> 标签
+func (p *Preprocessor) InferSayAs(text string) string {
+	return ssml.InferSayAs(text, p.sayAs)
+}
+
+// ConvertRuby 按 ssml.ruby 配置转换 <ruby> 注音，未配置时原样返回
+func (p *Preprocessor) ConvertRuby(text string) string {
+	return utils.ConvertRuby(text, p.rubyMode)
+}
+
+// Process 依次转换 <ruby> 注音、清理 Markdown、删除双向文本控制符（启用 bidi 时）、按句子难度调整语速（启用 pacing 时）、
+// 转换读音提示（启用 inline_hints 时）、添加 <say-as>（按 say_as 配置）、朗读缩写词（启用 acronyms 时），再进行SSML转义，结果可直接嵌入SSML文档
+func (p *Preprocessor) Process(text string) string {
+	text = p.processor.StripMarkdown(utils.ConvertRuby(text, p.rubyMode))
+	if p.bidi {
+		text = utils.StripBidiControls(text)
+	}
+	text = p.pacing.Apply(text)
+	if p.inlineHints {
+		text = ssml.ExpandHints(text)
+	}
+	text = ssml.InferSayAs(text, p.sayAs)
+	text = p.acronyms.Expand(text)
+	return p.processor.EscapeSSML(text)
+}
@@ -0,0 +1,23 @@
+package tts
+
+import "testing"
+
+const benchMarkdownText = "# 标题\n\n这是**加粗**和*斜体*文本，参见 [链接](https://example.com)。\n\n- 列表项一\n- 列表项二\n\n```go\nfmt.Println(1)\n```\n"
+
+func BenchmarkProcess(b *testing.B) {
+	p, err := NewPreprocessor(&DefaultConfig().SSML)
+	if err != nil {
+		b.Fatalf("NewPreprocessor: %v", err)
+	}
+	for _, bm := range []struct{ name, text string }{
+		{"short", benchShortText},
+		{"markdown", benchMarkdownText},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p.Process(bm.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package tts
+
+import (
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tts/internal/config"
+	"tts/internal/ssml"
+	"tts/internal/utils"
+)
+
+// shortTextLength 低于该长度的文本不做分割
+const shortTextLength = 100
+
+// SegmentRule 描述一种语言的分句规则
+type SegmentRule = config.SegmentRule
+
+// Segmenter 将长文本切分为适合单次合成的句子片段
+type Segmenter struct {
+	Threshold int                    // 超过该长度才进行分段
+	MinLength int                    // 合并后片段的最小长度
+	MaxLength int                    // 合并后片段的最大长度
+	Rules     map[string]SegmentRule // 按语言的分句规则，键为小写的 default、语言或区域
+
+	MaxDuration    time.Duration // 单个片段预计音频时长上限，0 表示不限制
+	CharsPerSecond float64       // 估算时长使用的语速（字/秒）
+
+	// 低延迟模式：流式合成时第一段只取开头的一句或分句，长度按目标延迟估算并限制在 [FirstChunkMinLength, FirstChunkMaxLength] 内
+	LowLatency          bool
+	FirstChunkTarget    time.Duration
+	FirstChunkMinLength int
+	FirstChunkMaxLength int
+}
+
+// NewSegmenter 根据TTS配置创建分段器
+func NewSegmenter(cfg *TTSConfig) *Segmenter {
+	rules := make(map[string]SegmentRule, len(cfg.SegmentRules))
+	for key, rule := range cfg.SegmentRules {
+		rules[strings.ToLower(key)] = rule
+	}
+	return &Segmenter{
+		Threshold: cfg.SegmentThreshold,
+		MinLength: cfg.MinSentenceLength,
+		MaxLength: cfg.MaxSentenceLength,
+		Rules:     rules,
+
+		MaxDuration:    time.Duration(cfg.MaxAudioSeconds) * time.Second,
+		CharsPerSecond: cfg.EstimatedCharsPerSecond,
+
+		LowLatency:          cfg.FirstChunk.Enabled,
+		FirstChunkTarget:    orDuration(time.Duration(cfg.FirstChunk.TargetMs)*time.Millisecond, defaultFirstChunkTarget),
+		FirstChunkMinLength: orInt(cfg.FirstChunk.MinLength, defaultFirstChunkMinLength),
+		FirstChunkMaxLength: orInt(cfg.FirstChunk.MaxLength, defaultFirstChunkMaxLength),
+	}
+}
+
+// NeedsSplit 判断文本是否需要分段处理
+func (s *Segmenter) NeedsSplit(text string) bool {
+	return utf8.RuneCountInString(text) > s.Threshold
+}
+
+// OverBudget 判断文本的预计音频时长是否超过上限
+func (s *Segmenter) OverBudget(text, rate string) bool {
+	return s.MaxDuration > 0 && ssml.EstimateDuration(text, rate, s.CharsPerSecond) > s.MaxDuration
+}
+
+// FitBudget 将预计音频时长超过上限的片段继续对半切分，直到每段都在上限内或无法再切分
+func (s *Segmenter) FitBudget(sentences []string, rate string) []string {
+	if s.MaxDuration <= 0 {
+		return sentences
+	}
+	result := make([]string, 0, len(sentences))
+	for _, sentence := range sentences {
+		if !s.OverBudget(sentence, rate) {
+			result = append(result, sentence)
+			continue
+		}
+		half := (utils.UnitCount(sentence) + 1) / 2
+		parts := utils.SplitByGraphemeLimit(sentence, half)
+		if len(parts) < 2 {
+			log.Printf("片段预计时长超过上限但无法继续切分，长度: %d", utils.GraphemeCount(sentence))
+			result = append(result, sentence)
+			continue
+		}
+		result = append(result, s.FitBudget(parts, rate)...)
+	}
+	return result
+}
+
+// Plan 返回合成文本时使用的片段：不需要分段时为整段文本，否则按句子切分并保证每段在时长上限内，
+// 与 Synthesizer.Synthesize 的分段方式一致
+func (s *Segmenter) Plan(text, rate, locale string) []string {
+	if !s.NeedsSplit(text) && !s.OverBudget(text, rate) {
+		return []string{text}
+	}
+	return s.FitBudget(s.SplitLocale(text, locale), rate)
+}
+
+// FirstChunk 从文本开头切出不超过 limit 个单位的第一段，优先在句末、其次在分号与逗号等分句的标点处切开，
+// 返回去掉首尾空白的第一段与剩余的文本；全文不超过 limit 时剩余部分为空，只有空白时两者都为空
+func (s *Segmenter) FirstChunk(text string, limit int) (string, string) {
+	text = strings.TrimSpace(text)
+	if utils.UnitCount(text) <= limit {
+		return text, ""
+	}
+	cut := utils.CutByGraphemeLimit(text, limit)
+	return strings.TrimSpace(text[:cut]), strings.TrimSpace(text[cut:])
+}
+
+// previewCharsPerSecond 未配置 estimated_chars_per_second 时预览估算使用的语速（字/秒）
+const previewCharsPerSecond = 4
+
+// Preview 截取文本开头预计朗读约 seconds 秒的部分：按句子累加直到预计时长达到目标，
+// 第一句就远超目标时按比例截断该句。截取结果与原文相同时说明全文都在目标时长内
+func (s *Segmenter) Preview(text, rate, locale string, seconds float64) string {
+	target := time.Duration(seconds * float64(time.Second))
+	cps := s.CharsPerSecond
+	if cps <= 0 {
+		cps = previewCharsPerSecond
+	}
+
+	rule := s.rule(locale)
+	var preview strings.Builder
+	var elapsed time.Duration
+	for i, line := range utils.SplitAndFilterEmptyLines(text) {
+		if i > 0 {
+			preview.WriteString("\n")
+		}
+		for j, sentence := range utils.SplitByDelimiters(line, rule.Delimiters, rule.Protected) {
+			estimate := ssml.EstimateDuration(sentence, rate, cps)
+			if preview.Len() == 0 && estimate > 2*target {
+				// 按预计时长的比例截断第一句，在标点或字素边界处切开
+				limit := max(1, int(float64(utils.UnitCount(sentence))*float64(target)/float64(estimate)))
+				return utils.SplitByGraphemeLimit(sentence, limit)[0]
+			}
+			// 分句时去掉了句子两端的空白，西文句子之间补回空格
+			if j > 0 {
+				preview.WriteString(" ")
+			}
+			preview.WriteString(sentence)
+			if elapsed += estimate; elapsed >= target {
+				return preview.String()
+			}
+		}
+	}
+	return text
+}
+
+// Split 使用默认规则将文本按句子分割
+func (s *Segmenter) Split(text string) []string {
+	return s.SplitLocale(text, "")
+}
+
+// SplitLocale 使用指定区域（如 zh-CN）的分句规则将文本按句子分割
+func (s *Segmenter) SplitLocale(text, locale string) []string {
+	// 如果文本过短，直接作为一个句子返回
+	if utf8.RuneCountInString(text) < shortTextLength {
+		return []string{text}
+	}
+
+	// 第一次分割：按行与配置的分句符号拆分，超过最大长度的句子再按标点在字素边界切开
+	rule := s.rule(locale)
+	var sentences []string
+	for _, line := range utils.SplitAndFilterEmptyLines(text) {
+		for _, sentence := range utils.SplitByDelimiters(line, rule.Delimiters, rule.Protected) {
+			sentences = append(sentences, utils.SplitByGraphemeLimit(sentence, s.MaxLength)...)
+		}
+	}
+	// 第二次处理：合并过短的句子
+	merged := utils.MergeStringsWithLimit(sentences, s.MinLength, s.MaxLength)
+	log.Printf("分割后的句子数: %d → %d", len(sentences), len(merged))
+	return merged
+}
+
+// rule 按区域、语言、default 的顺序查找分句规则
+func (s *Segmenter) rule(locale string) SegmentRule {
+	locale = strings.ToLower(locale)
+	if rule, ok := s.Rules[locale]; ok && locale != "" {
+		return rule
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		if rule, ok := s.Rules[lang]; ok {
+			return rule
+		}
+	}
+	return s.Rules["default"]
+}
+
+// LocaleOf 从语音名称（如 zh-CN-XiaoxiaoNeural）中提取区域
+func LocaleOf(voice string) string {
+	parts := strings.Split(voice, "-")
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "-" + parts[1]
+}
+
+func orInt(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func orDuration(value, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
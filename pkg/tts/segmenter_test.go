@@ -0,0 +1,180 @@
+package tts
+
+import (
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"tts/internal/utils"
+)
+
+func newTestSegmenter() *Segmenter {
+	return NewSegmenter(&TTSConfig{
+		SegmentThreshold:  100,
+		MinSentenceLength: 1, // 不合并短句，便于检查分句结果
+		MaxSentenceLength: 40,
+		SegmentRules: map[string]SegmentRule{
+			"default": {Delimiters: []string{".", "?", "!", ";"}, Protected: []string{"Mr.", "Dr.", "e.g."}},
+			"zh":      {Delimiters: []string{"。", "！", "？", "；"}, Protected: []string{"世纪"}},
+			"zh-TW":   {Delimiters: []string{"。", "、"}},
+		},
+	})
+}
+
+// pad 把文本补足到 shortTextLength，短文本不分句
+func pad(text, filler string) string {
+	for utils.GraphemeCount(text) < shortTextLength {
+		text += filler
+	}
+	return text
+}
+
+func TestSplitLocale(t *testing.T) {
+	s := newTestSegmenter()
+	zhFiller := "一二三四五六七八九十。"
+	tests := []struct {
+		name   string
+		text   string
+		locale string
+		check  func(t *testing.T, got []string)
+	}{
+		{"区域规则优先于语言规则", pad("甲、乙、", zhFiller), "zh-TW", func(t *testing.T, got []string) {
+			want := []string{"甲、", "乙、", "一二三四五六七八九十。"}
+			if !reflect.DeepEqual(got[:3], want) {
+				t.Errorf("got %q, want prefix %q", got[:3], want)
+			}
+		}},
+		{"语言规则：中文分号与感叹号", pad("好的；走吧！", zhFiller), "zh-CN", func(t *testing.T, got []string) {
+			if got[0] != "好的；" || got[1] != "走吧！" {
+				t.Errorf("got %q", got[:2])
+			}
+		}},
+		{"语言规则不使用其他语言的符号", pad("甲、乙。", zhFiller), "zh-CN", func(t *testing.T, got []string) {
+			if got[0] != "甲、乙。" {
+				t.Errorf("got %q", got[0])
+			}
+		}},
+		{"默认规则：ASCII 标点", pad("Hi. How? Go! Stop; ", "Filler text here. "), "en-US", func(t *testing.T, got []string) {
+			want := []string{"Hi.", "How?", "Go!", "Stop;"}
+			if !reflect.DeepEqual(got[:4], want) {
+				t.Errorf("got %q, want prefix %q", got[:4], want)
+			}
+		}},
+		{"保护词不切分", pad("Mr. Smith met Dr. Lee, e.g. today. ", "Filler text here. "), "", func(t *testing.T, got []string) {
+			if got[0] != "Mr. Smith met Dr. Lee, e.g. today." {
+				t.Errorf("got %q", got[0])
+			}
+		}},
+		{"中文保护词", pad("二十世纪。", zhFiller), "zh-CN", func(t *testing.T, got []string) {
+			if !strings.HasPrefix(got[0], "二十世纪。一二三") {
+				t.Errorf("got %q", got[0])
+			}
+		}},
+		{"超过最大长度时按标点切开", strings.Repeat("甲乙丙丁戊己庚辛，", 12), "zh-CN", func(t *testing.T, got []string) {
+			for _, piece := range got {
+				if n := utils.GraphemeCount(piece); n > 40 {
+					t.Errorf("片段超过最大长度 (%d): %q", n, piece)
+				}
+				if !strings.HasSuffix(piece, "，") {
+					t.Errorf("片段应在逗号处切开: %q", piece)
+				}
+			}
+		}},
+		{"换行总是切分", pad("第一行\n第二行\n", zhFiller), "zh-CN", func(t *testing.T, got []string) {
+			if got[0] != "第一行" || got[1] != "第二行" {
+				t.Errorf("got %q", got[:2])
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.SplitLocale(tt.text, tt.locale)
+			if len(got) < 2 {
+				t.Fatalf("应切分为多段: %q", got)
+			}
+			if joined := strings.Join(got, ""); utils.GraphemeCount(joined) > utils.GraphemeCount(tt.text) {
+				t.Errorf("切分后的文本比原文长: %q", joined)
+			}
+			tt.check(t, got)
+		})
+	}
+}
+
+func TestSplitLocaleShortAndEmpty(t *testing.T) {
+	s := newTestSegmenter()
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"短文本不切分", "你好。再见。", []string{"你好。再见。"}},
+		{"空文本", "", []string{""}},
+		{"只有空白的短文本", "   \n  ", []string{"   \n  "}},
+		{"只有空白的长文本", strings.Repeat(" \n", shortTextLength), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.SplitLocale(tt.text, "zh-CN"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitLocale(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	s := newTestSegmenter()
+	if got := s.Plan("短文本。", "0", "zh-CN"); !reflect.DeepEqual(got, []string{"短文本。"}) {
+		t.Errorf("短文本应整段合成: %q", got)
+	}
+	long := pad("", "一二三四五六七八九十。")
+	if got := s.Plan(long, "0", "zh-CN"); len(got) < 2 {
+		t.Errorf("超过阈值的文本应分段: %q", got)
+	}
+}
+
+func TestFirstChunk(t *testing.T) {
+	s := newTestSegmenter()
+	tests := []struct {
+		name        string
+		text        string
+		limit       int
+		first, rest string
+	}{
+		{"未超过上限不拆分", "你好。", 10, "你好。", ""},
+		{"在句末切开", "第一句话。第二句话比较长一些，还有更多内容。", 8, "第一句话。", "第二句话比较长一些，还有更多内容。"},
+		{"没有句末时在逗号切开", "一二三，四五六七八九十", 6, "一二三，", "四五六七八九十"},
+		{"没有标点时硬切", "一二三四五六七八九十", 4, "一二三四", "五六七八九十"},
+		{"第一段在后文中重复出现", "好的。好的。好的。好的。", 3, "好的。", "好的。好的。好的。"},
+		{"开头的空白超过上限", strings.Repeat(" ", 30) + "你好，世界。", 5, "你好，", "世界。"},
+		{"只有空白", strings.Repeat(" ", 50), 10, "", ""},
+		{"空文本", "", 10, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, rest := s.FirstChunk(tt.text, tt.limit)
+			if first != tt.first || rest != tt.rest {
+				t.Errorf("FirstChunk(%q, %d) = (%q, %q), want (%q, %q)", tt.text, tt.limit, first, rest, tt.first, tt.rest)
+			}
+		})
+	}
+}
+
+func BenchmarkSegmenter(b *testing.B) {
+	// 分句时会打印句子数，避免日志输出影响测量
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+	s := NewSegmenter(&DefaultConfig().TTS)
+	for _, bm := range []struct{ name, text string }{
+		{"short", benchShortText},
+		{"long", benchLongText},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.Split(bm.text)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"tts/internal/apperr"
+	"tts/internal/metrics"
+	"tts/internal/utils"
+)
+
+// defaultStreamBuffer 是未配置时每个流式连接最多缓冲的片段数
+const defaultStreamBuffer = 4
+
+// 请求的 latency 参数
+const (
+	LatencyLow    = "low"    // 流式合成时第一段尽量短，尽快返回第一段音频
+	LatencyNormal = "normal" // 按正常大小分段
+)
+
+var (
+	streamActive = metrics.NewGauge("tts_stream_active",
+		"正在进行的流式合成连接数")
+	streamBufferedBytes = metrics.NewGauge("tts_stream_buffered_bytes",
+		"已合成但尚未写给客户端的音频字节数（所有流式连接之和）")
+	streamPausedSeconds = metrics.NewCounter("tts_stream_paused_seconds_total",
+		"缓冲已满、等待客户端读取而暂停启动新片段的总时长（秒）")
+	firstChunkLength = metrics.NewHistogram("tts_first_chunk_length",
+		"低延迟模式下第一段的字数，source 为 tuned（按观测的合成速度估算）或 default（观测不足，使用默认长度）",
+		[]float64{5, 10, 15, 20, 30, 40, 60, 80, 120}, "source")
+)
+
+// streamSegment 是一个片段的合成结果
+type streamSegment struct {
+	audio []byte
+	err   error
+}
+
+// Stream 分段合成语音并按顺序写入 w，每个片段合成完成即可写出，客户端可以边下载边播放。
+// 已开始合成但尚未写出的片段最多 buffer 个（<= 0 时使用默认值 4）：客户端读取较慢、缓冲已满时暂停启动新的片段，
+// 每个连接的内存占用不随文本长度增长。开始写出之前的错误原样返回，之后的错误只能中断连接。
+func (s *Synthesizer) Stream(ctx context.Context, req Request, w io.Writer, buffer int) error {
+	if req.Text == "" {
+		return apperr.New(apperr.CodeInvalidRequest, "文本不能为空")
+	}
+	if buffer <= 0 {
+		buffer = defaultStreamBuffer
+	}
+
+	sentences := s.streamPlan(req)
+	if len(sentences) == 0 {
+		// 只有空白的文本切分后没有片段
+		return apperr.New(apperr.CodeInvalidRequest, "文本不能为空")
+	}
+
+	streamActive.Add(1)
+	defer streamActive.Add(-1)
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	results := make([]chan streamSegment, len(sentences))
+	for i := range results {
+		results[i] = make(chan streamSegment, 1)
+	}
+	// slots 中的每个令牌代表一个已开始合成、尚未写出的片段，写出后归还
+	slots := make(chan struct{}, buffer)
+	semaphore := make(chan struct{}, s.maxConcurrent)
+
+	// 提前结束时等待已开始的片段完成，把未写出的音频从缓冲字节数中扣除
+	var wg sync.WaitGroup
+	next := 0
+	defer func() {
+		cancel()
+		go func(from int) {
+			wg.Wait()
+			for _, result := range results[from:] {
+				select {
+				case seg := <-result:
+					streamBufferedBytes.Add(-float64(len(seg.audio)))
+				default:
+				}
+			}
+		}(next)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range sentences {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			default:
+				// 缓冲已满，等待写出一个片段后再继续
+				paused := time.Now()
+				select {
+				case slots <- struct{}{}:
+					streamPausedSeconds.Add(time.Since(paused).Seconds())
+				case <-ctx.Done():
+					return
+				}
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				s.streamSegment(ctx, req, sentences[i], semaphore, results[i])
+			}(i)
+		}
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	for i := range sentences {
+		var seg streamSegment
+		select {
+		case seg = <-results[i]:
+		case <-ctx.Done():
+			return fmt.Errorf("请求被取消: %w", ctx.Err())
+		}
+		next = i + 1
+		if seg.err != nil {
+			return fmt.Errorf("句子 %d 合成失败: %w", i+1, seg.err)
+		}
+
+		_, err := w.Write(seg.audio)
+		streamBufferedBytes.Add(-float64(len(seg.audio)))
+		if err != nil {
+			return fmt.Errorf("写入音频失败: %w", err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		<-slots
+	}
+	return nil
+}
+
+// streamPlan 返回流式合成的片段。低延迟模式下第一段只取开头的一句或分句，长度按目标延迟与最近的合成速度估算，
+// 使第一段音频尽快返回；其余部分按正常大小分段，减少请求次数
+func (s *Synthesizer) streamPlan(req Request) []string {
+	locale := LocaleOf(req.Voice)
+	if !s.lowLatency(req) {
+		return s.segmenter.Plan(req.Text, req.Rate, locale)
+	}
+	limit, tuned := s.latency.length(s.segmenter.FirstChunkTarget)
+	if !tuned {
+		limit = defaultFirstChunkLength
+	}
+	limit = min(max(limit, s.segmenter.FirstChunkMinLength), s.segmenter.FirstChunkMaxLength)
+	first, rest := s.segmenter.FirstChunk(req.Text, limit)
+	if first == "" {
+		return nil
+	}
+	if rest == "" {
+		return []string{first}
+	}
+	source := "default"
+	if tuned {
+		source = "tuned"
+	}
+	firstChunkLength.Observe(float64(utils.GraphemeCount(first)), source)
+	return append([]string{first}, s.segmenter.Plan(rest, req.Rate, locale)...)
+}
+
+// lowLatency 判断是否使用低延迟模式，请求的 latency 优先于 tts.first_chunk.enabled
+func (s *Synthesizer) lowLatency(req Request) bool {
+	switch req.Latency {
+	case LatencyLow:
+		return true
+	case LatencyNormal:
+		return false
+	}
+	return s.segmenter.LowLatency
+}
+
+// streamSegment 合成一个片段并把结果发送到 result，合成的音频计入缓冲字节数直到被写出
+func (s *Synthesizer) streamSegment(ctx context.Context, req Request, text string, semaphore chan struct{}, result chan<- streamSegment) {
+	select {
+	case semaphore <- struct{}{}:
+		defer func() { <-semaphore }()
+	case <-ctx.Done():
+		result <- streamSegment{err: ctx.Err()}
+		return
+	}
+
+	req.Text = text
+	resp, err := s.speak(ctx, req)
+	if err != nil {
+		result <- streamSegment{err: err}
+		return
+	}
+	addUsage(ctx, utils.GraphemeCount(text))
+	streamBufferedBytes.Add(float64(len(resp.AudioContent)))
+	result <- streamSegment{audio: resp.AudioContent}
+}
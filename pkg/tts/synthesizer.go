@@ -0,0 +1,297 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"tts/internal/apperr"
+	"tts/internal/privacy"
+	"tts/internal/utils"
+)
+
+// Synthesizer 组合分段、并发合成与音频合并，构成完整的合成管线
+type Synthesizer struct {
+	provider      Provider
+	segmenter     *Segmenter
+	maxConcurrent int
+	latency       latencyModel // 最近的合成耗时，用于低延迟模式估算第一段的长度
+}
+
+// NewSynthesizer 创建一个新的合成器
+func NewSynthesizer(provider Provider, segmenter *Segmenter, maxConcurrent int) *Synthesizer {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &Synthesizer{
+		provider:      provider,
+		segmenter:     segmenter,
+		maxConcurrent: maxConcurrent,
+	}
+}
+
+// Provider 返回底层语音合成服务
+func (s *Synthesizer) Provider() Provider {
+	return s.provider
+}
+
+// Segmenter 返回合成器使用的分段器
+func (s *Synthesizer) Segmenter() *Segmenter {
+	return s.segmenter
+}
+
+// speak 调用底层服务合成一个片段，未命中缓存时记录合成耗时
+func (s *Synthesizer) speak(ctx context.Context, req Request) (*Response, error) {
+	start := time.Now()
+	resp, err := s.provider.SynthesizeSpeech(ctx, req)
+	if err == nil && !resp.CacheHit {
+		s.latency.observe(utils.UnitCount(req.Text), time.Since(start))
+	}
+	return resp, err
+}
+
+// ListVoices 获取可用的语音列表
+func (s *Synthesizer) ListVoices(ctx context.Context, locale string) ([]Voice, error) {
+	return s.provider.ListVoices(ctx, locale)
+}
+
+// Synthesize 合成语音，长文本会自动分段并发合成后合并
+func (s *Synthesizer) Synthesize(ctx context.Context, req Request) (*Response, error) {
+	if req.Text == "" {
+		return nil, apperr.New(apperr.CodeInvalidRequest, "文本不能为空")
+	}
+
+	// 快速路径：短文本直接合成，完全跳过分段、并发与合并
+	if !s.segmenter.NeedsSplit(req.Text) && !s.segmenter.OverBudget(req.Text, req.Rate) {
+		resp, err := s.speak(ctx, req)
+		if err == nil {
+			addUsage(ctx, utils.GraphemeCount(req.Text))
+		}
+		return resp, err
+	}
+
+	log.Printf("文本长度 %d 超过阈值 %d 或预计时长超过上限，使用分段处理", utf8.RuneCountInString(req.Text), s.segmenter.Threshold)
+	audio, err := s.SynthesizeSegmented(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		AudioContent: audio,
+		ContentType:  "audio/mpeg",
+	}, nil
+}
+
+// SegmentResult 记录单个片段的合成信息
+type SegmentResult struct {
+	Index     int
+	Length    int
+	AudioSize int
+	Content   string
+	Duration  time.Duration
+}
+
+// SynthesizeSegmented 分割文本、并发合成每个片段并合并为一个音频
+func (s *Synthesizer) SynthesizeSegmented(ctx context.Context, req Request) ([]byte, error) {
+	// 开始计时：分割文本
+	splitStart := time.Now()
+	sentences := s.segmenter.FitBudget(s.segmenter.SplitLocale(req.Text, LocaleOf(req.Voice)), req.Rate)
+	splitTime := time.Since(splitStart)
+	if len(sentences) == 0 {
+		// 只有空白的长文本切分后没有片段
+		return nil, apperr.New(apperr.CodeInvalidRequest, "文本不能为空")
+	}
+
+	log.Printf("分割文本耗时: %v, 文本总长度: %d, 分段数: %d, 平均句子长度: %.2f",
+		splitTime, utf8.RuneCountInString(req.Text), len(sentences),
+		float64(utf8.RuneCountInString(req.Text))/float64(len(sentences)))
+
+	// 合成阶段开始时间
+	synthesisStart := time.Now()
+	segments, results, err := s.SynthesizeSegments(ctx, req, sentences)
+	if err != nil {
+		return nil, err
+	}
+	logSegmentResults(results)
+
+	synthesisTime := time.Since(synthesisStart)
+	log.Printf("所有分段合成总耗时: %v, 平均每段耗时: %v",
+		synthesisTime, synthesisTime/time.Duration(len(sentences)))
+
+	audio, err := MergeAudio(segments)
+	if err != nil {
+		return nil, apperr.Wrap(apperr.CodeInternal, "音频合并失败", err)
+	}
+	return audio, nil
+}
+
+// SynthesizeSegments 以有限并发合成给定的文本片段，结果顺序与输入一致。
+// 任一片段失败或 ctx 被取消（如客户端断开）时，尚未开始的片段不再合成，进行中的请求随之取消。
+func (s *Synthesizer) SynthesizeSegments(ctx context.Context, req Request, sentences []string) ([][]byte, []SegmentResult, error) {
+	segmentCount := len(sentences)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 创建用于存储每段音频的切片
+	audio := make([][]byte, segmentCount)
+	// 创建用于收集合成结果信息的切片
+	results := make([]SegmentResult, segmentCount)
+
+	errChan := make(chan error, 1)
+	var wg sync.WaitGroup
+
+	// 限制并发数量
+	semaphore := make(chan struct{}, s.maxConcurrent)
+
+	// 并发处理每一个句子
+	for i := 0; i < segmentCount; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}: // 获取信号量
+				defer func() { <-semaphore }() // 释放信号量
+			case <-ctx.Done():
+				select {
+				case errChan <- ctx.Err():
+				default:
+				}
+				return
+			}
+			// 等待信号量期间请求可能已被取消
+			if ctx.Err() != nil {
+				return
+			}
+
+			// 创建该句的请求
+			segReq := req
+			segReq.Text = sentences[index]
+
+			startTime := time.Now()
+			// 合成该段音频
+			resp, err := s.speak(ctx, segReq)
+			synthDuration := time.Since(startTime)
+
+			if err != nil {
+				select {
+				case errChan <- fmt.Errorf("句子 %d 合成失败: %w", index+1, err):
+				default:
+				}
+				// 一个片段失败后整个请求都会失败，取消其余片段
+				cancel()
+				return
+			}
+			addUsage(ctx, utils.GraphemeCount(sentences[index]))
+
+			// 每个 goroutine 只写入自己的下标，无需加锁
+			results[index] = SegmentResult{
+				Index:     index,
+				Length:    utf8.RuneCountInString(sentences[index]),
+				AudioSize: len(resp.AudioContent),
+				Content:   privacy.LogText(ctx, sentences[index], 20),
+				Duration:  synthDuration,
+			}
+			audio[index] = resp.AudioContent
+		}(i)
+	}
+
+	// 等待所有goroutine完成或出错
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		// 所有goroutine正常完成，但仍可能有片段失败
+		select {
+		case err := <-errChan:
+			return nil, nil, err
+		default:
+		}
+		return audio, results, nil
+	case err := <-errChan:
+		return nil, nil, err
+	case <-ctx.Done():
+		// 片段失败时也会取消 ctx，优先返回片段的错误
+		select {
+		case err := <-errChan:
+			if !errors.Is(err, context.Canceled) {
+				return nil, nil, err
+			}
+		default:
+		}
+		return nil, nil, fmt.Errorf("请求被取消: %w", ctx.Err())
+	}
+}
+
+// logSegmentResults 打印表格格式的合成结果
+func logSegmentResults(results []SegmentResult) {
+	log.Println("句子合成结果表:")
+	log.Println("-------------------------------------------------------------")
+	log.Println("序号 | 长度  |    音频大小   |    耗时    | 内容")
+	log.Println("-------------------------------------------------------------")
+	for i, result := range results {
+		log.Printf("#%-3d | %4d | %12s | %10v | %s",
+			i+1,
+			result.Length,
+			utils.FormatFileSize(result.AudioSize),
+			result.Duration.Round(time.Millisecond),
+			result.Content)
+	}
+	log.Println("-------------------------------------------------------------")
+}
+
+// MergeAudio 使用 ffmpeg 合并多个 MP3 音频片段
+func MergeAudio(audioSegments [][]byte) ([]byte, error) {
+	if len(audioSegments) == 0 {
+		return nil, fmt.Errorf("没有音频片段可合并")
+	}
+
+	tempDir, err := os.MkdirTemp("", "audio_merge_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	listFile := filepath.Join(tempDir, "concat.txt")
+	lf, err := os.Create(listFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, seg := range audioSegments {
+		segFile := filepath.Join(tempDir, fmt.Sprintf("seg_%d.mp3", i))
+		if err := os.WriteFile(segFile, seg, 0644); err != nil {
+			lf.Close()
+			return nil, err
+		}
+		if _, err := lf.WriteString(fmt.Sprintf("file '%s'\n", segFile)); err != nil {
+			lf.Close()
+			return nil, err
+		}
+	}
+	lf.Close()
+
+	outputFile := filepath.Join(tempDir, "output.mp3")
+
+	cmd := exec.Command("ffmpeg", "-y", "-f", "concat", "-safe", "0", "-i", listFile, "-c", "copy", outputFile)
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	mergedData, err := os.ReadFile(outputFile)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("使用ffmpeg合并完成，总大小: %s", utils.FormatFileSize(len(mergedData)))
+	return mergedData, nil
+}
@@ -0,0 +1,38 @@
+package tts
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"tts/internal/models"
+)
+
+// 基准测试使用的文本
+const benchShortText = "你好，欢迎使用语音合成服务。今天天气不错，适合出门散步。"
+
+var benchLongText = strings.Repeat(benchShortText+"\n", 200)
+
+// noopProvider 立即返回固定音频，用于隔离测量管线本身的开销
+type noopProvider struct{}
+
+func (noopProvider) ListVoices(ctx context.Context, locale string) ([]models.Voice, error) {
+	return nil, nil
+}
+
+func (noopProvider) SynthesizeSpeech(ctx context.Context, req models.TTSRequest) (*models.TTSResponse, error) {
+	return &models.TTSResponse{AudioContent: []byte{0}, ContentType: "audio/mpeg"}, nil
+}
+
+func BenchmarkSynthesize(b *testing.B) {
+	cfg := DefaultConfig()
+	synthesizer := NewSynthesizer(noopProvider{}, NewSegmenter(&cfg.TTS), cfg.TTS.MaxConcurrent)
+	b.Run("short", func(b *testing.B) {
+		req := Request{Text: benchShortText}
+		for i := 0; i < b.N; i++ {
+			if _, err := synthesizer.Synthesize(context.Background(), req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,86 @@
+// Package tts 提供可嵌入的语音合成管线，
+// 其他 Go 程序无需启动 HTTP 服务即可直接使用 Azure + SSML 的处理逻辑。
+package tts
+
+import (
+	"tts/internal/config"
+	"tts/internal/models"
+	internaltts "tts/internal/tts"
+	"tts/internal/tts/microsoft"
+	"tts/internal/tts/mock"
+)
+
+// Config 是合成管线使用的完整配置
+type Config = config.Config
+
+// TTSConfig 是语音合成相关配置
+type TTSConfig = config.TTSConfig
+
+// SSMLConfig 是SSML标签保留配置
+type SSMLConfig = config.SSMLConfig
+
+// TagPattern 定义需要保留的SSML标签模式
+type TagPattern = config.TagPattern
+
+// Request 表示一个语音合成请求
+type Request = models.TTSRequest
+
+// Response 表示一个语音合成响应
+type Response = models.TTSResponse
+
+// Voice 表示一个可用语音
+type Voice = models.Voice
+
+// Provider 是底层语音合成服务需要实现的接口
+type Provider = internaltts.Service
+
+// DefaultConfig 返回一份与 configs/config.yaml 一致的默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		TTS: config.TTSConfig{
+			Region:            "eastasia",
+			DefaultVoice:      "zh-CN-XiaoxiaoNeural",
+			DefaultRate:       "0",
+			DefaultPitch:      "0",
+			DefaultFormat:     "audio-24khz-48kbitrate-mono-mp3",
+			MaxTextLength:     65535,
+			RequestTimeout:    30,
+			MaxConcurrent:     20,
+			SegmentThreshold:  300,
+			MinSentenceLength: 200,
+			MaxSentenceLength: 300,
+			MaxAudioSeconds:   540,
+
+			EstimatedCharsPerSecond: 4,
+		},
+		SSML: config.SSMLConfig{PreserveTags: []config.TagPattern{
+			{Name: "break", Pattern: `<break\s+[^>]*/>`},
+			{Name: "speak", Pattern: `<speak>|</speak>`},
+			{Name: "prosody", Pattern: `<prosody\s+[^>]*>|</prosody>`},
+			{Name: "emphasis", Pattern: `<emphasis\s+[^>]*>|</emphasis>`},
+			{Name: "voice", Pattern: `<voice\s+[^>]*>|</voice>`},
+			{Name: "say-as", Pattern: `<say-as\s+[^>]*>|</say-as>`},
+			{Name: "phoneme", Pattern: `<phoneme\s+[^>]*>|</phoneme>`},
+			{Name: "audio", Pattern: `<audio\s+[^>]*>|</audio>`},
+			{Name: "p", Pattern: `<p>|</p>`},
+			{Name: "s", Pattern: `<s>|</s>`},
+			{Name: "sub", Pattern: `<sub\s+[^>]*>|</sub>`},
+			{Name: "mstts", Pattern: `<mstts:[^>]*>|</mstts:[^>]*>`},
+		}, InlineHints: true},
+	}
+}
+
+// NewAzureProvider 根据配置创建 Azure 语音合成客户端
+func NewAzureProvider(cfg *Config) Provider {
+	return microsoft.NewClient(cfg)
+}
+
+// NewMockProvider 创建输出确定性静音音频的模拟服务，适用于测试
+func NewMockProvider(cfg *Config) Provider {
+	return mock.NewClient(cfg)
+}
+
+// New 使用 Azure 客户端创建一个完整的合成器
+func New(cfg *Config) *Synthesizer {
+	return NewSynthesizer(NewAzureProvider(cfg), NewSegmenter(&cfg.TTS), cfg.TTS.MaxConcurrent)
+}
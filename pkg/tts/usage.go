@@ -0,0 +1,32 @@
+package tts
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// usageKey 是上下文中保存用量统计的键
+type usageKey struct{}
+
+// Usage 统计一次合成实际完成的字符数（按字素计），分段合成中途取消时只包含已经合成的片段
+type Usage struct {
+	characters atomic.Int64
+}
+
+// WithUsage 返回携带用量统计的上下文，合成器会把每个成功合成的片段计入其中
+func WithUsage(ctx context.Context) (context.Context, *Usage) {
+	usage := &Usage{}
+	return context.WithValue(ctx, usageKey{}, usage), usage
+}
+
+// Characters 返回已经合成的字符数
+func (u *Usage) Characters() int {
+	return int(u.characters.Load())
+}
+
+// addUsage 把成功合成的字符数计入上下文中的用量统计
+func addUsage(ctx context.Context, characters int) {
+	if usage, ok := ctx.Value(usageKey{}).(*Usage); ok {
+		usage.characters.Add(int64(characters))
+	}
+}